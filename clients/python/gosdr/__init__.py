@@ -0,0 +1,244 @@
+"""Client for the GoSDR monopulse tracker HTTP API.
+
+Uses only the Python standard library. The client is maintained by hand, not
+generated; TestPythonClientEndpoints in internal/telemetry checks its paths
+against the Go web server's routes. The methods map one to one onto the
+endpoints described in the project README:
+
+    from gosdr import Client
+    c = Client("http://sdr-host:8080")
+    c.update_config(rxLoHz=2.3e9, snrThreshold=6)
+    for sample in c.live(limit=10):
+        print(sample["tracks"][0]["angleDeg"])
+    arrays = c.export_history()   # needs numpy
+
+Errors reported by the server raise APIError with the HTTP status and the
+server's message.
+"""
+
+import base64
+import io
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+
+__all__ = ["APIError", "Client"]
+
+
+class APIError(Exception):
+    """An error response from the tracker API.
+
+    body is the decoded JSON error document when there is one; for a 409 from
+    update_config it holds the current revision, config and diff.
+    """
+
+    def __init__(self, status, message, body=None):
+        super().__init__(f"{status}: {message}")
+        self.status = status
+        self.message = message
+        self.body = body
+
+
+class Client:
+    """Talks to one tracker, telemetryd or aggregator web server.
+
+    device scopes every call to one device of a multi-device run, using the
+    /api/devices/{id}/ routes where they exist. user and password enable HTTP
+    basic authentication (telemetryd -auth). token is a session token from
+    pair() or the admin token, for servers that require pairing.
+    """
+
+    def __init__(self, base_url, device=None, user=None, password=None, token=None, timeout=10.0):
+        self.base_url = base_url.rstrip("/")
+        self.device = device
+        self.timeout = timeout
+        self._headers = {}
+        self._revision = None
+        if user is not None:
+            basic = base64.b64encode(f"{user}:{password or ''}".encode()).decode()
+            self._headers["Authorization"] = "Basic " + basic
+        elif token is not None:
+            self._headers["Authorization"] = "Bearer " + token
+
+    # Pairing
+
+    def pair(self, code):
+        """Exchange the pairing code printed by the server for a session token.
+
+        The token is used for later calls and replaced automatically when the
+        server rotates it. Returns the token.
+        """
+        result = self._request("POST", "/api/pair", body={"code": code})
+        self._headers["Authorization"] = "Bearer " + result["token"]
+        return result["token"]
+
+    # Configuration
+
+    def config(self):
+        """Return the live tracker configuration and its revision."""
+        cfg = self._get_json("/api/config")
+        self._revision = cfg.get("revision")
+        return cfg
+
+    def update_config(self, revision=None, **fields):
+        """Apply configuration fields, e.g. rxLoHz=2.3e9, and return the result.
+
+        Field names are the JSON names returned by config(); omitted fields
+        keep their current value. The update is based on revision, by default
+        the one seen by the last config() or update_config() call (fetched
+        first if there is none). If someone else changed the config since,
+        APIError with status 409 is raised and its body lists the changes;
+        call config() and retry to apply on top of them.
+        """
+        if revision is None:
+            revision = self._revision if self._revision is not None else self.config()["revision"]
+        cfg = self._request("POST", "/api/config/update", body={**fields, "revision": revision})
+        self._revision = cfg.get("revision")
+        return cfg
+
+    def capabilities(self):
+        """Return the SDR backend capabilities and supported ranges."""
+        return self._get_json(self._scoped("sdr/capabilities"))
+
+    def version(self):
+        """Return the server build information."""
+        return self._get_json("/api/version")
+
+    def devices(self):
+        """Return the devices of a multi-device run."""
+        return self._get_json("/api/devices")
+
+    # Telemetry
+
+    def history(self, tracks=None, after=None):
+        """Return the recorded samples, oldest first.
+
+        tracks limits the result to the given track IDs; after returns only
+        samples with a larger sequence number.
+        """
+        return self._get_json(self._scoped("history"), tracks=_join(tracks), after=after)
+
+    def tracks(self, **query):
+        """Return the current tracks; query takes state, sort, min_snr, since,
+        offset and limit like /api/tracks."""
+        return self._get_json(self._scoped("tracks"), **query)
+
+    def track_history(self, track_id):
+        """Return the observations of one track."""
+        return self._get_json("/api/tracks/" + urllib.parse.quote(track_id, safe=""))
+
+    def spectrum(self):
+        """Return the latest spectrum snapshot."""
+        return self._get_json("/api/diagnostics/spectrum")
+
+    def diagnostics(self):
+        """Return the diagnostics summary and recent events."""
+        return self._get_json("/api/diagnostics")
+
+    def live(self, tracks=None, after=None, rate=None, limit=None):
+        """Yield samples from the live stream as they arrive.
+
+        The stream first replays the history after the after cursor. limit
+        stops after that many samples; otherwise the generator runs until the
+        connection closes.
+        """
+        path = self._scoped("live")
+        url = self._url(path, tracks=_join(tracks), after=after, rate=rate)
+        req = urllib.request.Request(url, headers=dict(self._headers, Accept="text/event-stream"))
+        count = 0
+        with self._open(req, timeout=None) as resp:
+            data = []
+            for raw in resp:
+                line = raw.decode("utf-8").rstrip("\r\n")
+                if line.startswith("data:"):
+                    data.append(line[5:].lstrip())
+                elif line == "" and data:
+                    yield json.loads("\n".join(data))
+                    data = []
+                    count += 1
+                    if limit is not None and count >= limit:
+                        return
+
+    def export_history(self, path=None, tracks=None, after=None):
+        """Download the history as a NumPy .npz archive.
+
+        With path the archive is written there and path is returned; without
+        it the archive is loaded with numpy and returned as a dict of arrays.
+        """
+        url = self._url(self._scoped("history/export"), format="npz", tracks=_join(tracks), after=after)
+        with self._open(urllib.request.Request(url, headers=self._headers)) as resp:
+            payload = resp.read()
+        if path is not None:
+            with open(path, "wb") as f:
+                f.write(payload)
+            return path
+        import numpy
+
+        with numpy.load(io.BytesIO(payload)) as archive:
+            return {name: archive[name] for name in archive.files}
+
+    # SDR
+
+    def loopback(self, measure=False, **options):
+        """Return the last TX/RX loopback result, or run a new measurement.
+
+        options take patternLength, buffers and trials.
+        """
+        if measure:
+            return self._request("POST", self._scoped("sdr/loopback"), body=options)
+        return self._get_json(self._scoped("sdr/loopback"))
+
+    # Transport
+
+    def _scoped(self, resource):
+        if self.device:
+            return "/api/devices/" + urllib.parse.quote(self.device, safe="") + "/" + resource
+        return "/api/" + resource
+
+    def _url(self, path, **query):
+        params = {k: v for k, v in query.items() if v is not None}
+        url = self.base_url + path
+        if params:
+            url += "?" + urllib.parse.urlencode(params)
+        return url
+
+    def _get_json(self, path, **query):
+        return self._request("GET", path, query=query)
+
+    def _request(self, method, path, body=None, query=None):
+        headers = dict(self._headers)
+        data = None
+        if body is not None:
+            data = json.dumps(body).encode()
+            headers["Content-Type"] = "application/json"
+        req = urllib.request.Request(self._url(path, **(query or {})), data=data, method=method, headers=headers)
+        with self._open(req) as resp:
+            payload = resp.read()
+        return json.loads(payload) if payload else None
+
+    def _open(self, req, timeout=0):
+        try:
+            resp = urllib.request.urlopen(req, timeout=self.timeout if timeout == 0 else timeout)
+        except urllib.error.HTTPError as err:
+            message, body = _error_body(err)
+            raise APIError(err.code, message, body) from None
+        rotated = resp.headers.get("X-GoSDR-Token")
+        if rotated:
+            self._headers["Authorization"] = "Bearer " + rotated
+        return resp
+
+
+def _join(values):
+    if values is None or isinstance(values, str):
+        return values
+    return ",".join(values)
+
+
+def _error_body(err):
+    body = err.read()
+    try:
+        doc = json.loads(body)
+        return doc["error"], doc
+    except (ValueError, KeyError, TypeError):
+        return body.decode("utf-8", "replace").strip() or err.reason, None
@@ -33,8 +33,8 @@ func main() {
 	log.Println("[STEP 1] Creating connection manager...")
 	m := connectionmgr.New(iiodAddress)
 	m.SetTimeout(ioTimeout)
-	log.Printf("Manager created: Address=%s Mode=%v Timeout=%s",
-		m.Address, m.Mode, m.Timeout)
+	log.Printf("Manager created: Address=%s Mode=%v Timeouts=%+v",
+		m.Address, m.Mode, m.Timeouts)
 
 	log.Println("[STEP 2] Connecting to IIOD...")
 	if err := m.Connect(); err != nil {
@@ -55,14 +55,11 @@ func main() {
 	// 2. Set remote timeout via TIMEOUT (same pattern as libiio)
 	// ---------------------------------------------------------------------
 	log.Println("[STEP 3] Setting remote TIMEOUT...")
-	remoteMs := (ioTimeout / 2).Milliseconds()
-	timeoutCmd := fmt.Sprintf("TIMEOUT %d", remoteMs)
-
-	log.Printf("[DEBUG] Executing ASCII command: %q", timeoutCmd)
-	if ret, err := m.ExecCommand(timeoutCmd); err != nil {
+	log.Printf("[DEBUG] Remote TIMEOUT from policy: %s", m.Timeouts.RemoteTimeout())
+	if err := m.ApplyRemoteTimeout(); err != nil {
 		log.Printf("[WARN] TIMEOUT command error (tinyiiod may not support this): %v", err)
 	} else {
-		log.Printf("[INFO] TIMEOUT response integer=%d", ret)
+		log.Printf("[INFO] TIMEOUT accepted")
 	}
 
 	// ---------------------------------------------------------------------
@@ -112,8 +109,8 @@ func main() {
 		bm := connectionmgr.New(iiodAddress)
 		bm.SetTimeout(ioTimeout)
 
-		log.Printf("[DEBUG] Binary-test manager created: Address=%s Timeout=%s",
-			bm.Address, bm.Timeout)
+		log.Printf("[DEBUG] Binary-test manager created: Address=%s Timeouts=%+v",
+			bm.Address, bm.Timeouts)
 
 		log.Println("[DEBUG] Connecting binary-test manager...")
 		if err := bm.Connect(); err != nil {
@@ -2,8 +2,6 @@ package main
 
 import (
 	"bytes"
-	"encoding/binary"
-	"io"
 	"log"
 	"net"
 	"strconv"
@@ -12,75 +10,8 @@ import (
 	"time"
 )
 
-const (
-	plutoAddr = "192.168.2.1:30431"
-
-	// confirmed responder opcode
-	opGetVersion = 0x01
-)
-
-func main() {
-	workingprobe4()
-}
-
-func must(err error) {
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-func readLine(conn net.Conn) string {
-	var buf []byte
-	tmp := make([]byte, 1)
-	for {
-		// _, err := conn.ReadAll(tmp)
-		// conn.SetReadDeadline(time.Now().Add(5 * time.Second))
-		_, err := conn.Read(tmp)
-		must(err)
-		buf = append(buf, tmp[0])
-		if tmp[0] == '\n' {
-			return string(buf)
-		}
-	}
-}
-
-func writeASCII(conn net.Conn, s string) {
-	log.Printf("[ASCII TX] %q", s)
-	_, err := conn.Write([]byte(s + "\n"))
-	must(err)
-}
-
-func writeBinaryHeader(conn net.Conn, clientID uint16, op, dev uint8, code int32) {
-	hdr := make([]byte, 8)
-	binary.BigEndian.PutUint16(hdr[0:2], clientID)
-	hdr[2] = op
-	hdr[3] = dev
-	binary.BigEndian.PutUint32(hdr[4:8], uint32(code))
-
-	log.Printf("[BIN TX] % X (client=%d op=%d dev=%d code=%d)",
-		hdr, clientID, op, dev, code)
-
-	_, err := conn.Write(hdr)
-	must(err)
-}
-
-func readBinaryHeader(conn net.Conn) {
-	hdr := make([]byte, 8)
-	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
-	_, err := io.ReadFull(conn, hdr)
-	must(err)
-
-	log.Printf("[BIN RX] % X", hdr)
-
-	clientID := binary.BigEndian.Uint16(hdr[0:2])
-	op := hdr[2]
-	dev := hdr[3]
-	code := int32(binary.BigEndian.Uint32(hdr[4:8]))
-
-	log.Printf("[BIN RX DECODED] client=%d op=%d dev=%d code=%d",
-		clientID, op, dev, code)
-}
-
+// workingprobe4 checks the raw ASCII PRINT exchange with a background reader
+// that splits the stream into lines, without the connection manager.
 func workingprobe4() {
 	// ============================================================
 	// PlutoSDR IIOD ASCII ReadAll Probe with Lock + Splitter
@@ -1,46 +1,46 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"log"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
-	man "github.com/RJBOER/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/iiodwire"
 )
 
+const plutoAddr = "192.168.2.1:30431"
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 
-	addr := "192.168.2.1:30431"
-
-	m := &man.Manager{
-		Address: addr,
-		Timeout: 5 * time.Second,
+	ascii := flag.Bool("ascii", false, "probe the raw ASCII PRINT exchange instead of the binary protocol")
+	flag.Parse()
+	if *ascii {
+		workingprobe4()
+		return
 	}
 
+	m := connectionmgr.New(plutoAddr)
+
 	// ------------------------------------------------------------
 	// 1) Connect
 	// ------------------------------------------------------------
-	log.Printf("[TEST] Connecting to Pluto at %s", addr)
-	m.Connect()
+	log.Printf("[TEST] Connecting to Pluto at %s", plutoAddr)
+	if err := m.Connect(); err != nil {
+		log.Fatalf("[TEST] connect failed: %v", err)
+	}
 	defer m.Close()
 
-	m.EnterBinaryMode3()
-	m.clientID = 0x1234 // any non-zero client ID is fine
-
 	// ------------------------------------------------------------
-	// 2) Switch to binary mode (ASCII bootstrap)
+	// 2) Binary mode is implied by the first binary header
 	// ------------------------------------------------------------
-	log.Printf("[TEST] Switching to binary mode")
-	if _, err := m.conn.Write([]byte("BINARY\r\n")); err != nil {
-		log.Fatalf("[TEST] failed to switch to binary mode: %v", err)
-	}
+	m.SetClientID(0x1234) // any non-zero client ID is fine
 
 	// ------------------------------------------------------------
-	// 3) Retrieve XML using your existing wrapper
+	// 3) Retrieve XML using the wrapper
 	// ------------------------------------------------------------
 	log.Printf("[TEST] Retrieving XML")
 	xml, err := m.GetXML(0)
@@ -56,27 +56,8 @@ func main() {
 	}
 
 	// ------------------------------------------------------------
-	// 4) Read some device attributes (direct base-function usage)
+	// 4) Read some device attributes (raw calls)
 	// ------------------------------------------------------------
-	readDevAttr := func(name string) (string, error) {
-		hdr, plan, err := m.sendBinaryCommand(man.opReadAttr, 0, 0, man.lpString(name))
-		if err != nil {
-			return "", err
-		}
-		if hdr == nil || hdr.Opcode != man.opResponse {
-			return "", fmt.Errorf("readDevAttr(%s): unexpected response opcode", name)
-		}
-
-		status, _, data, err := m.readResponse(plan)
-		if err != nil {
-			return "", err
-		}
-		if status != 0 {
-			return "", fmt.Errorf("readDevAttr(%s): status=%d", name, status)
-		}
-		return strings.TrimSpace(string(data)), nil
-	}
-
 	log.Printf("[TEST] Reading common device attributes")
 	for _, attr := range []string{
 		"sampling_frequency",
@@ -85,39 +66,20 @@ func main() {
 		"hardwaregain",
 		"temperature",
 	} {
-		v, err := readDevAttr(attr)
+		resp, err := m.Call(iiodwire.OpReadAttr, 0, 0, iiodwire.LPString(attr))
 		if err != nil {
 			log.Printf("[TEST] %s: %v", attr, err)
 			continue
 		}
-		log.Printf("[TEST] %s = %q", attr, v)
+		log.Printf("[TEST] %s = %q", attr, strings.TrimSpace(string(resp.Data)))
 	}
 
 	// ------------------------------------------------------------
 	// 5) Read channel-indexed attributes (code = channel index)
 	// ------------------------------------------------------------
-	readChnAttr := func(chIdx int32, name string) (string, error) {
-		hdr, plan, err := m.sendBinaryCommand(man.opReadChnAttr, 0, chIdx, man.lpString(name))
-		if err != nil {
-			return "", err
-		}
-		if hdr == nil || hdr.Opcode != man.opResponse {
-			return "", fmt.Errorf("readChnAttr(%d,%s): unexpected response opcode", chIdx, name)
-		}
-
-		status, _, data, err := m.readResponse(plan)
-		if err != nil {
-			return "", err
-		}
-		if status != 0 {
-			return "", fmt.Errorf("readChnAttr(%d,%s): status=%d", chIdx, name, status)
-		}
-		return strings.TrimSpace(string(data)), nil
-	}
-
 	log.Printf("[TEST] Reading channel attributes (hardwaregain)")
 	for _, ch := range []int32{0, 1} {
-		v, err := readChnAttr(ch, "hardwaregain")
+		v, err := m.GetChnAttrIdx(0, ch, "hardwaregain")
 		if err != nil {
 			log.Printf("[TEST] ch=%d hardwaregain: %v", ch, err)
 			continue
@@ -134,26 +96,13 @@ func main() {
 	// ------------------------------------------------------------
 	/*
 		log.Printf("[TEST] Setting gain_control_mode=manual")
-		hdr, plan, err := m.sendBinaryCommand(
-			opWriteAttr,
-			0,
-			0,
-			nameValue("gain_control_mode", "manual"),
-		)
+		resp, err := m.Call(iiodwire.OpWriteAttr, 0, 0, iiodwire.NameValue("gain_control_mode", "manual"))
 		if err != nil {
 			log.Printf("[TEST] write failed: %v", err)
-		} else if hdr == nil || hdr.Opcode != opResponse {
-			log.Printf("[TEST] unexpected response opcode on write")
 		} else {
-			status, _, _, err := m.readResponse(plan)
-			if err != nil {
-				log.Printf("[TEST] write response error: %v", err)
-			} else {
-				log.Printf("[TEST] write status=%d", status)
-			}
+			log.Printf("[TEST] write status=%d", resp.Status)
 		}
 	*/
 
 	log.Printf("[TEST] Pluto binary protocol test completed successfully")
-
 }
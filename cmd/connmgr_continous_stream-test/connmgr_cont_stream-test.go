@@ -20,7 +20,7 @@ func main() {
 	}
 	defer m.Close()
 
-	_, _ = m.ExecCommand("TIMEOUT 2500")
+	_ = m.ApplyRemoteTimeout()
 
 	xml, err := m.FetchXML()
 	if err != nil {
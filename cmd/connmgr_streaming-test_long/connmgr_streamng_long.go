@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"time"
 
 	"github.com/rjboer/GoSDR/internal/connectionmgr"
 )
@@ -13,6 +16,10 @@ const (
 )
 
 func main() {
+	depth := flag.Int("depth", connectionmgr.DefaultStreamDepth, "blocks in flight")
+	iterations := flag.Int("blocks", 10000, "blocks to receive")
+	flag.Parse()
+
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
 
 	log.Println("====================================================")
@@ -34,10 +41,10 @@ func main() {
 	// STEP 2 — Set TIMEOUT
 	// ------------------------------------------------------------------
 	log.Println("[STEP 2] Setting remote TIMEOUT...")
-	if ret, err := m.ExecCommand("TIMEOUT 2000"); err != nil {
+	if err := m.ApplyRemoteTimeout(); err != nil {
 		log.Printf("[WARN] TIMEOUT failed: %v", err)
 	} else {
-		log.Printf("[INFO] TIMEOUT response=%d", ret)
+		log.Printf("[INFO] TIMEOUT set to %s", m.Timeouts.RemoteTimeout())
 	}
 
 	// ------------------------------------------------------------------
@@ -127,85 +134,47 @@ func main() {
 	// m.Mode = connectionmgr.ModeBinary
 
 	// ------------------------------------------------------------------
-	// STEP 6 — Create RX buffer
+	// STEP 6 — Start the pipelined RX stream (block pool of -depth blocks)
 	// ------------------------------------------------------------------
-	log.Println("[STEP 6] Sending CREATE_BUFFER (binary)")
+	log.Printf("[STEP 6] Starting RX stream (depth=%d)...", *depth)
 
-	buf, err := m.CreateBuffer3(
-		0,             // device index (cf-ad9361-lpc)
-		[]uint8{0, 1}, // I/Q channels
-		true,          // RX
-	)
+	out := make(chan []byte, *depth)
+	h, err := m.StartRXStream(context.Background(), connectionmgr.StreamConfig{
+		Buffer: connectionmgr.BufferConfig{
+			DeviceIndex: 0, // cf-ad9361-lpc
+			Samples:     blockSize / 4,
+			Mask:        0b11, // I/Q channels
+		},
+		Depth: *depth,
+		Out:   out,
+	})
 	if err != nil {
-		log.Fatalf("CREATE_BUFFER failed: %v", err)
+		log.Fatalf("StartRXStream failed: %v", err)
 	}
 
-	log.Printf("[OK] CREATE_BUFFER returned bufferID=%d", buf.ID)
-
 	// ------------------------------------------------------------------
-	// STEP 7 — Create RX buffer
+	// STEP 7 — Streaming loop (finite)
 	// ------------------------------------------------------------------
-	// log.Println("[STEP 7] CreateBuffer...")
-	// For cf-ad9361-lpc, dev=0 and channels 0,1 are typical for I/Q
-	// rxBuf, err := m.CreateBuffer(0, []uint8{0, 1}, false)
-	// if err != nil {
-	// 	log.Fatalf("CreateBuffer failed: %v", err)
-	// }
-
-	log.Println("[STEP 7] EnableBuffer...")
-	if err := m.EnableBuffer(buf); err != nil {
-		log.Fatalf("EnableBuffer failed: %v", err)
+	for i := 0; i < *iterations; i++ {
+		<-out
+		if (i+1)%1000 == 0 {
+			st := h.Stats()
+			log.Printf("[RX] iter=%d total=%d rate=%.1f MB/s", i+1, st.Bytes, st.BytesPerSecond()/1e6)
+		}
 	}
 
 	// ------------------------------------------------------------------
-	// STEP 8 — Create RX block
+	// STEP 8 — Cleanup
 	// ------------------------------------------------------------------
-	log.Println("[STEP 8] CreateBlock...")
-	rxBlock, err := m.CreateBlock(buf, blockSize)
-	if err != nil {
-		log.Fatalf("CreateBlock failed: %v", err)
-	}
-
-	// ------------------------------------------------------------------
-	// STEP 9 — Streaming loop (finite)
-	// ------------------------------------------------------------------
-	log.Println("[STEP 9] Starting RX stream...")
-
-	const iterations = 10000
-	totalBytes := 0
-	data := make([]byte, blockSize)
-
-	for i := 0; i < iterations; i++ {
-		ret, err := m.TransferBlock(rxBlock, data)
-		if err != nil {
-			log.Fatalf("TransferBlock error: %v", err)
-		}
-		if ret < 0 {
-			log.Fatalf("TransferBlock returned error code %d", ret)
-		}
-		if ret == 0 {
-			log.Fatalf("TransferBlock returned zero-length payload")
-		}
-
-		totalBytes += ret
-
-		if (i+1)%1000 == 0 {
-			log.Printf(
-				"[RX] iter=%d bytes=%d total=%d\n",
-				i+1, ret, totalBytes,
-			)
-		}
+	log.Println("[STEP 8] Stopping stream...")
+	h.Stop()
+	if err := h.Err(); err != nil {
+		log.Fatalf("stream error: %v", err)
 	}
 
+	st := h.Stats()
 	log.Println("----------------------------------------------------")
-	log.Printf(" RX streaming OK — received %d bytes total\n", totalBytes)
+	log.Printf(" RX streaming OK — %d bytes in %s, %.1f MB/s (%.2f MS/s)\n",
+		st.Bytes, st.Elapsed.Round(time.Millisecond), st.BytesPerSecond()/1e6, st.BytesPerSecond()/4/1e6)
 	log.Println("----------------------------------------------------")
-
-	// ------------------------------------------------------------------
-	// STEP 10 — Cleanup
-	// ------------------------------------------------------------------
-	log.Println("[STEP 10] Disabling buffer...")
-	_ = m.DisableBuffer(buf)
-
-	log.Println("Test completed successfully.")
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"time"
 
@@ -50,11 +49,10 @@ func main() {
 	// 2. TIMEOUT
 	// ---------------------------------------------------------------------
 	log.Println("[STEP 3] Setting remote TIMEOUT...")
-	remoteMs := (ioTimeout / 2).Milliseconds()
-	if ret, err := m.ExecCommand(fmt.Sprintf("TIMEOUT %d", remoteMs)); err != nil {
+	if err := m.ApplyRemoteTimeout(); err != nil {
 		log.Printf("[WARN] TIMEOUT failed: %v", err)
 	} else {
-		log.Printf("[INFO] TIMEOUT response=%d", ret)
+		log.Printf("[INFO] TIMEOUT set to %s", m.Timeouts.RemoteTimeout())
 	}
 
 	// ---------------------------------------------------------------------
@@ -76,55 +74,39 @@ func main() {
 	}
 
 	// ---------------------------------------------------------------------
-	// 5. Create/enable binary buffer
+	// 5. Open buffer (CREATE_BUFFER, CREATE_BLOCK, ENABLE_BUFFER)
 	// ---------------------------------------------------------------------
-	log.Println("[STEP 6] Creating binary buffer...")
-	buf, err := m.CreateBuffer(devIndex, channels, cyclic)
-	if err != nil {
-		log.Fatalf("CreateBuffer failed: %v", err)
-	}
-	defer func() {
-		log.Println("[CLEANUP] Disabling buffer...")
-		if err := m.DisableBuffer(buf); err != nil {
-			log.Printf("[WARN] DisableBuffer error: %v", err)
-		}
-		log.Println("[CLEANUP] Freeing buffer...")
-		if err := m.FreeBuffer(buf); err != nil {
-			log.Printf("[WARN] FreeBuffer error: %v", err)
-		}
-	}()
-
-	log.Println("[STEP 7] Enabling buffer...")
-	if err := m.EnableBuffer(buf); err != nil {
-		log.Fatalf("EnableBuffer failed: %v", err)
+	var mask uint32
+	for _, ch := range channels {
+		mask |= 1 << ch
 	}
-
-	// ---------------------------------------------------------------------
-	// 6. Create block
-	// ---------------------------------------------------------------------
-	log.Printf("[STEP 8] Creating block (size=%d)...", blockSize)
-	blk, err := m.CreateBlock(buf, blockSize)
+	log.Printf("[STEP 6] Opening binary buffer (size=%d)...", blockSize)
+	buf, err := m.OpenBuffer(connectionmgr.BufferConfig{
+		DeviceIndex: devIndex,
+		Samples:     blockSize / (2 * len(channels)),
+		Mask:        mask,
+		Cyclic:      cyclic,
+	})
 	if err != nil {
-		log.Fatalf("CreateBlock failed: %v", err)
+		log.Fatalf("OpenBuffer failed: %v", err)
 	}
 	defer func() {
-		log.Println("[CLEANUP] Freeing block...")
-		if err := m.FreeBlock(blk); err != nil {
-			log.Printf("[WARN] FreeBlock error: %v", err)
+		log.Println("[CLEANUP] Closing buffer...")
+		if err := buf.Close(); err != nil {
+			log.Printf("[WARN] Close error: %v", err)
 		}
 	}()
 
 	// ---------------------------------------------------------------------
-	// 7. Stream blocks
+	// 6. Stream blocks
 	// ---------------------------------------------------------------------
-	log.Println("[STEP 9] Streaming RX data...")
-	payload := make([]byte, blockSize)
+	log.Println("[STEP 7] Streaming RX data...")
 	for i := 0; i < streamTransfers; i++ {
-		n, err := m.TransferBlock(blk, payload)
+		payload, err := buf.ReadSamples()
 		if err != nil {
-			log.Fatalf("TransferBlock failed: %v", err)
+			log.Fatalf("ReadSamples failed: %v", err)
 		}
-		log.Printf("[STREAM] Block %d received %d bytes", i+1, n)
+		log.Printf("[STREAM] Block %d received %d bytes", i+1, len(payload))
 	}
 
 	log.Println("====================================================")
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+	"github.com/rjboer/GoSDR/internal/sdrxml"
+)
+
+// target is the IIOD server under test and the settings shared by all cases.
+type target struct {
+	URI      string
+	RXDevice string
+	TXDevice string
+	Mask     uint32
+	Duration time.Duration // throughput measurement per case
+	Pings    int           // attribute reads for the latency figures
+	Timeout  time.Duration
+}
+
+// result holds the measurements of one case.
+type result struct {
+	benchCase
+	Bytes    uint64
+	Elapsed  time.Duration
+	Ops      int // attribute reads and buffer transfers attempted
+	Errors   int
+	P50, P99 time.Duration
+	Err      error // the error that ended the case, if any
+}
+
+func (r result) bytesPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Elapsed.Seconds()
+}
+
+func (r result) errorRate() float64 {
+	if r.Ops == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Ops)
+}
+
+// endpoint is what a case needs from the context XML: the buffer device and
+// a device attribute to time round trips with.
+type endpoint struct {
+	buf      connectionmgr.BufferConfig
+	attrDev  string // device of the latency attribute, for the ASCII protocol
+	attrIdx  uint8  // its index, for the binary protocol
+	attrName string
+}
+
+// measure runs one case on a fresh connection: it resolves the devices over
+// ASCII, switches to the binary protocol if the mode needs it, then times the
+// attribute reads and the buffer transfers.
+func (t target) measure(c benchCase) result {
+	r := result{benchCase: c}
+	m := connectionmgr.New(t.URI)
+	m.Timeouts.Connect = t.Timeout
+	m.Timeouts.Control = t.Timeout
+	if err := m.Connect(); err != nil {
+		r.Err = err
+		return r
+	}
+	defer m.Close()
+
+	ep, err := t.resolve(m, c)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	if c.KernelBuffers > 0 {
+		if err := m.SetKernelBuffersCountASCII(ep.buf.DeviceID, c.KernelBuffers); err != nil {
+			r.Err = err
+			return r
+		}
+	}
+	if c.Mode != "ascii" {
+		// The server switches protocols on the first binary header.
+		m.Mode = connectionmgr.ModeBinary
+	}
+
+	t.latency(m, c, ep, &r)
+	if r.Err != nil {
+		return r
+	}
+	if c.Mode == "stream" {
+		t.stream(m, c, ep.buf, &r)
+	} else {
+		t.buffered(m, c, ep.buf, &r)
+	}
+	return r
+}
+
+// resolve reads the context XML and looks up the buffer device of the case's
+// direction and the first device attribute of the context.
+func (t target) resolve(m *connectionmgr.Manager, c benchCase) (endpoint, error) {
+	raw, err := m.GetContextXMLASCII()
+	if err != nil {
+		return endpoint{}, fmt.Errorf("fetch context: %w", err)
+	}
+	var ctx sdrxml.SDRContext
+	if err := ctx.Parse(raw); err != nil {
+		return endpoint{}, err
+	}
+
+	dev := t.RXDevice
+	if c.Dir == "tx" {
+		dev = t.TXDevice
+	}
+	ep := endpoint{buf: connectionmgr.BufferConfig{DeviceID: dev, Samples: c.Samples, Mask: t.Mask}}
+	found := false
+	for i, d := range ctx.Device {
+		if d.Name == dev || d.ID == dev {
+			ep.buf.DeviceIndex = uint8(i)
+			found = true
+		}
+		if ep.attrName == "" && len(d.Attribute) > 0 {
+			ep.attrDev, ep.attrIdx, ep.attrName = d.ID, uint8(i), d.Attribute[0].Name
+		}
+	}
+	if !found {
+		return endpoint{}, fmt.Errorf("device %q not in context", dev)
+	}
+	return ep, nil
+}
+
+// latency times t.Pings attribute reads and stores their median and 99th
+// percentile in r. A device error is counted and the reads go on; a broken
+// connection ends the case.
+func (t target) latency(m *connectionmgr.Manager, c benchCase, ep endpoint, r *result) {
+	if ep.attrName == "" {
+		return
+	}
+	samples := make([]time.Duration, 0, t.Pings)
+	for i := 0; i < t.Pings; i++ {
+		start := time.Now()
+		var err error
+		if c.Mode == "ascii" {
+			_, err = m.ReadDeviceAttrASCII(ep.attrDev, ep.attrName)
+		} else {
+			_, err = m.Call(iiodwire.OpReadAttr, ep.attrIdx, 0, iiodwire.LPString(ep.attrName))
+		}
+		r.Ops++
+		if err != nil {
+			r.Errors++
+			if transportError(err) {
+				r.Err = fmt.Errorf("read %s: %w", ep.attrName, err)
+				break
+			}
+			continue
+		}
+		samples = append(samples, time.Since(start))
+	}
+	r.P50, r.P99 = percentile(samples, 50), percentile(samples, 99)
+}
+
+// buffered moves one buffer at a time through Buffer.ReadSamples or
+// WriteSamples for t.Duration, each transfer waiting for its reply.
+func (t target) buffered(m *connectionmgr.Manager, c benchCase, cfg connectionmgr.BufferConfig, r *result) {
+	buf, err := m.OpenBuffer(cfg)
+	if err != nil {
+		r.Err = err
+		return
+	}
+	payload := make([]byte, buf.Size())
+	start := time.Now()
+	for time.Since(start) < t.Duration {
+		if c.Dir == "rx" {
+			_, err = buf.ReadSamples()
+		} else {
+			err = buf.WriteSamples(payload)
+		}
+		r.Ops++
+		if err != nil {
+			r.Errors++
+			if transportError(err) {
+				r.Err = err
+				break
+			}
+			continue
+		}
+		r.Bytes += uint64(len(payload))
+	}
+	r.Elapsed = time.Since(start)
+	if err := buf.Close(); err != nil && r.Err == nil {
+		r.Err = fmt.Errorf("close buffer: %w", err)
+	}
+}
+
+// stream runs a pipelined binary stream of c.Depth blocks for t.Duration.
+func (t target) stream(m *connectionmgr.Manager, c benchCase, cfg connectionmgr.BufferConfig, r *result) {
+	out := make(chan []byte, c.Depth)
+	in := make(chan []byte)
+	scfg := connectionmgr.StreamConfig{Buffer: cfg, Depth: c.Depth, LogPrefix: "iiobench"}
+	var h *connectionmgr.StreamHandle
+	var err error
+	var send chan<- []byte // nil for RX, so only the receive case fires
+	if c.Dir == "rx" {
+		scfg.Out = out
+		h, err = m.StartRXStream(context.Background(), scfg)
+	} else {
+		scfg.In = in
+		send = in
+		h, err = m.StartTXStream(context.Background(), scfg)
+	}
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	block := make([]byte, c.Samples*bytesPerSample(cfg.Mask))
+	deadline := time.After(t.Duration)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+loop:
+	for {
+		select {
+		case <-out:
+		case send <- block:
+		case <-tick.C:
+			if h.Err() != nil {
+				break loop
+			}
+		case <-deadline:
+			break loop
+		}
+	}
+	close(in)
+	h.Stop()
+
+	st := h.Stats()
+	r.Bytes, r.Elapsed, r.Ops = st.Bytes, st.Elapsed, r.Ops+int(st.Blocks)
+	if err := h.Err(); err != nil {
+		r.Ops++
+		r.Errors++
+		r.Err = err
+	}
+}
+
+// transportError reports whether err broke the connection, after which the
+// protocol stream is no longer aligned.
+func transportError(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed)
+}
+
+// percentile returns the p-th percentile (nearest rank) of samples, or 0 if
+// there are none.
+func percentile(samples []time.Duration, p int) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	rank := (len(sorted)*p + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// bytesPerSample returns the bytes of one sample across the channels in mask
+// (int16 per channel).
+func bytesPerSample(mask uint32) int {
+	return bits.OnesCount32(mask) * 2
+}
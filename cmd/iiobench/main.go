@@ -0,0 +1,226 @@
+// Command iiobench measures what an IIOD target sustains over each protocol
+// mode: RX/TX throughput per buffer size, attribute round-trip latency and
+// the error rate, printed as one comparison table. Use it to choose between
+// the text and binary protocols, buffer sizes and kernel buffer counts.
+//
+//	iiobench -uri 192.168.2.1:30431
+//	iiobench -modes ascii,stream -dir rx,tx -sizes 16384,65536 -kbuffers 2,4,8
+//
+// Every case runs on a fresh connection, so a failing case does not affect
+// the next one.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("iiobench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	uri := fs.String("uri", "192.168.2.1:30431", "IIOD address (host:port)")
+	rxDev := fs.String("rx-device", "cf-ad9361-lpc", "RX buffer device (name or id)")
+	txDev := fs.String("tx-device", "cf-ad9361-dds-core-lpc", "TX buffer device (name or id)")
+	mask := fs.Uint("mask", 0x3, "Channel mask of the buffers")
+	modes := fs.String("modes", "ascii,binary,stream", "Protocol modes to compare: ascii, binary, stream")
+	dirs := fs.String("dir", "rx", "Directions to measure: rx, tx")
+	sizes := fs.String("sizes", "4096,16384,65536", "Buffer sizes in samples")
+	kbufs := fs.String("kbuffers", "0", "Kernel buffer counts for the ascii mode (0 keeps the device setting)")
+	depth := fs.Int("depth", connectionmgr.DefaultStreamDepth, "Blocks in flight for the stream mode")
+	duration := fs.Duration("duration", 3*time.Second, "Throughput measurement time per case")
+	pings := fs.Int("latency-n", 50, "Attribute reads per case for the latency figures")
+	timeout := fs.Duration("timeout", 5*time.Second, "Connect and control-operation timeout")
+	verbose := fs.Bool("v", false, "Keep the connection manager's protocol log")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(stderr, "usage: iiobench [-uri host:port] [-modes m,...] [-dir rx,tx] [-sizes n,...] [-kbuffers n,...]")
+		return 2
+	}
+
+	cases, err := plan(*modes, *dirs, *sizes, *kbufs, *depth)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if *mask == 0 || *mask > 0xffffffff {
+		fmt.Fprintf(stderr, "error: -mask 0x%x out of range\n", *mask)
+		return 2
+	}
+	if *duration <= 0 || *pings < 0 {
+		fmt.Fprintln(stderr, "error: -duration must be > 0 and -latency-n >= 0")
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	target := target{
+		URI:      *uri,
+		RXDevice: *rxDev,
+		TXDevice: *txDev,
+		Mask:     uint32(*mask),
+		Duration: *duration,
+		Pings:    *pings,
+		Timeout:  *timeout,
+	}
+	results := make([]result, 0, len(cases))
+	failed := 0
+	for _, c := range cases {
+		r := target.measure(c)
+		if r.Err != nil {
+			failed++
+		}
+		results = append(results, r)
+	}
+	printTable(stdout, results, target.Mask)
+	if failed == len(results) {
+		return 1
+	}
+	return 0
+}
+
+// benchCase is one row of the comparison.
+type benchCase struct {
+	Mode          string // "ascii", "binary" or "stream"
+	Dir           string // "rx" or "tx"
+	Samples       int
+	KernelBuffers int // ascii only; 0 keeps the device setting
+	Depth         int // stream only
+}
+
+// plan expands the flag lists into cases. Kernel buffer counts only vary the
+// ascii mode, which is the only one that can set them, and the depth only
+// applies to the stream mode.
+func plan(modes, dirs, sizes, kbufs string, depth int) ([]benchCase, error) {
+	modeList := splitList(modes)
+	for _, m := range modeList {
+		if m != "ascii" && m != "binary" && m != "stream" {
+			return nil, fmt.Errorf("unknown mode %q", m)
+		}
+	}
+	dirList := splitList(dirs)
+	for _, d := range dirList {
+		if d != "rx" && d != "tx" {
+			return nil, fmt.Errorf("unknown direction %q", d)
+		}
+	}
+	sizeList, err := parseInts(sizes, 1)
+	if err != nil {
+		return nil, fmt.Errorf("-sizes: %w", err)
+	}
+	kbufList, err := parseInts(kbufs, 0)
+	if err != nil {
+		return nil, fmt.Errorf("-kbuffers: %w", err)
+	}
+	if depth <= 0 || depth > 0xff {
+		return nil, fmt.Errorf("-depth %d out of range", depth)
+	}
+	if len(modeList) == 0 || len(dirList) == 0 {
+		return nil, fmt.Errorf("no modes or directions selected")
+	}
+
+	var cases []benchCase
+	for _, m := range modeList {
+		for _, d := range dirList {
+			for _, n := range sizeList {
+				switch m {
+				case "ascii":
+					for _, k := range kbufList {
+						cases = append(cases, benchCase{Mode: m, Dir: d, Samples: n, KernelBuffers: k})
+					}
+				case "stream":
+					cases = append(cases, benchCase{Mode: m, Dir: d, Samples: n, Depth: depth})
+				default:
+					cases = append(cases, benchCase{Mode: m, Dir: d, Samples: n})
+				}
+			}
+		}
+	}
+	return cases, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(strings.ToLower(f)); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// parseInts parses a comma-separated list of integers >= min.
+func parseInts(s string, min int) ([]int, error) {
+	var out []int
+	for _, f := range splitList(s) {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", f)
+		}
+		if v < min {
+			return nil, fmt.Errorf("%d is below %d", v, min)
+		}
+		out = append(out, v)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty list")
+	}
+	return out, nil
+}
+
+// printTable writes the comparison table and, below it, the error of every
+// failed case. mask gives the bytes per sample for the MS/s column.
+func printTable(w io.Writer, results []result, mask uint32) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "mode\tdir\tsamples\tkbufs\tdepth\tMB/s\tMS/s\tp50\tp99\terr%\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%.2f\t%.3f\t%s\t%s\t%.2f\t\n",
+			r.Mode, r.Dir, r.Samples,
+			optional(r.KernelBuffers, r.Mode == "ascii"), optional(r.Depth, r.Mode == "stream"),
+			r.bytesPerSecond()/1e6, r.bytesPerSecond()/float64(bytesPerSample(mask))/1e6,
+			formatLatency(r.P50), formatLatency(r.P99), r.errorRate()*100)
+	}
+	tw.Flush()
+	for i, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "row %d (%s %s %d): %v\n", i+1, r.Mode, r.Dir, r.Samples, r.Err)
+		}
+	}
+}
+
+// optional formats a per-mode setting, or "-" where it does not apply.
+func optional(v int, applies bool) string {
+	switch {
+	case !applies:
+		return "-"
+	case v == 0:
+		return "dev"
+	default:
+		return strconv.Itoa(v)
+	}
+}
+
+// formatLatency prints a latency in milliseconds, or "-" if none was measured.
+func formatLatency(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		modes   string
+		sizes   string
+		kbufs   string
+		want    []benchCase
+		wantErr bool
+	}{
+		{
+			name:  "kernel buffers only vary ascii",
+			modes: "ascii,binary,stream",
+			sizes: "1024",
+			kbufs: "2,8",
+			want: []benchCase{
+				{Mode: "ascii", Dir: "rx", Samples: 1024, KernelBuffers: 2},
+				{Mode: "ascii", Dir: "rx", Samples: 1024, KernelBuffers: 8},
+				{Mode: "binary", Dir: "rx", Samples: 1024},
+				{Mode: "stream", Dir: "rx", Samples: 1024, Depth: 4},
+			},
+		},
+		{
+			name:  "sizes per mode",
+			modes: " Binary ,",
+			sizes: "16,32",
+			kbufs: "0",
+			want: []benchCase{
+				{Mode: "binary", Dir: "rx", Samples: 16},
+				{Mode: "binary", Dir: "rx", Samples: 32},
+			},
+		},
+		{name: "unknown mode", modes: "usb", sizes: "16", kbufs: "0", wantErr: true},
+		{name: "zero size", modes: "ascii", sizes: "0", kbufs: "0", wantErr: true},
+		{name: "negative kernel buffers", modes: "ascii", sizes: "16", kbufs: "-1", wantErr: true},
+		{name: "no modes", modes: ",", sizes: "16", kbufs: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plan(tt.modes, "rx", tt.sizes, tt.kbufs, 4)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("plan = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	if got := percentile(samples, 50); got != 50*time.Millisecond {
+		t.Fatalf("p50 = %v", got)
+	}
+	if got := percentile(samples, 99); got != 99*time.Millisecond {
+		t.Fatalf("p99 = %v", got)
+	}
+	if got := percentile(samples[:1], 99); got != 100*time.Millisecond {
+		t.Fatalf("p99 of one sample = %v", got)
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Fatalf("p50 of nothing = %v", got)
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	results := []result{
+		{benchCase: benchCase{Mode: "ascii", Dir: "rx", Samples: 4096, KernelBuffers: 4}, Bytes: 8e6, Elapsed: 2 * time.Second, Ops: 10, P50: time.Millisecond, P99: 3 * time.Millisecond},
+		{benchCase: benchCase{Mode: "stream", Dir: "tx", Samples: 4096, Depth: 8}, Ops: 4, Errors: 1, Err: errors.New("boom")},
+	}
+	var out bytes.Buffer
+	printTable(&out, results, 0x3)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("output:\n%s", out.String())
+	}
+	// 8 MB in 2 s at 4 bytes per sample.
+	if f := strings.Fields(lines[1]); strings.Join(f, " ") != "ascii rx 4096 4 - 4.00 1.000 1.00ms 3.00ms 0.00" {
+		t.Fatalf("ascii row = %q", f)
+	}
+	if f := strings.Fields(lines[2]); strings.Join(f, " ") != "stream tx 4096 - 8 0.00 0.000 - - 25.00" {
+		t.Fatalf("stream row = %q", f)
+	}
+	if lines[3] != "row 2 (stream tx 4096): boom" {
+		t.Fatalf("error line = %q", lines[3])
+	}
+}
+
+func TestRunRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-modes", "usb"},
+		{"-dir", "up"},
+		{"-mask", "0"},
+		{"-duration", "0s"},
+		{"extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Errorf("run(%q) = %d, want 2", args, code)
+		}
+	}
+}
+
+// benchXML is the context served by serveBench.
+const benchXML = `<context name="network">` +
+	`<device id="iio:device0" name="ad9361-phy"><attribute name="calib_mode"/></device>` +
+	`<device id="iio:device1" name="cf-ad9361-lpc"></device>` +
+	`</context>`
+
+// serveBench answers one connection: the ASCII PRINT, then binary commands,
+// serving zeroed RX blocks.
+func serveBench(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	if line, err := br.ReadString('\n'); err != nil || strings.TrimSpace(line) != "PRINT" {
+		return
+	}
+	fmt.Fprintf(conn, "%d\n%s\n", len(benchXML), benchXML)
+	for {
+		hdr, err := iiodwire.ReadHeader(br)
+		if err != nil {
+			return
+		}
+		reply := iiodwire.Header{ClientID: hdr.ClientID, Opcode: iiodwire.OpResponse, Dev: hdr.Dev}.Marshal()
+		var arg [8]byte
+		switch hdr.Opcode {
+		case iiodwire.OpReadAttr:
+			if _, err := io.ReadFull(br, arg[:4]); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(br, make([]byte, binary.BigEndian.Uint32(arg[:4]))); err != nil {
+				return
+			}
+			reply = append(append(reply, iiodwire.I32(4)...), iiodwire.LPString("auto")...)
+		case iiodwire.OpCreateBuffer, iiodwire.OpCreateBlock:
+			if _, err := io.ReadFull(br, arg[:]); err != nil {
+				return
+			}
+			reply = append(append(reply, iiodwire.I32(0)...), iiodwire.U32(0)...)
+		case iiodwire.OpTransferBlock:
+			if _, err := io.ReadFull(br, arg[:]); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint64(arg[:])
+			reply = append(append(reply, iiodwire.I32(int32(n))...), iiodwire.LPBytes(make([]byte, n))...)
+		default:
+			reply = append(reply, iiodwire.I32(0)...)
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func TestRunBinaryModes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveBench(conn)
+		}
+	}()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-uri", ln.Addr().String(), "-modes", "binary,stream", "-sizes", "1024", "-duration", "50ms", "-latency-n", "5"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run = %d, stderr:\n%s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output:\n%s", stdout.String())
+	}
+	for _, line := range lines[1:] {
+		f := strings.Fields(line)
+		if f[5] == "0.00" || f[7] == "-" || f[9] != "0.00" {
+			t.Fatalf("row without throughput, latency or with errors: %q", line)
+		}
+	}
+}
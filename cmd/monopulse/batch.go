@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// Batch outcomes, reported as "outcome" in the summary.
+const (
+	batchIterations  = "iterations"  // every device reported -iterations samples
+	batchDuration    = "duration"    // -run-for elapsed
+	batchLocked      = "locked"      // every device locked (-until-lock)
+	batchNoLock      = "no-lock"     // a limit was reached before every device locked
+	batchError       = "error"       // a tracker failed
+	batchInterrupted = "interrupted" // the run was cancelled from outside
+)
+
+// Batch exit codes. Configuration and init failures exit 1 as in a normal
+// run.
+const (
+	batchExitOK     = 0
+	batchExitError  = 1
+	batchExitFailed = 2 // no lock, or interrupted
+)
+
+// batchPoll is how often the -run-for limit is checked, in clock time.
+const batchPoll = 50 * time.Millisecond
+
+// batchDevice summarizes one device's run. The track values are those of
+// the primary track in the last sample.
+type batchDevice struct {
+	ID            string              `json:"id,omitempty"`
+	Iterations    int                 `json:"iterations"`
+	Locked        bool                `json:"locked"`
+	TimeToLockSec *float64            `json:"timeToLockSec,omitempty"`
+	LockState     telemetry.LockState `json:"lockState,omitempty"`
+	AngleDeg      float64             `json:"angleDeg"`
+	SNR           float64             `json:"snr"`
+	Confidence    float64             `json:"trackingConfidence"`
+	Tracks        int                 `json:"tracks"`
+}
+
+// batchSummary is printed to stdout as JSON when a batch run ends.
+type batchSummary struct {
+	Outcome    string        `json:"outcome"`
+	Pass       bool          `json:"pass"`
+	ElapsedSec float64       `json:"elapsedSec"`
+	Error      string        `json:"error,omitempty"`
+	Devices    []batchDevice `json:"devices"`
+}
+
+// batchMode reports whether any exit criterion is set.
+func (c cliConfig) batchMode() bool {
+	return c.runFor > 0 || c.iterations > 0 || c.untilLock
+}
+
+// batchMonitor follows the track samples of every device on the bus and
+// decides when a batch run is complete.
+type batchMonitor struct {
+	runFor     time.Duration
+	iterations int
+	untilLock  bool
+	clock      clock.Clock
+	start      time.Time
+
+	mu      sync.Mutex
+	devices []*batchDevice
+	byID    map[string]*batchDevice
+	outcome string
+	done    chan struct{}
+}
+
+// newBatchMonitor starts the clock for a batch run over devices.
+func newBatchMonitor(cfg cliConfig, devices []deviceConfig) *batchMonitor {
+	clk := cfg.clock
+	if clk == nil {
+		clk = clock.Real
+	}
+	m := &batchMonitor{
+		runFor:     cfg.runFor,
+		iterations: cfg.iterations,
+		untilLock:  cfg.untilLock,
+		clock:      clk,
+		start:      clk.Now(),
+		byID:       make(map[string]*batchDevice, len(devices)),
+		done:       make(chan struct{}),
+	}
+	for _, dev := range devices {
+		d := &batchDevice{ID: dev.ID}
+		m.devices = append(m.devices, d)
+		m.byID[dev.ID] = d
+	}
+	return m
+}
+
+// observe is the bus.TopicTrack handler. Every sample counts as one
+// iteration of its device.
+func (m *batchMonitor) observe(msg bus.Message) {
+	sample, ok := msg.Payload.(telemetry.MultiTrackSample)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.byID[msg.Source]
+	if d == nil || m.outcome != "" {
+		return
+	}
+	d.Iterations++
+	d.Tracks = len(sample.Tracks)
+	if len(sample.Tracks) > 0 {
+		primary := sample.Tracks[0]
+		d.LockState, d.AngleDeg, d.SNR, d.Confidence = primary.LockState, primary.AngleDeg, primary.SNR, primary.Confidence
+	}
+	for _, track := range sample.Tracks {
+		if track.LockState == telemetry.LockStateLocked && !d.Locked {
+			d.Locked = true
+			secs := m.clock.Now().Sub(m.start).Seconds()
+			d.TimeToLockSec = &secs
+		}
+	}
+	m.checkDoneLocked()
+}
+
+// checkDoneLocked ends the run when the sample just observed met the exit
+// criteria.
+func (m *batchMonitor) checkDoneLocked() {
+	allLocked, allIterated := true, m.iterations > 0
+	for _, d := range m.devices {
+		allLocked = allLocked && d.Locked
+		allIterated = allIterated && d.Iterations >= m.iterations
+	}
+	switch {
+	case m.untilLock && allLocked:
+		m.finishLocked(batchLocked)
+	case allIterated && m.untilLock:
+		m.finishLocked(batchNoLock)
+	case allIterated:
+		m.finishLocked(batchIterations)
+	}
+}
+
+func (m *batchMonitor) finishLocked(outcome string) {
+	if m.outcome == "" {
+		m.outcome = outcome
+		close(m.done)
+	}
+}
+
+// wait blocks until the run is complete, -run-for elapses or ctx ends, and
+// returns the outcome ("" when ctx ended first).
+func (m *batchMonitor) wait(ctx context.Context) string {
+	ticker := m.clock.NewTicker(batchPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ""
+		case <-m.done:
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return m.outcome
+		case now := <-ticker.C():
+			if m.runFor <= 0 || now.Sub(m.start) < m.runFor {
+				continue
+			}
+			m.mu.Lock()
+			if m.untilLock {
+				m.finishLocked(batchNoLock)
+			} else {
+				m.finishLocked(batchDuration)
+			}
+			outcome := m.outcome
+			m.mu.Unlock()
+			return outcome
+		}
+	}
+}
+
+// summary reports the run and the exit code for outcome.
+func (m *batchMonitor) summary(outcome string, runErr error) (batchSummary, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := batchSummary{
+		Outcome:    outcome,
+		ElapsedSec: m.clock.Now().Sub(m.start).Seconds(),
+		Devices:    make([]batchDevice, len(m.devices)),
+	}
+	for i, d := range m.devices {
+		out.Devices[i] = *d
+	}
+	code := batchExitFailed
+	switch {
+	case runErr != nil:
+		out.Outcome, out.Error = batchError, runErr.Error()
+		code = batchExitError
+	case outcome == "":
+		out.Outcome = batchInterrupted
+	case outcome != batchNoLock:
+		out.Pass, code = true, batchExitOK
+	}
+	return out, code
+}
+
+// runBatch runs the trackers until monitor decides the run is complete,
+// stops them, prints the JSON summary to stdout and returns the exit code.
+func runBatch(ctx context.Context, trackers []*app.Tracker, monitor *batchMonitor, stdout io.Writer) int {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- runTrackers(ctx, trackers)
+		cancel()
+	}()
+	outcome := monitor.wait(ctx)
+	cancel()
+	summary, code := monitor.summary(outcome, <-errCh)
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(summary)
+	return code
+}
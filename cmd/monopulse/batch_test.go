@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestBatchMonitorCriteria(t *testing.T) {
+	locked := telemetry.LockStateLocked
+	tracking := telemetry.LockStateTracking
+	tests := []struct {
+		name      string
+		cfg       cliConfig
+		states    []telemetry.LockState // one sample each for devices "a" and "b"
+		want      string
+		wantCode  int
+		wantIters int
+	}{
+		{name: "iterations", cfg: cliConfig{iterations: 2}, states: []telemetry.LockState{tracking, tracking, tracking}, want: batchIterations, wantIters: 2},
+		{name: "until lock", cfg: cliConfig{untilLock: true}, states: []telemetry.LockState{tracking, locked, locked}, want: batchLocked, wantIters: 2},
+		{name: "lock limit", cfg: cliConfig{untilLock: true, iterations: 2}, states: []telemetry.LockState{tracking, tracking, tracking}, want: batchNoLock, wantCode: batchExitFailed, wantIters: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.clock = clock.NewFake(time.Unix(0, 0))
+			m := newBatchMonitor(tt.cfg, []deviceConfig{{ID: "a"}, {ID: "b"}})
+			for _, state := range tt.states {
+				for _, id := range []string{"a", "b"} {
+					m.observe(bus.Message{Source: id, Payload: telemetry.MultiTrackSample{Tracks: []telemetry.TrackSample{{LockState: state}}}})
+				}
+			}
+			summary, code := m.summary(m.wait(context.Background()), nil)
+			if summary.Outcome != tt.want || code != tt.wantCode || summary.Pass != (tt.wantCode == batchExitOK) {
+				t.Fatalf("outcome %q code %d pass %v, want %q code %d", summary.Outcome, code, summary.Pass, tt.want, tt.wantCode)
+			}
+			for _, d := range summary.Devices {
+				if d.Iterations != tt.wantIters {
+					t.Fatalf("device %s ran %d iterations, want %d", d.ID, d.Iterations, tt.wantIters)
+				}
+				if d.Locked != (d.TimeToLockSec != nil) {
+					t.Fatalf("device %s locked %v with time to lock %v", d.ID, d.Locked, d.TimeToLockSec)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchMonitorRunFor(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	m := newBatchMonitor(cliConfig{runFor: time.Second, untilLock: true, clock: fake}, []deviceConfig{{}})
+	outcome := make(chan string, 1)
+	go func() { outcome <- m.wait(context.Background()) }()
+	for range 25 {
+		fake.Advance(batchPoll)
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case got := <-outcome:
+		if got != batchNoLock {
+			t.Fatalf("outcome %q, want %q", got, batchNoLock)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not end after -run-for")
+	}
+	if _, code := m.summary(batchNoLock, errors.New("rx failed")); code != batchExitError {
+		t.Fatalf("tracker failure exits %d, want %d", code, batchExitError)
+	}
+}
+
+func TestRunBatchMock(t *testing.T) {
+	cfg, err := parseConfig([]string{"-sdr-backend", "mock", "-iterations", "5"}, defaultPersistentConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := bus.New()
+	tracker := newTracker(cfg, sdr.NewMock(), events.Publisher(""), logging.New(logging.Info, logging.Text, io.Discard))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	monitor := newBatchMonitor(cfg, []deviceConfig{{}})
+	events.Subscribe(bus.TopicTrack, monitor.observe)
+
+	var stdout bytes.Buffer
+	code := runBatch(ctx, []*app.Tracker{tracker}, monitor, &stdout)
+	var summary batchSummary
+	if err := json.Unmarshal(stdout.Bytes(), &summary); err != nil {
+		t.Fatalf("summary is not JSON: %v\n%s", err, stdout.String())
+	}
+	if code != batchExitOK || summary.Outcome != batchIterations || summary.Devices[0].Iterations != 5 {
+		t.Fatalf("code %d, summary %s", code, stdout.String())
+	}
+}
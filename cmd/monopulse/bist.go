@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// runBISTCommand implements "monopulse bist": it initializes the configured
+// backend, runs the transceiver's built-in self test and prints the result.
+// The exit code is 1 when the test fails. Tracker flags go after "--".
+func runBISTCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bist", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts sdr.BISTOptions
+	fs.StringVar(&opts.Mode, "mode", sdr.BISTTone, "Test signal (tone|prbs)")
+	fs.Float64Var(&opts.ToneHz, "tone-hz", 0, "Tone frequency, snapped to k/32 of the sample rate (0 selects fs/16)")
+	fs.IntVar(&opts.LevelDB, "level-db", 0, "Tone attenuation (0, 6, 12 or 18 dB)")
+	fs.IntVar(&opts.Settle, "settle", 2, "RX buffers discarded after enabling the generator")
+	timeout := fs.Duration("timeout", 30*time.Second, "Time allowed for init and test")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	configPath := fs.String("config", defaultConfigPath(), "Tracker settings file (read only; defaults when missing)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	defaults := defaultPersistentConfig()
+	if raw, err := os.ReadFile(*configPath); err == nil {
+		if err := json.Unmarshal(raw, &defaults); err != nil {
+			fmt.Fprintf(stderr, "error: decode %s: %v\n", *configPath, err)
+			return 2
+		}
+	}
+	cfg, err := parseConfig(fs.Args(), defaults)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	result, err := bist(ctx, cfg, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(result)
+	} else {
+		printBIST(stdout, result)
+	}
+	if !result.Pass {
+		return 1
+	}
+	return 0
+}
+
+// bist initializes the backend selected by cfg and runs one self test.
+func bist(ctx context.Context, cfg cliConfig, opts sdr.BISTOptions) (sdr.BISTResult, error) {
+	cfg.bist = false // wrapped below
+	backend, err := selectBackend(cfg)
+	if err != nil {
+		return sdr.BISTResult{}, err
+	}
+	runner := sdr.NewBISTRunner(backend)
+	if err := runner.Init(ctx, sdr.Config{
+		URI:         cfg.sdrURI,
+		SampleRate:  cfg.sampleRate,
+		RxLO:        cfg.rxLO,
+		RxGain0:     cfg.rxGain0,
+		RxGain1:     cfg.rxGain1,
+		TxGain:      cfg.txGain,
+		ToneOffset:  cfg.toneOffset,
+		NumSamples:  cfg.numSamples,
+		SSHHost:     cfg.sshHost,
+		SSHUser:     cfg.sshUser,
+		SSHPassword: cfg.sshSecret,
+		SSHKeyPath:  cfg.sshKeyPath,
+		SSHPort:     cfg.sshPort,
+		SysfsRoot:   cfg.sysfsRoot,
+		ClockSource: cfg.clockSource,
+		TimeSource:  cfg.timeSource,
+		RefClockHz:  cfg.refClockHz,
+		DisableTX:   true,
+	}); err != nil {
+		return sdr.BISTResult{}, fmt.Errorf("init SDR: %w", err)
+	}
+	defer runner.Close()
+	return runner.Run(ctx, opts)
+}
+
+// printBIST writes one row per RX channel and the verdict.
+func printBIST(w io.Writer, result sdr.BISTResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "channel\tfreq Hz\tlevel dBFS\tpeak/mean dB\tpass\t")
+	for ch, c := range result.Channels {
+		freq := "-"
+		if result.Mode == sdr.BISTTone {
+			freq = fmt.Sprintf("%.0f", c.FreqHz)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%.1f\t%.1f\t%t\t\n", ch, freq, c.LevelDBFS, c.PeakMeanDB, c.Pass)
+	}
+	_ = tw.Flush()
+	verdict := "PASS"
+	if !result.Pass {
+		verdict = "FAIL"
+	}
+	if result.Mode == sdr.BISTTone {
+		fmt.Fprintf(w, "%s: tone expected at %.0f Hz\n", verdict, result.ExpectedHz)
+		return
+	}
+	fmt.Fprintf(w, "%s: %s\n", verdict, result.Mode)
+}
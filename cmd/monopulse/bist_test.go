@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestRunBISTCommand(t *testing.T) {
+	none := filepath.Join(t.TempDir(), "none.json")
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{name: "tone", args: []string{"-config", none, "--", "-sdr-backend", "mock"}, wantCode: 0, wantOut: "PASS: tone expected at"},
+		{name: "prbs", args: []string{"-config", none, "-mode", "prbs", "--", "-sdr-backend", "mock"}, wantCode: 0, wantOut: "PASS: prbs"},
+		{name: "bad level", args: []string{"-config", none, "-level-db", "5", "--", "-sdr-backend", "mock"}, wantCode: 1},
+		{name: "no bist", args: []string{"-config", none, "--", "-sdr-backend", "soapy"}, wantCode: 1},
+		{name: "bad flag", args: []string{"-mode"}, wantCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runBISTCommand(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("code = %d, want %d (stdout %q, stderr %q)", code, tt.wantCode, stdout.String(), stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Fatalf("stdout = %q, want %q", stdout.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestRunBISTCommandJSON(t *testing.T) {
+	args := []string{"-config", filepath.Join(t.TempDir(), "none.json"), "-json", "-tone-hz", "190e3", "--", "-sdr-backend", "mock", "-sample-rate", "2e6"}
+	var stdout, stderr bytes.Buffer
+	if code := runBISTCommand(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("code = %d (stderr %q)", code, stderr.String())
+	}
+	var result sdr.BISTResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !result.Pass || result.ExpectedHz != 187500 {
+		t.Fatalf("result = %+v", result)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+)
+
+// bundleFormat is the version of the bundle layout, checked on import.
+const bundleFormat = 1
+
+// bundleManifestName is the archive entry describing the bundle.
+const bundleManifestName = "manifest.json"
+
+// Roles of the files in a bundle.
+const (
+	bundleConfig         = "config"
+	bundleCalibration    = "calibration"
+	bundleElementPattern = "element-pattern"
+	bundleJournal        = "journal"
+)
+
+// bundleFile describes one file of a bundle in the manifest. Name is the
+// archive entry; element patterns are restored to it, relative to the
+// working directory, because the bundled config refers to them by that path.
+type bundleFile struct {
+	Role   string `json:"role"`
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// bundleManifest is stored as manifest.json, the first entry of a bundle.
+type bundleManifest struct {
+	Format  int          `json:"format"`
+	Created time.Time    `json:"created"`
+	Build   string       `json:"build"`
+	Files   []bundleFile `json:"files"`
+}
+
+// bundleEntry is a file read for, or from, a bundle.
+type bundleEntry struct {
+	bundleFile
+	data []byte
+}
+
+// runExportBundle implements "monopulse export-bundle": it validates the
+// config and packs it with the calibration store, the element pattern it
+// references and, with -journal, the state journal into a gzipped tar
+// archive that import-bundle restores on another unit. It returns the exit
+// code.
+func runExportBundle(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export-bundle", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", defaultConfigPath(), "Config file to export (default from $"+configEnvVar+")")
+	calibrationPath := fs.String("calibration-file", "calibration.json", "Calibration store to include; skipped when missing")
+	journal := fs.String("journal", "", "State journal (telemetry and track history) to include (empty leaves it out)")
+	out := fs.String("out", "gosdr-bundle.tar.gz", "Output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	entries, err := collectBundle(*configPath, *calibrationPath, *journal)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	var buf bytes.Buffer
+	if err := writeBundle(&buf, entries, time.Now()); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *out == "-" {
+		_, err = stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*out, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *out != "-" {
+		for _, e := range entries {
+			fmt.Fprintf(stdout, "added %s %s (%d bytes)\n", e.Role, e.Name, e.Size)
+		}
+		fmt.Fprintf(stdout, "wrote %s\n", *out)
+	}
+	return 0
+}
+
+// collectBundle reads the files of a bundle. The config must pass the same
+// validation as a run. An element pattern outside the working directory is
+// bundled under its base name and the bundled config rewritten to match.
+func collectBundle(configPath, calibrationPath, journal string) ([]bundleEntry, error) {
+	stored, _, err := loadConfigStrict(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if _, err := parseConfig(nil, stored); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	config, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []bundleEntry
+	if pattern := stored.ElementPattern; pattern != "" {
+		name := path.Clean(filepath.ToSlash(pattern))
+		if !filepath.IsLocal(pattern) {
+			name = filepath.Base(pattern)
+			stored.ElementPattern = name
+			if config, err = json.MarshalIndent(stored, "", "  "); err != nil {
+				return nil, fmt.Errorf("marshal config: %w", err)
+			}
+			config = append(config, '\n')
+		}
+		data, err := os.ReadFile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("element pattern: %w", err)
+		}
+		entries = append(entries, newBundleEntry(bundleElementPattern, name, data))
+	}
+	entries = append([]bundleEntry{newBundleEntry(bundleConfig, "config.json", config)}, entries...)
+
+	data, err := os.ReadFile(calibrationPath)
+	switch {
+	case err == nil:
+		entries = append(entries, newBundleEntry(bundleCalibration, "calibration.json", data))
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("calibration store: %w", err)
+	}
+	if journal != "" {
+		data, err := os.ReadFile(journal)
+		if err != nil {
+			return nil, fmt.Errorf("journal: %w", err)
+		}
+		entries = append(entries, newBundleEntry(bundleJournal, "journal/"+filepath.Base(journal), data))
+	}
+	return entries, nil
+}
+
+func newBundleEntry(role, name string, data []byte) bundleEntry {
+	sum := sha256.Sum256(data)
+	return bundleEntry{bundleFile: bundleFile{Role: role, Name: name, Size: len(data), SHA256: hex.EncodeToString(sum[:])}, data: data}
+}
+
+// writeBundle writes the manifest and entries as a gzipped tar archive.
+func writeBundle(w io.Writer, entries []bundleEntry, now time.Time) error {
+	manifest := bundleManifest{Format: bundleFormat, Created: now.UTC(), Build: buildinfo.Get().String()}
+	for _, e := range entries {
+		manifest.Files = append(manifest.Files, e.bundleFile)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files := append([]bundleEntry{{bundleFile: bundleFile{Name: bundleManifestName}, data: data}}, entries...)
+	for _, e := range files {
+		hdr := &tar.Header{Name: e.Name, Mode: 0o644, Size: int64(len(e.data)), ModTime: manifest.Created, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			return err
+		}
+	}
+	return errors.Join(tw.Close(), gz.Close())
+}
+
+// readBundle reads a bundle and checks it against its manifest: every listed
+// file must be present with the recorded checksum.
+func readBundle(r io.Reader) (bundleManifest, []bundleEntry, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return bundleManifest{}, nil, fmt.Errorf("read bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	contents := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return bundleManifest{}, nil, fmt.Errorf("read bundle: %w", err)
+		}
+		if contents[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return bundleManifest{}, nil, fmt.Errorf("read bundle %s: %w", hdr.Name, err)
+		}
+	}
+
+	var manifest bundleManifest
+	if err := json.Unmarshal(contents[bundleManifestName], &manifest); err != nil {
+		return bundleManifest{}, nil, fmt.Errorf("bundle manifest: %w", err)
+	}
+	if manifest.Format != bundleFormat {
+		return bundleManifest{}, nil, fmt.Errorf("unsupported bundle format %d (want %d)", manifest.Format, bundleFormat)
+	}
+	entries := make([]bundleEntry, 0, len(manifest.Files))
+	for _, f := range manifest.Files {
+		data, ok := contents[f.Name]
+		if !ok {
+			return bundleManifest{}, nil, fmt.Errorf("bundle lacks %s", f.Name)
+		}
+		if newBundleEntry(f.Role, f.Name, data).bundleFile != f {
+			return bundleManifest{}, nil, fmt.Errorf("bundle file %s is corrupt (checksum mismatch)", f.Name)
+		}
+		if !filepath.IsLocal(filepath.FromSlash(f.Name)) || path.Clean(f.Name) != f.Name {
+			return bundleManifest{}, nil, fmt.Errorf("bundle file name %q is not a local path", f.Name)
+		}
+		entries = append(entries, bundleEntry{bundleFile: f, data: data})
+	}
+	return manifest, entries, nil
+}
+
+// runImportBundle implements "monopulse import-bundle": it verifies a bundle
+// written by export-bundle and restores its files. Files it replaces are kept
+// with a .bak suffix, so "config rollback" undoes the config change. It
+// returns the exit code.
+func runImportBundle(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import-bundle", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", defaultConfigPath(), "Where to restore the config (default from $"+configEnvVar+")")
+	calibrationPath := fs.String("calibration-file", "calibration.json", "Where to restore the calibration store")
+	journal := fs.String("journal", "", "Where to restore the state journal (default its bundled name)")
+	skipJournal := fs.Bool("skip-journal", false, "Leave a bundled state journal out")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: monopulse import-bundle [flags] bundle.tar.gz (- for stdin)")
+		return 2
+	}
+
+	in := io.Reader(os.Stdin)
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	manifest, entries, err := readBundle(in)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	targets := map[string]string{bundleConfig: *configPath, bundleCalibration: *calibrationPath, bundleJournal: *journal}
+	for _, e := range entries {
+		if e.Role == bundleConfig {
+			var cfg persistentConfig
+			if err := json.Unmarshal(e.data, &cfg); err != nil {
+				fmt.Fprintf(stderr, "error: bundled config: %v\n", err)
+				return 1
+			}
+		}
+	}
+	for _, e := range entries {
+		if e.Role == bundleJournal && *skipJournal {
+			continue
+		}
+		target := targets[e.Role]
+		if target == "" {
+			target = filepath.FromSlash(e.Name)
+		}
+		if err := restoreFile(target, e.data); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "restored %s to %s\n", e.Role, target)
+	}
+	fmt.Fprintf(stdout, "imported bundle from %s (%s)\n", manifest.Created.Format(time.RFC3339), manifest.Build)
+	return 0
+}
+
+// restoreFile writes data to name, creating its directory. A previous file
+// with different contents is kept as name+configBackupSuffix.
+func restoreFile(name string, data []byte) error {
+	if previous, err := os.ReadFile(name); err == nil && !bytes.Equal(previous, data) {
+		if err := os.WriteFile(name+configBackupSuffix, previous, 0o644); err != nil {
+			return fmt.Errorf("back up %s: %w", name, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
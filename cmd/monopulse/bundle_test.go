@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	pattern := filepath.Join(src, "antenna.csv")
+	if err := os.WriteFile(pattern, []byte("angle_deg,gain_db\n-90,-12\n0,0\n90,-12\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultPersistentConfig()
+	cfg.ElementPattern = pattern
+	if err := saveConfig(filepath.Join(src, "config.json"), cfg); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"calibration.json": `{"noise_figures":[]}`, "state.journal": "journal"}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bundle := filepath.Join(src, "unit.tar.gz")
+	var stdout, stderr bytes.Buffer
+	if code := runExportBundle([]string{"-config", filepath.Join(src, "config.json"), "-calibration-file", filepath.Join(src, "calibration.json"), "-journal", filepath.Join(src, "state.journal"), "-out", bundle}, &stdout, &stderr); code != 0 {
+		t.Fatalf("export exit %d: %s", code, stderr.String())
+	}
+
+	// The target unit runs from its own directory with an older config.
+	t.Chdir(t.TempDir())
+	if err := saveConfig("config.json", defaultPersistentConfig()); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := runImportBundle([]string{"-config", "config.json", bundle}, &stdout, &stderr); code != 0 {
+		t.Fatalf("import exit %d: %s", code, stderr.String())
+	}
+	restored, _, err := loadConfigStrict("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ElementPattern != "antenna.csv" {
+		t.Fatalf("element pattern path %q, want the bundled antenna.csv", restored.ElementPattern)
+	}
+	if _, err := parseConfig(nil, restored); err != nil {
+		t.Fatalf("restored config does not load: %v", err)
+	}
+	for name, want := range map[string]string{"calibration.json": files["calibration.json"], "journal/state.journal": "journal"} {
+		if got, err := os.ReadFile(name); err != nil || string(got) != want {
+			t.Fatalf("%s = %q (%v), want %q", name, got, err, want)
+		}
+	}
+	if _, err := os.Stat("config.json" + configBackupSuffix); err != nil {
+		t.Fatalf("replaced config not backed up: %v", err)
+	}
+}
+
+func TestReadBundleRejectsTampering(t *testing.T) {
+	config := newBundleEntry(bundleConfig, "config.json", []byte("{}"))
+	edited := config
+	edited.data = []byte(`{"rx_lo":1}`)
+	tests := []struct {
+		name    string
+		entries []bundleEntry
+		want    string
+	}{
+		{name: "intact", entries: []bundleEntry{config}},
+		{name: "edited after export", entries: []bundleEntry{edited}, want: "checksum"},
+		{name: "escaping path", entries: []bundleEntry{newBundleEntry(bundleElementPattern, "../pattern.csv", nil)}, want: "not a local path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeBundle(&buf, tt.entries, time.Unix(0, 0)); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := readBundle(&buf)
+			if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// phaseCalTimeout bounds a calibration started through /api/calibrate.
+const phaseCalTimeout = 60 * time.Second
+
+// applyPhaseCalTable replaces the device's phase cal with the value the
+// calibration table holds for its RX LO, if any.
+func applyPhaseCalTable(devCfg cliConfig, device string, store *calibration.Store, logger logging.Logger) cliConfig {
+	if store == nil {
+		return devCfg
+	}
+	if cal, ok := store.PhaseCalAt(device, devCfg.rxLO); ok {
+		logger.Info("phase cal from calibration table", logging.Field{Key: "phase_cal_deg", Value: cal}, logging.Field{Key: "rx_lo_hz", Value: devCfg.rxLO})
+		devCfg.phaseCal = cal
+	}
+	return devCfg
+}
+
+// applyLastLock seeds the device's first coarse scan with the steering it
+// last locked on, if the calibration store has one.
+func applyLastLock(devCfg cliConfig, device string, store *calibration.Store, logger logging.Logger) cliConfig {
+	if store == nil {
+		return devCfg
+	}
+	if last, ok := store.LastLock(device); ok {
+		logger.Info("warm start from last lock", logging.Field{Key: "angle_deg", Value: last.AngleDeg}, logging.Field{Key: "locked_at", Value: last.Timestamp})
+		devCfg.lastLock = &app.WarmStart{AngleDeg: last.AngleDeg, SpanDeg: devCfg.warmStartSpan}
+	}
+	return devCfg
+}
+
+// saveLastLocks stores the steering each tracker last locked on, for the
+// warm start of the next run. Trackers that never locked keep the stored
+// one.
+func saveLastLocks(store *calibration.Store, devices []deviceConfig, trackers []*app.Tracker, logger logging.Logger) {
+	for i, tracker := range trackers {
+		rec, ok := tracker.LastLock()
+		if !ok {
+			continue
+		}
+		rec.Device = devices[i].ID
+		if err := store.SetLastLock(rec); err != nil {
+			logger.Warn("save last lock", logging.Field{Key: "device", Value: rec.Device}, logging.Field{Key: "error", Value: err})
+		}
+	}
+}
+
+// characterizeChains runs a TX characterization on every tracker, prints the
+// matrices and, with -tx-sweep-apply, stores the refined phase cal in the
+// calibration table. -tx-sweep-out also writes the results as JSON, keyed by
+// device.
+func characterizeChains(ctx context.Context, cfg cliConfig, devices []deviceConfig, trackers []*app.Tracker, logger logging.Logger) error {
+	store, err := calibration.Open(cfg.calibration)
+	if err != nil {
+		return err
+	}
+	opts := app.TXSweepOptions{StepDeg: cfg.txSweepStep, Apply: cfg.txSweepApply}
+	results := make(map[string]app.TXCharacterization, len(trackers))
+	for i, tracker := range trackers {
+		logger.Info("characterizing TX/RX chains", logging.Field{Key: "device", Value: devices[i].ID})
+		result, err := tracker.CharacterizeTX(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("device %q: %w", devices[i].ID, err)
+		}
+		results[devices[i].ID] = result
+		printTXCharacterization(os.Stdout, devices[i].ID, result)
+		if !cfg.txSweepApply {
+			continue
+		}
+		rec := result.PhaseCalibration(cfg.forDevice(devices[i]).sdrBackend)
+		rec.Device = devices[i].ID
+		if err := store.SetPhaseCalibration(rec); err != nil {
+			return err
+		}
+	}
+	if cfg.txSweepOut == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cfg.txSweepOut, append(data, '\n'), 0o644)
+}
+
+// printTXCharacterization writes the sweep points and the path matrix of one
+// device.
+func printTXCharacterization(w io.Writer, device string, c app.TXCharacterization) {
+	fmt.Fprintf(w, "device %q at %.0f Hz, probe tone %.0f Hz:\n", device, c.RxLOHz, c.ToneHz)
+	fmt.Fprintf(w, "  %-5s %8s %9s %9s %9s\n", "tx", "offset", "rx0 dBFS", "rx1 dBFS", "rx phase")
+	for _, p := range c.Points {
+		fmt.Fprintf(w, "  %-5s %7.1f° %9.1f %9.1f %8.1f°\n", p.TX, p.OffsetDeg, p.RX0DBFS, p.RX1DBFS, p.RXPhaseDeg)
+	}
+	for tx, row := range c.Matrix {
+		fmt.Fprintf(w, "  tx%d -> rx0 %+6.1f dB %+7.1f°   rx1 %+6.1f dB %+7.1f°\n", tx, row[0].GainDB, row[0].PhaseDeg, row[1].GainDB, row[1].PhaseDeg)
+	}
+	fmt.Fprintf(w, "  rx phase %.2f° (spread %.2f°), tx phase %.2f° (best offset %.0f°), phase cal %.2f°\n",
+		c.RXPhaseDeg, c.SpreadDeg, c.TXPhaseDeg, c.BestOffsetDeg, c.PhaseCalDeg)
+}
+
+// calibratePhases runs a phase calibration on every tracker and stores the
+// results in the calibration table.
+func calibratePhases(ctx context.Context, cfg cliConfig, devices []deviceConfig, trackers []*app.Tracker, logger logging.Logger) error {
+	store, err := calibration.Open(cfg.calibration)
+	if err != nil {
+		return err
+	}
+	opts := app.PhaseCalOptions{SourceDeg: cfg.calibrateSource, Inject: cfg.calibrateInject}
+	for i, tracker := range trackers {
+		logger.Info("calibrating phase", logging.Field{Key: "device", Value: devices[i].ID}, logging.Field{Key: "source_deg", Value: opts.SourceDeg})
+		rec, err := tracker.CalibratePhase(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("device %q: %w", devices[i].ID, err)
+		}
+		rec.Device = devices[i].ID
+		if err := store.SetPhaseCalibration(rec); err != nil {
+			return err
+		}
+		fmt.Printf("device %q at %.0f Hz: phase cal %.2f° (residual %.2f°)\n", rec.Device, rec.RxLOHz, rec.PhaseCalDeg, rec.ResidualDeg)
+	}
+	return nil
+}
+
+// phaseCalAPI serves /api/calibrate: GET lists the calibration table (of one
+// device with ?device=), POST calibrates a running tracker with the
+// app.PhaseCalOptions in the optional body and stores the result.
+type phaseCalAPI struct {
+	store    *calibration.Store
+	trackers map[string]*app.Tracker
+	hub      *telemetry.Hub
+}
+
+func (a *phaseCalAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	switch r.Method {
+	case http.MethodGet:
+		writePhaseCalJSON(w, http.StatusOK, a.store.PhaseCalibrations(device))
+	case http.MethodPost:
+		tracker, ok := a.trackers[device]
+		if !ok {
+			writePhaseCalJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("unknown device %q", device)})
+			return
+		}
+		if !tracker.Running() {
+			writePhaseCalJSON(w, http.StatusConflict, map[string]string{"error": "tracker is not running"})
+			return
+		}
+		var opts app.PhaseCalOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && !errors.Is(err, io.EOF) {
+			writePhaseCalJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid payload: %v", err)})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), phaseCalTimeout)
+		defer cancel()
+		rec, err := tracker.CalibratePhase(ctx, opts)
+		if err == nil {
+			rec.Device = device
+			err = a.store.SetPhaseCalibration(rec)
+		}
+		if err != nil {
+			writePhaseCalJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		a.hub.LogEvent("info", fmt.Sprintf("phase calibration: device %q phase cal %.2f° at %.0f Hz", device, rec.PhaseCalDeg, rec.RxLOHz))
+		writePhaseCalJSON(w, http.StatusOK, rec)
+	default:
+		writePhaseCalJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func writePhaseCalJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+func TestApplyPhaseCalTable(t *testing.T) {
+	store, err := calibration.Open(filepath.Join(t.TempDir(), "calibration.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPhaseCalibration(calibration.PhaseCalibration{Device: "east", RxLOHz: 2.3e9, PhaseCalDeg: 12}); err != nil {
+		t.Fatal(err)
+	}
+	logger := logging.New(logging.Info, logging.Text, io.Discard)
+	cfg := cliConfig{rxLO: 2.3e9, phaseCal: 3}
+	if got := applyPhaseCalTable(cfg, "east", store, logger); got.phaseCal != 12 {
+		t.Fatalf("calibrated device phase cal %v, want 12", got.phaseCal)
+	}
+	if got := applyPhaseCalTable(cfg, "west", store, logger); got.phaseCal != 3 {
+		t.Fatalf("uncalibrated device phase cal %v, want the configured 3", got.phaseCal)
+	}
+}
+
+func TestApplyLastLock(t *testing.T) {
+	store, err := calibration.Open(filepath.Join(t.TempDir(), "calibration.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLastLock(calibration.LastLock{Device: "east", RxLOHz: 2.3e9, PhaseDelayDeg: -25, AngleDeg: -8}); err != nil {
+		t.Fatal(err)
+	}
+	logger := logging.New(logging.Info, logging.Text, io.Discard)
+	cfg := cliConfig{warmStartSpan: 30}
+	if got := applyLastLock(cfg, "east", store, logger); got.lastLock == nil || *got.lastLock != (app.WarmStart{AngleDeg: -8, SpanDeg: 30}) {
+		t.Fatalf("warm start %+v, want -8° over 30°", got.lastLock)
+	}
+	if got := applyLastLock(cfg, "west", store, logger); got.lastLock != nil {
+		t.Fatalf("device without a last lock has warm start %+v", got.lastLock)
+	}
+}
+
+func TestPhaseCalAPI(t *testing.T) {
+	store, err := calibration.Open(filepath.Join(t.TempDir(), "calibration.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &phaseCalAPI{store: store, trackers: map[string]*app.Tracker{"": app.NewTracker(nil, nil, logging.New(logging.Info, logging.Text, io.Discard), app.Config{NumSamples: 512})}}
+	tests := []struct {
+		method   string
+		target   string
+		body     string
+		wantCode int
+	}{
+		{http.MethodGet, "/api/calibrate", "", http.StatusOK},
+		{http.MethodPost, "/api/calibrate?device=north", "", http.StatusNotFound},
+		{http.MethodPost, "/api/calibrate", `{"sourceDeg":0}`, http.StatusConflict},
+		{http.MethodDelete, "/api/calibrate", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+		if rr.Code != tt.wantCode {
+			t.Fatalf("%s %s: status %d, want %d", tt.method, tt.target, rr.Code, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/sdrxml"
+)
+
+// plutoPHY is the AD9361 PHY device whose attributes Init writes.
+const plutoPHY = "ad9361-phy"
+
+// contextReport is the result of "monopulse context-xml".
+type contextReport struct {
+	Source          string           `json:"source"`
+	Baseline        string           `json:"baseline,omitempty"`
+	BaselineUpdated bool             `json:"baselineUpdated,omitempty"`
+	Schema          []string         `json:"schema,omitempty"`
+	Missing         []sdrxml.AttrRef `json:"missing,omitempty"`
+	Changes         []sdrxml.Change  `json:"changes,omitempty"`
+	Pass            bool             `json:"pass"`
+}
+
+// runContextXMLCommand implements "monopulse context-xml": it fetches the
+// radio's context XML (or reads -file), validates it against the IIO
+// schema, checks that it still offers every attribute Init writes and diffs
+// it against the baseline stored for the device. The first check of a
+// device stores its baseline; -update replaces it after a firmware change
+// was reviewed. The exit code is 1 when the document is invalid, an
+// attribute Init needs is missing or something the baseline had is gone.
+// Tracker flags go after "--".
+func runContextXMLCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("context-xml", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("file", "", "Check a saved context XML instead of fetching it from -sdr-uri")
+	baselineDir := fs.String("baseline-dir", "context-baselines", "Directory of the baselines, one per hw_serial")
+	baselinePath := fs.String("baseline", "", "Baseline file (overrides -baseline-dir)")
+	update := fs.Bool("update", false, "Store the document as the device's new baseline")
+	timeout := fs.Duration("timeout", 30*time.Second, "Time allowed for the fetch")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	configPath := fs.String("config", defaultConfigPath(), "Tracker settings file (read only; defaults when missing)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	defaults := defaultPersistentConfig()
+	if raw, err := os.ReadFile(*configPath); err == nil {
+		if err := json.Unmarshal(raw, &defaults); err != nil {
+			fmt.Fprintf(stderr, "error: decode %s: %v\n", *configPath, err)
+			return 2
+		}
+	}
+	cfg, err := parseConfig(fs.Args(), defaults)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	var raw []byte
+	source := *file
+	if source != "" {
+		raw, err = os.ReadFile(source)
+	} else {
+		source = cfg.sdrURI
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		raw, err = fetchContextXML(ctx, cfg.sdrURI)
+		cancel()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	report, err := checkContextXML(raw, source, *baselineDir, *baselinePath, *update, sdr.Config{
+		SampleRate:  cfg.sampleRate,
+		RxLO:        cfg.rxLO,
+		ClockSource: cfg.clockSource,
+		DisableTX:   !cfg.enabled(subsystemTX),
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		printContextReport(stdout, report)
+	}
+	if !report.Pass {
+		return 1
+	}
+	return 0
+}
+
+// fetchContextXML reads the context XML of the IIOD server at uri.
+func fetchContextXML(ctx context.Context, uri string) ([]byte, error) {
+	addr, err := sdr.PlutoAddress(uri)
+	if err != nil {
+		return nil, err
+	}
+	m, err := connectionmgr.DialContext(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+	return m.FetchContextXML(ctx, connectionmgr.XMLFetchOptions{})
+}
+
+// checkContextXML validates raw, checks it for the attributes Init writes
+// for initCfg and compares it with the baseline, which is stored when it
+// does not exist yet or update is set and the document is usable.
+func checkContextXML(raw []byte, source, baselineDir, baselinePath string, update bool, initCfg sdr.Config) (contextReport, error) {
+	report := contextReport{Source: source}
+	var doc sdrxml.SDRContext
+	if err := doc.Parse(raw); err != nil {
+		return report, err
+	}
+	if err := doc.Validate(); err != nil {
+		var joined interface{ Unwrap() []error }
+		if errors.As(err, &joined) {
+			for _, e := range joined.Unwrap() {
+				report.Schema = append(report.Schema, e.Error())
+			}
+		} else {
+			report.Schema = append(report.Schema, err.Error())
+		}
+	}
+	if _, err := doc.Index.LookupDevice(plutoPHY); err == nil {
+		report.Missing = doc.MissingAttributes(sdr.InitAttributes(initCfg, plutoPHY))
+	}
+
+	if baselinePath == "" {
+		serial := contextAttr(&doc, "hw_serial")
+		if serial == "" {
+			return report, errors.New("the document has no hw_serial; name the baseline with -baseline")
+		}
+		baselinePath = filepath.Join(baselineDir, baselineFileName(serial))
+	}
+	report.Baseline = baselinePath
+
+	stored, err := os.ReadFile(baselinePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		update = true
+	case err != nil:
+		return report, err
+	default:
+		var baseline sdrxml.SDRContext
+		if err := baseline.Parse(stored); err != nil {
+			return report, fmt.Errorf("baseline %s: %w", baselinePath, err)
+		}
+		report.Changes = sdrxml.Diff(&baseline, &doc)
+	}
+
+	usable := len(report.Schema) == 0
+	for _, ref := range report.Missing {
+		usable = usable && ref.Optional
+	}
+	report.Pass = usable
+	if !update {
+		for _, c := range report.Changes {
+			report.Pass = report.Pass && !c.Breaking()
+		}
+		return report, nil
+	}
+	if !usable {
+		return report, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(baselinePath), 0o755); err != nil {
+		return report, err
+	}
+	if err := os.WriteFile(baselinePath, raw, 0o644); err != nil {
+		return report, err
+	}
+	report.BaselineUpdated = true
+	return report, nil
+}
+
+// contextAttr returns the value of a context attribute, or "".
+func contextAttr(doc *sdrxml.SDRContext, name string) string {
+	for _, attr := range doc.ContextAttribute {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// baselineFileName keeps a serial from escaping the baseline directory.
+func baselineFileName(serial string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '.' || r == ':' {
+			return '_'
+		}
+		return r
+	}, serial) + ".xml"
+}
+
+// printContextReport writes one line per finding and the verdict.
+func printContextReport(w io.Writer, report contextReport) {
+	for _, e := range report.Schema {
+		fmt.Fprintf(w, "schema: %s\n", e)
+	}
+	for _, ref := range report.Missing {
+		if ref.Optional {
+			fmt.Fprintf(w, "warning: optional init attribute %s missing\n", ref)
+			continue
+		}
+		fmt.Fprintf(w, "init attribute %s missing\n", ref)
+	}
+	for _, c := range report.Changes {
+		mark := " "
+		if c.Breaking() {
+			mark = "!"
+		}
+		fmt.Fprintf(w, "%s %s\n", mark, c)
+	}
+	if report.BaselineUpdated {
+		fmt.Fprintf(w, "baseline %s stored\n", report.Baseline)
+	}
+	verdict := "PASS"
+	if !report.Pass {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(w, "%s: %s (%d schema errors, %d missing attributes, %d changes)\n", verdict, report.Source, len(report.Schema), len(report.Missing), len(report.Changes))
+}
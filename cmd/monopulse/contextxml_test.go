@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunContextXMLCommand(t *testing.T) {
+	dir := t.TempDir()
+	raw, err := os.ReadFile("../../internal/sdrxml/pluto.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, doc string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pluto := write("pluto.xml", string(raw))
+	// Firmware that renamed the RX LO channel and dropped the RX gain.
+	changed := write("changed.xml", strings.NewReplacer(
+		`name="RX_LO"`, `name="RX_LO1"`,
+		`<attribute name="hardwaregain" filename="in_voltage0_hardwaregain" />`, "",
+	).Replace(string(raw)))
+	invalid := write("invalid.xml", strings.Replace(string(raw), `type="input"`, `type="inbound"`, 1))
+
+	base := []string{"-config", filepath.Join(dir, "none.json"), "-baseline-dir", filepath.Join(dir, "baselines")}
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  []string
+	}{
+		{name: "first check stores the baseline", args: []string{"-file", pluto}, wantOut: []string{"baseline", "stored", "PASS"}},
+		{name: "unchanged", args: []string{"-file", pluto}, wantOut: []string{"PASS", "0 changes"}},
+		{name: "firmware change", args: []string{"-file", changed}, wantCode: 1, wantOut: []string{
+			`! channel-renamed ad9361-phy/altvoltage0(output): "RX_LO" -> "RX_LO1"`,
+			"! attribute-removed ad9361-phy/voltage0(input)/hardwaregain",
+			"FAIL",
+		}},
+		{name: "invalid", args: []string{"-file", invalid}, wantCode: 1, wantOut: []string{"schema: ", "neither input nor output", "FAIL"}},
+		{name: "bad flag", args: []string{"-update=maybe"}, wantCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runContextXMLCommand(append(append([]string{}, base...), tt.args...), &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("code = %d, want %d (stdout %q, stderr %q)", code, tt.wantCode, stdout.String(), stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Fatalf("stdout = %q, want %q", stdout.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+	"github.com/rjboer/GoSDR/internal/control"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// controlSocketEnvVar names the default control socket of the server and
+// the control subcommand.
+const controlSocketEnvVar = "GOSDR_CONTROL_SOCKET"
+
+// codeConfigConflict is the JSON-RPC error code of a config.set naming a
+// stale revision; the error data is the telemetry.ConfigConflict.
+const codeConfigConflict = -32001
+
+// controlAPI implements the methods of the -control-socket server.
+type controlAPI struct {
+	hub      *telemetry.Hub // nil without the web UI; the config methods then fail
+	devices  []deviceConfig
+	backends []string
+	trackers []*app.Tracker
+	started  time.Time
+}
+
+type controlDeviceParams struct {
+	// Device selects one device; empty selects all of them.
+	Device string `json:"device"`
+}
+
+type controlDeviceStatus struct {
+	Device  string `json:"device"`
+	Backend string `json:"backend"`
+	Running bool   `json:"running"`
+	Paused  bool   `json:"paused"`
+}
+
+type controlStatus struct {
+	Version        string                `json:"version"`
+	Started        time.Time             `json:"started"`
+	UptimeSeconds  float64               `json:"uptimeSeconds"`
+	Devices        []controlDeviceStatus `json:"devices"`
+	ConfigRevision *uint64               `json:"configRevision,omitempty"`
+}
+
+// newControlServer builds the -control-socket server for the trackers of
+// devices.
+func newControlServer(cfg cliConfig, devices []deviceConfig, trackers []*app.Tracker, hub *telemetry.Hub, logger logging.Logger) *control.Server {
+	api := &controlAPI{hub: hub, devices: devices, trackers: trackers, started: time.Now()}
+	for _, dev := range devices {
+		api.backends = append(api.backends, cfg.forDevice(dev).sdrBackend)
+	}
+	server := control.NewServer(cfg.controlSocket, logger)
+	api.register(server)
+	return server
+}
+
+// register adds the control methods to s.
+func (c *controlAPI) register(s *control.Server) {
+	s.Handle("status", c.status)
+	s.Handle("start", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return c.setPaused(params, false)
+	})
+	s.Handle("stop", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return c.setPaused(params, true)
+	})
+	s.Handle("config.get", c.configGet)
+	s.Handle("config.set", c.configSet)
+}
+
+func (c *controlAPI) status(context.Context, json.RawMessage) (any, error) {
+	st := controlStatus{
+		Version:       buildinfo.Get().Version,
+		Started:       c.started,
+		UptimeSeconds: time.Since(c.started).Seconds(),
+		Devices:       make([]controlDeviceStatus, 0, len(c.trackers)),
+	}
+	for i, tracker := range c.trackers {
+		st.Devices = append(st.Devices, controlDeviceStatus{
+			Device:  c.devices[i].ID,
+			Backend: c.backends[i],
+			Running: tracker.Running(),
+			Paused:  tracker.Paused(),
+		})
+	}
+	if c.hub != nil {
+		revision := c.hub.ConfigDocument().Revision
+		st.ConfigRevision = &revision
+	}
+	return st, nil
+}
+
+// setPaused stops or resumes the selected trackers and returns the new
+// status. A tracker schedule overrides it at its next window edge.
+func (c *controlAPI) setPaused(params json.RawMessage, paused bool) (any, error) {
+	var p controlDeviceParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, control.InvalidParams("params: %v", err)
+		}
+	}
+	found := false
+	for i, tracker := range c.trackers {
+		if p.Device != "" && c.devices[i].ID != p.Device {
+			continue
+		}
+		found = true
+		tracker.SetPaused(paused)
+	}
+	if !found {
+		return nil, control.InvalidParams("unknown device %q", p.Device)
+	}
+	if c.hub != nil {
+		action := "started"
+		if paused {
+			action = "stopped"
+		}
+		target := "all devices"
+		if p.Device != "" {
+			target = p.Device
+		}
+		c.hub.LogEvent("info", fmt.Sprintf("control: tracker %s (%s)", action, target))
+	}
+	return c.status(context.Background(), nil)
+}
+
+func (c *controlAPI) configGet(context.Context, json.RawMessage) (any, error) {
+	if c.hub == nil {
+		return nil, fmt.Errorf("config is not available without the web UI (-web-addr)")
+	}
+	return c.hub.ConfigDocument(), nil
+}
+
+// configSet applies a partial config with the revision it was edited from,
+// as POST /api/config/update does.
+func (c *controlAPI) configSet(_ context.Context, params json.RawMessage) (any, error) {
+	if c.hub == nil {
+		return nil, fmt.Errorf("config is not available without the web UI (-web-addr)")
+	}
+	if len(params) == 0 || params[0] != '{' {
+		return nil, control.InvalidParams("params must be a config object with its revision")
+	}
+	doc, conflict, err := c.hub.UpdateConfig(params)
+	if conflict != nil {
+		return nil, &control.Error{Code: codeConfigConflict, Message: conflict.Error, Data: conflict}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// runControlCommand implements "monopulse control": it calls one method on
+// the control socket of a running tracker and prints the result.
+func runControlCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("control", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	socket := fs.String("socket", cmp.Or(os.Getenv(controlSocketEnvVar), "monopulse.sock"), "Control socket of the running tracker (default from $"+controlSocketEnvVar+")")
+	timeout := fs.Duration("timeout", 10*time.Second, "Time to wait for the answer")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fmt.Fprintln(stderr, "usage: monopulse control [flags] method [params-json]")
+		fmt.Fprintln(stderr, `  e.g. monopulse control status; monopulse control stop '{"device":"north"}'`)
+		return 2
+	}
+	var params any
+	if fs.NArg() == 2 {
+		raw := json.RawMessage(fs.Arg(1))
+		if !json.Valid(raw) {
+			fmt.Fprintln(stderr, "error: params are not valid JSON")
+			return 2
+		}
+		params = raw
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	var result json.RawMessage
+	if err := control.Call(ctx, *socket, fs.Arg(0), params, &result); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	var pretty any
+	_ = json.Unmarshal(result, &pretty)
+	out, _ := json.MarshalIndent(pretty, "", "  ")
+	fmt.Fprintln(stdout, string(out))
+	return 0
+}
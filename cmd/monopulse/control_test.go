@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/control"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestControlAPI(t *testing.T) {
+	t.Chdir(t.TempDir()) // config.set persists to config.json
+	logger := logging.New(logging.Info, logging.Text, io.Discard)
+	newTracker := func() *app.Tracker { return app.NewTracker(nil, nil, logger, app.Config{NumSamples: 512}) }
+	api := &controlAPI{
+		hub:      telemetry.NewHub(10, logger),
+		devices:  []deviceConfig{{ID: "north"}, {ID: "south"}},
+		backends: []string{"mock", "mock"},
+		trackers: []*app.Tracker{newTracker(), newTracker()},
+	}
+	server := control.NewServer("", logger)
+	api.register(server)
+	call := func(method, params string) (json.RawMessage, *control.Error) {
+		t.Helper()
+		msg := `{"jsonrpc":"2.0","id":1,"method":"` + method + `"`
+		if params != "" {
+			msg += `,"params":` + params
+		}
+		data, err := json.Marshal(server.HandleMessage(context.Background(), []byte(msg+"}")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reply struct {
+			Result json.RawMessage `json:"result"`
+			Error  *control.Error  `json:"error"`
+		}
+		if err := json.Unmarshal(data, &reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply.Result, reply.Error
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		params     string
+		wantCode   int
+		wantPaused []bool
+	}{
+		{name: "stop one", method: "stop", params: `{"device":"south"}`, wantPaused: []bool{false, true}},
+		{name: "stop all", method: "stop", wantPaused: []bool{true, true}},
+		{name: "start all", method: "start", params: `{}`, wantPaused: []bool{false, false}},
+		{name: "unknown device", method: "stop", params: `{"device":"east"}`, wantCode: control.CodeInvalidParams, wantPaused: []bool{false, false}},
+		{name: "config without revision", method: "config.set", params: `{"rxGain0":10}`, wantCode: control.CodeInternalError},
+		{name: "config stale revision", method: "config.set", params: `{"rxGain0":10,"revision":99}`, wantCode: codeConfigConflict},
+		{name: "config not an object", method: "config.set", params: `[1]`, wantCode: control.CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rpcErr := call(tt.method, tt.params)
+			code := 0
+			if rpcErr != nil {
+				code = rpcErr.Code
+			}
+			if code != tt.wantCode {
+				t.Fatalf("error %v, want code %d", rpcErr, tt.wantCode)
+			}
+			for i, want := range tt.wantPaused {
+				if api.trackers[i].Paused() != want {
+					t.Fatalf("%s paused %t, want %t", api.devices[i].ID, !want, want)
+				}
+			}
+		})
+	}
+
+	result, rpcErr := call("config.get", "")
+	if rpcErr != nil {
+		t.Fatal(rpcErr)
+	}
+	var doc telemetry.ConfigDocument
+	if err := json.Unmarshal(result, &doc); err != nil {
+		t.Fatal(err)
+	}
+	update, _ := json.Marshal(map[string]any{"rxGain0": doc.Config.RxGain0 + 1, "revision": doc.Revision})
+	if result, rpcErr = call("config.set", string(update)); rpcErr != nil {
+		t.Fatal(rpcErr)
+	}
+	var updated telemetry.ConfigDocument
+	if err := json.Unmarshal(result, &updated); err != nil || updated.Config.RxGain0 != doc.Config.RxGain0+1 || updated.Revision == doc.Revision {
+		t.Fatalf("config.set = %+v (%v)", updated, err)
+	}
+
+	result, rpcErr = call("status", "")
+	var status controlStatus
+	if rpcErr != nil || json.Unmarshal(result, &status) != nil || len(status.Devices) != 2 || *status.ConfigRevision != updated.Revision {
+		t.Fatalf("status %s (%v)", result, rpcErr)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// writeDerivedDefaults replaces the static sample rate, buffer size and tone
+// offset of the config generated on this start with the values derived from
+// the connected radio's context XML (see sdr.DeriveDefaults), so later starts
+// match the hardware. Backends without a context XML leave the config as it
+// is. It reports whether the config was rewritten.
+func writeDerivedDefaults(ctx context.Context, path string, backend sdr.SDR) (sdr.Defaults, bool, error) {
+	if _, ok := sdr.As[sdr.ContextReader](backend); !ok {
+		return sdr.Defaults{}, false, nil
+	}
+	defaults, _, err := sdr.DeriveDefaults(ctx, backend)
+	if err != nil {
+		return sdr.Defaults{}, false, fmt.Errorf("derive defaults: %w", err)
+	}
+	cfg, err := loadOrCreateConfig(path)
+	if err != nil {
+		return sdr.Defaults{}, false, err
+	}
+	cfg.SampleRate = defaults.SampleRate
+	cfg.NumSamples = defaults.NumSamples
+	cfg.ToneOffset = defaults.ToneOffset
+	if err := saveConfig(path, cfg); err != nil {
+		return sdr.Defaults{}, false, err
+	}
+	return defaults, true, nil
+}
+
+// logDerivedDefaults runs writeDerivedDefaults and logs its outcome. A
+// failure only costs the derived values, so it is not fatal.
+func logDerivedDefaults(ctx context.Context, path string, backend sdr.SDR, logger logging.Logger) {
+	defaults, written, err := writeDerivedDefaults(ctx, path, backend)
+	switch {
+	case err != nil:
+		logger.Warn("hardware defaults not written", logging.Field{Key: "error", Value: err})
+	case written:
+		logger.Info("wrote defaults derived from the hardware; they apply from the next start",
+			logging.Field{Key: "path", Value: path},
+			logging.Field{Key: "sampleRate", Value: defaults.SampleRate},
+			logging.Field{Key: "numSamples", Value: defaults.NumSamples},
+			logging.Field{Key: "toneOffset", Value: defaults.ToneOffset})
+	}
+}
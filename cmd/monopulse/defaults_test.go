@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// xmlBackend is a mock that serves a fixed context XML.
+type xmlBackend struct {
+	*sdr.MockSDR
+	xml string
+}
+
+func (b xmlBackend) ContextXML(context.Context) (string, error) { return b.xml, nil }
+
+func TestWriteDerivedDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if _, err := loadOrCreateConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, written, err := writeDerivedDefaults(context.Background(), path, sdr.NewMock()); err != nil || written {
+		t.Fatalf("mock backend: written = %v, err = %v; want the config untouched", written, err)
+	}
+
+	raw, err := os.ReadFile("../../internal/sdrxml/pluto.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := xmlBackend{MockSDR: sdr.NewMock(), xml: string(raw)}
+	if _, written, err := writeDerivedDefaults(context.Background(), path, backend); err != nil || !written {
+		t.Fatalf("written = %v, err = %v", written, err)
+	}
+	cfg, err := loadOrCreateConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SampleRate != 2e6 || cfg.NumSamples != 16384 || cfg.ToneOffset != 200e3 {
+		t.Fatalf("config = %g S/s, %d samples, %g Hz tone", cfg.SampleRate, cfg.NumSamples, cfg.ToneOffset)
+	}
+	if cfg.RxLO != defaultPersistentConfig().RxLO {
+		t.Fatalf("RxLO changed to %g", cfg.RxLO)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// runExportCommand implements "monopulse export": it converts the telemetry
+// history stored in a state journal (-journal) to a NumPy .npz archive, the
+// same layout served by /api/history/export. It returns the exit code.
+func runExportCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	journal := fs.String("journal", "", "State journal written by a run with -journal")
+	out := fs.String("out", "history.npz", "Output file (- for stdout)")
+	format := fs.String("format", "npz", "Output format (npz)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *journal == "" {
+		fmt.Fprintln(stderr, "usage: monopulse export -journal path [-out file.npz]")
+		return 2
+	}
+	if *format != "npz" {
+		fmt.Fprintf(stderr, "error: unsupported format %q (only npz is available)\n", *format)
+		return 2
+	}
+
+	history, err := telemetry.ReadJournalHistory(*journal)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	var buf bytes.Buffer
+	if err := telemetry.WriteNPZ(&buf, history, telemetry.SpectrumSnapshot{}); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *out == "-" {
+		_, err = stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*out, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *out != "-" {
+		fmt.Fprintf(stdout, "wrote %d samples to %s\n", len(history), *out)
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExportCommand(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal.jsonl")
+	line := `{"kind":"sample","time":"2024-01-01T00:00:00Z","sample":{"seq":1,"timestamp":"2024-01-01T00:00:00Z","tracks":[{"id":"t1","angleDeg":3}]}}` + "\n"
+	if err := os.WriteFile(journal, []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "run.npz")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "missing journal flag", wantCode: 2, wantErr: "usage"},
+		{name: "hdf5", args: []string{"-journal", journal, "-format", "hdf5"}, wantCode: 2, wantErr: "only npz"},
+		{name: "missing file", args: []string{"-journal", filepath.Join(dir, "none")}, wantCode: 1, wantErr: "error:"},
+		{name: "ok", args: []string{"-journal", journal, "-out", out}, wantCode: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runExportCommand(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("code = %d, want %d (stderr %q)", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	if !names["angle_deg.npy"] || !names["track_id.npy"] {
+		t.Fatalf("archive entries = %v", names)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/secrets"
+)
+
+// configEnvVar names the environment variable that selects the config file
+// checked by "config lint" when -config is not given.
+const configEnvVar = "GOSDR_CONFIG"
+
+// runConfigCommand implements "monopulse config <subcommand>" and returns the
+// process exit code.
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	switch {
+	case len(args) > 0 && args[0] == "lint":
+		return runConfigLint(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "rollback":
+		return runConfigRollback(args[1:], stdout, stderr)
+	}
+	fmt.Fprintln(stderr, "usage: monopulse config lint [-config path] [-- flags...]")
+	fmt.Fprintln(stderr, "       monopulse config rollback [-config path]")
+	return 2
+}
+
+// defaultConfigPath returns the config file used by the config subcommands
+// when -config is not given.
+func defaultConfigPath() string {
+	if path := os.Getenv(configEnvVar); path != "" {
+		return path
+	}
+	return "config.json"
+}
+
+// runConfigLint loads a config file without creating or rewriting it, merges
+// the run flags given after "--", validates the result without touching
+// hardware and prints the effective configuration. Problems go to stderr;
+// any error makes the exit code non-zero.
+func runConfigLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", defaultConfigPath(), "Config file to check (default from $"+configEnvVar+")")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	stored, warnings, err := loadConfigStrict(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s: %v\n", *path, err)
+		return 1
+	}
+	cfg, err := parseConfig(fs.Args(), stored)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	errs, more := lintConfig(cfg)
+	warnings = append(warnings, more...)
+
+	effective := persistentFromCLI(cfg)
+	effective.SSHPassword = secrets.Redact(effective.SSHPassword)
+	data, _ := json.MarshalIndent(effective, "", "  ")
+	fmt.Fprintf(stdout, "%s\n", data)
+
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	for _, e := range errs {
+		fmt.Fprintf(stderr, "error: %s\n", e)
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadConfigStrict decodes path, reporting keys the config does not know as
+// warnings (usually typos that would otherwise be silently ignored).
+func loadConfigStrict(path string) (persistentConfig, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return persistentConfig{}, nil, err
+	}
+	var cfg persistentConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return persistentConfig{}, nil, fmt.Errorf("decode config: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(data, &fields)
+	known := jsonKeys(reflect.TypeOf(cfg))
+	var warnings []string
+	for key := range fields {
+		if !known[key] {
+			warnings = append(warnings, fmt.Sprintf("unknown key %q", key))
+		}
+	}
+	var devices struct {
+		Devices []json.RawMessage `json:"devices"`
+	}
+	_ = json.Unmarshal(data, &devices)
+	deviceKeys := jsonKeys(reflect.TypeOf(deviceConfig{}))
+	for i, raw := range devices.Devices {
+		var dev map[string]json.RawMessage
+		if json.Unmarshal(raw, &dev) != nil {
+			continue
+		}
+		for key := range dev {
+			if !deviceKeys[key] {
+				warnings = append(warnings, fmt.Sprintf("devices[%d]: unknown key %q", i, key))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return cfg, warnings, nil
+}
+
+// jsonKeys returns the JSON field names of struct type t.
+func jsonKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// lintConfig runs the device-independent checks on every device's effective
+// config: the backend and gain schedule must build, and the settings must fit
+// the backend's advertised limits.
+func lintConfig(cfg cliConfig) (errs, warnings []string) {
+	devices := cfg.devices
+	if len(devices) == 0 {
+		devices = []deviceConfig{{}}
+	}
+	for _, dev := range devices {
+		prefix := ""
+		if dev.ID != "" {
+			prefix = "devices[" + dev.ID + "]: "
+		}
+		e, w := lintDevice(cfg.forDevice(dev))
+		for _, msg := range e {
+			errs = append(errs, prefix+msg)
+		}
+		for _, msg := range w {
+			warnings = append(warnings, prefix+msg)
+		}
+	}
+	if cfg.sshPassword != "" && !secrets.IsReference(cfg.sshPassword) {
+		warnings = append(warnings, plaintextPasswordWarning)
+	}
+	return errs, warnings
+}
+
+func lintDevice(cfg cliConfig) (errs, warnings []string) {
+	backend, err := selectBackend(cfg)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	caps := backend.Capabilities()
+
+	if cfg.sampleRate <= 0 {
+		errs = append(errs, "sample_rate must be positive")
+	} else if caps.MaxSampleRateHz > 0 && (cfg.sampleRate < caps.MinSampleRateHz || cfg.sampleRate > caps.MaxSampleRateHz) {
+		errs = append(errs, fmt.Sprintf("sample_rate %.0f Hz outside %s range %.0f-%.0f Hz", cfg.sampleRate, caps.Backend, caps.MinSampleRateHz, caps.MaxSampleRateHz))
+	}
+	if caps.MaxFrequencyHz > 0 && (cfg.rxLO < caps.MinFrequencyHz || cfg.rxLO > caps.MaxFrequencyHz) {
+		errs = append(errs, fmt.Sprintf("rx_lo %.0f Hz outside %s range %.0f-%.0f Hz", cfg.rxLO, caps.Backend, caps.MinFrequencyHz, caps.MaxFrequencyHz))
+	}
+	if cfg.numSamples <= 0 || cfg.numSamples&(cfg.numSamples-1) != 0 {
+		errs = append(errs, fmt.Sprintf("num_samples %d must be a positive power of two", cfg.numSamples))
+	}
+	if cfg.sampleRate > 0 && math.Abs(cfg.toneOffset) >= cfg.sampleRate/2 {
+		errs = append(errs, fmt.Sprintf("tone_offset %.0f Hz is outside the ±%.0f Hz Nyquist band", cfg.toneOffset, cfg.sampleRate/2))
+	}
+	if cfg.spacing <= 0 {
+		errs = append(errs, "spacing_wavelength must be positive")
+	}
+	switch cfg.trackingMode {
+	case "single", "multi":
+	default:
+		errs = append(errs, fmt.Sprintf("tracking_mode %q must be single or multi", cfg.trackingMode))
+	}
+	if cfg.maxTracks < 1 {
+		errs = append(errs, "max_tracks must be at least 1")
+	}
+	if cfg.trackTimeout <= 0 {
+		warnings = append(warnings, "track_timeout should be positive")
+	}
+	for _, g := range []struct {
+		name string
+		gain int
+	}{{"rx_gain0", cfg.rxGain0}, {"rx_gain1", cfg.rxGain1}} {
+		if clamped := caps.ClampRxGain(g.gain); clamped != g.gain {
+			warnings = append(warnings, fmt.Sprintf("%s %d dB will be clamped to %d dB", g.name, g.gain, clamped))
+		}
+	}
+	if clamped := caps.ClampTxGain(cfg.txGain); clamped != cfg.txGain {
+		warnings = append(warnings, fmt.Sprintf("tx_gain %d dB will be clamped to %d dB", cfg.txGain, clamped))
+	}
+	if err := caps.ValidateSync(sdr.Config{ClockSource: cfg.clockSource, TimeSource: cfg.timeSource, RefClockHz: cfg.refClockHz}); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs, warnings
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunConfigLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(map[string]any)
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "defaults", wantCode: 0},
+		{name: "unknown key", edit: func(m map[string]any) { m["rx_l0"] = 1 }, wantCode: 0, wantErr: `warning: unknown key "rx_l0"`},
+		{name: "pluto lo out of range", edit: func(m map[string]any) {
+			m["sdr_backend"] = "pluto"
+			m["rx_lo"] = 10e9
+		}, wantCode: 1, wantErr: "rx_lo 10000000000 Hz outside pluto range"},
+		{name: "flag override", args: []string{"--", "-num-samples", "1000"}, wantCode: 1, wantErr: "num_samples 1000 must be a positive power of two"},
+		{name: "device error", edit: func(m map[string]any) {
+			m["devices"] = []map[string]any{{"id": "north"}, {"id": "south", "sdr_backend": "bogus"}}
+		}, wantCode: 1, wantErr: "devices[south]: unknown backend bogus"},
+		{name: "pluto time source", edit: func(m map[string]any) {
+			m["sdr_backend"] = "pluto"
+			m["time_source"] = "external"
+		}, wantCode: 1, wantErr: "pluto backend cannot select a time source"},
+		{name: "duplicate devices", edit: func(m map[string]any) {
+			m["devices"] = []map[string]any{{"id": "a"}, {"id": "a"}}
+		}, wantCode: 1, wantErr: "duplicate id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := map[string]any{}
+			data, _ := json.Marshal(defaultPersistentConfig())
+			_ = json.Unmarshal(data, &fields)
+			if tt.edit != nil {
+				tt.edit(fields)
+			}
+			path := filepath.Join(t.TempDir(), "config.json")
+			data, _ = json.Marshal(fields)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			var stdout, stderr bytes.Buffer
+			code := runConfigLint(append([]string{"-config", path}, tt.args...), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("exit code %d, want %d (stderr %q)", code, tt.wantCode, stderr.String())
+			}
+			if tt.wantErr != "" && !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr %q does not contain %q", stderr.String(), tt.wantErr)
+			}
+			if code == 0 && !json.Valid(stdout.Bytes()) {
+				t.Fatalf("expected effective config JSON, got %q", stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunConfigLintMissingFileFromEnv(t *testing.T) {
+	t.Setenv(configEnvVar, filepath.Join(t.TempDir(), "missing.json"))
+	var stdout, stderr bytes.Buffer
+	if code := runConfigCommand([]string{"lint"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "missing.json") {
+		t.Fatalf("unexpected stderr %q", stderr.String())
+	}
+}
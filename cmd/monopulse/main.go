@@ -1,24 +1,86 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/rjboer/GoSDR/internal/agent"
 	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/capture"
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/dsp"
 	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/otlp"
+	"github.com/rjboer/GoSDR/internal/schedule"
 	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/secrets"
+	"github.com/rjboer/GoSDR/internal/storage"
 	"github.com/rjboer/GoSDR/internal/telemetry"
+	"github.com/rjboer/GoSDR/internal/trackout"
+	"github.com/rjboer/GoSDR/internal/tsdb"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExportCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export-bundle" {
+		os.Exit(runExportBundle(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-bundle" {
+		os.Exit(runImportBundle(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "soak" {
+		os.Exit(runSoakCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bist" {
+		os.Exit(runBISTCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "context-xml" {
+		os.Exit(runContextXMLCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "control" {
+		os.Exit(runControlCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "store" {
+		os.Exit(runStoreCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "secrets" {
+		os.Exit(runSecretsCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "-version" || os.Args[1] == "--version") {
+		fmt.Println("monopulse", buildinfo.Get())
+		return
+	}
 	const configPath = "config.json"
 	logger := logging.New(logging.Warn, logging.Text, os.Stdout).With(logging.Field{Key: "subsystem", Value: "cli"})
 	logging.SetDefault(logger)
 
+	// A config generated now gets defaults derived from the radio once it
+	// is connected.
+	_, statErr := os.Stat(configPath)
+	generatedConfig := os.IsNotExist(statErr)
 	persistentCfg, err := loadOrCreateConfig(configPath)
 	if err != nil {
 		logger.Error("load config", logging.Field{Key: "error", Value: err})
@@ -50,196 +112,1128 @@ func main() {
 		os.Exit(1)
 	}
 
-	logger = logging.New(level, format, os.Stdout).With(logging.Field{Key: "subsystem", Value: "cli"})
+	// In batch mode stdout carries only the JSON summary.
+	logOut := io.Writer(os.Stdout)
+	if cfg.batchMode() {
+		logOut = os.Stderr
+	}
+	logger = logging.New(level, format, logOut).With(logging.Field{Key: "subsystem", Value: "cli"})
 	logging.SetDefault(logger)
 	logStartupBanner(logger, cfg)
+	logSubsystems(logger, cfg)
+	if cfg.sshPassword != "" && !secrets.IsReference(cfg.sshPassword) {
+		logger.Warn(plaintextPasswordWarning)
+	}
 
-	if err := saveConfig(configPath, persistentFromCLI(cfg)); err != nil {
-		logger.Error("save config", logging.Field{Key: "error", Value: err})
-		os.Exit(1)
+	if cfg.save {
+		if err := saveConfig(configPath, persistentFromCLI(cfg)); err != nil {
+			logger.Error("save config", logging.Field{Key: "error", Value: err})
+			os.Exit(1)
+		}
+		logger.Info("saved settings", logging.Field{Key: "path", Value: configPath}, logging.Field{Key: "backup", Value: configPath + configBackupSuffix})
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	// The first interrupt shuts down cleanly, so state such as the last lock
+	// is saved; a second one kills the process.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+	// Everything that runs alongside the trackers is started through sup,
+	// which stops it on every way out of main and reports what failed.
+	sup := newSupervisor(ctx, logger)
+	ctx = sup.Context()
+	defer func() {
+		if err := sup.Stop(); err != nil {
+			logger.Error("shutdown", logging.Field{Key: "error", Value: err})
+		}
+	}()
+	// exit is os.Exit for the rest of main: the deferred Stop would not run.
+	exit := func(code int) {
+		if err := sup.Stop(); err != nil {
+			logger.Error("shutdown", logging.Field{Key: "error", Value: err})
+		}
+		os.Exit(code)
+	}
+	if cfg.tracing != nil {
+		sup.GoLoop("otlp", cfg.tracing.Run)
+		defer flushTracing(cfg.tracing, logger)
+		logger.Info("exporting traces and metrics", logging.Field{Key: "endpoint", Value: cfg.otlpEndpoint})
+	}
+	if cfg.tsdbWriter != nil {
+		sup.GoLoop("tsdb", cfg.tsdbWriter.Run)
+		logger.Info("writing to time-series database", logging.Field{Key: "url", Value: cfg.tsdbConfig.URL})
+	}
 
-	logger.Info("selecting SDR backend", logging.Field{Key: "backend", Value: cfg.sdrBackend})
-	backend, err := selectBackend(cfg)
-	if err != nil {
-		logger.Error("select backend", logging.Field{Key: "error", Value: err})
-		os.Exit(1)
+	// A config without a device list runs a single, un-namespaced tracker.
+	devices := cfg.devices
+	if len(devices) == 0 {
+		devices = []deviceConfig{{}}
+	}
+
+	var hub *telemetry.Hub
+	var ws *telemetry.WebServer
+	var hubLogger logging.Logger
+	// Agent nodes push their telemetry upstream instead of serving it.
+	var upstream *agent.Client
+	if cfg.agentUpstream != "" {
+		node := cfg.agentNode
+		if node == "" {
+			node, _ = os.Hostname()
+		}
+		upstream = agent.NewClient(cfg.agentUpstream, node, cfg.agentBuffer, logger)
+		sup.GoLoop("agent upstream", upstream.Run)
+		logger.Info("agent mode: reporting to aggregator", logging.Field{Key: "upstream", Value: cfg.agentUpstream}, logging.Field{Key: "node", Value: node})
 	}
-	logger.Info("backend selected successfully", logging.Field{Key: "backend", Value: cfg.sdrBackend})
 
-	// Only use web telemetry (no stdout spam)
-	var reporters []telemetry.Reporter
-	if cfg.webAddr != "" {
+	if cfg.webAddr != "" && upstream == nil && cfg.enabled(subsystemWeb) {
 		logger.Info("initializing telemetry hub")
-		hubLogger := logger.With(logging.Field{Key: "subsystem", Value: "telemetry"})
-		hub := telemetry.NewHub(cfg.historyLimit, hubLogger)
-		reporters = append(reporters, hub)
+		hubLogger = logger.With(logging.Field{Key: "subsystem", Value: "telemetry"})
+		hub = telemetry.NewHub(cfg.historyLimit, hubLogger)
+		if err := hub.SetAuditLog(cfg.auditLog); err != nil {
+			logger.Error("open audit log", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		_ = hub.SetEventLevel(cfg.eventLevel)
+		hub.SetClock(cfg.clock)
+		if cfg.journal != "" && cfg.enabled(subsystemRecording) {
+			if err := hub.OpenJournal(cfg.journal, cfg.journalWindow); err != nil {
+				logger.Error("open state journal", logging.Field{Key: "error", Value: err})
+				exit(1)
+			}
+			sup.GoLoop("journal", hub.RunJournal)
+		}
+		if cfg.store != "" && cfg.enabled(subsystemRecording) {
+			store, err := telemetry.OpenStore(cfg.store)
+			if err != nil {
+				logger.Error("open telemetry store", logging.Field{Key: "error", Value: err})
+				exit(1)
+			}
+			hub.SetStore(store)
+			sup.GoLoop("store", hub.RunStore)
+		}
+		_ = hub.SetFrame(cfg.angleFrame)
+		_ = hub.SetWaterfall(cfg.waterfall())
+		_ = hub.SetOutput(cfg.output())
+		if cfg.headingDeg != nil {
+			hub.SetHeading(*cfg.headingDeg, time.Now(), true)
+		}
+		if cfg.position != nil {
+			hub.SetPosition(*cfg.position, time.Now(), true)
+		}
+		hub.SetBearingLineLength(cfg.bearingLineM)
+		sup.GoLoop("config watch", func(ctx context.Context) { hub.WatchConfig(ctx, cfg.configWatch) })
+		sup.GoLoop("leak check", func(ctx context.Context) { hub.WatchLeaks(ctx, cfg.leakCheck) })
+		if cfg.aggregatorListen != "" {
+			aggregator := agent.NewAggregator(hub, logger)
+			sup.Go("aggregator", func(ctx context.Context) error {
+				return aggregator.ListenAndServe(ctx, cfg.aggregatorListen)
+			})
+			logger.Info("accepting agent nodes", logging.Field{Key: "addr", Value: cfg.aggregatorListen})
+		}
+	}
 
-		// Wire up Pluto SDR event logger if using Pluto backend
-		if pluto, ok := backend.(*sdr.PlutoSDR); ok {
-			logger.Info("configuring Pluto SDR event logging")
-			pluto.SetEventLogger(hub)
-			pluto.SetDebugMode(cfg.debugMode)
+	var auditLock sync.Locker
+	if hub != nil {
+		auditLock = hub.AuditLocker()
+	}
+	artifacts, err := storageArtifacts(cfg, devices, auditLock)
+	if err != nil {
+		logger.Error("retention", logging.Field{Key: "error", Value: err})
+		exit(1)
+	}
+	store := storage.New(artifacts, logger)
+	if cfg.enabled(subsystemRecording) {
+		sup.GoLoop("retention", func(ctx context.Context) { store.Run(ctx, cfg.retentionIval) })
+	}
+
+	// Trackers and SDR backends publish on the bus; the hub (or stdout) is
+	// attached as a consumer per device.
+	events := bus.New()
+	events.SetClock(cfg.clock)
+	events.Subscribe(bus.TopicLifecycle, func(msg bus.Message) {
+		ev := msg.Payload.(sdr.LifecycleEvent)
+		logger.Info("sdr lifecycle", logging.Field{Key: "device", Value: msg.Source}, logging.Field{Key: "kind", Value: ev.Kind}, logging.Field{Key: "error", Value: ev.Err})
+	})
+	if cfg.trackUDP != "" {
+		trackOut, err := trackout.Dial(cfg.trackUDP, cfg.output(), logger)
+		if err != nil {
+			logger.Error("track output", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		defer trackOut.Close()
+		events.Subscribe(bus.TopicTrack, func(msg bus.Message) {
+			trackOut.Send(msg.Source, msg.Payload.(telemetry.MultiTrackSample))
+		})
+		logger.Info("sending tracks over UDP", logging.Field{Key: "addr", Value: cfg.trackUDP})
+	}
+	if cfg.tsdbWriter != nil {
+		events.Subscribe(bus.TopicTrack, func(msg bus.Message) {
+			cfg.tsdbWriter.WriteSample(msg.Source, msg.Payload.(telemetry.MultiTrackSample))
+		})
+	}
+
+	calStore, err := calibration.Open(cfg.calibration)
+	if err != nil {
+		logger.Error("calibration store", logging.Field{Key: "error", Value: err})
+		exit(1)
+	}
+	phaseCals := &phaseCalAPI{store: calStore, trackers: make(map[string]*app.Tracker, len(devices)), hub: hub}
+
+	trackers := make([]*app.Tracker, 0, len(devices))
+	backends := make([]sdr.SDR, 0, len(devices))
+	for _, dev := range devices {
+		devCfg := cfg.forDevice(dev)
+		devLogger := logger
+		if dev.ID != "" {
+			devLogger = logger.With(logging.Field{Key: "device", Value: dev.ID})
+		}
+		if cfg.phaseCalTable {
+			devCfg = applyPhaseCalTable(devCfg, dev.ID, calStore, devLogger)
+		}
+		if cfg.warmStart {
+			devCfg = applyLastLock(devCfg, dev.ID, calStore, devLogger)
+		}
+		if dev.ID != "" {
+			devCfg.tracing = cfg.tracing.With(otlp.String("device", dev.ID))
+		}
+
+		devLogger.Info("selecting SDR backend", logging.Field{Key: "backend", Value: devCfg.sdrBackend})
+		backend, err := selectBackend(devCfg)
+		if err != nil {
+			devLogger.Error("select backend", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		devLogger.Info("backend selected successfully", logging.Field{Key: "backend", Value: devCfg.sdrBackend})
+		backends = append(backends, backend)
+
+		publisher := events.Publisher(dev.ID)
+		if pluto, ok := sdr.As[*sdr.PlutoSDR](backend); ok {
+			pluto.SetEventLogger(publisher)
+			if devCfg.tracing != nil {
+				pluto.SetTransportObserver(devCfg.tracing)
+			}
+		}
+		if checker, ok := sdr.As[*sdr.IntegrityChecker](backend); ok {
+			checker.SetEventLogger(publisher)
+		}
+		if recorder, ok := sdr.As[*sdr.RingRecorder](backend); ok {
+			recorder.SetEventLogger(publisher)
+		}
+		if recorder, ok := sdr.As[*sdr.IQRecorder](backend); ok {
+			recorder.SetEventLogger(publisher)
+		}
+		if monitor, ok := sdr.As[*sdr.LifecycleMonitor](backend); ok {
+			monitor.SetLifecycleObserver(publisher)
+		}
+
+		// Only use web telemetry (no stdout spam)
+		var reporter telemetry.Reporter
+		if upstream != nil {
+			reporter = upstream.ForDevice(dev.ID)
+		} else if hub != nil {
+			reporter = hub
+			if dev.ID != "" {
+				reporter = hub.ForDevice(dev.ID, devCfg.sdrBackend)
+				hub.SetSector(dev.ID, dev.BoresightDeg, dev.FOVDeg)
+				hub.SetMount(dev.ID, telemetry.Mount{AzimuthDeg: dev.BoresightDeg, RollDeg: dev.RollDeg, TiltDeg: dev.TiltDeg})
+			} else {
+				hub.SetMount("", cfg.mount)
+			}
+
+			// Pluto debug messages are only published when the hub can show them.
+			if pluto, ok := sdr.As[*sdr.PlutoSDR](backend); ok {
+				devLogger.Info("configuring Pluto SDR event logging")
+				pluto.SetDebugMode(devCfg.debugMode)
+			}
+
+			if ws == nil {
+				adminToken := cfg.adminToken
+				if !cfg.enabled(subsystemAdmin) {
+					adminToken = ""
+				}
+				pairing := telemetry.NewPairing(cfg.pairing, func(code string) {
+					fmt.Fprintf(logOut, "Pair the web UI: open %s (code %s)\n", pairingURL(cfg.webAddr, code), code)
+				})
+				ws = telemetry.NewWebServer(cfg.webAddr, hub, backend, hubLogger,
+					telemetry.WithMacros(cfg.macros), telemetry.WithAdminToken(adminToken), telemetry.WithPairing(pairing),
+					telemetry.WithRoute("/api/storage", store), telemetry.WithAdminRoute("/api/calibrate", phaseCals),
+					telemetry.WithMiddleware(cfg.tracing.Middleware))
+			}
+			if dev.ID != "" {
+				ws.AddDevice(dev.ID, backend)
+			}
+		} else {
+			// Fallback to stdout if no web interface
+			reporter = telemetry.NewStdoutReporter(devLogger.With(logging.Field{Key: "subsystem", Value: "telemetry"}))
 		}
+		events.Forward(dev.ID, reporter)
+
+		devLogger.Info("creating tracker")
+		trackerLogger := devLogger.With(logging.Field{Key: "subsystem", Value: "tracker"})
+		tracker := newTracker(devCfg, backend, publisher, trackerLogger)
+		phaseCals.trackers[dev.ID] = tracker
+		trackers = append(trackers, tracker)
+	}
 
+	if ws != nil {
 		logger.Info("starting web server", logging.Field{Key: "addr", Value: cfg.webAddr})
-		go telemetry.NewWebServer(cfg.webAddr, hub, backend, hubLogger).Start(ctx)
+		sup.Go("web server", ws.Start)
 		hubLogger.Info("web interface available", logging.Field{Key: "addr", Value: cfg.webAddr})
-	} else {
-		// Fallback to stdout if no web interface
-		reporters = append(reporters, telemetry.NewStdoutReporter(logger.With(logging.Field{Key: "subsystem", Value: "telemetry"})))
-	}
-
-	logger.Info("creating tracker")
-	trackerLogger := logger.With(logging.Field{Key: "subsystem", Value: "tracker"})
-	tracker := app.NewTracker(backend, telemetry.MultiReporter(reporters), trackerLogger, app.Config{
-		URI:               cfg.sdrURI,
-		SampleRate:        cfg.sampleRate,
-		RxLO:              cfg.rxLO,
-		RxGain0:           cfg.rxGain0,
-		RxGain1:           cfg.rxGain1,
-		TxGain:            cfg.txGain,
-		ToneOffset:        cfg.toneOffset,
-		NumSamples:        cfg.numSamples,
-		SpacingWavelength: cfg.spacing,
-		TrackingLength:    cfg.trackingLength,
-		PhaseStep:         cfg.phaseStep,
-		PhaseCal:          cfg.phaseCal,
-		ScanStep:          cfg.scanStep,
-		PhaseDelta:        cfg.phaseDelta,
-		WarmupBuffers:     cfg.warmupBuffers,
-		HistoryLimit:      cfg.historyLimit,
-		DebugMode:         cfg.debugMode,
-		TrackingMode:      cfg.trackingMode,
-		MaxTracks:         cfg.maxTracks,
-		TrackTimeout:      cfg.trackTimeout,
-		MinSNRThreshold:   cfg.minSNR,
-		SSHHost:           cfg.sshHost,
-		SSHUser:           cfg.sshUser,
-		SSHPassword:       cfg.sshPassword,
-		SSHKeyPath:        cfg.sshKeyPath,
-		SSHPort:           cfg.sshPort,
-		SysfsRoot:         cfg.sysfsRoot,
-	})
+	}
+	if cfg.controlSocket != "" {
+		server := newControlServer(cfg, devices, trackers, hub, logger)
+		sup.Go("control socket", server.ListenAndServe)
+	}
 
-	logger.Info("initializing tracker (this may take a few seconds)")
-	if err := tracker.Init(ctx); err != nil {
-		trackerLogger.Error("init tracker", logging.Field{Key: "error", Value: err})
-		os.Exit(1)
+	logger.Info("initializing trackers (this may take a few seconds)", logging.Field{Key: "count", Value: len(trackers)})
+	for i, tracker := range trackers {
+		if err := tracker.Init(ctx); err != nil {
+			logger.Error("init tracker", logging.Field{Key: "device", Value: devices[i].ID}, logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+	}
+	logger.Info("trackers initialized successfully")
+	if generatedConfig && !cfg.save && len(cfg.devices) == 0 {
+		logDerivedDefaults(ctx, configPath, backends[0], logger)
+	}
+
+	if recorders := iqRecorders(devices, backends); len(recorders) > 0 {
+		for id, recorder := range recorders {
+			if _, err := recorder.Start(""); err != nil {
+				logger.Error("start IQ recording", logging.Field{Key: "device", Value: id}, logging.Field{Key: "error", Value: err})
+				exit(1)
+			}
+		}
+		defer annotateRecordings(events, recorders)()
+	}
+
+	if cfg.runMacro != "" {
+		if err := runAttributeMacro(ctx, cfg, devices, backends, logger); err != nil {
+			logger.Error("attribute macro", logging.Field{Key: "macro", Value: cfg.runMacro}, logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+	}
+
+	if cfg.noiseFigure {
+		if err := measureNoiseFigures(ctx, cfg, devices, trackers, logger); err != nil {
+			logger.Error("noise figure measurement", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		return
+	}
+	if cfg.txSweep {
+		if err := characterizeChains(ctx, cfg, devices, trackers, logger); err != nil {
+			logger.Error("TX characterization", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		return
+	}
+	if cfg.calibrate {
+		if err := calibratePhases(ctx, cfg, devices, trackers, logger); err != nil {
+			logger.Error("phase calibration", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		return
+	}
+	if cfg.patternCSV != "" {
+		if err := measurePatterns(ctx, cfg, devices, trackers, logger); err != nil {
+			logger.Error("pattern sweep", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		return
+	}
+
+	if cfg.sweepStop > 0 {
+		scanners := newScanners(cfg, devices, backends, events, logger)
+		if !cfg.sweepLock {
+			logger.Info("surveying the spectrum", logging.Field{Key: "start_hz", Value: cfg.sweepStart}, logging.Field{Key: "stop_hz", Value: cfg.sweepStop}, logging.Field{Key: "note", Value: "Ctrl+C to stop"})
+			if err := runScanners(ctx, scanners, cfg.frequencySweep()); err != nil {
+				logger.Error("frequency sweep", logging.Field{Key: "error", Value: err})
+				exit(1)
+			}
+			return
+		}
+		if err := sweepAndLock(ctx, cfg, devices, scanners, trackers, logger); err != nil {
+			logger.Error("frequency sweep", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+	}
+
+	if len(cfg.schedule) > 0 {
+		sup.GoLoop("schedule", func(ctx context.Context) { runSchedule(ctx, cfg, devices, backends, trackers, hub, logger) })
+	}
+	if (hub != nil || cfg.tsdbWriter != nil) && cfg.sensorInterval > 0 {
+		for i, dev := range devices {
+			var hubObserver sdr.SensorObserver
+			switch {
+			case hub != nil && dev.ID != "":
+				hubObserver = hub.ForDevice(dev.ID, cfg.forDevice(dev).sdrBackend).(sdr.SensorObserver)
+			case hub != nil:
+				hubObserver = hub
+			}
+			device := dev.ID
+			observer := sdr.SensorObserverFunc(func(s sdr.Sensors) {
+				if hubObserver != nil {
+					hubObserver.ObserveSensors(s)
+				}
+				cfg.tsdbWriter.WriteSensors(device, s)
+			})
+			backend := backends[i]
+			sup.GoLoop("sensors", func(ctx context.Context) { sdr.SampleSensors(ctx, backend, cfg.sensorInterval, observer) })
+		}
+	}
+	if len(cfg.captures) > 0 && cfg.enabled(subsystemRecording) {
+		rings := make(map[string]string, len(devices))
+		for _, dev := range devices {
+			rings[dev.ID] = cfg.forDevice(dev).ringFile
+		}
+		dir := cfg.captureDir
+		if dir == "" {
+			dir = "captures"
+		}
+		captures, err := capture.New(cfg.captures, dir, rings)
+		if err != nil {
+			logger.Error("captures", logging.Field{Key: "error", Value: err})
+			exit(1)
+		}
+		defer captures.Attach(events)()
+	}
+
+	if cfg.batchMode() {
+		logger.Info("starting trackers in batch mode", logging.Field{Key: "run_for", Value: cfg.runFor}, logging.Field{Key: "iterations", Value: cfg.iterations}, logging.Field{Key: "until_lock", Value: cfg.untilLock})
+		monitor := newBatchMonitor(cfg, devices)
+		events.Subscribe(bus.TopicTrack, monitor.observe)
+		code := runBatch(ctx, trackers, monitor, os.Stdout)
+		if cfg.warmStart {
+			saveLastLocks(calStore, devices, trackers, logger)
+		}
+		// Closing the backends also finalizes running IQ recordings.
+		for _, backend := range backends {
+			_ = backend.Close()
+		}
+		if cfg.tracing != nil {
+			flushTracing(cfg.tracing, logger)
+		}
+		exit(code)
 	}
-	logger.Info("tracker initialized successfully")
 
 	// Run continuously (no timeout)
-	trackerLogger.Info("starting tracker", logging.Field{Key: "note", Value: "Ctrl+C to stop"})
-	if err := tracker.Run(ctx); err != nil && err != context.Canceled {
-		trackerLogger.Error("run tracker", logging.Field{Key: "error", Value: err})
+	logger.Info("starting trackers", logging.Field{Key: "note", Value: "Ctrl+C to stop"})
+	err = runTrackers(ctx, trackers)
+	if cfg.warmStart {
+		saveLastLocks(calStore, devices, trackers, logger)
+	}
+	if err != nil {
+		logger.Error("run tracker", logging.Field{Key: "error", Value: err})
+		exit(1)
+	}
+	// The trackers also stop when a component fails; that is an error too.
+	if err := sup.Stop(); err != nil {
+		logger.Error("shutdown", logging.Field{Key: "error", Value: err})
 		os.Exit(1)
 	}
 }
 
+// newTracker builds a tracker for one device from its effective CLI config.
+func newTracker(cfg cliConfig, backend sdr.SDR, reporter telemetry.Reporter, logger logging.Logger) *app.Tracker {
+	return app.NewTracker(backend, reporter, logger, app.Config{
+		URI:                  cfg.sdrURI,
+		Clock:                cfg.clock,
+		Tracing:              cfg.tracing,
+		SampleRate:           cfg.sampleRate,
+		RxLO:                 cfg.rxLO,
+		RxGain0:              cfg.rxGain0,
+		RxGain1:              cfg.rxGain1,
+		TxGain:               cfg.txGain,
+		ToneOffset:           cfg.toneOffset,
+		NumSamples:           cfg.numSamples,
+		SpacingWavelength:    cfg.spacing,
+		TrackingLength:       cfg.trackingLength,
+		PhaseStep:            cfg.phaseStep,
+		PhaseCal:             cfg.phaseCal,
+		ScanStep:             cfg.scanStep,
+		ScanStepMin:          cfg.scanStepMin,
+		ScanStepMax:          cfg.scanStepMax,
+		PhaseDelta:           cfg.phaseDelta,
+		WarmupBuffers:        cfg.warmupBuffers,
+		HistoryLimit:         cfg.historyLimit,
+		DebugMode:            cfg.debugMode,
+		TrackingMode:         cfg.trackingMode,
+		MaxTracks:            cfg.maxTracks,
+		TrackTimeout:         cfg.trackTimeout,
+		MinSNRThreshold:      cfg.minSNR,
+		SSHHost:              cfg.sshHost,
+		SSHUser:              cfg.sshUser,
+		SSHPassword:          cfg.sshSecret,
+		SSHKeyPath:           cfg.sshKeyPath,
+		SSHPort:              cfg.sshPort,
+		SysfsRoot:            cfg.sysfsRoot,
+		LOSource:             cfg.loSource,
+		LOExport:             cfg.loExport,
+		ClockSource:          cfg.clockSource,
+		TimeSource:           cfg.timeSource,
+		RefClockHz:           cfg.refClockHz,
+		FreqCorrection:       cfg.freqCorrection,
+		CFOTracking:          cfg.cfoTracking,
+		FastTrack:            cfg.fastTrack,
+		FFTWindow:            cfg.window(),
+		AutoGainBackoff:      cfg.autoGain,
+		OccupancyBands:       cfg.occBands,
+		OccupancyThresholdDB: cfg.occThreshold,
+		BurstMode:            cfg.burstMode,
+		BurstThresholdDB:     cfg.burstThreshold,
+		SquelchSNR:           cfg.squelchSNR,
+		MinDwell:             cfg.minDwell,
+		SteeringDeadbandDeg:  cfg.steeringDeadband,
+		SteeringPersist:      cfg.steeringPersist,
+		ConvergenceStdDeg:    cfg.convergeStd,
+		ConvergeIterations:   cfg.convergeIters,
+		AcquireWindow:        cfg.acquireWindow,
+		AcquireMass:          cfg.acquireMass,
+		TrackGateDeg:         cfg.trackGate,
+		ConfirmHits:          cfg.confirmHits,
+		ConfirmWindow:        cfg.confirmWindow,
+		MaxMisses:            cfg.maxMisses,
+		TrackScorer:          cfg.trackScorer,
+		Association:          cfg.association,
+		ScoreWeights:         cfg.scoreWeights,
+		PolarityCheck:        cfg.polarityCheck,
+		PolarityRefDeg:       cfg.polarityRefDeg,
+		ElementPattern:       cfg.elementPattern,
+		Polarization:         cfg.polarization,
+		MockPolarizationDeg:  cfg.mockPolarization,
+		Reconnect:            cfg.reconnect,
+		XMLCacheDir:          cfg.xmlCacheDir,
+		WarmStart:            cfg.lastLock,
+	})
+}
+
+// runAttributeMacro runs cfg.runMacro on every device. A failing macro is
+// rolled back on that device and stops the run.
+func runAttributeMacro(ctx context.Context, cfg cliConfig, devices []deviceConfig, backends []sdr.SDR, logger logging.Logger) error {
+	steps := cfg.macros[cfg.runMacro]
+	for i, backend := range backends {
+		accessor, ok := sdr.As[sdr.RawAttributeAccessor](backend)
+		if !ok {
+			return fmt.Errorf("device %q: backend does not expose raw attributes", devices[i].ID)
+		}
+		if _, err := sdr.RunMacro(ctx, accessor, steps); err != nil {
+			return fmt.Errorf("device %q: %w", devices[i].ID, err)
+		}
+		logger.Info("attribute macro applied", logging.Field{Key: "macro", Value: cfg.runMacro}, logging.Field{Key: "device", Value: devices[i].ID}, logging.Field{Key: "steps", Value: len(steps)})
+	}
+	return nil
+}
+
+// measurePatterns runs a steering-phase sweep on every tracker, writing one
+// CSV per device.
+func measurePatterns(ctx context.Context, cfg cliConfig, devices []deviceConfig, trackers []*app.Tracker, logger logging.Logger) error {
+	sweep := app.PatternSweep{StartDeg: -180, StopDeg: 180, StepDeg: cfg.patternStep, Dwell: cfg.patternDwell}
+	for i, tracker := range trackers {
+		path := patternPath(cfg.patternCSV, devices[i].ID)
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("measuring array pattern", logging.Field{Key: "device", Value: devices[i].ID}, logging.Field{Key: "path", Value: path})
+		_, err = tracker.MeasurePattern(ctx, sweep, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// measureNoiseFigures runs a Y-factor measurement on every tracker and appends
+// the results to the calibration store.
+func measureNoiseFigures(ctx context.Context, cfg cliConfig, devices []deviceConfig, trackers []*app.Tracker, logger logging.Logger) error {
+	store, err := calibration.Open(cfg.calibration)
+	if err != nil {
+		return err
+	}
+	var src app.NoiseSource = app.PromptNoiseSource{In: os.Stdin, Out: os.Stdout}
+	if cfg.noiseGPIO != "" {
+		src = app.GPIONoiseSource{Path: cfg.noiseGPIO}
+	}
+	for i, tracker := range trackers {
+		logger.Info("measuring noise figure", logging.Field{Key: "device", Value: devices[i].ID}, logging.Field{Key: "enr_db", Value: cfg.noiseENR})
+		records, err := tracker.MeasureNoiseFigure(ctx, src, cfg.noiseENR, cfg.noiseBuffers)
+		if err != nil {
+			return err
+		}
+		for j := range records {
+			records[j].Device = devices[i].ID
+			fmt.Printf("device %q channel %d: Y=%.2f dB NF=%.2f dB\n", devices[i].ID, records[j].Channel, records[j].YFactorDB, records[j].NoiseFigureDB)
+		}
+		if err := store.AddNoiseFigures(records...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pairingURL is the address printed with a pairing code. A listen address
+// without a host is shown with this machine's host name.
+func pairingURL(webAddr, code string) string {
+	host, port, err := net.SplitHostPort(webAddr)
+	if err != nil {
+		host, port = webAddr, ""
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host, _ = os.Hostname()
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	return "http://" + host + "/?pair=" + code
+}
+
+// patternPath inserts the device ID before the extension so multi-device
+// sweeps do not overwrite each other.
+func patternPath(path, device string) string {
+	if device == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + device + ext
+}
+
+// runSchedule applies the time-of-day schedule until ctx is done. Disabling
+// TX turns the TX gain of every device down to the backend minimum.
+func runSchedule(ctx context.Context, cfg cliConfig, devices []deviceConfig, backends []sdr.SDR, trackers []*app.Tracker, hub *telemetry.Hub, logger logging.Logger) {
+	sched, err := schedule.New(cfg.schedule, nil)
+	if err != nil {
+		logger.Error("schedule", logging.Field{Key: "error", Value: err})
+		return
+	}
+	sched.Run(ctx, 30*time.Second, func(action string, enabled bool) {
+		logger.Info("schedule", logging.Field{Key: "action", Value: action}, logging.Field{Key: "enabled", Value: enabled})
+		if hub != nil {
+			hub.LogEvent("info", fmt.Sprintf("schedule: %s enabled=%t", action, enabled))
+		}
+		switch action {
+		case schedule.ActionTracker:
+			for _, tracker := range trackers {
+				tracker.SetPaused(!enabled)
+			}
+		case schedule.ActionRecording:
+			if hub != nil {
+				hub.SetRecording(enabled)
+			}
+		case schedule.ActionTX:
+			if !cfg.enabled(subsystemTX) {
+				return
+			}
+			for i, backend := range backends {
+				gain := float64(cfg.forDevice(devices[i]).txGain)
+				if !enabled {
+					gain = backend.Capabilities().MinTxGainDB
+				}
+				accessor, ok := sdr.As[sdr.AttributeAccessor](backend)
+				if !ok {
+					logger.Warn("schedule: backend cannot change TX gain", logging.Field{Key: "device", Value: devices[i].ID})
+					continue
+				}
+				if err := accessor.WriteAttribute(ctx, sdr.AttrTxGain, gain); err != nil {
+					logger.Warn("schedule: set TX gain", logging.Field{Key: "device", Value: devices[i].ID}, logging.Field{Key: "error", Value: err})
+				}
+			}
+		}
+	})
+}
+
+// runTrackers runs all trackers concurrently. The first failure cancels the
+// others and is returned; a plain cancellation is not an error.
+func runTrackers(ctx context.Context, trackers []*app.Tracker) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(trackers))
+	var wg sync.WaitGroup
+	for _, tracker := range trackers {
+		wg.Add(1)
+		go func(t *app.Tracker) {
+			defer wg.Done()
+			if err := t.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				errCh <- err
+				cancel()
+			}
+		}(tracker)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
 type cliConfig struct {
-	sampleRate     float64
-	rxLO           float64
-	rxGain0        int
-	rxGain1        int
-	txGain         int
-	toneOffset     float64
-	numSamples     int
-	trackingLength int
-	phaseStep      float64
-	phaseCal       float64
-	scanStep       float64
-	spacing        float64
-	phaseDelta     float64
-	trackingMode   string
-	maxTracks      int
-	trackTimeout   time.Duration
-	minSNR         float64
-	sdrBackend     string
-	sdrURI         string
-	warmupBuffers  int
-	historyLimit   int
-	webAddr        string
-	logLevel       string
-	logFormat      string
-	debugMode      bool
-	verbose        bool
-	sshHost        string
-	sshUser        string
-	sshPassword    string
-	sshKeyPath     string
-	sshPort        int
-	sysfsRoot      string
+	sampleRate       float64
+	rxLO             float64
+	rxGain0          int
+	rxGain1          int
+	txGain           int
+	toneOffset       float64
+	numSamples       int
+	trackingLength   int
+	phaseStep        float64
+	phaseCal         float64
+	scanStep         float64
+	scanStepMin      float64
+	scanStepMax      float64
+	spacing          float64
+	phaseDelta       float64
+	trackingMode     string
+	maxTracks        int
+	trackTimeout     time.Duration
+	minSNR           float64
+	sdrBackend       string
+	sdrURI           string
+	warmupBuffers    int
+	historyLimit     int
+	webAddr          string
+	logLevel         string
+	logFormat        string
+	debugMode        bool
+	verbose          bool
+	sshHost          string
+	sshUser          string
+	sshPassword      string // as configured, possibly a secrets reference
+	sshSecret        string // sshPassword resolved
+	secretsFile      string
+	secretsKey       string
+	sshKeyPath       string
+	sshPort          int
+	sysfsRoot        string
+	reconnect        sdr.ReconnectPolicy
+	xmlCacheDir      string
+	loSource         string
+	loExport         bool
+	clockSource      string
+	timeSource       string
+	refClockHz       float64
+	freqCorrection   string
+	cfoTracking      bool
+	fastTrack        bool
+	fftWindow        string
+	rxIntegrity      bool
+	loopback         bool
+	bist             bool
+	ringFile         string
+	ringSizeMB       int
+	recordDir        string
+	captures         []capture.Rule
+	captureDir       string
+	retentionAge     time.Duration
+	retentionMB      int
+	retentionIval    time.Duration
+	retention        retentionRules
+	swapChannels     bool
+	invertRX1        bool
+	polarityCheck    bool
+	polarityRefDeg   float64
+	patternFile      string
+	elementPattern   *app.ElementPattern // loaded from patternFile
+	polarization     string
+	mockPolarization float64
+	autoGain         bool
+	occBands         int
+	occThreshold     float64
+	waterfallRate    float64
+	waterfallBins    int
+	burstMode        bool
+	burstThreshold   float64
+	squelchSNR       float64
+	minDwell         time.Duration
+	steeringDeadband float64
+	steeringPersist  int
+	outputRes        float64
+	outputRate       float64
+	convergeStd      float64
+	convergeIters    int
+	acquireWindow    int
+	acquireMass      float64
+	trackGate        float64
+	confirmHits      int
+	confirmWindow    int
+	maxMisses        int
+	trackScorer      string
+	association      string
+	scoreWeights     app.ScoreWeights
+	gainSchedule     []sdr.GainPoint
+	macros           sdr.Macros
+	runMacro         string
+	debugInject      bool
+	patternCSV       string
+	patternStep      float64
+	patternDwell     int
+	noiseFigure      bool
+	noiseENR         float64
+	noiseGPIO        string
+	noiseBuffers     int
+	calibration      string
+	calibrate        bool
+	calibrateSource  float64
+	calibrateInject  bool
+	txSweep          bool
+	txSweepStep      float64
+	txSweepApply     bool
+	txSweepOut       string
+	phaseCalTable    bool
+	warmStart        bool
+	warmStartSpan    float64
+	lastLock         *app.WarmStart // set per device from the calibration store
+	sweepStart       float64
+	sweepStop        float64
+	sweepStep        float64
+	sweepDwell       int
+	sweepThreshold   float64
+	sweepLock        bool
+	auditLog         string
+	auditMaxAge      time.Duration
+	auditMaxMB       int
+	eventLevel       string
+	timeScale        float64
+	clock            clock.Clock // scaled clock shared by the trackers and hub; nil is real time
+	controlSocket    string
+	otlpEndpoint     string
+	otlpHeaders      string
+	otlpService      string
+	otlpInterval     time.Duration
+	tracing          *otlp.Exporter // built from the -otlp flags; nil disables tracing
+	tsdbConfig       *tsdb.Config
+	tsdbWriter       *tsdb.Writer // built from tsdbConfig; nil writes nothing
+	runFor           time.Duration
+	iterations       int
+	untilLock        bool
+	journal          string
+	journalWindow    time.Duration
+	agentUpstream    string
+	agentNode        string
+	agentBuffer      int
+	aggregatorListen string
+	trackUDP         string
+	store            string
+	adminToken       string
+	pairing          string
+	configWatch      time.Duration
+	leakCheck        time.Duration
+	sensorInterval   time.Duration
+	save             bool // write the effective settings back to config.json
+	disabled         []string
+	angleUnit        string
+	powerUnit        string
+	powerOffset      float64
+	angleFrame       string
+	mount            telemetry.Mount
+	headingDeg       *float64
+	position         *telemetry.Position
+	bearingLineM     float64
+	schedule         []schedule.Rule
+	devices          []deviceConfig
+}
+
+// deviceConfig describes one SDR in a multi-device setup. Empty or zero fields
+// inherit the top-level settings; pointer fields distinguish "unset" from 0.
+type deviceConfig struct {
+	ID         string   `json:"id"`
+	SDRBackend string   `json:"sdr_backend,omitempty"`
+	SDRURI     string   `json:"sdr_uri,omitempty"`
+	SampleRate float64  `json:"sample_rate,omitempty"`
+	RxLO       float64  `json:"rx_lo,omitempty"`
+	RxGain0    *int     `json:"rx_gain0,omitempty"`
+	RxGain1    *int     `json:"rx_gain1,omitempty"`
+	TxGain     *int     `json:"tx_gain,omitempty"`
+	PhaseCal   *float64 `json:"phase_cal,omitempty"`
+	PhaseDelta *float64 `json:"phase_delta,omitempty"`
+	SSHHost    string   `json:"ssh_host,omitempty"`
+	// BoresightDeg and FOVDeg place the array in the common 360° bearing frame
+	// used for sector stitching.
+	BoresightDeg float64 `json:"boresight_deg,omitempty"`
+	FOVDeg       float64 `json:"fov_deg,omitempty"`
+	// RollDeg and TiltDeg complete the mount for vehicle and true-north
+	// bearings; BoresightDeg is the mount azimuth.
+	RollDeg float64 `json:"roll_deg,omitempty"`
+	TiltDeg float64 `json:"tilt_deg,omitempty"`
+}
+
+// window returns the FFT window; parseConfig has validated it.
+func (c cliConfig) window() dsp.Window {
+	w, _ := dsp.ParseWindow(c.fftWindow)
+	return w
+}
+
+// output returns the hub's limits for external angle outputs.
+func (c cliConfig) output() telemetry.OutputConfig {
+	return telemetry.OutputConfig{ResolutionDeg: c.outputRes, MaxRateHz: c.outputRate}
+}
+
+// waterfall returns the hub's waterfall settings: -waterfall-rate and
+// -waterfall-bins over the defaults, with a negative rate disabling it.
+func (c cliConfig) waterfall() telemetry.WaterfallConfig {
+	wf := telemetry.DefaultWaterfallConfig()
+	if c.waterfallRate != 0 {
+		wf.RowRateHz = max(c.waterfallRate, 0)
+	}
+	if c.waterfallBins != 0 {
+		wf.Bins = c.waterfallBins
+	}
+	return wf
+}
+
+// forDevice returns the effective configuration for dev.
+func (c cliConfig) forDevice(dev deviceConfig) cliConfig {
+	out := c
+	out.devices = nil
+	if dev.SDRBackend != "" {
+		out.sdrBackend = dev.SDRBackend
+	}
+	if dev.SDRURI != "" {
+		out.sdrURI = dev.SDRURI
+	}
+	if dev.SampleRate != 0 {
+		out.sampleRate = dev.SampleRate
+	}
+	if dev.RxLO != 0 {
+		out.rxLO = dev.RxLO
+	}
+	if dev.SSHHost != "" {
+		out.sshHost = dev.SSHHost
+	}
+	for _, o := range []struct {
+		src *int
+		dst *int
+	}{{dev.RxGain0, &out.rxGain0}, {dev.RxGain1, &out.rxGain1}, {dev.TxGain, &out.txGain}} {
+		if o.src != nil {
+			*o.dst = *o.src
+		}
+	}
+	if dev.PhaseCal != nil {
+		out.phaseCal = *dev.PhaseCal
+	}
+	if dev.PhaseDelta != nil {
+		out.phaseDelta = *dev.PhaseDelta
+	}
+	if dev.ID != "" && out.ringFile != "" {
+		// Each device records into its own ring.
+		ext := filepath.Ext(out.ringFile)
+		out.ringFile = strings.TrimSuffix(out.ringFile, ext) + "-" + dev.ID + ext
+	}
+	if dev.ID != "" && out.recordDir != "" {
+		out.recordDir = filepath.Join(out.recordDir, dev.ID)
+	}
+	return out
 }
 
 type persistentConfig struct {
-	SampleRate     float64 `json:"sample_rate"`
-	RxLO           float64 `json:"rx_lo"`
-	RxGain0        int     `json:"rx_gain0"`
-	RxGain1        int     `json:"rx_gain1"`
-	TxGain         int     `json:"tx_gain"`
-	ToneOffset     float64 `json:"tone_offset"`
-	NumSamples     int     `json:"num_samples"`
-	TrackingLength int     `json:"tracking_length"`
-	PhaseStep      float64 `json:"phase_step"`
-	PhaseCal       float64 `json:"phase_cal"`
-	ScanStep       float64 `json:"scan_step"`
-	Spacing        float64 `json:"spacing_wavelength"`
-	PhaseDelta     float64 `json:"phase_delta"`
-	TrackingMode   string  `json:"tracking_mode"`
-	MaxTracks      int     `json:"max_tracks"`
-	TrackTimeout   string  `json:"track_timeout"`
-	MinSNR         float64 `json:"min_snr_threshold"`
-	SDRBackend     string  `json:"sdr_backend"`
-	SDRURI         string  `json:"sdr_uri"`
-	WarmupBuffers  int     `json:"warmup_buffers"`
-	HistoryLimit   int     `json:"history_limit"`
-	WebAddr        string  `json:"web_addr"`
-	LogLevel       string  `json:"log_level"`
-	LogFormat      string  `json:"log_format"`
-	DebugMode      bool    `json:"debug_mode"`
-	SSHHost        string  `json:"ssh_host"`
-	SSHUser        string  `json:"ssh_user"`
-	SSHPassword    string  `json:"ssh_password"`
-	SSHKeyPath     string  `json:"ssh_key_path"`
-	SSHPort        int     `json:"ssh_port"`
-	SysfsRoot      string  `json:"sysfs_root"`
+	SampleRate       float64         `json:"sample_rate"`
+	RxLO             float64         `json:"rx_lo"`
+	RxGain0          int             `json:"rx_gain0"`
+	RxGain1          int             `json:"rx_gain1"`
+	TxGain           int             `json:"tx_gain"`
+	ToneOffset       float64         `json:"tone_offset"`
+	NumSamples       int             `json:"num_samples"`
+	TrackingLength   int             `json:"tracking_length"`
+	PhaseStep        float64         `json:"phase_step"`
+	PhaseCal         float64         `json:"phase_cal"`
+	ScanStep         float64         `json:"scan_step"`
+	ScanStepMin      float64         `json:"scan_step_min,omitempty"`
+	ScanStepMax      float64         `json:"scan_step_max,omitempty"`
+	Spacing          float64         `json:"spacing_wavelength"`
+	PhaseDelta       float64         `json:"phase_delta"`
+	TrackingMode     string          `json:"tracking_mode"`
+	MaxTracks        int             `json:"max_tracks"`
+	TrackTimeout     string          `json:"track_timeout"`
+	MinSNR           float64         `json:"min_snr_threshold"`
+	SDRBackend       string          `json:"sdr_backend"`
+	SDRURI           string          `json:"sdr_uri"`
+	WarmupBuffers    int             `json:"warmup_buffers"`
+	HistoryLimit     int             `json:"history_limit"`
+	WebAddr          string          `json:"web_addr"`
+	Pairing          string          `json:"pairing,omitempty"`
+	LogLevel         string          `json:"log_level"`
+	LogFormat        string          `json:"log_format"`
+	DebugMode        bool            `json:"debug_mode"`
+	SSHHost          string          `json:"ssh_host"`
+	SSHUser          string          `json:"ssh_user"`
+	SSHPassword      string          `json:"ssh_password"`
+	SecretsFile      string          `json:"secrets_file,omitempty"`
+	SSHKeyPath       string          `json:"ssh_key_path"`
+	SSHPort          int             `json:"ssh_port"`
+	SysfsRoot        string          `json:"sysfs_root"`
+	LOSource         string          `json:"lo_source,omitempty"`
+	ClockSource      string          `json:"clock_source,omitempty"`
+	TimeSource       string          `json:"time_source,omitempty"`
+	RefClockHz       float64         `json:"ref_clock_hz,omitempty"`
+	LOExport         bool            `json:"lo_export,omitempty"`
+	FreqCorrection   string          `json:"freq_correction,omitempty"`
+	CFOTracking      bool            `json:"cfo_tracking,omitempty"`
+	FastTrack        bool            `json:"fast_track,omitempty"`
+	FFTWindow        string          `json:"fft_window,omitempty"`
+	RXIntegrity      bool            `json:"rx_integrity,omitempty"`
+	Loopback         bool            `json:"loopback,omitempty"`
+	BIST             bool            `json:"bist,omitempty"`
+	RingFile         string          `json:"ring_file,omitempty"`
+	RingSizeMB       int             `json:"ring_size_mb,omitempty"`
+	Captures         []capture.Rule  `json:"captures,omitempty"`
+	TSDB             *tsdb.Config    `json:"tsdb,omitempty"`
+	CaptureDir       string          `json:"capture_dir,omitempty"`
+	RetentionMaxAge  string          `json:"retention_max_age,omitempty"`
+	RetentionMaxMB   int             `json:"retention_max_mb,omitempty"`
+	Retention        retentionRules  `json:"retention,omitempty"`
+	AgentUpstream    string          `json:"agent_upstream,omitempty"`
+	AgentNode        string          `json:"agent_node,omitempty"`
+	AgentBuffer      int             `json:"agent_buffer,omitempty"`
+	AggregatorListen string          `json:"aggregator_listen,omitempty"`
+	TrackUDP         string          `json:"track_udp,omitempty"`
+	Store            string          `json:"store,omitempty"`
+	SwapChannels     bool            `json:"swap_channels,omitempty"`
+	InvertRX1        bool            `json:"invert_rx1,omitempty"`
+	PolarityCheck    bool            `json:"polarity_check,omitempty"`
+	PolarityRefDeg   float64         `json:"polarity_ref_deg,omitempty"`
+	ElementPattern   string          `json:"element_pattern,omitempty"`
+	Polarization     string          `json:"polarization,omitempty"`
+	MockPolarization float64         `json:"mock_polarization_deg,omitempty"`
+	AutoGainBackoff  bool            `json:"auto_gain_backoff,omitempty"`
+	OccupancyBands   int             `json:"occupancy_bands,omitempty"`
+	OccupancyThresh  float64         `json:"occupancy_threshold_db,omitempty"`
+	WaterfallRate    float64         `json:"waterfall_rate_hz,omitempty"`
+	WaterfallBins    int             `json:"waterfall_bins,omitempty"`
+	BurstMode        bool            `json:"burst_mode,omitempty"`
+	BurstThreshold   float64         `json:"burst_threshold_db,omitempty"`
+	SquelchSNR       float64         `json:"squelch_snr,omitempty"`
+	MinDwell         string          `json:"min_dwell,omitempty"`
+	SteeringDeadband float64         `json:"steering_deadband_deg,omitempty"`
+	SteeringPersist  int             `json:"steering_persist,omitempty"`
+	OutputRes        float64         `json:"output_resolution_deg,omitempty"`
+	OutputRate       float64         `json:"output_max_rate_hz,omitempty"`
+	ConvergeStd      float64         `json:"converge_std_deg,omitempty"`
+	ConvergeIters    int             `json:"converge_iterations,omitempty"`
+	AcquireWindow    int             `json:"acquire_window,omitempty"`
+	AcquireMass      float64         `json:"acquire_mass,omitempty"`
+	TrackGate        float64         `json:"track_gate_deg,omitempty"`
+	ConfirmHits      int             `json:"confirm_hits,omitempty"`
+	ConfirmWindow    int             `json:"confirm_window,omitempty"`
+	MaxMisses        int             `json:"max_misses,omitempty"`
+	TrackScorer      string          `json:"track_scorer,omitempty"`
+	Association      string          `json:"track_association,omitempty"`
+	ScoreWeights     string          `json:"score_weights,omitempty"`
+	GainSchedule     []sdr.GainPoint `json:"gain_schedule,omitempty"`
+	AttributeMacros  sdr.Macros      `json:"attribute_macros,omitempty"`
+	AngleUnit        string          `json:"angle_unit,omitempty"`
+	PowerUnit        string          `json:"power_unit,omitempty"`
+	PowerOffsetDB    float64         `json:"power_offset_db,omitempty"`
+	AngleFrame       string          `json:"angle_frame,omitempty"`
+	MountAzimuth     float64         `json:"mount_azimuth_deg,omitempty"`
+	MountRoll        float64         `json:"mount_roll_deg,omitempty"`
+	MountTilt        float64         `json:"mount_tilt_deg,omitempty"`
+	HeadingDeg       *float64        `json:"heading_deg,omitempty"`
+	LatitudeDeg      *float64        `json:"latitude_deg,omitempty"`
+	LongitudeDeg     *float64        `json:"longitude_deg,omitempty"`
+	BearingLineM     float64         `json:"bearing_line_m,omitempty"`
+	Schedule         []schedule.Rule `json:"schedule,omitempty"`
+	Disable          []string        `json:"disable,omitempty"`
+	Devices          []deviceConfig  `json:"devices,omitempty"`
 }
 
 func logStartupBanner(logger logging.Logger, cfg cliConfig) {
-	logger.Info("starting monopulse tracker", logging.Field{Key: "config", Value: map[string]any{
-		"sample_rate":      cfg.sampleRate,
-		"rx_lo":            cfg.rxLO,
-		"rx_gain0":         cfg.rxGain0,
-		"rx_gain1":         cfg.rxGain1,
-		"tx_gain":          cfg.txGain,
-		"tone_offset":      cfg.toneOffset,
-		"spacing":          cfg.spacing,
-		"phase_step":       cfg.phaseStep,
-		"phase_cal":        cfg.phaseCal,
-		"scan_step":        cfg.scanStep,
-		"tracking_length":  cfg.trackingLength,
-		"warmup_buffers":   cfg.warmupBuffers,
-		"history_limit":    cfg.historyLimit,
-		"tracking_mode":    cfg.trackingMode,
-		"max_tracks":       cfg.maxTracks,
-		"track_timeout":    cfg.trackTimeout,
-		"min_snr":          cfg.minSNR,
-		"sdr_backend":      cfg.sdrBackend,
-		"sdr_uri":          cfg.sdrURI,
-		"ssh_host":         cfg.sshHost,
-		"ssh_user":         cfg.sshUser,
-		"ssh_password":     cfg.sshPassword,
-		"ssh_port":         cfg.sshPort,
-		"sysfs_root":       cfg.sysfsRoot,
-		"log_level":        cfg.logLevel,
-		"log_format":       cfg.logFormat,
-		"debug_mode":       cfg.debugMode,
-		"verbose":          cfg.verbose,
-		"web_addr":         cfg.webAddr,
-		"mock_phase_delta": cfg.phaseDelta,
+	logger.Info("starting monopulse tracker", logging.Field{Key: "build", Value: buildinfo.Get().String()}, logging.Field{Key: "config", Value: map[string]any{
+		"sample_rate":           cfg.sampleRate,
+		"rx_lo":                 cfg.rxLO,
+		"rx_gain0":              cfg.rxGain0,
+		"rx_gain1":              cfg.rxGain1,
+		"tx_gain":               cfg.txGain,
+		"tone_offset":           cfg.toneOffset,
+		"spacing":               cfg.spacing,
+		"phase_step":            cfg.phaseStep,
+		"phase_cal":             cfg.phaseCal,
+		"scan_step":             cfg.scanStep,
+		"scan_step_min":         cfg.scanStepMin,
+		"scan_step_max":         cfg.scanStepMax,
+		"tracking_length":       cfg.trackingLength,
+		"warmup_buffers":        cfg.warmupBuffers,
+		"history_limit":         cfg.historyLimit,
+		"tracking_mode":         cfg.trackingMode,
+		"max_tracks":            cfg.maxTracks,
+		"track_timeout":         cfg.trackTimeout,
+		"min_snr":               cfg.minSNR,
+		"sdr_backend":           cfg.sdrBackend,
+		"sdr_uri":               cfg.sdrURI,
+		"ssh_host":              cfg.sshHost,
+		"ssh_user":              cfg.sshUser,
+		"ssh_password":          secrets.Redact(cfg.sshPassword),
+		"secrets_file":          cfg.secretsFile,
+		"ssh_port":              cfg.sshPort,
+		"sysfs_root":            cfg.sysfsRoot,
+		"lo_source":             cfg.loSource,
+		"lo_export":             cfg.loExport,
+		"clock_source":          cfg.clockSource,
+		"time_source":           cfg.timeSource,
+		"ref_clock_hz":          cfg.refClockHz,
+		"freq_correction":       cfg.freqCorrection,
+		"cfo_tracking":          cfg.cfoTracking,
+		"fast_track":            cfg.fastTrack,
+		"fft_window":            cfg.fftWindow,
+		"rx_integrity":          cfg.rxIntegrity,
+		"loopback":              cfg.loopback,
+		"bist":                  cfg.bist,
+		"ring_file":             cfg.ringFile,
+		"ring_size_mb":          cfg.ringSizeMB,
+		"captures":              cfg.captures,
+		"capture_dir":           cfg.captureDir,
+		"retention_max_age":     cfg.retentionAge,
+		"retention_max_mb":      cfg.retentionMB,
+		"agent_upstream":        cfg.agentUpstream,
+		"agent_node":            cfg.agentNode,
+		"aggregator_listen":     cfg.aggregatorListen,
+		"track_udp":             cfg.trackUDP,
+		"store":                 cfg.store,
+		"swap_channels":         cfg.swapChannels,
+		"invert_rx1":            cfg.invertRX1,
+		"polarity_check":        cfg.polarityCheck,
+		"polarity_ref_deg":      cfg.polarityRefDeg,
+		"element_pattern":       cfg.patternFile,
+		"polarization":          cfg.polarization,
+		"mock_polarization_deg": cfg.mockPolarization,
+		"auto_gain_backoff":     cfg.autoGain,
+		"occupancy_bands":       cfg.occBands,
+		"waterfall":             cfg.waterfall(),
+		"burst_mode":            cfg.burstMode,
+		"squelch_snr":           cfg.squelchSNR,
+		"min_dwell":             cfg.minDwell,
+		"steering_deadband_deg": cfg.steeringDeadband,
+		"steering_persist":      cfg.steeringPersist,
+		"output":                cfg.output(),
+		"converge_std_deg":      cfg.convergeStd,
+		"converge_iterations":   cfg.convergeIters,
+		"acquire_window":        cfg.acquireWindow,
+		"acquire_mass":          cfg.acquireMass,
+		"track_gate_deg":        cfg.trackGate,
+		"confirm_hits":          cfg.confirmHits,
+		"confirm_window":        cfg.confirmWindow,
+		"max_misses":            cfg.maxMisses,
+		"track_scorer":          cfg.trackScorer,
+		"track_association":     cfg.association,
+		"score_weights":         cfg.scoreWeights.String(),
+		"angle_unit":            cfg.angleUnit,
+		"power_unit":            cfg.powerUnit,
+		"power_offset_db":       cfg.powerOffset,
+		"angle_frame":           cfg.angleFrame,
+		"mount":                 cfg.mount,
+		"position":              cfg.position,
+		"bearing_line_m":        cfg.bearingLineM,
+		"schedule":              cfg.schedule,
+		"disable":               cfg.disabled,
+		"log_level":             cfg.logLevel,
+		"log_format":            cfg.logFormat,
+		"debug_mode":            cfg.debugMode,
+		"verbose":               cfg.verbose,
+		"web_addr":              cfg.webAddr,
+		"pairing":               cfg.pairing,
+		"mock_phase_delta":      cfg.phaseDelta,
 	}})
 }
 
@@ -253,80 +1247,457 @@ func parseConfig(args []string, defaults persistentConfig) (cliConfig, error) {
 	fs.IntVar(&cfg.txGain, "tx-gain", defaults.TxGain, "TX gain (dB)")
 	fs.Float64Var(&cfg.toneOffset, "tone-offset", defaults.ToneOffset, "Tone offset in Hz")
 	fs.IntVar(&cfg.numSamples, "num-samples", defaults.NumSamples, "Number of samples per RX call")
-	fs.IntVar(&cfg.trackingLength, "tracking-length", defaults.TrackingLength, "Number of tracking iterations")
+	fs.IntVar(&cfg.trackingLength, "tracking-length", defaults.TrackingLength, "Tracking iterations a coarse scan gets to converge before the tracker scans again")
 	fs.Float64Var(&cfg.phaseStep, "phase-step", defaults.PhaseStep, "Phase step (degrees) for monopulse updates")
 	fs.Float64Var(&cfg.phaseCal, "phase-cal", defaults.PhaseCal, "Additional calibration phase (degrees)")
 	fs.Float64Var(&cfg.scanStep, "scan-step", defaults.ScanStep, "Scan step in degrees for coarse search")
+	fs.Float64Var(&cfg.scanStepMin, "scan-step-min", defaults.ScanStepMin, "Finest adaptive scan step in degrees, used at high SNR (0 with -scan-step-max 0 keeps -scan-step fixed)")
+	fs.Float64Var(&cfg.scanStepMax, "scan-step-max", defaults.ScanStepMax, "Coarsest adaptive scan step in degrees, used at low SNR")
 	fs.Float64Var(&cfg.spacing, "spacing-wavelength", defaults.Spacing, "Antenna spacing as a fraction of wavelength")
 	fs.Float64Var(&cfg.phaseDelta, "mock-phase-delta", defaults.PhaseDelta, "Mock SDR phase delta in degrees")
 	fs.StringVar(&cfg.trackingMode, "tracking-mode", defaults.TrackingMode, "Tracking mode (single|multi)")
 	fs.IntVar(&cfg.maxTracks, "max-tracks", defaults.MaxTracks, "Maximum number of simultaneous tracks")
 	fs.DurationVar(&cfg.trackTimeout, "track-timeout", durationFromString(defaults.TrackTimeout, 0), "Duration after which inactive tracks are marked lost")
 	fs.Float64Var(&cfg.minSNR, "min-snr-threshold", defaults.MinSNR, "Minimum SNR required to create or update a track")
-	fs.StringVar(&cfg.sdrBackend, "sdr-backend", defaults.SDRBackend, "SDR backend (mock|pluto)")
-	fs.StringVar(&cfg.sdrURI, "sdr-uri", defaults.SDRURI, "SDR URI")
+	fs.StringVar(&cfg.sdrBackend, "sdr-backend", defaults.SDRBackend, "SDR backend (mock|pluto|usrp|soapy|file)")
+	fs.StringVar(&cfg.sdrURI, "sdr-uri", defaults.SDRURI, "SDR URI (SigMF recording path for the file backend)")
 	fs.StringVar(&cfg.sshHost, "sdr-ssh-host", defaults.SSHHost, "SSH hostname/IP for sysfs fallback when IIOD writes are disabled")
 	fs.StringVar(&cfg.sshUser, "sdr-ssh-user", defaults.SSHUser, "SSH username for sysfs fallback (default root)")
-	fs.StringVar(&cfg.sshPassword, "sdr-ssh-password", defaults.SSHPassword, "SSH password for sysfs fallback")
+	fs.StringVar(&cfg.sshPassword, "sdr-ssh-password", defaults.SSHPassword, "SSH password for sysfs fallback, or a reference: env:NAME, file:PATH or secret:NAME")
+	fs.StringVar(&cfg.secretsFile, "secrets-file", defaults.SecretsFile, "Encrypted secrets store for secret: references (default secrets.json)")
+	fs.StringVar(&cfg.secretsKey, "secrets-key", "", "Key file for -secrets-file (default the base64 key in $"+secrets.KeyEnvVar+")")
 	fs.StringVar(&cfg.sshKeyPath, "sdr-ssh-key", defaults.SSHKeyPath, "Path to private key for SSH sysfs fallback")
 	fs.IntVar(&cfg.sshPort, "sdr-ssh-port", defaults.SSHPort, "SSH port for sysfs fallback (default 22)")
 	fs.StringVar(&cfg.sysfsRoot, "sdr-sysfs-root", defaults.SysfsRoot, "Sysfs root on device (default /sys/bus/iio/devices)")
+	fs.BoolVar(&cfg.reconnect.Enabled, "sdr-reconnect", true, "Re-dial a dropped IIOD connection with exponential backoff, restoring the configuration and buffers, instead of stopping the tracker")
+	fs.DurationVar(&cfg.reconnect.MaxDelay, "sdr-reconnect-max-delay", 30*time.Second, "Longest wait between reconnection attempts")
+	fs.IntVar(&cfg.reconnect.MaxAttempts, "sdr-reconnect-attempts", 0, "Give up after this many failed reconnection attempts (0 retries until stopped)")
+	fs.StringVar(&cfg.xmlCacheDir, "sdr-xml-cache", "", "Directory caching the IIOD context XML by device, so reconnects and restarts skip its transfer (empty disables)")
+	fs.StringVar(&cfg.loSource, "sdr-lo-source", defaults.LOSource, "LO source for USRP backends (internal|external|companion)")
+	fs.BoolVar(&cfg.loExport, "sdr-lo-export", defaults.LOExport, "Export the channel 0 LO to the other RX channel (USRP)")
+	fs.StringVar(&cfg.clockSource, "sdr-clock-source", defaults.ClockSource, "Reference clock source (internal|external; USRP also gpsdo|mimo)")
+	fs.StringVar(&cfg.timeSource, "sdr-time-source", defaults.TimeSource, "PPS/time source (USRP: none|internal|external|gpsdo|mimo)")
+	fs.Float64Var(&cfg.refClockHz, "sdr-ref-clock", defaults.RefClockHz, "External reference frequency in Hz (Pluto; 0 = 40 MHz nominal)")
+	fs.StringVar(&cfg.freqCorrection, "freq-correction", defaults.FreqCorrection, "Tone frequency correction (off|report|xo|digital)")
+	fs.BoolVar(&cfg.cfoTracking, "cfo-tracking", defaults.CFOTracking, "Continuously track and remove residual carrier frequency offset")
+	fs.BoolVar(&cfg.fastTrack, "fast-track", defaults.FastTrack, "While locked, compute only the bins around the tone (Goertzel) instead of full FFTs")
+	fs.StringVar(&cfg.fftWindow, "fft-window", defaults.FFTWindow, "Window applied before every FFT (hann|hamming|blackman-harris|kaiser[:beta]|rectangular; default hamming)")
+	fs.BoolVar(&cfg.rxIntegrity, "rx-integrity", defaults.RXIntegrity, "Check RX buffers for short, repeated, all-zero or saturated data")
+	fs.BoolVar(&cfg.loopback, "loopback", defaults.Loopback, "Enable TX/RX loopback delay measurement via /api/sdr/loopback (needs TX cabled to RX through an attenuator)")
+	fs.BoolVar(&cfg.bist, "bist", defaults.BIST, "Enable the AD9361 built-in self test via /api/sdr/bist (admin token required to run)")
+	fs.StringVar(&cfg.ringFile, "ring-file", defaults.RingFile, "Record all RX IQ into this pre-allocated ring file; cut events out with ringcut (empty disables)")
+	fs.IntVar(&cfg.ringSizeMB, "ring-size", defaults.RingSizeMB, "Size of -ring-file in MiB (0 selects 512)")
+	fs.StringVar(&cfg.recordDir, "record", "", "Record both RX channels as SigMF into this directory from start-up; /api/sdr/record stops and restarts recording (empty disables)")
+	fs.StringVar(&cfg.captureDir, "capture-dir", defaults.CaptureDir, "Directory for IQ captures saved by the captures rules (default captures)")
+	fs.DurationVar(&cfg.retentionAge, "retention-max-age", durationFromString(defaults.RetentionMaxAge, 0), "Delete captures and recordings older than this, e.g. 720h (0 keeps them)")
+	fs.IntVar(&cfg.retentionMB, "retention-max-mb", defaults.RetentionMaxMB, "Disk quota in MB for the captures and for the recordings; the oldest are pruned first (0 disables)")
+	fs.DurationVar(&cfg.retentionIval, "retention-interval", 10*time.Minute, "Interval between retention pruning passes")
+	fs.BoolVar(&cfg.swapChannels, "swap-channels", defaults.SwapChannels, "Swap the rx0 and rx1 sample streams (cabling correction)")
+	fs.BoolVar(&cfg.invertRX1, "invert-rx1", defaults.InvertRX1, "Negate rx1 samples, correcting a 180 degree polarity flip")
+	fs.BoolVar(&cfg.polarityCheck, "polarity-check", defaults.PolarityCheck, "At start-up, measure a reference emitter at -polarity-ref and correct swapped or inverted channels")
+	fs.Float64Var(&cfg.polarityRefDeg, "polarity-ref", defaults.PolarityRefDeg, "Angle of the reference emitter used by -polarity-check (degrees; away from 0 to detect swaps)")
+	fs.StringVar(&cfg.polarization, "polarization", defaults.Polarization, "Dual-polarized elements: "+strings.Join(app.PolarizationModes, "|")+" (empty reads one polarization per element)")
+	fs.Float64Var(&cfg.mockPolarization, "mock-polarization", defaults.MockPolarization, "Mock emitter polarization tilt in degrees (0 horizontal, 90 vertical)")
+	fs.StringVar(&cfg.patternFile, "element-pattern", defaults.ElementPattern, "CSV of antenna element gain (dB) versus angle (degrees); reported peak and SNR are corrected for the roll-off at the measured angle")
+	fs.BoolVar(&cfg.autoGain, "auto-gain-backoff", defaults.AutoGainBackoff, "Step RX gain down automatically while the ADC clips")
+	fs.BoolVar(&cfg.burstMode, "burst-mode", defaults.BurstMode, "Track intermittent emitters: process only energy bursts and feed one averaged detection per burst to the track manager")
+	fs.Float64Var(&cfg.burstThreshold, "burst-threshold", defaults.BurstThreshold, "Buffer power above the noise floor (dB) that starts a burst (default 10)")
+	fs.Float64Var(&cfg.squelchSNR, "squelch-snr", defaults.SquelchSNR, "Withhold measurements below this SNR (dB) from telemetry; they are still used for tracking (0 disables)")
+	fs.DurationVar(&cfg.minDwell, "min-dwell", durationFromString(defaults.MinDwell, 0), "How long a new angle must persist before it is reported (0 disables)")
+	fs.Float64Var(&cfg.steeringDeadband, "steering-deadband", defaults.SteeringDeadband, "Degrees the measured angle must move before the conditioned steering angle follows (0 with -steering-persist 0 disables the steering output)")
+	fs.IntVar(&cfg.steeringPersist, "steering-persist", defaults.SteeringPersist, "Consecutive reports outside the deadband before the steering angle moves")
+	fs.Float64Var(&cfg.outputRes, "output-resolution", defaults.OutputRes, "Degrees the steering and map export angles are rounded to (0 keeps full precision)")
+	fs.Float64Var(&cfg.outputRate, "output-rate", defaults.OutputRate, "Steering stream updates per second and device (0 is unlimited)")
+	fs.Float64Var(&cfg.convergeStd, "converge-std", defaults.ConvergeStd, "Angle standard deviation (degrees) below which tracking counts as converged (0 = 0.5)")
+	fs.IntVar(&cfg.convergeIters, "converge-iterations", defaults.ConvergeIters, "Tracking iterations the angle spread must stay below -converge-std (0 = 10)")
+	fs.IntVar(&cfg.acquireWindow, "acquire-window", defaults.AcquireWindow, "Coarse-scan estimates kept in the acquisition histogram while searching (0 trusts a single coarse scan)")
+	fs.Float64Var(&cfg.acquireMass, "acquire-mass", defaults.AcquireMass, "Share of -acquire-window estimates that must agree on an angle before tracking starts (0 selects 0.6)")
+	fs.Float64Var(&cfg.trackGate, "track-gate", defaults.TrackGate, "Largest angle change (degrees) still associated with an existing track in multi mode (0 selects 5)")
+	fs.IntVar(&cfg.confirmHits, "confirm-hits", defaults.ConfirmHits, "Detections within -confirm-window that confirm a track (0 selects 3)")
+	fs.IntVar(&cfg.confirmWindow, "confirm-window", defaults.ConfirmWindow, "Updates considered when confirming a track (0 selects 5)")
+	fs.IntVar(&cfg.maxMisses, "max-misses", defaults.MaxMisses, "Consecutive misses that mark a track lost (0 selects 3)")
+	fs.StringVar(&cfg.trackScorer, "track-scorer", defaults.TrackScorer, "Track score used for pruning ("+strings.Join(app.ScorerNames(), "|")+"; default weighted)")
+	fs.StringVar(&cfg.association, "track-association", defaults.Association, "Multi-target data association ("+strings.Join(app.AssociationNames(), "|")+"; default nearest)")
+	scoreWeights := fs.String("score-weights", defaults.ScoreWeights, "Track score weights as snr,confidence,miss (default 0.6,0.3,0.1)")
+	fs.IntVar(&cfg.occBands, "occupancy-bands", defaults.OccupancyBands, "Sub-bands for spectrum occupancy statistics (0 disables)")
+	fs.Float64Var(&cfg.occThreshold, "occupancy-threshold", defaults.OccupancyThresh, "Sub-band power above the noise floor (dB) that counts as occupied (default 6)")
+	fs.Float64Var(&cfg.waterfallRate, "waterfall-rate", defaults.WaterfallRate, "Waterfall rows per second kept and streamed per device (0 selects 5, negative disables the waterfall)")
+	fs.IntVar(&cfg.waterfallBins, "waterfall-bins", defaults.WaterfallBins, "Bins each waterfall row is decimated to (0 selects 512)")
+	fs.StringVar(&cfg.angleUnit, "angle-unit", defaults.AngleUnit, "Telemetry display angle unit (deg|rad|mil)")
+	fs.StringVar(&cfg.powerUnit, "power-unit", defaults.PowerUnit, "Telemetry display power unit (dBFS|dBm)")
+	fs.Float64Var(&cfg.powerOffset, "power-offset", defaults.PowerOffsetDB, "Calibration offset in dB added to dBFS for dBm display")
+	fs.StringVar(&cfg.angleFrame, "angle-frame", defaults.AngleFrame, "Reference frame for reported bearings (array|vehicle|true)")
+	fs.Float64Var(&cfg.mount.AzimuthDeg, "mount-azimuth", defaults.MountAzimuth, "Array boresight azimuth clockwise from the vehicle's forward axis (degrees)")
+	fs.Float64Var(&cfg.mount.RollDeg, "mount-roll", defaults.MountRoll, "Array roll about boresight, positive right side down (degrees)")
+	fs.Float64Var(&cfg.mount.TiltDeg, "mount-tilt", defaults.MountTilt, "Array boresight tilt, positive up (degrees)")
 	fs.IntVar(&cfg.warmupBuffers, "warmup-buffers", defaults.WarmupBuffers, "Number of RX buffers to discard for warm-up")
 	fs.IntVar(&cfg.historyLimit, "history-limit", defaults.HistoryLimit, "Maximum samples to keep in telemetry history")
 	fs.StringVar(&cfg.webAddr, "web-addr", defaults.WebAddr, "Optional web telemetry listen address (e.g. :8080)")
+	fs.StringVar(&cfg.pairing, "pairing", defaults.Pairing, "Web UI pairing with the one-time code printed at startup (off|remote|all; default remote, which lets localhost in unpaired)")
+	fs.StringVar(&cfg.agentUpstream, "agent-upstream", defaults.AgentUpstream, "Run as an agent node: push telemetry to the aggregator at host:port instead of serving the web UI")
+	fs.StringVar(&cfg.agentNode, "agent-node", defaults.AgentNode, "Node name reported to the aggregator (default the host name)")
+	fs.IntVar(&cfg.agentBuffer, "agent-buffer", defaults.AgentBuffer, "Messages buffered while the aggregator is unreachable (0 selects 10000)")
+	fs.StringVar(&cfg.aggregatorListen, "aggregator-listen", defaults.AggregatorListen, "Accept agent nodes on this address (e.g. :7100) and show their tracks in the web UI")
+	fs.StringVar(&cfg.trackUDP, "track-udp", defaults.TrackUDP, "Send every tracking iteration as a binary UDP datagram to host:port (unicast or multicast)")
+	fs.StringVar(&cfg.store, "store", defaults.Store, "Keep the telemetry history and events in this store beyond -history-limit, e.g. file:/var/lib/gosdr/store or sqlite:/var/lib/gosdr/telemetry.db (empty disables it; see \"monopulse store\")")
 	fs.StringVar(&cfg.logLevel, "log-level", defaults.LogLevel, "Log level (debug|info|warn|error)")
 	fs.StringVar(&cfg.logFormat, "log-format", defaults.LogFormat, "Log format (text|json)")
 	fs.BoolVar(&cfg.debugMode, "debug-mode", defaults.DebugMode, "Include debug telemetry fields")
 	fs.BoolVar(&cfg.verbose, "verbose", false, "Enable verbose logging and debug output")
+	fs.BoolVar(&cfg.debugInject, "debug-inject", false, "Enable synthetic test signal injection via /api/debug/inject")
+	fs.StringVar(&cfg.patternCSV, "pattern-csv", "", "Sweep the steering phase, log sum/delta response to this CSV file and exit")
+	fs.Float64Var(&cfg.patternStep, "pattern-step", 1, "Steering phase step (degrees) for -pattern-csv")
+	fs.IntVar(&cfg.patternDwell, "pattern-dwell", 4, "RX buffers averaged per step for -pattern-csv")
+	fs.BoolVar(&cfg.noiseFigure, "noise-figure", false, "Measure per-channel noise figure with a noise source (Y-factor), store it and exit")
+	fs.Float64Var(&cfg.noiseENR, "noise-enr", 15, "Excess noise ratio of the noise source in dB for -noise-figure")
+	fs.StringVar(&cfg.noiseGPIO, "noise-gpio", "", "Sysfs GPIO value file that switches the noise source (empty prompts the operator)")
+	fs.IntVar(&cfg.noiseBuffers, "noise-buffers", 8, "RX buffers averaged per noise source state for -noise-figure")
+	fs.StringVar(&cfg.calibration, "calibration-file", "calibration.json", "Calibration store path")
+	fs.BoolVar(&cfg.calibrate, "calibrate", false, "Calibrate the phase against a reference source, store it in the calibration table and exit")
+	fs.Float64Var(&cfg.calibrateSource, "calibrate-source", 0, "Angle (degrees) of the reference source for -calibrate; 0 is boresight")
+	fs.BoolVar(&cfg.calibrateInject, "calibrate-inject", false, "Simulate the reference source for -calibrate (mock backend)")
+	fs.BoolVar(&cfg.txSweep, "tx-sweep", false, "Characterize the TX/RX chains by transmitting from TX0, TX1 and both with a swept phase offset, print the matrix and exit")
+	fs.Float64Var(&cfg.txSweepStep, "tx-sweep-step", 30, "TX1 phase offset step (degrees) of -tx-sweep")
+	fs.BoolVar(&cfg.txSweepApply, "tx-sweep-apply", false, "Store the phase cal measured by -tx-sweep in the calibration table")
+	fs.StringVar(&cfg.txSweepOut, "tx-sweep-out", "", "Also write the -tx-sweep results to this JSON file")
+	fs.Float64Var(&cfg.sweepStart, "sweep-start", 0, "Lowest frequency (Hz) of the spectrum survey enabled by -sweep-stop")
+	fs.Float64Var(&cfg.sweepStop, "sweep-stop", 0, "Survey the spectrum up to this frequency (Hz) by stepping the RX LO instead of tracking (0 disables it)")
+	fs.Float64Var(&cfg.sweepStep, "sweep-step", 0, "RX LO step (Hz) of the survey, at most the sample rate (0 selects 80% of it)")
+	fs.IntVar(&cfg.sweepDwell, "sweep-dwell", 4, "RX buffers averaged per survey step")
+	fs.Float64Var(&cfg.sweepThreshold, "sweep-threshold", 10, "Detection threshold (dB) above the noise floor for the survey")
+	fs.BoolVar(&cfg.sweepLock, "sweep-lock", false, "Sweep once, tune each device to the strongest signal found and track it")
+	fs.BoolVar(&cfg.phaseCalTable, "phase-cal-table", true, "Take -phase-cal from the calibration table for the RX LO when it has an entry")
+	fs.BoolVar(&cfg.warmStart, "warm-start", true, "Save the angle each device last locked on in the calibration file at shutdown and scan around it first on the next start")
+	fs.Float64Var(&cfg.warmStartSpan, "warm-start-span", 40, "Width (degrees of phase) of the window scanned around the last lock before falling back to the full scan")
+	fs.StringVar(&cfg.auditLog, "audit-log", "audit.jsonl", "Config change audit log path (empty keeps the log in memory only)")
+	fs.DurationVar(&cfg.auditMaxAge, "audit-max-age", 0, "Delete audit log entries older than this, e.g. 8760h (0 keeps them)")
+	fs.IntVar(&cfg.auditMaxMB, "audit-max-mb", 0, "Disk quota in MB for the audit log; the oldest entries are trimmed first (0 disables)")
+	fs.StringVar(&cfg.eventLevel, "event-level", "debug", "Lowest severity kept in the event log (debug|info|warn|error)")
+	fs.Float64Var(&cfg.timeScale, "time-scale", 1, "Run the mock backend simulation this many times faster than real time")
+	fs.DurationVar(&cfg.runFor, "run-for", 0, "Stop after this long and print a JSON summary (0 runs until stopped)")
+	fs.IntVar(&cfg.iterations, "iterations", 0, "Stop after this many tracker iterations per device and print a JSON summary (0 is unlimited)")
+	fs.BoolVar(&cfg.untilLock, "until-lock", false, "Stop once every device has locked; -run-for and -iterations become limits that fail the run")
+	fs.StringVar(&cfg.journal, "journal", "", "State journal path for restoring the telemetry history after a crash (empty disables it)")
+	fs.DurationVar(&cfg.journalWindow, "journal-window", 10*time.Minute, "Telemetry history restored from -journal at startup")
+	fs.Float64Var(&cfg.bearingLineM, "bearing-line-length", defaults.BearingLineM, "Length in metres of the lines of bearing in /api/tracks.geojson (0 selects 10 km)")
+	fs.StringVar(&cfg.runMacro, "run-macro", "", "Attribute macro from attribute_macros to run on every device after init")
+	fs.StringVar(&cfg.adminToken, "admin-token", os.Getenv("GOSDR_ADMIN_TOKEN"), "Bearer token enabling the admin endpoints such as /api/iiod/exec, or a secrets reference (default from $GOSDR_ADMIN_TOKEN; empty disables them)")
+	fs.StringVar(&cfg.controlSocket, "control-socket", os.Getenv(controlSocketEnvVar), "Unix socket serving the JSON-RPC control plane (status, start, stop, config.get, config.set) to local processes (default from $"+controlSocketEnvVar+"; empty disables it)")
+	fs.StringVar(&cfg.otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP collector receiving traces and metrics, e.g. http://collector:4318 (default from $OTEL_EXPORTER_OTLP_ENDPOINT; empty disables them)")
+	fs.StringVar(&cfg.otlpHeaders, "otlp-headers", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), "Headers sent to the OTLP collector as key=value,... or a secrets reference (default from $OTEL_EXPORTER_OTLP_HEADERS)")
+	fs.StringVar(&cfg.otlpService, "otlp-service-name", cmp.Or(os.Getenv("OTEL_SERVICE_NAME"), "gosdr"), "service.name of the exported telemetry (default from $OTEL_SERVICE_NAME)")
+	fs.DurationVar(&cfg.otlpInterval, "otlp-interval", 10*time.Second, "Interval between OTLP exports")
+	fs.DurationVar(&cfg.configWatch, "config-watch", 2*time.Second, "Apply external config.json edits as they are saved; polls at this interval where file notifications are unavailable (0 disables)")
+	fs.DurationVar(&cfg.leakCheck, "leak-check", time.Minute, "Interval of the goroutine, open file and heap leak self-check reported in /api/health (0 disables)")
+	fs.DurationVar(&cfg.sensorInterval, "sensor-interval", sdr.DefaultSensorInterval, "Interval of the radio temperature and RSSI readings in /api/sensors (0 disables)")
+	disable := fs.String("disable", strings.Join(defaults.Disable, ","), "Subsystems to switch off ("+strings.Join(subsystemNames, ",")+"), e.g. tx,ssh for a receive-only deployment")
+	fs.BoolVar(&cfg.save, "save", false, "Save the effective settings to config.json (previous file kept as config.json.bak)")
 
 	if err := fs.Parse(args); err != nil {
 		return cliConfig{}, fmt.Errorf("parse flags: %w", err)
 	}
-	return cfg, nil
+	cfg.devices = defaults.Devices
+	cfg.gainSchedule = defaults.GainSchedule
+	cfg.macros = defaults.AttributeMacros
+	cfg.headingDeg = defaults.HeadingDeg
+	cfg.schedule = defaults.Schedule
+	if _, err := schedule.New(cfg.schedule, nil); err != nil {
+		return cliConfig{}, err
+	}
+	cfg.captures = defaults.Captures
+	if _, err := capture.New(cfg.captures, "", nil); err != nil {
+		return cliConfig{}, err
+	}
+	if len(cfg.captures) > 0 && cfg.ringFile == "" {
+		return cliConfig{}, fmt.Errorf("captures need -ring-file")
+	}
+	if cfg.retentionAge < 0 || cfg.retentionMB < 0 || cfg.retentionIval <= 0 {
+		return cliConfig{}, fmt.Errorf("-retention-max-age and -retention-max-mb must not be negative and -retention-interval must be positive")
+	}
+	if cfg.auditMaxAge < 0 || cfg.auditMaxMB < 0 {
+		return cliConfig{}, fmt.Errorf("-audit-max-age and -audit-max-mb must not be negative")
+	}
+	cfg.retention = defaults.Retention
+	if _, err := storageArtifacts(cfg, nil, nil); err != nil {
+		return cliConfig{}, err
+	}
+	if cfg.aggregatorListen != "" && (cfg.webAddr == "" || cfg.agentUpstream != "") {
+		return cliConfig{}, fmt.Errorf("-aggregator-listen needs -web-addr and cannot be combined with -agent-upstream")
+	}
+	if defaults.LatitudeDeg != nil || defaults.LongitudeDeg != nil {
+		if defaults.LatitudeDeg == nil || defaults.LongitudeDeg == nil {
+			return cliConfig{}, fmt.Errorf("latitude_deg and longitude_deg must be set together")
+		}
+		cfg.position = &telemetry.Position{LatitudeDeg: *defaults.LatitudeDeg, LongitudeDeg: *defaults.LongitudeDeg}
+		if !cfg.position.Valid() {
+			return cliConfig{}, fmt.Errorf("invalid position %v, %v", *defaults.LatitudeDeg, *defaults.LongitudeDeg)
+		}
+	}
+	if err := cfg.macros.Validate(); err != nil {
+		return cliConfig{}, err
+	}
+	if _, ok := cfg.macros[cfg.runMacro]; cfg.runMacro != "" && !ok {
+		return cliConfig{}, fmt.Errorf("unknown attribute macro %q", cfg.runMacro)
+	}
+	if cfg.polarization != "" && !slices.Contains(app.PolarizationModes, cfg.polarization) {
+		return cliConfig{}, fmt.Errorf("unknown -polarization %q (want %s)", cfg.polarization, strings.Join(app.PolarizationModes, "|"))
+	}
+	if (cfg.scanStepMin > 0) != (cfg.scanStepMax > 0) || cfg.scanStepMin < 0 || cfg.scanStepMin > cfg.scanStepMax {
+		return cliConfig{}, fmt.Errorf("-scan-step-min and -scan-step-max must both be set with min <= max, or both 0")
+	}
+	if cfg.association != "" && !slices.Contains(app.AssociationNames(), cfg.association) {
+		return cliConfig{}, fmt.Errorf("unknown -track-association %q (want %s)", cfg.association, strings.Join(app.AssociationNames(), "|"))
+	}
+	if err := cfg.waterfall().Validate(); err != nil {
+		return cliConfig{}, fmt.Errorf("-waterfall-rate/-waterfall-bins: %w", err)
+	}
+	if err := cfg.output().Validate(); err != nil {
+		return cliConfig{}, fmt.Errorf("-output-resolution/-output-rate: %w", err)
+	}
+	if cfg.sensorInterval < 0 {
+		return cliConfig{}, fmt.Errorf("-sensor-interval must not be negative")
+	}
+	if cfg.convergeStd < 0 || cfg.convergeIters < 0 {
+		return cliConfig{}, fmt.Errorf("-converge-std and -converge-iterations must not be negative")
+	}
+	if cfg.acquireWindow < 0 || cfg.acquireMass < 0 || cfg.acquireMass > 1 {
+		return cliConfig{}, fmt.Errorf("-acquire-window must not be negative and -acquire-mass must be between 0 and 1")
+	}
+	if cfg.sweepStop > 0 && (cfg.sweepStart <= 0 || cfg.sweepStart >= cfg.sweepStop) {
+		return cliConfig{}, fmt.Errorf("-sweep-start must be positive and below -sweep-stop")
+	}
+	if cfg.sweepStep < 0 || cfg.sweepStep > cfg.sampleRate {
+		return cliConfig{}, fmt.Errorf("-sweep-step must be between 0 and the sample rate")
+	}
+	if cfg.sweepLock && cfg.sweepStop <= 0 {
+		return cliConfig{}, fmt.Errorf("-sweep-lock needs a -sweep-start/-sweep-stop range")
+	}
+	if cfg.calibrateSource < -90 || cfg.calibrateSource > 90 {
+		return cliConfig{}, fmt.Errorf("-calibrate-source must be between -90 and 90 degrees")
+	}
+	var err error
+	if cfg.disabled, err = parseDisabled(*disable); err != nil {
+		return cliConfig{}, err
+	}
+	if cfg.txSweep && (cfg.txSweepStep <= 0 || cfg.txSweepStep > 120) {
+		return cliConfig{}, fmt.Errorf("-tx-sweep-step must be in (0, 120] degrees")
+	}
+	if cfg.txSweep && !cfg.enabled(subsystemTX) {
+		return cliConfig{}, fmt.Errorf("-tx-sweep needs the tx subsystem")
+	}
+	resolver := &secrets.Resolver{StorePath: cfg.secretsFile, KeyPath: cfg.secretsKey}
+	if cfg.sshSecret, err = resolver.Resolve(cfg.sshPassword); err != nil {
+		return cliConfig{}, fmt.Errorf("ssh_password: %w", err)
+	}
+	if cfg.adminToken, err = resolver.Resolve(cfg.adminToken); err != nil {
+		return cliConfig{}, fmt.Errorf("admin token: %w", err)
+	}
+	if cfg.scoreWeights, err = app.ParseScoreWeights(*scoreWeights); err != nil {
+		return cliConfig{}, err
+	}
+	if cfg.warmStartSpan <= 0 || cfg.warmStartSpan > 360 {
+		return cliConfig{}, fmt.Errorf("warm-start-span must be in (0, 360] degrees, got %v", cfg.warmStartSpan)
+	}
+	if cfg.reconnect.MaxDelay <= 0 || cfg.reconnect.MaxAttempts < 0 {
+		return cliConfig{}, fmt.Errorf("sdr-reconnect-max-delay must be positive and sdr-reconnect-attempts not negative")
+	}
+	if cfg.otlpEndpoint != "" {
+		if cfg.tracing, err = newTracing(cfg, resolver); err != nil {
+			return cliConfig{}, err
+		}
+	}
+	cfg.tsdbConfig = defaults.TSDB
+	if cfg.tsdbConfig != nil {
+		if cfg.tsdbWriter, err = newTSDB(*cfg.tsdbConfig, resolver); err != nil {
+			return cliConfig{}, err
+		}
+	}
+	if cfg.patternFile != "" {
+		if cfg.elementPattern, err = app.LoadElementPattern(cfg.patternFile); err != nil {
+			return cliConfig{}, err
+		}
+	}
+	if cfg.pairing, err = telemetry.ParsePairingMode(cfg.pairing); err != nil {
+		return cliConfig{}, err
+	}
+	if cfg.angleFrame, err = telemetry.ParseFrame(cfg.angleFrame); err != nil {
+		return cliConfig{}, err
+	}
+	if cfg.angleUnit, err = telemetry.ParseAngleUnit(cfg.angleUnit); err != nil {
+		return cliConfig{}, err
+	}
+	window, err := dsp.ParseWindow(cfg.fftWindow)
+	if err != nil {
+		return cliConfig{}, fmt.Errorf("-fft-window: %w", err)
+	}
+	cfg.fftWindow = window.String()
+	if cfg.powerUnit, err = telemetry.ParsePowerUnit(cfg.powerUnit); err != nil {
+		return cliConfig{}, err
+	}
+	if cfg.eventLevel, err = telemetry.ParseEventLevel(cfg.eventLevel); err != nil {
+		return cliConfig{}, err
+	}
+	if cfg.runFor < 0 || cfg.iterations < 0 {
+		return cliConfig{}, fmt.Errorf("-run-for and -iterations must not be negative")
+	}
+	if cfg.timeScale <= 0 {
+		return cliConfig{}, fmt.Errorf("-time-scale must be positive, got %g", cfg.timeScale)
+	}
+	if cfg.timeScale != 1 {
+		if cfg.sdrBackend != "mock" {
+			return cliConfig{}, fmt.Errorf("-time-scale needs the mock backend, got %q", cfg.sdrBackend)
+		}
+		cfg.clock = clock.NewScaled(cfg.timeScale)
+	}
+	return cfg, validateDevices(cfg.devices)
 }
 
 func persistentFromCLI(cfg cliConfig) persistentConfig {
+	var lat, lon *float64
+	if cfg.position != nil {
+		lat, lon = &cfg.position.LatitudeDeg, &cfg.position.LongitudeDeg
+	}
 	if cfg.logLevel == "" {
 		cfg.logLevel = "warn"
 	}
 	if cfg.logFormat == "" {
 		cfg.logFormat = "text"
 	}
+	var weights string
+	if cfg.scoreWeights != (app.ScoreWeights{}) && cfg.scoreWeights != app.DefaultScoreWeights() {
+		weights = cfg.scoreWeights.String()
+	}
 	return persistentConfig{
-		SampleRate:     cfg.sampleRate,
-		RxLO:           cfg.rxLO,
-		RxGain0:        cfg.rxGain0,
-		RxGain1:        cfg.rxGain1,
-		TxGain:         cfg.txGain,
-		ToneOffset:     cfg.toneOffset,
-		NumSamples:     cfg.numSamples,
-		TrackingLength: cfg.trackingLength,
-		PhaseStep:      cfg.phaseStep,
-		PhaseCal:       cfg.phaseCal,
-		ScanStep:       cfg.scanStep,
-		Spacing:        cfg.spacing,
-		PhaseDelta:     cfg.phaseDelta,
-		TrackingMode:   cfg.trackingMode,
-		MaxTracks:      cfg.maxTracks,
-		TrackTimeout:   cfg.trackTimeout.String(),
-		MinSNR:         cfg.minSNR,
-		SDRBackend:     cfg.sdrBackend,
-		SDRURI:         cfg.sdrURI,
-		WarmupBuffers:  cfg.warmupBuffers,
-		HistoryLimit:   cfg.historyLimit,
-		WebAddr:        cfg.webAddr,
-		LogLevel:       cfg.logLevel,
-		LogFormat:      cfg.logFormat,
-		DebugMode:      cfg.debugMode,
-		SSHHost:        cfg.sshHost,
-		SSHUser:        cfg.sshUser,
-		SSHPassword:    cfg.sshPassword,
-		SSHKeyPath:     cfg.sshKeyPath,
-		SSHPort:        cfg.sshPort,
-		SysfsRoot:      cfg.sysfsRoot,
+		SampleRate:       cfg.sampleRate,
+		RxLO:             cfg.rxLO,
+		RxGain0:          cfg.rxGain0,
+		RxGain1:          cfg.rxGain1,
+		TxGain:           cfg.txGain,
+		ToneOffset:       cfg.toneOffset,
+		NumSamples:       cfg.numSamples,
+		TrackingLength:   cfg.trackingLength,
+		PhaseStep:        cfg.phaseStep,
+		PhaseCal:         cfg.phaseCal,
+		ScanStep:         cfg.scanStep,
+		ScanStepMin:      cfg.scanStepMin,
+		ScanStepMax:      cfg.scanStepMax,
+		Spacing:          cfg.spacing,
+		PhaseDelta:       cfg.phaseDelta,
+		TrackingMode:     cfg.trackingMode,
+		MaxTracks:        cfg.maxTracks,
+		TrackTimeout:     cfg.trackTimeout.String(),
+		MinSNR:           cfg.minSNR,
+		SDRBackend:       cfg.sdrBackend,
+		SDRURI:           cfg.sdrURI,
+		WarmupBuffers:    cfg.warmupBuffers,
+		HistoryLimit:     cfg.historyLimit,
+		WebAddr:          cfg.webAddr,
+		Pairing:          cfg.pairing,
+		LogLevel:         cfg.logLevel,
+		LogFormat:        cfg.logFormat,
+		DebugMode:        cfg.debugMode,
+		SSHHost:          cfg.sshHost,
+		SSHUser:          cfg.sshUser,
+		SSHPassword:      cfg.sshPassword,
+		SecretsFile:      cfg.secretsFile,
+		SSHKeyPath:       cfg.sshKeyPath,
+		SSHPort:          cfg.sshPort,
+		SysfsRoot:        cfg.sysfsRoot,
+		LOSource:         cfg.loSource,
+		LOExport:         cfg.loExport,
+		ClockSource:      cfg.clockSource,
+		TimeSource:       cfg.timeSource,
+		RefClockHz:       cfg.refClockHz,
+		FreqCorrection:   cfg.freqCorrection,
+		CFOTracking:      cfg.cfoTracking,
+		FastTrack:        cfg.fastTrack,
+		FFTWindow:        cfg.fftWindow,
+		RXIntegrity:      cfg.rxIntegrity,
+		Loopback:         cfg.loopback,
+		BIST:             cfg.bist,
+		RingFile:         cfg.ringFile,
+		RingSizeMB:       cfg.ringSizeMB,
+		Captures:         cfg.captures,
+		TSDB:             cfg.tsdbConfig,
+		CaptureDir:       cfg.captureDir,
+		RetentionMaxAge:  durationString(cfg.retentionAge),
+		RetentionMaxMB:   cfg.retentionMB,
+		Retention:        cfg.retention,
+		AgentUpstream:    cfg.agentUpstream,
+		AgentNode:        cfg.agentNode,
+		AgentBuffer:      cfg.agentBuffer,
+		AggregatorListen: cfg.aggregatorListen,
+		TrackUDP:         cfg.trackUDP,
+		Store:            cfg.store,
+		SwapChannels:     cfg.swapChannels,
+		InvertRX1:        cfg.invertRX1,
+		PolarityCheck:    cfg.polarityCheck,
+		PolarityRefDeg:   cfg.polarityRefDeg,
+		ElementPattern:   cfg.patternFile,
+		Polarization:     cfg.polarization,
+		MockPolarization: cfg.mockPolarization,
+		AutoGainBackoff:  cfg.autoGain,
+		OccupancyBands:   cfg.occBands,
+		OccupancyThresh:  cfg.occThreshold,
+		WaterfallRate:    cfg.waterfallRate,
+		WaterfallBins:    cfg.waterfallBins,
+		BurstMode:        cfg.burstMode,
+		BurstThreshold:   cfg.burstThreshold,
+		SquelchSNR:       cfg.squelchSNR,
+		MinDwell:         durationString(cfg.minDwell),
+		SteeringDeadband: cfg.steeringDeadband,
+		SteeringPersist:  cfg.steeringPersist,
+		OutputRes:        cfg.outputRes,
+		OutputRate:       cfg.outputRate,
+		ConvergeStd:      cfg.convergeStd,
+		ConvergeIters:    cfg.convergeIters,
+		AcquireWindow:    cfg.acquireWindow,
+		AcquireMass:      cfg.acquireMass,
+		TrackGate:        cfg.trackGate,
+		ConfirmHits:      cfg.confirmHits,
+		ConfirmWindow:    cfg.confirmWindow,
+		MaxMisses:        cfg.maxMisses,
+		TrackScorer:      cfg.trackScorer,
+		Association:      cfg.association,
+		ScoreWeights:     weights,
+		GainSchedule:     cfg.gainSchedule,
+		AttributeMacros:  cfg.macros,
+		AngleUnit:        cfg.angleUnit,
+		PowerUnit:        cfg.powerUnit,
+		PowerOffsetDB:    cfg.powerOffset,
+		AngleFrame:       cfg.angleFrame,
+		MountAzimuth:     cfg.mount.AzimuthDeg,
+		MountRoll:        cfg.mount.RollDeg,
+		MountTilt:        cfg.mount.TiltDeg,
+		HeadingDeg:       cfg.headingDeg,
+		LatitudeDeg:      lat,
+		LongitudeDeg:     lon,
+		BearingLineM:     cfg.bearingLineM,
+		Schedule:         cfg.schedule,
+		Disable:          cfg.disabled,
+		Devices:          cfg.devices,
 	}
 }
 
+// validateDevices checks that every configured device has a unique,
+// URL-safe ID.
+func validateDevices(devices []deviceConfig) error {
+	seen := make(map[string]struct{}, len(devices))
+	for i, dev := range devices {
+		if dev.ID == "" {
+			return fmt.Errorf("devices[%d]: id is required", i)
+		}
+		if strings.ContainsAny(dev.ID, "/:?# ") {
+			return fmt.Errorf("devices[%d]: id %q must not contain '/', ':', '?', '#' or spaces", i, dev.ID)
+		}
+		if _, dup := seen[dev.ID]; dup {
+			return fmt.Errorf("devices[%d]: duplicate id %q", i, dev.ID)
+		}
+		seen[dev.ID] = struct{}{}
+	}
+	return nil
+}
+
 func loadOrCreateConfig(path string) (persistentConfig, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -348,12 +1719,24 @@ func loadOrCreateConfig(path string) (persistentConfig, error) {
 	return cfg, nil
 }
 
+// configBackupSuffix names the copy of the previous config kept by
+// saveConfig and restored by "config rollback".
+const configBackupSuffix = ".bak"
+
+// saveConfig writes cfg to path. A previous file with different contents is
+// kept as path+configBackupSuffix first.
 func saveConfig(path string, cfg persistentConfig) error {
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
-	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+	data = append(data, '\n')
+	if previous, err := os.ReadFile(path); err == nil && !bytes.Equal(previous, data) {
+		if err := os.WriteFile(path+configBackupSuffix, previous, 0o644); err != nil {
+			return fmt.Errorf("back up config file: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
 		return fmt.Errorf("write config file: %w", err)
 	}
 	return nil
@@ -401,13 +1784,66 @@ func durationFromString(value string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
+// durationString formats an optional duration for the config file; zero is
+// left out.
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
 func selectBackend(cfg cliConfig) (sdr.SDR, error) {
+	var backend sdr.SDR
 	switch cfg.sdrBackend {
 	case "mock":
-		return sdr.NewMock(), nil
+		backend = sdr.NewMock()
 	case "pluto":
-		return sdr.NewPluto(), nil
+		backend = sdr.NewPluto()
+	case "usrp":
+		backend = sdr.NewUSRP()
+	case "soapy":
+		backend = sdr.NewSoapy()
+	case "file":
+		backend = sdr.NewFile(cfg.sdrURI)
 	default:
 		return nil, fmt.Errorf("unknown backend %s", cfg.sdrBackend)
 	}
+	if noTX, noSSH := !cfg.enabled(subsystemTX), !cfg.enabled(subsystemSSH); noTX || noSSH {
+		backend = sdr.NewRestricted(backend, noTX, noSSH)
+	}
+	if cfg.swapChannels || cfg.invertRX1 || cfg.polarityCheck {
+		backend = sdr.NewChannelMapper(backend, cfg.swapChannels, cfg.invertRX1)
+	}
+	backend = sdr.NewLifecycleMonitor(backend, cfg.sdrBackend)
+	if len(cfg.gainSchedule) > 0 {
+		schedule, err := sdr.NewGainSchedule(cfg.gainSchedule)
+		if err != nil {
+			return nil, err
+		}
+		backend = sdr.NewGainScheduler(backend, schedule)
+	}
+	if cfg.rxIntegrity {
+		backend = sdr.NewIntegrityChecker(backend)
+	}
+	if cfg.ringFile != "" && cfg.enabled(subsystemRecording) {
+		sizeMB := cfg.ringSizeMB
+		if sizeMB <= 0 {
+			sizeMB = 512
+		}
+		backend = sdr.NewRingRecorder(backend, cfg.ringFile, int64(sizeMB)<<20)
+	}
+	if cfg.recordDir != "" && cfg.enabled(subsystemRecording) {
+		backend = sdr.NewIQRecorder(backend, cfg.recordDir)
+	}
+	if cfg.loopback && cfg.enabled(subsystemTX) {
+		backend = sdr.NewLoopbackMeter(backend)
+	}
+	if cfg.bist && cfg.enabled(subsystemAdmin) {
+		backend = sdr.NewBISTRunner(backend)
+	}
+	if cfg.debugInject && cfg.enabled(subsystemAdmin) {
+		backend = sdr.NewInjector(backend)
+	}
+	return backend, nil
 }
@@ -1,8 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/capture"
+	"github.com/rjboer/GoSDR/internal/schedule"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/storage"
+	"github.com/rjboer/GoSDR/internal/tsdb"
 )
 
 func TestParseConfigDefaults(t *testing.T) {
@@ -49,3 +62,558 @@ func TestSelectBackendMock(t *testing.T) {
 		t.Fatalf("backend should not be nil")
 	}
 }
+
+func TestSelectBackendUSRP(t *testing.T) {
+	backend, err := selectBackend(cliConfig{sdrBackend: "usrp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := backend.(*sdr.LifecycleMonitor); !ok {
+		t.Fatalf("expected lifecycle monitor, got %T", backend)
+	}
+	if _, ok := sdr.As[*sdr.USRPSDR](backend); !ok {
+		t.Fatalf("expected wrapped *sdr.USRPSDR")
+	}
+}
+
+func TestSelectBackendSoapy(t *testing.T) {
+	backend, err := selectBackend(cliConfig{sdrBackend: "soapy"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := sdr.As[*sdr.SoapySDR](backend); !ok {
+		t.Fatalf("expected wrapped *sdr.SoapySDR")
+	}
+}
+
+func TestSelectBackendWithInjection(t *testing.T) {
+	backend, err := selectBackend(cliConfig{sdrBackend: "pluto", debugInject: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := backend.(*sdr.Injector); !ok {
+		t.Fatalf("expected injector wrapper, got %T", backend)
+	}
+	if _, ok := sdr.As[*sdr.PlutoSDR](backend); !ok {
+		t.Fatalf("expected wrapped Pluto backend to be reachable")
+	}
+}
+
+func TestForDeviceOverrides(t *testing.T) {
+	base := cliConfig{sdrBackend: "pluto", sdrURI: "ip:a", sampleRate: 2e6, rxLO: 2.3e9, rxGain0: 60, rxGain1: 60, txGain: -10, phaseCal: 5}
+	zero := 0
+	cal := 0.0
+	got := base.forDevice(deviceConfig{ID: "b", SDRURI: "ip:b", RxGain1: &zero, PhaseCal: &cal})
+
+	if got.sdrURI != "ip:b" || got.rxGain1 != 0 || got.phaseCal != 0 {
+		t.Fatalf("overrides not applied: %#v", got)
+	}
+	if got.sdrBackend != "pluto" || got.rxGain0 != 60 || got.txGain != -10 || got.rxLO != 2.3e9 {
+		t.Fatalf("inherited settings changed: %#v", got)
+	}
+}
+
+func TestValidateDevices(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []deviceConfig
+		wantErr bool
+	}{
+		{name: "none", devices: nil},
+		{name: "unique", devices: []deviceConfig{{ID: "north"}, {ID: "south"}}},
+		{name: "missing id", devices: []deviceConfig{{SDRURI: "ip:a"}}, wantErr: true},
+		{name: "duplicate", devices: []deviceConfig{{ID: "a"}, {ID: "a"}}, wantErr: true},
+		{name: "separator", devices: []deviceConfig{{ID: "a:b"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDevices(tt.devices); (err != nil) != tt.wantErr {
+				t.Fatalf("validateDevices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPatternPath(t *testing.T) {
+	tests := []struct {
+		path, device, want string
+	}{
+		{path: "pattern.csv", device: "", want: "pattern.csv"},
+		{path: "out/pattern.csv", device: "north", want: "out/pattern-north.csv"},
+		{path: "pattern", device: "a", want: "pattern-a"},
+	}
+	for _, tt := range tests {
+		if got := patternPath(tt.path, tt.device); got != tt.want {
+			t.Fatalf("patternPath(%q, %q) = %q, want %q", tt.path, tt.device, got, tt.want)
+		}
+	}
+}
+
+func TestSelectBackendWithGainSchedule(t *testing.T) {
+	backend, err := selectBackend(cliConfig{sdrBackend: "mock", gainSchedule: []sdr.GainPoint{{FrequencyHz: 2.4e9, RxGain0DB: 40, RxGain1DB: 40}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := sdr.As[*sdr.GainScheduler](backend); !ok {
+		t.Fatalf("expected gain scheduler wrapper, got %T", backend)
+	}
+	if _, err := selectBackend(cliConfig{sdrBackend: "mock", gainSchedule: []sdr.GainPoint{{FrequencyHz: -1}}}); err == nil {
+		t.Fatal("expected error for invalid gain schedule")
+	}
+}
+
+func TestParseConfigUnitsAndFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "canonical", args: []string{"--angle-unit", "Mils", "--power-unit", "dbm", "--angle-frame", "north", "--event-level", "Warning"}},
+		{name: "bad angle unit", args: []string{"--angle-unit", "grad"}, wantErr: true},
+		{name: "bad power unit", args: []string{"--power-unit", "W"}, wantErr: true},
+		{name: "bad frame", args: []string{"--angle-frame", "body"}, wantErr: true},
+		{name: "bad event level", args: []string{"--angle-unit", "Mils", "--power-unit", "dbm", "--angle-frame", "north", "--event-level", "loud"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.angleUnit != "mil" || cfg.powerUnit != "dBm" || cfg.angleFrame != "true" || cfg.eventLevel != "warn") {
+				t.Fatalf("units not canonicalized: %q %q %q %q", cfg.angleUnit, cfg.powerUnit, cfg.angleFrame, cfg.eventLevel)
+			}
+		})
+	}
+}
+
+func TestParseConfigFFTWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: "hamming"},
+		{name: "kaiser", args: []string{"-fft-window", "Kaiser"}, want: "kaiser:8.6"},
+		{name: "kaiser beta", args: []string{"-fft-window", "kaiser:5"}, want: "kaiser:5"},
+		{name: "unknown", args: []string{"-fft-window", "flattop"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.fftWindow != tt.want || cfg.window().String() != tt.want || persistentFromCLI(cfg).FFTWindow != tt.want) {
+				t.Fatalf("window %q (%v), want %q", cfg.fftWindow, cfg.window(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigTSDB(t *testing.T) {
+	t.Setenv("GOSDR_TEST_INFLUX_TOKEN", "s3cret")
+	defaults := defaultPersistentConfig()
+	defaults.TSDB = &tsdb.Config{URL: "http://influx:8086", Bucket: "sdr", Token: "env:GOSDR_TEST_INFLUX_TOKEN"}
+	cfg, err := parseConfig(nil, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.tsdbWriter == nil || persistentFromCLI(cfg).TSDB.Token != "env:GOSDR_TEST_INFLUX_TOKEN" {
+		t.Fatalf("writer %v, persisted %+v", cfg.tsdbWriter, persistentFromCLI(cfg).TSDB)
+	}
+
+	defaults.TSDB = &tsdb.Config{Kind: tsdb.KindTimescale, URL: "http://pg:5432", Bucket: "sdr"}
+	if _, err := parseConfig(nil, defaults); err == nil {
+		t.Fatal("timescale accepted")
+	}
+	if cfg, err := parseConfig(nil, defaultPersistentConfig()); err != nil || cfg.tsdbWriter != nil {
+		t.Fatalf("no tsdb config: writer %v, %v", cfg.tsdbWriter, err)
+	}
+}
+
+func TestParseConfigTimeScale(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    bool
+		wantScaled bool
+	}{
+		{name: "real time", args: []string{"--sdr-backend", "pluto"}},
+		{name: "fast mock", args: []string{"--sdr-backend", "mock", "--time-scale", "20"}, wantScaled: true},
+		{name: "hardware", args: []string{"--sdr-backend", "pluto", "--time-scale", "20"}, wantErr: true},
+		{name: "zero", args: []string{"--sdr-backend", "mock", "--time-scale", "0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.clock != nil) != tt.wantScaled {
+				t.Fatalf("clock = %v, want scaled %v", cfg.clock, tt.wantScaled)
+			}
+		})
+	}
+}
+
+func TestParseConfigScanStepBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "fixed", args: nil},
+		{name: "adaptive", args: []string{"-scan-step-min", "0.5", "-scan-step-max", "8"}},
+		{name: "min only", args: []string{"-scan-step-min", "0.5"}, wantErr: true},
+		{name: "inverted", args: []string{"-scan-step-min", "8", "-scan-step-max", "0.5"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && persistentFromCLI(cfg).ScanStepMax != cfg.scanStepMax {
+				t.Fatalf("scan step bounds not persisted")
+			}
+		})
+	}
+}
+
+func TestParseConfigTrackAssociation(t *testing.T) {
+	cfg, err := parseConfig([]string{"-track-association", "jpda"}, defaultPersistentConfig())
+	if err != nil || cfg.association != app.AssociateJPDA {
+		t.Fatalf("association %q, err %v", cfg.association, err)
+	}
+	if _, err := parseConfig([]string{"-track-association", "closest"}, defaultPersistentConfig()); err == nil {
+		t.Fatal("unknown association accepted")
+	}
+}
+
+func TestParseConfigCalibrate(t *testing.T) {
+	cfg, err := parseConfig([]string{"-calibrate", "-calibrate-source", "-20", "-calibrate-inject"}, defaultPersistentConfig())
+	if err != nil || !cfg.calibrate || cfg.calibrateSource != -20 || !cfg.calibrateInject || !cfg.phaseCalTable {
+		t.Fatalf("calibrate %t source %v inject %t table %t, err %v", cfg.calibrate, cfg.calibrateSource, cfg.calibrateInject, cfg.phaseCalTable, err)
+	}
+	if _, err := parseConfig([]string{"-calibrate-source", "95"}, defaultPersistentConfig()); err == nil {
+		t.Fatal("source beyond endfire accepted")
+	}
+}
+
+func TestParseConfigSweep(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "survey", args: []string{"-sweep-start", "2.3e9", "-sweep-stop", "2.5e9", "-sweep-step", "1e6"}},
+		{name: "lock", args: []string{"-sweep-start", "2.3e9", "-sweep-stop", "2.5e9", "-sweep-lock"}},
+		{name: "reversed range", args: []string{"-sweep-start", "2.5e9", "-sweep-stop", "2.3e9"}, wantErr: true},
+		{name: "step above sample rate", args: []string{"-sweep-start", "2.3e9", "-sweep-stop", "2.5e9", "-sweep-step", "1e9"}, wantErr: true},
+		{name: "lock without range", args: []string{"-sweep-lock"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseConfig(tt.args, defaultPersistentConfig()); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfigOTLP(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantErr     bool
+		wantTracing bool
+	}{
+		{name: "disabled"},
+		{name: "collector", args: []string{"-otlp-endpoint", "http://collector:4318", "-otlp-headers", "api-key=s%3Dcret"}, wantTracing: true},
+		{name: "not a URL", args: []string{"-otlp-endpoint", "collector:4318"}, wantErr: true},
+		{name: "bad headers", args: []string{"-otlp-endpoint", "http://collector:4318", "-otlp-headers", "api-key"}, wantErr: true},
+		{name: "zero interval", args: []string{"-otlp-endpoint", "http://collector:4318", "-otlp-interval", "0s"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+			t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "")
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr || err == nil && (cfg.tracing != nil) != tt.wantTracing {
+				t.Fatalf("err = %v, tracing %v; want error %t, tracing %t", err, cfg.tracing != nil, tt.wantErr, tt.wantTracing)
+			}
+		})
+	}
+}
+
+func TestParseConfigWarmStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantErr  bool
+		wantWarm bool
+	}{
+		{name: "default", wantWarm: true},
+		{name: "disabled", args: []string{"-warm-start=false"}},
+		{name: "span", args: []string{"-warm-start-span", "60"}, wantWarm: true},
+		{name: "zero span", args: []string{"-warm-start-span", "0"}, wantErr: true},
+		{name: "span over a turn", args: []string{"-warm-start-span", "400"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr || err == nil && cfg.warmStart != tt.wantWarm {
+				t.Fatalf("err = %v, warm start %t; want error %t, warm start %t", err, cfg.warmStart, tt.wantErr, tt.wantWarm)
+			}
+		})
+	}
+}
+
+func TestParseConfigTXSweep(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "default", args: []string{"-tx-sweep"}},
+		{name: "step", args: []string{"-tx-sweep", "-tx-sweep-step", "15"}},
+		{name: "zero step", args: []string{"-tx-sweep", "-tx-sweep-step", "0"}, wantErr: true},
+		{name: "coarse step", args: []string{"-tx-sweep", "-tx-sweep-step", "180"}, wantErr: true},
+		{name: "tx disabled", args: []string{"-tx-sweep", "-disable", "tx"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseConfig(tt.args, defaultPersistentConfig()); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfigAttributeMacros(t *testing.T) {
+	defaults := defaultPersistentConfig()
+	defaults.AttributeMacros = sdr.Macros{"loopback": {{Device: "ad9361-phy", Attr: "loopback", Value: "1"}}}
+	tests := []struct {
+		name    string
+		macros  sdr.Macros
+		args    []string
+		wantErr bool
+	}{
+		{name: "run known", macros: defaults.AttributeMacros, args: []string{"-run-macro", "loopback"}},
+		{name: "run unknown", macros: defaults.AttributeMacros, args: []string{"-run-macro", "obs"}, wantErr: true},
+		{name: "invalid step", macros: sdr.Macros{"bad": {{Attr: "loopback"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := defaults
+			d.AttributeMacros = tt.macros
+			if _, err := parseConfig(tt.args, d); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfigSchedule(t *testing.T) {
+	defaults := defaultPersistentConfig()
+	defaults.Schedule = []schedule.Rule{{Action: "tx", Windows: []string{"08:00-18:00"}}}
+	cfg, err := parseConfig(nil, defaults)
+	if err != nil || len(cfg.schedule) != 1 {
+		t.Fatalf("cfg.schedule = %+v, err = %v", cfg.schedule, err)
+	}
+	defaults.Schedule = []schedule.Rule{{Action: "tx", Cron: "0 8 * * *"}}
+	if _, err := parseConfig(nil, defaults); err == nil {
+		t.Fatal("cron rule without duration accepted")
+	}
+}
+
+func TestParseConfigCaptures(t *testing.T) {
+	defaults := defaultPersistentConfig()
+	defaults.Captures = []capture.Rule{{Name: "new", On: "track_new"}}
+	if _, err := parseConfig(nil, defaults); err == nil {
+		t.Fatal("captures without -ring-file accepted")
+	}
+	cfg, err := parseConfig([]string{"-ring-file", "capture.ring"}, defaults)
+	if err != nil || len(cfg.captures) != 1 {
+		t.Fatalf("cfg.captures = %+v, err = %v", cfg.captures, err)
+	}
+	defaults.Captures = []capture.Rule{{Name: "x", On: "sunrise"}}
+	if _, err := parseConfig([]string{"-ring-file", "capture.ring"}, defaults); err == nil {
+		t.Fatal("unknown trigger accepted")
+	}
+}
+
+func TestParseConfigRetention(t *testing.T) {
+	defaults := defaultPersistentConfig()
+	defaults.Retention = retentionRules{
+		"audit":   {MaxMB: 5},
+		"exports": {Path: "exports", MaxAge: "168h"},
+	}
+	cfg, err := parseConfig([]string{"-retention-max-age", "720h", "-retention-max-mb", "2048", "-ring-file", "rx.ring"}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts, err := storageArtifacts(cfg, []deviceConfig{{ID: "a"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]storage.Artifact)
+	for _, a := range artifacts {
+		got[a.Name] = a
+	}
+	if p := got["captures"].Policy; p.MaxAge != 720*time.Hour || p.MaxBytes != 2048<<20 {
+		t.Fatalf("captures policy = %+v", p)
+	}
+	if p := got["audit"].Policy; p.MaxAge != 0 || p.MaxBytes != 5<<20 {
+		t.Fatalf("audit policy = %+v", p)
+	}
+	if a := got["exports"]; a.Path != "exports" || a.Kind != storage.KindDir || a.Policy.MaxAge != 168*time.Hour {
+		t.Fatalf("exports artifact = %+v", a)
+	}
+	if a := got["ring-a"]; a.Path != "rx-a.ring" || a.Kind != storage.KindFixed {
+		t.Fatalf("ring artifact = %+v", a)
+	}
+
+	for _, rules := range []retentionRules{
+		{"journal": {MaxMB: 1}},
+		{"exports": {MaxAge: "1w"}},
+		{"exports": {MaxMB: 1}},
+	} {
+		defaults.Retention = rules
+		if _, err := parseConfig(nil, defaults); err == nil {
+			t.Fatalf("retention %+v accepted", rules)
+		}
+	}
+	if _, err := parseConfig([]string{"-retention-max-mb", "-1"}, defaultPersistentConfig()); err == nil {
+		t.Fatal("negative quota accepted")
+	}
+
+	// Without a rule the audit log follows its own flags, not the global
+	// policy, and is trimmed under the lock it is appended under.
+	cfg, err = parseConfig([]string{"-retention-max-age", "720h", "-audit-max-mb", "7"}, defaultPersistentConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var auditLock sync.Mutex
+	artifacts, err = storageArtifacts(cfg, nil, &auditLock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range artifacts {
+		if a.Name == "audit" && (a.Policy != storage.Policy{MaxBytes: 7 << 20} || a.Lock != &auditLock) {
+			t.Fatalf("audit artifact = %+v", a)
+		}
+	}
+	if _, err := parseConfig([]string{"-audit-max-age", "-1h"}, defaultPersistentConfig()); err == nil {
+		t.Fatal("negative audit age accepted")
+	}
+}
+
+func TestParseConfigAgentMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"node", []string{"-agent-upstream", "central:7100", "-agent-node", "mast"}, false},
+		{"aggregator", []string{"-aggregator-listen", ":7100"}, false},
+		{"aggregator without web", []string{"-aggregator-listen", ":7100", "-web-addr", ""}, true},
+		{"aggregator and node", []string{"-aggregator-listen", ":7100", "-agent-upstream", "central:7100"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfigDisable(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{"none", nil, nil, false},
+		{"sorted and deduplicated", []string{"-disable", "web, TX,tx"}, []string{"tx", "web"}, false},
+		{"unknown", []string{"-disable", "tx,radar"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(cfg.disabled, tt.want) {
+				t.Fatalf("disabled = %v, want %v", cfg.disabled, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigElementPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "element.csv")
+	if err := os.WriteFile(path, []byte("angle_deg,gain_db\n-90,-12\n0,0\n90,-12\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := parseConfig([]string{"-element-pattern", path}, defaultPersistentConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.elementPattern == nil || cfg.elementPattern.GainDB(45) != -6 {
+		t.Fatalf("element pattern not loaded: %+v", cfg.elementPattern)
+	}
+	if _, err := parseConfig([]string{"-element-pattern", path + ".missing"}, defaultPersistentConfig()); err == nil {
+		t.Fatal("expected an error for a missing pattern file")
+	}
+}
+
+func TestSelectBackendDisabledSubsystems(t *testing.T) {
+	backend, err := selectBackend(cliConfig{sdrBackend: "mock", loopback: true, bist: true, debugInject: true, recordDir: "rec", disabled: []string{"admin", "recording", "ssh", "tx"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sdr.As[*sdr.LoopbackMeter](backend); ok {
+		t.Fatal("loopback meter built with TX disabled")
+	}
+	if _, ok := sdr.As[*sdr.Injector](backend); ok {
+		t.Fatal("injector built with admin disabled")
+	}
+	if _, ok := sdr.As[*sdr.BISTRunner](backend); ok {
+		t.Fatal("BIST runner built with admin disabled")
+	}
+	if _, ok := sdr.As[*sdr.IQRecorder](backend); ok {
+		t.Fatal("IQ recorder built with recording disabled")
+	}
+	if _, ok := sdr.As[*sdr.Restricted](backend); !ok {
+		t.Fatal("backend not restricted")
+	}
+	if err := backend.TX(context.Background(), nil, nil); !errors.Is(err, sdr.ErrTXDisabled) {
+		t.Fatalf("TX error = %v, want ErrTXDisabled", err)
+	}
+	if got := ignoredSettings(cliConfig{loopback: true, ringFile: "x.ring", disabled: []string{"tx"}}); !reflect.DeepEqual(got, []string{"loopback (tx disabled)"}) {
+		t.Fatalf("ignoredSettings = %v", got)
+	}
+}
+
+func TestPairingURL(t *testing.T) {
+	host, _ := os.Hostname()
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"localhost:8080", "http://localhost:8080/?pair=ABCD-EFGH"},
+		{":8080", "http://" + host + ":8080/?pair=ABCD-EFGH"},
+		{"[::1]:9000", "http://[::1]:9000/?pair=ABCD-EFGH"},
+	}
+	for _, tt := range tests {
+		if got := pairingURL(tt.addr, "ABCD-EFGH"); got != tt.want {
+			t.Errorf("pairingURL(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
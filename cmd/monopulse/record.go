@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// iqRecorders maps device IDs to the SigMF recorders among their backends.
+func iqRecorders(devices []deviceConfig, backends []sdr.SDR) map[string]*sdr.IQRecorder {
+	out := make(map[string]*sdr.IQRecorder)
+	for i, backend := range backends {
+		if recorder, ok := sdr.As[*sdr.IQRecorder](backend); ok {
+			out[devices[i].ID] = recorder
+		}
+	}
+	return out
+}
+
+// annotateRecordings subscribes to the track samples on b and adds a
+// "detection" annotation to the device's running recording whenever a track
+// becomes confirmed, so offline reprocessing can find the interesting IQ.
+func annotateRecordings(b *bus.Bus, recorders map[string]*sdr.IQRecorder) (cancel func()) {
+	var mu sync.Mutex
+	confirmed := make(map[string]map[string]bool)
+	return b.Subscribe(bus.TopicTrack, func(msg bus.Message) {
+		sample, ok := msg.Payload.(telemetry.MultiTrackSample)
+		recorder := recorders[msg.Source]
+		if !ok || recorder == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		prev := confirmed[msg.Source]
+		next := make(map[string]bool, len(sample.Tracks))
+		for _, track := range sample.Tracks {
+			next[track.ID] = track.Confirmed()
+			if !next[track.ID] || prev[track.ID] {
+				continue
+			}
+			angle := track.AngleDeg
+			recorder.Annotate(sdr.SigMFAnnotation{
+				Label:    "detection",
+				Comment:  fmt.Sprintf("SNR %.1f dB", track.SNR),
+				TrackID:  track.ID,
+				AngleDeg: &angle,
+			})
+		}
+		confirmed[msg.Source] = next
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestAnnotateRecordingsOnConfirmedTracks(t *testing.T) {
+	ctx := context.Background()
+	recorder := sdr.NewIQRecorder(sdr.NewMock(), t.TempDir())
+	if err := recorder.Init(ctx, sdr.Config{SampleRate: 1e6, NumSamples: 64}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.Start("tracks"); err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+	if _, _, err := recorder.RX(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bus.New()
+	defer annotateRecordings(b, iqRecorders([]deviceConfig{{ID: ""}}, []sdr.SDR{recorder}))()
+	samples := [][]telemetry.TrackSample{
+		{{ID: "T1", State: "tentative"}},
+		{{ID: "T1", State: "confirmed", AngleDeg: 10}, {ID: "T2", State: "confirmed", AngleDeg: -5}},
+		{{ID: "T1", State: "confirmed"}},
+	}
+	for _, tracks := range samples {
+		b.Publish(bus.TopicTrack, "", telemetry.MultiTrackSample{Tracks: tracks})
+		b.Publish(bus.TopicTrack, "other", telemetry.MultiTrackSample{Tracks: tracks})
+	}
+	if got := recorder.Status().Annotations; got != 2 {
+		t.Fatalf("annotations = %d, want 2", got)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runConfigRollback implements "monopulse config rollback": it swaps the
+// config file with the backup kept by the last -save, so running it again
+// undoes the rollback. It returns the exit code.
+func runConfigRollback(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config rollback", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", defaultConfigPath(), "Config file to restore (default from $"+configEnvVar+")")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	backupPath := *path + configBackupSuffix
+
+	backup, err := os.ReadFile(backupPath)
+	if os.IsNotExist(err) {
+		fmt.Fprintf(stderr, "error: no backup %s; backups are written by -save\n", backupPath)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	current, err := os.ReadFile(*path)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if err := os.WriteFile(*path, backup, 0o644); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if current != nil {
+		if err := os.WriteFile(backupPath, current, 0o644); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stdout, "restored %s from %s\n", *path, backupPath)
+	return 0
+}
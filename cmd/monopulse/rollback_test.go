@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveConfigKeepsBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	first := defaultPersistentConfig()
+	if err := saveConfig(path, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + configBackupSuffix); !os.IsNotExist(err) {
+		t.Fatalf("backup written for a new file: %v", err)
+	}
+	original, _ := os.ReadFile(path)
+
+	// Saving identical settings must not replace the backup.
+	if err := saveConfig(path, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + configBackupSuffix); !os.IsNotExist(err) {
+		t.Fatalf("backup written for an unchanged file: %v", err)
+	}
+
+	second := first
+	second.RxLO = 2.4e9
+	if err := saveConfig(path, second); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := os.ReadFile(path + configBackupSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup, original) {
+		t.Fatalf("backup = %s, want the previous file", backup)
+	}
+}
+
+func TestRunConfigRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	var stdout, stderr bytes.Buffer
+	if code := runConfigCommand([]string{"rollback", "-config", path}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "no backup") {
+		t.Fatalf("code = %d, stderr %q; want a missing backup error", code, stderr.String())
+	}
+
+	first := defaultPersistentConfig()
+	second := first
+	second.RxLO = 2.4e9
+	if err := saveConfig(path, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveConfig(path, second); err != nil {
+		t.Fatal(err)
+	}
+
+	rxLO := func() float64 {
+		cfg, err := loadOrCreateConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg.RxLO
+	}
+	for i, want := range []float64{first.RxLO, second.RxLO} {
+		stderr.Reset()
+		if code := runConfigCommand([]string{"rollback", "-config", path}, &stdout, &stderr); code != 0 {
+			t.Fatalf("rollback %d: code %d, stderr %q", i, code, stderr.String())
+		}
+		if got := rxLO(); got != want {
+			t.Fatalf("rollback %d: rx_lo = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestParseConfigSave(t *testing.T) {
+	defaults := defaultPersistentConfig()
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{args: nil, want: false},
+		{args: []string{"-save"}, want: true},
+	} {
+		cfg, err := parseConfig(tt.args, defaults)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.save != tt.want {
+			t.Fatalf("parseConfig(%v).save = %v, want %v", tt.args, cfg.save, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rjboer/GoSDR/internal/secrets"
+)
+
+// plaintextPasswordWarning is logged at startup and by "config lint".
+const plaintextPasswordWarning = "ssh_password is stored in plaintext; use an env:, file: or secret: reference"
+
+// runSecretsCommand implements "monopulse secrets <subcommand>", managing the
+// encrypted store read by secret: references. It returns the exit code.
+func runSecretsCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	usage := func() int {
+		fmt.Fprintln(stderr, "usage: monopulse secrets keygen [-key path]")
+		fmt.Fprintln(stderr, "       monopulse secrets set [-file path] [-key path] name   (value read from stdin)")
+		fmt.Fprintln(stderr, "       monopulse secrets list [-file path] [-key path]")
+		fmt.Fprintln(stderr, "       monopulse secrets delete [-file path] [-key path] name")
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("secrets "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyPath := fs.String("key", "", "Key file (default the base64 key in $"+secrets.KeyEnvVar+")")
+	storePath := fs.String("file", secrets.DefaultStorePath, "Encrypted secrets store")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if cmd == "keygen" {
+		return secretsKeygen(*keyPath, stdout, stderr)
+	}
+	wantArgs := map[string]int{"set": 1, "list": 0, "delete": 1}
+	n, ok := wantArgs[cmd]
+	if !ok || fs.NArg() != n {
+		return usage()
+	}
+	key, err := secrets.LoadKey(*keyPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	store, err := secrets.Open(*storePath, key)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	switch cmd {
+	case "list":
+		for _, name := range store.Names() {
+			fmt.Fprintln(stdout, name)
+		}
+		return 0
+	case "delete":
+		if !store.Delete(fs.Arg(0)) {
+			fmt.Fprintf(stderr, "error: %q is not in %s\n", fs.Arg(0), *storePath)
+			return 1
+		}
+	case "set":
+		value, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(stderr, "error: read value: %v\n", err)
+			return 1
+		}
+		if err := store.Set(fs.Arg(0), strings.TrimRight(value, "\r\n")); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+	if err := store.Save(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "updated %s\n", *storePath)
+	return 0
+}
+
+// secretsKeygen writes a new store key to path (owner-only) or prints it.
+func secretsKeygen(path string, stdout, stderr io.Writer) int {
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if path == "" {
+		fmt.Fprintln(stdout, key)
+		return 0
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := fmt.Fprintln(f, key); err != nil {
+		f.Close()
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "wrote %s\n", path)
+	return 0
+}
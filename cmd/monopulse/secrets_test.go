@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/secrets"
+)
+
+func TestRunSecretsCommand(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "secrets.key")
+	storePath := filepath.Join(dir, "secrets.json")
+	run := func(stdin string, args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := runSecretsCommand(args, strings.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	if code, out := run("", "keygen", "-key", keyPath); code != 0 {
+		t.Fatalf("keygen: %d %s", code, out)
+	}
+	if code, _ := run("", "keygen", "-key", keyPath); code == 0 {
+		t.Fatal("keygen overwrote an existing key")
+	}
+	if code, out := run("analog\n", "set", "-key", keyPath, "-file", storePath, "pluto-root"); code != 0 {
+		t.Fatalf("set: %d %s", code, out)
+	}
+	if code, out := run("", "list", "-key", keyPath, "-file", storePath); code != 0 || out != "pluto-root\n" {
+		t.Fatalf("list: %d %q", code, out)
+	}
+
+	cfg, err := parseConfig([]string{"-sdr-ssh-password", "secret:pluto-root", "-secrets-file", storePath, "-secrets-key", keyPath}, defaultPersistentConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.sshSecret != "analog" || persistentFromCLI(cfg).SSHPassword != "secret:pluto-root" {
+		t.Fatalf("sshSecret = %q, persisted %q", cfg.sshSecret, persistentFromCLI(cfg).SSHPassword)
+	}
+
+	if code, out := run("", "delete", "-key", keyPath, "-file", storePath, "pluto-root"); code != 0 {
+		t.Fatalf("delete: %d %s", code, out)
+	}
+	if _, err := parseConfig([]string{"-sdr-ssh-password", "secret:pluto-root", "-secrets-file", storePath, "-secrets-key", keyPath}, defaultPersistentConfig()); err == nil {
+		t.Fatal("deleted secret resolved")
+	}
+	if code, _ := run("", "rotate"); code != 2 {
+		t.Fatalf("unknown subcommand code = %d, want 2", code)
+	}
+}
+
+func TestParseConfigSecretReferences(t *testing.T) {
+	t.Setenv("GOSDR_TEST_SSH", "from-env")
+	t.Setenv("GOSDR_TEST_TOKEN", "token")
+	tests := []struct {
+		name      string
+		args      []string
+		wantSSH   string
+		wantToken string
+		redacted  string
+		wantErr   bool
+	}{
+		{"plaintext", []string{"-sdr-ssh-password", "analog", "-admin-token", "t0k"}, "analog", "t0k", secrets.Redacted, false},
+		{"env", []string{"-sdr-ssh-password", "env:GOSDR_TEST_SSH", "-admin-token", "env:GOSDR_TEST_TOKEN"}, "from-env", "token", "env:GOSDR_TEST_SSH", false},
+		{"unset env", []string{"-sdr-ssh-password", "env:GOSDR_TEST_UNSET"}, "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(tt.args, defaultPersistentConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.sshSecret != tt.wantSSH || cfg.adminToken != tt.wantToken || secrets.Redact(cfg.sshPassword) != tt.redacted {
+				t.Fatalf("sshSecret = %q, adminToken = %q, redacted %q", cfg.sshSecret, cfg.adminToken, secrets.Redact(cfg.sshPassword))
+			}
+		})
+	}
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// Soak fault kinds, injected in this order.
+const (
+	faultDrop   = "drop"   // the next RX fails; the tracker is re-initialized
+	faultConfig = "config" // concurrent config update flood through the API
+	faultSlow   = "slow"   // a live-stream subscriber that reads slowly
+)
+
+var errInjectedDrop = errors.New("soak: injected connection drop")
+
+// soakOptions are the soak command's own flags; the tracker settings come
+// from the config file and the arguments after them.
+type soakOptions struct {
+	duration      time.Duration
+	faultInterval time.Duration
+	faults        []string
+	settle        time.Duration
+	stall         time.Duration
+	maxMissed     int
+	maxRecovery   time.Duration
+	maxGoroutines int
+	maxHeapMB     float64
+	floodUpdates  int
+}
+
+// SoakCheck is one verified invariant.
+type SoakCheck struct {
+	Name   string `json:"name"`
+	Pass   bool   `json:"pass"`
+	Detail string `json:"detail"`
+}
+
+// SoakFault records one injected fault.
+type SoakFault struct {
+	Kind   string    `json:"kind"`
+	At     time.Time `json:"at"`
+	Detail string    `json:"detail,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// SoakReport is the machine-readable result of a soak run.
+type SoakReport struct {
+	Pass       bool        `json:"pass"`
+	Backend    string      `json:"backend"`
+	Started    time.Time   `json:"started"`
+	Ended      time.Time   `json:"ended"`
+	DurationS  float64     `json:"durationS"`
+	Iterations int         `json:"iterations"`
+	Missed     int         `json:"missedIterations"` // gaps longer than -stall outside drop recovery
+	MaxGapMs   float64     `json:"maxGapMs"`
+	Recoveries []float64   `json:"recoveriesMs"` // drop to next iteration
+	Goroutines soakGauge   `json:"goroutines"`
+	HeapMB     soakGauge   `json:"heapMB"`
+	Faults     []SoakFault `json:"faults"`
+	Checks     []SoakCheck `json:"checks"`
+	TrackerErr string      `json:"trackerError,omitempty"`
+}
+
+type soakGauge struct {
+	Baseline float64 `json:"baseline"`
+	Peak     float64 `json:"peak"`
+	Final    float64 `json:"final"`
+}
+
+// faultSDR wraps the backend under soak and fails the next RX once a
+// connection drop is injected.
+type faultSDR struct {
+	sdr.SDR
+	drop   atomic.Bool
+	onDrop func(at time.Time) // called when the drop takes effect
+}
+
+func (f *faultSDR) Unwrap() sdr.SDR { return f.SDR }
+
+func (f *faultSDR) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	if f.drop.CompareAndSwap(true, false) {
+		f.onDrop(time.Now())
+		return nil, nil, errInjectedDrop
+	}
+	return f.SDR.RX(ctx)
+}
+
+// soakRun is the state of one soak: the stack under test and the iteration
+// bookkeeping fed from the bus.
+type soakRun struct {
+	opts    soakOptions
+	backend *faultSDR
+	tracker *app.Tracker
+	baseURL string
+	client  *http.Client
+
+	mu         sync.Mutex
+	report     SoakReport
+	trackerErr error     // set when the supervised tracker stopped
+	last       time.Time // last tracker iteration
+	dropAt     time.Time // pending drop, zero once recovered
+}
+
+// runSoakCommand implements "monopulse soak": it runs the tracker, hub and
+// web API for -duration, injecting faults every -fault-interval, and checks
+// that goroutines and heap return to their baseline, the tracker keeps
+// iterating and recovers from dropped connections. Tracker flags follow the
+// soak flags after "--" (e.g. -- -sdr-backend pluto). The JSON report goes
+// to -report. It returns 0 when every check passed, 1 otherwise.
+func runSoakCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts soakOptions
+	fs.DurationVar(&opts.duration, "duration", 10*time.Minute, "How long to run")
+	fs.DurationVar(&opts.faultInterval, "fault-interval", 30*time.Second, "Time between injected faults")
+	faults := fs.String("faults", "drop,config,slow", "Faults to inject in turn (drop|config|slow, comma separated; empty for none)")
+	fs.DurationVar(&opts.settle, "settle", 5*time.Second, "Warm-up before the baseline and quiet time before the final measurement")
+	fs.DurationVar(&opts.stall, "stall", time.Second, "Gap between tracker iterations counted as missed")
+	fs.IntVar(&opts.maxMissed, "max-missed", 0, "Missed-iteration gaps allowed")
+	fs.DurationVar(&opts.maxRecovery, "max-recovery", 10*time.Second, "Longest allowed recovery from an injected drop")
+	fs.IntVar(&opts.maxGoroutines, "max-goroutine-growth", 5, "Goroutines allowed above the baseline at the end")
+	fs.Float64Var(&opts.maxHeapMB, "max-heap-growth", 32, "Heap growth allowed over the baseline at the end (MB)")
+	fs.IntVar(&opts.floodUpdates, "flood-updates", 50, "Config updates per config fault")
+	configPath := fs.String("config", defaultConfigPath(), "Tracker settings file (read only; defaults when missing)")
+	reportPath := fs.String("report", "soak-report.json", "Report file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	for _, kind := range strings.Split(*faults, ",") {
+		switch kind = strings.TrimSpace(kind); kind {
+		case "":
+		case faultDrop, faultConfig, faultSlow:
+			opts.faults = append(opts.faults, kind)
+		default:
+			fmt.Fprintf(stderr, "error: unknown fault %q\n", kind)
+			return 2
+		}
+	}
+	if opts.duration <= 0 || (len(opts.faults) > 0 && opts.faultInterval <= 0) {
+		fmt.Fprintln(stderr, "error: -duration and -fault-interval must be positive")
+		return 2
+	}
+
+	defaults := defaultPersistentConfig()
+	if raw, err := os.ReadFile(*configPath); err == nil {
+		if err := json.Unmarshal(raw, &defaults); err != nil {
+			fmt.Fprintf(stderr, "error: decode %s: %v\n", *configPath, err)
+			return 2
+		}
+	}
+	cfg, err := parseConfig(fs.Args(), defaults)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if *reportPath != "-" {
+		if *reportPath, err = filepath.Abs(*reportPath); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+
+	report, err := soak(cfg, opts, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+	if *reportPath == "-" {
+		_, err = stdout.Write(data.Bytes())
+	} else {
+		err = os.WriteFile(*reportPath, data.Bytes(), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if *reportPath != "-" {
+		verdict := "PASS"
+		if !report.Pass {
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(stdout, "soak %s: %d iterations, %d faults, report %s\n", verdict, report.Iterations, len(report.Faults), *reportPath)
+	}
+	if !report.Pass {
+		return 1
+	}
+	return 0
+}
+
+// soak runs the stack in a scratch working directory, so the config files
+// the hub writes during config floods do not touch the operator's settings.
+func soak(cfg cliConfig, opts soakOptions, logOut io.Writer) (SoakReport, error) {
+	prev, err := os.Getwd()
+	if err != nil {
+		return SoakReport{}, err
+	}
+	dir, err := os.MkdirTemp("", "gosdr-soak-")
+	if err != nil {
+		return SoakReport{}, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		return SoakReport{}, err
+	}
+	defer os.Chdir(prev)
+	if err := saveConfig("config.json", persistentFromCLI(cfg)); err != nil {
+		return SoakReport{}, err
+	}
+
+	logger := logging.New(logging.Warn, logging.Text, logOut).With(logging.Field{Key: "subsystem", Value: "soak"})
+	backend, err := selectBackend(cfg)
+	if err != nil {
+		return SoakReport{}, err
+	}
+	s := &soakRun{opts: opts, backend: &faultSDR{SDR: backend}, client: &http.Client{Timeout: 10 * time.Second}}
+	s.report.Backend = cfg.sdrBackend
+	s.backend.onDrop = func(at time.Time) {
+		s.mu.Lock()
+		s.dropAt = at
+		s.mu.Unlock()
+	}
+
+	hub := telemetry.NewHub(cfg.historyLimit, logger)
+	_ = hub.SetAuditLog("")
+	events := bus.New()
+	events.Forward("", hub)
+	events.Subscribe(bus.TopicTrack, func(bus.Message) { s.observe(time.Now()) })
+	s.tracker = newTracker(cfg, s.backend, events.Publisher(""), logger)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return SoakReport{}, err
+	}
+	s.baseURL = "http://" + ln.Addr().String()
+	server := &http.Server{Handler: telemetry.NewWebServer(ln.Addr().String(), hub, backend, logger).Handler()}
+	go server.Serve(ln)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.tracker.Init(ctx); err != nil {
+		return SoakReport{}, fmt.Errorf("init tracker: %w", err)
+	}
+	trackerDone := make(chan struct{})
+	go func() {
+		err := s.superviseTracker(ctx)
+		if err == nil && ctx.Err() == nil {
+			err = errors.New("tracker stopped")
+		}
+		s.mu.Lock()
+		s.trackerErr = err
+		s.mu.Unlock()
+		close(trackerDone)
+	}()
+
+	s.report.Started = time.Now()
+	if s.wait(opts.settle, trackerDone) {
+		goroutines, heap := resourceUsage()
+		s.report.Goroutines = soakGauge{Baseline: goroutines, Peak: goroutines}
+		s.report.HeapMB = soakGauge{Baseline: heap, Peak: heap}
+		s.injectFaults(trackerDone)
+		// Idle keep-alive connections of the fault clients are not leaks.
+		s.client.CloseIdleConnections()
+		if s.wait(opts.settle, trackerDone) {
+			s.report.Goroutines.Final, s.report.HeapMB.Final = resourceUsage()
+		}
+	}
+	report := s.finish()
+	cancel()
+	<-trackerDone
+	return report, nil
+}
+
+// superviseTracker runs the tracker, re-initializing it after injected drops
+// the way an operator restarting a failed link would. Other errors end it.
+func (s *soakRun) superviseTracker(ctx context.Context) error {
+	for {
+		err := s.tracker.Run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !errors.Is(err, errInjectedDrop) {
+			return err
+		}
+		if err := s.tracker.Init(ctx); err != nil {
+			return fmt.Errorf("re-init after drop: %w", err)
+		}
+	}
+}
+
+// observe records one tracker iteration.
+func (s *soakRun) observe(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report.Iterations++
+	if !s.dropAt.IsZero() {
+		s.report.Recoveries = append(s.report.Recoveries, ms(now.Sub(s.dropAt)))
+		s.dropAt = time.Time{}
+	} else if !s.last.IsZero() {
+		s.recordGap(now.Sub(s.last))
+	}
+	s.last = now
+}
+
+// recordGap counts a gap between iterations. s.mu must be held.
+func (s *soakRun) recordGap(gap time.Duration) {
+	s.report.MaxGapMs = max(s.report.MaxGapMs, ms(gap))
+	if gap > s.opts.stall {
+		s.report.Missed++
+	}
+}
+
+// wait sleeps for d while sampling resource usage, and reports false when
+// the tracker stopped.
+func (s *soakRun) wait(d time.Duration, trackerDone <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case <-trackerDone:
+			return false
+		case <-ticker.C:
+			goroutines, heap := resourceUsage()
+			s.mu.Lock()
+			s.report.Goroutines.Peak = max(s.report.Goroutines.Peak, goroutines)
+			s.report.HeapMB.Peak = max(s.report.HeapMB.Peak, heap)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// injectFaults runs until -duration has passed since the start, injecting
+// the configured faults in turn. Drops are skipped once the run ends within
+// -max-recovery, so every drop has the full budget to recover.
+func (s *soakRun) injectFaults(trackerDone <-chan struct{}) {
+	end := s.report.Started.Add(s.opts.duration - s.opts.settle)
+	for i := 0; time.Until(end) > 0; i++ {
+		step := min(s.opts.faultInterval, time.Until(end))
+		if len(s.opts.faults) == 0 {
+			step = time.Until(end)
+		}
+		if !s.wait(step, trackerDone) {
+			return
+		}
+		if len(s.opts.faults) == 0 || time.Until(end) <= 0 {
+			continue
+		}
+		kind := s.opts.faults[i%len(s.opts.faults)]
+		if kind == faultDrop && time.Until(end)+s.opts.settle < s.opts.maxRecovery {
+			// Too close to the end for a drop to recover within
+			// -max-recovery.
+			continue
+		}
+		fault := SoakFault{Kind: kind, At: time.Now()}
+		var err error
+		switch fault.Kind {
+		case faultDrop:
+			s.backend.drop.Store(true)
+			fault.Detail = "next RX fails"
+		case faultConfig:
+			fault.Detail, err = s.floodConfig()
+		case faultSlow:
+			fault.Detail, err = s.slowSubscriber(min(s.opts.faultInterval/2, 5*time.Second))
+		}
+		if err != nil {
+			fault.Error = err.Error()
+		}
+		s.report.Faults = append(s.report.Faults, fault)
+	}
+}
+
+// floodConfig sends -flood-updates config changes from two concurrent
+// clients through the API. Conflicts (409) are expected; any other failure
+// is an error.
+func (s *soakRun) floodConfig() (string, error) {
+	var applied, conflicts atomic.Int64
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for c := 0; c < 2; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := c; i < s.opts.floodUpdates; i += 2 {
+				status, err := s.updateConfig(i % 2)
+				switch {
+				case err != nil:
+					errs <- err
+					return
+				case status == http.StatusOK:
+					applied.Add(1)
+				case status == http.StatusConflict:
+					conflicts.Add(1)
+				default:
+					errs <- fmt.Errorf("config update: status %d", status)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	detail := fmt.Sprintf("%d updates applied, %d conflicts", applied.Load(), conflicts.Load())
+	return detail, <-errs
+}
+
+// updateConfig reads the config and writes it back with the history limit
+// nudged by delta.
+func (s *soakRun) updateConfig(delta int) (int, error) {
+	resp, err := s.client.Get(s.baseURL + "/api/config")
+	if err != nil {
+		return 0, err
+	}
+	var doc telemetry.ConfigDocument
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	resp.Body.Close()
+	if err != nil {
+		return 0, fmt.Errorf("read config: %w", err)
+	}
+	body, _ := json.Marshal(map[string]any{"revision": doc.Revision, "historyLimit": max(doc.HistoryLimit-1+2*delta, 1)})
+	resp, err = s.client.Post(s.baseURL+"/api/config/update", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// slowSubscriber follows the live stream for d, reading a few bytes at a
+// time, then hangs up. The tracker must not slow down meanwhile.
+func (s *soakRun) slowSubscriber(d time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/live", nil)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReaderSize(resp.Body, 16)
+	buf := make([]byte, 16)
+	read := 0
+	for ctx.Err() == nil {
+		n, err := reader.Read(buf)
+		read += n
+		if err != nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Sprintf("read %d bytes in %s", read, d), nil
+}
+
+// finish ends the measurement and evaluates the checks.
+func (s *soakRun) finish() SoakReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &s.report
+	r.Ended = time.Now()
+	r.DurationS = r.Ended.Sub(r.Started).Seconds()
+	if s.trackerErr != nil {
+		r.TrackerErr = s.trackerErr.Error()
+	}
+	if !s.last.IsZero() && s.dropAt.IsZero() {
+		s.recordGap(r.Ended.Sub(s.last))
+	}
+
+	var faultErrs []string
+	for _, f := range r.Faults {
+		if f.Error != "" {
+			faultErrs = append(faultErrs, f.Kind+": "+f.Error)
+		}
+	}
+	slowest := 0.0
+	for _, rec := range r.Recoveries {
+		slowest = max(slowest, rec)
+	}
+	recovered := slowest <= ms(s.opts.maxRecovery)
+	recoveryDetail := fmt.Sprintf("%d recoveries, slowest %.0f ms (max %s)", len(r.Recoveries), slowest, s.opts.maxRecovery)
+	if !s.dropAt.IsZero() {
+		// A drop still pending at the end fails only once it has taken
+		// longer than a recovery may.
+		pending := r.Ended.Sub(s.dropAt)
+		recovered = recovered && pending <= s.opts.maxRecovery
+		recoveryDetail += fmt.Sprintf(", 1 pending for %.0f ms", ms(pending))
+	}
+	measured := r.Goroutines.Final > 0
+	r.Checks = []SoakCheck{
+		{Name: "tracker", Pass: r.TrackerErr == "" && r.Iterations > 0, Detail: strings.TrimSpace(fmt.Sprintf("%d iterations %s", r.Iterations, r.TrackerErr))},
+		{Name: "missed_iterations", Pass: r.Missed <= s.opts.maxMissed, Detail: fmt.Sprintf("%d gaps over %s (max %d), longest %.0f ms", r.Missed, s.opts.stall, s.opts.maxMissed, r.MaxGapMs)},
+		{Name: "drop_recovery", Pass: recovered, Detail: recoveryDetail},
+		{Name: "goroutines", Pass: measured && r.Goroutines.Final-r.Goroutines.Baseline <= float64(s.opts.maxGoroutines), Detail: fmt.Sprintf("%.0f -> %.0f (max growth %d)", r.Goroutines.Baseline, r.Goroutines.Final, s.opts.maxGoroutines)},
+		{Name: "heap", Pass: measured && r.HeapMB.Final-r.HeapMB.Baseline <= s.opts.maxHeapMB, Detail: fmt.Sprintf("%.1f -> %.1f MB (max growth %g MB)", r.HeapMB.Baseline, r.HeapMB.Final, s.opts.maxHeapMB)},
+		{Name: "faults", Pass: len(faultErrs) == 0, Detail: strings.Join(faultErrs, "; ")},
+	}
+	r.Pass = true
+	for _, c := range r.Checks {
+		r.Pass = r.Pass && c.Pass
+	}
+	return *r
+}
+
+// resourceUsage returns the goroutine count and the live heap in MB after a
+// collection.
+func resourceUsage() (goroutines, heapMB float64) {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return float64(runtime.NumGoroutine()), float64(mem.HeapAlloc) / (1 << 20)
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSoakCommandFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown fault", args: []string{"-faults", "drop,meteor"}, wantErr: `unknown fault "meteor"`},
+		{name: "zero duration", args: []string{"-duration", "0"}, wantErr: "must be positive"},
+		{name: "bad tracker flag", args: []string{"-config", "none.json", "-duration", "1s", "--", "-track-scorer", "x", "-score-weights", "1"}, wantErr: "score weights"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runSoakCommand(tt.args, &stdout, &stderr); code != 2 {
+				t.Fatalf("code = %d, want 2 (stderr %q)", code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunSoakCommandMock(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	report := filepath.Join(dir, "soak.json")
+	args := []string{
+		"-config", filepath.Join(dir, "none.json"),
+		"-duration", "4s", "-settle", "500ms", "-fault-interval", "300ms", "-max-recovery", "2s",
+		"-flood-updates", "10", "-report", report,
+		"--", "-sdr-backend", "mock",
+	}
+	var stdout, stderr bytes.Buffer
+	code := runSoakCommand(args, &stdout, &stderr)
+	raw, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("read report: %v (stderr %q)", err, stderr.String())
+	}
+	var got SoakReport
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if code != 0 || !got.Pass {
+		t.Fatalf("soak failed (code %d): %s", code, raw)
+	}
+	kinds := map[string]bool{}
+	for _, f := range got.Faults {
+		kinds[f.Kind] = true
+	}
+	if !kinds[faultDrop] || !kinds[faultConfig] || !kinds[faultSlow] || len(got.Recoveries) == 0 {
+		t.Fatalf("expected every fault kind and a drop recovery, got %s", raw)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.json")); !os.IsNotExist(err) {
+		t.Fatalf("soak must not write config.json to the working directory: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "soak PASS") {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/storage"
+)
+
+// retentionRule is one entry of the "retention" config map. For a built-in
+// artifact (captures, recordings, audit) it replaces the policy set by
+// -retention-max-age and -retention-max-mb (-audit-max-age and -audit-max-mb
+// for the audit log); any other name adds an artifact at Path, such as a
+// directory of exports.
+type retentionRule struct {
+	Path   string `json:"path,omitempty"`
+	Kind   string `json:"kind,omitempty"` // dir (default) or log
+	MaxAge string `json:"max_age,omitempty"`
+	MaxMB  int    `json:"max_mb,omitempty"`
+}
+
+// retentionRules maps artifact names to retention rules.
+type retentionRules map[string]retentionRule
+
+// storageArtifacts lists the persistent artifacts of cfg with their
+// retention policies. The state journal, IQ rings and calibration store bound
+// their own size and are reported only. auditLock, when set, is the lock the
+// audit log is appended under (see telemetry.Hub.AuditLocker).
+func storageArtifacts(cfg cliConfig, devices []deviceConfig, auditLock sync.Locker) ([]storage.Artifact, error) {
+	global := storage.Policy{MaxAge: cfg.retentionAge, MaxBytes: int64(cfg.retentionMB) << 20}
+	audit := storage.Policy{MaxAge: cfg.auditMaxAge, MaxBytes: int64(cfg.auditMaxMB) << 20}
+	captureDir := cfg.captureDir
+	if captureDir == "" {
+		captureDir = "captures"
+	}
+	artifacts := []storage.Artifact{
+		{Name: "captures", Path: captureDir, Kind: storage.KindDir, Policy: global},
+		{Name: "recordings", Path: cfg.recordDir, Kind: storage.KindDir, Policy: global},
+		{Name: "audit", Path: cfg.auditLog, Kind: storage.KindLog, Policy: audit, Lock: auditLock},
+		{Name: "journal", Path: cfg.journal, Kind: storage.KindFixed},
+		{Name: "calibration", Path: cfg.calibration, Kind: storage.KindFixed},
+	}
+	for _, dev := range devices {
+		name := "ring"
+		if dev.ID != "" {
+			name += "-" + dev.ID
+		}
+		artifacts = append(artifacts, storage.Artifact{Name: name, Path: cfg.forDevice(dev).ringFile, Kind: storage.KindFixed})
+	}
+
+	names := make([]string, 0, len(cfg.retention))
+	for name := range cfg.retention {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rule := cfg.retention[name]
+		policy, err := rule.policy()
+		if err != nil {
+			return nil, fmt.Errorf("retention %q: %w", name, err)
+		}
+		builtin := false
+		for i := range artifacts {
+			if artifacts[i].Name != name {
+				continue
+			}
+			if artifacts[i].Kind == storage.KindFixed {
+				return nil, fmt.Errorf("retention %q: artifact bounds its own size", name)
+			}
+			artifacts[i].Policy, builtin = policy, true
+		}
+		if builtin {
+			continue
+		}
+		kind := rule.Kind
+		if kind == "" {
+			kind = storage.KindDir
+		}
+		if rule.Path == "" || (kind != storage.KindDir && kind != storage.KindLog) {
+			return nil, fmt.Errorf("retention %q: need a path and kind dir or log", name)
+		}
+		artifacts = append(artifacts, storage.Artifact{Name: name, Path: rule.Path, Kind: kind, Policy: policy})
+	}
+	return artifacts, nil
+}
+
+// policy converts the rule to a storage policy.
+func (r retentionRule) policy() (storage.Policy, error) {
+	var p storage.Policy
+	if r.MaxAge != "" {
+		age, err := time.ParseDuration(r.MaxAge)
+		if err != nil {
+			return p, fmt.Errorf("max_age: %w", err)
+		}
+		p.MaxAge = age
+	}
+	if r.MaxMB < 0 || p.MaxAge < 0 {
+		return p, fmt.Errorf("max_age and max_mb must not be negative")
+	}
+	p.MaxBytes = int64(r.MaxMB) << 20
+	return p, nil
+}
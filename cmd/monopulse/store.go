@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// runStoreCommand implements "monopulse store <subcommand>", inspecting the
+// telemetry stores selected by -store and copying data between them. It
+// returns the exit code.
+func runStoreCommand(args []string, stdout, stderr io.Writer) int {
+	usage := func() int {
+		fmt.Fprintln(stderr, "usage: monopulse store info dsn")
+		fmt.Fprintln(stderr, "       monopulse store migrate -from dsn -to dsn [-since t] [-until t] [-chunk 24h]")
+		fmt.Fprintf(stderr, "stores: %s\n", strings.Join(telemetry.StoreSchemes(), ", "))
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("store "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", "", "Source store, e.g. journal:state.journal")
+	to := fs.String("to", "", "Destination store, e.g. file:/var/lib/gosdr/store")
+	since := fs.String("since", "", "Copy from this RFC 3339 time or duration ago (default the oldest entry)")
+	until := fs.String("until", "", "Copy up to this RFC 3339 time or duration ago (default the newest entry)")
+	chunk := fs.Duration("chunk", 24*time.Hour, "Time range copied at once")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	switch {
+	case cmd == "info" && fs.NArg() == 1:
+		if err := storeInfo(fs.Arg(0), stdout); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		return 0
+	case cmd == "migrate" && fs.NArg() == 0 && *from != "" && *to != "":
+	default:
+		return usage()
+	}
+	now := time.Now()
+	var start, end time.Time
+	for _, bound := range []struct {
+		flag, raw string
+		t         *time.Time
+	}{{"since", *since, &start}, {"until", *until, &end}} {
+		if bound.raw == "" {
+			continue
+		}
+		t, err := parseTimeOrAgo(bound.raw, now)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: -%s: %v\n", bound.flag, err)
+			return 2
+		}
+		*bound.t = t
+	}
+	if *chunk <= 0 {
+		fmt.Fprintln(stderr, "error: -chunk: must be positive")
+		return 2
+	}
+
+	samples, events, err := storeMigrate(*from, *to, start, end, *chunk)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v (copied %d samples and %d events)\n", err, samples, events)
+		return 1
+	}
+	fmt.Fprintf(stdout, "copied %d samples and %d events to %s\n", samples, events, *to)
+	return 0
+}
+
+// parseTimeOrAgo parses an RFC 3339 time or a duration before now.
+func parseTimeOrAgo(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", raw)
+	}
+	return now.Add(-d), nil
+}
+
+// storeInfo prints the time range held by the store dsn.
+func storeInfo(dsn string, stdout io.Writer) (err error) {
+	s, err := telemetry.OpenStore(dsn)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, s.Close()) }()
+	first, last, err := s.Span()
+	if err != nil {
+		return err
+	}
+	if first.IsZero() {
+		fmt.Fprintf(stdout, "%s: empty\n", dsn)
+		return nil
+	}
+	fmt.Fprintf(stdout, "%s: %s to %s\n", dsn, first.UTC().Format(time.RFC3339), last.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// storeMigrate copies the samples and events of from in [since, until) to
+// to.
+func storeMigrate(from, to string, since, until time.Time, chunk time.Duration) (samples, events int, err error) {
+	src, err := telemetry.OpenStore(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { err = errors.Join(err, src.Close()) }()
+	dst, err := telemetry.OpenStore(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { err = errors.Join(err, dst.Close()) }()
+	return telemetry.MigrateStore(dst, src, since, until, chunk)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestRunStoreCommand(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := runStoreCommand(args, &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	src, err := telemetry.NewFileStore(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_ = src.Append([]telemetry.MultiTrackSample{
+		{Timestamp: start, Tracks: []telemetry.TrackSample{{ID: "a"}}},
+		{Timestamp: start.Add(36 * time.Hour), Tracks: []telemetry.TrackSample{{ID: "a"}}},
+	}, []telemetry.DiagnosticEvent{{Timestamp: start, Level: "info", Message: "x"}})
+	_ = src.Close()
+
+	if code, out := run("info", "file:"+filepath.Join(dir, "src")); code != 0 || !strings.Contains(out, "2024-05-01T12:00:00Z to 2024-05-03T00:00:00Z") {
+		t.Fatalf("info: %d %s", code, out)
+	}
+	if code, _ := run("migrate", "-from", "file:"+filepath.Join(dir, "src")); code != 2 {
+		t.Fatal("migrate without -to accepted")
+	}
+	if code, out := run("migrate", "-from", "file:"+filepath.Join(dir, "src"), "-to", "file:"+filepath.Join(dir, "dst"), "-until", "2024-05-02T00:00:00Z"); code != 0 || !strings.Contains(out, "copied 1 samples and 1 events") {
+		t.Fatalf("migrate: %d %s", code, out)
+	}
+	if code, out := run("migrate", "-from", "nosuch:x", "-to", "memory:"); code != 1 || !strings.Contains(out, "unknown store scheme") {
+		t.Fatalf("unknown scheme: %d %s", code, out)
+	}
+}
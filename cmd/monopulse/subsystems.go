@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// Subsystems that -disable can switch off. Each is enforced where it is
+// constructed, so a disabled subsystem never opens its sockets, files or
+// sessions.
+const (
+	subsystemTX        = "tx"        // transmit buffers, TX gain, loopback and scheduled TX
+	subsystemSSH       = "ssh"       // SSH sysfs fallback for attribute writes
+	subsystemWeb       = "web"       // telemetry hub, web UI/API and aggregator
+	subsystemAdmin     = "admin"     // admin endpoints (IIOD console, BIST) and debug injection
+	subsystemRecording = "recording" // IQ ring file, SigMF recording, captures and state journal
+)
+
+var subsystemNames = []string{subsystemTX, subsystemSSH, subsystemWeb, subsystemAdmin, subsystemRecording}
+
+// parseDisabled parses a comma-separated list of subsystem names, returning
+// them sorted and without duplicates.
+func parseDisabled(list string) ([]string, error) {
+	var out []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(out, name) {
+			continue
+		}
+		if !slices.Contains(subsystemNames, name) {
+			return nil, fmt.Errorf("unknown subsystem %q in -disable (have %s)", name, strings.Join(subsystemNames, ", "))
+		}
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// enabled reports whether subsystem was not disabled.
+func (c cliConfig) enabled(subsystem string) bool {
+	return !slices.Contains(c.disabled, subsystem)
+}
+
+// ignoredSettings lists configured settings that have no effect because
+// their subsystem is disabled.
+func ignoredSettings(cfg cliConfig) []string {
+	var out []string
+	note := func(subsystem, setting string, set bool) {
+		if set && !cfg.enabled(subsystem) {
+			out = append(out, setting+" ("+subsystem+" disabled)")
+		}
+	}
+	note(subsystemTX, "loopback", cfg.loopback)
+	note(subsystemSSH, "ssh_host", cfg.sshHost != "")
+	note(subsystemWeb, "web_addr", cfg.webAddr != "")
+	note(subsystemWeb, "aggregator_listen", cfg.aggregatorListen != "")
+	note(subsystemAdmin, "admin_token", cfg.adminToken != "")
+	note(subsystemAdmin, "debug_inject", cfg.debugInject)
+	note(subsystemAdmin, "bist", cfg.bist)
+	note(subsystemRecording, "ring_file", cfg.ringFile != "")
+	note(subsystemRecording, "record", cfg.recordDir != "")
+	note(subsystemRecording, "captures", len(cfg.captures) > 0)
+	note(subsystemRecording, "journal", cfg.journal != "")
+	return out
+}
+
+// logSubsystems reports which subsystems run. With any disabled it logs at
+// warn level, so the restriction shows at the default log level.
+func logSubsystems(logger logging.Logger, cfg cliConfig) {
+	var active []string
+	for _, name := range subsystemNames {
+		if cfg.enabled(name) {
+			active = append(active, name)
+		}
+	}
+	fields := []logging.Field{
+		{Key: "active", Value: strings.Join(active, ",")},
+		{Key: "disabled", Value: strings.Join(cfg.disabled, ",")},
+	}
+	if len(cfg.disabled) == 0 {
+		logger.Info("subsystems", fields...)
+		return
+	}
+	if ignored := ignoredSettings(cfg); len(ignored) > 0 {
+		fields = append(fields, logging.Field{Key: "ignored", Value: strings.Join(ignored, "; ")})
+	}
+	logger.Warn("subsystems disabled", fields...)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// shutdownGrace bounds how long Stop waits for components to return after
+// their context was canceled.
+const shutdownGrace = 5 * time.Second
+
+// supervisor owns the long-lived components of a run (web server, control
+// socket, background loops) under one context, like an errgroup: the first
+// component that fails cancels the context of all others, and Stop cancels
+// them, waits for them to return and reports their errors. A component that
+// does not return within shutdownGrace is reported by name instead of being
+// left running unnoticed.
+type supervisor struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	logger logging.Logger
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	errs    []error
+	running map[string]int // component name → instances still running
+}
+
+// newSupervisor returns a supervisor whose components run under a child of
+// parent.
+func newSupervisor(parent context.Context, logger logging.Logger) *supervisor {
+	ctx, cancel := context.WithCancelCause(parent)
+	return &supervisor{ctx: ctx, cancel: cancel, logger: logger, running: make(map[string]int)}
+}
+
+// Context is canceled when a component fails or Stop is called; everything
+// that should stop with the components, such as the trackers, runs under it.
+func (s *supervisor) Context() context.Context {
+	return s.ctx
+}
+
+// Go runs fn as the component name. An error other than the cancellation of
+// its context, or a panic, fails the run: it is logged, kept for Stop and
+// cancels the other components.
+func (s *supervisor) Go(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	s.running[name]++
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := s.call(fn)
+		s.mu.Lock()
+		s.running[name]--
+		s.mu.Unlock()
+		if err == nil || (errors.Is(err, context.Canceled) && s.ctx.Err() != nil) {
+			return
+		}
+		err = fmt.Errorf("%s: %w", name, err)
+		s.logger.Error("component failed", logging.Field{Key: "component", Value: name}, logging.Field{Key: "error", Value: err})
+		s.mu.Lock()
+		s.errs = append(s.errs, err)
+		s.mu.Unlock()
+		s.cancel(err)
+	}()
+}
+
+// GoLoop runs fn, a loop that only returns when its context ends, as the
+// component name.
+func (s *supervisor) GoLoop(name string, fn func(ctx context.Context)) {
+	s.Go(name, func(ctx context.Context) error {
+		fn(ctx)
+		return nil
+	})
+}
+
+// call runs fn, turning a panic into an error so one component cannot take
+// the process down without the others shutting down first.
+func (s *supervisor) call(fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn(s.ctx)
+}
+
+// Err returns the errors of the components that failed so far.
+func (s *supervisor) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.errs...)
+}
+
+// Stop cancels the components and waits up to shutdownGrace for them to
+// return. It returns the component failures and, if some did not return in
+// time, an error naming them.
+func (s *supervisor) Stop() error {
+	return s.stop(shutdownGrace)
+}
+
+func (s *supervisor) stop(grace time.Duration) error {
+	s.cancel(context.Canceled)
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return s.Err()
+	case <-time.After(grace):
+	}
+	s.mu.Lock()
+	var stuck []string
+	for name, n := range s.running {
+		if n > 0 {
+			stuck = append(stuck, name)
+		}
+	}
+	s.mu.Unlock()
+	slices.Sort(stuck)
+	return errors.Join(s.Err(), fmt.Errorf("components still running after %v: %v", grace, stuck))
+}
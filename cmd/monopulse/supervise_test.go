@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+func TestSupervisorFailureCancelsComponents(t *testing.T) {
+	sup := newSupervisor(context.Background(), logging.New(logging.Error, logging.Text, io.Discard))
+	stopped := make(chan struct{})
+	sup.GoLoop("loop", func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	sup.Go("web server", func(ctx context.Context) error {
+		return errors.New("address already in use")
+	})
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a failing component did not cancel the others")
+	}
+	err := sup.Stop()
+	if err == nil || !strings.Contains(err.Error(), "web server: address already in use") {
+		t.Fatalf("Stop = %v", err)
+	}
+	if cause := context.Cause(sup.Context()); cause == nil || !strings.Contains(cause.Error(), "web server") {
+		t.Fatalf("cause = %v", cause)
+	}
+}
+
+func TestSupervisorStop(t *testing.T) {
+	sup := newSupervisor(context.Background(), logging.New(logging.Error, logging.Text, io.Discard))
+	sup.Go("server", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	sup.Go("panics", func(ctx context.Context) error {
+		<-ctx.Done()
+		panic("boom")
+	})
+	release := make(chan struct{})
+	defer close(release)
+	sup.GoLoop("stuck", func(context.Context) { <-release })
+
+	err := sup.stop(50 * time.Millisecond)
+	if err == nil {
+		t.Fatal("Stop = nil")
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "server") || !strings.Contains(msg, "panics: panic: boom") || !strings.Contains(msg, "still running after 50ms: [stuck]") {
+		t.Fatalf("Stop = %v", err)
+	}
+}
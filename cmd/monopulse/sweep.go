@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// frequencySweep returns the sweep configured with the -sweep-* flags.
+func (cfg cliConfig) frequencySweep() app.FrequencySweep {
+	return app.FrequencySweep{StartHz: cfg.sweepStart, StopHz: cfg.sweepStop, StepHz: cfg.sweepStep, Dwell: cfg.sweepDwell, ThresholdDB: cfg.sweepThreshold}
+}
+
+// newScanners builds a scanner per device on the backends the trackers
+// initialized, publishing sweeps on the device's bus source.
+func newScanners(cfg cliConfig, devices []deviceConfig, backends []sdr.SDR, events *bus.Bus, logger logging.Logger) []*app.Scanner {
+	scanners := make([]*app.Scanner, len(devices))
+	for i, dev := range devices {
+		devCfg := cfg.forDevice(dev)
+		scannerLogger := logger.With(logging.Field{Key: "subsystem", Value: "scanner"})
+		if dev.ID != "" {
+			scannerLogger = scannerLogger.With(logging.Field{Key: "device", Value: dev.ID})
+		}
+		scanners[i] = app.NewScanner(backends[i], events.Publisher(dev.ID), scannerLogger, app.Config{
+			Clock:      cfg.clock,
+			SampleRate: devCfg.sampleRate,
+			RxLO:       devCfg.rxLO,
+			NumSamples: devCfg.numSamples,
+		})
+	}
+	return scanners
+}
+
+// runScanners surveys the spectrum with all scanners concurrently until ctx
+// is done. The first failure cancels the others and is returned.
+func runScanners(ctx context.Context, scanners []*app.Scanner, sweep app.FrequencySweep) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(scanners))
+	var wg sync.WaitGroup
+	for _, scanner := range scanners {
+		wg.Add(1)
+		go func(s *app.Scanner) {
+			defer wg.Done()
+			if err := s.Run(ctx, sweep); err != nil && !errors.Is(err, context.Canceled) {
+				errCh <- err
+				cancel()
+			}
+		}(scanner)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+// sweepAndLock sweeps once per device and retunes its tracker so the
+// strongest signal found lands on the tone offset. Devices without a
+// detection keep their configured RX LO.
+func sweepAndLock(ctx context.Context, cfg cliConfig, devices []deviceConfig, scanners []*app.Scanner, trackers []*app.Tracker, logger logging.Logger) error {
+	for i, scanner := range scanners {
+		id := devices[i].ID
+		logger.Info("sweeping for a signal", logging.Field{Key: "device", Value: id}, logging.Field{Key: "start_hz", Value: cfg.sweepStart}, logging.Field{Key: "stop_hz", Value: cfg.sweepStop})
+		result, err := scanner.Sweep(ctx, cfg.frequencySweep())
+		if err != nil {
+			return fmt.Errorf("device %q: %w", id, err)
+		}
+		if len(result.Detections) == 0 {
+			logger.Warn("no signal found; keeping the configured RX LO", logging.Field{Key: "device", Value: id})
+			continue
+		}
+		strongest := result.Detections[0]
+		rxLO := strongest.FrequencyHz - cfg.forDevice(devices[i]).toneOffset
+		fmt.Printf("device %q: strongest signal %.0f Hz (%.1f dBFS, SNR %.1f dB), RX LO %.0f Hz\n", id, strongest.FrequencyHz, strongest.PowerDBFS, strongest.SNRDB, rxLO)
+		if err := trackers[i].Retune(ctx, rxLO); err != nil {
+			return fmt.Errorf("device %q: %w", id, err)
+		}
+	}
+	return nil
+}
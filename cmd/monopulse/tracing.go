@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/otlp"
+	"github.com/rjboer/GoSDR/internal/secrets"
+	"github.com/rjboer/GoSDR/internal/tsdb"
+)
+
+// newTracing builds the OTLP exporter of the -otlp flags. The headers may
+// be a secrets reference, since collectors usually want an API key.
+func newTracing(cfg cliConfig, resolver *secrets.Resolver) (*otlp.Exporter, error) {
+	raw, err := resolver.Resolve(cfg.otlpHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("OTLP headers: %w", err)
+	}
+	headers, err := otlp.ParseHeaders(raw)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.otlpInterval <= 0 {
+		return nil, fmt.Errorf("otlp-interval must be positive, got %v", cfg.otlpInterval)
+	}
+	resource := []otlp.Attr{otlp.String("service.version", buildinfo.Get().Version)}
+	if host, err := os.Hostname(); err == nil {
+		resource = append(resource, otlp.String("host.name", host))
+	}
+	return otlp.New(otlp.Config{
+		Endpoint:    cfg.otlpEndpoint,
+		Headers:     headers,
+		ServiceName: cfg.otlpService,
+		Resource:    resource,
+		Interval:    cfg.otlpInterval,
+	})
+}
+
+// newTSDB builds the writer of the "tsdb" config. The token may be a
+// secrets reference.
+func newTSDB(c tsdb.Config, resolver *secrets.Resolver) (*tsdb.Writer, error) {
+	token, err := resolver.Resolve(c.Token)
+	if err != nil {
+		return nil, fmt.Errorf("tsdb token: %w", err)
+	}
+	c.Token = token
+	return tsdb.New(c, nil)
+}
+
+// flushTracing exports what was recorded since the last export before the
+// process exits.
+func flushTracing(exporter *otlp.Exporter, logger logging.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := exporter.Flush(ctx); err != nil {
+		logger.Warn("final OTLP export", logging.Field{Key: "error", Value: err})
+	}
+}
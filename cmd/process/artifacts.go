@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// trackSummary is one entry of the <name>.tracks.json track database.
+type trackSummary struct {
+	ID         string    `json:"id"`
+	First      time.Time `json:"first"`
+	Last       time.Time `json:"last"`
+	Samples    int       `json:"samples"`
+	MeanAngle  float64   `json:"meanAngleDeg"`
+	MaxSNR     float64   `json:"maxSnr"`
+	FinalState string    `json:"finalLockState"`
+}
+
+// artifactWriter is a telemetry.Reporter that stores a run as <base>.csv (one
+// row per track and sample), <base>.jsonl (one MultiTrackSample per line, as
+// served by /api/history) and, on Close, <base>.tracks.json (one summary per
+// track). Samples are stamped with clock, the recording time.
+type artifactWriter struct {
+	base    string
+	clock   func() time.Time
+	csvFile *os.File
+	csv     *csv.Writer
+	jsonl   *os.File
+	lines   *bufio.Writer
+	samples int
+	tracks  map[string]*trackSummary
+}
+
+func newArtifactWriter(base string, clock func() time.Time) (*artifactWriter, error) {
+	csvFile, err := os.Create(base + ".csv")
+	if err != nil {
+		return nil, err
+	}
+	jsonl, err := os.Create(base + ".jsonl")
+	if err != nil {
+		csvFile.Close()
+		return nil, err
+	}
+	w := &artifactWriter{
+		base:    base,
+		clock:   clock,
+		csvFile: csvFile,
+		csv:     csv.NewWriter(csvFile),
+		jsonl:   jsonl,
+		lines:   bufio.NewWriter(jsonl),
+		tracks:  make(map[string]*trackSummary),
+	}
+	w.csv.Write([]string{"timestamp", "track", "angle_deg", "peak", "snr", "confidence", "lock_state"})
+	return w, nil
+}
+
+// Report records a single-track sample.
+func (w *artifactWriter) Report(angleDeg float64, peak float64, snr float64, confidence float64, state telemetry.LockState, debug *telemetry.DebugInfo) {
+	w.ReportMultiTrack(telemetry.MultiTrackSample{Tracks: []telemetry.TrackSample{{
+		AngleDeg:   angleDeg,
+		Peak:       peak,
+		SNR:        snr,
+		Confidence: confidence,
+		LockState:  state,
+		Debug:      debug,
+	}}})
+}
+
+// ReportMultiTrack records sample in all three artifacts.
+func (w *artifactWriter) ReportMultiTrack(sample telemetry.MultiTrackSample) {
+	if sample.Timestamp.IsZero() {
+		sample.Timestamp = w.clock()
+	}
+	w.samples++
+	sample.Seq = uint64(w.samples)
+	stamp := sample.Timestamp.UTC().Format(time.RFC3339Nano)
+	for _, track := range sample.Tracks {
+		w.csv.Write([]string{
+			stamp,
+			track.ID,
+			strconv.FormatFloat(track.AngleDeg, 'f', 3, 64),
+			strconv.FormatFloat(track.Peak, 'f', 3, 64),
+			strconv.FormatFloat(track.SNR, 'f', 3, 64),
+			strconv.FormatFloat(track.Confidence, 'f', 3, 64),
+			string(track.LockState),
+		})
+		w.summarize(track, sample.Timestamp)
+	}
+	data, _ := json.Marshal(sample)
+	w.lines.Write(append(data, '\n'))
+}
+
+func (w *artifactWriter) summarize(track telemetry.TrackSample, at time.Time) {
+	s, ok := w.tracks[track.ID]
+	if !ok {
+		s = &trackSummary{ID: track.ID, First: at, MaxSNR: track.SNR}
+		w.tracks[track.ID] = s
+	}
+	s.Samples++
+	s.MeanAngle += (track.AngleDeg - s.MeanAngle) / float64(s.Samples)
+	if track.SNR > s.MaxSNR {
+		s.MaxSNR = track.SNR
+	}
+	s.Last = at
+	s.FinalState = string(track.LockState)
+}
+
+// Close flushes the CSV and JSON lines files and writes the track database.
+func (w *artifactWriter) Close() error {
+	w.csv.Flush()
+	errs := []error{w.csv.Error(), w.csvFile.Close(), w.lines.Flush(), w.jsonl.Close()}
+
+	summaries := make([]*trackSummary, 0, len(w.tracks))
+	for _, s := range w.tracks {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+	data, err := json.MarshalIndent(map[string]any{"build": buildinfo.Get(), "samples": w.samples, "tracks": summaries}, "", "  ")
+	if err == nil {
+		err = os.WriteFile(w.base+".tracks.json", append(data, '\n'), 0o644)
+	}
+	return errors.Join(append(errs, err)...)
+}
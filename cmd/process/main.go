@@ -0,0 +1,200 @@
+// Command process runs the tracking pipeline over SigMF recordings as fast as
+// the CPU allows and writes the telemetry of each recording to disk, so
+// archived field data can be reprocessed whenever the algorithms improve.
+//
+//	process [flags] capture1.sigmf-meta capture2 ...
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/app"
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// options holds the processing settings shared by all recordings.
+type options struct {
+	outDir       string
+	sampleRate   float64
+	rxLO         float64
+	toneOffset   float64
+	numSamples   int
+	spacing      float64
+	phaseCal     float64
+	phaseStep    float64
+	scanStep     float64
+	trackingMode string
+	maxTracks    int
+	trackTimeout time.Duration
+	minSNR       float64
+	warmup       int
+	cfoTracking  bool
+	fftWindow    dsp.Window
+	burstMode    bool
+	swapChannels bool
+	invertRX1    bool
+	debugMode    bool
+}
+
+// run processes every recording named in args and returns the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var opts options
+	fs := flag.NewFlagSet("process", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.outDir, "out", ".", "Directory for the CSV, JSON lines and track summary of each recording")
+	fs.Float64Var(&opts.sampleRate, "sample-rate", 0, "Processing sample rate in Hz; recordings at other rates are resampled (default the recording's rate)")
+	fs.Float64Var(&opts.rxLO, "rx-lo", 0, "RX LO frequency in Hz (default from the recording's core:frequency, else 2.3 GHz)")
+	fs.Float64Var(&opts.toneOffset, "tone-offset", 200e3, "Tone offset in Hz")
+	fs.IntVar(&opts.numSamples, "num-samples", 1<<12, "Samples per processed buffer")
+	fs.Float64Var(&opts.spacing, "spacing-wavelength", 0.5, "Antenna spacing as a fraction of wavelength")
+	fs.Float64Var(&opts.phaseCal, "phase-cal", 0, "Additional calibration phase (degrees)")
+	fs.Float64Var(&opts.phaseStep, "phase-step", 1, "Phase step (degrees) for monopulse updates")
+	fs.Float64Var(&opts.scanStep, "scan-step", 2, "Scan step in degrees for coarse search")
+	fs.StringVar(&opts.trackingMode, "tracking-mode", "single", "Tracking mode (single|multi)")
+	fs.IntVar(&opts.maxTracks, "max-tracks", 1, "Maximum number of simultaneous tracks")
+	fs.DurationVar(&opts.trackTimeout, "track-timeout", 3*time.Second, "Recording time after which inactive tracks are marked lost")
+	fs.Float64Var(&opts.minSNR, "min-snr-threshold", 3, "Minimum SNR required to create or update a track")
+	fs.IntVar(&opts.warmup, "warmup-buffers", 3, "Number of buffers to discard at the start of each recording")
+	fs.BoolVar(&opts.cfoTracking, "cfo-tracking", false, "Continuously track and remove residual carrier frequency offset")
+	window := fs.String("fft-window", "hamming", "Window applied before every FFT (hann|hamming|blackman-harris|kaiser[:beta]|rectangular)")
+	fs.BoolVar(&opts.burstMode, "burst-mode", false, "Process only energy bursts and feed one averaged detection per burst to the track manager")
+	fs.BoolVar(&opts.swapChannels, "swap-channels", false, "Swap the rx0 and rx1 sample streams (cabling correction)")
+	fs.BoolVar(&opts.invertRX1, "invert-rx1", false, "Negate rx1 samples, correcting a 180 degree polarity flip")
+	fs.BoolVar(&opts.debugMode, "debug-mode", false, "Include debug fields in the JSON lines output")
+	logLevel := fs.String("log-level", "warn", "Log level (debug|info|warn|error)")
+	version := fs.Bool("version", false, "Print build information and exit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *version {
+		fmt.Fprintln(stdout, "process", buildinfo.Get())
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: process [flags] recording.sigmf-meta ...")
+		return 2
+	}
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if opts.fftWindow, err = dsp.ParseWindow(*window); err != nil {
+		fmt.Fprintf(stderr, "error: -fft-window: %v\n", err)
+		return 2
+	}
+	logger := logging.New(level, logging.Text, stderr)
+	if err := os.MkdirAll(opts.outDir, 0o755); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		summary, err := processRecording(ctx, path, opts, logger)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %s: %v\n", path, err)
+			code = 1
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		fmt.Fprintln(stdout, summary)
+	}
+	return code
+}
+
+// processRecording replays one recording through a tracker until the end of
+// the file and returns a one-line summary.
+func processRecording(ctx context.Context, path string, opts options, logger logging.Logger) (string, error) {
+	meta, err := sdr.ReadSigMFMeta(path)
+	if err != nil {
+		return "", err
+	}
+	sampleRate := opts.sampleRate
+	if sampleRate == 0 {
+		sampleRate = meta.Global.SampleRate
+	}
+	rxLO := opts.rxLO
+	if rxLO == 0 {
+		rxLO = meta.Frequency()
+	}
+	if rxLO == 0 {
+		rxLO = 2.3e9
+	}
+
+	metaPath, _ := sdr.SigMFPaths(path)
+	name := filepath.Base(strings.TrimSuffix(metaPath, ".sigmf-meta"))
+	backend := sdr.NewFile(path)
+	artifacts, err := newArtifactWriter(filepath.Join(opts.outDir, name), backend.Time)
+	if err != nil {
+		return "", err
+	}
+
+	var source sdr.SDR = backend
+	if opts.swapChannels || opts.invertRX1 {
+		source = sdr.NewChannelMapper(backend, opts.swapChannels, opts.invertRX1)
+	}
+	tracker := app.NewTracker(source, artifacts, logger.With(logging.Field{Key: "recording", Value: name}), app.Config{
+		SampleRate:        sampleRate,
+		RxLO:              rxLO,
+		ToneOffset:        opts.toneOffset,
+		NumSamples:        opts.numSamples,
+		SpacingWavelength: opts.spacing,
+		PhaseCal:          opts.phaseCal,
+		PhaseStep:         opts.phaseStep,
+		ScanStep:          opts.scanStep,
+		WarmupBuffers:     opts.warmup,
+		DebugMode:         opts.debugMode,
+		TrackingMode:      opts.trackingMode,
+		MaxTracks:         opts.maxTracks,
+		TrackTimeout:      opts.trackTimeout,
+		MinSNRThreshold:   opts.minSNR,
+		CFOTracking:       opts.cfoTracking,
+		FFTWindow:         opts.fftWindow,
+		BurstMode:         opts.burstMode,
+		Unpaced:           true,
+		Clock:             clock.Func(backend.Time),
+	})
+	started := time.Now()
+	err = tracker.Init(ctx)
+	if err == nil {
+		err = tracker.Run(ctx)
+	}
+	backend.Close()
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if closeErr := artifacts.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	recorded := backend.Elapsed()
+	elapsed := time.Since(started)
+	return fmt.Sprintf("%s: %d samples, %d tracks, %s of data in %s (%.1fx real time) -> %s.*",
+		name, artifacts.samples, len(artifacts.tracks), recorded.Round(time.Millisecond), elapsed.Round(time.Millisecond),
+		recorded.Seconds()/elapsed.Seconds(), filepath.Join(opts.outDir, name)), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeToneRecording writes a two-channel ci16 SigMF recording of a tone at
+// toneHz with channel 1 lagging by phaseDeg.
+func writeToneRecording(t *testing.T, dir string, sampleRate, toneHz, phaseDeg float64, n int) string {
+	t.Helper()
+	base := filepath.Join(dir, "tone")
+	meta := map[string]any{
+		"global":   map[string]any{"core:datatype": "ci16_le", "core:sample_rate": sampleRate, "core:num_channels": 2},
+		"captures": []any{map[string]any{"core:sample_start": 0, "core:frequency": 2.3e9, "core:datetime": "2024-05-01T12:00:00Z"}},
+	}
+	data, _ := json.Marshal(meta)
+	if err := os.WriteFile(base+".sigmf-meta", data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	iq := make([]byte, 0, n*8)
+	for i := 0; i < n; i++ {
+		s0 := cmplx.Rect(0.5, 2*math.Pi*toneHz*float64(i)/sampleRate)
+		s1 := s0 * cmplx.Rect(1, -phaseDeg*math.Pi/180)
+		for _, v := range []float64{real(s0), imag(s0), real(s1), imag(s1)} {
+			iq = binary.LittleEndian.AppendUint16(iq, uint16(int16(v*32767)))
+		}
+	}
+	if err := os.WriteFile(base+".sigmf-data", iq, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return base + ".sigmf-meta"
+}
+
+func TestRunProcessesRecording(t *testing.T) {
+	dir := t.TempDir()
+	recording := writeToneRecording(t, dir, 2e6, 200e3, 30, 1<<12*20)
+	out := filepath.Join(dir, "out")
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"-out", out, recording}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "tone:") {
+		t.Fatalf("expected summary line, got %q", stdout.String())
+	}
+
+	csvData, err := os.ReadFile(filepath.Join(out, "tone.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(csvData)), "\n")
+	// 20 buffers minus the 3 warm-up buffers, plus the header.
+	if len(rows) != 18 {
+		t.Fatalf("expected 18 CSV rows, got %d", len(rows))
+	}
+	if !strings.HasPrefix(rows[1], "2024-05-01T12:00:00.00") {
+		t.Fatalf("expected recording timestamps, got %q", rows[1])
+	}
+
+	var db struct {
+		Samples int            `json:"samples"`
+		Tracks  []trackSummary `json:"tracks"`
+	}
+	data, err := os.ReadFile(filepath.Join(out, "tone.tracks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &db); err != nil || db.Samples != 17 || len(db.Tracks) != 1 {
+		t.Fatalf("unexpected track db %s (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "tone.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunReportsBadRecording(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"-out", t.TempDir(), "missing.sigmf-meta"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "missing.sigmf-meta") {
+		t.Fatalf("expected error naming the recording, got %q", stderr.String())
+	}
+}
+
+func TestRunPrintsVersion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"-version"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "process ") || !strings.Contains(stdout.String(), "features:") {
+		t.Fatalf("unexpected version output %q", stdout.String())
+	}
+}
@@ -0,0 +1,124 @@
+// Command ringcut lists and extracts the IQ held in a ring file recorded with
+// monopulse -ring-file, so an event can be saved after the fact as a SigMF
+// recording for cmd/process or the file backend.
+//
+//	ringcut -list capture.ring
+//	ringcut -from 2024-05-01T12:00:00Z -to 2024-05-01T12:00:30Z -out event capture.ring
+//	ringcut -last 30s -out event capture.ring
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ringcut", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	list := fs.Bool("list", false, "Print the recorded time spans and exit")
+	fromStr := fs.String("from", "", "Start of the range (RFC 3339)")
+	toStr := fs.String("to", "", "End of the range (RFC 3339; default the newest sample)")
+	last := fs.Duration("last", 0, "Extract this much before -to instead of giving -from")
+	out := fs.String("out", "", "Base name of the SigMF recording to write")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: ringcut [-list] [-from t] [-to t] [-last d] [-out name] capture.ring")
+		return 2
+	}
+
+	reader, err := sdr.OpenIQRing(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	defer reader.Close()
+	blocks, err := reader.Blocks()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if len(blocks) == 0 {
+		fmt.Fprintln(stderr, "error: ring is empty")
+		return 1
+	}
+	if *list {
+		printSpans(stdout, blocks)
+		return 0
+	}
+
+	from, to, err := parseRange(*fromStr, *toStr, *last, blocks[len(blocks)-1].End())
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if *out == "" {
+		fmt.Fprintln(stderr, "error: -out is required")
+		return 2
+	}
+	meta, err := reader.ExtractSigMF(from, to, *out, fmt.Sprintf("cut from %s", fs.Arg(0)))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	metaPath, _ := sdr.SigMFPaths(*out)
+	fmt.Fprintf(stdout, "%s: %s to %s, %d segment(s)\n", metaPath, from.UTC().Format(time.RFC3339Nano), to.UTC().Format(time.RFC3339Nano), len(meta.Captures))
+	return 0
+}
+
+// parseRange resolves the extraction range. newest is the end of the newest
+// block and the default end.
+func parseRange(fromStr, toStr string, last time.Duration, newest time.Time) (from, to time.Time, err error) {
+	to = newest
+	if toStr != "" {
+		if to, err = time.Parse(time.RFC3339Nano, toStr); err != nil {
+			return from, to, fmt.Errorf("-to: %w", err)
+		}
+	}
+	switch {
+	case fromStr != "" && last != 0:
+		return from, to, fmt.Errorf("give either -from or -last")
+	case fromStr != "":
+		if from, err = time.Parse(time.RFC3339Nano, fromStr); err != nil {
+			return from, to, fmt.Errorf("-from: %w", err)
+		}
+	case last > 0:
+		from = to.Add(-last)
+	default:
+		return from, to, fmt.Errorf("-from or -last is required")
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("empty range %s to %s", from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano))
+	}
+	return from, to, nil
+}
+
+// printSpans prints one line per stretch of back-to-back blocks at the same
+// frequency.
+func printSpans(w io.Writer, blocks []sdr.RingBlock) {
+	start := blocks[0]
+	for i, b := range blocks {
+		if i+1 < len(blocks) {
+			next := blocks[i+1]
+			gap := next.Start.Sub(b.End())
+			if next.FrequencyHz == b.FrequencyHz && next.SampleRate == b.SampleRate && gap < b.End().Sub(b.Start) {
+				continue
+			}
+		}
+		fmt.Fprintf(w, "%s  %s  %.0f Hz  %.0f S/s\n", start.Start.UTC().Format(time.RFC3339Nano), b.End().UTC().Format(time.RFC3339Nano), b.FrequencyHz, b.SampleRate)
+		if i+1 < len(blocks) {
+			start = blocks[i+1]
+		}
+	}
+}
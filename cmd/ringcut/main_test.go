@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func writeRing(t *testing.T, path string, t0 time.Time) {
+	t.Helper()
+	ring, err := sdr.CreateIQRing(path, 1<<20, 1000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rx0 := make([]complex64, 1000)
+	rx1 := make([]complex64, 1000)
+	// Two seconds at 1 kHz, then a second after a pause.
+	for _, start := range []time.Time{t0, t0.Add(time.Second), t0.Add(5 * time.Second)} {
+		if err := ring.Append(start, 2.4e9, 1000, rx0, rx1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ring.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunListsAndCuts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capture.ring")
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	writeRing(t, path, t0)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-list", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("list exit %d: %s", code, stderr.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], "2024-05-01T12:00:00Z  2024-05-01T12:00:02Z") {
+		t.Fatalf("spans = %q", stdout.String())
+	}
+
+	stdout.Reset()
+	out := filepath.Join(dir, "event")
+	if code := run([]string{"-last", "1500ms", "-out", out, path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("cut exit %d: %s", code, stderr.String())
+	}
+	meta, err := sdr.ReadSigMFMeta(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Captures) != 1 || !meta.Start().Equal(t0.Add(5*time.Second)) {
+		t.Fatalf("captures = %+v, want the final second only", meta.Captures)
+	}
+}
+
+func TestRunRejectsBadRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.ring")
+	writeRing(t, path, time.Now())
+	for _, args := range [][]string{
+		{"-out", "x", path},
+		{"-from", "yesterday", "-out", "x", path},
+		{"-from", "2024-05-01T12:00:00Z", "-last", "1s", "-out", "x", path},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Fatalf("%v: exit %d, want 2", args, code)
+		}
+	}
+}
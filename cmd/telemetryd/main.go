@@ -0,0 +1,185 @@
+// Command telemetryd serves the telemetry web UI and API for trackers
+// running elsewhere, so a headless SDR host can be watched from another
+// machine. It follows a tracker's live stream, accepts agent nodes, or both,
+// and keeps its own history, track list and events.
+//
+//	monopulse -web-addr :8080                      (on the SDR host)
+//	telemetryd -source http://sdr-host:8080 -addr :8080
+//
+//	monopulse -agent-upstream central:7100          (on each node)
+//	telemetryd -aggregator-listen :7100 -addr :8080
+//
+// SDR control endpoints answer 503, as no backend is attached here.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/agent"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/secrets"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// options holds the parsed command line.
+type options struct {
+	addr         string
+	source       string
+	aggregator   string
+	assets       string
+	auth         string
+	pairing      string
+	sourceToken  string
+	historyLimit int
+	store        string
+	logLevel     string
+	leakCheck    time.Duration
+}
+
+func parseFlags(args []string, stderr io.Writer) (options, error) {
+	var opts options
+	fs := flag.NewFlagSet("telemetryd", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.addr, "addr", ":8080", "Listen address for the web UI and API")
+	fs.StringVar(&opts.source, "source", "", "Base URL of the tracker web server to follow")
+	fs.StringVar(&opts.aggregator, "aggregator-listen", "", "Accept monopulse -agent-upstream nodes on this address (e.g. :7100)")
+	fs.StringVar(&opts.assets, "assets", "", "Serve the UI from this directory instead of the built-in files")
+	fs.StringVar(&opts.auth, "auth", "", "Require HTTP basic authentication as user:password; the password may be an env:NAME, file:PATH or secret:NAME reference")
+	fs.StringVar(&opts.pairing, "pairing", "", "Web UI pairing with the code printed at startup (off|remote|all; default remote, or off with -auth)")
+	fs.StringVar(&opts.sourceToken, "source-token", "", "Bearer token for a -source tracker that requires pairing (its admin token, or an env:, file: or secret: reference)")
+	fs.IntVar(&opts.historyLimit, "history-limit", 500, "Samples kept in the local history")
+	fs.StringVar(&opts.store, "store", "", "Keep the history and events of all sources in this store, e.g. file:/var/lib/gosdr/store or sqlite:/var/lib/gosdr/telemetry.db (empty disables it)")
+	fs.StringVar(&opts.logLevel, "log-level", "info", "Log level (debug|info|warn|error)")
+	fs.DurationVar(&opts.leakCheck, "leak-check", time.Minute, "Interval of the leak self-check reported in /api/health (0 disables)")
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	if opts.source == "" && opts.aggregator == "" {
+		return options{}, errors.New("-source or -aggregator-listen is required")
+	}
+	if opts.source != "" {
+		u, err := url.Parse(opts.source)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return options{}, fmt.Errorf("-source %q must be an http(s) URL", opts.source)
+		}
+	}
+	if opts.auth != "" && !strings.Contains(opts.auth, ":") {
+		return options{}, errors.New("-auth must be user:password")
+	}
+	resolver := &secrets.Resolver{}
+	if user, password, ok := strings.Cut(opts.auth, ":"); ok {
+		password, err := resolver.Resolve(password)
+		if err != nil {
+			return options{}, fmt.Errorf("-auth: %w", err)
+		}
+		opts.auth = user + ":" + password
+	}
+	var err error
+	if opts.sourceToken, err = resolver.Resolve(opts.sourceToken); err != nil {
+		return options{}, fmt.Errorf("-source-token: %w", err)
+	}
+	if opts.pairing == "" && opts.auth != "" {
+		opts.pairing = telemetry.PairingOff
+	}
+	if opts.pairing, err = telemetry.ParsePairingMode(opts.pairing); err != nil {
+		return options{}, err
+	}
+	return opts, nil
+}
+
+// run executes the command until ctx is done and returns the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	opts, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	level, err := logging.ParseLevel(opts.logLevel)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	logger := logging.New(level, logging.Text, stdout)
+
+	var serverOpts []telemetry.Option
+	if opts.assets != "" {
+		serverOpts = append(serverOpts, telemetry.WithAssets(os.DirFS(opts.assets)))
+	}
+	if user, password, ok := strings.Cut(opts.auth, ":"); ok {
+		serverOpts = append(serverOpts, telemetry.WithAuth(telemetry.BasicAuth(user, password)))
+	}
+	serverOpts = append(serverOpts, telemetry.WithPairing(telemetry.NewPairing(opts.pairing, func(code string) {
+		fmt.Fprintf(stdout, "Pair the web UI: open /?pair=%s on %s (code %s)\n", code, opts.addr, code)
+	})))
+	hub := telemetry.NewHub(opts.historyLimit, logger.With(logging.Field{Key: "subsystem", Value: "telemetry"}))
+	ws := telemetry.NewWebServer(opts.addr, hub, nil, logger, serverOpts...)
+	go hub.WatchLeaks(ctx, opts.leakCheck)
+	storeDone := make(chan struct{})
+	if opts.store != "" {
+		store, err := telemetry.OpenStore(opts.store)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		hub.SetStore(store)
+	}
+	go func() {
+		hub.RunStore(ctx)
+		close(storeDone)
+	}()
+
+	if opts.source != "" {
+		logger.Info("following tracker", logging.Field{Key: "source", Value: opts.source})
+		var client *http.Client
+		if opts.sourceToken != "" {
+			client = &http.Client{Transport: bearerTransport{token: opts.sourceToken, base: http.DefaultTransport}}
+		}
+		go hub.Follow(ctx, opts.source, client)
+	}
+	if opts.aggregator != "" {
+		aggregator := agent.NewAggregator(hub, logger)
+		go func() {
+			if err := aggregator.ListenAndServe(ctx, opts.aggregator); err != nil {
+				logger.Error("aggregator", logging.Field{Key: "error", Value: err})
+			}
+		}()
+		logger.Info("accepting agent nodes", logging.Field{Key: "addr", Value: opts.aggregator})
+	}
+	logger.Info("serving web UI", logging.Field{Key: "addr", Value: opts.addr})
+	if err := ws.Start(ctx); err != nil {
+		return 1
+	}
+	<-storeDone // RunStore writes the rest once ctx is done
+	return 0
+}
+
+// bearerTransport adds a bearer token to every request.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
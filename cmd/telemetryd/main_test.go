@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"valid", []string{"-source", "http://sdr-host:8080", "-auth", "ops:secret"}, ""},
+		{"aggregator only", []string{"-aggregator-listen", ":7100"}, ""},
+		{"missing source", nil, "-source or -aggregator-listen is required"},
+		{"bad scheme", []string{"-source", "mqtt://broker"}, "must be an http(s) URL"},
+		{"bad auth", []string{"-source", "http://sdr-host", "-auth", "ops"}, "user:password"},
+		{"auth from env", []string{"-source", "http://sdr-host:8080", "-auth", "ops:env:GOSDR_TEST_UI_PASSWORD"}, ""},
+		{"auth env unset", []string{"-source", "http://sdr-host", "-auth", "ops:env:GOSDR_TEST_UNSET"}, "environment variable not set"},
+	}
+	t.Setenv("GOSDR_TEST_UI_PASSWORD", "secret")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseFlags(tt.args, io.Discard)
+			if tt.wantErr == "" {
+				if err != nil || opts.addr != ":8080" {
+					t.Fatalf("parseFlags = %+v, %v", opts, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFlagsPairing(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-source", "http://sdr-host:8080"}, "remote"},
+		{[]string{"-source", "http://sdr-host:8080", "-auth", "ops:secret"}, "off"},
+		{[]string{"-source", "http://sdr-host:8080", "-auth", "ops:secret", "-pairing", "all"}, "all"},
+	}
+	for _, tt := range tests {
+		opts, err := parseFlags(tt.args, io.Discard)
+		if err != nil || opts.pairing != tt.want {
+			t.Errorf("parseFlags(%v) pairing = %q, %v; want %q", tt.args, opts.pairing, err, tt.want)
+		}
+	}
+}
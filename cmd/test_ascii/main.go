@@ -89,20 +89,19 @@ func main() {
 	m := connectionmgr.New(*uri)
 	m.SetTimeout(2 * time.Second)
 
-	conn, err := net.DialTimeout("tcp", m.Address, m.Timeout)
+	conn, err := net.DialTimeout("tcp", m.Address, m.Timeouts.Connect)
 	if err != nil {
 		log.Fatalf("dial %s failed: %v", m.Address, err)
 	}
 	log.Printf("[BOOT] TCP connection established to %s", m.Address)
 	m.SetConn(&loggingConn{Conn: conn})
 	m.Mode = connectionmgr.ModeASCII
-	m.SetTimeout(m.Timeout)
-	log.Printf("[BOOT] manager configured for ASCII mode with timeout=%s", m.Timeout)
+	log.Printf("[BOOT] manager configured for ASCII mode with timeouts=%+v", m.Timeouts)
 
-	if ret, err := m.ExecCommand(fmt.Sprintf("TIMEOUT %d", m.Timeout.Milliseconds())); err != nil {
+	if err := m.ApplyRemoteTimeout(); err != nil {
 		log.Printf("[WARN] TIMEOUT command failed (continuing with local deadline): %v", err)
 	} else {
-		log.Printf("[INFO] Remote TIMEOUT set, device replied with %d", ret)
+		log.Printf("[INFO] Remote TIMEOUT set to %s", m.Timeouts.RemoteTimeout())
 	}
 
 	log.Printf("[INFO] Fetching XML context from %s", m.Address)
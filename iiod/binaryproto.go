@@ -0,0 +1,107 @@
+package iiod
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// call sends a libiio 1.x binary command and reads a response of the given
+// shape over the client's wire (see wireLocked). The whole exchange holds
+// c.mu, so concurrent callers cannot interleave their frames.
+func (c *Client) call(ctx context.Context, shape iiodwire.Shape, opcode, dev uint8, code int32, payloads ...[]byte) (iiodwire.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.reader == nil {
+		return iiodwire.Response{}, fmt.Errorf("client is not connected")
+	}
+	c.metrics.CommandsSent.Add(1)
+	resp, err := c.wireLocked().CallShapeContext(ctx, shape, opcode, dev, code, payloads...)
+	// The text commands expect a socket without a deadline.
+	_ = c.conn.SetDeadline(time.Time{})
+	if err != nil {
+		c.metrics.CommandsFailed.Add(1)
+		if resp.Status < 0 {
+			return resp, &IIODError{Status: int(resp.Status)}
+		}
+		c.isConnected.Store(false)
+		return resp, err
+	}
+
+	sent := iiodwire.HeaderSize
+	for _, p := range payloads {
+		sent += len(p)
+	}
+	c.metrics.BytesSent.Add(uint64(sent))
+	if shape != iiodwire.ShapeNone {
+		c.metrics.BytesReceived.Add(uint64(iiodwire.HeaderSize + 4 + len(resp.Data)))
+	}
+	c.metrics.LastCommandTime.Store(time.Now())
+	return resp, nil
+}
+
+// wireLocked returns the connectionmgr.Manager attached to the client's
+// connection, which carries the binary commands, the buffers and the event
+// streams. It is created for each connection and follows the protocol mode.
+// The caller holds c.mu.
+func (c *Client) wireLocked() *connectionmgr.Manager {
+	mode := connectionmgr.ModeASCII
+	if c.mode == ProtocolBinary {
+		mode = connectionmgr.ModeBinary
+	}
+	if c.wire == nil {
+		c.wire = connectionmgr.Attach(c.conn, c.reader, mode, &c.mu)
+	}
+	c.wire.Mode = mode
+	return c.wire
+}
+
+// transport is wireLocked for callers that do not hold c.mu. The buffer and
+// event stream methods of the Manager take c.mu themselves.
+func (c *Client) transport() *connectionmgr.Manager {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.wireLocked()
+}
+
+// binaryTarget resolves a device and an optional channel to the indexes the
+// binary protocol addresses them by: their position in the context XML.
+func (c *Client) binaryTarget(ctx context.Context, device, channel string) (uint8, int32, error) {
+	if c.devices == nil {
+		xmlContent := c.xmlContext
+		if xmlContent == "" {
+			var err error
+			if xmlContent, err = c.GetXMLContextWithContext(ctx); err != nil {
+				return 0, 0, err
+			}
+		}
+		devices, err := parseDeviceInfoFromXML(xmlContent)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse IIOD context: %w", err)
+		}
+		c.devices = devices
+	}
+
+	for i, dev := range c.devices {
+		if dev.ID != device && dev.Name != device {
+			continue
+		}
+		if i > 0x7f {
+			return 0, 0, fmt.Errorf("device %q: index %d out of range", device, i)
+		}
+		if channel == "" {
+			return uint8(i), 0, nil
+		}
+		for j, ch := range dev.Channels {
+			if ch.ID == channel {
+				return uint8(i), int32(j), nil
+			}
+		}
+		return 0, 0, fmt.Errorf("channel %q not found on device %q", channel, device)
+	}
+	return 0, 0, fmt.Errorf("device %q not found in IIOD context", device)
+}
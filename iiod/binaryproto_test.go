@@ -0,0 +1,320 @@
+package iiod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// binaryStep is one request of the binary mock: the header and payload
+// expected and the response body sent after the response header.
+type binaryStep struct {
+	op       uint8
+	dev      uint8
+	code     int32
+	payload  []byte
+	response []byte
+}
+
+func runBinaryMock(conn net.Conn, steps []binaryStep) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		for i, step := range steps {
+			hdr, err := iiodwire.ReadHeader(conn)
+			if err != nil {
+				errCh <- fmt.Errorf("step %d: read header: %w", i, err)
+				return
+			}
+			if hdr.Opcode != step.op || hdr.Dev != step.dev || hdr.Code != step.code {
+				errCh <- fmt.Errorf("step %d: got %+v, want op 0x%02x dev %d code %d", i, hdr, step.op, step.dev, step.code)
+				return
+			}
+			payload := make([]byte, len(step.payload))
+			if _, err := io.ReadFull(conn, payload); err != nil || !bytes.Equal(payload, step.payload) {
+				errCh <- fmt.Errorf("step %d: payload % x (%v), want % x", i, payload, err, step.payload)
+				return
+			}
+			if step.response == nil {
+				continue
+			}
+			resp := append(iiodwire.Header{Opcode: iiodwire.OpResponse, Dev: hdr.Dev}.Marshal(), step.response...)
+			if _, err := conn.Write(resp); err != nil {
+				errCh <- fmt.Errorf("step %d: write response: %w", i, err)
+				return
+			}
+		}
+	}()
+	return errCh
+}
+
+func newBinaryPipeClient() (*Client, net.Conn) {
+	clientConn, serverConn := net.Pipe()
+	client := &Client{conn: clientConn, reader: bufio.NewReader(clientConn), mode: ProtocolBinary}
+	client.cacheXMLMetadata(testContextXML)
+	return client, serverConn
+}
+
+func TestBinaryClientAttrsBuffersAndEvents(t *testing.T) {
+	status := iiodwire.I32(0)
+	withU32 := append(iiodwire.I32(0), iiodwire.U32(0)...)
+	data := func(p []byte) []byte { return append(iiodwire.I32(0), iiodwire.LPBytes(p)...) }
+	samples := []byte{1, 0, 2, 0, 3, 0, 4, 0, 5, 0, 6, 0, 7, 0, 8, 0}
+	block := int32(0)
+	raw := make([]byte, iiodwire.EventSize)
+	binary.LittleEndian.PutUint64(raw[0:8], uint64(1)<<48|1)
+	binary.LittleEndian.PutUint64(raw[8:16], 42)
+
+	client, server := newBinaryPipeClient()
+	defer client.Close()
+	defer server.Close()
+	errCh := runBinaryMock(server, []binaryStep{
+		{op: iiodwire.OpReadAttr, dev: 0, payload: iiodwire.LPString("ensm_mode"), response: data([]byte("fdd\n"))},
+		{op: iiodwire.OpReadChnAttr, dev: 0, code: 1, payload: iiodwire.LPString("hardwaregain"), response: data([]byte("71.000000 dB"))},
+		{op: iiodwire.OpWriteChnAttr, dev: 0, code: 0, payload: iiodwire.NameValue("frequency", "2400000000"), response: status},
+		{op: iiodwire.OpReadDbgAttr, dev: 0, payload: iiodwire.LPString("direct_reg_access"), response: data([]byte("0x0"))},
+		{op: iiodwire.OpTimeout, code: 500},
+		// CreateStreamBuffer with channels 0 and 1 of cf-ad9361-lpc.
+		{op: iiodwire.OpCreateBuffer, dev: 1, code: 0, payload: iiodwire.U32SliceWithCount([]uint32{0x3}), response: withU32},
+		{op: iiodwire.OpCreateBlock, dev: 1, code: block, payload: iiodwire.U64(2 * 8), response: withU32},
+		{op: iiodwire.OpEnableBuffer, dev: 1, code: 0, response: status},
+		{op: iiodwire.OpTransferBlock, dev: 1, code: block, payload: iiodwire.U64(16), response: data(samples)},
+		{op: iiodwire.OpTransferBlock, dev: 1, code: block, payload: append(iiodwire.U64(16), samples...), response: status},
+		{op: iiodwire.OpDisableBuffer, dev: 1, code: 0, response: status},
+		{op: iiodwire.OpFreeBlock, dev: 1, code: block, response: status},
+		{op: iiodwire.OpFreeBuffer, dev: 1, code: 0, response: status},
+		{op: iiodwire.OpCreateEvStream, dev: 0, code: 1, response: withU32},
+		{op: iiodwire.OpReadEvent, dev: 0, code: 1, response: data(raw)},
+		{op: iiodwire.OpFreeEvStream, dev: 0, code: 1, response: status},
+	})
+	ctx := context.Background()
+
+	if got, err := client.ReadAttr("ad9361-phy", "", "ensm_mode"); err != nil || got != "fdd" {
+		t.Fatalf("ReadAttr = %q, %v", got, err)
+	}
+	if got, err := client.ReadAttr("iio:device0", "voltage0", "hardwaregain"); err != nil || got != "71.000000 dB" {
+		t.Fatalf("ReadAttr channel = %q, %v", got, err)
+	}
+	if err := client.WriteAttrCompatWithContext(ctx, "ad9361-phy", "altvoltage0", "frequency", "2400000000"); err != nil {
+		t.Fatalf("WriteAttrCompat: %v", err)
+	}
+	if got, err := client.ReadDebugAttr("ad9361-phy", "direct_reg_access"); err != nil || got != "0x0" {
+		t.Fatalf("ReadDebugAttr = %q, %v", got, err)
+	}
+	if err := client.SetTimeout(500 * time.Millisecond); err != nil {
+		t.Fatalf("SetTimeout: %v", err)
+	}
+
+	buf, err := client.CreateStreamBuffer(ctx, "cf-ad9361-lpc", 2, 0x3)
+	if err != nil {
+		t.Fatalf("CreateStreamBuffer: %v", err)
+	}
+	got, err := buf.ReadSamples()
+	if err != nil || !bytes.Equal(got, samples) {
+		t.Fatalf("ReadSamples = % x, %v", got, err)
+	}
+	if err := buf.WriteSamples(samples); err != nil {
+		t.Fatalf("WriteSamples: %v", err)
+	}
+	if err := buf.WriteSamples(append(samples, 0)); err == nil {
+		t.Fatal("write larger than the block accepted")
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatalf("Close buffer: %v", err)
+	}
+
+	stream, err := client.OpenEventStream(ctx, "ad9361-phy")
+	if err != nil {
+		t.Fatalf("OpenEventStream: %v", err)
+	}
+	ev, err := stream.Read(ctx)
+	if err != nil || ev.Direction() != 1 || ev.Channel() != 1 || ev.Timestamp != 42 {
+		t.Fatalf("Read = %+v, %v", ev, err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close stream: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("server: %v", err)
+	}
+}
+
+func TestBinaryClientErrors(t *testing.T) {
+	client, server := newBinaryPipeClient()
+	defer client.Close()
+	defer server.Close()
+	errCh := runBinaryMock(server, []binaryStep{
+		{op: iiodwire.OpReadAttr, dev: 1, payload: iiodwire.LPString("nope"), response: append(iiodwire.I32(-2), iiodwire.U32(0)...)},
+	})
+
+	_, err := client.ReadAttr("cf-ad9361-lpc", "", "nope")
+	var iiErr *IIODError
+	if !errors.As(err, &iiErr) || iiErr.Status != -2 {
+		t.Fatalf("ReadAttr error = %v, want iiod error -2", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("server: %v", err)
+	}
+	if _, err := client.ReadAttr("no-such-device", "", "x"); err == nil {
+		t.Fatal("read on an unknown device succeeded")
+	}
+	if _, err := client.ReadAttr("ad9361-phy", "voltage9", "x"); err == nil {
+		t.Fatal("read on an unknown channel succeeded")
+	}
+
+	text := &Client{mode: ProtocolText}
+	if _, err := text.OpenEventStream(context.Background(), "ad9361-phy"); err == nil {
+		t.Fatal("event stream opened in text mode")
+	}
+}
+
+// startDelayedWriteServer accepts one connection and answers each attribute
+// write delay after it arrives, as a link with that round trip would, without
+// waiting for earlier replies. A write of the attribute "bad" fails with
+// -22. The requests are sent on the returned channel in arrival order.
+func startDelayedWriteServer(t *testing.T, mode ProtocolMode, delay time.Duration) (net.Conn, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	requests := make(chan string, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		type reply struct {
+			due  time.Time
+			data []byte
+		}
+		replies := make(chan reply, 16)
+		defer close(replies)
+		go func() {
+			for r := range replies {
+				time.Sleep(time.Until(r.due))
+				if _, err := conn.Write(r.data); err != nil {
+					return
+				}
+			}
+		}()
+		reader := bufio.NewReader(conn)
+		for {
+			var req, attr string
+			var hdr iiodwire.Header
+			if mode == ProtocolBinary {
+				if hdr, err = iiodwire.ReadHeader(reader); err != nil {
+					return
+				}
+				var fields [2][]byte
+				for i := range fields {
+					var n uint32
+					if err := binary.Read(reader, binary.BigEndian, &n); err != nil {
+						return
+					}
+					fields[i] = make([]byte, n)
+					if _, err := io.ReadFull(reader, fields[i]); err != nil {
+						return
+					}
+				}
+				attr = string(fields[0])
+				req = fmt.Sprintf("op 0x%02x dev %d code %d %s=%s", hdr.Opcode, hdr.Dev, hdr.Code, fields[0], fields[1])
+			} else {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				req = line[:len(line)-1]
+				if fields := strings.Fields(req); len(fields) >= 2 {
+					attr = fields[len(fields)-2]
+				}
+			}
+			requests <- req
+			status := int32(0)
+			if attr == "bad" {
+				status = -22
+			}
+			data := []byte(fmt.Sprintf("%d\n", status))
+			if mode == ProtocolBinary {
+				data = append(iiodwire.Header{Opcode: iiodwire.OpResponse, Dev: hdr.Dev}.Marshal(), iiodwire.I32(status)...)
+			}
+			replies <- reply{due: time.Now().Add(delay), data: data}
+		}
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, requests
+}
+
+func TestWriteAttrsPipelined(t *testing.T) {
+	const delay = 150 * time.Millisecond
+	ops := []AttrOperation{
+		{Device: "ad9361-phy", Channel: "voltage0", Attr: "hardwaregain", Value: "10", IsWrite: true},
+		{Device: "ad9361-phy", Channel: "altvoltage0", Attr: "frequency", Value: "2400000000", IsWrite: true},
+		{Device: "ad9361-phy", Attr: "bad", Value: "1", IsWrite: true},
+		{Device: "ad9361-phy", Attr: "ensm_mode", Value: "fdd", IsWrite: true},
+	}
+	tests := []struct {
+		name string
+		mode ProtocolMode
+		want []string
+	}{
+		{name: "binary", mode: ProtocolBinary, want: []string{
+			"op 0x0a dev 0 code 1 hardwaregain=10",
+			"op 0x0a dev 0 code 0 frequency=2400000000",
+			"op 0x07 dev 0 code 0 bad=1",
+			"op 0x07 dev 0 code 0 ensm_mode=fdd",
+		}},
+		{name: "text", mode: ProtocolText, want: []string{
+			"WRITE ad9361-phy voltage0 hardwaregain 10",
+			"WRITE ad9361-phy altvoltage0 frequency 2400000000",
+			"WRITE ad9361-phy bad 1",
+			"WRITE ad9361-phy ensm_mode fdd",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, requests := startDelayedWriteServer(t, tt.mode, delay)
+			client := &Client{conn: conn, reader: bufio.NewReader(conn), mode: tt.mode}
+			client.cacheXMLMetadata(testContextXML)
+
+			start := time.Now()
+			errs := client.WriteAttrsWithContext(context.Background(), ops)
+			elapsed := time.Since(start)
+
+			// One write after another would take len(ops) round trips.
+			if elapsed >= 2*delay {
+				t.Fatalf("%d writes took %v, want about one round trip (%v)", len(ops), elapsed, delay)
+			}
+			for i, err := range errs {
+				var iiodErr *IIODError
+				switch {
+				case i == 2 && (!errors.As(err, &iiodErr) || iiodErr.Status != -22):
+					t.Fatalf("write %d: err = %v, want status -22", i, err)
+				case i != 2 && err != nil:
+					t.Fatalf("write %d: %v", i, err)
+				}
+			}
+			for i, want := range tt.want {
+				if got := <-requests; got != want {
+					t.Fatalf("request %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
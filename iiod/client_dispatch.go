@@ -0,0 +1,266 @@
+package iiod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// getContextInfoWithContextBinary reports the version the server gave during
+// negotiation; the binary protocol has no VERSION command.
+func (c *Client) getContextInfoWithContextBinary(ctx context.Context) (ContextInfo, error) {
+	v := c.serverVersion
+	return ContextInfo{Major: v.Major, Minor: v.Minor, Description: v.Git}, nil
+}
+
+func (c *Client) getContextInfoWithContextText(ctx context.Context) (ContextInfo, error) {
+	resp, err := c.sendCommandString(ctx, "VERSION")
+	if err != nil {
+		return ContextInfo{}, err
+	}
+	return parseContextInfo(resp)
+}
+
+func (c *Client) listDevicesWithContextBinary(ctx context.Context) ([]string, error) {
+	return c.ListDevicesFromXML(ctx)
+}
+
+func (c *Client) listDevicesWithContextText(ctx context.Context) ([]string, error) {
+	resp, err := c.sendCommandString(ctx, "LISTDEVICES")
+	if err != nil {
+		return nil, err
+	}
+	if resp == "" {
+		return []string{}, nil
+	}
+	return strings.Fields(resp), nil
+}
+
+func (c *Client) getXMLContextWithContextBinary(ctx context.Context) (string, error) {
+	if c.xmlContext != "" {
+		return c.xmlContext, nil
+	}
+	resp, err := c.call(ctx, iiodwire.ShapeStatusBytes, iiodwire.OpPrint, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	c.cacheXMLMetadata(string(resp.Data))
+	return c.xmlContext, nil
+}
+
+func (c *Client) getXMLContextWithContextText(ctx context.Context) (string, error) {
+	// Send PRINT command
+	if _, err := c.sendCommandString(ctx, "PRINT"); err != nil {
+		return "", err
+	}
+	if c.xmlContext != "" {
+		return c.xmlContext, nil
+	}
+	// readRawXML handles the streaming response for PRINT
+	return c.readRawXML(ctx)
+}
+
+func (c *Client) getChannelsWithContextBinary(ctx context.Context, device string) ([]string, error) {
+	dev, _, err := c.binaryTarget(ctx, device, "")
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]string, 0, len(c.devices[dev].Channels))
+	for _, ch := range c.devices[dev].Channels {
+		channels = append(channels, ch.ID)
+	}
+	return channels, nil
+}
+
+func (c *Client) getChannelsWithContextText(ctx context.Context, device string) ([]string, error) {
+	resp, err := c.sendCommandString(ctx, fmt.Sprintf("LISTCHANNELS %s", device))
+	if err != nil {
+		return nil, err
+	}
+	if resp == "" {
+		return []string{}, nil
+	}
+	return strings.Fields(resp), nil
+}
+
+func (c *Client) readAttrText(ctx context.Context, device, channel, attr string) (string, error) {
+	var cmd string
+	if channel == "" {
+		cmd = fmt.Sprintf("READ %s %s", device, attr)
+	} else {
+		cmd = fmt.Sprintf("READ %s %s %s", device, channel, attr)
+	}
+	return c.sendCommandString(ctx, cmd)
+}
+
+func textWriteCommand(device, channel, attr, value string) string {
+	if channel == "" {
+		return fmt.Sprintf("WRITE %s %s %s", device, attr, value)
+	}
+	return fmt.Sprintf("WRITE %s %s %s %s", device, channel, attr, value)
+}
+
+func (c *Client) writeAttrText(ctx context.Context, device, channel, attr, value string) error {
+	_, err := c.sendCommandString(ctx, textWriteCommand(device, channel, attr, value))
+	return err
+}
+
+// writeAttrsText sends the WRITE commands of ops in one write and then reads
+// their replies. A reply that is not a status leaves the stream out of step,
+// so it fails the remaining operations too.
+func (c *Client) writeAttrsText(ctx context.Context, ops []AttrOperation, errs []error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fail := func(from int, err error) {
+		for i := from; i < len(ops); i++ {
+			errs[i] = err
+		}
+	}
+	if c.conn == nil || c.reader == nil {
+		fail(0, fmt.Errorf("client is not connected"))
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			fail(0, err)
+			return
+		}
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	var req strings.Builder
+	for _, op := range ops {
+		req.WriteString(textWriteCommand(op.Device, op.Channel, op.Attr, op.Value) + "\n")
+	}
+	c.metrics.CommandsSent.Add(uint64(len(ops)))
+	n, err := c.conn.Write([]byte(req.String()))
+	if err != nil {
+		c.metrics.CommandsFailed.Add(uint64(len(ops)))
+		c.isConnected.Store(false)
+		fail(0, err)
+		return
+	}
+	c.metrics.BytesSent.Add(uint64(n))
+
+	for i := range ops {
+		if _, err := c.readTextReplyLocked(); err != nil {
+			var iiodErr *IIODError
+			if !errors.As(err, &iiodErr) {
+				fail(i, err)
+				return
+			}
+			errs[i] = err
+		}
+	}
+}
+
+func (c *Client) readAttrBinary(ctx context.Context, device, channel, attr string) (string, error) {
+	dev, ch, err := c.binaryTarget(ctx, device, channel)
+	if err != nil {
+		return "", err
+	}
+	opcode, code := iiodwire.OpReadAttr, int32(0)
+	if channel != "" {
+		opcode, code = iiodwire.OpReadChnAttr, ch
+	}
+	resp, err := c.call(ctx, iiodwire.ShapeStatusBytes, opcode, dev, code, iiodwire.LPString(attr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(resp.Data)), nil
+}
+
+func (c *Client) writeAttrBinary(ctx context.Context, device, channel, attr, value string) error {
+	dev, ch, err := c.binaryTarget(ctx, device, channel)
+	if err != nil {
+		return err
+	}
+	opcode, code := iiodwire.OpWriteAttr, int32(0)
+	if channel != "" {
+		opcode, code = iiodwire.OpWriteChnAttr, ch
+	}
+	_, err = c.call(ctx, iiodwire.ShapeStatus, opcode, dev, code, iiodwire.NameValue(attr, value))
+	return err
+}
+
+// writeAttrsBinary resolves the targets of ops and sends the writes as one
+// batch (see connectionmgr.Manager.CallBatch).
+func (c *Client) writeAttrsBinary(ctx context.Context, ops []AttrOperation, errs []error) {
+	cmds := make([]connectionmgr.Command, 0, len(ops))
+	index := make([]int, 0, len(ops))
+	for i, op := range ops {
+		dev, ch, err := c.binaryTarget(ctx, op.Device, op.Channel)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		opcode, code := iiodwire.OpWriteAttr, int32(0)
+		if op.Channel != "" {
+			opcode, code = iiodwire.OpWriteChnAttr, ch
+		}
+		cmds = append(cmds, connectionmgr.Command{
+			Shape:    iiodwire.ShapeStatus,
+			Opcode:   opcode,
+			Dev:      dev,
+			Code:     code,
+			Payloads: [][]byte{iiodwire.NameValue(op.Attr, op.Value)},
+		})
+		index = append(index, i)
+	}
+	if len(cmds) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil || c.reader == nil {
+		for _, i := range index {
+			errs[i] = fmt.Errorf("client is not connected")
+		}
+		return
+	}
+	c.metrics.CommandsSent.Add(uint64(len(cmds)))
+	resps, cmdErrs := c.wireLocked().CallBatchContext(ctx, cmds)
+	_ = c.conn.SetDeadline(time.Time{})
+	for j, i := range index {
+		err := cmdErrs[j]
+		switch {
+		case err == nil:
+			continue
+		case resps[j].Status < 0:
+			err = &IIODError{Status: int(resps[j].Status)}
+		default:
+			c.isConnected.Store(false)
+		}
+		c.metrics.CommandsFailed.Add(1)
+		errs[i] = err
+	}
+	c.metrics.LastCommandTime.Store(time.Now())
+}
+
+func (c *Client) readDebugAttrBinary(ctx context.Context, device, attr string) (string, error) {
+	dev, _, err := c.binaryTarget(ctx, device, "")
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.call(ctx, iiodwire.ShapeStatusBytes, iiodwire.OpReadDbgAttr, dev, 0, iiodwire.LPString(attr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(resp.Data)), nil
+}
+
+func (c *Client) writeDebugAttrBinary(ctx context.Context, device, attr, value string) error {
+	dev, _, err := c.binaryTarget(ctx, device, "")
+	if err != nil {
+		return err
+	}
+	_, err = c.call(ctx, iiodwire.ShapeStatus, iiodwire.OpWriteDbgAttr, dev, 0, iiodwire.NameValue(attr, value))
+	return err
+}
@@ -0,0 +1,56 @@
+package iiod
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// Event is one IIO event, such as a threshold crossing reported by a driver.
+type Event = iiodwire.Event
+
+// EventStream is an open event stream of one device. Event streams need the
+// binary protocol of libiio 1.x servers; the stream is a
+// connectionmgr.EventStream on the client's connection.
+type EventStream struct {
+	s *connectionmgr.EventStream
+}
+
+// OpenEventStream opens the event stream of device. Stream ids share the
+// counter of the binary buffers.
+func (c *Client) OpenEventStream(ctx context.Context, device string) (*EventStream, error) {
+	if c.mode != ProtocolBinary {
+		return nil, errors.New("event streams need the IIOD binary protocol")
+	}
+	dev, _, err := c.binaryTarget(ctx, device, "")
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.transport().OpenEventStreamContext(ctx, dev)
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{s: s}, nil
+}
+
+// Read waits for the next event until ctx is done or the stream budget of
+// the connection's timeout policy runs out. A read cut short leaves the
+// connection out of step; close the client afterwards.
+func (s *EventStream) Read(ctx context.Context) (Event, error) {
+	if s == nil || s.s == nil {
+		return Event{}, errors.New("event stream not open")
+	}
+	return s.s.ReadContext(ctx)
+}
+
+// Close frees the stream on the server. Closing a closed stream is a no-op.
+func (s *EventStream) Close() error {
+	if s == nil || s.s == nil {
+		return nil
+	}
+	stream := s.s
+	s.s = nil
+	return stream.Close()
+}
@@ -0,0 +1,81 @@
+package iiod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// negotiate selects the protocol of a fresh connection. It asks for the
+// server version over the text protocol, which every IIOD server speaks, and
+// switches a libiio 1.x server to the binary protocol with BINARY. Older
+// servers (Pluto firmware with IIOD v0.25) and servers that refuse BINARY
+// stay on the text protocol.
+func (c *Client) negotiate(ctx context.Context) error {
+	raw, err := c.readVersionLine(ctx)
+	if err != nil {
+		return fmt.Errorf("negotiate: %w", err)
+	}
+	version, err := iiodwire.ParseServerVersion(raw)
+	if err != nil {
+		return fmt.Errorf("negotiate: %w", err)
+	}
+	c.serverVersion = version
+	c.versionReply = raw
+	c.mode = ProtocolText
+	if !version.SupportsBinary() {
+		log.Printf("[IIOD] %s runs IIOD %s: text protocol", c.addr, version)
+		return nil
+	}
+
+	if _, err := c.sendCommandString(ctx, "BINARY"); err != nil {
+		var iiErr *IIODError
+		if errors.As(err, &iiErr) {
+			log.Printf("[IIOD] %s runs IIOD %s but refused BINARY: text protocol", c.addr, version)
+			return nil
+		}
+		return fmt.Errorf("negotiate: BINARY: %w", err)
+	}
+	c.mode = ProtocolBinary
+	log.Printf("[IIOD] %s runs IIOD %s: binary protocol", c.addr, version)
+	return nil
+}
+
+// readVersionLine sends the text VERSION command. Unlike other text
+// commands, the reply is a bare "major.minor.git" line without a status.
+func (c *Client) readVersionLine(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.reader == nil {
+		return "", fmt.Errorf("client is not connected")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return "", err
+		}
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	c.metrics.CommandsSent.Add(1)
+	n, err := c.conn.Write([]byte("VERSION\n"))
+	if err != nil {
+		c.metrics.CommandsFailed.Add(1)
+		return "", err
+	}
+	c.metrics.BytesSent.Add(uint64(n))
+
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.metrics.CommandsFailed.Add(1)
+		return "", err
+	}
+	c.metrics.BytesReceived.Add(uint64(len(line)))
+	c.metrics.LastCommandTime.Store(time.Now())
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,183 @@
+package iiod
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+const testContextXML = `<?xml version="1.0" encoding="utf-8"?>
+<context name="network" version-major="1" version-minor="0">
+<device id="iio:device0" name="ad9361-phy"><channel id="altvoltage0" type="output"><attribute name="frequency"/></channel><channel id="voltage0" type="input"><attribute name="hardwaregain"/></channel><attribute name="ensm_mode"/></device>
+<device id="iio:device1" name="cf-ad9361-lpc"><channel id="voltage0" type="input"/><channel id="voltage1" type="input"/><channel id="voltage2" type="input"/><channel id="voltage3" type="input"/></device>
+</context>
+`
+
+func TestDialNegotiatesProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		binary  string // reply to BINARY; empty expects no BINARY
+		want    ProtocolMode
+		wantErr bool
+	}{
+		{name: "pluto v0.25", version: "0.25.gb6028fd", want: ProtocolText},
+		{name: "libiio 1.x", version: "1.0.abc1234", binary: "0", want: ProtocolBinary},
+		{name: "refused", version: "1.0.abc1234", binary: "-22", want: ProtocolText},
+		{name: "malformed", version: "iiod", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			serverErr := make(chan error, 1)
+			go func() {
+				serverErr <- serveNegotiation(ln, tt.version, tt.binary, tt.want)
+			}()
+
+			client, err := Dial(ln.Addr().String())
+			if tt.wantErr {
+				if err == nil {
+					client.Close()
+					t.Fatal("Dial accepted a malformed version")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Dial: %v", err)
+			}
+			defer client.Close()
+			if err := <-serverErr; err != nil {
+				t.Fatalf("server: %v", err)
+			}
+			if client.ProtocolMode() != tt.want {
+				t.Fatalf("mode = %v, want %v", client.ProtocolMode(), tt.want)
+			}
+			if !strings.Contains(client.xmlContext, "cf-ad9361-lpc") {
+				t.Fatalf("context XML not fetched: %q", client.xmlContext)
+			}
+		})
+	}
+}
+
+func TestDialWithOptionsXMLCache(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	cache := &connectionmgr.XMLCache{Dir: t.TempDir()}
+
+	for _, cached := range []bool{false, true} {
+		serverErr := make(chan error, 1)
+		go func() {
+			if !cached {
+				serverErr <- serveNegotiation(ln, "0.25.gb6028fd", "", ProtocolText)
+				return
+			}
+			serverErr <- serveCachedDial(ln, "0.25.gb6028fd")
+		}()
+
+		var progress []connectionmgr.XMLProgress
+		client, err := DialWithOptions(context.Background(), ln.Addr().String(), DialOptions{
+			XML: connectionmgr.XMLFetchOptions{Cache: cache, Progress: func(p connectionmgr.XMLProgress) { progress = append(progress, p) }},
+		})
+		if err != nil {
+			t.Fatalf("cached=%v: Dial: %v", cached, err)
+		}
+		if !strings.Contains(client.xmlContext, "cf-ad9361-lpc") {
+			t.Fatalf("cached=%v: context XML not loaded: %q", cached, client.xmlContext)
+		}
+		client.Close()
+		if err := <-serverErr; err != nil {
+			t.Fatalf("cached=%v: server: %v", cached, err)
+		}
+		if len(progress) == 0 {
+			t.Fatalf("cached=%v: no progress reported", cached)
+		}
+		if last := progress[len(progress)-1]; last.Cached != cached || last.Received != last.Total {
+			t.Fatalf("cached=%v: last progress %+v", cached, last)
+		}
+	}
+}
+
+// serveCachedDial answers VERSION and expects the client to hang up without
+// sending PRINT, its context XML coming from the cache.
+func serveCachedDial(ln net.Listener, version string) error {
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	if line, err := br.ReadString('\n'); err != nil || strings.TrimSpace(line) != "VERSION" {
+		return fmt.Errorf("got %q (%v), want VERSION", line, err)
+	}
+	if _, err := io.WriteString(conn, version+"\n"); err != nil {
+		return err
+	}
+	if line, err := br.ReadString('\n'); err != io.EOF {
+		return fmt.Errorf("got %q (%v) after VERSION, want the connection closed", line, err)
+	}
+	return nil
+}
+
+// serveNegotiation answers VERSION, BINARY when the client sends it, and the
+// PRINT of the protocol the client should have settled on.
+func serveNegotiation(ln net.Listener, version, binaryReply string, mode ProtocolMode) error {
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	expect := func(cmd, reply string) error {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(line) != cmd {
+			return fmt.Errorf("got %q, want %s", strings.TrimSpace(line), cmd)
+		}
+		_, err = io.WriteString(conn, reply+"\n")
+		return err
+	}
+	if err := expect("VERSION", version); err != nil {
+		return err
+	}
+	if binaryReply != "" {
+		if err := expect("BINARY", binaryReply); err != nil {
+			return err
+		}
+	}
+	if _, err := iiodwire.ParseServerVersion(version); err != nil {
+		return nil
+	}
+
+	if mode == ProtocolText {
+		// IIOD follows the document with a newline of its own.
+		return expect("PRINT", fmt.Sprintf("%d\n%s", len(testContextXML), testContextXML))
+	}
+	hdr, err := iiodwire.ReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if hdr.Opcode != iiodwire.OpPrint {
+		return fmt.Errorf("got opcode 0x%02x, want PRINT", hdr.Opcode)
+	}
+	reply := iiodwire.Header{Opcode: iiodwire.OpResponse}.Marshal()
+	reply = append(reply, iiodwire.I32(0)...)
+	_, err = conn.Write(append(reply, iiodwire.LPString(testContextXML)...))
+	return err
+}
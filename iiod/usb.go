@@ -0,0 +1,159 @@
+package iiod
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// USB transport
+//
+// A Pluto serves IIOD on a vendor-specific USB interface as well as over its
+// USB network interface. The interface carries pairs of bulk endpoints, one
+// pair per pipe; pipe i uses endpoints 2i (IN) and 2i+1 (OUT). Vendor control
+// requests to the interface reset the pipes and open or close one, after
+// which the pipe carries the same byte stream as a TCP connection to IIOD.
+
+// Vendor control requests of the IIO USB interface (wValue = pipe, wIndex =
+// interface).
+const (
+	usbCmdResetPipes = 0
+	usbCmdOpenPipe   = 1
+	usbCmdClosePipe  = 2
+)
+
+const (
+	usbClassVendor  = 0xff
+	usbVendorADI    = 0x0456
+	usbProductPluto = 0xb673
+)
+
+// usbURI is a parsed libiio "usb:[bus.address[.interface]]" URI.
+type usbURI struct {
+	bus, address int // both 0 select the first Pluto found
+	intf         int // -1 selects the first IIO interface of the device
+}
+
+func isUSBAddr(addr string) bool {
+	return strings.HasPrefix(addr, "usb:")
+}
+
+func parseUSBURI(addr string) (usbURI, error) {
+	uri := usbURI{intf: -1}
+	rest, ok := strings.CutPrefix(addr, "usb:")
+	if !ok {
+		return uri, fmt.Errorf("not a USB URI: %q", addr)
+	}
+	if rest == "" {
+		return uri, nil
+	}
+	fields := strings.Split(rest, ".")
+	if len(fields) < 2 || len(fields) > 3 {
+		return uri, fmt.Errorf("malformed USB URI %q, want usb:bus.address[.interface]", addr)
+	}
+	nums := make([]int, len(fields))
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 || n > 255 {
+			return uri, fmt.Errorf("malformed USB URI %q, want usb:bus.address[.interface]", addr)
+		}
+		nums[i] = n
+	}
+	uri.bus, uri.address = nums[0], nums[1]
+	if len(nums) == 3 {
+		uri.intf = nums[2]
+	}
+	return uri, nil
+}
+
+// usbDevice is the part of a device's descriptors the transport needs: the
+// ids and the interfaces of the first configuration, alternate setting 0.
+type usbDevice struct {
+	vendor, product uint16
+	interfaces      []usbInterface
+}
+
+type usbInterface struct {
+	number, class uint8
+	bulk          []usbEndpoint // in descriptor order
+}
+
+type usbEndpoint struct {
+	address   uint8
+	maxPacket int
+}
+
+// parseUSBDescriptors parses the raw descriptors usbfs returns for a device:
+// the device descriptor followed by its configuration descriptors.
+func parseUSBDescriptors(raw []byte) (usbDevice, error) {
+	var dev usbDevice
+	if len(raw) < 18 || raw[1] != 1 {
+		return dev, fmt.Errorf("no USB device descriptor")
+	}
+	dev.vendor = binary.LittleEndian.Uint16(raw[8:10])
+	dev.product = binary.LittleEndian.Uint16(raw[10:12])
+
+	var current *usbInterface
+	configs := 0
+	for off := int(raw[0]); off+2 <= len(raw); {
+		n := int(raw[off])
+		if n < 2 || off+n > len(raw) {
+			return dev, fmt.Errorf("truncated USB descriptor at offset %d", off)
+		}
+		d := raw[off : off+n]
+		off += n
+		switch d[1] {
+		case 2: // configuration
+			configs++
+			current = nil
+		case 4: // interface
+			if configs != 1 || n < 9 {
+				current = nil
+				continue
+			}
+			if d[3] != 0 { // alternate setting
+				current = nil
+				continue
+			}
+			dev.interfaces = append(dev.interfaces, usbInterface{number: d[2], class: d[5]})
+			current = &dev.interfaces[len(dev.interfaces)-1]
+		case 5: // endpoint
+			if current == nil || n < 7 || d[3]&0x3 != 2 {
+				continue
+			}
+			maxPacket := int(binary.LittleEndian.Uint16(d[4:6]) & 0x7ff)
+			current.bulk = append(current.bulk, usbEndpoint{address: d[2], maxPacket: maxPacket})
+		}
+	}
+	return dev, nil
+}
+
+// iioInterface returns interface number intf, or for -1 the first
+// vendor-specific interface with a pipe.
+func (d usbDevice) iioInterface(intf int) (usbInterface, error) {
+	for _, it := range d.interfaces {
+		if intf >= 0 && int(it.number) == intf {
+			return it, nil
+		}
+		if intf < 0 && it.class == usbClassVendor && len(it.bulk) >= 2 {
+			return it, nil
+		}
+	}
+	if intf >= 0 {
+		return usbInterface{}, fmt.Errorf("USB interface %d not found", intf)
+	}
+	return usbInterface{}, fmt.Errorf("no IIO interface on USB device %04x:%04x", d.vendor, d.product)
+}
+
+// pipe returns the IN and OUT endpoints of pipe i.
+func (it usbInterface) pipe(i int) (in, out usbEndpoint, err error) {
+	if 2*i+1 >= len(it.bulk) {
+		return in, out, fmt.Errorf("USB interface %d has no pipe %d", it.number, i)
+	}
+	in, out = it.bulk[2*i], it.bulk[2*i+1]
+	if in.address&0x80 == 0 || out.address&0x80 != 0 {
+		return in, out, fmt.Errorf("USB interface %d: pipe %d endpoints 0x%02x/0x%02x are not an IN/OUT pair", it.number, i, in.address, out.address)
+	}
+	return in, out, nil
+}
@@ -0,0 +1,311 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || loong64)
+
+package iiod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// The USB transport talks to usbfs (/dev/bus/usb/BBB/DDD) with ioctls, so it
+// needs neither cgo nor libusb. The build tag lists the architectures with
+// the generic ioctl number encoding used below.
+
+// usbCtrlTransfer is struct usbdevfs_ctrltransfer.
+type usbCtrlTransfer struct {
+	requestType uint8
+	request     uint8
+	value       uint16
+	index       uint16
+	length      uint16
+	timeout     uint32 // milliseconds
+	data        unsafe.Pointer
+}
+
+// usbBulkTransfer is struct usbdevfs_bulktransfer.
+type usbBulkTransfer struct {
+	endpoint uint32
+	length   uint32
+	timeout  uint32 // milliseconds, 0 waits forever
+	data     unsafe.Pointer
+}
+
+func usbIOC(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'U'<<8 | nr
+}
+
+var (
+	usbdevfsControl          = usbIOC(3, 0, unsafe.Sizeof(usbCtrlTransfer{}))
+	usbdevfsBulk             = usbIOC(3, 2, unsafe.Sizeof(usbBulkTransfer{}))
+	usbdevfsClaimInterface   = usbIOC(2, 15, 4)
+	usbdevfsReleaseInterface = usbIOC(2, 16, 4)
+)
+
+const (
+	usbControlTimeout = 1000 // milliseconds
+	usbPollTimeout    = 200  // milliseconds, the longest a bulk transfer blocks
+	usbMaxWrite       = 64 << 10
+)
+
+// usbConn is pipe 0 of the IIO interface of a USB device, as a net.Conn.
+type usbConn struct {
+	f       *os.File
+	uri     string
+	intf    uint8
+	in, out usbEndpoint
+	closed  atomic.Bool
+
+	// Reads are a packet at a time: a bulk read completes with the first
+	// packet, so it never waits for data IIOD has no reason to send.
+	pkt     []byte
+	pending []byte
+
+	io            sync.RWMutex // held for reading by transfers, for writing by Close
+	mu            sync.Mutex   // guards the deadlines
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// dialUSB opens the device of a libiio "usb:" URI, claims its IIO interface
+// and opens pipe 0.
+func dialUSB(ctx context.Context, addr string) (net.Conn, error) {
+	uri, err := parseUSBURI(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("/dev/bus/usb/%03d/%03d", uri.bus, uri.address)
+	if uri.bus == 0 && uri.address == 0 {
+		if path, err = findPlutoUSB(); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open USB device: %w", err)
+	}
+	c, err := openUSBPipe(f, addr, uri.intf)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+	return c, nil
+}
+
+func openUSBPipe(f *os.File, addr string, intf int) (*usbConn, error) {
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read USB descriptors: %w", err)
+	}
+	dev, err := parseUSBDescriptors(raw)
+	if err != nil {
+		return nil, err
+	}
+	it, err := dev.iioInterface(intf)
+	if err != nil {
+		return nil, err
+	}
+	in, out, err := it.pipe(0)
+	if err != nil {
+		return nil, err
+	}
+
+	pktSize := in.maxPacket
+	if pktSize < 64 {
+		pktSize = 64
+	}
+	c := &usbConn{f: f, uri: addr, intf: it.number, in: in, out: out, pkt: make([]byte, pktSize)}
+	number := uint32(it.number)
+	if err := c.ioctl(usbdevfsClaimInterface, unsafe.Pointer(&number)); err != nil {
+		return nil, fmt.Errorf("claim USB interface %d: %w", it.number, err)
+	}
+	if err := c.control(usbCmdResetPipes, 0); err != nil {
+		c.ioctl(usbdevfsReleaseInterface, unsafe.Pointer(&number))
+		return nil, fmt.Errorf("reset USB pipes: %w", err)
+	}
+	if err := c.control(usbCmdOpenPipe, 0); err != nil {
+		c.ioctl(usbdevfsReleaseInterface, unsafe.Pointer(&number))
+		return nil, fmt.Errorf("open USB pipe: %w", err)
+	}
+	return c, nil
+}
+
+// findPlutoUSB returns the usbfs node of the first Pluto attached.
+func findPlutoUSB() (string, error) {
+	paths, _ := filepath.Glob("/dev/bus/usb/[0-9]*/[0-9]*")
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if dev, err := parseUSBDescriptors(raw); err == nil && dev.vendor == usbVendorADI && dev.product == usbProductPluto {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no Pluto found on USB")
+}
+
+func (c *usbConn) ioctl(req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, c.f.Fd(), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// control sends a vendor request to the IIO interface.
+func (c *usbConn) control(cmd uint8, pipe uint16) error {
+	t := usbCtrlTransfer{
+		requestType: 0x41, // host to device, vendor, interface
+		request:     cmd,
+		value:       pipe,
+		index:       uint16(c.intf),
+		timeout:     usbControlTimeout,
+	}
+	return c.ioctl(usbdevfsControl, unsafe.Pointer(&t))
+}
+
+// bulk transfers p on ep within the read or write deadline. The transfer is
+// issued in slices of at most usbPollTimeout, so a pending Read or Write
+// notices Close and deadline changes instead of waiting forever in the
+// kernel.
+func (c *usbConn) bulk(ep usbEndpoint, p []byte, read bool) (int, error) {
+	for {
+		timeout := uint32(usbPollTimeout)
+		if deadline := c.deadline(read); !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			switch ms := left.Milliseconds(); {
+			case ms < 1:
+				timeout = 1
+			case ms < usbPollTimeout:
+				timeout = uint32(ms)
+			}
+		}
+		n, err := c.transfer(ep, p, timeout)
+		if err == syscall.ETIMEDOUT {
+			continue
+		}
+		if err != nil && err != net.ErrClosed {
+			err = fmt.Errorf("USB bulk transfer on endpoint 0x%02x: %w", ep.address, err)
+		}
+		return n, err
+	}
+}
+
+// transfer issues one bulk transfer. It holds io for reading so Close does
+// not close the device under it.
+func (c *usbConn) transfer(ep usbEndpoint, p []byte, timeout uint32) (int, error) {
+	c.io.RLock()
+	defer c.io.RUnlock()
+	if c.closed.Load() {
+		return 0, net.ErrClosed
+	}
+	t := usbBulkTransfer{endpoint: uint32(ep.address), length: uint32(len(p)), timeout: timeout}
+	if len(p) > 0 {
+		t.data = unsafe.Pointer(&p[0])
+	}
+	n, _, errno := syscall.Syscall(syscall.SYS_IOCTL, c.f.Fd(), usbdevfsBulk, uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
+
+func (c *usbConn) deadline(read bool) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if read {
+		return c.readDeadline
+	}
+	return c.writeDeadline
+}
+
+func (c *usbConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		n, err := c.bulk(c.in, c.pkt, true)
+		if err != nil {
+			return 0, err
+		}
+		c.pending = c.pkt[:n]
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *usbConn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		chunk := p[written:]
+		if len(chunk) > usbMaxWrite {
+			chunk = chunk[:usbMaxWrite]
+		}
+		n, err := c.bulk(c.out, chunk, false)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Close closes the pipe, releases the interface and closes the device. A
+// pending Read or Write returns net.ErrClosed within usbPollTimeout.
+func (c *usbConn) Close() error {
+	if c.closed.Swap(true) {
+		return net.ErrClosed
+	}
+	c.io.Lock()
+	defer c.io.Unlock()
+	number := uint32(c.intf)
+	return errors.Join(
+		c.control(usbCmdClosePipe, 0),
+		c.ioctl(usbdevfsReleaseInterface, unsafe.Pointer(&number)),
+		c.f.Close(),
+	)
+}
+
+func (c *usbConn) LocalAddr() net.Addr  { return usbAddr("usb:") }
+func (c *usbConn) RemoteAddr() net.Addr { return usbAddr(c.uri) }
+
+func (c *usbConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline, c.writeDeadline = t, t
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *usbConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *usbConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+// usbAddr is the net.Addr of a USB pipe: its URI.
+type usbAddr string
+
+func (a usbAddr) Network() string { return "usb" }
+func (a usbAddr) String() string  { return string(a) }
@@ -0,0 +1,19 @@
+//go:build !(linux && (386 || amd64 || arm || arm64 || riscv64 || loong64))
+
+package iiod
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// dialUSB reports that this platform has no USB transport: usb: URIs are
+// only supported on Linux, through usbfs. A Pluto is also reachable over its
+// USB network interface at ip:192.168.2.1.
+func dialUSB(ctx context.Context, addr string) (net.Conn, error) {
+	if _, err := parseUSBURI(addr); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: the USB transport needs Linux usbfs; use ip:192.168.2.1 instead", addr)
+}
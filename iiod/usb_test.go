@@ -0,0 +1,110 @@
+package iiod
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUSBURI(t *testing.T) {
+	tests := []struct {
+		addr    string
+		want    usbURI
+		wantErr bool
+	}{
+		{addr: "usb:", want: usbURI{intf: -1}},
+		{addr: "usb:1.2", want: usbURI{bus: 1, address: 2, intf: -1}},
+		{addr: "usb:1.2.5", want: usbURI{bus: 1, address: 2, intf: 5}},
+		{addr: "usb:1", wantErr: true},
+		{addr: "usb:1.2.3.4", wantErr: true},
+		{addr: "usb:1.x", wantErr: true},
+		{addr: "usb:1.256", wantErr: true},
+		{addr: "ip:192.168.2.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, err := parseUSBURI(tt.addr)
+			if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+				t.Fatalf("parseUSBURI(%q) = %+v, %v", tt.addr, got, err)
+			}
+		})
+	}
+}
+
+// plutoDescriptors is a trimmed Pluto descriptor set: a network function
+// interface, the IIO interface with two pipes, and a second configuration
+// that must be ignored.
+func plutoDescriptors() []byte {
+	device := []byte{18, 1, 0x00, 0x02, 0xef, 0x02, 0x01, 64, 0x56, 0x04, 0x73, 0xb6, 0, 0, 1, 2, 3, 2}
+	config := []byte{9, 2, 0, 0, 3, 1, 0, 0x80, 250}
+	cdc := []byte{
+		9, 4, 0, 0, 1, 0x02, 0x06, 0, 0,
+		7, 5, 0x83, 0x03, 16, 0, 9, // interrupt, not a bulk endpoint
+	}
+	iio := []byte{
+		9, 4, 2, 0, 4, 0xff, 0, 0, 0,
+		7, 5, 0x81, 0x02, 0x00, 0x02, 0,
+		7, 5, 0x01, 0x02, 0x00, 0x02, 0,
+		7, 5, 0x82, 0x02, 0x00, 0x02, 0,
+		7, 5, 0x02, 0x02, 0x00, 0x02, 0,
+	}
+	alt := []byte{
+		9, 4, 2, 1, 2, 0xff, 0, 0, 0,
+		7, 5, 0x85, 0x02, 0x40, 0x00, 0,
+		7, 5, 0x05, 0x02, 0x40, 0x00, 0,
+	}
+	second := []byte{
+		9, 2, 0, 0, 1, 2, 0, 0x80, 250,
+		9, 4, 7, 0, 2, 0xff, 0, 0, 0,
+		7, 5, 0x86, 0x02, 0x00, 0x02, 0,
+		7, 5, 0x06, 0x02, 0x00, 0x02, 0,
+	}
+	var raw []byte
+	for _, d := range [][]byte{device, config, cdc, iio, alt, second} {
+		raw = append(raw, d...)
+	}
+	return raw
+}
+
+func TestParseUSBDescriptors(t *testing.T) {
+	dev, err := parseUSBDescriptors(plutoDescriptors())
+	if err != nil {
+		t.Fatalf("parseUSBDescriptors: %v", err)
+	}
+	if dev.vendor != usbVendorADI || dev.product != usbProductPluto {
+		t.Fatalf("ids = %04x:%04x", dev.vendor, dev.product)
+	}
+	if len(dev.interfaces) != 2 {
+		t.Fatalf("interfaces = %+v, want 2 from the first configuration", dev.interfaces)
+	}
+
+	it, err := dev.iioInterface(-1)
+	if err != nil || it.number != 2 || len(it.bulk) != 4 {
+		t.Fatalf("iioInterface(-1) = %+v, %v", it, err)
+	}
+	in, out, err := it.pipe(1)
+	if err != nil || in.address != 0x82 || out.address != 0x02 || in.maxPacket != 512 {
+		t.Fatalf("pipe(1) = %+v %+v, %v", in, out, err)
+	}
+	if _, _, err := it.pipe(2); err == nil {
+		t.Fatal("pipe(2) found on a two-pipe interface")
+	}
+	if _, err := dev.iioInterface(7); err == nil {
+		t.Fatal("interface of the second configuration selected")
+	}
+	if _, err := dev.iioInterface(0); err != nil {
+		t.Fatalf("iioInterface(0): %v", err)
+	}
+
+	if _, err := parseUSBDescriptors(plutoDescriptors()[:30]); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Fatalf("truncated descriptors: %v", err)
+	}
+	if _, err := parseUSBDescriptors([]byte{9, 2}); err == nil {
+		t.Fatal("missing device descriptor accepted")
+	}
+}
+
+func TestDialUSBRejectsMalformedURI(t *testing.T) {
+	if _, err := Dial("usb:1"); err == nil || !strings.Contains(err.Error(), "malformed") {
+		t.Fatalf("Dial(usb:1) = %v, want a malformed URI error", err)
+	}
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func testLogger() logging.Logger {
+	return logging.New(logging.Debug, logging.Text, io.Discard)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123, time.UTC)
+	sample := telemetry.MultiTrackSample{Timestamp: at, Tracks: []telemetry.TrackSample{
+		{ID: "t1", AngleDeg: -12.5, Peak: -30, SNR: 18, Confidence: 0.9, LockState: telemetry.LockStateTracking, State: "confirmed", Score: 4, AgeSeconds: 2.5},
+		{ID: "t2", AngleDeg: 40},
+	}}
+	tests := []envelope{
+		{Hello: &hello{Node: "mast", Session: 42}},
+		{Sample: &sampleMsg{Seq: 7, Source: "north", Sample: sample}},
+		{Event: &eventMsg{Seq: 8, Time: at, Level: "warn", Message: "overload"}},
+		{Ack: 8},
+	}
+	var buf bytes.Buffer
+	for _, e := range tests {
+		if err := writeFrame(&buf, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := bufio.NewReader(&buf)
+	for i, want := range tests {
+		got, err := readFrame(r)
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		switch {
+		case want.Hello != nil:
+			if got.Hello == nil || *got.Hello != *want.Hello {
+				t.Fatalf("hello = %+v", got.Hello)
+			}
+		case want.Sample != nil:
+			s := got.Sample
+			if s == nil || s.Seq != 7 || s.Source != "north" || !s.Sample.Timestamp.Equal(at) || len(s.Sample.Tracks) != 2 || s.Sample.Tracks[0] != sample.Tracks[0] {
+				t.Fatalf("sample = %+v", s)
+			}
+		case want.Event != nil:
+			if got.Event == nil || got.Event.Message != "overload" || !got.Event.Time.Equal(at) {
+				t.Fatalf("event = %+v", got.Event)
+			}
+		default:
+			if got.Ack != 8 {
+				t.Fatalf("ack = %d", got.Ack)
+			}
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientBuffersUntilAggregatorIsUp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listens yet
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := NewClient(addr, "mast", 3, testLogger())
+	north := client.ForDevice("north")
+	for i := range 5 {
+		north.Report(float64(i), -30, 15, 0.9, telemetry.LockStateTracking, nil)
+	}
+	if client.Dropped() != 2 {
+		t.Fatalf("dropped %d, want the 2 oldest of 5 in a buffer of 3", client.Dropped())
+	}
+	clientDone := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(clientDone)
+	}()
+
+	hub := telemetry.NewHub(10, testLogger())
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agg := NewAggregator(hub, testLogger())
+	serveDone := make(chan struct{})
+	go func() {
+		agg.Serve(ctx, ln)
+		close(serveDone)
+	}()
+
+	waitFor(t, "buffered samples", func() bool { return len(hub.History()) == 3 })
+	if got := hub.History()[0].Tracks[0]; got.AngleDeg != 2 || got.Device != "mast.north" {
+		t.Fatalf("first track = %+v, want angle 2 from mast.north", got)
+	}
+	waitFor(t, "acknowledgement", func() bool { return len(client.unsent(0)) == 0 })
+
+	north.(eventLogger).LogEvent("warn", "overload")
+	client.ForDevice("").Report(9, -30, 15, 0.9, telemetry.LockStateTracking, nil)
+	waitFor(t, "live sample", func() bool { return len(hub.History()) == 4 })
+	if devices := hub.Devices(); len(devices) != 2 || devices[0].ID != "mast" {
+		t.Fatalf("devices = %+v", devices)
+	}
+
+	cancel()
+	<-clientDone
+	<-serveDone
+}
+
+func TestAggregatorSkipsResentMessages(t *testing.T) {
+	hub := telemetry.NewHub(10, testLogger())
+	agg := NewAggregator(hub, testLogger())
+	sample := func(seq uint64) envelope {
+		return envelope{Sample: &sampleMsg{Seq: seq, Sample: telemetry.MultiTrackSample{
+			Timestamp: time.Now(), Tracks: []telemetry.TrackSample{{AngleDeg: float64(seq)}},
+		}}}
+	}
+	agg.connect("mast", 1)
+	agg.accept("mast", sample(1))
+	agg.accept("mast", sample(2))
+	if last := agg.connect("mast", 1); last != 2 {
+		t.Fatalf("reconnect resumes after %d, want 2", last)
+	}
+	agg.accept("mast", sample(2)) // resent after the reconnect
+	agg.accept("mast", sample(3))
+	if n := len(hub.History()); n != 3 {
+		t.Fatalf("history has %d samples, want 3 without the duplicate", n)
+	}
+	if last := agg.connect("mast", 2); last != 0 {
+		t.Fatalf("restarted node resumes after %d, want 0", last)
+	}
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// deviceSeparator joins a node name and a device ID on that node into the
+// aggregator's device ID, e.g. "mast.north".
+const deviceSeparator = "."
+
+// eventLogger is implemented by the hub's device reporters.
+type eventLogger interface {
+	LogEvent(level, message string)
+}
+
+// Aggregator accepts node connections and reports their samples and events
+// into a telemetry hub. Every node device appears as a hub device, so the
+// per-device API and UI work unchanged.
+type Aggregator struct {
+	hub *telemetry.Hub
+	log logging.Logger
+
+	mu    sync.Mutex
+	nodes map[string]*nodeState
+}
+
+// nodeState tracks what has been accepted from a node, so messages resent
+// after a reconnect are reported once.
+type nodeState struct {
+	session   uint64
+	lastSeq   uint64
+	reporters map[string]telemetry.Reporter
+}
+
+// NewAggregator builds an aggregator that reports into hub.
+func NewAggregator(hub *telemetry.Hub, logger logging.Logger) *Aggregator {
+	if logger == nil {
+		logger = logging.Default()
+	}
+	return &Aggregator{
+		hub:   hub,
+		log:   logger.With(logging.Field{Key: "subsystem", Value: "aggregator"}),
+		nodes: make(map[string]*nodeState),
+	}
+}
+
+// ListenAndServe listens on addr and serves nodes until ctx is done.
+func (a *Aggregator) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("aggregator listen: %w", err)
+	}
+	return a.Serve(ctx, ln)
+}
+
+// Serve accepts node connections on ln until ctx is done, then closes ln and
+// every open connection.
+func (a *Aggregator) Serve(ctx context.Context, ln net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("aggregator accept: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			closeConn := context.AfterFunc(ctx, func() { conn.Close() })
+			defer closeConn()
+			a.serveConn(conn)
+		}()
+	}
+}
+
+// serveConn reads one node connection until it closes, acknowledging what
+// was accepted whenever the read buffer drains.
+func (a *Aggregator) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	first, err := readFrame(r)
+	if err != nil || first.Hello == nil || first.Hello.Node == "" {
+		a.log.Warn("rejected agent connection", logging.Field{Key: "remote", Value: conn.RemoteAddr().String()}, logging.Field{Key: "error", Value: err})
+		return
+	}
+	node := first.Hello.Node
+	acked := a.connect(node, first.Hello.Session)
+	a.hub.LogEvent("info", fmt.Sprintf("agent %s connected from %s", node, conn.RemoteAddr()))
+	a.log.Info("agent connected", logging.Field{Key: "node", Value: node}, logging.Field{Key: "remote", Value: conn.RemoteAddr().String()})
+	// Release what a previous connection accepted but could not acknowledge.
+	if acked > 0 && writeFrame(conn, envelope{Ack: acked}) != nil {
+		return
+	}
+
+	for {
+		e, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				a.hub.LogEvent("warn", fmt.Sprintf("agent %s disconnected: %v", node, err))
+			}
+			return
+		}
+		seq := a.accept(node, e)
+		if r.Buffered() > 0 || seq == acked {
+			continue
+		}
+		if err := writeFrame(conn, envelope{Ack: seq}); err != nil {
+			return
+		}
+		acked = seq
+	}
+}
+
+// connect registers a node connection and returns the last sequence number
+// accepted in its current session. A new session, i.e. a restarted node,
+// starts counting from zero again.
+func (a *Aggregator) connect(node string, session uint64) uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state, ok := a.nodes[node]
+	if !ok {
+		state = &nodeState{reporters: make(map[string]telemetry.Reporter)}
+		a.nodes[node] = state
+	}
+	if state.session != session {
+		state.session = session
+		state.lastSeq = 0
+	}
+	return state.lastSeq
+}
+
+// accept reports e into the hub unless it was already accepted, and returns
+// the node's last accepted sequence number.
+func (a *Aggregator) accept(node string, e envelope) uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state := a.nodes[node]
+	seq := envelopeSeq(e)
+	if seq == 0 || seq <= state.lastSeq {
+		return state.lastSeq
+	}
+	state.lastSeq = seq
+	switch {
+	case e.Sample != nil:
+		a.reporter(node, state, e.Sample.Source).ReportMultiTrack(e.Sample.Sample)
+	case e.Event != nil:
+		if events, ok := a.reporter(node, state, e.Event.Source).(eventLogger); ok {
+			events.LogEvent(e.Event.Level, e.Event.Message)
+		}
+	}
+	return seq
+}
+
+// reporter returns the hub reporter for a device on node, registering the
+// device on first use.
+func (a *Aggregator) reporter(node string, state *nodeState, source string) telemetry.Reporter {
+	if r, ok := state.reporters[source]; ok {
+		return r
+	}
+	id := node
+	if source != "" {
+		id = node + deviceSeparator + source
+	}
+	r := a.hub.ForDevice(id, "agent")
+	state.reporters[source] = r
+	return r
+}
@@ -0,0 +1,245 @@
+// Package agent links thin tracker nodes to a central aggregator. A node runs
+// only the SDR and tracker and pushes its track samples and events upstream
+// over TCP; the aggregator feeds them into its telemetry hub, which serves
+// storage and the web UI for every node.
+package agent
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+const (
+	// DefaultBuffer is the number of unacknowledged messages a node keeps
+	// while the aggregator is unreachable.
+	DefaultBuffer = 10000
+	// retryMin and retryMax bound the reconnect backoff.
+	retryMin = time.Second
+	retryMax = 30 * time.Second
+	// dialTimeout bounds a single connection attempt.
+	dialTimeout = 5 * time.Second
+)
+
+// Client pushes samples and events from a node to an aggregator. Messages
+// are buffered until acknowledged, so a dropped link or aggregator restart
+// loses nothing as long as the buffer does not overflow; on overflow the
+// oldest messages are dropped and counted.
+type Client struct {
+	addr    string
+	node    string
+	session uint64
+	limit   int
+	log     logging.Logger
+
+	mu      sync.Mutex
+	seq     uint64
+	pending []envelope // unacknowledged, in sequence order
+	dropped uint64
+	notify  chan struct{}
+}
+
+// NewClient builds a client for the aggregator at addr (host:port). node
+// names this node on the aggregator; buffer bounds the unacknowledged
+// messages kept (DefaultBuffer when non-positive). Call Run to connect.
+func NewClient(addr, node string, buffer int, logger logging.Logger) *Client {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	if logger == nil {
+		logger = logging.Default()
+	}
+	return &Client{
+		addr:    addr,
+		node:    node,
+		session: uint64(time.Now().UnixNano()),
+		limit:   buffer,
+		log:     logger.With(logging.Field{Key: "subsystem", Value: "agent"}, logging.Field{Key: "upstream", Value: addr}),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// ForDevice returns a reporter that sends updates tagged with source, the
+// device ID on this node ("" for a single-device node).
+func (c *Client) ForDevice(source string) telemetry.Reporter {
+	return &nodeReporter{client: c, source: source}
+}
+
+// Dropped returns how many messages were discarded because the buffer was
+// full.
+func (c *Client) Dropped() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// enqueue numbers e and appends it to the send buffer.
+func (c *Client) enqueue(e envelope) {
+	c.mu.Lock()
+	c.seq++
+	switch {
+	case e.Sample != nil:
+		e.Sample.Seq = c.seq
+	case e.Event != nil:
+		e.Event.Seq = c.seq
+	}
+	c.pending = append(c.pending, e)
+	if over := len(c.pending) - c.limit; over > 0 {
+		c.pending = c.pending[over:]
+		c.dropped += uint64(over)
+	}
+	c.mu.Unlock()
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// ack discards buffered messages up to and including seq.
+func (c *Client) ack(seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for n < len(c.pending) && envelopeSeq(c.pending[n]) <= seq {
+		n++
+	}
+	c.pending = c.pending[n:]
+}
+
+// unsent returns the buffered messages after seq.
+func (c *Client) unsent(after uint64) []envelope {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.pending {
+		if envelopeSeq(e) > after {
+			return append([]envelope(nil), c.pending[i:]...)
+		}
+	}
+	return nil
+}
+
+func envelopeSeq(e envelope) uint64 {
+	if e.Sample != nil {
+		return e.Sample.Seq
+	}
+	if e.Event != nil {
+		return e.Event.Seq
+	}
+	return 0
+}
+
+// Run connects to the aggregator and streams buffered messages until ctx is
+// done, reconnecting with backoff (1 s up to 30 s) whenever the link drops.
+func (c *Client) Run(ctx context.Context) {
+	retry := retryMin
+	for ctx.Err() == nil {
+		dialer := net.Dialer{Timeout: dialTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+		if err == nil {
+			c.log.Info("connected to aggregator")
+			var sent int
+			sent, err = c.stream(ctx, conn)
+			if sent > 0 {
+				retry = retryMin
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		c.log.Warn("aggregator link down", logging.Field{Key: "error", Value: err}, logging.Field{Key: "retry", Value: retry.String()})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(2*retry, retryMax)
+	}
+}
+
+// stream sends the hello and then every buffered message over conn, reading
+// acknowledgements in the background, until the link fails or ctx is done.
+// It returns the number of messages sent.
+func (c *Client) stream(ctx context.Context, conn net.Conn) (int, error) {
+	defer conn.Close()
+	// Unblock a write stalled on a dead link when ctx ends.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	readErr := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(conn)
+		for {
+			e, err := readFrame(r)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			c.ack(e.Ack)
+		}
+	}()
+
+	w := bufio.NewWriter(conn)
+	err := writeFrame(w, envelope{Hello: &hello{Node: c.node, Session: c.session}})
+	var last uint64
+	sent := 0
+	for err == nil {
+		for _, e := range c.unsent(last) {
+			if err = writeFrame(w, e); err != nil {
+				break
+			}
+			last = envelopeSeq(e)
+			sent++
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return sent, ctx.Err()
+		case err = <-readErr:
+		case <-c.notify:
+		}
+	}
+	return sent, err
+}
+
+// nodeReporter implements telemetry.Reporter for one device on the node.
+type nodeReporter struct {
+	client *Client
+	source string
+}
+
+// Report implements telemetry.Reporter for a single-track update.
+func (r *nodeReporter) Report(angleDeg float64, peak float64, snr float64, confidence float64, state telemetry.LockState, _ *telemetry.DebugInfo) {
+	r.ReportMultiTrack(telemetry.MultiTrackSample{
+		Timestamp: time.Now(),
+		Tracks: []telemetry.TrackSample{{
+			AngleDeg:   angleDeg,
+			Peak:       peak,
+			SNR:        snr,
+			Confidence: confidence,
+			LockState:  state,
+		}},
+	})
+}
+
+// ReportMultiTrack queues the sample for the aggregator.
+func (r *nodeReporter) ReportMultiTrack(sample telemetry.MultiTrackSample) {
+	if len(sample.Tracks) == 0 {
+		return
+	}
+	r.client.enqueue(envelope{Sample: &sampleMsg{Source: r.source, Sample: sample}})
+}
+
+// LogEvent queues an event for the aggregator's event log.
+func (r *nodeReporter) LogEvent(level, message string) {
+	r.client.enqueue(envelope{Event: &eventMsg{Source: r.source, Time: time.Now(), Level: level, Message: message}})
+}
@@ -0,0 +1,331 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// The link carries protobuf-encoded envelopes, each preceded by its length as
+// a uvarint. The schema, in proto3 terms:
+//
+//	message Envelope { Hello hello = 1; Sample sample = 2; Event event = 3; uint64 ack = 4; }
+//	message Hello    { string node = 1; uint64 session = 2; }
+//	message Sample   { uint64 seq = 1; string source = 2; int64 time_unix_nano = 3; repeated Track tracks = 4; }
+//	message Track    { string id = 1; double angle_deg = 2; double peak = 3; double snr = 4;
+//	                   double confidence = 5; string lock_state = 6; string state = 7;
+//	                   double score = 8; double range = 9; double age_seconds = 10; }
+//	message Event    { uint64 seq = 1; string source = 2; int64 time_unix_nano = 3; string level = 4; string message = 5; }
+//
+// Debug and display fields are not sent; the aggregator recomputes display
+// values itself. Unknown fields are skipped so either side can be extended.
+
+// maxFrameSize bounds a single envelope so a corrupt length cannot force a
+// huge allocation.
+const maxFrameSize = 1 << 20
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// envelope is the decoded form of one frame; exactly one field is set.
+type envelope struct {
+	Hello  *hello
+	Sample *sampleMsg
+	Event  *eventMsg
+	Ack    uint64
+}
+
+type hello struct {
+	Node    string
+	Session uint64
+}
+
+// sampleMsg is a MultiTrackSample from one source on a node, numbered in the
+// node's upstream sequence.
+type sampleMsg struct {
+	Seq    uint64
+	Source string
+	Sample telemetry.MultiTrackSample
+}
+
+type eventMsg struct {
+	Seq     uint64
+	Source  string
+	Time    time.Time
+	Level   string
+	Message string
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+func appendDoubleField(b []byte, field int, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(appendTag(b, field, wireFixed64), math.Float64bits(v))
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendStringField(b []byte, field int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	return appendBytesField(b, field, []byte(v))
+}
+
+func encodeTrack(t telemetry.TrackSample) []byte {
+	var b []byte
+	b = appendStringField(b, 1, t.ID)
+	b = appendDoubleField(b, 2, t.AngleDeg)
+	b = appendDoubleField(b, 3, t.Peak)
+	b = appendDoubleField(b, 4, t.SNR)
+	b = appendDoubleField(b, 5, t.Confidence)
+	b = appendStringField(b, 6, string(t.LockState))
+	b = appendStringField(b, 7, t.State)
+	b = appendDoubleField(b, 8, t.Score)
+	b = appendDoubleField(b, 9, t.Range)
+	return appendDoubleField(b, 10, t.AgeSeconds)
+}
+
+// marshal encodes e as a protobuf Envelope.
+func (e envelope) marshal() []byte {
+	var b []byte
+	switch {
+	case e.Hello != nil:
+		var m []byte
+		m = appendStringField(m, 1, e.Hello.Node)
+		m = appendVarintField(m, 2, e.Hello.Session)
+		b = appendBytesField(b, 1, m)
+	case e.Sample != nil:
+		var m []byte
+		m = appendVarintField(m, 1, e.Sample.Seq)
+		m = appendStringField(m, 2, e.Sample.Source)
+		m = appendVarintField(m, 3, uint64(e.Sample.Sample.Timestamp.UnixNano()))
+		for _, t := range e.Sample.Sample.Tracks {
+			m = appendBytesField(m, 4, encodeTrack(t))
+		}
+		b = appendBytesField(b, 2, m)
+	case e.Event != nil:
+		var m []byte
+		m = appendVarintField(m, 1, e.Event.Seq)
+		m = appendStringField(m, 2, e.Event.Source)
+		m = appendVarintField(m, 3, uint64(e.Event.Time.UnixNano()))
+		m = appendStringField(m, 4, e.Event.Level)
+		m = appendStringField(m, 5, e.Event.Message)
+		b = appendBytesField(b, 3, m)
+	default:
+		b = appendVarintField(b, 4, e.Ack)
+	}
+	return b
+}
+
+// field is one decoded protobuf field. Varint and fixed64 values are in value;
+// length-delimited values in data.
+type field struct {
+	num   int
+	value uint64
+	data  []byte
+}
+
+// fields splits a protobuf message into its fields.
+func fields(b []byte) ([]field, error) {
+	var out []field
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("bad field tag")
+		}
+		b = b[n:]
+		f := field{num: int(tag >> 3)}
+		switch tag & 7 {
+		case wireVarint:
+			f.value, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errors.New("bad varint")
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errors.New("short fixed64")
+			}
+			f.value = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return nil, errors.New("bad length-delimited field")
+			}
+			f.data = b[n : n+int(size)]
+			b = b[n+int(size):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", tag&7)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func decodeTrack(b []byte) (telemetry.TrackSample, error) {
+	var t telemetry.TrackSample
+	fs, err := fields(b)
+	if err != nil {
+		return t, err
+	}
+	for _, f := range fs {
+		v := math.Float64frombits(f.value)
+		switch f.num {
+		case 1:
+			t.ID = string(f.data)
+		case 2:
+			t.AngleDeg = v
+		case 3:
+			t.Peak = v
+		case 4:
+			t.SNR = v
+		case 5:
+			t.Confidence = v
+		case 6:
+			t.LockState = telemetry.LockState(f.data)
+		case 7:
+			t.State = string(f.data)
+		case 8:
+			t.Score = v
+		case 9:
+			t.Range = v
+		case 10:
+			t.AgeSeconds = v
+		}
+	}
+	return t, nil
+}
+
+func decodeHello(b []byte) (*hello, error) {
+	fs, err := fields(b)
+	m := &hello{}
+	for _, f := range fs {
+		switch f.num {
+		case 1:
+			m.Node = string(f.data)
+		case 2:
+			m.Session = f.value
+		}
+	}
+	return m, err
+}
+
+func decodeSample(b []byte) (*sampleMsg, error) {
+	fs, err := fields(b)
+	if err != nil {
+		return nil, err
+	}
+	m := &sampleMsg{}
+	for _, f := range fs {
+		switch f.num {
+		case 1:
+			m.Seq = f.value
+		case 2:
+			m.Source = string(f.data)
+		case 3:
+			m.Sample.Timestamp = time.Unix(0, int64(f.value))
+		case 4:
+			t, err := decodeTrack(f.data)
+			if err != nil {
+				return nil, err
+			}
+			m.Sample.Tracks = append(m.Sample.Tracks, t)
+		}
+	}
+	return m, nil
+}
+
+func decodeEvent(b []byte) (*eventMsg, error) {
+	fs, err := fields(b)
+	m := &eventMsg{}
+	for _, f := range fs {
+		switch f.num {
+		case 1:
+			m.Seq = f.value
+		case 2:
+			m.Source = string(f.data)
+		case 3:
+			m.Time = time.Unix(0, int64(f.value))
+		case 4:
+			m.Level = string(f.data)
+		case 5:
+			m.Message = string(f.data)
+		}
+	}
+	return m, err
+}
+
+// unmarshalEnvelope decodes a protobuf Envelope.
+func unmarshalEnvelope(b []byte) (envelope, error) {
+	var e envelope
+	fs, err := fields(b)
+	if err != nil {
+		return e, err
+	}
+	for _, f := range fs {
+		switch f.num {
+		case 1:
+			e.Hello, err = decodeHello(f.data)
+		case 2:
+			e.Sample, err = decodeSample(f.data)
+		case 3:
+			e.Event, err = decodeEvent(f.data)
+		case 4:
+			e.Ack = f.value
+		}
+		if err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// writeFrame writes e with its length prefix.
+func writeFrame(w io.Writer, e envelope) error {
+	data := e.marshal()
+	frame := binary.AppendUvarint(make([]byte, 0, len(data)+binary.MaxVarintLen32), uint64(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// readFrame reads one length-prefixed envelope.
+func readFrame(r *bufio.Reader) (envelope, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return envelope{}, err
+	}
+	if size > maxFrameSize {
+		return envelope{}, fmt.Errorf("frame of %d bytes exceeds limit", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return envelope{}, err
+	}
+	return unmarshalEnvelope(data)
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"math"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+const (
+	// defaultAcquireMass is the share of the window a mode must hold when
+	// Config.AcquireMass is zero.
+	defaultAcquireMass = 0.6
+	// acquireBinDeg is the histogram bin width. A mode is a bin together
+	// with both neighbours, so estimates a few degrees apart still agree.
+	acquireBinDeg = 2
+	acquireBins   = 180 / acquireBinDeg
+)
+
+// acquisitionHistogram collects the coarse-scan estimates of one acquisition
+// in angle bins and finds the angle enough of them agree on. Only the last
+// window estimates count, so an acquisition that began on noise recovers
+// once the target shows up.
+type acquisitionHistogram struct {
+	need   int // estimates a mode must hold
+	counts [acquireBins]int
+	angles []float64 // ring of the last window estimates
+	delays []float64
+	next   int
+	filled int
+}
+
+// newAcquisitionHistogram returns nil when window is not positive, which
+// turns the histogram off.
+func newAcquisitionHistogram(window int, mass float64) *acquisitionHistogram {
+	if window <= 0 {
+		return nil
+	}
+	if mass <= 0 {
+		mass = defaultAcquireMass
+	}
+	return &acquisitionHistogram{
+		need:   max(int(math.Ceil(mass*float64(window))), 1),
+		angles: make([]float64, window),
+		delays: make([]float64, window),
+	}
+}
+
+// collecting reports whether an acquisition is under way.
+func (h *acquisitionHistogram) collecting() bool {
+	return h != nil && h.filled > 0
+}
+
+// add records one estimate and returns the mean angle and phase delay of the
+// estimates in the strongest mode, the share of the window the mode holds
+// and whether that is enough to acquire.
+func (h *acquisitionHistogram) add(angle, delay float64) (modeAngle, modeDelay, mass float64, ok bool) {
+	if h.filled == len(h.angles) {
+		h.counts[acquireBin(h.angles[h.next])]--
+	}
+	h.angles[h.next], h.delays[h.next] = angle, delay
+	h.counts[acquireBin(angle)]++
+	h.next = (h.next + 1) % len(h.angles)
+	h.filled = min(h.filled+1, len(h.angles))
+
+	best, bestCount := 0, 0
+	for k := range h.counts {
+		count := h.counts[k]
+		if k > 0 {
+			count += h.counts[k-1]
+		}
+		if k < acquireBins-1 {
+			count += h.counts[k+1]
+		}
+		if count > bestCount {
+			best, bestCount = k, count
+		}
+	}
+	for i := range h.filled {
+		if bin := acquireBin(h.angles[i]); bin >= best-1 && bin <= best+1 {
+			modeAngle += h.angles[i]
+			modeDelay += h.delays[i]
+		}
+	}
+	n := float64(bestCount)
+	return modeAngle / n, modeDelay / n, n / float64(len(h.angles)), bestCount >= h.need
+}
+
+// reset empties the histogram for the next acquisition.
+func (h *acquisitionHistogram) reset() {
+	h.counts = [acquireBins]int{}
+	h.next, h.filled = 0, 0
+}
+
+// acquireBin returns the histogram bin of an angle in degrees.
+func acquireBin(angle float64) int {
+	return min(max(int(math.Floor((angle+90)/acquireBinDeg)), 0), acquireBins-1)
+}
+
+// acquire passes a coarse-scan estimate made while searching through the
+// acquisition histogram. Until a mode with enough mass has emerged it keeps
+// the tracker searching and schedules another coarse scan; then it replaces
+// the estimate with the mode's mean angle and phase delay. prev is the lock
+// state before this estimate. With the histogram off it returns its input.
+func (t *Tracker) acquire(theta, delay float64, prev, state telemetry.LockState) (float64, float64, telemetry.LockState) {
+	if t.acq == nil || prev != telemetry.LockStateSearching {
+		return theta, delay, state
+	}
+	modeTheta, modeDelay, mass, ok := t.acq.add(theta, delay)
+	if !ok || state == telemetry.LockStateSearching {
+		t.logger.Debug("acquisition histogram",
+			logging.Field{Key: "estimate_deg", Value: theta},
+			logging.Field{Key: "mode_deg", Value: modeTheta},
+			logging.Field{Key: "mass", Value: mass})
+		t.rescan = t.conv.reason
+		return theta, delay, telemetry.LockStateSearching
+	}
+	t.logger.Info("target acquired",
+		logging.Field{Key: "subsystem", Value: "tracker"},
+		logging.Field{Key: "angle_deg", Value: modeTheta},
+		logging.Field{Key: "mass", Value: mass},
+		logging.Field{Key: "estimates", Value: t.acq.filled})
+	t.acq.reset()
+	t.rescan = ""
+	return modeTheta, modeDelay, state
+}
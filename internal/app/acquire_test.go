@@ -0,0 +1,76 @@
+package app
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestAcquisitionHistogram(t *testing.T) {
+	tests := []struct {
+		name      string
+		window    int
+		mass      float64
+		estimates []float64
+		wantAt    int // index of the estimate that acquires, -1 for none
+		wantAngle float64
+	}{
+		{name: "noise spikes", window: 5, estimates: []float64{-60, 12, 47, -3, 80}, wantAt: -1},
+		{name: "steady target", window: 5, estimates: []float64{20, 21, 70, 19}, wantAt: 3, wantAngle: 20},
+		{name: "spike first", window: 3, mass: 1, estimates: []float64{-45, 30, 31, 30.5}, wantAt: 3, wantAngle: 30.5},
+		{name: "single estimate", window: 1, estimates: []float64{-10}, wantAt: 0, wantAngle: -10},
+		{name: "edge bins", window: 2, mass: 1, estimates: []float64{89.9, 88.5}, wantAt: 1, wantAngle: 89.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newAcquisitionHistogram(tt.window, tt.mass)
+			at := -1
+			var angle, delay float64
+			for i, est := range tt.estimates {
+				var ok bool
+				if angle, delay, _, ok = h.add(est, 2*est); ok {
+					at = i
+					break
+				}
+			}
+			if at != tt.wantAt {
+				t.Fatalf("acquired at estimate %d, want %d", at, tt.wantAt)
+			}
+			if at >= 0 && (math.Abs(angle-tt.wantAngle) > 1e-9 || math.Abs(delay-2*tt.wantAngle) > 1e-9) {
+				t.Fatalf("mode %v° (delay %v), want %v°", angle, delay, tt.wantAngle)
+			}
+		})
+	}
+	if newAcquisitionHistogram(0, 0) != nil {
+		t.Fatal("zero window should turn the histogram off")
+	}
+}
+
+func TestAcquireHoldsSearching(t *testing.T) {
+	tr := &Tracker{logger: logging.New(logging.Info, logging.Text, io.Discard), acq: newAcquisitionHistogram(3, 0)}
+	tr.conv = newConvergenceDetector(0, 0, 0)
+	tr.conv.scanned(time.Unix(0, 0), scanStartup)
+	searching, tracking := telemetry.LockStateSearching, telemetry.LockStateTracking
+
+	if _, _, state := tr.acquire(10, 1, searching, tracking); state != searching || tr.rescan != scanStartup {
+		t.Fatalf("first estimate: state %v, rescan %q", state, tr.rescan)
+	}
+	if !tr.acq.collecting() {
+		t.Fatal("histogram not collecting after the first estimate")
+	}
+	tr.rescan = ""
+	theta, _, state := tr.acquire(12, 1, searching, tracking)
+	if state != tracking || tr.rescan != "" || theta != 11 {
+		t.Fatalf("second estimate: state %v at %v°, rescan %q", state, theta, tr.rescan)
+	}
+	if tr.acq.collecting() {
+		t.Fatal("histogram not reset after acquisition")
+	}
+	if theta, _, state := tr.acquire(-40, 1, tracking, tracking); state != tracking || theta != -40 {
+		t.Fatalf("estimate while tracking changed to %v at %v°", state, theta)
+	}
+}
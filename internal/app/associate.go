@@ -0,0 +1,350 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Association strategies built into the multi-target track manager.
+const (
+	// AssociateNearest lets each detection in turn update the closest track
+	// in the gate. It is cheap but swaps tracks when targets cross.
+	AssociateNearest = "nearest"
+	// AssociateGNN (global nearest neighbour) pairs tracks and detections
+	// one to one so the summed angle distance is smallest, solved with the
+	// Hungarian algorithm.
+	AssociateGNN = "gnn"
+	// AssociateJPDA (joint probabilistic data association) weighs every
+	// detection in a track's gate by the probability of all feasible joint
+	// assignments and moves the track by the weighted innovation, so nearby
+	// targets pull each other less than a hard assignment would.
+	AssociateJPDA = "jpda"
+)
+
+// Assignment updates Tracks[Track] with Detection.
+type Assignment struct {
+	Track     int
+	Detection Detection
+}
+
+// Associator decides which detections of one update update which tracks.
+// tracks are the live tracks in creation order, gateDeg the largest angle
+// change still associated with a track. It returns the track updates, in the
+// order they are applied, and the indices of the detections left to start
+// new tracks.
+type Associator func(tracks []Track, detections []Detection, gateDeg float64) (updates []Assignment, unassigned []int)
+
+var (
+	associatorsMu sync.RWMutex
+	associators   = map[string]Associator{
+		AssociateNearest: NearestAssociation,
+		AssociateGNN:     GNNAssociation,
+		AssociateJPDA:    JPDAAssociation,
+	}
+)
+
+// RegisterAssociator makes an association strategy selectable by name
+// (Config Association, monopulse -track-association). Register from an init
+// function before the tracker starts.
+func RegisterAssociator(name string, associate Associator) error {
+	if name == "" || associate == nil {
+		return fmt.Errorf("register associator: name and function are required")
+	}
+	associatorsMu.Lock()
+	defer associatorsMu.Unlock()
+	if _, ok := associators[name]; ok {
+		return fmt.Errorf("register associator: %q already registered", name)
+	}
+	associators[name] = associate
+	return nil
+}
+
+// AssociationNames returns the registered association strategies, sorted.
+func AssociationNames() []string {
+	associatorsMu.RLock()
+	defer associatorsMu.RUnlock()
+	names := make([]string, 0, len(associators))
+	for name := range associators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupAssociator returns the strategy registered as name; empty selects
+// AssociateNearest.
+func lookupAssociator(name string) (Associator, error) {
+	if name == "" {
+		name = AssociateNearest
+	}
+	associatorsMu.RLock()
+	associate, ok := associators[name]
+	associatorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown track association %q (have %s)", name, strings.Join(AssociationNames(), ", "))
+	}
+	return associate, nil
+}
+
+// NearestAssociation gives each detection the closest track within the
+// gate, independently of the other detections, so two detections may update
+// the same track.
+func NearestAssociation(tracks []Track, detections []Detection, gateDeg float64) ([]Assignment, []int) {
+	var updates []Assignment
+	var unassigned []int
+	for j, det := range detections {
+		best, bestDelta := -1, math.MaxFloat64
+		for i, track := range tracks {
+			if delta := math.Abs(track.Angle - det.Angle); delta < bestDelta && delta <= gateDeg {
+				best, bestDelta = i, delta
+			}
+		}
+		if best < 0 {
+			unassigned = append(unassigned, j)
+			continue
+		}
+		updates = append(updates, Assignment{Track: best, Detection: det})
+	}
+	return updates, unassigned
+}
+
+// GNNAssociation assigns detections to tracks one to one, minimizing the
+// summed angle distance over all gated pairs.
+func GNNAssociation(tracks []Track, detections []Detection, gateDeg float64) ([]Assignment, []int) {
+	// Pairs outside the gate cost more than any gated assignment could, so
+	// the solver only picks them when nothing else is left; they are then
+	// dropped.
+	forbidden := gateDeg*float64(len(tracks)+len(detections)) + 1
+	cost := make([][]float64, len(tracks))
+	for i, track := range tracks {
+		cost[i] = make([]float64, len(detections))
+		for j, det := range detections {
+			if delta := math.Abs(track.Angle - det.Angle); delta <= gateDeg {
+				cost[i][j] = delta
+			} else {
+				cost[i][j] = forbidden
+			}
+		}
+	}
+	used := make([]bool, len(detections))
+	var updates []Assignment
+	for i, j := range hungarian(cost) {
+		if j >= 0 && cost[i][j] < forbidden {
+			updates = append(updates, Assignment{Track: i, Detection: detections[j]})
+			used[j] = true
+		}
+	}
+	var unassigned []int
+	for j := range detections {
+		if !used[j] {
+			unassigned = append(unassigned, j)
+		}
+	}
+	return updates, unassigned
+}
+
+// hungarian solves the rectangular assignment problem for cost (rows ×
+// columns) and returns, per row, the assigned column or -1.
+func hungarian(cost [][]float64) []int {
+	rows := len(cost)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(cost[0])
+	n := max(rows, cols)
+	// Square the matrix with zero-cost dummy rows and columns.
+	at := func(i, j int) float64 {
+		if i < rows && j < cols {
+			return cost[i][j]
+		}
+		return 0
+	}
+	// Potentials u (rows) and v (columns), 1-based with column 0 as the
+	// virtual start; match[j] is the row matched to column j.
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	match := make([]int, n+1)
+	way := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		match[0] = i
+		j0 := 0
+		minv := make([]float64, n+1)
+		usedCol := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for match[j0] != 0 {
+			usedCol[j0] = true
+			i0, delta, j1 := match[j0], math.Inf(1), 0
+			for j := 1; j <= n; j++ {
+				if usedCol[j] {
+					continue
+				}
+				if cur := at(i0-1, j-1) - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if usedCol[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			match[j0] = match[j1]
+			j0 = j1
+		}
+	}
+	out := make([]int, rows)
+	for i := range out {
+		out[i] = -1
+	}
+	for j := 1; j <= n; j++ {
+		if i := match[j] - 1; i >= 0 && i < rows && j-1 < cols {
+			out[i] = j - 1
+		}
+	}
+	return out
+}
+
+const (
+	// jpdaDetectProb is the probability that a live track yields a detection
+	// in an update.
+	jpdaDetectProb = 0.9
+	// jpdaClutterPerDeg is the expected number of false detections per
+	// degree and update.
+	jpdaClutterPerDeg = 0.01
+	// jpdaMaxEvents bounds the joint events enumerated; denser scenes fall
+	// back to GNNAssociation.
+	jpdaMaxEvents = 100000
+)
+
+// JPDAAssociation updates every track that has a detection in its gate with
+// the probability-weighted mean innovation of those detections. The angle
+// error is taken as Gaussian with a standard deviation of half the gate. The
+// strongest-weighted detection supplies peak, SNR and confidence. Detections
+// outside every gate start new tracks.
+func JPDAAssociation(tracks []Track, detections []Detection, gateDeg float64) ([]Assignment, []int) {
+	sigma := math.Max(gateDeg/2, 1e-6)
+	// like[i][j] is the likelihood of detection j originating from track i,
+	// zero outside the gate.
+	like := make([][]float64, len(tracks))
+	gated := make([]bool, len(detections))
+	for i, track := range tracks {
+		like[i] = make([]float64, len(detections))
+		for j, det := range detections {
+			d := track.Angle - det.Angle
+			if math.Abs(d) > gateDeg {
+				continue
+			}
+			like[i][j] = jpdaDetectProb * math.Exp(-d*d/(2*sigma*sigma)) / (sigma * math.Sqrt(2*math.Pi))
+			gated[j] = true
+		}
+	}
+	beta, ok := jpdaWeights(like, len(detections))
+	if !ok {
+		return GNNAssociation(tracks, detections, gateDeg)
+	}
+
+	var updates []Assignment
+	for i, track := range tracks {
+		best, bestBeta := -1, 0.0
+		var angle, delay float64
+		for j, det := range detections {
+			if beta[i][j] == 0 {
+				continue
+			}
+			angle += beta[i][j] * (det.Angle - track.Angle)
+			delay += beta[i][j] * (det.PhaseDelay - track.PhaseDelay)
+			if beta[i][j] > bestBeta {
+				best, bestBeta = j, beta[i][j]
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		det := detections[best]
+		det.Angle, det.PhaseDelay = track.Angle+angle, track.PhaseDelay+delay
+		updates = append(updates, Assignment{Track: i, Detection: det})
+	}
+	var unassigned []int
+	for j := range detections {
+		if !gated[j] {
+			unassigned = append(unassigned, j)
+		}
+	}
+	return updates, unassigned
+}
+
+// jpdaWeights enumerates the joint events, in which every track takes at
+// most one gated detection and no detection goes to two tracks, and returns
+// the marginal probability beta[i][j] of detection j belonging to track i.
+// ok is false when the scene exceeds jpdaMaxEvents.
+func jpdaWeights(like [][]float64, numDetections int) (beta [][]float64, ok bool) {
+	beta = make([][]float64, len(like))
+	for i := range beta {
+		beta[i] = make([]float64, numDetections)
+	}
+	pick := make([]int, len(like)) // detection per track in the current event, -1 for none
+	used := make([]bool, numDetections)
+	var total float64
+	events := 0
+	var walk func(i int, weight float64) bool
+	walk = func(i int, weight float64) bool {
+		if i == len(like) {
+			if events++; events > jpdaMaxEvents {
+				return false
+			}
+			// Every detection not taken by a track is clutter.
+			for _, u := range used {
+				if !u {
+					weight *= jpdaClutterPerDeg
+				}
+			}
+			total += weight
+			for t, j := range pick {
+				if j >= 0 {
+					beta[t][j] += weight
+				}
+			}
+			return true
+		}
+		pick[i] = -1
+		if !walk(i+1, weight*(1-jpdaDetectProb)) {
+			return false
+		}
+		for j, l := range like[i] {
+			if l == 0 || used[j] {
+				continue
+			}
+			used[j], pick[i] = true, j
+			if !walk(i+1, weight*l) {
+				return false
+			}
+			used[j], pick[i] = false, -1
+		}
+		return true
+	}
+	if !walk(0, 1) {
+		return nil, false
+	}
+	if total > 0 {
+		for i := range beta {
+			for j := range beta[i] {
+				beta[i][j] /= total
+			}
+		}
+	}
+	return beta, true
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHungarian(t *testing.T) {
+	tests := []struct {
+		name string
+		cost [][]float64
+		want []int
+	}{
+		{name: "square", cost: [][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}, want: []int{1, 0, 2}},
+		{name: "more columns", cost: [][]float64{{9, 1, 8}, {1, 9, 8}}, want: []int{1, 0}},
+		{name: "more rows", cost: [][]float64{{5}, {1}, {3}}, want: []int{-1, 0, -1}},
+		{name: "empty", cost: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hungarian(tt.cost)
+			if len(got) != len(tt.want) {
+				t.Fatalf("hungarian() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("hungarian() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// crossingUpdate starts tracks at 0° and 4° with a 3° gate, then feeds two
+// detections that are both closer to the first track, as when targets cross.
+func crossingUpdate(t *testing.T, association string) []Track {
+	t.Helper()
+	policy, err := Config{TrackGateDeg: 3, Association: association}.trackPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := NewTrackManager(4, time.Minute, 0, 10)
+	tm.SetPolicy(policy)
+	now := time.Now()
+	tm.Update([]Detection{{Angle: 0, SNR: 20}, {Angle: 4, SNR: 20}}, now)
+	return tm.Update([]Detection{{Angle: 1.5, SNR: 20}, {Angle: 1.9, SNR: 20}}, now.Add(time.Second))
+}
+
+func TestAssociationCrossingTargets(t *testing.T) {
+	tests := []struct {
+		association  string
+		wantA, wantB float64 // angles of the tracks started at 0° and 4°
+		tolerance    float64
+		wantBMissed  bool
+	}{
+		{association: AssociateNearest, wantA: 1.9, wantB: 4, wantBMissed: true},
+		{association: AssociateGNN, wantA: 1.5, wantB: 1.9},
+		// JPDA blends both detections into each track.
+		{association: AssociateJPDA, wantA: 1.6, wantB: 1.8, tolerance: 0.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.association, func(t *testing.T) {
+			tracks := crossingUpdate(t, tt.association)
+			if len(tracks) != 2 {
+				t.Fatalf("want 2 tracks, got %+v", tracks)
+			}
+			a, b := tracks[0], tracks[1]
+			if math.Abs(a.Angle-tt.wantA) > tt.tolerance+1e-9 || math.Abs(b.Angle-tt.wantB) > tt.tolerance+1e-9 {
+				t.Fatalf("tracks at %.3f and %.3f, want %.3f and %.3f", a.Angle, b.Angle, tt.wantA, tt.wantB)
+			}
+			if missed := b.ConsecutiveMisses > 0; missed != tt.wantBMissed {
+				t.Fatalf("second track missed = %v, want %v", missed, tt.wantBMissed)
+			}
+		})
+	}
+}
+
+func TestAssociationStartsTracksOutsideGates(t *testing.T) {
+	for _, association := range []string{AssociateNearest, AssociateGNN, AssociateJPDA} {
+		t.Run(association, func(t *testing.T) {
+			policy, err := Config{Association: association}.trackPolicy()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tm := NewTrackManager(4, time.Minute, 0, 10)
+			tm.SetPolicy(policy)
+			now := time.Now()
+			tm.Update([]Detection{{Angle: -30, SNR: 20}}, now)
+			tracks := tm.Update([]Detection{{Angle: -29, SNR: 20}, {Angle: 40, SNR: 20}, {Angle: 41, SNR: 20}}, now.Add(time.Second))
+			// 41° falls in the gate of the track 40° just started.
+			if len(tracks) != 2 || math.Abs(tracks[0].Angle+29) > 0.5 || tracks[1].Angle != 41 || tracks[1].TotalDetections != 2 {
+				t.Fatalf("want the old track near -29° and one new track at 40-41°, got %+v", tracks)
+			}
+		})
+	}
+}
+
+func TestAssociationLookup(t *testing.T) {
+	if _, err := (Config{Association: "psychic"}).trackPolicy(); err == nil || !strings.Contains(err.Error(), "gnn") {
+		t.Fatalf("unknown association accepted or not listed: %v", err)
+	}
+	if err := RegisterAssociator(AssociateGNN, NearestAssociation); err == nil {
+		t.Fatal("duplicate registration should fail")
+	}
+}
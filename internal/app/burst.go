@@ -0,0 +1,128 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+const (
+	// burstHysteresisDB separates the burst start and end thresholds.
+	burstHysteresisDB = 4
+	// burstFlushBuffers bounds how many buffers of one burst are accumulated
+	// before they are flushed, so a continuous emitter still updates tracks.
+	burstFlushBuffers = 50
+	// burstGateDeg groups estimates within one burst into one detection.
+	burstGateDeg = 5.0
+)
+
+// burstState accumulates the angle estimates of the current burst. Estimates
+// are grouped by track ID, or by angle for detections without one, and
+// averaged weighted by linear SNR.
+type burstState struct {
+	detector *dsp.BurstDetector
+	buffers  int
+	groups   []burstGroup
+}
+
+type burstGroup struct {
+	id                       int
+	weight                   float64
+	angle, delay, confidence float64 // weighted sums
+	peak, snr                float64 // maxima
+	lock                     telemetry.LockState
+}
+
+func newBurstState(thresholdDB float64) *burstState {
+	return &burstState{detector: dsp.NewBurstDetector(thresholdDB, thresholdDB-burstHysteresisDB)}
+}
+
+// gateBurst reports whether the buffer lies inside a burst and should be
+// processed. The estimates of a burst that just ended are flushed.
+func (t *Tracker) gateBurst(rx0, rx1 []complex64) bool {
+	if t.burst == nil {
+		return true
+	}
+	active, ended := t.burst.detector.Update(rx0, rx1)
+	if ended {
+		t.flushBurst(t.now())
+	}
+	return active
+}
+
+// updateManager feeds detections to the track manager, or accumulates them
+// into per-burst detections when burst mode is enabled.
+func (t *Tracker) updateManager(detections []Detection, now time.Time) {
+	if t.burst == nil {
+		t.manager.Update(detections, now)
+		return
+	}
+	t.burst.add(detections)
+	if t.burst.buffers >= burstFlushBuffers {
+		t.flushBurst(now)
+	}
+}
+
+// flushBurst hands the accumulated burst detections to the track manager.
+func (t *Tracker) flushBurst(now time.Time) {
+	detections := t.burst.detections()
+	t.burst.buffers, t.burst.groups = 0, nil
+	if len(detections) == 0 || t.manager == nil {
+		return
+	}
+	t.manager.Update(detections, now)
+	t.logger.Debug("burst detections", logging.Field{Key: "subsystem", Value: "tracker"}, logging.Field{Key: "count", Value: len(detections)})
+}
+
+func (b *burstState) add(detections []Detection) {
+	b.buffers++
+	for _, det := range detections {
+		g := b.group(det)
+		w := math.Pow(10, det.SNR/10)
+		g.weight += w
+		g.angle += w * det.Angle
+		g.delay += w * det.PhaseDelay
+		g.confidence += w * det.Confidence
+		g.peak = math.Max(g.peak, det.Peak)
+		g.snr = math.Max(g.snr, det.SNR)
+		g.lock = det.LockState
+	}
+}
+
+// group returns the group det belongs to, creating it when needed.
+func (b *burstState) group(det Detection) *burstGroup {
+	for i := range b.groups {
+		g := &b.groups[i]
+		if det.ID > 0 && g.id == det.ID {
+			return g
+		}
+		if (det.ID <= 0 || g.id <= 0) && math.Abs(g.angle/g.weight-det.Angle) <= burstGateDeg {
+			return g
+		}
+	}
+	b.groups = append(b.groups, burstGroup{id: det.ID, peak: math.Inf(-1), snr: math.Inf(-1)})
+	return &b.groups[len(b.groups)-1]
+}
+
+// detections returns one SNR-weighted detection per group.
+func (b *burstState) detections() []Detection {
+	out := make([]Detection, 0, len(b.groups))
+	for _, g := range b.groups {
+		if g.weight == 0 {
+			continue
+		}
+		out = append(out, Detection{
+			ID:         g.id,
+			Angle:      g.angle / g.weight,
+			PhaseDelay: g.delay / g.weight,
+			Confidence: g.confidence / g.weight,
+			Peak:       g.peak,
+			SNR:        g.snr,
+			LockState:  g.lock,
+		})
+	}
+	return out
+}
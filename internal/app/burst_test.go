@@ -0,0 +1,63 @@
+package app
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestBurstStateAveragesPerGroup(t *testing.T) {
+	b := newBurstState(0)
+	b.add([]Detection{{Angle: 10, SNR: 20}, {Angle: -30, SNR: 10}})
+	b.add([]Detection{{Angle: 12, SNR: 20}, {ID: 4, Angle: -31, SNR: 10}})
+
+	got := b.detections()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 grouped detections, got %+v", got)
+	}
+	if math.Abs(got[0].Angle-11) > 1e-9 || got[0].SNR != 20 {
+		t.Fatalf("unexpected first detection %+v", got[0])
+	}
+	if math.Abs(got[1].Angle+30.5) > 1e-9 {
+		t.Fatalf("unexpected second detection %+v", got[1])
+	}
+}
+
+func TestBurstModeFeedsOneDetectionPerBurst(t *testing.T) {
+	tracker := NewTracker(sdr.NewMock(), nil, logging.New(logging.Info, logging.Text, io.Discard), Config{
+		NumSamples:   64,
+		TrackingMode: "multi",
+		MaxTracks:    4,
+		TrackTimeout: time.Minute,
+		BurstMode:    true,
+	})
+	tracker.applyTrackingMode("multi")
+	tracker.burst = newBurstState(tracker.cfg.BurstThresholdDB)
+
+	quiet, loud := make([]complex64, 64), make([]complex64, 64)
+	for i := range quiet {
+		quiet[i], loud[i] = 0.001, 0.1
+	}
+	now := time.Now()
+	for i, buf := range [][]complex64{quiet, quiet, loud, loud, loud, quiet} {
+		if !tracker.gateBurst(buf, buf) {
+			if i >= 2 && i < 5 {
+				t.Fatalf("buffer %d should be inside the burst", i)
+			}
+			continue
+		}
+		tracker.updateManager([]Detection{{Angle: 20 + float64(i), SNR: 15, Confidence: 0.8}}, now)
+		if n := len(tracker.manager.Tracks()); n != 0 {
+			t.Fatalf("manager updated mid-burst (%d tracks)", n)
+		}
+	}
+
+	tracks := tracker.manager.Tracks()
+	if len(tracks) != 1 || math.Abs(tracks[0].Angle-23) > 1e-9 || tracks[0].TotalDetections != 1 {
+		t.Fatalf("expected one averaged burst detection at 23°, got %+v", tracks)
+	}
+}
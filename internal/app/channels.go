@@ -0,0 +1,54 @@
+package app
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+const (
+	// channelPhaseWindow is the number of iterations the inter-channel phase
+	// difference variance is computed over.
+	channelPhaseWindow = 32
+	// channelPowerFloorDBFS stands in for the -Inf power of a silent buffer,
+	// which JSON cannot carry.
+	channelPowerFloorDBFS = -200
+)
+
+// channelStats follows the raw per-channel power and the inter-channel phase
+// difference, the first things to check for cabling and calibration faults.
+type channelStats struct {
+	fullScale float64
+	phasors   [channelPhaseWindow]complex128
+	next      int
+	count     int
+}
+
+// observe records the phase difference (radians) of one iteration and
+// returns its circular variance over the window in degrees squared. The
+// variance is that of the wrapped normal distribution with the same mean
+// resultant length, so it stays meaningful across the ±180° wrap.
+func (s *channelStats) observe(diff float64) float64 {
+	s.phasors[s.next] = cmplx.Rect(1, diff)
+	s.next = (s.next + 1) % channelPhaseWindow
+	s.count = min(s.count+1, channelPhaseWindow)
+	var sum complex128
+	for _, p := range s.phasors[:s.count] {
+		sum += p
+	}
+	r := math.Min(math.Max(cmplx.Abs(sum)/float64(s.count), 1e-12), 1)
+	return -2 * math.Log(r) * (180 / math.Pi) * (180 / math.Pi)
+}
+
+// annotateChannels adds the raw channel power and phase difference of the
+// buffers to debug. The phase difference is measured before phase
+// calibration.
+func (t *Tracker) annotateChannels(debug *telemetry.DebugInfo, rx0, rx1 []complex64) {
+	diff := dsp.PhaseDifference(rx0, rx1)
+	debug.Rx0PowerDBFS = math.Max(dsp.PowerDBFS(rx0, t.channels.fullScale), channelPowerFloorDBFS)
+	debug.Rx1PowerDBFS = math.Max(dsp.PowerDBFS(rx1, t.channels.fullScale), channelPowerFloorDBFS)
+	debug.PhaseDiffDeg = diff * 180 / math.Pi
+	debug.PhaseDiffVarDeg2 = t.channels.observe(diff)
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestChannelStatsPhaseVariance(t *testing.T) {
+	deg := math.Pi / 180
+	tests := []struct {
+		name    string
+		diffs   []float64 // radians
+		wantMin float64   // deg²
+		wantMax float64
+	}{
+		{name: "steady", diffs: []float64{30 * deg, 30 * deg, 30 * deg}, wantMin: 0, wantMax: 1e-9},
+		{name: "across the wrap", diffs: []float64{179 * deg, -179 * deg, 179 * deg, -179 * deg}, wantMin: 0.5, wantMax: 1.5},
+		{name: "jitter", diffs: []float64{-10 * deg, 10 * deg, -10 * deg, 10 * deg}, wantMin: 95, wantMax: 105},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s channelStats
+			var got float64
+			for _, d := range tt.diffs {
+				got = s.observe(d)
+			}
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Fatalf("variance %.3f deg², want [%g, %g]", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestAnnotateChannels(t *testing.T) {
+	rx0 := make([]complex64, 256)
+	rx1 := make([]complex64, 256)
+	for i := range rx0 {
+		rx0[i] = complex64(cmplx.Rect(0.5, float64(i)*0.1))
+		rx1[i] = complex64(cmplx.Rect(0.05, float64(i)*0.1-math.Pi/4))
+	}
+	tr := &Tracker{channels: channelStats{fullScale: 1}}
+	debug := &telemetry.DebugInfo{}
+	tr.annotateChannels(debug, rx0, rx1)
+	if math.Abs(debug.Rx0PowerDBFS+6.02) > 0.01 || math.Abs(debug.Rx1PowerDBFS+26.02) > 0.01 {
+		t.Fatalf("powers %.2f / %.2f dBFS, want -6.02 / -26.02", debug.Rx0PowerDBFS, debug.Rx1PowerDBFS)
+	}
+	if math.Abs(debug.PhaseDiffDeg+45) > 0.01 || debug.PhaseDiffVarDeg2 != 0 {
+		t.Fatalf("phase %.2f° variance %.2f, want -45° and 0", debug.PhaseDiffDeg, debug.PhaseDiffVarDeg2)
+	}
+
+	tr.annotateChannels(debug, rx0, make([]complex64, 256))
+	if debug.Rx1PowerDBFS != channelPowerFloorDBFS {
+		t.Fatalf("silent channel reported %.1f dBFS, want the floor", debug.Rx1PowerDBFS)
+	}
+}
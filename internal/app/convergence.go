@@ -0,0 +1,175 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// Convergence defaults, used when the Config fields are zero.
+const (
+	defaultConvergenceStdDeg  = 0.5
+	defaultConvergeIterations = 10
+	// divergenceFactor is the hysteresis between converging and diverging: a
+	// converged track is only declared diverged once the angle spread exceeds
+	// the threshold by this factor.
+	divergenceFactor = 2
+)
+
+// Reasons for a coarse scan, as reported in telemetry.Convergence.
+const (
+	scanStartup   = "startup"
+	scanTimeout   = "not converged"
+	scanLockLost  = "lock lost"
+	scanDiverging = "diverged"
+)
+
+// convergenceReporter is implemented by reporters that keep the convergence
+// state.
+type convergenceReporter interface {
+	ReportConvergence(c telemetry.Convergence)
+}
+
+// convergenceDetector decides when tracking has converged: the standard
+// deviation of the last hold angles is below stdDeg. It also schedules coarse
+// scans, which re-acquire the target when tracking has not converged within
+// budget iterations of the last scan or when a converged track is lost.
+type convergenceDetector struct {
+	stdDeg float64
+	hold   int
+	budget int
+
+	window      []float64 // ring of the last hold angles
+	next        int
+	filled      int
+	scanAt      time.Time // time of the last coarse scan
+	iterations  int       // tracking iterations since the last scan
+	scans       int
+	reason      string // why the last scan ran
+	converged   bool
+	convergedIn time.Duration
+	wasLocked   bool
+}
+
+func newConvergenceDetector(stdDeg float64, hold, budget int) *convergenceDetector {
+	if stdDeg <= 0 {
+		stdDeg = defaultConvergenceStdDeg
+	}
+	if hold <= 0 {
+		hold = defaultConvergeIterations
+	}
+	return &convergenceDetector{stdDeg: stdDeg, hold: hold, budget: max(budget, hold), window: make([]float64, hold)}
+}
+
+// scanned restarts acquisition after a coarse scan at now.
+func (c *convergenceDetector) scanned(now time.Time, reason string) {
+	c.scanAt, c.reason = now, reason
+	c.scans++
+	c.iterations, c.next, c.filled = 0, 0, 0
+	c.converged, c.convergedIn, c.wasLocked = false, 0, false
+}
+
+// update feeds the angle of one tracking iteration. It reports whether the
+// converged state changed and, when a coarse scan is due, its reason.
+func (c *convergenceDetector) update(angle float64, state telemetry.LockState, now time.Time) (changed bool, rescan string) {
+	c.iterations++
+	c.window[c.next] = angle
+	c.next = (c.next + 1) % c.hold
+	c.filled = min(c.filled+1, c.hold)
+	if state == telemetry.LockStateLocked {
+		c.wasLocked = true
+	}
+
+	std := c.std()
+	switch {
+	case !c.converged && c.filled == c.hold && std < c.stdDeg:
+		c.converged, c.convergedIn = true, now.Sub(c.scanAt)
+		return true, ""
+	case c.converged && state == telemetry.LockStateSearching:
+		c.converged = false
+		return true, scanLockLost
+	case c.converged && std > divergenceFactor*c.stdDeg:
+		// Give the track a fresh budget to settle before scanning again.
+		c.converged, c.iterations = false, 0
+		return true, ""
+	case !c.converged && c.iterations >= c.budget:
+		if c.wasLocked {
+			return false, scanDiverging
+		}
+		return false, scanTimeout
+	}
+	return false, ""
+}
+
+// std returns the standard deviation of the angles in the window.
+func (c *convergenceDetector) std() float64 {
+	if c.filled < 2 {
+		return math.Inf(1)
+	}
+	var sum, sumSq float64
+	for _, a := range c.window[:c.filled] {
+		sum += a
+		sumSq += a * a
+	}
+	n := float64(c.filled)
+	mean := sum / n
+	return math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+}
+
+// snapshot returns the state for telemetry.
+func (c *convergenceDetector) snapshot(now time.Time) telemetry.Convergence {
+	out := telemetry.Convergence{
+		Timestamp:  now,
+		Converged:  c.converged,
+		Iterations: c.iterations,
+		Scans:      c.scans,
+		ScanReason: c.reason,
+	}
+	if c.converged {
+		out.ConvergenceMs = float64(c.convergedIn) / float64(time.Millisecond)
+	}
+	if std := c.std(); !math.IsInf(std, 1) {
+		out.StdDeg = std
+	}
+	return out
+}
+
+// observeConvergence feeds a tracking iteration to the detector, schedules a
+// coarse scan when one is due and reports state changes.
+func (t *Tracker) observeConvergence(angle float64, state telemetry.LockState) {
+	now := t.now()
+	changed, rescan := t.conv.update(angle, state, now)
+	if rescan != "" {
+		t.rescan = rescan
+	}
+	if !changed {
+		return
+	}
+	snap := t.conv.snapshot(now)
+	if snap.Converged {
+		t.logger.Info("tracking converged",
+			logging.Field{Key: "subsystem", Value: "tracker"},
+			logging.Field{Key: "convergence_ms", Value: snap.ConvergenceMs},
+			logging.Field{Key: "iterations", Value: snap.Iterations})
+	}
+	if reporter, ok := t.reporter.(convergenceReporter); ok {
+		reporter.ReportConvergence(snap)
+	}
+}
+
+// startAcquisition records a coarse scan and reports the restarted
+// acquisition. Further scans of an acquisition histogram that is still
+// collecting belong to the same acquisition.
+func (t *Tracker) startAcquisition(reason string) {
+	if t.acq.collecting() {
+		return
+	}
+	now := t.now()
+	t.conv.scanned(now, reason)
+	t.rescan = ""
+	if reporter, ok := t.reporter.(convergenceReporter); ok {
+		reporter.ReportConvergence(t.conv.snapshot(now))
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestConvergenceDetector(t *testing.T) {
+	const (
+		searching = telemetry.LockStateSearching
+		tracking  = telemetry.LockStateTracking
+		locked    = telemetry.LockStateLocked
+	)
+	type step struct {
+		angle     float64
+		state     telemetry.LockState
+		changed   bool
+		rescan    string
+		converged bool
+	}
+	steady := func(n int, angle float64, state telemetry.LockState) []step {
+		out := make([]step, n)
+		for i := range out {
+			out[i] = step{angle: angle, state: state}
+		}
+		return out
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "converges after hold iterations",
+			steps: append(steady(2, 10, tracking),
+				step{angle: 10.1, state: locked, changed: true, converged: true}),
+		},
+		{
+			name: "noisy track scans after the budget",
+			steps: []step{
+				{angle: 0, state: tracking},
+				{angle: 5, state: tracking},
+				{angle: -5, state: tracking},
+				{angle: 5, state: tracking},
+				{angle: -5, state: tracking},
+				{angle: 5, state: tracking, rescan: scanTimeout},
+			},
+		},
+		{
+			name: "lock lost after convergence",
+			steps: append(steady(2, 10, locked),
+				step{angle: 10, state: locked, changed: true, converged: true},
+				step{angle: 10, state: searching, changed: true, rescan: scanLockLost}),
+		},
+		{
+			name: "divergence restarts the budget",
+			steps: append(steady(2, 10, locked),
+				step{angle: 10, state: locked, changed: true, converged: true},
+				step{angle: 14, state: locked, changed: true},
+				step{angle: 20, state: locked},
+				step{angle: 30, state: locked},
+				step{angle: 40, state: locked},
+				step{angle: 50, state: locked},
+				step{angle: 60, state: locked},
+				step{angle: 70, state: locked, rescan: scanDiverging}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(0, 0)
+			c := newConvergenceDetector(0.5, 3, 6)
+			c.scanned(start, scanStartup)
+			for i, s := range tt.steps {
+				now := start.Add(time.Duration(i+1) * 100 * time.Millisecond)
+				changed, rescan := c.update(s.angle, s.state, now)
+				if changed != s.changed || rescan != s.rescan || c.converged != s.converged {
+					t.Fatalf("step %d: update(%v, %s) = %v, %q, converged %v; want %v, %q, %v",
+						i, s.angle, s.state, changed, rescan, c.converged, s.changed, s.rescan, s.converged)
+				}
+			}
+		})
+	}
+
+	c := newConvergenceDetector(0.5, 3, 6)
+	start := time.Unix(0, 0)
+	c.scanned(start, scanStartup)
+	for i := 1; i <= 3; i++ {
+		c.update(1, locked, start.Add(time.Duration(i)*time.Second))
+	}
+	snap := c.snapshot(start.Add(3 * time.Second))
+	if !snap.Converged || snap.ConvergenceMs != 3000 || snap.Scans != 1 || snap.ScanReason != scanStartup {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+}
+
+type convergenceRecorder struct {
+	stoppingReporter
+	states []telemetry.Convergence
+}
+
+func (r *convergenceRecorder) ReportConvergence(c telemetry.Convergence) {
+	r.states = append(r.states, c)
+}
+
+func TestTrackerReportsConvergence(t *testing.T) {
+	backend := sdr.NewMock()
+	backend.SetNoiseSource(rand.New(rand.NewSource(3)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reporter := &convergenceRecorder{stoppingReporter: stoppingReporter{limit: 50, cancel: cancel}}
+	cfg := Config{
+		SampleRate:        2e6,
+		RxLO:              2.3e9,
+		ToneOffset:        200e3,
+		NumSamples:        512,
+		SpacingWavelength: 0.5,
+		TrackingLength:    50,
+		PhaseStep:         1,
+		ScanStep:          2,
+		PhaseDelta:        35,
+		HistoryLimit:      20,
+		Unpaced:           true,
+	}
+	tracker := NewTracker(backend, reporter, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := tracker.Run(ctx); err != nil && err != context.Canceled {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if len(reporter.states) < 2 || reporter.states[0].ScanReason != scanStartup || reporter.states[0].Converged {
+		t.Fatalf("expected a startup scan followed by convergence, got %+v", reporter.states)
+	}
+	converged := reporter.states[1]
+	if !converged.Converged || converged.ConvergenceMs <= 0 || converged.Iterations < defaultConvergeIterations {
+		t.Fatalf("unexpected convergence report %+v", converged)
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// maxPatternCorrectionDB caps the pattern correction so that a bearing in a
+// pattern null does not inflate the reported signal strength without bound.
+const maxPatternCorrectionDB = 20
+
+// ElementPattern is a measured antenna element pattern: gain versus angle
+// from boresight, normalised so its maximum is 0 dB.
+type ElementPattern struct {
+	angles []float64 // degrees, ascending
+	gains  []float64 // dB relative to the maximum
+}
+
+// LoadElementPattern reads an element pattern from a CSV file; see
+// ParseElementPattern.
+func LoadElementPattern(path string) (*ElementPattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("element pattern: %w", err)
+	}
+	defer f.Close()
+	p, err := ParseElementPattern(f)
+	if err != nil {
+		return nil, fmt.Errorf("element pattern %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParseElementPattern reads CSV rows of angle (degrees) and gain (dB). An
+// optional header row is skipped, as are blank lines and lines starting with
+// '#'. Angles may come in any order; gains measured twice at the same angle
+// are averaged.
+func ParseElementPattern(r io.Reader) (*ElementPattern, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	sums := make(map[float64][2]float64) // angle -> gain sum, count
+	for line := 1; ; line++ {
+		rec, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("line %d: want angle and gain columns", line)
+		}
+		angle, errA := strconv.ParseFloat(strings.TrimSpace(rec[0]), 64)
+		gain, errG := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if errA != nil || errG != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid angle or gain %q", line, strings.Join(rec[:2], ","))
+		}
+		if math.IsNaN(angle) || math.IsNaN(gain) || math.IsInf(gain, 0) {
+			return nil, fmt.Errorf("line %d: invalid angle or gain", line)
+		}
+		s := sums[angle]
+		sums[angle] = [2]float64{s[0] + gain, s[1] + 1}
+	}
+	if len(sums) < 2 {
+		return nil, fmt.Errorf("need at least two angles, have %d", len(sums))
+	}
+
+	p := &ElementPattern{}
+	for angle := range sums {
+		p.angles = append(p.angles, angle)
+	}
+	sort.Float64s(p.angles)
+	peak := math.Inf(-1)
+	for _, angle := range p.angles {
+		gain := sums[angle][0] / sums[angle][1]
+		p.gains = append(p.gains, gain)
+		peak = math.Max(peak, gain)
+	}
+	for i := range p.gains {
+		p.gains[i] -= peak
+	}
+	return p, nil
+}
+
+// GainDB returns the relative gain at angle, interpolated linearly between
+// the measured angles and held at the end values outside them.
+func (p *ElementPattern) GainDB(angle float64) float64 {
+	n := len(p.angles)
+	i := sort.SearchFloat64s(p.angles, angle)
+	switch {
+	case i == 0:
+		return p.gains[0]
+	case i == n:
+		return p.gains[n-1]
+	}
+	a0, a1 := p.angles[i-1], p.angles[i]
+	frac := (angle - a0) / (a1 - a0)
+	return p.gains[i-1] + frac*(p.gains[i]-p.gains[i-1])
+}
+
+// CorrectionDB returns the dB to add to a signal measured at angle to refer
+// it to the pattern maximum, capped at maxPatternCorrectionDB.
+func (p *ElementPattern) CorrectionDB(angle float64) float64 {
+	return math.Min(-p.GainDB(angle), maxPatternCorrectionDB)
+}
+
+// correctForPattern refers peak and SNR measured at angle to the pattern
+// maximum. Without a pattern they are returned unchanged. The applied
+// correction is recorded in debug when present.
+func (t *Tracker) correctForPattern(angle, peak, snr float64, debug *telemetry.DebugInfo) (float64, float64) {
+	if t.cfg.ElementPattern == nil {
+		return peak, snr
+	}
+	corr := t.cfg.ElementPattern.CorrectionDB(angle)
+	if debug != nil {
+		debug.PatternCorrectionDB = corr
+	}
+	return peak + corr, snr + corr
+}
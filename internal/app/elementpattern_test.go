@@ -0,0 +1,89 @@
+package app
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestParseElementPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+		gains   map[float64]float64 // angle -> relative gain
+	}{
+		{
+			name: "header and normalisation",
+			csv:  "angle_deg,gain_db\n-60,-4\n0,2\n60,-4\n",
+			gains: map[float64]float64{
+				0: 0, 60: -6, -60: -6, 30: -3, -90: -6, 90: -6,
+			},
+		},
+		{
+			name: "unsorted with duplicates and comments",
+			csv:  "# measured 2026-10-01\n30,-3\n0,0\n30,-1\n\n-30,-2\n",
+			gains: map[float64]float64{
+				30: -2, -15: -1, 0: 0,
+			},
+		},
+		{name: "single angle", csv: "0,0\n0,1\n", wantErr: "at least two angles"},
+		{name: "bad value", csv: "0,0\n10,x\n", wantErr: "line 2"},
+		{name: "one column", csv: "0\n", wantErr: "angle and gain columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseElementPattern(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for angle, want := range tt.gains {
+				if got := p.GainDB(angle); math.Abs(got-want) > 1e-9 {
+					t.Errorf("GainDB(%v) = %v, want %v", angle, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReportCorrectsForPattern(t *testing.T) {
+	pattern, err := ParseElementPattern(strings.NewReader("-90,-40\n0,0\n45,-6\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		angle    float64
+		wantCorr float64
+	}{
+		{0, 0},
+		{45, 6},
+		{-90, maxPatternCorrectionDB}, // capped in the null
+	}
+	for _, tt := range tests {
+		rec := &debugRecorder{}
+		tr := &Tracker{reporter: rec, cfg: Config{ElementPattern: pattern}}
+		tr.report(tt.angle, -30, 10, 0.9, telemetry.LockStateLocked, &telemetry.DebugInfo{})
+		if rec.peak != -30+tt.wantCorr || rec.snr != 10+tt.wantCorr || rec.debug.PatternCorrectionDB != tt.wantCorr {
+			t.Errorf("angle %v: peak %v snr %v correction %v, want correction %v", tt.angle, rec.peak, rec.snr, rec.debug.PatternCorrectionDB, tt.wantCorr)
+		}
+	}
+}
+
+type debugRecorder struct {
+	peak, snr float64
+	debug     *telemetry.DebugInfo
+}
+
+func (r *debugRecorder) Report(_ float64, peak, snr, _ float64, _ telemetry.LockState, debug *telemetry.DebugInfo) {
+	r.peak, r.snr, r.debug = peak, snr, debug
+}
+
+func (r *debugRecorder) ReportMultiTrack(telemetry.MultiTrackSample) {}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// Frequency correction modes for Config.FreqCorrection.
+const (
+	FreqCorrectionOff     = "off"
+	FreqCorrectionReport  = "report"  // measure and report only
+	FreqCorrectionXO      = "xo"      // trim the backend reference oscillator
+	FreqCorrectionDigital = "digital" // shift samples in software
+)
+
+// minXOCorrectionPPM is the smallest error worth retuning the hardware for.
+const minXOCorrectionPPM = 0.2
+
+// calibrateFrequency measures the apparent offset of the known tone and, based
+// on FreqCorrection, trims the XO or enables a digital frequency shift. The
+// residual offset and ppm error are kept for debug telemetry.
+func (t *Tracker) calibrateFrequency(ctx context.Context) error {
+	mode := t.cfg.FreqCorrection
+	if mode == "" || mode == FreqCorrectionOff {
+		return nil
+	}
+	offset, err := t.measureToneOffset(ctx)
+	if err != nil {
+		return err
+	}
+	carrier := t.cfg.RxLO + t.cfg.ToneOffset
+	t.freqPPM = dsp.FrequencyErrorPPM(offset, carrier)
+	t.freqResidualHz = offset
+
+	switch mode {
+	case FreqCorrectionXO:
+		corrector, ok := sdr.As[sdr.XOCorrector](t.sdr)
+		if !ok {
+			t.logger.Warn("backend does not support XO correction; reporting only", logging.Field{Key: "subsystem", Value: "tracker"})
+			break
+		}
+		if math.Abs(t.freqPPM) < minXOCorrectionPPM {
+			break
+		}
+		xo, err := corrector.XOCorrection(ctx)
+		if err != nil {
+			return err
+		}
+		if err := corrector.SetXOCorrection(ctx, dsp.CorrectedXO(xo, t.freqPPM)); err != nil {
+			return err
+		}
+		if t.freqResidualHz, err = t.measureToneOffset(ctx); err != nil {
+			return err
+		}
+	case FreqCorrectionDigital:
+		t.freqShiftHz = -offset
+		t.freqResidualHz = 0
+	case FreqCorrectionReport:
+	default:
+		return fmt.Errorf("unknown frequency correction mode %q", mode)
+	}
+
+	t.logger.Info("frequency offset measured",
+		logging.Field{Key: "subsystem", Value: "tracker"},
+		logging.Field{Key: "mode", Value: mode},
+		logging.Field{Key: "offset_hz", Value: offset},
+		logging.Field{Key: "error_ppm", Value: t.freqPPM},
+		logging.Field{Key: "residual_hz", Value: t.freqResidualHz})
+	return nil
+}
+
+// measureToneOffset returns the channel 0 tone frequency error in Hz. A spare
+// buffer is discarded first so a preceding retune has settled.
+func (t *Tracker) measureToneOffset(ctx context.Context) (float64, error) {
+	var rx0 []complex64
+	for i := 0; i < 2; i++ {
+		var err error
+		if rx0, _, err = t.sdr.RX(ctx); err != nil {
+			return 0, fmt.Errorf("frequency calibration RX: %w", err)
+		}
+	}
+	return dsp.EstimateToneFrequency(rx0, t.cfg.SampleRate) - t.cfg.ToneOffset, nil
+}
+
+// residualOffset reports the live CFO loop residual when tracking is enabled,
+// otherwise the offset left after start-up calibration.
+func (t *Tracker) residualOffset() float64 {
+	if t.cfo != nil {
+		return t.cfo.Residual()
+	}
+	return t.freqResidualHz
+}
+
+func (t *Tracker) cfoCorrection() float64 {
+	if t.cfo == nil {
+		return 0
+	}
+	return t.cfo.Correction()
+}
+
+// applyFrequencyShift applies the digital correction to both channels with a
+// shared starting phase so their phase difference is unchanged.
+func (t *Tracker) applyFrequencyShift(rx0, rx1 []complex64) {
+	if t.freqShiftHz == 0 {
+		return
+	}
+	start := t.mixPhase
+	t.mixPhase = dsp.MixInPlace(rx0, t.freqShiftHz, t.cfg.SampleRate, start)
+	dsp.MixInPlace(rx1, t.freqShiftHz, t.cfg.SampleRate, start)
+}
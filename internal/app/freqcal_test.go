@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// xoMock shifts the mock tone as if the receiver reference oscillator ran at
+// trueXO while the driver assumes xo.
+type xoMock struct {
+	*sdr.MockSDR
+	carrier float64
+	trueXO  float64
+	xo      float64
+}
+
+func (m *xoMock) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := m.MockSDR.RX(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	shift := -m.carrier * (m.trueXO/m.xo - 1)
+	dsp.MixInPlace(rx0, shift, 2e6, 0)
+	dsp.MixInPlace(rx1, shift, 2e6, 0)
+	return rx0, rx1, nil
+}
+
+func (m *xoMock) XOCorrection(context.Context) (float64, error) { return m.xo, nil }
+
+func (m *xoMock) SetXOCorrection(_ context.Context, xoHz float64) error {
+	m.xo = xoHz
+	return nil
+}
+
+func newFreqCalTracker(t *testing.T, backend sdr.SDR, mode string) *Tracker {
+	t.Helper()
+	cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 4096, FreqCorrection: mode}
+	tracker := NewTracker(backend, nil, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+	if err := tracker.Init(context.Background()); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	return tracker
+}
+
+func TestCalibrateFrequencyModes(t *testing.T) {
+	const carrier = 2.3e9 + 200e3
+	tests := []struct {
+		name      string
+		mode      string
+		wantXO    bool
+		wantShift bool
+	}{
+		{name: "report", mode: FreqCorrectionReport},
+		{name: "xo", mode: FreqCorrectionXO, wantXO: true},
+		{name: "digital", mode: FreqCorrectionDigital, wantShift: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &xoMock{MockSDR: sdr.NewMock(), carrier: carrier, trueXO: 40e6 * (1 + 10e-6), xo: 40e6}
+			tracker := newFreqCalTracker(t, backend, tt.mode)
+			if err := tracker.calibrateFrequency(context.Background()); err != nil {
+				t.Fatalf("calibrate failed: %v", err)
+			}
+			if math.Abs(tracker.freqPPM+10) > 0.2 {
+				t.Fatalf("measured %.3f ppm, want about -10", tracker.freqPPM)
+			}
+			if tt.wantXO {
+				if math.Abs(backend.xo-backend.trueXO) > 20 {
+					t.Fatalf("xo corrected to %.1f, want near %.1f", backend.xo, backend.trueXO)
+				}
+				if math.Abs(tracker.freqResidualHz) > 500 {
+					t.Fatalf("residual offset %.1f Hz too large", tracker.freqResidualHz)
+				}
+			} else if backend.xo != 40e6 {
+				t.Fatalf("xo should not change in %s mode", tt.mode)
+			}
+			if tt.wantShift != (tracker.freqShiftHz != 0) {
+				t.Fatalf("unexpected digital shift %.1f Hz", tracker.freqShiftHz)
+			}
+		})
+	}
+}
+
+func TestCalibrateFrequencyRejectsUnknownMode(t *testing.T) {
+	tracker := newFreqCalTracker(t, sdr.NewMock(), "bogus")
+	if err := tracker.calibrateFrequency(context.Background()); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+}
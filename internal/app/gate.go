@@ -0,0 +1,66 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// dwellGateDeg is how far an angle may move from the last reported one and
+// still count as the same bearing, bypassing the dwell requirement.
+const dwellGateDeg = 5.0
+
+// reportGate decides which measurements reach the reporter. Squelched
+// samples (SNR below squelchSNR) are dropped, and an angle away from the
+// last reported one must persist for minDwell before it is reported. The
+// tracker keeps using every measurement internally.
+type reportGate struct {
+	squelchSNR float64
+	minDwell   time.Duration
+
+	reported       bool
+	lastAngle      float64
+	candidate      float64
+	candidateSince time.Time
+	hasCandidate   bool
+}
+
+// admit reports whether a measurement should be published.
+func (g *reportGate) admit(angle, snr float64, now time.Time) bool {
+	if g.squelchSNR != 0 && snr < g.squelchSNR {
+		g.hasCandidate = false
+		return false
+	}
+	if g.minDwell <= 0 || (g.reported && math.Abs(angle-g.lastAngle) <= dwellGateDeg) {
+		g.reported, g.lastAngle = true, angle
+		return true
+	}
+	if !g.hasCandidate || math.Abs(angle-g.candidate) > dwellGateDeg {
+		g.candidate, g.candidateSince, g.hasCandidate = angle, now, true
+	}
+	if now.Sub(g.candidateSince) < g.minDwell {
+		return false
+	}
+	g.hasCandidate = false
+	g.reported, g.lastAngle = true, angle
+	return true
+}
+
+// report publishes a measurement unless the gate holds it back. The squelch
+// sees the measured SNR; reporters get the pattern-corrected values.
+func (t *Tracker) report(angle, peak, snr, confidence float64, state telemetry.LockState, debug *telemetry.DebugInfo) {
+	if t.reporter == nil {
+		return
+	}
+	if t.gate != nil && !t.gate.admit(angle, snr, t.now()) {
+		return
+	}
+	peak, snr = t.correctForPattern(angle, peak, snr, debug)
+	if t.dualPol != nil {
+		t.reporter.ReportMultiTrack(t.polarizationSample(angle, peak, snr, confidence, state, debug))
+	} else {
+		t.reporter.Report(angle, peak, snr, confidence, state, debug)
+	}
+	t.reportSteering(angle)
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportGate(t *testing.T) {
+	start := time.Unix(0, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	type step struct {
+		angle, snr float64
+		at         int
+		want       bool
+	}
+	tests := []struct {
+		name  string
+		gate  reportGate
+		steps []step
+	}{
+		{
+			name: "squelch",
+			gate: reportGate{squelchSNR: 6},
+			steps: []step{
+				{angle: 10, snr: 3, want: false},
+				{angle: 10, snr: 9, want: true},
+				{angle: 40, snr: 9, want: true},
+			},
+		},
+		{
+			name: "dwell",
+			gate: reportGate{minDwell: 100 * time.Millisecond},
+			steps: []step{
+				{angle: 10, snr: 9, at: 0, want: false},
+				{angle: 12, snr: 9, at: 50, want: false},
+				{angle: 11, snr: 9, at: 100, want: true},  // persisted 100 ms
+				{angle: 13, snr: 9, at: 110, want: true},  // near the reported angle
+				{angle: 40, snr: 9, at: 120, want: false}, // jump needs a new dwell
+				{angle: 60, snr: 9, at: 200, want: false}, // another jump restarts it
+				{angle: 61, snr: 9, at: 300, want: true},
+			},
+		},
+		{
+			name: "squelch breaks dwell",
+			gate: reportGate{squelchSNR: 6, minDwell: 100 * time.Millisecond},
+			steps: []step{
+				{angle: 10, snr: 9, at: 0, want: false},
+				{angle: 10, snr: 2, at: 50, want: false},
+				{angle: 10, snr: 9, at: 100, want: false},
+				{angle: 10, snr: 9, at: 200, want: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.gate
+			for i, s := range tt.steps {
+				if got := g.admit(s.angle, s.snr, at(s.at)); got != s.want {
+					t.Fatalf("step %d: admit(%v, %v) = %v, want %v", i, s.angle, s.snr, got, s.want)
+				}
+			}
+		})
+	}
+}
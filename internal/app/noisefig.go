@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// noiseSettleBuffers are discarded after toggling the noise source so AGC and
+// filter transients do not skew the measurement.
+const noiseSettleBuffers = 2
+
+// NoiseSource switches a calibrated noise source (or the LNA) between its hot
+// and cold state for Y-factor measurements.
+type NoiseSource interface {
+	SetNoiseSource(ctx context.Context, on bool) error
+}
+
+// GPIONoiseSource drives a noise source through a sysfs GPIO value file such
+// as /sys/class/gpio/gpio17/value.
+type GPIONoiseSource struct {
+	Path string
+}
+
+// SetNoiseSource writes "1" or "0" to the GPIO value file.
+func (g GPIONoiseSource) SetNoiseSource(_ context.Context, on bool) error {
+	value := "0"
+	if on {
+		value = "1"
+	}
+	if err := os.WriteFile(g.Path, []byte(value), 0o644); err != nil {
+		return fmt.Errorf("set noise source gpio: %w", err)
+	}
+	return nil
+}
+
+// PromptNoiseSource asks an operator to toggle the source by hand and waits
+// for Enter.
+type PromptNoiseSource struct {
+	In  io.Reader
+	Out io.Writer
+}
+
+// SetNoiseSource prints the instruction and blocks until a line is read.
+func (p PromptNoiseSource) SetNoiseSource(ctx context.Context, on bool) error {
+	state := "OFF (cold)"
+	if on {
+		state = "ON (hot)"
+	}
+	fmt.Fprintf(p.Out, "Switch the noise source %s and press Enter: ", state)
+	done := make(chan error, 1)
+	go func() {
+		// Read byte-wise so no input beyond the newline is consumed.
+		buf := make([]byte, 1)
+		for {
+			if _, err := p.In.Read(buf); err != nil {
+				done <- err
+				return
+			}
+			if buf[0] == '\n' {
+				done <- nil
+				return
+			}
+		}
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("read operator input: %w", err)
+		}
+		return nil
+	}
+}
+
+// MeasureNoiseFigure measures the per-channel noise floor with the source
+// cold and hot, averaging buffers RX buffers in each state, and returns a
+// Y-factor noise figure estimate per channel. The source is left off. The
+// signal band is excluded so a stray tone does not bias the result.
+func (t *Tracker) MeasureNoiseFigure(ctx context.Context, src NoiseSource, enrDB float64, buffers int) ([]calibration.NoiseFigure, error) {
+	if buffers <= 0 {
+		buffers = 8
+	}
+	if err := src.SetNoiseSource(ctx, false); err != nil {
+		return nil, err
+	}
+	cold, err := t.measureNoiseFloor(ctx, buffers)
+	if err != nil {
+		return nil, fmt.Errorf("cold measurement: %w", err)
+	}
+	if err := src.SetNoiseSource(ctx, true); err != nil {
+		return nil, err
+	}
+	hot, err := t.measureNoiseFloor(ctx, buffers)
+	if offErr := src.SetNoiseSource(ctx, false); err == nil {
+		err = offErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hot measurement: %w", err)
+	}
+
+	now := t.now()
+	backend := t.sdr.Capabilities().Backend
+	gains := [2]int{t.cfg.RxGain0, t.cfg.RxGain1}
+	records := make([]calibration.NoiseFigure, 0, len(cold))
+	for ch := range cold {
+		nf, err := dsp.YFactorNoiseFigure(hot[ch], cold[ch], enrDB)
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", ch, err)
+		}
+		records = append(records, calibration.NoiseFigure{
+			Timestamp:     now,
+			Backend:       backend,
+			Channel:       ch,
+			RxLOHz:        t.cfg.RxLO,
+			RxGainDB:      gains[ch],
+			ENRDB:         enrDB,
+			ColdDBFS:      cold[ch],
+			HotDBFS:       hot[ch],
+			YFactorDB:     hot[ch] - cold[ch],
+			NoiseFigureDB: nf,
+		})
+		t.logger.Info("noise figure measured",
+			logging.Field{Key: "subsystem", Value: "tracker"},
+			logging.Field{Key: "channel", Value: ch},
+			logging.Field{Key: "y_factor_db", Value: hot[ch] - cold[ch]},
+			logging.Field{Key: "noise_figure_db", Value: nf})
+	}
+	return records, nil
+}
+
+// measureNoiseFloor returns the average noise power (dBFS/bin) of both
+// channels after discarding settle buffers.
+func (t *Tracker) measureNoiseFloor(ctx context.Context, buffers int) ([2]float64, error) {
+	var sums [2]float64
+	var used int
+	for i := 0; i < noiseSettleBuffers+buffers; i++ {
+		rx0, rx1, err := t.sdr.RX(ctx)
+		if err != nil {
+			return sums, fmt.Errorf("receive samples: %w", err)
+		}
+		if i < noiseSettleBuffers {
+			continue
+		}
+		p0, ok0 := dsp.NoisePowerDB(rx0, t.startBin, t.endBin)
+		p1, ok1 := dsp.NoisePowerDB(rx1, t.startBin, t.endBin)
+		if !ok0 || !ok1 {
+			continue
+		}
+		sums[0] += math.Pow(10, p0/10)
+		sums[1] += math.Pow(10, p1/10)
+		used++
+	}
+	if used == 0 {
+		return sums, fmt.Errorf("no usable buffers")
+	}
+	for ch := range sums {
+		sums[ch] = 10 * math.Log10(sums[ch]/float64(used))
+	}
+	return sums, nil
+}
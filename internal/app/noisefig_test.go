@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// noiseMock adds receiver noise to the mock and raises it by yFactor while
+// its built-in noise source is on.
+type noiseMock struct {
+	*sdr.MockSDR
+	rng     *rand.Rand
+	sigma   float64
+	yFactor float64
+	hot     bool
+}
+
+func (m *noiseMock) SetNoiseSource(_ context.Context, on bool) error {
+	m.hot = on
+	return nil
+}
+
+func (m *noiseMock) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := m.MockSDR.RX(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	sigma := m.sigma
+	if m.hot {
+		sigma *= math.Sqrt(m.yFactor)
+	}
+	for _, buf := range [][]complex64{rx0, rx1} {
+		for i := range buf {
+			buf[i] += complex64(complex(m.rng.NormFloat64()*sigma, m.rng.NormFloat64()*sigma))
+		}
+	}
+	return rx0, rx1, nil
+}
+
+func TestMeasureNoiseFigure(t *testing.T) {
+	backend := &noiseMock{MockSDR: sdr.NewMock(), rng: rand.New(rand.NewSource(1)), sigma: 0.05, yFactor: 11}
+	cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 2048, RxGain0: 40, RxGain1: 41}
+	tracker := NewTracker(backend, nil, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+	if err := tracker.Init(context.Background()); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	// Y = 11 with a 15 dB ENR source corresponds to a 5 dB noise figure.
+	records, err := tracker.MeasureNoiseFigure(context.Background(), backend, 15, 16)
+	if err != nil {
+		t.Fatalf("MeasureNoiseFigure failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 channel records, got %d", len(records))
+	}
+	for _, rec := range records {
+		if math.Abs(rec.NoiseFigureDB-5) > 0.5 {
+			t.Fatalf("channel %d: NF %.2f dB, want ~5 dB", rec.Channel, rec.NoiseFigureDB)
+		}
+		if rec.Backend != "mock" || rec.ENRDB != 15 {
+			t.Fatalf("unexpected record metadata: %+v", rec)
+		}
+	}
+	if records[1].RxGainDB != 41 {
+		t.Fatalf("expected channel 1 gain 41, got %d", records[1].RxGainDB)
+	}
+	if backend.hot {
+		t.Fatal("noise source left on after measurement")
+	}
+}
+
+func TestPromptNoiseSource(t *testing.T) {
+	var out bytes.Buffer
+	src := PromptNoiseSource{In: strings.NewReader("\n\n"), Out: &out}
+	if err := src.SetNoiseSource(context.Background(), true); err != nil {
+		t.Fatalf("first prompt: %v", err)
+	}
+	if err := src.SetNoiseSource(context.Background(), false); err != nil {
+		t.Fatalf("second prompt: %v", err)
+	}
+	if !strings.Contains(out.String(), "ON (hot)") || !strings.Contains(out.String(), "OFF (cold)") {
+		t.Fatalf("unexpected prompts: %q", out.String())
+	}
+	if err := src.SetNoiseSource(context.Background(), true); err == nil {
+		t.Fatal("expected error once input is exhausted")
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// occupancyInterval is the minimum recording or wall time between occupancy
+// reports; the statistics themselves include every buffer.
+const occupancyInterval = time.Second
+
+// occupancyReporter is implemented by reporters that keep spectrum occupancy.
+type occupancyReporter interface {
+	ReportOccupancy(occ telemetry.Occupancy)
+}
+
+// observeOccupancy adds the channel 0 spectrum to the occupancy statistics and
+// reports them at most once per occupancyInterval.
+func (t *Tracker) observeOccupancy(spectrum []float64) {
+	if t.occupancy == nil {
+		return
+	}
+	t.occupancy.Update(spectrum)
+
+	reporter, ok := t.reporter.(occupancyReporter)
+	now := t.now()
+	if !ok || now.Sub(t.occupancyAt) < occupancyInterval {
+		return
+	}
+	t.occupancyAt = now
+	bands, frames := t.occupancy.Stats()
+	reporter.ReportOccupancy(telemetry.Occupancy{Timestamp: now, CenterHz: t.cfg.RxLO, Frames: frames, Bands: bands})
+}
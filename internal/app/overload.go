@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+const (
+	// clipRateThreshold is the fraction of clipped samples that marks a
+	// channel as overloaded.
+	clipRateThreshold = 0.001
+	// overloadHoldBuffers consecutive overloaded buffers trigger a backoff.
+	overloadHoldBuffers = 2
+	// gainBackoffStepDB is removed from a channel's gain on each backoff.
+	gainBackoffStepDB = 3
+	// gainRecoverStepDB is restored after gainRecoverBuffers clean buffers
+	// with at least gainRecoverHeadroomDB of headroom. The asymmetric steps
+	// and headroom requirement provide hysteresis.
+	gainRecoverStepDB     = 1
+	gainRecoverBuffers    = 50
+	gainRecoverHeadroomDB = 6
+)
+
+// eventLogger is implemented by reporters that keep an event log.
+type eventLogger interface {
+	LogEvent(level, message string)
+}
+
+// channelGain tracks the overload state and applied gain of one RX channel.
+type channelGain struct {
+	attr       string
+	configured int
+	current    int
+	over       int
+	clean      int
+	clip       float64
+}
+
+// overloadMonitor detects ADC clipping per channel and, when enabled, backs
+// the hardware gain off until the clipping stops.
+type overloadMonitor struct {
+	fullScale   float64
+	autoBackoff bool
+	channels    [2]channelGain
+	overloaded  bool
+}
+
+func newOverloadMonitor(fullScale float64, auto bool, gain0, gain1 int) *overloadMonitor {
+	return &overloadMonitor{
+		fullScale:   fullScale,
+		autoBackoff: auto,
+		channels: [2]channelGain{
+			{attr: sdr.AttrRxGain0, configured: gain0, current: gain0},
+			{attr: sdr.AttrRxGain1, configured: gain1, current: gain1},
+		},
+	}
+}
+
+// checkOverload measures clipping on both channels, raises an event when the
+// overload state changes and adjusts gains if automatic backoff is enabled.
+func (t *Tracker) checkOverload(ctx context.Context, rx0, rx1 []complex64) {
+	m := t.overload
+	if m == nil {
+		return
+	}
+	overloaded := false
+	for ch, samples := range [][]complex64{rx0, rx1} {
+		g := &m.channels[ch]
+		g.clip = dsp.ClipFraction(samples, m.fullScale)
+		if g.clip > clipRateThreshold {
+			overloaded = true
+		}
+		if m.autoBackoff {
+			if delta := g.step(headroomDB(samples, m.fullScale)); delta != 0 {
+				t.setChannelGain(ctx, ch, g.current+delta)
+			}
+		}
+	}
+	if overloaded != m.overloaded {
+		m.overloaded = overloaded
+		msg := "RX overload cleared"
+		level := "info"
+		if overloaded {
+			msg = fmt.Sprintf("RX overload: clipping %.2f%% / %.2f%% of samples", 100*m.channels[0].clip, 100*m.channels[1].clip)
+			level = "warn"
+		}
+		t.logEvent(level, msg)
+	}
+}
+
+// step updates the channel counters and returns the gain change to apply.
+func (g *channelGain) step(headroom float64) int {
+	if g.clip > clipRateThreshold {
+		g.clean = 0
+		g.over++
+		if g.over >= overloadHoldBuffers {
+			g.over = 0
+			return -gainBackoffStepDB
+		}
+		return 0
+	}
+	g.over = 0
+	if g.current >= g.configured || headroom < gainRecoverHeadroomDB {
+		g.clean = 0
+		return 0
+	}
+	g.clean++
+	if g.clean >= gainRecoverBuffers {
+		g.clean = 0
+		return min(gainRecoverStepDB, g.configured-g.current)
+	}
+	return 0
+}
+
+// setChannelGain writes a new hardware gain for ch, clamped to the backend
+// range.
+func (t *Tracker) setChannelGain(ctx context.Context, ch, gain int) {
+	g := &t.overload.channels[ch]
+	gain = t.sdr.Capabilities().ClampRxGain(gain)
+	if gain == g.current {
+		return
+	}
+	accessor, ok := sdr.As[sdr.AttributeAccessor](t.sdr)
+	if !ok {
+		return
+	}
+	if err := accessor.WriteAttribute(ctx, g.attr, float64(gain)); err != nil {
+		t.logger.Warn("gain backoff failed", logging.Field{Key: "subsystem", Value: "tracker"}, logging.Field{Key: "channel", Value: ch}, logging.Field{Key: "error", Value: err})
+		return
+	}
+	t.logEvent("info", fmt.Sprintf("RX%d gain %d -> %d dB (auto backoff)", ch, g.current, gain))
+	g.current = gain
+}
+
+// annotateOverload attaches clipping information to debug, creating it when
+// the receiver is overloaded so the flag reaches telemetry even outside debug
+// mode.
+func (t *Tracker) annotateOverload(debug *telemetry.DebugInfo) *telemetry.DebugInfo {
+	m := t.overload
+	if m == nil || (debug == nil && !m.overloaded) {
+		return debug
+	}
+	if debug == nil {
+		debug = &telemetry.DebugInfo{}
+	}
+	debug.Overload = m.overloaded
+	debug.ClipFraction = []float64{m.channels[0].clip, m.channels[1].clip}
+	debug.RxGainDB = []int{m.channels[0].current, m.channels[1].current}
+	return debug
+}
+
+// logEvent logs message and forwards it to the reporter's event log.
+func (t *Tracker) logEvent(level, message string) {
+	if level == "warn" {
+		t.logger.Warn(message, logging.Field{Key: "subsystem", Value: "tracker"})
+	} else {
+		t.logger.Info(message, logging.Field{Key: "subsystem", Value: "tracker"})
+	}
+	if ev, ok := t.reporter.(eventLogger); ok {
+		ev.LogEvent(level, message)
+	}
+}
+
+// headroomDB returns how far (dB) the largest I or Q component is below
+// fullScale.
+func headroomDB(samples []complex64, fullScale float64) float64 {
+	peak := 0.0
+	for _, s := range samples {
+		peak = math.Max(peak, math.Max(math.Abs(float64(real(s))), math.Abs(float64(imag(s)))))
+	}
+	if peak == 0 {
+		return math.Inf(1)
+	}
+	return 20 * math.Log10(fullScale/peak)
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// clippingMock scales the mock tone with the programmed RX gain (unity at
+// 60 dB scaled by level) and clips it at a full scale of 1.
+type clippingMock struct {
+	*sdr.MockSDR
+	level float64
+}
+
+func (m *clippingMock) Capabilities() sdr.Capabilities {
+	caps := m.MockSDR.Capabilities()
+	caps.FullScale = 1
+	return caps
+}
+
+func (m *clippingMock) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := m.MockSDR.RX(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	attrs, _ := m.ReadAttributes(ctx)
+	for ch, buf := range [][]complex64{rx0, rx1} {
+		gain := attrs.RxGain0DB
+		if ch == 1 {
+			gain = attrs.RxGain1DB
+		}
+		scale := m.level * math.Pow(10, (gain-60)/20)
+		for i, s := range buf {
+			re := math.Max(-1, math.Min(1, float64(real(s))*scale))
+			im := math.Max(-1, math.Min(1, float64(imag(s))*scale))
+			buf[i] = complex64(complex(re, im))
+		}
+	}
+	return rx0, rx1, nil
+}
+
+type eventCollector struct {
+	telemetry.Reporter
+	events []string
+}
+
+func (e *eventCollector) LogEvent(_, message string) { e.events = append(e.events, message) }
+
+func newOverloadTracker(t *testing.T, backend sdr.SDR, reporter telemetry.Reporter, auto bool) *Tracker {
+	t.Helper()
+	cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 1024, RxGain0: 60, RxGain1: 60, AutoGainBackoff: auto}
+	tracker := NewTracker(backend, reporter, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+	if err := tracker.Init(context.Background()); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	return tracker
+}
+
+func TestOverloadBackoffAndRecovery(t *testing.T) {
+	backend := &clippingMock{MockSDR: sdr.NewMock(), level: 1.2}
+	reporter := &eventCollector{}
+	tracker := newOverloadTracker(t, backend, reporter, true)
+	ctx := context.Background()
+
+	rxStep := func() {
+		rx0, rx1, err := backend.RX(ctx)
+		if err != nil {
+			t.Fatalf("RX: %v", err)
+		}
+		tracker.checkOverload(ctx, rx0, rx1)
+	}
+
+	rxStep()
+	debug := tracker.annotateOverload(nil)
+	if debug == nil || !debug.Overload || debug.ClipFraction[0] <= clipRateThreshold {
+		t.Fatalf("expected overload flagged in telemetry, got %+v", debug)
+	}
+	for i := 0; i < 10; i++ {
+		rxStep()
+	}
+	attrs, _ := backend.ReadAttributes(ctx)
+	if attrs.RxGain0DB != 57 || attrs.RxGain1DB != 57 {
+		t.Fatalf("expected one 3 dB backoff to 57 dB, got %.0f/%.0f", attrs.RxGain0DB, attrs.RxGain1DB)
+	}
+	if tracker.annotateOverload(nil) != nil {
+		t.Fatal("expected no overload annotation once clipping stopped")
+	}
+
+	// Headroom is below the recovery threshold, so gain must hold.
+	for i := 0; i < 2*gainRecoverBuffers; i++ {
+		rxStep()
+	}
+	if attrs, _ = backend.ReadAttributes(ctx); attrs.RxGain0DB != 57 {
+		t.Fatalf("gain recovered without headroom: %.0f dB", attrs.RxGain0DB)
+	}
+
+	// Once the signal drops, gain steps back up to the configured value.
+	backend.level = 0.2
+	for i := 0; i < 4*gainRecoverBuffers; i++ {
+		rxStep()
+	}
+	if attrs, _ = backend.ReadAttributes(ctx); attrs.RxGain0DB != 60 {
+		t.Fatalf("expected gain restored to 60 dB, got %.0f", attrs.RxGain0DB)
+	}
+	if len(reporter.events) == 0 || reporter.events[0][:11] != "RX overload" {
+		t.Fatalf("expected overload events, got %v", reporter.events)
+	}
+}
+
+func TestOverloadDetectionWithoutBackoff(t *testing.T) {
+	backend := &clippingMock{MockSDR: sdr.NewMock(), level: 1.5}
+	tracker := newOverloadTracker(t, backend, nil, false)
+	for i := 0; i < 5; i++ {
+		rx0, rx1, _ := backend.RX(context.Background())
+		tracker.checkOverload(context.Background(), rx0, rx1)
+	}
+	if attrs, _ := backend.ReadAttributes(context.Background()); attrs.RxGain0DB != 60 {
+		t.Fatalf("gain changed without auto backoff: %.0f", attrs.RxGain0DB)
+	}
+	if debug := tracker.annotateOverload(nil); debug == nil || !debug.Overload {
+		t.Fatal("expected overload flag")
+	}
+}
+
+func TestNoOverloadMonitorWithoutFullScale(t *testing.T) {
+	tracker := newOverloadTracker(t, sdr.NewMock(), nil, true)
+	if tracker.overload != nil {
+		t.Fatal("expected overload monitor disabled when full scale is unknown")
+	}
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// PatternSweep configures a steering-phase sweep for measuring the array's
+// monopulse response.
+type PatternSweep struct {
+	StartDeg float64 // first steering phase
+	StopDeg  float64 // last steering phase (inclusive)
+	StepDeg  float64 // phase increment, defaults to 1°
+	Dwell    int     // buffers averaged per step, defaults to 4
+}
+
+// PatternPoint is the averaged response at one steering phase.
+type PatternPoint struct {
+	PhaseDeg     float64
+	ThetaDeg     float64
+	SumDBFS      float64
+	DeltaDBFS    float64
+	RatioDB      float64 // delta relative to sum
+	MonoPhaseRad float64
+}
+
+// patternCSVHeader names the columns written by MeasurePattern.
+var patternCSVHeader = []string{"phase_deg", "theta_deg", "sum_dbfs", "delta_dbfs", "delta_sum_db", "mono_phase_rad"}
+
+// MeasurePattern slowly sweeps the steering phase, averaging Dwell buffers at
+// each step, and writes one CSV row per step to w as it goes so a cancelled
+// sweep still leaves usable data. The tracker must be initialized.
+func (t *Tracker) MeasurePattern(ctx context.Context, sweep PatternSweep, w io.Writer) ([]PatternPoint, error) {
+	if sweep.StepDeg <= 0 {
+		sweep.StepDeg = 1
+	}
+	if sweep.Dwell <= 0 {
+		sweep.Dwell = 4
+	}
+	if sweep.StartDeg == 0 && sweep.StopDeg == 0 {
+		sweep.StartDeg, sweep.StopDeg = -180, 180
+	}
+	if sweep.StopDeg < sweep.StartDeg {
+		return nil, fmt.Errorf("pattern sweep stop %.1f° is below start %.1f°", sweep.StopDeg, sweep.StartDeg)
+	}
+	if err := t.warmup(ctx); err != nil {
+		return nil, fmt.Errorf("warmup: %w", err)
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write(patternCSVHeader); err != nil {
+		return nil, err
+	}
+	steps := int(math.Floor((sweep.StopDeg-sweep.StartDeg)/sweep.StepDeg)) + 1
+	points := make([]PatternPoint, 0, steps)
+	for i := 0; i < steps; i++ {
+		phase := sweep.StartDeg + float64(i)*sweep.StepDeg
+		point, err := t.measurePatternPoint(ctx, phase, sweep.Dwell)
+		if err != nil {
+			return points, fmt.Errorf("phase %.1f°: %w", phase, err)
+		}
+		points = append(points, point)
+		if err := out.Write(point.record()); err != nil {
+			return points, err
+		}
+		out.Flush()
+		if err := out.Error(); err != nil {
+			return points, err
+		}
+	}
+	t.logger.Info("pattern sweep complete",
+		logging.Field{Key: "subsystem", Value: "tracker"},
+		logging.Field{Key: "points", Value: len(points)})
+	return points, nil
+}
+
+// measurePatternPoint averages sum and delta power over dwell fresh buffers.
+func (t *Tracker) measurePatternPoint(ctx context.Context, phase float64, dwell int) (PatternPoint, error) {
+	var sumPow, deltaPow, monoSin, monoCos float64
+	used := 0
+	for i := 0; i < dwell; i++ {
+		rx0, rx1, err := t.sdr.RX(ctx)
+		if err != nil {
+			return PatternPoint{}, fmt.Errorf("receive samples: %w", err)
+		}
+		t.applyFrequencyShift(rx0, rx1)
+		sumDB, deltaDB, mono, ok := dsp.SumDeltaResponse(rx0, rx1, phase, t.cfg.PhaseCal, t.startBin, t.endBin)
+		if !ok {
+			continue
+		}
+		sumPow += math.Pow(10, sumDB/10)
+		deltaPow += math.Pow(10, deltaDB/10)
+		monoSin += math.Sin(mono)
+		monoCos += math.Cos(mono)
+		used++
+	}
+	if used == 0 {
+		return PatternPoint{}, fmt.Errorf("no usable buffers")
+	}
+	sumDB := 10 * math.Log10(sumPow/float64(used))
+	deltaDB := 10 * math.Log10(deltaPow/float64(used))
+	return PatternPoint{
+		PhaseDeg:     phase,
+		ThetaDeg:     dsp.PhaseToTheta(phase, t.cfg.RxLO, t.cfg.SpacingWavelength),
+		SumDBFS:      sumDB,
+		DeltaDBFS:    deltaDB,
+		RatioDB:      deltaDB - sumDB,
+		MonoPhaseRad: math.Atan2(monoSin, monoCos),
+	}, nil
+}
+
+func (p PatternPoint) record() []string {
+	values := []float64{p.PhaseDeg, p.ThetaDeg, p.SumDBFS, p.DeltaDBFS, p.RatioDB, p.MonoPhaseRad}
+	rec := make([]string, len(values))
+	for i, v := range values {
+		rec[i] = strconv.FormatFloat(v, 'f', 4, 64)
+	}
+	return rec
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestMeasurePatternFindsSumPeak(t *testing.T) {
+	cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 1024, SpacingWavelength: 0.5, PhaseDelta: 35}
+	tracker := NewTracker(sdr.NewMock(), nil, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+	if err := tracker.Init(context.Background()); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	points, err := tracker.MeasurePattern(context.Background(), PatternSweep{StartDeg: -90, StopDeg: 30, StepDeg: 5, Dwell: 2}, &buf)
+	if err != nil {
+		t.Fatalf("MeasurePattern failed: %v", err)
+	}
+	if len(points) != 25 {
+		t.Fatalf("expected 25 points, got %d", len(points))
+	}
+
+	best := points[0]
+	for _, p := range points {
+		if p.SumDBFS > best.SumDBFS {
+			best = p
+		}
+	}
+	if math.Abs(best.PhaseDeg+cfg.PhaseDelta) > 5 {
+		t.Fatalf("expected sum peak near %.0f°, got %.0f°", -cfg.PhaseDelta, best.PhaseDeg)
+	}
+	if best.RatioDB > -20 {
+		t.Fatalf("expected deep delta null at the sum peak, got %.1f dB", best.RatioDB)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	if len(records) != len(points)+1 || records[0][0] != "phase_deg" {
+		t.Fatalf("unexpected CSV output: %v", records[:2])
+	}
+}
+
+func TestMeasurePatternRejectsInvertedRange(t *testing.T) {
+	tracker := NewTracker(sdr.NewMock(), nil, logging.New(logging.Info, logging.Text, io.Discard), Config{SampleRate: 2e6, NumSamples: 256})
+	if _, err := tracker.MeasurePattern(context.Background(), PatternSweep{StartDeg: 10, StopDeg: -10}, io.Discard); err == nil {
+		t.Fatal("expected error for stop below start")
+	}
+}
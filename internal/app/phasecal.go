@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// scanCalibrated is the coarse scan reason after a phase calibration changed
+// PhaseCal under a running tracker.
+const scanCalibrated = "phase calibrated"
+
+// PhaseCalOptions configures a phase calibration against a reference source.
+type PhaseCalOptions struct {
+	// SourceDeg is the angle of the reference source; 0 is boresight.
+	SourceDeg float64 `json:"sourceDeg"`
+	// StepDeg is the PhaseCal increment of the sweep over ±180°, default 5°.
+	StepDeg float64 `json:"stepDeg,omitempty"`
+	// Dwell is the number of buffers averaged per step, default 4.
+	Dwell int `json:"dwell,omitempty"`
+	// Inject points a simulated emitter (the mock backend) at SourceDeg for
+	// the measurement instead of expecting a real source there.
+	Inject bool `json:"inject,omitempty"`
+}
+
+// emitterSteerer is implemented by backends that simulate the emitter, such
+// as sdr.MockSDR.
+type emitterSteerer interface {
+	SetPhaseDelta(phaseDeltaDeg float64)
+	GetPhaseDelta() float64
+}
+
+// phaseCalPoint is the averaged monopulse error at one swept PhaseCal.
+type phaseCalPoint struct {
+	phaseCal float64
+	err      float64
+	sumPower float64
+}
+
+type phaseCalRequest struct {
+	ctx   context.Context
+	opts  PhaseCalOptions
+	reply chan phaseCalReply
+}
+
+type phaseCalReply struct {
+	cal calibration.PhaseCalibration
+	err error
+}
+
+// CalibratePhase sweeps PhaseCal with the array steered at a reference
+// source, fits the value that zeroes the monopulse error and applies it. A
+// running tracker performs the calibration between two iterations and then
+// re-acquires; otherwise it runs at once, which requires an initialized
+// tracker. The caller stores the result.
+func (t *Tracker) CalibratePhase(ctx context.Context, opts PhaseCalOptions) (calibration.PhaseCalibration, error) {
+	if !t.running.Load() {
+		return t.calibratePhase(ctx, opts)
+	}
+	req := phaseCalRequest{ctx: ctx, opts: opts, reply: make(chan phaseCalReply, 1)}
+	select {
+	case t.calReq <- req:
+	case <-ctx.Done():
+		return calibration.PhaseCalibration{}, ctx.Err()
+	}
+	select {
+	case rep := <-req.reply:
+		return rep.cal, rep.err
+	case <-ctx.Done():
+		return calibration.PhaseCalibration{}, ctx.Err()
+	}
+}
+
+// Running reports whether Run is executing.
+func (t *Tracker) Running() bool {
+	return t.running.Load()
+}
+
+// calibratePhase performs the sweep and applies the fitted PhaseCal.
+func (t *Tracker) calibratePhase(ctx context.Context, opts PhaseCalOptions) (calibration.PhaseCalibration, error) {
+	if opts.StepDeg <= 0 {
+		opts.StepDeg = 5
+	}
+	if opts.Dwell <= 0 {
+		opts.Dwell = 4
+	}
+	steer := dsp.ThetaToPhase(opts.SourceDeg, t.cfg.RxLO, t.cfg.SpacingWavelength)
+	if opts.Inject {
+		emitter, ok := sdr.As[emitterSteerer](t.sdr)
+		if !ok {
+			return calibration.PhaseCalibration{}, errors.New("backend cannot inject a reference source")
+		}
+		defer emitter.SetPhaseDelta(emitter.GetPhaseDelta())
+		// Steering at steer cancels an emitter phase delta of -steer.
+		emitter.SetPhaseDelta(-steer)
+	}
+	if err := t.warmup(ctx); err != nil {
+		return calibration.PhaseCalibration{}, fmt.Errorf("warmup: %w", err)
+	}
+
+	var points []phaseCalPoint
+	for cal := -180.0; cal < 180; cal += opts.StepDeg {
+		point, err := t.measurePhaseCalPoint(ctx, steer, cal, opts.Dwell)
+		if err != nil {
+			return calibration.PhaseCalibration{}, fmt.Errorf("phase cal %.1f°: %w", cal, err)
+		}
+		points = append(points, point)
+	}
+	phaseCal, residual, ok := fitPhaseCal(points)
+	if !ok {
+		return calibration.PhaseCalibration{}, errors.New("phase calibration: no signal from the reference source")
+	}
+
+	t.cfg.PhaseCal = phaseCal
+	if t.running.Load() {
+		t.rescan = scanCalibrated
+	}
+	t.logger.Info("phase calibrated",
+		logging.Field{Key: "subsystem", Value: "tracker"},
+		logging.Field{Key: "phase_cal_deg", Value: phaseCal},
+		logging.Field{Key: "residual_deg", Value: residual},
+		logging.Field{Key: "rx_lo_hz", Value: t.cfg.RxLO})
+	return calibration.PhaseCalibration{
+		Timestamp:   t.now(),
+		Backend:     t.sdr.Capabilities().Backend,
+		RxLOHz:      t.cfg.RxLO,
+		SourceDeg:   opts.SourceDeg,
+		PhaseCalDeg: phaseCal,
+		ResidualDeg: residual,
+		Points:      len(points),
+	}, nil
+}
+
+// measurePhaseCalPoint averages the monopulse error over dwell fresh
+// buffers with the array steered at steer and PhaseCal set to cal.
+func (t *Tracker) measurePhaseCalPoint(ctx context.Context, steer, cal float64, dwell int) (phaseCalPoint, error) {
+	point := phaseCalPoint{phaseCal: cal}
+	used := 0
+	for i := 0; i < dwell; i++ {
+		rx0, rx1, err := t.sdr.RX(ctx)
+		if err != nil {
+			return point, fmt.Errorf("receive samples: %w", err)
+		}
+		t.applyFrequencyShift(rx0, rx1)
+		e, power, ok := dsp.MonopulseError(rx0, rx1, steer, cal, t.startBin, t.endBin)
+		if !ok {
+			continue
+		}
+		point.err += e
+		point.sumPower += power
+		used++
+	}
+	if used == 0 {
+		return point, fmt.Errorf("no usable buffers")
+	}
+	point.err /= float64(used)
+	point.sumPower /= float64(used)
+	return point, nil
+}
+
+// fitPhaseCal inverts the monopulse error -tan((cal-cal₀)/2) of every sweep
+// point into an estimate of cal₀ and returns their circular mean, weighted
+// by sum power so points near the sum null, where the error is mostly
+// noise, count little. residual is the weighted circular spread in degrees.
+func fitPhaseCal(points []phaseCalPoint) (phaseCal, residual float64, ok bool) {
+	var sx, sy, total float64
+	for _, p := range points {
+		est := p.phaseCal*math.Pi/180 + 2*math.Atan(p.err)
+		sx += p.sumPower * math.Cos(est)
+		sy += p.sumPower * math.Sin(est)
+		total += p.sumPower
+	}
+	if total == 0 {
+		return 0, 0, false
+	}
+	r := math.Hypot(sx, sy) / total
+	residual = math.Sqrt(-2*math.Log(math.Max(r, 1e-12))) * 180 / math.Pi
+	return math.Atan2(sy, sx) * 180 / math.Pi, residual, true
+}
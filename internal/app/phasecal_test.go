@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestFitPhaseCal(t *testing.T) {
+	for _, want := range []float64{0, 37, -150, 179} {
+		var points []phaseCalPoint
+		for cal := -180.0; cal < 180; cal += 10 {
+			half := (cal - want) * math.Pi / 360
+			points = append(points, phaseCalPoint{phaseCal: cal, err: -math.Tan(half), sumPower: math.Cos(half) * math.Cos(half)})
+		}
+		got, residual, ok := fitPhaseCal(points)
+		if !ok || math.Abs(math.Remainder(got-want, 360)) > 1e-6 || residual > 1e-3 {
+			t.Fatalf("fit %v° (residual %v, ok %v), want %v°", got, residual, ok, want)
+		}
+	}
+	if _, _, ok := fitPhaseCal([]phaseCalPoint{{phaseCal: 10}}); ok {
+		t.Fatal("fit without signal power succeeded")
+	}
+}
+
+func TestCalibratePhaseMock(t *testing.T) {
+	tests := []struct {
+		name string
+		opts PhaseCalOptions
+		run  bool
+		want float64
+	}{
+		{name: "boresight source", opts: PhaseCalOptions{StepDeg: 15, Dwell: 1}, want: -35},
+		{name: "injected source", opts: PhaseCalOptions{SourceDeg: 20, StepDeg: 15, Dwell: 1, Inject: true}, want: 0},
+		{name: "running tracker", opts: PhaseCalOptions{StepDeg: 15, Dwell: 1}, run: true, want: -35},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := sdr.NewMock()
+			cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 512, SpacingWavelength: 0.5, PhaseStep: 1, ScanStep: 2, PhaseDelta: 35, Unpaced: true}
+			tracker := NewTracker(backend, &recordingReporter{}, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := tracker.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+			done := make(chan error, 1)
+			if tt.run {
+				go func() { done <- tracker.Run(ctx) }()
+				for !tracker.running.Load() {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			cal, err := tracker.CalibratePhase(ctx, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(math.Remainder(cal.PhaseCalDeg-tt.want, 360)) > 2 || cal.Points != 24 {
+				t.Fatalf("phase cal %.2f° from %d points, want %.0f°", cal.PhaseCalDeg, cal.Points, tt.want)
+			}
+			if tt.run {
+				cancel()
+				<-done
+			}
+			if tracker.cfg.PhaseCal != cal.PhaseCalDeg || backend.GetPhaseDelta() != 35 {
+				t.Fatalf("phase cal applied %v, emitter left at %v°", tracker.cfg.PhaseCal, backend.GetPhaseDelta())
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// polarityBuffers is the number of buffers averaged by the polarity check.
+const polarityBuffers = 8
+
+// polarityMarginDeg is how much better a corrected channel mapping must fit
+// the reference angle before it replaces the configured one. It keeps the
+// check from swapping channels when both fit equally, as at boresight.
+const polarityMarginDeg = 20.0
+
+// checkPolarity measures the inter-channel phase of a reference emitter at
+// PolarityRefDeg and detects swapped or inverted channels. A detected error
+// is corrected through the backend's ChannelMapper when present and reported
+// as a warning otherwise.
+func (t *Tracker) checkPolarity(ctx context.Context) error {
+	if !t.cfg.PolarityCheck {
+		return nil
+	}
+	var cross complex128
+	for i := 0; i < polarityBuffers; i++ {
+		rx0, rx1, err := t.sdr.RX(ctx)
+		if err != nil {
+			return fmt.Errorf("polarity check RX: %w", err)
+		}
+		if x, ok := dsp.CrossSpectrum(rx0, rx1, t.startBin, t.endBin); ok {
+			cross += x
+		}
+	}
+	if cross == 0 {
+		return fmt.Errorf("polarity check: no signal in band")
+	}
+	expected := dsp.ThetaToPhase(t.cfg.PolarityRefDeg, t.cfg.RxLO, t.cfg.SpacingWavelength)
+	swap, invert := polarityCorrection(cmplx.Phase(cross)*180/math.Pi, t.cfg.PhaseCal, expected)
+	if !swap && !invert {
+		t.logEvent("info", "polarity check passed")
+		return nil
+	}
+
+	mapper, ok := sdr.As[*sdr.ChannelMapper](t.sdr)
+	if !ok {
+		t.logEvent("warn", fmt.Sprintf("polarity check: channels appear swapped=%t inverted=%t; backend cannot remap them", swap, invert))
+		return nil
+	}
+	// Negating either channel flips the phase difference alike, so the
+	// corrections compose by toggling.
+	curSwap, curInvert := mapper.Mapping()
+	mapper.SetMapping(curSwap != swap, curInvert != invert)
+	t.logEvent("warn", fmt.Sprintf("polarity check: corrected channel mapping to swap=%t invert=%t; update the config to match", curSwap != swap, curInvert != invert))
+	return nil
+}
+
+// polarityCorrection returns the swap and inversion that best map the
+// measured rx0-rx1 phase (degrees) onto the steering delay expected for the
+// reference angle, preferring no change within polarityMarginDeg.
+func polarityCorrection(crossDeg, phaseCal, expectedDeg float64) (swap, invert bool) {
+	mismatch := func(swap, invert bool) float64 {
+		phase := crossDeg
+		if swap {
+			phase = -phase
+		}
+		if invert {
+			phase += 180
+		}
+		return math.Abs(math.Mod(phase-phaseCal-expectedDeg+540, 360) - 180)
+	}
+	best := mismatch(false, false) - polarityMarginDeg
+	for _, c := range [][2]bool{{false, true}, {true, false}, {true, true}} {
+		if m := mismatch(c[0], c[1]); m < best {
+			best, swap, invert = m, c[0], c[1]
+		}
+	}
+	return swap, invert
+}
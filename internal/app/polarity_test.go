@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestPolarityCorrection(t *testing.T) {
+	tests := []struct {
+		name         string
+		cross, cal   float64
+		expected     float64
+		swap, invert bool
+	}{
+		{name: "matching", cross: 90, expected: 90},
+		{name: "with phase cal", cross: 100, cal: 10, expected: 90},
+		{name: "inverted", cross: -90, expected: 90, invert: true},
+		{name: "swapped", cross: -40, expected: 40, swap: true},
+		{name: "swapped and inverted", cross: 120, expected: 60, swap: true, invert: true},
+		{name: "boresight keeps mapping", cross: 2, expected: 0},
+		{name: "within margin", cross: -5, expected: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swap, invert := polarityCorrection(tt.cross, tt.cal, tt.expected)
+			if swap != tt.swap || invert != tt.invert {
+				t.Fatalf("polarityCorrection = swap %v invert %v, want %v %v", swap, invert, tt.swap, tt.invert)
+			}
+		})
+	}
+}
+
+func TestCheckPolarityCorrectsChannelMapper(t *testing.T) {
+	tests := []struct {
+		name         string
+		swap, invert bool
+	}{
+		{name: "inverted", invert: true},
+		{name: "swapped", swap: true},
+		{name: "both", swap: true, invert: true},
+		{name: "correct"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := sdr.NewMock()
+			// A reference at +20° needs a 61.56° steering delay at
+			// half-wavelength spacing; the mock's phase delta is its negative.
+			if err := mock.Init(context.Background(), sdr.Config{SampleRate: 2e6, ToneOffset: 250e3, NumSamples: 256, PhaseDelta: -61.56}); err != nil {
+				t.Fatal(err)
+			}
+			mapper := sdr.NewChannelMapper(mock, tt.swap, tt.invert)
+			tracker := NewTracker(mapper, nil, logging.New(logging.Info, logging.Text, io.Discard), Config{
+				NumSamples:        256,
+				RxLO:              2.3e9,
+				SpacingWavelength: 0.5,
+				PolarityCheck:     true,
+				PolarityRefDeg:    20,
+			})
+			tracker.startBin, tracker.endBin = 0, 256
+			if err := tracker.checkPolarity(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if swap, invert := mapper.Mapping(); swap || invert {
+				t.Fatalf("mapping after check = swap %v invert %v, want neither", swap, invert)
+			}
+		})
+	}
+}
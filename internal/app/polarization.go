@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// Polarization modes for arrays with dual-polarized elements
+// (Config.Polarization). The empty mode reads one polarization per element
+// through SDR.RX.
+const (
+	PolarizationH       = "h"       // horizontal ports only
+	PolarizationV       = "v"       // vertical ports only
+	PolarizationSelect  = "select"  // the stronger polarization, per buffer
+	PolarizationCombine = "combine" // maximal-ratio combination of both
+)
+
+// PolarizationModes lists the accepted non-empty Config.Polarization values.
+var PolarizationModes = []string{PolarizationH, PolarizationV, PolarizationSelect, PolarizationCombine}
+
+// maxPolarizationRatioDB bounds the reported H/V ratio, which is infinite
+// when one polarization receives nothing.
+const maxPolarizationRatioDB = 60
+
+// initPolarization finds the dual-polarized receive path for a polarization
+// mode.
+func (t *Tracker) initPolarization(caps sdr.Capabilities) error {
+	switch t.cfg.Polarization {
+	case "":
+		return nil
+	case PolarizationH, PolarizationV, PolarizationSelect, PolarizationCombine:
+	default:
+		return fmt.Errorf("unknown polarization mode %q", t.cfg.Polarization)
+	}
+	dualPol, ok := sdr.As[sdr.DualPolRX](t.sdr)
+	if !ok || !caps.DualPol {
+		return fmt.Errorf("polarization mode %q needs a backend with dual-polarized elements (%s has none)", t.cfg.Polarization, caps.Backend)
+	}
+	t.dualPol = dualPol
+	return nil
+}
+
+// receive reads one buffer per element. In a polarization mode the four
+// dual-polarized channels are read and reduced to two, and the H/V ratio at
+// the signal is kept for the next report.
+func (t *Tracker) receive(ctx context.Context) ([]complex64, []complex64, error) {
+	if t.dualPol == nil {
+		return t.sdr.RX(ctx)
+	}
+	bufs, err := t.dualPol.RXDualPol(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	rx0, rx1, ratio, ok := reducePolarization(t.cfg.Polarization, bufs, t.startBin, t.endBin)
+	t.polRatioDB, t.polValid = ratio, ok
+	return rx0, rx1, nil
+}
+
+// reducePolarization selects or combines the polarizations of both elements
+// for mode and returns the H/V power ratio at the signal. Combining uses the
+// weights of element 0 for both elements, preserving their phase difference.
+func reducePolarization(mode string, b sdr.DualPolBuffers, startBin, endBin int) (rx0, rx1 []complex64, ratioDB float64, ok bool) {
+	h0, v0, ok0 := dsp.PolarizationResponse(b.H0, b.V0, startBin, endBin)
+	h1, v1, ok1 := dsp.PolarizationResponse(b.H1, b.V1, startBin, endBin)
+	ok = ok0 && ok1
+	if ok {
+		ratioDB = clamp(dsp.PolarizationRatioDB([]complex128{h0, h1}, []complex128{v0, v1}), -maxPolarizationRatioDB, maxPolarizationRatioDB)
+	}
+	switch {
+	case mode == PolarizationV, mode == PolarizationSelect && ok && ratioDB < 0:
+		return b.V0, b.V1, ratioDB, ok
+	case mode == PolarizationCombine && ok:
+		wH, wV := dsp.PolarizationWeights(h0, v0)
+		return dsp.CombinePolarizations(b.H0, b.V0, wH, wV), dsp.CombinePolarizations(b.H1, b.V1, wH, wV), ratioDB, ok
+	}
+	return b.H0, b.H1, ratioDB, ok
+}
+
+// polarizationSample builds the reported track with its dominant
+// polarization.
+func (t *Tracker) polarizationSample(angle, peak, snr, confidence float64, state telemetry.LockState, debug *telemetry.DebugInfo) telemetry.MultiTrackSample {
+	track := telemetry.TrackSample{
+		AngleDeg:   angle,
+		Peak:       peak,
+		SNR:        snr,
+		Confidence: confidence,
+		LockState:  state,
+		Debug:      debug,
+	}
+	if t.polValid {
+		track.Polarization = telemetry.PolarizationHorizontal
+		if t.polRatioDB < 0 {
+			track.Polarization = telemetry.PolarizationVertical
+		}
+		track.PolarizationRatioDB = t.polRatioDB
+	}
+	return telemetry.MultiTrackSample{Timestamp: t.now(), Tracks: []telemetry.TrackSample{track}}
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestReducePolarization(t *testing.T) {
+	const n = 512
+	start, end := dsp.SignalBinRange(n, 2e6, 200e3)
+	tests := []struct {
+		mode      string
+		tiltDeg   float64
+		wantRatio float64 // sign only
+		wantPort  string  // which ports were passed through, "" when combined
+	}{
+		{PolarizationH, 80, -1, "h"},
+		{PolarizationV, 10, 1, "v"},
+		{PolarizationSelect, 10, 1, "h"},
+		{PolarizationSelect, 80, -1, "v"},
+		{PolarizationCombine, 60, -1, ""},
+	}
+	for _, tt := range tests {
+		mock := sdr.NewMock()
+		if err := mock.Init(context.Background(), sdr.Config{SampleRate: 2e6, ToneOffset: 200e3, NumSamples: n, PhaseDelta: 40, MockPolarizationDeg: tt.tiltDeg}); err != nil {
+			t.Fatal(err)
+		}
+		bufs, err := mock.RXDualPol(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rx0, rx1, ratio, ok := reducePolarization(tt.mode, bufs, start, end)
+		if !ok || math.Signbit(ratio) != math.Signbit(tt.wantRatio) {
+			t.Errorf("%s tilt %v: ratio %v ok %v, want sign of %v", tt.mode, tt.tiltDeg, ratio, ok, tt.wantRatio)
+			continue
+		}
+		switch tt.wantPort {
+		case "h":
+			if &rx0[0] != &bufs.H0[0] || &rx1[0] != &bufs.H1[0] {
+				t.Errorf("%s tilt %v: want the H ports", tt.mode, tt.tiltDeg)
+			}
+		case "v":
+			if &rx0[0] != &bufs.V0[0] || &rx1[0] != &bufs.V1[0] {
+				t.Errorf("%s tilt %v: want the V ports", tt.mode, tt.tiltDeg)
+			}
+		}
+		// The inter-element phase survives selection and combining.
+		x, _ := dsp.CrossSpectrum(rx1, rx0, start, end)
+		if got := math.Atan2(imag(x), real(x)) * 180 / math.Pi; math.Abs(got-40) > 1 {
+			t.Errorf("%s tilt %v: element phase %v°, want 40°", tt.mode, tt.tiltDeg, got)
+		}
+	}
+}
+
+func TestTrackerReportsPolarization(t *testing.T) {
+	rand.Seed(11)
+	reporter := &polarizationRecorder{}
+	cfg := Config{
+		SampleRate:          2e6,
+		RxLO:                2.3e9,
+		ToneOffset:          200e3,
+		NumSamples:          512,
+		SpacingWavelength:   0.5,
+		PhaseStep:           1,
+		ScanStep:            2,
+		PhaseDelta:          30,
+		Polarization:        PolarizationCombine,
+		MockPolarizationDeg: 75,
+		Unpaced:             true,
+	}
+	tracker := NewTracker(sdr.NewMock(), reporter, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := tracker.Run(ctx); err != nil && err != context.DeadlineExceeded {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(reporter.tracks) == 0 {
+		t.Fatal("no reports")
+	}
+	last := reporter.tracks[len(reporter.tracks)-1]
+	wantRatio := 20 * math.Log10(math.Cos(75*math.Pi/180)/math.Sin(75*math.Pi/180))
+	if last.Polarization != telemetry.PolarizationVertical || math.Abs(last.PolarizationRatioDB-wantRatio) > 0.5 {
+		t.Fatalf("polarization %q ratio %v dB, want V %v dB", last.Polarization, last.PolarizationRatioDB, wantRatio)
+	}
+	if math.Abs(tracker.LastDelay()+cfg.PhaseDelta) > 5 {
+		t.Fatalf("expected delay near %.2f got %.2f", -cfg.PhaseDelta, tracker.LastDelay())
+	}
+}
+
+func TestPolarizationNeedsDualPolBackend(t *testing.T) {
+	backend := struct{ sdr.SDR }{sdr.NewMock()} // hides RXDualPol
+	tracker := NewTracker(backend, nil, logging.New(logging.Info, logging.Text, io.Discard), Config{NumSamples: 512, Polarization: PolarizationSelect})
+	if err := tracker.Init(context.Background()); err == nil {
+		t.Fatal("expected an error for a single-polarized backend")
+	}
+}
+
+type polarizationRecorder struct {
+	tracks []telemetry.TrackSample
+}
+
+func (r *polarizationRecorder) Report(float64, float64, float64, float64, telemetry.LockState, *telemetry.DebugInfo) {
+}
+
+func (r *polarizationRecorder) ReportMultiTrack(sample telemetry.MultiTrackSample) {
+	r.tracks = append(r.tracks, sample.Tracks...)
+}
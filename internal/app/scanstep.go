@@ -0,0 +1,41 @@
+package app
+
+import "math"
+
+const (
+	// scanStepLowSNR and scanStepHighSNR (dB) bound the adaptive scan step:
+	// at or below the low SNR the coarse scan uses ScanStepMax, since finer
+	// steps only resolve noise; at or above the high SNR it uses ScanStepMin.
+	// In between the step follows the SNR linearly.
+	scanStepLowSNR  = 6.0
+	scanStepHighSNR = 25.0
+)
+
+// adaptiveScanStep reports whether ScanStepMin and ScanStepMax are set.
+func (c Config) adaptiveScanStep() bool {
+	return c.ScanStepMin > 0 && c.ScanStepMax > 0
+}
+
+// scanStep returns the phase step in degrees for the next coarse scan. With
+// ScanStepMin and ScanStepMax set it adapts to the SNR of the latest
+// measurement: coarse at low SNR, where fine steps only measure noise, and
+// fine at high SNR, where they improve the acquired angle. Until an SNR is
+// measured, ScanStep is used within the bounds. Otherwise ScanStep is fixed.
+func (t *Tracker) scanStep() float64 {
+	if !t.cfg.adaptiveScanStep() {
+		return t.cfg.ScanStep
+	}
+	lo, hi := t.cfg.ScanStepMin, t.cfg.ScanStepMax
+	if !t.snrValid {
+		return math.Min(math.Max(t.cfg.ScanStep, lo), hi)
+	}
+	frac := (t.lastSNR - scanStepLowSNR) / (scanStepHighSNR - scanStepLowSNR)
+	frac = math.Min(math.Max(frac, 0), 1)
+	return hi - frac*(hi-lo)
+}
+
+// observeSNR records the SNR of the latest measurement for the adaptive
+// scan step.
+func (t *Tracker) observeSNR(snr float64) {
+	t.lastSNR, t.snrValid = snr, true
+}
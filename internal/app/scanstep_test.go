@@ -0,0 +1,34 @@
+package app
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScanStep(t *testing.T) {
+	adaptive := Config{ScanStep: 2, ScanStepMin: 0.5, ScanStepMax: 8}
+	tests := []struct {
+		name string
+		cfg  Config
+		snr  float64 // NaN: nothing measured yet
+		want float64
+	}{
+		{name: "fixed", cfg: Config{ScanStep: 2}, snr: 3, want: 2},
+		{name: "no SNR yet", cfg: adaptive, snr: math.NaN(), want: 2},
+		{name: "no SNR, step below bounds", cfg: Config{ScanStep: 0.1, ScanStepMin: 0.5, ScanStepMax: 8}, snr: math.NaN(), want: 0.5},
+		{name: "noise", cfg: adaptive, snr: 2, want: 8},
+		{name: "strong", cfg: adaptive, snr: 40, want: 0.5},
+		{name: "midway", cfg: adaptive, snr: (scanStepLowSNR + scanStepHighSNR) / 2, want: 4.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Tracker{cfg: tt.cfg}
+			if !math.IsNaN(tt.snr) {
+				tr.observeSNR(tt.snr)
+			}
+			if got := tr.scanStep(); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("scanStep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
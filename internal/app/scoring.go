@@ -0,0 +1,188 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ScoreFunc rates a track, higher is better. When the track manager is over
+// capacity it drops the lowest-scoring track first.
+type ScoreFunc func(track Track) float64
+
+// ScoreWeights weight the terms of the built-in scorers: SNR (normalized to
+// 30 dB), confidence and the consecutive-miss penalty.
+type ScoreWeights struct {
+	SNR        float64
+	Confidence float64
+	Miss       float64
+}
+
+// DefaultScoreWeights returns the weights used when none are configured.
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{SNR: 0.6, Confidence: 0.3, Miss: 0.1}
+}
+
+// ParseScoreWeights parses "snr,confidence,miss", e.g. "0.6,0.3,0.1". An
+// empty string selects the defaults.
+func ParseScoreWeights(s string) (ScoreWeights, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultScoreWeights(), nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return ScoreWeights{}, fmt.Errorf("score weights %q: want snr,confidence,miss", s)
+	}
+	var values [3]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || v < 0 {
+			return ScoreWeights{}, fmt.Errorf("score weights %q: %q is not a non-negative number", s, part)
+		}
+		values[i] = v
+	}
+	return ScoreWeights{SNR: values[0], Confidence: values[1], Miss: values[2]}, nil
+}
+
+// String formats the weights the way ParseScoreWeights reads them.
+func (w ScoreWeights) String() string {
+	return strconv.FormatFloat(w.SNR, 'g', -1, 64) + "," +
+		strconv.FormatFloat(w.Confidence, 'g', -1, 64) + "," +
+		strconv.FormatFloat(w.Miss, 'g', -1, 64)
+}
+
+// WeightedScore scores a track on its latest SNR and confidence, minus 0.2
+// per consecutive miss. It suits fast movers, whose tracks should give way
+// as soon as they fade.
+func WeightedScore(w ScoreWeights) ScoreFunc {
+	return func(track Track) float64 {
+		snrScore := clamp(track.SNR/30.0, 0, 1)
+		confScore := clamp(track.Confidence, 0, 1)
+		missPenalty := clamp(1-0.2*float64(track.ConsecutiveMisses), 0, 1)
+		return w.SNR*snrScore + w.Confidence*confScore + w.Miss*missPenalty
+	}
+}
+
+// PersistenceScore replaces the latest confidence with the fraction of
+// updates the track has been detected in over its lifetime, so a
+// long-established static beacon survives a brief fade while a new
+// interferer is pruned first.
+func PersistenceScore(w ScoreWeights) ScoreFunc {
+	return func(track Track) float64 {
+		snrScore := clamp(track.SNR/30.0, 0, 1)
+		hitRatio := 0.0
+		if total := track.TotalDetections + track.Misses; total > 0 {
+			hitRatio = float64(track.TotalDetections) / float64(total)
+		}
+		missPenalty := clamp(1-0.2*float64(track.ConsecutiveMisses), 0, 1)
+		return w.SNR*snrScore + w.Confidence*hitRatio + w.Miss*missPenalty
+	}
+}
+
+var (
+	scorersMu sync.RWMutex
+	scorers   = map[string]func(ScoreWeights) ScoreFunc{
+		"weighted":    WeightedScore,
+		"persistence": PersistenceScore,
+	}
+)
+
+// RegisterScorer makes a scoring function selectable by name (Config
+// TrackScorer, monopulse -track-scorer). newScorer receives the configured
+// weights. Register from an init function before the tracker starts.
+func RegisterScorer(name string, newScorer func(ScoreWeights) ScoreFunc) error {
+	if name == "" || newScorer == nil {
+		return fmt.Errorf("register scorer: name and function are required")
+	}
+	scorersMu.Lock()
+	defer scorersMu.Unlock()
+	if _, ok := scorers[name]; ok {
+		return fmt.Errorf("register scorer: %q already registered", name)
+	}
+	scorers[name] = newScorer
+	return nil
+}
+
+// ScorerNames returns the registered scorer names, sorted.
+func ScorerNames() []string {
+	scorersMu.RLock()
+	defer scorersMu.RUnlock()
+	names := make([]string, 0, len(scorers))
+	for name := range scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TrackPolicy holds the multi-target track manager's association gate and
+// strategy, lifecycle thresholds and scoring.
+type TrackPolicy struct {
+	GateDeg       float64 // largest angle change still associated with a track
+	ConfirmHits   int     // detections within ConfirmWindow that confirm a track
+	ConfirmWindow int     // updates considered for confirmation
+	MaxMisses     int     // consecutive misses that mark a track lost
+	Score         ScoreFunc
+	Associate     Associator
+}
+
+// DefaultTrackPolicy returns the built-in gate, thresholds and scorer.
+func DefaultTrackPolicy() TrackPolicy {
+	return TrackPolicy{
+		GateDeg:       5,
+		ConfirmHits:   3,
+		ConfirmWindow: 5,
+		MaxMisses:     3,
+		Score:         WeightedScore(DefaultScoreWeights()),
+		Associate:     NearestAssociation,
+	}
+}
+
+// trackPolicy resolves the configured overrides onto the default policy.
+func (c Config) trackPolicy() (TrackPolicy, error) {
+	policy := DefaultTrackPolicy()
+	if c.TrackGateDeg != 0 {
+		policy.GateDeg = c.TrackGateDeg
+	}
+	if c.ConfirmHits != 0 {
+		policy.ConfirmHits = c.ConfirmHits
+	}
+	if c.ConfirmWindow != 0 {
+		policy.ConfirmWindow = c.ConfirmWindow
+	}
+	if c.MaxMisses != 0 {
+		policy.MaxMisses = c.MaxMisses
+	}
+	switch {
+	case policy.GateDeg < 0:
+		return TrackPolicy{}, fmt.Errorf("track gate must be positive, got %g", policy.GateDeg)
+	case policy.ConfirmHits < 1 || policy.ConfirmWindow < policy.ConfirmHits:
+		return TrackPolicy{}, fmt.Errorf("confirm hits (%d) must be between 1 and the confirm window (%d)", policy.ConfirmHits, policy.ConfirmWindow)
+	case policy.MaxMisses < 1:
+		return TrackPolicy{}, fmt.Errorf("max misses must be at least 1, got %d", policy.MaxMisses)
+	}
+
+	weights := DefaultScoreWeights()
+	if c.ScoreWeights != (ScoreWeights{}) {
+		weights = c.ScoreWeights
+	}
+	name := c.TrackScorer
+	if name == "" {
+		name = "weighted"
+	}
+	scorersMu.RLock()
+	newScorer, ok := scorers[name]
+	scorersMu.RUnlock()
+	if !ok {
+		return TrackPolicy{}, fmt.Errorf("unknown track scorer %q (have %s)", name, strings.Join(ScorerNames(), ", "))
+	}
+	policy.Score = newScorer(weights)
+	associate, err := lookupAssociator(c.Association)
+	if err != nil {
+		return TrackPolicy{}, err
+	}
+	policy.Associate = associate
+	return policy, nil
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScoreWeights(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ScoreWeights
+		wantErr bool
+	}{
+		{in: "", want: DefaultScoreWeights()},
+		{in: "1, 0, 0.5", want: ScoreWeights{SNR: 1, Miss: 0.5}},
+		{in: "1,2", wantErr: true},
+		{in: "1,x,0", wantErr: true},
+		{in: "1,-1,0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseScoreWeights(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseScoreWeights(%q) = %+v, %v", tt.in, got, err)
+		}
+	}
+	if w := DefaultScoreWeights(); w.String() != "0.6,0.3,0.1" {
+		t.Fatalf("String() = %q", w.String())
+	}
+}
+
+func TestTrackPolicyFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "defaults", cfg: Config{}},
+		{name: "overrides", cfg: Config{TrackGateDeg: 10, ConfirmHits: 2, ConfirmWindow: 2, MaxMisses: 8, TrackScorer: "persistence"}},
+		{name: "hits beyond window", cfg: Config{ConfirmHits: 6}, wantErr: "confirm hits"},
+		{name: "negative gate", cfg: Config{TrackGateDeg: -1}, wantErr: "track gate"},
+		{name: "unknown scorer", cfg: Config{TrackScorer: "nope"}, wantErr: "unknown track scorer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := tt.cfg.trackPolicy()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || policy.Score == nil {
+				t.Fatalf("unexpected policy %+v, %v", policy, err)
+			}
+		})
+	}
+}
+
+func TestWeightedScoreMatchesDefaultWeights(t *testing.T) {
+	score := WeightedScore(DefaultScoreWeights())
+	got := score(Track{SNR: 15, Confidence: 0.5, ConsecutiveMisses: 1})
+	if want := 0.6*0.5 + 0.3*0.5 + 0.1*0.8; got < want-1e-12 || got > want+1e-12 {
+		t.Fatalf("score = %v, want %v", got, want)
+	}
+}
+
+func TestRegisteredScorerDrivesPruning(t *testing.T) {
+	// Keep the leftmost targets regardless of signal strength.
+	leftmost := func(ScoreWeights) ScoreFunc {
+		return func(track Track) float64 { return -track.Angle }
+	}
+	if err := RegisterScorer("test-leftmost", leftmost); err != nil {
+		t.Fatalf("RegisterScorer: %v", err)
+	}
+	if err := RegisterScorer("test-leftmost", leftmost); err == nil {
+		t.Fatal("duplicate registration should fail")
+	}
+
+	policy, err := Config{TrackScorer: "test-leftmost"}.trackPolicy()
+	if err != nil {
+		t.Fatalf("trackPolicy: %v", err)
+	}
+	tm := NewTrackManager(2, time.Minute, 0, 10)
+	tm.SetPolicy(policy)
+	now := time.Now()
+	tracks := tm.Update([]Detection{
+		{Angle: -20, SNR: 5},
+		{Angle: 30, SNR: 30},
+		{Angle: 0, SNR: 20},
+	}, now)
+	if len(tracks) != 2 {
+		t.Fatalf("expected 2 tracks, got %+v", tracks)
+	}
+	for _, track := range tracks {
+		if track.Angle == 30 {
+			t.Fatalf("strongest but rightmost track should be pruned: %+v", tracks)
+		}
+	}
+}
+
+func TestTrackPolicyMaxMisses(t *testing.T) {
+	tm := NewTrackManager(4, time.Minute, 0, 10)
+	policy := DefaultTrackPolicy()
+	policy.MaxMisses = 1
+	tm.SetPolicy(policy)
+	now := time.Now()
+	tm.Update([]Detection{{Angle: 10, SNR: 20}}, now)
+	tracks := tm.Update(nil, now.Add(time.Second))
+	if len(tracks) != 1 || tracks[0].State != TrackLost {
+		t.Fatalf("one miss should lose the track, got %+v", tracks)
+	}
+}
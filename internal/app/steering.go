@@ -0,0 +1,59 @@
+package app
+
+import (
+	"math"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// steeringReporter is implemented by reporters that keep the steering angle.
+type steeringReporter interface {
+	ReportSteering(s telemetry.Steering)
+}
+
+// steeringConditioner turns the noisy measured angle into a steering angle
+// for rotators: the output only moves once the measurement has been more
+// than deadband away from it for persist consecutive iterations.
+type steeringConditioner struct {
+	deadband float64
+	persist  int
+
+	output  float64
+	primed  bool
+	pending int
+}
+
+func newSteeringConditioner(deadband float64, persist int) *steeringConditioner {
+	return &steeringConditioner{deadband: math.Max(deadband, 0), persist: max(persist, 1)}
+}
+
+// update feeds one measured angle and returns the conditioned angle and
+// whether it changed. The first measurement is passed through.
+func (c *steeringConditioner) update(angle float64) (float64, bool) {
+	if !c.primed {
+		c.output, c.primed = angle, true
+		return c.output, true
+	}
+	if math.Abs(angle-c.output) <= c.deadband {
+		c.pending = 0
+		return c.output, false
+	}
+	c.pending++
+	if c.pending < c.persist {
+		return c.output, false
+	}
+	c.output, c.pending = angle, 0
+	return c.output, true
+}
+
+// reportSteering passes a reported angle through the conditioner and
+// publishes the raw and conditioned angles.
+func (t *Tracker) reportSteering(angle float64) {
+	if t.steering == nil {
+		return
+	}
+	out, changed := t.steering.update(angle)
+	if reporter, ok := t.reporter.(steeringReporter); ok {
+		reporter.ReportSteering(telemetry.Steering{Timestamp: t.now(), RawDeg: angle, AngleDeg: out, Changed: changed})
+	}
+}
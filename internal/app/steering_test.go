@@ -0,0 +1,53 @@
+package app
+
+import "testing"
+
+func TestSteeringConditioner(t *testing.T) {
+	type step struct {
+		angle   float64
+		want    float64
+		changed bool
+	}
+	tests := []struct {
+		name     string
+		deadband float64
+		persist  int
+		steps    []step
+	}{
+		{
+			name:     "deadband",
+			deadband: 2,
+			steps: []step{
+				{10, 10, true},
+				{11.5, 10, false},
+				{8.2, 10, false},
+				{12.5, 12.5, true},
+			},
+		},
+		{
+			name:     "persistence",
+			deadband: 2,
+			persist:  3,
+			steps: []step{
+				{10, 10, true},
+				{15, 10, false},
+				{15.5, 10, false},
+				{10.5, 10, false}, // back inside resets the count
+				{15, 10, false},
+				{16, 10, false},
+				{15.5, 15.5, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSteeringConditioner(tt.deadband, tt.persist)
+			for i, s := range tt.steps {
+				got, changed := c.update(s.angle)
+				if got != s.want || changed != s.changed {
+					t.Fatalf("step %d: update(%v) = %v, %v; want %v, %v", i, s.angle, got, changed, s.want, s.changed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// FrequencySweep configures a spectrum survey over a range of RX LO
+// frequencies.
+type FrequencySweep struct {
+	StartHz float64
+	StopHz  float64
+	// StepHz is the LO increment, at most the sample rate. The default of
+	// 80% of the sample rate keeps the filtered band edges of every capture
+	// out of the survey.
+	StepHz float64
+	// Dwell is the number of buffers averaged per step, default 4.
+	Dwell int
+	// ThresholdDB is how far above the noise floor a signal must rise to be
+	// detected, default 10 dB.
+	ThresholdDB float64
+	// Interval is the time between the starts of the passes of Scanner.Run,
+	// default one second. Passes that take longer follow each other at once.
+	Interval time.Duration
+}
+
+// sweepReporter is implemented by reporters that keep frequency sweeps.
+type sweepReporter interface {
+	ReportSweep(s telemetry.Sweep)
+}
+
+// Scanner surveys the spectrum by stepping the RX LO of a backend that a
+// Tracker has initialized, recording the peak power and the signals found at
+// every step. It finds an emitter's frequency before the tracker is tuned to
+// it; the two must not use the backend at the same time.
+type Scanner struct {
+	sdr      sdr.SDR
+	reporter telemetry.Reporter
+	logger   logging.Logger
+	cfg      Config
+	dsp      *dsp.CachedDSP
+	pass     int
+}
+
+// NewScanner creates a scanner for backend. cfg is the configuration the
+// backend was initialized with; its RxLO is restored after every sweep.
+func NewScanner(backend sdr.SDR, reporter telemetry.Reporter, logger logging.Logger, cfg Config) *Scanner {
+	if logger == nil {
+		logger = logging.Default()
+	}
+	s := &Scanner{sdr: backend, reporter: reporter, logger: logger, cfg: cfg, dsp: dsp.NewCachedDSP(cfg.NumSamples)}
+	s.dsp.SetWindow(cfg.FFTWindow)
+	return s
+}
+
+// Run repeats Sweep every sweep.Interval until ctx is done.
+func (s *Scanner) Run(ctx context.Context, sweep FrequencySweep) error {
+	if sweep.Interval <= 0 {
+		sweep.Interval = time.Second
+	}
+	ticker := s.clock().NewTicker(sweep.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Sweep(ctx, sweep); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// Sweep steps the RX LO across the range once, reporting the sweep after
+// every step, and returns it with the detections of all steps, strongest
+// first. The RX LO is restored afterwards, also on error.
+func (s *Scanner) Sweep(ctx context.Context, sweep FrequencySweep) (result telemetry.Sweep, err error) {
+	sweep, err = s.normalize(sweep)
+	if err != nil {
+		return telemetry.Sweep{}, err
+	}
+	accessor, ok := sdr.As[sdr.AttributeAccessor](s.sdr)
+	if !ok {
+		return telemetry.Sweep{}, errors.New("frequency sweep: backend cannot change the RX LO")
+	}
+	defer func() {
+		if rerr := accessor.WriteAttribute(context.WithoutCancel(ctx), sdr.AttrRxLO, s.cfg.RxLO); rerr != nil && err == nil {
+			err = fmt.Errorf("restore RX LO: %w", rerr)
+		}
+	}()
+
+	s.pass++
+	result = telemetry.Sweep{StartHz: sweep.StartHz, StopHz: sweep.StopHz, StepHz: sweep.StepHz, Pass: s.pass}
+	for center := sweep.StartHz + sweep.StepHz/2; center-sweep.StepHz/2 < sweep.StopHz; center += sweep.StepHz {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		step, err := s.measureStep(ctx, accessor, center, sweep)
+		if err != nil {
+			return result, fmt.Errorf("sweep step %.0f Hz: %w", center, err)
+		}
+		result.Steps = append(result.Steps, step)
+		result.Detections = append(result.Detections, step.Detections...)
+		result.Complete = center+sweep.StepHz/2 >= sweep.StopHz
+		result.Timestamp = s.clock().Now()
+		sort.Slice(result.Detections, func(i, j int) bool { return result.Detections[i].PowerDBFS > result.Detections[j].PowerDBFS })
+		if reporter, ok := s.reporter.(sweepReporter); ok {
+			reporter.ReportSweep(result)
+		}
+	}
+	s.logger.Info("frequency sweep complete",
+		logging.Field{Key: "subsystem", Value: "scanner"},
+		logging.Field{Key: "pass", Value: result.Pass},
+		logging.Field{Key: "steps", Value: len(result.Steps)},
+		logging.Field{Key: "detections", Value: len(result.Detections)})
+	return result, nil
+}
+
+// normalize applies the FrequencySweep defaults and checks the range.
+func (s *Scanner) normalize(sweep FrequencySweep) (FrequencySweep, error) {
+	if sweep.StepHz <= 0 {
+		sweep.StepHz = 0.8 * s.cfg.SampleRate
+	}
+	if sweep.Dwell <= 0 {
+		sweep.Dwell = 4
+	}
+	if sweep.ThresholdDB <= 0 {
+		sweep.ThresholdDB = 10
+	}
+	switch {
+	case sweep.StartHz <= 0 || sweep.StopHz <= sweep.StartHz:
+		return sweep, fmt.Errorf("frequency sweep: need 0 < start < stop, got %.0f to %.0f Hz", sweep.StartHz, sweep.StopHz)
+	case sweep.StepHz > s.cfg.SampleRate:
+		return sweep, fmt.Errorf("frequency sweep: step %.0f Hz exceeds the sample rate %.0f Hz", sweep.StepHz, s.cfg.SampleRate)
+	}
+	return sweep, nil
+}
+
+// measureStep tunes to center, drops one buffer captured while the LO
+// settled and averages the channel 0 power spectrum over sweep.Dwell
+// buffers. Detections and the peak are limited to the StepHz wide slice
+// around center that this step covers.
+func (s *Scanner) measureStep(ctx context.Context, accessor sdr.AttributeAccessor, center float64, sweep FrequencySweep) (telemetry.SweepStep, error) {
+	step := telemetry.SweepStep{CenterHz: center}
+	if err := accessor.WriteAttribute(ctx, sdr.AttrRxLO, center); err != nil {
+		return step, fmt.Errorf("tune: %w", err)
+	}
+	if _, _, err := s.sdr.RX(ctx); err != nil {
+		return step, fmt.Errorf("receive samples: %w", err)
+	}
+	var power []float64
+	for i := 0; i < sweep.Dwell; i++ {
+		rx0, _, err := s.sdr.RX(ctx)
+		if err != nil {
+			return step, fmt.Errorf("receive samples: %w", err)
+		}
+		_, spectrum := s.dsp.FFTAndDBFS(rx0)
+		if power == nil {
+			power = make([]float64, len(spectrum))
+		}
+		for bin, db := range spectrum {
+			power[bin] += math.Pow(10, db/10) / float64(sweep.Dwell)
+		}
+	}
+	spectrum := make([]float64, len(power))
+	step.PeakDBFS = math.Inf(-1)
+	n := len(power)
+	for bin, p := range power {
+		// Floor at -200 dB so silent bins stay finite.
+		spectrum[bin] = 10 * math.Log10(math.Max(p, 1e-20))
+		offset := float64(bin-n/2) * s.cfg.SampleRate / float64(n)
+		if math.Abs(offset) < sweep.StepHz/2 && spectrum[bin] > step.PeakDBFS {
+			step.PeakDBFS, step.PeakHz = spectrum[bin], center+offset
+		}
+	}
+	floor, detections := dsp.DetectSignals(spectrum, s.cfg.SampleRate, sweep.ThresholdDB)
+	step.NoiseFloorDBFS = floor
+	for _, d := range detections {
+		if math.Abs(d.OffsetHz) >= sweep.StepHz/2 {
+			continue
+		}
+		step.Detections = append(step.Detections, telemetry.SweepDetection{FrequencyHz: center + d.OffsetHz, PowerDBFS: d.PowerDB, SNRDB: d.SNRDB})
+		s.logger.Debug("sweep detection",
+			logging.Field{Key: "subsystem", Value: "scanner"},
+			logging.Field{Key: "frequency_hz", Value: center + d.OffsetHz},
+			logging.Field{Key: "power_dbfs", Value: d.PowerDB})
+	}
+	return step, nil
+}
+
+func (s *Scanner) clock() clock.Clock {
+	if s.cfg.Clock != nil {
+		return s.cfg.Clock
+	}
+	return clock.Real
+}
+
+// Retune moves the RX LO of an initialized tracker that is not running, for
+// example onto a signal found by a Scanner. The tone is expected ToneOffset
+// above the new LO.
+func (t *Tracker) Retune(ctx context.Context, rxLO float64) error {
+	if t.running.Load() {
+		return errors.New("retune: tracker is running")
+	}
+	accessor, ok := sdr.As[sdr.AttributeAccessor](t.sdr)
+	if !ok {
+		return errors.New("retune: backend cannot change the RX LO")
+	}
+	if err := sdr.ValidateAttribute(t.sdr.Capabilities(), sdr.AttrRxLO, rxLO); err != nil {
+		return fmt.Errorf("retune: %w", err)
+	}
+	if err := accessor.WriteAttribute(ctx, sdr.AttrRxLO, rxLO); err != nil {
+		return fmt.Errorf("retune: %w", err)
+	}
+	t.logger.Info("retuned",
+		logging.Field{Key: "subsystem", Value: "tracker"},
+		logging.Field{Key: "rx_lo_hz", Value: rxLO},
+		logging.Field{Key: "previous_hz", Value: t.cfg.RxLO})
+	t.cfg.RxLO = rxLO
+	return nil
+}
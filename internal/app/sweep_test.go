@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+type sweepRecorder struct {
+	recordingReporter
+	sweeps []telemetry.Sweep
+}
+
+func (r *sweepRecorder) ReportSweep(s telemetry.Sweep) { r.sweeps = append(r.sweeps, s) }
+
+func TestScannerSweep(t *testing.T) {
+	backend := sdr.NewMock()
+	logger := logging.New(logging.Info, logging.Text, io.Discard)
+	cfg := Config{SampleRate: 2e6, RxLO: 2.4e9, ToneOffset: 200e3, NumSamples: 1024, SpacingWavelength: 0.5, PhaseStep: 1, ScanStep: 2}
+	tracker := NewTracker(backend, &recordingReporter{}, logger, cfg)
+	ctx := context.Background()
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	reporter := &sweepRecorder{}
+	scanner := NewScanner(backend, reporter, logger, cfg)
+
+	tests := []struct {
+		name    string
+		sweep   FrequencySweep
+		wantErr bool
+		steps   int
+		found   bool
+	}{
+		{name: "around the emitter", sweep: FrequencySweep{StartHz: 2.39e9, StopHz: 2.41e9}, steps: 13, found: true},
+		{name: "empty band", sweep: FrequencySweep{StartHz: 2.2e9, StopHz: 2.21e9, StepHz: 2e6}, steps: 5},
+		{name: "step above sample rate", sweep: FrequencySweep{StartHz: 2.39e9, StopHz: 2.41e9, StepHz: 3e6}, wantErr: true},
+		{name: "reversed range", sweep: FrequencySweep{StartHz: 2.41e9, StopHz: 2.39e9}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter.sweeps = nil
+			got, err := scanner.Sweep(ctx, tt.sweep)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("invalid sweep accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Steps) != tt.steps || len(reporter.sweeps) != tt.steps || !got.Complete {
+				t.Fatalf("%d steps, %d reports (complete %t), want %d", len(got.Steps), len(reporter.sweeps), got.Complete, tt.steps)
+			}
+			if found := len(got.Detections) > 0; found != tt.found {
+				t.Fatalf("detections %+v", got.Detections)
+			}
+			if tt.found && (len(got.Detections) != 1 || math.Abs(got.Detections[0].FrequencyHz-2.4002e9) > 2e6/1024) {
+				t.Fatalf("detections %+v, want one at 2.4002 GHz", got.Detections)
+			}
+			attrs, _ := backend.ReadAttributes(ctx)
+			if attrs.RxLOHz != cfg.RxLO {
+				t.Fatalf("RX LO left at %.0f Hz", attrs.RxLOHz)
+			}
+		})
+	}
+}
+
+func TestTrackerRetune(t *testing.T) {
+	backend := sdr.NewMock()
+	tracker := NewTracker(backend, &recordingReporter{}, logging.New(logging.Info, logging.Text, io.Discard), Config{SampleRate: 2e6, RxLO: 2.4e9, ToneOffset: 200e3, NumSamples: 512})
+	ctx := context.Background()
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Retune(ctx, 2.45e9); err != nil {
+		t.Fatal(err)
+	}
+	if attrs, _ := backend.ReadAttributes(ctx); attrs.RxLOHz != 2.45e9 || tracker.cfg.RxLO != 2.45e9 {
+		t.Fatalf("backend at %.0f Hz, tracker at %.0f Hz", attrs.RxLOHz, tracker.cfg.RxLO)
+	}
+	if err := tracker.Retune(ctx, 10e9); err == nil {
+		t.Fatal("retune beyond the backend range accepted")
+	}
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+
+	"github.com/rjboer/GoSDR/internal/otlp"
+)
+
+// startIteration opens the span of a Run iteration and returns ctx carrying
+// it, so the backend's IIOD operations appear as its children.
+func (t *Tracker) startIteration(ctx context.Context, iteration int) context.Context {
+	t.iterCoarse = t.rescan != ""
+	ctx, t.iterSpan = t.cfg.Tracing.Start(ctx, "tracker.iteration",
+		otlp.Int("iteration", iteration),
+		otlp.Bool("coarse", t.iterCoarse))
+	return ctx
+}
+
+// endIteration closes the span of the iteration in progress, if any, with
+// the lock state it ended in and records its duration. Run calls it at the
+// top of every loop and on return, which covers the iterations that end
+// early with continue.
+func (t *Tracker) endIteration() {
+	if t.iterSpan == nil {
+		return
+	}
+	t.iterSpan.SetAttributes(otlp.String("lock_state", string(t.lockState)))
+	t.iterSpan.End()
+	t.cfg.Tracing.Histogram("gosdr.tracker.iteration.duration", "s", "Duration of tracker iterations.").
+		Record(t.iterSpan.Duration().Seconds(), otlp.Bool("coarse", t.iterCoarse), otlp.String("lock_state", string(t.lockState)))
+	t.iterSpan = nil
+}
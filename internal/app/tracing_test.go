@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/otlp"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestTrackerIterationSpans(t *testing.T) {
+	var mu sync.Mutex
+	var traces []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/v1/traces" {
+			mu.Lock()
+			traces = append(traces, string(body))
+			mu.Unlock()
+		}
+	}))
+	defer srv.Close()
+	exp, err := otlp.New(otlp.Config{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 512, SpacingWavelength: 0.5, PhaseStep: 1, ScanStep: 2, Unpaced: true, Tracing: exp}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker := NewTracker(sdr.NewMock(), &stoppingReporter{limit: 5, cancel: cancel}, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_ = tracker.Run(ctx)
+	if err := exp.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(traces) != 1 {
+		t.Fatalf("%d trace exports, want 1", len(traces))
+	}
+	var payload struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					Name       string `json:"name"`
+					Attributes []struct {
+						Key   string `json:"key"`
+						Value struct {
+							BoolValue   *bool   `json:"boolValue"`
+							StringValue *string `json:"stringValue"`
+						} `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.NewDecoder(strings.NewReader(traces[0])).Decode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	spans := payload.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) < 5 {
+		t.Fatalf("%d spans, want one per iteration", len(spans))
+	}
+	attrs := make(map[string]any)
+	for _, a := range spans[0].Attributes {
+		if a.Value.BoolValue != nil {
+			attrs[a.Key] = *a.Value.BoolValue
+		} else if a.Value.StringValue != nil {
+			attrs[a.Key] = *a.Value.StringValue
+		}
+	}
+	if spans[0].Name != "tracker.iteration" || attrs["coarse"] != true || attrs["lock_state"] == nil {
+		t.Fatalf("first span %s %v, want a coarse tracker.iteration with its lock state", spans[0].Name, attrs)
+	}
+}
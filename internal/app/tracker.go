@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sync/atomic"
 	"time"
 
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
 	"github.com/rjboer/GoSDR/internal/dsp"
 	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/otlp"
 	"github.com/rjboer/GoSDR/internal/sdr"
 	"github.com/rjboer/GoSDR/internal/telemetry"
 )
@@ -22,25 +27,121 @@ type Config struct {
 	ToneOffset        float64
 	NumSamples        int
 	SpacingWavelength float64
-	TrackingLength    int
-	PhaseStep         float64
-	PhaseCal          float64
-	ScanStep          float64
-	PhaseDelta        float64
-	WarmupBuffers     int
-	HistoryLimit      int
-	DebugMode         bool
-	TrackingMode      string
-	MaxTracks         int
-	TrackTimeout      time.Duration
-	MinSNRThreshold   float64
-	URI               string // SDR backend connection URI
-	SSHHost           string
-	SSHUser           string
-	SSHPassword       string
-	SSHKeyPath        string
-	SSHPort           int
-	SysfsRoot         string
+	// TrackingLength is the number of tracking iterations a coarse scan gets
+	// to converge on the target before the tracker scans again.
+	TrackingLength  int
+	PhaseStep       float64
+	PhaseCal        float64
+	ScanStep        float64
+	ScanStepMin     float64 // with ScanStepMax, adapts the scan step to SNR (see scanStep)
+	ScanStepMax     float64
+	PhaseDelta      float64
+	WarmupBuffers   int
+	HistoryLimit    int
+	DebugMode       bool
+	TrackingMode    string
+	MaxTracks       int
+	TrackTimeout    time.Duration
+	MinSNRThreshold float64
+	URI             string // SDR backend connection URI
+	SSHHost         string
+	SSHUser         string
+	SSHPassword     string
+	SSHKeyPath      string
+	SSHPort         int
+	SysfsRoot       string
+	LOSource        string // LO sharing source for backends that support it (USRP)
+	LOExport        bool
+	ClockSource     string  // reference clock source, validated against capabilities
+	TimeSource      string  // PPS/time source, validated against capabilities
+	RefClockHz      float64 // external reference frequency; 0 = backend nominal
+	FreqCorrection  string  // off|report|xo|digital tone frequency correction
+	CFOTracking     bool    // continuously remove residual CFO before monopulse
+	AutoGainBackoff bool    // step RX gain down while the ADC clips
+	// OccupancyBands splits the capture bandwidth into this many sub-bands
+	// for spectrum occupancy statistics; zero disables them.
+	OccupancyBands       int
+	OccupancyThresholdDB float64 // band power above the noise floor that counts as occupied
+	// BurstMode processes only buffers inside energy bursts and feeds the
+	// track manager one averaged detection per burst, for intermittent
+	// emitters. BurstThresholdDB is the burst power above the noise floor.
+	BurstMode        bool
+	BurstThresholdDB float64
+	// SquelchSNR withholds measurements below this SNR (dB) from the
+	// reporter; zero disables the squelch. MinDwell is how long a new angle
+	// must persist before it is reported.
+	SquelchSNR float64
+	MinDwell   time.Duration
+	// SteeringDeadbandDeg and SteeringPersist condition the steering angle
+	// published next to the raw one: it only moves when the measurement
+	// leaves the deadband for SteeringPersist consecutive reports. Both zero
+	// disables the steering output.
+	SteeringDeadbandDeg float64
+	SteeringPersist     int
+	// PolarityCheck measures a reference emitter at PolarityRefDeg during
+	// start-up calibration and corrects swapped or inverted channels.
+	PolarityCheck  bool
+	PolarityRefDeg float64
+	// ElementPattern, when set, corrects reported peak and SNR for the
+	// element gain at the measured angle so targets at different angles
+	// compare fairly. Tracking itself uses the uncorrected values.
+	ElementPattern *ElementPattern
+	// Polarization selects how dual-polarized elements are read; see
+	// PolarizationModes. Empty reads one polarization through SDR.RX.
+	// MockPolarizationDeg tilts the mock emitter's polarization.
+	Polarization        string
+	MockPolarizationDeg float64
+	// Reconnect recovers a dropped IIOD connection instead of ending Run.
+	Reconnect sdr.ReconnectPolicy
+	// XMLCacheDir keeps the IIOD context XML of network backends between
+	// runs and reconnects; empty fetches it on every connection.
+	XMLCacheDir string
+	// WarmStart, when set, makes the first coarse scan search around the
+	// steering a previous run locked on before scanning fully.
+	WarmStart *WarmStart
+	// FFTWindow is the window applied before every spectrum FFT; the zero
+	// value is Hamming.
+	FFTWindow dsp.Window
+	// FastTrack computes only the few bins around the tone with Goertzel
+	// filters while locked, instead of full FFTs; see dsp.MonopulseTrackBins.
+	FastTrack bool
+	// TrackGateDeg, ConfirmHits, ConfirmWindow and MaxMisses override the
+	// multi-target association gate and lifecycle thresholds; zero keeps
+	// DefaultTrackPolicy. TrackScorer names a registered scorer (see
+	// RegisterScorer; default "weighted") and ScoreWeights tunes it.
+	// Association names the data association strategy (see
+	// AssociationNames; default "nearest").
+	TrackGateDeg  float64
+	ConfirmHits   int
+	ConfirmWindow int
+	MaxMisses     int
+	TrackScorer   string
+	ScoreWeights  ScoreWeights
+	Association   string
+	// ConvergenceStdDeg and ConvergeIterations define convergence: the angle
+	// standard deviation over the last ConvergeIterations tracking
+	// iterations is below ConvergenceStdDeg. Zero selects 0.5° and 10. A
+	// converged track that loses lock triggers a new coarse scan.
+	ConvergenceStdDeg  float64
+	ConvergeIterations int
+	// AcquireWindow, when positive, makes acquisition keep coarse scanning
+	// while searching and only start tracking once a mode holding
+	// AcquireMass (default 0.6) of the last AcquireWindow estimates has
+	// emerged in their angle histogram, so a single noise spike cannot
+	// capture the tracker.
+	AcquireWindow int
+	AcquireMass   float64
+	// Unpaced processes buffers back to back instead of polling every 10 ms,
+	// for replaying recordings faster than real time.
+	Unpaced bool
+	// Clock provides the time used for track ageing, report timestamps and
+	// the 10 ms poll; nil means the system clock. Replays pass the recording
+	// time so tracks expire as they did live, tests a clock.Fake.
+	Clock clock.Clock
+	// Tracing receives a span per iteration, with the IIOD operations of
+	// the backend below it, and the iteration duration histogram; nil
+	// disables tracing.
+	Tracing *otlp.Exporter
 }
 
 // TrackLifecycle represents the lifecycle of a track.
@@ -98,30 +199,52 @@ type TrackManager struct {
 	confirmHits   int
 	confirmWindow int
 	maxMisses     int
+	score         ScoreFunc
+	associate     Associator
 }
 
-// NewTrackManager creates a track manager with lifecycle controls.
+// NewTrackManager creates a track manager with lifecycle controls and the
+// default track policy.
 func NewTrackManager(maxTracks int, timeout time.Duration, minSNR float64, historyLimit int) *TrackManager {
 	if maxTracks <= 0 {
 		maxTracks = 1
 	}
-	return &TrackManager{
-		tracks:        make(map[int]*Track),
-		nextID:        1,
-		maxTracks:     maxTracks,
-		timeout:       timeout,
-		minSNR:        minSNR,
-		historyLimit:  historyLimit,
-		gate:          5.0,
-		confirmHits:   3,
-		confirmWindow: 5,
-		maxMisses:     3,
+	tm := &TrackManager{
+		tracks:       make(map[int]*Track),
+		nextID:       1,
+		maxTracks:    maxTracks,
+		timeout:      timeout,
+		minSNR:       minSNR,
+		historyLimit: historyLimit,
 	}
+	tm.SetPolicy(DefaultTrackPolicy())
+	return tm
+}
+
+// SetPolicy replaces the association gate and strategy, lifecycle
+// thresholds and scorer. A nil Score keeps the default weighted score, a nil
+// Associate nearest-neighbour association. Existing tracks are rescored on
+// their next update.
+func (tm *TrackManager) SetPolicy(p TrackPolicy) {
+	if p.Score == nil {
+		p.Score = WeightedScore(DefaultScoreWeights())
+	}
+	if p.Associate == nil {
+		p.Associate = NearestAssociation
+	}
+	tm.gate = p.GateDeg
+	tm.confirmHits = p.ConfirmHits
+	tm.confirmWindow = p.ConfirmWindow
+	tm.maxMisses = p.MaxMisses
+	tm.score = p.Score
+	tm.associate = p.Associate
 }
 
 // Update ingests a batch of detections, updates matching tracks, creates new
 // ones when capacity allows, and prunes tracks based on timeouts and score.
-// Returns the current list of tracks ordered by creation time.
+// Detections carrying the ID of a live track update it directly; the others
+// go through the association strategy. Returns the current list of tracks
+// ordered by creation time.
 func (tm *TrackManager) Update(detections []Detection, now time.Time) []Track {
 	if tm == nil {
 		return nil
@@ -130,28 +253,19 @@ func (tm *TrackManager) Update(detections []Detection, now time.Time) []Track {
 	tm.expire(now)
 
 	matched := make(map[int]bool, len(detections))
+	free := make([]Detection, 0, len(detections))
 	for _, det := range detections {
 		if det.SNR < tm.minSNR {
 			continue
 		}
-
-		track := tm.findMatch(det.Angle)
-		if det.ID > 0 {
-			if byID, ok := tm.tracks[det.ID]; ok {
-				track = byID
-			}
-		}
-
-		if track == nil {
-			if len(tm.tracks) >= tm.maxTracks {
-				tm.pruneExcess()
-			}
-			track = tm.newTrack(det.Angle, det.PhaseDelay, det.Peak, det.SNR, det.Confidence, det.LockState, now)
-		} else {
+		if track, ok := tm.tracks[det.ID]; ok && det.ID > 0 {
 			tm.updateTrack(track, det.Angle, det.PhaseDelay, det.Peak, det.SNR, det.Confidence, det.LockState, now)
+			matched[track.ID] = true
+			continue
 		}
-		matched[track.ID] = true
+		free = append(free, det)
 	}
+	tm.associateFree(free, matched, now)
 
 	tm.markUnmatched(matched, now)
 	tm.expire(now)
@@ -254,7 +368,6 @@ func (tm *TrackManager) newTrack(angle, phaseDelay, peak, snr, confidence float6
 		Peak:             peak,
 		SNR:              snr,
 		Confidence:       confidence,
-		Score:            tm.scoreTrack(snr, confidence, 0),
 		LockState:        lock,
 		State:            TrackTentative,
 		CreatedAt:        now,
@@ -265,6 +378,7 @@ func (tm *TrackManager) newTrack(angle, phaseDelay, peak, snr, confidence float6
 		ConsecutiveHits:  1,
 		TotalDetections:  1,
 	}
+	track.Score = tm.score(*track)
 	tm.tracks[id] = track
 	tm.order = append(tm.order, id)
 	tm.updateLifecycle(track)
@@ -288,6 +402,61 @@ func (tm *TrackManager) updateTrack(track *Track, angle, phaseDelay, peak, snr,
 	tm.updateLifecycle(track)
 }
 
+// associateFree runs the association strategy over the live tracks not yet
+// updated and starts tracks for the detections left over. A leftover within
+// the gate of a track started in the same update refreshes that track rather
+// than duplicating it.
+func (tm *TrackManager) associateFree(detections []Detection, matched map[int]bool, now time.Time) {
+	if len(detections) == 0 {
+		return
+	}
+	live := make([]Track, 0, len(tm.tracks))
+	for _, id := range tm.order {
+		if track, ok := tm.tracks[id]; ok && track.State != TrackLost && !matched[id] {
+			live = append(live, *track)
+		}
+	}
+	updates, unassigned := tm.associate(live, detections, tm.gate)
+	for _, u := range updates {
+		track, ok := tm.tracks[live[u.Track].ID]
+		if !ok {
+			continue
+		}
+		det := u.Detection
+		tm.updateTrack(track, det.Angle, det.PhaseDelay, det.Peak, det.SNR, det.Confidence, det.LockState, now)
+		matched[track.ID] = true
+	}
+	var born []*Track
+	for _, j := range unassigned {
+		det := detections[j]
+		if track := nearestWithin(born, det.Angle, tm.gate); track != nil {
+			tm.updateTrack(track, det.Angle, det.PhaseDelay, det.Peak, det.SNR, det.Confidence, det.LockState, now)
+			continue
+		}
+		if len(tm.tracks) >= tm.maxTracks {
+			tm.pruneExcess()
+		}
+		track := tm.newTrack(det.Angle, det.PhaseDelay, det.Peak, det.SNR, det.Confidence, det.LockState, now)
+		born = append(born, track)
+		matched[track.ID] = true
+	}
+}
+
+// nearestWithin returns the track in tracks closest to angle within gate, or
+// nil.
+func nearestWithin(tracks []*Track, angle, gate float64) *Track {
+	var (
+		best      *Track
+		bestDelta = math.MaxFloat64
+	)
+	for _, track := range tracks {
+		if delta := math.Abs(track.Angle - angle); delta < bestDelta && delta <= gate {
+			best, bestDelta = track, delta
+		}
+	}
+	return best
+}
+
 func (tm *TrackManager) findMatch(angle float64) *Track {
 	var (
 		best      *Track
@@ -372,7 +541,7 @@ func (tm *TrackManager) recordDetection(track *Track, hit bool) {
 		track.Misses++
 	}
 
-	track.Score = tm.scoreTrack(track.SNR, track.Confidence, track.ConsecutiveMisses)
+	track.Score = tm.score(*track)
 }
 
 func (tm *TrackManager) updateLifecycle(track *Track) {
@@ -402,13 +571,6 @@ func (tm *TrackManager) removeTrack(id int) {
 	}
 }
 
-func (tm *TrackManager) scoreTrack(snr, confidence float64, misses int) float64 {
-	snrScore := clamp(snr/30.0, 0, 1)
-	confScore := clamp(confidence, 0, 1)
-	missPenalty := clamp(1-0.2*float64(misses), 0, 1)
-	return 0.6*snrScore + 0.3*confScore + 0.1*missPenalty
-}
-
 func (tm *TrackManager) pruneExcess() {
 	for len(tm.tracks) > tm.maxTracks {
 		var (
@@ -441,27 +603,72 @@ type Tracker struct {
 	startBin  int
 	endBin    int
 	lastDelay float64
+	peakBin   int // spectrum bin of the last measured peak, for FastTrack
 	history   []float64
 	dsp       *dsp.CachedDSP // Cached DSP resources for performance
 	lockState telemetry.LockState
 	stableCnt int
 	dropCnt   int
 	manager   *TrackManager
+	policy    TrackPolicy
 	mode      string
+
+	// Frequency offset calibration state (see freqcal.go).
+	freqPPM        float64
+	freqResidualHz float64
+	freqShiftHz    float64
+	mixPhase       float64
+	cfo            *dsp.CFOTracker
+
+	overload *overloadMonitor // nil when the backend reports no full scale
+	channels channelStats     // debug-mode channel power and phase difference
+
+	occupancy   *dsp.OccupancyMonitor // nil unless OccupancyBands is set
+	occupancyAt time.Time             // time of the last occupancy report
+
+	burst *burstState // nil unless BurstMode is set
+	gate  *reportGate // nil when neither squelch nor dwell is configured
+
+	steering *steeringConditioner // nil unless a steering deadband or persistence is set
+
+	dualPol    sdr.DualPolRX // nil unless a polarization mode is set
+	polRatioDB float64       // H/V power ratio of the last buffer
+	polValid   bool
+
+	lastSNR  float64 // SNR of the latest measurement, for the adaptive scan step
+	snrValid bool
+
+	conv   *convergenceDetector
+	rescan string                // reason for the coarse scan due next iteration, or empty
+	acq    *acquisitionHistogram // nil unless AcquireWindow is set
+	warm   *WarmStart            // seeds the next coarse scan; cleared by it
+
+	lastLock atomic.Pointer[calibration.LastLock] // see LastLock
+
+	paused atomic.Bool // set by SetPaused, e.g. outside scheduled windows
+
+	running atomic.Bool          // Run is processing buffers
+	calReq  chan phaseCalRequest // phase calibrations for the Run loop
+
+	iterSpan   *otlp.Span // span of the iteration in progress
+	iterCoarse bool       // the iteration in progress runs a coarse scan
 }
 
 func NewTracker(backend sdr.SDR, reporter telemetry.Reporter, logger logging.Logger, cfg Config) *Tracker {
 	if logger == nil {
 		logger = logging.Default()
 	}
-	return &Tracker{
+	t := &Tracker{
 		sdr:       backend,
 		reporter:  reporter,
 		logger:    logger,
 		cfg:       cfg,
 		dsp:       dsp.NewCachedDSP(cfg.NumSamples),
 		lockState: telemetry.LockStateSearching,
+		calReq:    make(chan phaseCalRequest),
 	}
+	t.dsp.SetWindow(cfg.FFTWindow)
+	return t
 }
 
 // Init configures the SDR and precomputes FFT bin indices.
@@ -499,71 +706,190 @@ func (t *Tracker) Init(ctx context.Context) error {
 		t.cfg.MinSNRThreshold = 3
 	}
 
+	policy, err := t.cfg.trackPolicy()
+	if err != nil {
+		return fmt.Errorf("init tracker: %w", err)
+	}
+	t.policy = policy
 	t.applyTrackingMode(t.cfg.TrackingMode)
+	caps := t.sdr.Capabilities()
+	t.applyCapabilities(caps)
+	syncCfg := sdr.Config{ClockSource: t.cfg.ClockSource, TimeSource: t.cfg.TimeSource, RefClockHz: t.cfg.RefClockHz}
+	if err := caps.ValidateSync(syncCfg); err != nil {
+		return fmt.Errorf("init SDR: %w", err)
+	}
+	if err := t.initPolarization(caps); err != nil {
+		return fmt.Errorf("init tracker: %w", err)
+	}
+	t.channels = channelStats{fullScale: caps.FullScale}
+	if caps.FullScale > 0 {
+		t.overload = newOverloadMonitor(caps.FullScale, t.cfg.AutoGainBackoff, t.cfg.RxGain0, t.cfg.RxGain1)
+	}
+	if t.cfg.CFOTracking {
+		t.cfo = dsp.NewCFOTracker(t.cfg.SampleRate, t.cfg.ToneOffset, 0, 0)
+	}
+	if t.cfg.BurstMode {
+		t.burst = newBurstState(t.cfg.BurstThresholdDB)
+	}
+	if t.cfg.SquelchSNR != 0 || t.cfg.MinDwell > 0 {
+		t.gate = &reportGate{squelchSNR: t.cfg.SquelchSNR, minDwell: t.cfg.MinDwell}
+	}
+	if t.cfg.SteeringDeadbandDeg > 0 || t.cfg.SteeringPersist > 0 {
+		t.steering = newSteeringConditioner(t.cfg.SteeringDeadbandDeg, t.cfg.SteeringPersist)
+	}
+	if t.cfg.OccupancyBands > 0 {
+		t.occupancy = dsp.NewOccupancyMonitor(t.cfg.OccupancyBands, t.cfg.SampleRate, t.cfg.OccupancyThresholdDB)
+	}
 
 	// Update cached DSP size if needed
 	t.dsp.UpdateSize(t.cfg.NumSamples)
+	var xmlCache *connectionmgr.XMLCache
+	if t.cfg.XMLCacheDir != "" {
+		xmlCache = &connectionmgr.XMLCache{Dir: t.cfg.XMLCacheDir}
+	}
 	if err := t.sdr.Init(ctx, sdr.Config{
-		URI:         t.cfg.URI,
-		SampleRate:  t.cfg.SampleRate,
-		RxLO:        t.cfg.RxLO,
-		RxGain0:     t.cfg.RxGain0,
-		RxGain1:     t.cfg.RxGain1,
-		TxGain:      t.cfg.TxGain,
-		ToneOffset:  t.cfg.ToneOffset,
-		NumSamples:  t.cfg.NumSamples,
-		PhaseDelta:  t.cfg.PhaseDelta,
-		SSHHost:     t.cfg.SSHHost,
-		SSHUser:     t.cfg.SSHUser,
-		SSHPassword: t.cfg.SSHPassword,
-		SSHKeyPath:  t.cfg.SSHKeyPath,
-		SSHPort:     t.cfg.SSHPort,
-		SysfsRoot:   t.cfg.SysfsRoot,
+		URI:                 t.cfg.URI,
+		SampleRate:          t.cfg.SampleRate,
+		RxLO:                t.cfg.RxLO,
+		RxGain0:             t.cfg.RxGain0,
+		RxGain1:             t.cfg.RxGain1,
+		TxGain:              t.cfg.TxGain,
+		ToneOffset:          t.cfg.ToneOffset,
+		NumSamples:          t.cfg.NumSamples,
+		PhaseDelta:          t.cfg.PhaseDelta,
+		SSHHost:             t.cfg.SSHHost,
+		SSHUser:             t.cfg.SSHUser,
+		SSHPassword:         t.cfg.SSHPassword,
+		SSHKeyPath:          t.cfg.SSHKeyPath,
+		SSHPort:             t.cfg.SSHPort,
+		SysfsRoot:           t.cfg.SysfsRoot,
+		LOSource:            t.cfg.LOSource,
+		LOExport:            t.cfg.LOExport,
+		ClockSource:         t.cfg.ClockSource,
+		TimeSource:          t.cfg.TimeSource,
+		RefClockHz:          t.cfg.RefClockHz,
+		MockPolarizationDeg: t.cfg.MockPolarizationDeg,
+		Reconnect:           t.cfg.Reconnect,
+		XMLCache:            xmlCache,
+		XMLProgress: func(p connectionmgr.XMLProgress) {
+			t.logger.Debug("context XML", logging.Field{Key: "received", Value: p.Received}, logging.Field{Key: "total", Value: p.Total}, logging.Field{Key: "cached", Value: p.Cached})
+		},
 	}); err != nil {
 		return fmt.Errorf("init SDR: %w", err)
 	}
 	return nil
 }
 
+// applyCapabilities clamps configured gains to what the backend supports so an
+// out-of-range value does not abort hardware initialization.
+func (t *Tracker) applyCapabilities(caps sdr.Capabilities) {
+	gains := []struct {
+		name string
+		val  *int
+		fn   func(int) int
+	}{
+		{"rx_gain0", &t.cfg.RxGain0, caps.ClampRxGain},
+		{"rx_gain1", &t.cfg.RxGain1, caps.ClampRxGain},
+		{"tx_gain", &t.cfg.TxGain, caps.ClampTxGain},
+	}
+	for _, g := range gains {
+		clamped := g.fn(*g.val)
+		if clamped != *g.val {
+			t.logger.Warn("gain outside backend range, clamping",
+				logging.Field{Key: "subsystem", Value: "tracker"},
+				logging.Field{Key: "backend", Value: caps.Backend},
+				logging.Field{Key: "setting", Value: g.name},
+				logging.Field{Key: "requested", Value: *g.val},
+				logging.Field{Key: "applied", Value: clamped})
+			*g.val = clamped
+		}
+	}
+}
+
 // Run executes a coarse scan and then a monopulse tracking loop.
 // Runs continuously until context is canceled.
 func (t *Tracker) Run(ctx context.Context) error {
 	if t.cfg.TrackingLength == 0 {
 		t.cfg.TrackingLength = 50
 	}
+	t.conv = newConvergenceDetector(t.cfg.ConvergenceStdDeg, t.cfg.ConvergeIterations, t.cfg.TrackingLength)
+	t.acq = newAcquisitionHistogram(t.cfg.AcquireWindow, t.cfg.AcquireMass)
+	t.rescan = scanStartup
+	t.warm = t.cfg.WarmStart
 	if err := t.warmup(ctx); err != nil {
 		return fmt.Errorf("warmup: %w", err)
 	}
+	if err := t.calibrateFrequency(ctx); err != nil {
+		return fmt.Errorf("frequency calibration: %w", err)
+	}
+	if err := t.checkPolarity(ctx); err != nil {
+		return err
+	}
 	multiMode := t.mode == "multi"
-	ticker := time.NewTicker(10 * time.Millisecond)
-	defer ticker.Stop()
+	t.running.Store(true)
+	defer t.running.Store(false)
+	defer t.endIteration()
+	var tick <-chan time.Time
+	if t.cfg.Unpaced {
+		ready := make(chan time.Time)
+		close(ready)
+		tick = ready
+	} else {
+		ticker := t.clock().NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
 
 	// Run continuously
 	iteration := 0
 	for {
+		t.endIteration()
 		// Check for cancellation
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-ticker.C:
+		case <-tick:
 			// Continue to next iteration
+		case req := <-t.calReq:
+			cal, err := t.calibratePhase(req.ctx, req.opts)
+			req.reply <- phaseCalReply{cal: cal, err: err}
+			continue
+		}
+		if t.paused.Load() {
+			continue
 		}
 
-		iterationStart := time.Now()
-		rx0, rx1, err := t.sdr.RX(ctx)
+		iterationStart := t.now()
+		ictx := t.startIteration(ctx, iteration)
+		rx0, rx1, err := t.receive(ictx)
 		if err != nil {
+			t.iterSpan.RecordError(err)
 			return fmt.Errorf("receive samples: %w", err)
 		}
 		if len(rx0) == 0 || len(rx1) == 0 {
 			t.logger.Warn("received empty buffer", logging.Field{Key: "subsystem", Value: "tracker"})
 			continue
 		}
+		t.checkOverload(ictx, rx0, rx1)
+		t.observeSpectrum(rx0)
+		if !t.gateBurst(rx0, rx1) {
+			continue
+		}
+		t.applyFrequencyShift(rx0, rx1)
+		if t.cfo != nil {
+			t.cfo.Process(rx0, rx1)
+		}
 
-		// First iteration: coarse scan
-		if iteration == 0 {
-			coarseStart := time.Now()
-			// Use parallel coarse scan with cached DSP
-			coarsePeaks := dsp.CoarseScanParallel(rx0, rx1, t.cfg.PhaseCal, t.startBin, t.endBin, t.cfg.ScanStep, t.cfg.RxLO, t.cfg.SpacingWavelength, t.dsp)
+		// Coarse scan at startup and whenever convergence detection asks
+		// for re-acquisition.
+		if t.rescan != "" {
+			coarseStart := t.now()
+			// Use parallel coarse scan with cached DSP, around the warm
+			// start steering first when there is one
+			scanStep := t.scanStep()
+			coarsePeaks := t.coarseScan(rx0, rx1, scanStep)
+			t.logger.Debug("coarse scan", logging.Field{Key: "reason", Value: t.rescan}, logging.Field{Key: "step_deg", Value: scanStep})
+			t.startAcquisition(t.rescan)
 			if len(coarsePeaks) == 0 {
 				t.logger.Warn("coarse scan produced no peaks", logging.Field{Key: "subsystem", Value: "tracker"})
 				iteration++
@@ -577,16 +903,21 @@ func (t *Tracker) Run(ctx context.Context) error {
 			monoPhase := primary.MonoPhase
 			peakBin := primary.Bin
 			snr := primary.SNR
-			coarseDuration := time.Since(coarseStart)
-			t.lastDelay = delay
-			t.appendHistory(theta)
+			t.observeSNR(snr)
+			coarseDuration := t.now().Sub(coarseStart)
 
 			confidence := t.trackingConfidence(snr, monoPhase)
+			prevState := t.lockState
 			state := t.updateLockState(snr, confidence)
+			theta, delay, state = t.acquire(theta, delay, prevState, state)
 			t.lockState = state
+			t.lastDelay = delay
+			t.peakBin = peakBin
+			t.appendHistory(theta)
+			t.noteLock(theta, state)
 
 			if multiMode && t.manager != nil {
-				now := time.Now()
+				now := t.now()
 				detections := make([]Detection, 0, min(len(coarsePeaks), t.cfg.MaxTracks))
 				for i, pk := range coarsePeaks {
 					if i >= t.cfg.MaxTracks {
@@ -602,7 +933,7 @@ func (t *Tracker) Run(ctx context.Context) error {
 						LockState:  state,
 					})
 				}
-				t.manager.Update(detections, now)
+				t.updateManager(detections, now)
 			}
 
 			var debug *telemetry.DebugInfo
@@ -615,21 +946,25 @@ func (t *Tracker) Run(ctx context.Context) error {
 						Bin:   peakBin,
 						Band:  [2]int{t.startBin, t.endBin},
 					},
+					FreqOffsetHz:    t.residualOffset(),
+					FreqErrorPPM:    t.freqPPM,
+					CFOCorrectionHz: t.cfoCorrection(),
+					ScanStepDeg:     scanStep,
 				}
+				t.annotateChannels(debug, rx0, rx1)
 			}
 
-			if t.reporter != nil {
-				t.reporter.Report(theta, peak, snr, confidence, state, debug)
-			}
+			debug = t.annotateOverload(debug)
+			t.report(theta, peak, snr, confidence, state, debug)
 			t.logger.Debug("coarse scan iteration", logging.Field{Key: "iteration", Value: iteration}, logging.Field{Key: "duration_ms", Value: coarseDuration.Seconds() * 1000})
 			iteration++
-			t.logger.Debug("iteration complete", logging.Field{Key: "iteration", Value: iteration}, logging.Field{Key: "elapsed_ms", Value: time.Since(iterationStart).Seconds() * 1000})
+			t.logger.Debug("iteration complete", logging.Field{Key: "iteration", Value: iteration}, logging.Field{Key: "elapsed_ms", Value: t.now().Sub(iterationStart).Seconds() * 1000})
 			continue
 		}
 
 		// Subsequent iterations: monopulse tracking
 		// Use shared FFTs with cached DSP
-		trackStart := time.Now()
+		trackStart := t.now()
 		trackIDs, trackDelays := t.manager.PhaseDelays()
 		if !multiMode || t.manager == nil {
 			trackDelays = []float64{t.lastDelay}
@@ -648,8 +983,13 @@ func (t *Tracker) Run(ctx context.Context) error {
 			targets = append(targets, dsp.TrackTarget{ID: id, Delay: delay})
 		}
 
-		measurements := dsp.MonopulseTrackParallel(targets, rx0, rx1, t.cfg.PhaseCal, t.startBin, t.endBin, t.cfg.PhaseStep, t.dsp)
-		trackDuration := time.Since(trackStart)
+		var measurements []dsp.TrackMeasurement
+		if t.useFastTrack(iteration) {
+			measurements = dsp.MonopulseTrackBins(targets, rx0, rx1, t.cfg.PhaseCal, t.peakBin, fastTrackHalfWidth, t.cfg.PhaseStep, t.dsp)
+		} else {
+			measurements = dsp.MonopulseTrackParallel(targets, rx0, rx1, t.cfg.PhaseCal, t.startBin, t.endBin, t.cfg.PhaseStep, t.dsp)
+		}
+		trackDuration := t.now().Sub(trackStart)
 		if len(measurements) == 0 {
 			t.logger.Warn("tracking produced no measurements", logging.Field{Key: "subsystem", Value: "tracker"})
 			iteration++
@@ -664,14 +1004,17 @@ func (t *Tracker) Run(ctx context.Context) error {
 		}
 
 		best := measurements[bestIdx]
+		t.observeSNR(best.SNR)
 		theta := dsp.PhaseToTheta(best.Delay, t.cfg.RxLO, t.cfg.SpacingWavelength)
 		confidence := t.trackingConfidence(best.SNR, best.MonoPhase)
 		state := t.updateLockState(best.SNR, confidence)
 		t.lockState = state
 		t.lastDelay = best.Delay
+		t.peakBin = best.PeakBin
 		t.appendHistory(theta)
+		t.noteLock(theta, state)
 
-		now := time.Now()
+		now := t.now()
 		if multiMode && t.manager != nil {
 			detections := make([]Detection, 0, len(measurements))
 			for i, m := range measurements {
@@ -691,7 +1034,7 @@ func (t *Tracker) Run(ctx context.Context) error {
 					LockState:  state,
 				})
 			}
-			t.manager.Update(detections, now)
+			t.updateManager(detections, now)
 		}
 
 		var debug *telemetry.DebugInfo
@@ -704,18 +1047,65 @@ func (t *Tracker) Run(ctx context.Context) error {
 					Bin:   best.PeakBin,
 					Band:  [2]int{t.startBin, t.endBin},
 				},
+				FreqOffsetHz:    t.residualOffset(),
+				FreqErrorPPM:    t.freqPPM,
+				CFOCorrectionHz: t.cfoCorrection(),
 			}
+			t.annotateChannels(debug, rx0, rx1)
 		}
 
-		if t.reporter != nil {
-			t.reporter.Report(theta, best.Peak, best.SNR, confidence, state, debug)
-		}
+		debug = t.annotateOverload(debug)
+		t.report(theta, best.Peak, best.SNR, confidence, state, debug)
+		t.observeConvergence(theta, state)
 		t.logger.Debug("tracking iteration", logging.Field{Key: "iteration", Value: iteration}, logging.Field{Key: "duration_ms", Value: trackDuration.Seconds() * 1000})
 		iteration++
-		t.logger.Debug("iteration complete", logging.Field{Key: "iteration", Value: iteration}, logging.Field{Key: "elapsed_ms", Value: time.Since(iterationStart).Seconds() * 1000})
+		t.logger.Debug("iteration complete", logging.Field{Key: "iteration", Value: iteration}, logging.Field{Key: "elapsed_ms", Value: t.now().Sub(iterationStart).Seconds() * 1000})
 	}
 }
 
+const (
+	// fastTrackHalfWidth is the number of bins computed on each side of the
+	// peak bin by the FastTrack path.
+	fastTrackHalfWidth = 2
+	// fastTrackRefresh makes every n-th FastTrack iteration use full FFTs,
+	// so the band-wide SNR and peak search are revalidated.
+	fastTrackRefresh = 32
+)
+
+// useFastTrack reports whether this tracking iteration may take the Goertzel
+// path: FastTrack is on, the tracker is locked and the last peak lies inside
+// the signal band.
+func (t *Tracker) useFastTrack(iteration int) bool {
+	return t.cfg.FastTrack &&
+		t.lockState == telemetry.LockStateLocked &&
+		iteration%fastTrackRefresh != 0 &&
+		t.peakBin >= t.startBin && t.peakBin < t.endBin
+}
+
+// SetPaused stops (true) or resumes (false) processing. A paused tracker
+// reads no samples and reports nothing; tracks age out while it is paused.
+func (t *Tracker) SetPaused(paused bool) {
+	t.paused.Store(paused)
+}
+
+// Paused reports whether processing is paused by SetPaused.
+func (t *Tracker) Paused() bool {
+	return t.paused.Load()
+}
+
+// clock returns the configured clock or the system clock.
+func (t *Tracker) clock() clock.Clock {
+	if t.cfg.Clock != nil {
+		return t.cfg.Clock
+	}
+	return clock.Real
+}
+
+// now returns the tracker's notion of the current time.
+func (t *Tracker) now() time.Time {
+	return t.clock().Now()
+}
+
 func (t *Tracker) trackingConfidence(snr float64, monoPhase float64) float64 {
 	snrScore := clamp((snr)/30.0, 0, 1)
 	monoScore := clamp(1-math.Min(math.Abs(monoPhase)/(10*(math.Pi/180)), 1), 0, 1)
@@ -795,6 +1185,9 @@ func (t *Tracker) applyTrackingMode(mode string) {
 
 	if mode == "multi" {
 		t.manager = NewTrackManager(t.cfg.MaxTracks, t.cfg.TrackTimeout, t.cfg.MinSNRThreshold, t.cfg.HistoryLimit)
+		if t.policy.Score != nil {
+			t.manager.SetPolicy(t.policy)
+		}
 	} else {
 		t.manager = nil
 	}
@@ -859,11 +1252,11 @@ func (t *Tracker) warmup(ctx context.Context) error {
 			return ctx.Err()
 		default:
 		}
-		warmupStart := time.Now()
+		warmupStart := t.now()
 		if _, _, err := t.sdr.RX(ctx); err != nil {
 			return fmt.Errorf("warmup RX buffer %d: %w", i, err)
 		}
-		t.logger.Debug("warmup buffer processed", logging.Field{Key: "index", Value: i}, logging.Field{Key: "duration_ms", Value: time.Since(warmupStart).Seconds() * 1000})
+		t.logger.Debug("warmup buffer processed", logging.Field{Key: "index", Value: i}, logging.Field{Key: "duration_ms", Value: t.now().Sub(warmupStart).Seconds() * 1000})
 	}
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rjboer/GoSDR/internal/clock"
 	"github.com/rjboer/GoSDR/internal/logging"
 	"github.com/rjboer/GoSDR/internal/sdr"
 	"github.com/rjboer/GoSDR/internal/telemetry"
@@ -27,6 +28,22 @@ func (r *recordingReporter) ReportMultiTrack(sample telemetry.MultiTrackSample)
 	}
 }
 
+// stoppingReporter cancels the run after limit reports, so a test drives the
+// tracker for a fixed number of iterations rather than until a wall-clock
+// deadline.
+type stoppingReporter struct {
+	recordingReporter
+	limit  int
+	cancel context.CancelFunc
+}
+
+func (r *stoppingReporter) Report(angleDeg float64, peak float64, snr float64, confidence float64, state telemetry.LockState, debug *telemetry.DebugInfo) {
+	r.recordingReporter.Report(angleDeg, peak, snr, confidence, state, debug)
+	if len(r.angles) == r.limit {
+		r.cancel()
+	}
+}
+
 func TestTrackerConvergesWithMock(t *testing.T) {
 	rand.Seed(3)
 	backend := sdr.NewMock()
@@ -74,3 +91,141 @@ func TestTrackerConvergesWithMock(t *testing.T) {
 		t.Fatalf("expected at least 10 history entries got %d", got)
 	}
 }
+
+func TestTrackerConvergesWithFastTrack(t *testing.T) {
+	rand.Seed(7)
+	backend := sdr.NewMock()
+	reporter := &recordingReporter{}
+	cfg := Config{
+		SampleRate:        2e6,
+		RxLO:              2.3e9,
+		ToneOffset:        200e3,
+		NumSamples:        512,
+		SpacingWavelength: 0.5,
+		PhaseStep:         1,
+		ScanStep:          2,
+		PhaseDelta:        25,
+		HistoryLimit:      20,
+		FastTrack:         true,
+	}
+	tracker := NewTracker(backend, reporter, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := tracker.Run(ctx); err != nil && err != context.DeadlineExceeded {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if tracker.lockState != telemetry.LockStateLocked {
+		t.Fatalf("lock state = %s, want locked so the fast path ran", tracker.lockState)
+	}
+	if !tracker.useFastTrack(1) {
+		t.Fatalf("fast path not eligible: peak bin %d outside band [%d,%d)", tracker.peakBin, tracker.startBin, tracker.endBin)
+	}
+	if math.Abs(tracker.LastDelay()+cfg.PhaseDelta) > 5 {
+		t.Fatalf("expected delay near %.2f got %.2f", -cfg.PhaseDelta, tracker.LastDelay())
+	}
+}
+
+func TestTrackerConvergesWithCFOTracking(t *testing.T) {
+	rand.Seed(5)
+	backend := sdr.NewMock()
+	reporter := &recordingReporter{}
+	cfg := Config{
+		SampleRate:        2e6,
+		RxLO:              2.3e9,
+		ToneOffset:        200e3,
+		NumSamples:        512,
+		SpacingWavelength: 0.5,
+		PhaseStep:         1,
+		ScanStep:          2,
+		PhaseDelta:        -20,
+		HistoryLimit:      20,
+		CFOTracking:       true,
+	}
+	tracker := NewTracker(backend, reporter, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := tracker.Run(ctx); err != nil && err != context.DeadlineExceeded {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if math.Abs(tracker.LastDelay()+cfg.PhaseDelta) > 5 {
+		t.Fatalf("expected delay near %.2f got %.2f", -cfg.PhaseDelta, tracker.LastDelay())
+	}
+	if math.Abs(tracker.cfoCorrection()) > 50 {
+		t.Fatalf("CFO loop drifted to %.1f Hz on a clean tone", tracker.cfoCorrection())
+	}
+}
+
+// notifyingReporter signals every report so a test can step the tracker.
+type notifyingReporter struct {
+	reports chan struct{}
+}
+
+func (r *notifyingReporter) Report(float64, float64, float64, float64, telemetry.LockState, *telemetry.DebugInfo) {
+	select {
+	case r.reports <- struct{}{}:
+	default:
+	}
+}
+
+func (r *notifyingReporter) ReportMultiTrack(telemetry.MultiTrackSample) {
+	r.Report(0, 0, 0, 0, "", nil)
+}
+
+func TestTrackerRunsOnInjectedClock(t *testing.T) {
+	rand.Seed(5)
+	backend := sdr.NewMock()
+	reporter := &notifyingReporter{reports: make(chan struct{}, 16)}
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cfg := Config{
+		SampleRate:        2e6,
+		RxLO:              2.3e9,
+		ToneOffset:        200e3,
+		NumSamples:        512,
+		SpacingWavelength: 0.5,
+		TrackingLength:    12,
+		PhaseStep:         1,
+		ScanStep:          2,
+		PhaseDelta:        35,
+		HistoryLimit:      20,
+		Clock:             fake,
+	}
+	tracker := NewTracker(backend, reporter, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- tracker.Run(ctx) }()
+
+	select {
+	case <-reporter.reports:
+		t.Fatal("tracker iterated before the clock advanced")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := range 5 {
+		fake.Advance(10 * time.Millisecond)
+		select {
+		case <-reporter.reports:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no report after tick %d", i)
+		}
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("run returned %v", err)
+	}
+}
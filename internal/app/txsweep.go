@@ -0,0 +1,241 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+const (
+	// txSweepAmplitude is the level of the probe tone relative to full scale.
+	txSweepAmplitude = 0.5
+	// txSweepToneFactor places the probe tone at 1.5 times the tone offset,
+	// inside the signal band but clear of an emitter at the tone offset.
+	txSweepToneFactor = 1.5
+	// txSweepMinSNR (dB) is how far the probe must rise above the level at
+	// its bin with TX silent.
+	txSweepMinSNR = 10.0
+)
+
+// TXSweepOptions configures a differential TX characterization. Zero values
+// select the defaults.
+type TXSweepOptions struct {
+	// StepDeg is the increment of the TX1 phase offset swept over a full
+	// turn with both channels transmitting, default 30°. It is rounded so
+	// the steps divide 360°.
+	StepDeg float64 `json:"stepDeg,omitempty"`
+	// Dwell is the number of buffers averaged per TX setting, default 4.
+	Dwell int `json:"dwell,omitempty"`
+	// Apply sets PhaseCal to the measured PhaseCalDeg.
+	Apply bool `json:"apply,omitempty"`
+}
+
+// ChainResponse is the gain and phase of one TX to RX path relative to the
+// TX0 to RX0 path.
+type ChainResponse struct {
+	GainDB   float64 `json:"gainDb"`
+	PhaseDeg float64 `json:"phaseDeg"`
+}
+
+// TXSweepPoint is the response of both receivers to one TX setting.
+type TXSweepPoint struct {
+	// TX is "tx0" or "tx1" for one channel transmitting, or "both" with
+	// TX1 shifted by OffsetDeg against TX0.
+	TX        string  `json:"tx"`
+	OffsetDeg float64 `json:"offsetDeg"`
+	RX0DBFS   float64 `json:"rx0Dbfs"`
+	RX1DBFS   float64 `json:"rx1Dbfs"`
+	// RXPhaseDeg is the phase of RX1 relative to RX0.
+	RXPhaseDeg float64 `json:"rxPhaseDeg"`
+}
+
+// TXCharacterization is the result of CharacterizeTX.
+type TXCharacterization struct {
+	Timestamp time.Time `json:"timestamp"`
+	RxLOHz    float64   `json:"rxLoHz"`
+	ToneHz    float64   `json:"toneHz"`
+	// Matrix[tx][rx] is the response of every TX/RX path pair.
+	Matrix [2][2]ChainResponse `json:"matrix"`
+	Points []TXSweepPoint      `json:"points"`
+	// RXPhaseDeg is the phase of the RX1 chain relative to RX0, the circular
+	// mean of the RX phases seen with TX0 and with TX1 alone. SpreadDeg is
+	// their difference; a large spread means the reference paths are not
+	// symmetric and the result does not hold.
+	RXPhaseDeg float64 `json:"rxPhaseDeg"`
+	SpreadDeg  float64 `json:"spreadDeg"`
+	// TXPhaseDeg is the phase of the TX1 chain relative to TX0, fitted to
+	// the received power over the offset sweep. BestOffsetDeg is the swept
+	// offset with the most received power.
+	TXPhaseDeg    float64 `json:"txPhaseDeg"`
+	BestOffsetDeg float64 `json:"bestOffsetDeg"`
+	// PhaseCalDeg is the PhaseCal that cancels RXPhaseDeg.
+	PhaseCalDeg float64 `json:"phaseCalDeg"`
+}
+
+// PhaseCalibration returns the result as an entry of the phase calibration
+// table, with the spread as its residual.
+func (c TXCharacterization) PhaseCalibration(backend string) calibration.PhaseCalibration {
+	return calibration.PhaseCalibration{
+		Timestamp:   c.Timestamp,
+		Backend:     backend,
+		RxLOHz:      c.RxLOHz,
+		PhaseCalDeg: c.PhaseCalDeg,
+		ResidualDeg: c.SpreadDeg,
+		Points:      len(c.Points),
+	}
+}
+
+// txResponse is the averaged probe response of one TX setting: the power at
+// each receiver and the cross spectrum conj(X0)·X1, whose argument is the
+// RX1 phase relative to RX0. Both are relative to the buffer, so they
+// average across buffers whose absolute phase differs.
+type txResponse struct {
+	p0, p1 float64
+	cross  complex128
+}
+
+// CharacterizeTX measures the analog chains by transmitting a probe tone
+// from TX0 alone, from TX1 alone and from both with the TX1 phase swept
+// against TX0. The RX phase seen with each TX alone gives the RX chain
+// mismatch and the PhaseCal that cancels it; the power over the sweep gives
+// the TX chain mismatch. TX must reach both receivers through symmetric
+// paths, such as a splitter or a reference antenna at boresight. It needs an
+// initialized tracker that is not running and leaves TX silent.
+func (t *Tracker) CharacterizeTX(ctx context.Context, opts TXSweepOptions) (TXCharacterization, error) {
+	if t.running.Load() {
+		return TXCharacterization{}, errors.New("TX characterization: tracker is running")
+	}
+	if opts.StepDeg <= 0 {
+		opts.StepDeg = 30
+	}
+	if opts.Dwell <= 0 {
+		opts.Dwell = 4
+	}
+	steps := int(math.Round(360 / opts.StepDeg))
+	if steps < 3 {
+		return TXCharacterization{}, fmt.Errorf("TX characterization: step %.0f° leaves fewer than 3 sweep points", opts.StepDeg)
+	}
+	if err := t.warmup(ctx); err != nil {
+		return TXCharacterization{}, fmt.Errorf("warmup: %w", err)
+	}
+
+	n := t.cfg.NumSamples
+	k := int(math.Round(txSweepToneFactor * t.cfg.ToneOffset * float64(n) / t.cfg.SampleRate))
+	tone, silence := make([]complex64, n), make([]complex64, n)
+	for i := range tone {
+		tone[i] = complex64(cmplx.Rect(txSweepAmplitude, 2*math.Pi*float64(k*i)/float64(n)))
+	}
+	bin := n/2 + k
+	defer func() { _ = t.sdr.TX(context.WithoutCancel(ctx), silence, silence) }()
+
+	background, err := t.measureTX(ctx, silence, silence, bin, opts.Dwell)
+	if err != nil {
+		return TXCharacterization{}, err
+	}
+	result := TXCharacterization{Timestamp: t.now(), RxLOHz: t.cfg.RxLO, ToneHz: float64(k) * t.cfg.SampleRate / float64(n)}
+	var single [2]txResponse
+	for tx := range single {
+		iq := [2][]complex64{silence, silence}
+		iq[tx] = tone
+		if single[tx], err = t.measureTX(ctx, iq[0], iq[1], bin, opts.Dwell); err != nil {
+			return TXCharacterization{}, err
+		}
+		if math.Min(single[tx].p0, single[tx].p1) < math.Max(background.p0, background.p1)*math.Pow(10, txSweepMinSNR/10) {
+			return TXCharacterization{}, fmt.Errorf("TX characterization: TX%d does not reach both receivers", tx)
+		}
+		result.Points = append(result.Points, single[tx].point(fmt.Sprintf("tx%d", tx), 0))
+	}
+
+	// The power at each receiver over the offset ψ is a + b·cos(ψ + φ),
+	// with φ the TX1 phase relative to TX0 at that receiver.
+	var fit0, fit1 complex128
+	best := -1.0
+	for i := range steps {
+		offset := float64(i) * 360 / float64(steps)
+		shifted := make([]complex64, n)
+		for j, v := range tone {
+			shifted[j] = v * complex64(cmplx.Rect(1, offset*math.Pi/180))
+		}
+		r, err := t.measureTX(ctx, tone, shifted, bin, opts.Dwell)
+		if err != nil {
+			return TXCharacterization{}, err
+		}
+		rot := cmplx.Rect(1, -offset*math.Pi/180)
+		fit0 += complex(r.p0, 0) * rot
+		fit1 += complex(r.p1, 0) * rot
+		if r.p0+r.p1 > best {
+			best, result.BestOffsetDeg = r.p0+r.p1, offset
+		}
+		result.Points = append(result.Points, r.point("both", offset))
+	}
+
+	rx0, rx1 := cmplx.Phase(single[0].cross), cmplx.Phase(single[1].cross)
+	tx0, tx1 := cmplx.Phase(fit0), cmplx.Phase(fit1)
+	result.RXPhaseDeg = degrees(cmplx.Phase(cmplx.Rect(1, rx0) + cmplx.Rect(1, rx1)))
+	result.SpreadDeg = math.Abs(degrees(math.Remainder(rx1-rx0, 2*math.Pi)))
+	result.TXPhaseDeg = degrees(cmplx.Phase(cmplx.Rect(1, tx0) + cmplx.Rect(1, tx1)))
+	result.PhaseCalDeg = -result.RXPhaseDeg
+	ref := single[0].p0
+	result.Matrix = [2][2]ChainResponse{
+		{{GainDB: 0}, {GainDB: powerDB(single[0].p1 / ref), PhaseDeg: degrees(rx0)}},
+		{{GainDB: powerDB(single[1].p0 / ref), PhaseDeg: degrees(tx0)}, {GainDB: powerDB(single[1].p1 / ref), PhaseDeg: degrees(math.Remainder(tx0+rx1, 2*math.Pi))}},
+	}
+
+	if opts.Apply {
+		t.cfg.PhaseCal = result.PhaseCalDeg
+	}
+	t.logger.Info("TX characterized",
+		logging.Field{Key: "subsystem", Value: "tracker"},
+		logging.Field{Key: "rx_phase_deg", Value: result.RXPhaseDeg},
+		logging.Field{Key: "spread_deg", Value: result.SpreadDeg},
+		logging.Field{Key: "tx_phase_deg", Value: result.TXPhaseDeg},
+		logging.Field{Key: "phase_cal_deg", Value: result.PhaseCalDeg},
+		logging.Field{Key: "applied", Value: opts.Apply})
+	return result, nil
+}
+
+// measureTX transmits iq0 and iq1 and averages the response at the probe bin
+// over dwell buffers, after one buffer for the TX to settle. TX is written
+// before every read since some backends, like the mock, send a buffer once.
+func (t *Tracker) measureTX(ctx context.Context, iq0, iq1 []complex64, bin, dwell int) (txResponse, error) {
+	var r txResponse
+	for i := 0; i <= dwell; i++ {
+		if err := t.sdr.TX(ctx, iq0, iq1); err != nil {
+			return r, fmt.Errorf("transmit: %w", err)
+		}
+		rx0, rx1, err := t.sdr.RX(ctx)
+		if err != nil {
+			return r, fmt.Errorf("receive samples: %w", err)
+		}
+		if i == 0 {
+			continue
+		}
+		fft0, _ := t.dsp.FFTAndDBFS(rx0)
+		fft1, _ := t.dsp.FFTAndDBFS(rx1)
+		if bin >= min(len(fft0), len(fft1)) {
+			return r, errors.New("probe tone outside the RX buffer spectrum")
+		}
+		x0, x1 := fft0[bin], fft1[bin]
+		r.p0 += sqAbs(x0) / float64(dwell)
+		r.p1 += sqAbs(x1) / float64(dwell)
+		r.cross += cmplx.Conj(x0) * x1
+	}
+	return r, nil
+}
+
+func (r txResponse) point(tx string, offset float64) TXSweepPoint {
+	return TXSweepPoint{TX: tx, OffsetDeg: offset, RX0DBFS: powerDB(r.p0), RX1DBFS: powerDB(r.p1), RXPhaseDeg: degrees(cmplx.Phase(r.cross))}
+}
+
+func sqAbs(x complex128) float64 { return real(x)*real(x) + imag(x)*imag(x) }
+
+// powerDB converts a power ratio to dB, floored at -200 dB.
+func powerDB(p float64) float64 { return 10 * math.Log10(math.Max(p, 1e-20)) }
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
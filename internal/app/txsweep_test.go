@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// chainSDR receives its own TX through the path gains h[tx][rx], each
+// buffer at a random phase as on hardware whose TX and RX buffers are not
+// aligned, on top of the mock emitter at the tone offset.
+type chainSDR struct {
+	*sdr.MockSDR
+	h        [2][2]complex128
+	iq0, iq1 []complex64
+}
+
+func (c *chainSDR) TX(_ context.Context, iq0, iq1 []complex64) error {
+	c.iq0, c.iq1 = iq0, iq1
+	return nil
+}
+
+func (c *chainSDR) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := c.MockSDR.RX(ctx)
+	if err != nil || c.iq0 == nil {
+		return rx0, rx1, err
+	}
+	rot := cmplx.Rect(1, 2*math.Pi*rand.Float64())
+	for i := range rx0 {
+		tx0, tx1 := complex128(c.iq0[i])*rot, complex128(c.iq1[i])*rot
+		rx0[i] += complex64(c.h[0][0]*tx0 + c.h[1][0]*tx1)
+		rx1[i] += complex64(c.h[0][1]*tx0 + c.h[1][1]*tx1)
+	}
+	return rx0, rx1, nil
+}
+
+// chainGains returns path gains for TX1 lagging TX0 by txDeg and RX1 by
+// rxDeg, with TX1 weaker by txLossDB.
+func chainGains(txDeg, rxDeg, txLossDB float64) [2][2]complex128 {
+	var h [2][2]complex128
+	for tx := range 2 {
+		for rx := range 2 {
+			amp := 0.3 * math.Pow(10, -txLossDB*float64(tx)/20)
+			h[tx][rx] = cmplx.Rect(amp, (txDeg*float64(tx)+rxDeg*float64(rx))*math.Pi/180)
+		}
+	}
+	return h
+}
+
+func TestCharacterizeTX(t *testing.T) {
+	tests := []struct {
+		name           string
+		h              [2][2]complex128
+		wantRX, wantTX float64
+		wantTX1GainDB  float64
+		wantErr        string
+	}{
+		{name: "matched", h: chainGains(0, 0, 0)},
+		{name: "mismatched", h: chainGains(40, -25, 3), wantRX: -25, wantTX: 40, wantTX1GainDB: -3},
+		{name: "wrapped", h: chainGains(-170, 160, 0), wantRX: 160, wantTX: -170},
+		{name: "TX1 dead", h: [2][2]complex128{{0.3, 0.3}, {0, 0}}, wantErr: "TX1 does not reach both receivers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 512, SpacingWavelength: 0.5, PhaseCal: 7, PhaseDelta: 30}
+			backend := &chainSDR{MockSDR: sdr.NewMock(), h: tt.h}
+			tracker := NewTracker(backend, nil, logging.New(logging.Info, logging.Text, io.Discard), cfg)
+			if err := tracker.Init(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tracker.CharacterizeTX(context.Background(), TXSweepOptions{Apply: true})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if tracker.cfg.PhaseCal != cfg.PhaseCal {
+					t.Fatalf("failed characterization changed PhaseCal to %.2f", tracker.cfg.PhaseCal)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			near := func(got, want float64) bool { return math.Abs(math.Remainder(got-want, 360)) < 1 }
+			if !near(got.RXPhaseDeg, tt.wantRX) || !near(got.TXPhaseDeg, tt.wantTX) || got.SpreadDeg > 1 {
+				t.Fatalf("rx phase %.2f° (spread %.2f°), tx phase %.2f°; want %.0f° and %.0f°", got.RXPhaseDeg, got.SpreadDeg, got.TXPhaseDeg, tt.wantRX, tt.wantTX)
+			}
+			if math.Abs(math.Remainder(got.BestOffsetDeg+tt.wantTX, 360)) > 15 {
+				t.Fatalf("best offset %.0f°, want the step nearest %.0f°", got.BestOffsetDeg, -tt.wantTX)
+			}
+			if !near(got.Matrix[1][1].PhaseDeg, tt.wantTX+tt.wantRX) || math.Abs(got.Matrix[1][0].GainDB-tt.wantTX1GainDB) > 0.2 {
+				t.Fatalf("matrix %+v", got.Matrix)
+			}
+			if len(got.Points) != 2+12 {
+				t.Fatalf("%d points, want 2 single-TX and 12 sweep points", len(got.Points))
+			}
+			if !near(tracker.cfg.PhaseCal, -tt.wantRX) {
+				t.Fatalf("applied PhaseCal %.2f°, want %.0f°", tracker.cfg.PhaseCal, -tt.wantRX)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"cmp"
+	"math"
+
+	"github.com/rjboer/GoSDR/internal/calibration"
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+const (
+	// defaultWarmStartSpan is the phase window (degrees) a warm start scans.
+	defaultWarmStartSpan = 40.0
+	// warmStartMinSNR (dB) is the SNR a warm start peak needs to be taken;
+	// it matches the SNR at which acquisition leaves the searching state.
+	warmStartMinSNR = 6.0
+)
+
+// WarmStart seeds the first coarse scan of Run with the steering a previous
+// run last locked on, typically from calibration.Store.LastLock.
+type WarmStart struct {
+	AngleDeg float64
+	// SpanDeg is the width of the phase window scanned around it, default
+	// 40°.
+	SpanDeg float64
+}
+
+// coarseScan runs the coarse scan of the current iteration. The first scan
+// after a warm start covers only the window around the known steering and
+// falls back to the full scan when it finds no peak there, or only one on
+// the window edge, which the true maximum may lie beyond. Multi-target mode
+// always scans fully, since other targets may be anywhere.
+func (t *Tracker) coarseScan(rx0, rx1 []complex64, step float64) []dsp.PeakInfo {
+	ws := t.warm
+	t.warm = nil
+	if ws != nil && t.mode != "multi" {
+		center := dsp.ThetaToPhase(ws.AngleDeg, t.cfg.RxLO, t.cfg.SpacingWavelength)
+		span := cmp.Or(ws.SpanDeg, defaultWarmStartSpan)
+		peaks := dsp.CoarseScanWindow(rx0, rx1, t.cfg.PhaseCal, t.startBin, t.endBin, center, span, step, t.cfg.RxLO, t.cfg.SpacingWavelength, t.dsp)
+		if warmStartHit(peaks, center, span, step) {
+			t.logger.Info("warm start", logging.Field{Key: "angle_deg", Value: ws.AngleDeg}, logging.Field{Key: "found_deg", Value: peaks[0].Angle})
+			return peaks
+		}
+		t.logger.Info("warm start found no target, scanning fully", logging.Field{Key: "angle_deg", Value: ws.AngleDeg})
+	}
+	return dsp.CoarseScanParallel(rx0, rx1, t.cfg.PhaseCal, t.startBin, t.endBin, step, t.cfg.RxLO, t.cfg.SpacingWavelength, t.dsp)
+}
+
+// warmStartHit reports whether the best peak of a window scan around center
+// is strong enough and lies inside the window rather than on its edge.
+func warmStartHit(peaks []dsp.PeakInfo, center, span, step float64) bool {
+	if len(peaks) == 0 || peaks[0].SNR < warmStartMinSNR {
+		return false
+	}
+	return math.Abs(math.Remainder(peaks[0].Phase-center, 360)) < span/2-step
+}
+
+// noteLock remembers the steering of a locked measurement for LastLock.
+func (t *Tracker) noteLock(theta float64, state telemetry.LockState) {
+	if state != telemetry.LockStateLocked {
+		return
+	}
+	t.lastLock.Store(&calibration.LastLock{Timestamp: t.now(), RxLOHz: t.cfg.RxLO, PhaseDelayDeg: t.lastDelay, AngleDeg: theta})
+}
+
+// LastLock returns the steering of the latest locked measurement, to be
+// stored for the WarmStart of the next run. ok is false until the tracker
+// has locked.
+func (t *Tracker) LastLock() (rec calibration.LastLock, ok bool) {
+	if last := t.lastLock.Load(); last != nil {
+		return *last, true
+	}
+	return calibration.LastLock{}, false
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestWarmStartHit(t *testing.T) {
+	tests := []struct {
+		name  string
+		peaks []dsp.PeakInfo
+		want  bool
+	}{
+		{"no peaks", nil, false},
+		{"inside", []dsp.PeakInfo{{Phase: 35, SNR: 20}}, true},
+		{"weak", []dsp.PeakInfo{{Phase: 35, SNR: 3}}, false},
+		{"edge", []dsp.PeakInfo{{Phase: 50, SNR: 20}}, false},
+		{"wrapped", []dsp.PeakInfo{{Phase: -178, SNR: 20}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := warmStartHit(tt.peaks, 30, 40, 2); got != tt.want {
+				t.Fatalf("warmStartHit = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerWarmStartScan(t *testing.T) {
+	cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 512, SpacingWavelength: 0.5, PhaseStep: 1, ScanStep: 2, PhaseDelta: 25}
+	tests := []struct {
+		name     string
+		delay    float64 // phase delay of the warm start angle
+		wantMiss bool
+	}{
+		{"hit", -20, false},
+		{"miss", 120, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			backend := sdr.NewMock()
+			tracker := NewTracker(backend, &recordingReporter{}, logging.New(logging.Info, logging.Text, &logs), cfg)
+			if err := tracker.Init(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			rx0, rx1, err := backend.RX(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracker.warm = &WarmStart{AngleDeg: dsp.PhaseToTheta(tt.delay, cfg.RxLO, cfg.SpacingWavelength)}
+			peaks := tracker.coarseScan(rx0, rx1, cfg.ScanStep)
+			if len(peaks) == 0 || math.Abs(peaks[0].Phase+cfg.PhaseDelta) > 5 {
+				t.Fatalf("coarse scan peaks %+v, want one near %.0f°", peaks, -cfg.PhaseDelta)
+			}
+			if missed := strings.Contains(logs.String(), "warm start found no target"); missed != tt.wantMiss {
+				t.Fatalf("warm start missed = %t, want %t; log %q", missed, tt.wantMiss, logs.String())
+			}
+			if tracker.warm != nil {
+				t.Fatal("warm start not consumed by the first scan")
+			}
+		})
+	}
+}
+
+func TestTrackerLastLock(t *testing.T) {
+	cfg := Config{SampleRate: 2e6, RxLO: 2.3e9, ToneOffset: 200e3, NumSamples: 512, SpacingWavelength: 0.5, PhaseStep: 1, ScanStep: 2, PhaseDelta: 25, Unpaced: true}
+	tracker := NewTracker(sdr.NewMock(), &recordingReporter{}, logging.New(logging.Info, logging.Text, &bytes.Buffer{}), cfg)
+	if _, ok := tracker.LastLock(); ok {
+		t.Fatal("last lock before Run")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := tracker.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_ = tracker.Run(ctx)
+
+	last, ok := tracker.LastLock()
+	if !ok {
+		t.Fatal("no last lock after a locked run")
+	}
+	if math.Abs(last.PhaseDelayDeg+cfg.PhaseDelta) > 5 || last.RxLOHz != cfg.RxLO {
+		t.Fatalf("last lock %+v, want delay near %.0f° at %.0f Hz", last, -cfg.PhaseDelta, cfg.RxLO)
+	}
+	if want := dsp.PhaseToTheta(last.PhaseDelayDeg, cfg.RxLO, cfg.SpacingWavelength); math.Abs(last.AngleDeg-want) > 1e-9 {
+		t.Fatalf("last lock angle %.2f°, want %.2f° for its delay", last.AngleDeg, want)
+	}
+}
@@ -0,0 +1,33 @@
+package app
+
+import "github.com/rjboer/GoSDR/internal/telemetry"
+
+// waterfallReporter is implemented by reporters that keep a spectrum
+// waterfall.
+type waterfallReporter interface {
+	WaterfallEnabled() bool
+	ReportWaterfall(row telemetry.WaterfallRow)
+}
+
+// observeSpectrum computes the channel 0 spectrum once per buffer for the
+// occupancy statistics and the waterfall, and skips the FFT when neither
+// wants it. The reporter bounds the waterfall's row rate and bins.
+func (t *Tracker) observeSpectrum(rx0 []complex64) {
+	waterfall, ok := t.reporter.(waterfallReporter)
+	if ok && !waterfall.WaterfallEnabled() {
+		ok = false
+	}
+	if t.occupancy == nil && !ok {
+		return
+	}
+	_, spectrum := t.dsp.FFTAndDBFS(rx0)
+	t.observeOccupancy(spectrum)
+	if ok {
+		waterfall.ReportWaterfall(telemetry.WaterfallRow{
+			Timestamp: t.now(),
+			CenterHz:  t.cfg.RxLO,
+			SpanHz:    t.cfg.SampleRate,
+			Bins:      spectrum,
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package buildinfo describes the running binary: version, commit, build date
+// and the optional features it was compiled with. Release builds set the
+// version variables at link time:
+//
+//	go build -ldflags "-X github.com/rjboer/GoSDR/internal/buildinfo.Version=v1.2.0 \
+//	  -X github.com/rjboer/GoSDR/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/rjboer/GoSDR/internal/buildinfo.Date=$(date -u +%FT%TZ)"
+//
+// Without them the values are taken from the module and VCS information the
+// Go toolchain embeds.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Link-time overrides; empty values fall back to the embedded build info.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Info identifies a build. Features lists build tags (e.g. uhd for the USRP
+// backend, soapy for SoapySDR), cgo, and the instruction set level the compiler may use for
+// vectorised code (e.g. amd64.v3).
+type Info struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit,omitempty"`
+	Date      string   `json:"date,omitempty"`
+	Modified  bool     `json:"modified,omitempty"`
+	GoVersion string   `json:"goVersion"`
+	Platform  string   `json:"platform"`
+	Features  []string `json:"features"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Features:  []string{},
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		fromBuildInfo(&info, bi)
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+func fromBuildInfo(info *Info, bi *debug.BuildInfo) {
+	if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		case "-tags":
+			for _, tag := range strings.Split(s.Value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					info.Features = append(info.Features, tag)
+				}
+			}
+		case "CGO_ENABLED":
+			if s.Value == "1" {
+				info.Features = append(info.Features, "cgo")
+			}
+		case "GOAMD64", "GOARM64", "GOARM", "GO386":
+			info.Features = append(info.Features, runtime.GOARCH+"."+s.Value)
+		}
+	}
+	sort.Strings(info.Features)
+}
+
+// String formats the info for --version output.
+func (i Info) String() string {
+	commit := i.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if i.Modified {
+		commit += "-dirty"
+	}
+	features := strings.Join(i.Features, ",")
+	if features == "" {
+		features = "none"
+	}
+	date := i.Date
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("%s (commit %s, built %s, %s, %s, features: %s)", i.Version, commit, date, i.GoVersion, i.Platform, features)
+}
@@ -0,0 +1,44 @@
+package buildinfo
+
+import (
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "-tags", Value: "uhd,netgo"},
+			{Key: "CGO_ENABLED", Value: "1"},
+			{Key: "GOAMD64", Value: "v3"},
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2025-01-15T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	info := Info{Commit: "override", Platform: "linux/amd64"}
+	fromBuildInfo(&info, bi)
+	if info.Version != "v1.4.0" || info.Commit != "override" || info.Date != "2025-01-15T10:00:00Z" || !info.Modified {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	// GOAMD64 is reported against the running GOARCH.
+	want := []string{runtime.GOARCH + ".v3", "cgo", "netgo", "uhd"}
+	sort.Strings(want)
+	if !reflect.DeepEqual(info.Features, want) {
+		t.Fatalf("features = %v", info.Features)
+	}
+	if s := info.String(); !strings.Contains(s, "v1.4.0 (commit override-dirty") || !strings.Contains(s, "uhd") {
+		t.Fatalf("String() = %q", s)
+	}
+}
+
+func TestGetDefaultsVersion(t *testing.T) {
+	if info := Get(); info.Version == "" || info.GoVersion == "" || info.Features == nil {
+		t.Fatalf("incomplete info %+v", info)
+	}
+}
@@ -0,0 +1,273 @@
+// Package bus is a small in-process publish/subscribe event bus. Producers
+// (trackers, SDR backends) publish on topics without knowing who listens;
+// consumers (the telemetry hub, recorders, alerting) subscribe to the topics
+// they care about.
+package bus
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// Topics published by the built-in producers.
+const (
+	// TopicTrack carries a telemetry.MultiTrackSample.
+	TopicTrack = "track"
+	// TopicEvent carries an Event.
+	TopicEvent = "event"
+	// TopicLifecycle carries an sdr.LifecycleEvent.
+	TopicLifecycle = "sdr.lifecycle"
+	// TopicOccupancy carries a telemetry.Occupancy.
+	TopicOccupancy = "spectrum.occupancy"
+	// TopicSweep carries a telemetry.Sweep.
+	TopicSweep = "spectrum.sweep"
+	// TopicSteering carries a telemetry.Steering.
+	TopicSteering = "steering"
+	// TopicConvergence carries a telemetry.Convergence.
+	TopicConvergence = "convergence"
+)
+
+// Message is one publication on the bus.
+type Message struct {
+	Topic string
+	// Source identifies the producer, normally the device ID ("" for a
+	// single-device setup).
+	Source  string
+	Time    time.Time
+	Payload any
+}
+
+// Event is the payload of TopicEvent messages.
+type Event struct {
+	Level   string
+	Message string
+}
+
+// Handler receives messages. Handlers run on the publisher's goroutine, in
+// subscription order, and must not modify the payload; slow consumers should
+// queue internally.
+type Handler func(Message)
+
+type subscription struct {
+	pattern string
+	handler Handler
+}
+
+// Bus routes published messages to matching subscribers.
+type Bus struct {
+	mu    sync.RWMutex
+	next  int
+	subs  map[int]subscription
+	clock clock.Clock
+}
+
+// New creates an empty bus.
+func New() *Bus {
+	return &Bus{subs: make(map[int]subscription), clock: clock.Real}
+}
+
+// SetClock replaces the clock that stamps messages and single-track samples,
+// so a simulation on scaled time stays consistent end to end. Nil selects
+// the system clock. Call it before publishing.
+func (b *Bus) SetClock(c clock.Clock) {
+	if c == nil {
+		c = clock.Real
+	}
+	b.clock = c
+}
+
+// Subscribe registers h for topics matching pattern: an exact topic, a
+// "prefix.*" wildcard or "*" for everything. The returned function removes
+// the subscription.
+func (b *Bus) Subscribe(pattern string, h Handler) (cancel func()) {
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = subscription{pattern: pattern, handler: h}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Publish delivers payload to every subscriber of topic.
+func (b *Bus) Publish(topic, source string, payload any) {
+	msg := Message{Topic: topic, Source: source, Time: b.clock.Now(), Payload: payload}
+	for _, h := range b.handlers(topic) {
+		h(msg)
+	}
+}
+
+// handlers returns the matching handlers in subscription order. They are
+// copied so a handler may subscribe or cancel without deadlocking.
+func (b *Bus) handlers(topic string) []Handler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ids := make([]int, 0, len(b.subs))
+	for id, sub := range b.subs {
+		if Match(sub.pattern, topic) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	out := make([]Handler, len(ids))
+	for i, id := range ids {
+		out[i] = b.subs[id].handler
+	}
+	return out
+}
+
+// Match reports whether topic matches a subscription pattern.
+func Match(pattern, topic string) bool {
+	if pattern == "*" || pattern == topic {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, ".*")
+	return ok && strings.HasPrefix(topic, prefix+".")
+}
+
+// Publisher publishes one producer's output. It implements
+// telemetry.Reporter, sdr.EventLogger and sdr.LifecycleObserver, so it can be
+// handed to a tracker or SDR backend in place of a concrete consumer.
+type Publisher struct {
+	bus    *Bus
+	source string
+}
+
+// Publisher returns a publisher tagging its messages with source.
+func (b *Bus) Publisher(source string) *Publisher {
+	return &Publisher{bus: b, source: source}
+}
+
+// Report publishes a single-track sample.
+func (p *Publisher) Report(angleDeg float64, peak float64, snr float64, confidence float64, state telemetry.LockState, debug *telemetry.DebugInfo) {
+	p.ReportMultiTrack(telemetry.MultiTrackSample{
+		Timestamp: p.bus.clock.Now(),
+		Tracks: []telemetry.TrackSample{{
+			AngleDeg:   angleDeg,
+			Peak:       peak,
+			SNR:        snr,
+			Confidence: confidence,
+			LockState:  state,
+			Debug:      debug,
+		}},
+	})
+}
+
+// ReportMultiTrack publishes sample on TopicTrack.
+func (p *Publisher) ReportMultiTrack(sample telemetry.MultiTrackSample) {
+	p.bus.Publish(TopicTrack, p.source, sample)
+}
+
+// LogEvent publishes an Event on TopicEvent.
+func (p *Publisher) LogEvent(level, message string) {
+	p.bus.Publish(TopicEvent, p.source, Event{Level: level, Message: message})
+}
+
+// ObserveLifecycle publishes ev on TopicLifecycle.
+func (p *Publisher) ObserveLifecycle(ev sdr.LifecycleEvent) {
+	p.bus.Publish(TopicLifecycle, p.source, ev)
+}
+
+// ReportOccupancy publishes occ on TopicOccupancy.
+func (p *Publisher) ReportOccupancy(occ telemetry.Occupancy) {
+	p.bus.Publish(TopicOccupancy, p.source, occ)
+}
+
+// ReportSweep publishes s on TopicSweep.
+func (p *Publisher) ReportSweep(s telemetry.Sweep) {
+	p.bus.Publish(TopicSweep, p.source, s)
+}
+
+// ReportSteering publishes s on TopicSteering.
+func (p *Publisher) ReportSteering(s telemetry.Steering) {
+	p.bus.Publish(TopicSteering, p.source, s)
+}
+
+// ReportConvergence publishes c on TopicConvergence.
+func (p *Publisher) ReportConvergence(c telemetry.Convergence) {
+	p.bus.Publish(TopicConvergence, p.source, c)
+}
+
+// eventLogger is implemented by reporters that keep an event log.
+type eventLogger interface {
+	LogEvent(level, message string)
+}
+
+// occupancyReporter is implemented by reporters that keep spectrum occupancy.
+type occupancyReporter interface {
+	ReportOccupancy(occ telemetry.Occupancy)
+}
+
+// sweepReporter is implemented by reporters that keep frequency sweeps.
+type sweepReporter interface {
+	ReportSweep(s telemetry.Sweep)
+}
+
+// steeringReporter is implemented by reporters that keep the steering angle.
+type steeringReporter interface {
+	ReportSteering(s telemetry.Steering)
+}
+
+// convergenceReporter is implemented by reporters that keep the convergence
+// state.
+type convergenceReporter interface {
+	ReportConvergence(c telemetry.Convergence)
+}
+
+// Forward subscribes r to the track samples, events, lifecycle events,
+// occupancy snapshots, sweeps, steering updates and convergence states
+// published by source. Payloads r does not implement a method for are dropped.
+func (b *Bus) Forward(source string, r telemetry.Reporter) (cancel func()) {
+	events, _ := r.(eventLogger)
+	lifecycle, _ := r.(sdr.LifecycleObserver)
+	occupancy, _ := r.(occupancyReporter)
+	sweep, _ := r.(sweepReporter)
+	steering, _ := r.(steeringReporter)
+	convergence, _ := r.(convergenceReporter)
+	return b.Subscribe("*", func(msg Message) {
+		if msg.Source != source {
+			return
+		}
+		switch payload := msg.Payload.(type) {
+		case telemetry.MultiTrackSample:
+			r.ReportMultiTrack(payload)
+		case Event:
+			if events != nil {
+				events.LogEvent(payload.Level, payload.Message)
+			}
+		case sdr.LifecycleEvent:
+			if lifecycle != nil {
+				lifecycle.ObserveLifecycle(payload)
+			}
+		case telemetry.Occupancy:
+			if occupancy != nil {
+				occupancy.ReportOccupancy(payload)
+			}
+		case telemetry.Sweep:
+			if sweep != nil {
+				sweep.ReportSweep(payload)
+			}
+		case telemetry.Steering:
+			if steering != nil {
+				steering.ReportSteering(payload)
+			}
+		case telemetry.Convergence:
+			if convergence != nil {
+				convergence.ReportConvergence(payload)
+			}
+		}
+	})
+}
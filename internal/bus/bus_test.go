@@ -0,0 +1,134 @@
+package bus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{pattern: "track", topic: "track", want: true},
+		{pattern: "track", topic: "tracks", want: false},
+		{pattern: "*", topic: "event", want: true},
+		{pattern: "sdr.*", topic: "sdr.overflow", want: true},
+		{pattern: "sdr.*", topic: "sdr", want: false},
+		{pattern: "sdr.*", topic: "sdrx.overflow", want: false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribeOrderAndCancel(t *testing.T) {
+	b := New()
+	var got []string
+	cancelA := b.Subscribe("event", func(msg Message) { got = append(got, "a:"+msg.Source) })
+	b.Subscribe("*", func(msg Message) { got = append(got, "b:"+msg.Topic) })
+
+	b.Publish(TopicEvent, "north", Event{})
+	cancelA()
+	cancelA()
+	b.Publish(TopicEvent, "north", Event{})
+
+	want := []string{"a:north", "b:event", "b:event"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+type recordingReporter struct {
+	samples   []telemetry.MultiTrackSample
+	events    []string
+	occupancy []telemetry.Occupancy
+	sweeps    []telemetry.Sweep
+	steering  []telemetry.Steering
+	converge  []telemetry.Convergence
+}
+
+func (r *recordingReporter) Report(float64, float64, float64, float64, telemetry.LockState, *telemetry.DebugInfo) {
+}
+
+func (r *recordingReporter) ReportMultiTrack(sample telemetry.MultiTrackSample) {
+	r.samples = append(r.samples, sample)
+}
+
+func (r *recordingReporter) LogEvent(level, message string) {
+	r.events = append(r.events, level+":"+message)
+}
+
+func (r *recordingReporter) ReportOccupancy(occ telemetry.Occupancy) {
+	r.occupancy = append(r.occupancy, occ)
+}
+
+func (r *recordingReporter) ReportSweep(s telemetry.Sweep) {
+	r.sweeps = append(r.sweeps, s)
+}
+
+func (r *recordingReporter) ReportSteering(s telemetry.Steering) {
+	r.steering = append(r.steering, s)
+}
+
+func (r *recordingReporter) ReportConvergence(c telemetry.Convergence) {
+	r.converge = append(r.converge, c)
+}
+
+func TestForwardFiltersBySource(t *testing.T) {
+	b := New()
+	rec := &recordingReporter{}
+	b.Forward("north", rec)
+
+	b.Publisher("north").Report(12, -20, 15, 0.9, telemetry.LockStateLocked, nil)
+	b.Publisher("north").LogEvent("warn", "overflow")
+	b.Publisher("north").ReportOccupancy(telemetry.Occupancy{Frames: 3})
+	b.Publisher("north").ReportSweep(telemetry.Sweep{Pass: 2})
+	b.Publisher("north").ReportSteering(telemetry.Steering{AngleDeg: 10, Changed: true})
+	b.Publisher("north").ReportConvergence(telemetry.Convergence{Converged: true, Scans: 1})
+	b.Publisher("south").Report(40, -20, 15, 0.9, telemetry.LockStateLocked, nil)
+
+	if len(rec.samples) != 1 || rec.samples[0].Tracks[0].AngleDeg != 12 {
+		t.Fatalf("unexpected samples %+v", rec.samples)
+	}
+	if len(rec.events) != 1 || rec.events[0] != "warn:overflow" {
+		t.Fatalf("unexpected events %v", rec.events)
+	}
+	if len(rec.occupancy) != 1 || rec.occupancy[0].Frames != 3 {
+		t.Fatalf("unexpected occupancy %+v", rec.occupancy)
+	}
+	if len(rec.sweeps) != 1 || rec.sweeps[0].Pass != 2 {
+		t.Fatalf("unexpected sweeps %+v", rec.sweeps)
+	}
+	if len(rec.steering) != 1 || rec.steering[0].AngleDeg != 10 {
+		t.Fatalf("unexpected steering %+v", rec.steering)
+	}
+	if len(rec.converge) != 1 || !rec.converge[0].Converged {
+		t.Fatalf("unexpected convergence %+v", rec.converge)
+	}
+}
+
+func TestSetClockStampsMessages(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	b := New()
+	b.SetClock(clock.NewFake(start))
+	var msg Message
+	b.Subscribe(TopicTrack, func(m Message) { msg = m })
+
+	b.Publisher("north").Report(12, 1, 20, 0.9, telemetry.LockStateLocked, nil)
+	sample := msg.Payload.(telemetry.MultiTrackSample)
+	if !msg.Time.Equal(start) || !sample.Timestamp.Equal(start) {
+		t.Fatalf("message %s, sample %s; want both at %s", msg.Time, sample.Timestamp, start)
+	}
+}
@@ -0,0 +1,248 @@
+// Package calibration persists measured calibration data (noise figures,
+// channel phase offsets) so receive chain health can be compared over time
+// and phase corrections are restored at startup. It also keeps the steering
+// phase each tracker last locked on, from which the next run starts.
+package calibration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRecordsPerChannel bounds the stored history for each device/channel.
+const maxRecordsPerChannel = 100
+
+// NoiseFigure is one Y-factor measurement of a receive channel.
+type NoiseFigure struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Device        string    `json:"device,omitempty"`
+	Backend       string    `json:"backend"`
+	Channel       int       `json:"channel"`
+	RxLOHz        float64   `json:"rx_lo_hz"`
+	RxGainDB      int       `json:"rx_gain_db"`
+	ENRDB         float64   `json:"enr_db"`
+	ColdDBFS      float64   `json:"cold_dbfs"`
+	HotDBFS       float64   `json:"hot_dbfs"`
+	YFactorDB     float64   `json:"y_factor_db"`
+	NoiseFigureDB float64   `json:"noise_figure_db"`
+}
+
+// PhaseCalibration is the channel phase correction measured against a
+// reference source at one RX LO frequency: the PhaseCal that zeroes the
+// monopulse error with the array steered at the source.
+type PhaseCalibration struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Device      string    `json:"device,omitempty"`
+	Backend     string    `json:"backend"`
+	RxLOHz      float64   `json:"rx_lo_hz"`
+	SourceDeg   float64   `json:"source_deg"`
+	PhaseCalDeg float64   `json:"phase_cal_deg"`
+	// ResidualDeg is the spread of the per-step estimates the fit combined;
+	// Points is their number.
+	ResidualDeg float64 `json:"residual_deg"`
+	Points      int     `json:"points"`
+}
+
+// LastLock is the steering a tracker last held in lock before it stopped.
+// The next run scans around it first, since the target of a fixed
+// installation rarely moves between restarts.
+type LastLock struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Device        string    `json:"device,omitempty"`
+	RxLOHz        float64   `json:"rx_lo_hz"`
+	PhaseDelayDeg float64   `json:"phase_delay_deg"`
+	AngleDeg      float64   `json:"angle_deg"`
+}
+
+// Data is the on-disk layout of the calibration store.
+type Data struct {
+	NoiseFigures []NoiseFigure      `json:"noise_figures,omitempty"`
+	PhaseCals    []PhaseCalibration `json:"phase_calibrations,omitempty"`
+	LastLocks    []LastLock         `json:"last_locks,omitempty"`
+}
+
+// Store is a JSON file backed calibration store safe for concurrent use.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data Data
+}
+
+// Open loads the store at path. A missing file yields an empty store that is
+// created on the first write.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read calibration store: %w", err)
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("decode calibration store: %w", err)
+	}
+	return s, nil
+}
+
+// AddNoiseFigures appends records, trims old entries per device/channel and
+// saves the store.
+func (s *Store) AddNoiseFigures(records ...NoiseFigure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := append(s.data.NoiseFigures, records...)
+	counts := make(map[string]int)
+	kept := make([]NoiseFigure, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		key := fmt.Sprintf("%s/%d", all[i].Device, all[i].Channel)
+		if counts[key] >= maxRecordsPerChannel {
+			continue
+		}
+		counts[key]++
+		kept = append(kept, all[i])
+	}
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	s.data.NoiseFigures = kept
+	return s.saveLocked()
+}
+
+// NoiseFigures returns the stored measurements for device, oldest first. An
+// empty device returns all records.
+func (s *Store) NoiseFigures(device string) []NoiseFigure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]NoiseFigure, 0, len(s.data.NoiseFigures))
+	for _, rec := range s.data.NoiseFigures {
+		if device == "" || rec.Device == device {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// SetPhaseCalibration stores rec as the device's calibration at its RX LO,
+// replacing an earlier one at the same frequency, and saves the store.
+func (s *Store) SetPhaseCalibration(rec PhaseCalibration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]PhaseCalibration, 0, len(s.data.PhaseCals)+1)
+	for _, old := range s.data.PhaseCals {
+		if old.Device != rec.Device || old.RxLOHz != rec.RxLOHz {
+			kept = append(kept, old)
+		}
+	}
+	kept = append(kept, rec)
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].Device != kept[j].Device {
+			return kept[i].Device < kept[j].Device
+		}
+		return kept[i].RxLOHz < kept[j].RxLOHz
+	})
+	s.data.PhaseCals = kept
+	return s.saveLocked()
+}
+
+// PhaseCalibrations returns the calibration table of device, ordered by
+// frequency. An empty device returns every table.
+func (s *Store) PhaseCalibrations(device string) []PhaseCalibration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]PhaseCalibration, 0, len(s.data.PhaseCals))
+	for _, rec := range s.data.PhaseCals {
+		if device == "" || rec.Device == device {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// PhaseCalAt returns the phase calibration of device at loHz, interpolated
+// linearly between the neighbouring table entries (taking the shorter way
+// round the circle) and held at the nearest entry outside the table. ok is
+// false when the device has no table.
+func (s *Store) PhaseCalAt(device string, loHz float64) (phaseCalDeg float64, ok bool) {
+	table := s.PhaseCalibrations(device)
+	if device == "" {
+		table = slices.DeleteFunc(table, func(rec PhaseCalibration) bool { return rec.Device != "" })
+	}
+	if len(table) == 0 {
+		return 0, false
+	}
+	i := sort.Search(len(table), func(i int) bool { return table[i].RxLOHz >= loHz })
+	switch {
+	case i == 0:
+		return table[0].PhaseCalDeg, true
+	case i == len(table):
+		return table[i-1].PhaseCalDeg, true
+	}
+	lo, hi := table[i-1], table[i]
+	frac := (loHz - lo.RxLOHz) / (hi.RxLOHz - lo.RxLOHz)
+	diff := math.Remainder(hi.PhaseCalDeg-lo.PhaseCalDeg, 360)
+	return math.Remainder(lo.PhaseCalDeg+frac*diff, 360), true
+}
+
+// SetLastLock stores rec as the device's last lock, replacing the previous
+// one, and saves the store.
+func (s *Store) SetLastLock(rec LastLock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.LastLocks = slices.DeleteFunc(s.data.LastLocks, func(old LastLock) bool { return old.Device == rec.Device })
+	s.data.LastLocks = append(s.data.LastLocks, rec)
+	return s.saveLocked()
+}
+
+// LastLock returns the last lock stored for device.
+func (s *Store) LastLock(device string) (LastLock, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rec := range s.data.LastLocks {
+		if rec.Device == device {
+			return rec, true
+		}
+	}
+	return LastLock{}, false
+}
+
+// saveLocked writes the store via a temporary file so a crash never leaves a
+// truncated file behind.
+func (s *Store) saveLocked() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal calibration store: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".calibration-*.json")
+	if err != nil {
+		return fmt.Errorf("write calibration store: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write calibration store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write calibration store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write calibration store: %w", err)
+	}
+	return nil
+}
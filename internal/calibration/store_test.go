@@ -0,0 +1,140 @@
+package calibration
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStorePersistsNoiseFigures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("open empty store: %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := store.AddNoiseFigures(
+		NoiseFigure{Timestamp: now, Device: "north", Channel: 0, NoiseFigureDB: 4.5},
+		NoiseFigure{Timestamp: now, Device: "south", Channel: 1, NoiseFigureDB: 5.1},
+	); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := reopened.NoiseFigures(""); len(got) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(got))
+	}
+	north := reopened.NoiseFigures("north")
+	if len(north) != 1 || north[0].NoiseFigureDB != 4.5 || !north[0].Timestamp.Equal(now) {
+		t.Fatalf("unexpected north records: %+v", north)
+	}
+}
+
+func TestStoreTrimsPerChannel(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "calibration.json"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < maxRecordsPerChannel+5; i++ {
+		if err := store.AddNoiseFigures(NoiseFigure{Channel: 0, NoiseFigureDB: float64(i)}, NoiseFigure{Channel: 1}); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	var ch0 []NoiseFigure
+	for _, rec := range store.NoiseFigures("") {
+		if rec.Channel == 0 {
+			ch0 = append(ch0, rec)
+		}
+	}
+	if len(ch0) != maxRecordsPerChannel {
+		t.Fatalf("expected %d channel 0 records, got %d", maxRecordsPerChannel, len(ch0))
+	}
+	if ch0[0].NoiseFigureDB != 5 || ch0[len(ch0)-1].NoiseFigureDB != maxRecordsPerChannel+4 {
+		t.Fatalf("expected oldest records dropped, got first %.0f last %.0f", ch0[0].NoiseFigureDB, ch0[len(ch0)-1].NoiseFigureDB)
+	}
+}
+
+func TestPhaseCalibrationTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, rec := range []PhaseCalibration{
+		{RxLOHz: 2.4e9, PhaseCalDeg: 170},
+		{RxLOHz: 2.2e9, PhaseCalDeg: 10},
+		{RxLOHz: 2.3e9, PhaseCalDeg: 99},
+		{RxLOHz: 2.3e9, PhaseCalDeg: -170}, // replaces the 99° entry
+		{Device: "north", RxLOHz: 2.3e9, PhaseCalDeg: 45},
+	} {
+		if err := store.SetPhaseCalibration(rec); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := reopened.PhaseCalibrations(""); len(got) != 4 || got[0].RxLOHz != 2.2e9 {
+		t.Fatalf("table not replaced and sorted: %+v", got)
+	}
+
+	tests := []struct {
+		name   string
+		device string
+		lo     float64
+		want   float64
+		ok     bool
+	}{
+		{name: "below table", lo: 1e9, want: 10, ok: true},
+		{name: "exact", lo: 2.2e9, want: 10, ok: true},
+		{name: "interpolated", lo: 2.25e9, want: -80, ok: true},
+		{name: "across ±180", lo: 2.35e9, want: 180, ok: true},
+		{name: "above table", lo: 6e9, want: 170, ok: true},
+		{name: "other device", device: "north", lo: 2.2e9, want: 45, ok: true},
+		{name: "no table", device: "south", lo: 2.3e9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := reopened.PhaseCalAt(tt.device, tt.lo)
+			if ok != tt.ok || math.Abs(math.Remainder(got-tt.want, 360)) > 1e-9 {
+				t.Fatalf("PhaseCalAt(%q, %g) = %v, %v; want %v, %v", tt.device, tt.lo, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestStorePersistsLastLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("open empty store: %v", err)
+	}
+	for _, rec := range []LastLock{
+		{Device: "north", RxLOHz: 2.3e9, PhaseDelayDeg: 40, AngleDeg: 12.8},
+		{Device: "south", RxLOHz: 2.3e9, PhaseDelayDeg: -20, AngleDeg: -6.4},
+		{Device: "north", RxLOHz: 2.3e9, PhaseDelayDeg: 42, AngleDeg: 13.4},
+	} {
+		if err := store.SetLastLock(rec); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got, ok := reopened.LastLock("north"); !ok || got.PhaseDelayDeg != 42 {
+		t.Fatalf("north last lock = %+v, %t; want the latest", got, ok)
+	}
+	if got, ok := reopened.LastLock("south"); !ok || got.AngleDeg != -6.4 {
+		t.Fatalf("south last lock = %+v, %t", got, ok)
+	}
+	if _, ok := reopened.LastLock("east"); ok {
+		t.Fatal("unknown device has a last lock")
+	}
+}
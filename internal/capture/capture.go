@@ -0,0 +1,331 @@
+// Package capture saves IQ around events, DVR style: when a trigger rule
+// fires, the samples from Pre before to Post after the event are cut from the
+// device's IQ ring into a named SigMF recording whose annotation links it to
+// the triggering track or event.
+package capture
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// Trigger kinds.
+const (
+	// OnTrackNew fires when a track ID appears for the first time.
+	OnTrackNew = "track_new"
+	// OnTrackConfirmed fires when a track becomes confirmed (or a single
+	// track locks).
+	OnTrackConfirmed = "track_confirmed"
+	// OnEvent fires on diagnostic events whose message contains Match.
+	OnEvent = "event"
+)
+
+// eventPrefix starts the events published by the manager itself, which never
+// trigger a capture.
+const eventPrefix = "capture "
+
+// settleDelay is added to Post before cutting, so the RX buffers covering the
+// end of the window have reached the ring.
+const settleDelay = time.Second
+
+// Rule saves a capture when the On condition is met. Pre and Post default to
+// 10s and 5s. Holdoff, by default Pre+Post, suppresses further captures of
+// the same rule and device for that long.
+type Rule struct {
+	Name    string `json:"name"`
+	On      string `json:"on"`
+	Match   string `json:"match,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Pre     string `json:"pre,omitempty"`
+	Post    string `json:"post,omitempty"`
+	Holdoff string `json:"holdoff,omitempty"`
+}
+
+type compiledRule struct {
+	Rule
+	pre, post, holdoff time.Duration
+}
+
+// Trigger describes one firing of a rule.
+type Trigger struct {
+	Rule     string
+	Device   string
+	Time     time.Time
+	TrackID  string
+	AngleDeg *float64
+	Message  string
+}
+
+// Manager watches the bus and saves captures. Captures are cut after Post
+// has elapsed, on their own goroutine.
+type Manager struct {
+	mu       sync.Mutex
+	rules    []compiledRule
+	dir      string
+	rings    map[string]string
+	tracks   map[string]map[string]bool // device -> track ID -> confirmed
+	lastFire map[string]time.Time       // rule name + device
+	settle   time.Duration
+	bus      *bus.Bus
+	pending  sync.WaitGroup
+}
+
+// New validates rules. Captures are written to dir; rings maps each device ID
+// ("" for a single device) to its IQ ring file.
+func New(rules []Rule, dir string, rings map[string]string) (*Manager, error) {
+	m := &Manager{
+		dir:      dir,
+		rings:    rings,
+		tracks:   make(map[string]map[string]bool),
+		lastFire: make(map[string]time.Time),
+		settle:   settleDelay,
+	}
+	names := make(map[string]bool)
+	for i, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("capture rule %d: %w", i, err)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("capture rule %d: duplicate name %q", i, c.Name)
+		}
+		names[c.Name] = true
+		m.rules = append(m.rules, c)
+	}
+	return m, nil
+}
+
+func compileRule(r Rule) (compiledRule, error) {
+	c := compiledRule{Rule: r}
+	c.Name = strings.TrimSpace(r.Name)
+	if c.Name == "" {
+		return c, fmt.Errorf("name required")
+	}
+	c.On = strings.ToLower(strings.TrimSpace(r.On))
+	switch c.On {
+	case OnTrackNew, OnTrackConfirmed:
+	case OnEvent:
+		if r.Match == "" {
+			return c, fmt.Errorf("%s: event rules need match", c.Name)
+		}
+	default:
+		return c, fmt.Errorf("%s: on must be track_new, track_confirmed or event, got %q", c.Name, r.On)
+	}
+	for _, d := range []struct {
+		value    string
+		fallback time.Duration
+		dst      *time.Duration
+	}{{r.Pre, 10 * time.Second, &c.pre}, {r.Post, 5 * time.Second, &c.post}, {r.Holdoff, -1, &c.holdoff}} {
+		*d.dst = d.fallback
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v < 0 {
+			return c, fmt.Errorf("%s: invalid duration %q", c.Name, d.value)
+		}
+		*d.dst = v
+	}
+	if c.pre+c.post <= 0 {
+		return c, fmt.Errorf("%s: pre and post are both zero", c.Name)
+	}
+	if c.holdoff < 0 {
+		c.holdoff = c.pre + c.post
+	}
+	return c, nil
+}
+
+// Attach subscribes the manager to track samples and events on b and
+// reports saved captures back on b as events.
+func (m *Manager) Attach(b *bus.Bus) (cancel func()) {
+	m.mu.Lock()
+	m.bus = b
+	m.mu.Unlock()
+	cancelTrack := b.Subscribe(bus.TopicTrack, m.handle)
+	cancelEvent := b.Subscribe(bus.TopicEvent, m.handle)
+	return func() {
+		cancelTrack()
+		cancelEvent()
+	}
+}
+
+// Wait blocks until every scheduled capture is written.
+func (m *Manager) Wait() { m.pending.Wait() }
+
+func (m *Manager) handle(msg bus.Message) {
+	var triggers []Trigger
+	switch p := msg.Payload.(type) {
+	case telemetry.MultiTrackSample:
+		triggers = m.trackTriggers(msg.Source, p)
+	case bus.Event:
+		if strings.HasPrefix(p.Message, eventPrefix) {
+			return
+		}
+		for _, r := range m.rules {
+			if r.On == OnEvent && (r.Level == "" || strings.EqualFold(r.Level, p.Level)) &&
+				strings.Contains(strings.ToLower(p.Message), strings.ToLower(r.Match)) {
+				triggers = append(triggers, Trigger{Rule: r.Name, Device: msg.Source, Time: msg.Time, Message: p.Message})
+			}
+		}
+	}
+	for _, t := range triggers {
+		m.fire(t)
+	}
+}
+
+// trackTriggers updates the per-device track state from sample and returns
+// the track rules that fire. Tracks missing from the sample are forgotten, so
+// one that reappears triggers again.
+func (m *Manager) trackTriggers(device string, sample telemetry.MultiTrackSample) []Trigger {
+	m.mu.Lock()
+	prev := m.tracks[device]
+	next := make(map[string]bool, len(sample.Tracks))
+	for _, track := range sample.Tracks {
+		next[track.ID] = track.Confirmed()
+	}
+	m.tracks[device] = next
+	m.mu.Unlock()
+
+	var out []Trigger
+	for _, track := range sample.Tracks {
+		confirmed, seen := prev[track.ID]
+		for _, r := range m.rules {
+			var msg string
+			switch {
+			case r.On == OnTrackNew && !seen && track.ID != "":
+				msg = "new track " + track.ID
+			case r.On == OnTrackConfirmed && track.Confirmed() && !confirmed:
+				msg = strings.TrimSpace("confirmed track " + track.ID)
+			default:
+				continue
+			}
+			angle := track.AngleDeg
+			out = append(out, Trigger{Rule: r.Name, Device: device, Time: sample.Timestamp, TrackID: track.ID, AngleDeg: &angle, Message: msg})
+		}
+	}
+	return out
+}
+
+// fire schedules the capture for t unless its rule is in holdoff or the
+// device has no ring.
+func (m *Manager) fire(t Trigger) {
+	r, ok := m.rule(t.Rule)
+	ring := m.rings[t.Device]
+	if !ok || ring == "" || !m.admit(r, t) {
+		return
+	}
+	m.pending.Add(1)
+	time.AfterFunc(r.post+m.settle, func() {
+		defer m.pending.Done()
+		path, err := m.save(r, ring, t)
+		if err != nil {
+			m.report(t.Device, "warn", fmt.Sprintf("%s%s failed: %v", eventPrefix, r.Name, err))
+			return
+		}
+		m.report(t.Device, "info", fmt.Sprintf("%ssaved: %s (%s)", eventPrefix, path, t.Message))
+	})
+}
+
+// admit reports whether t is outside the holdoff of its rule and device, and
+// if so starts a new holdoff.
+func (m *Manager) admit(r compiledRule, t Trigger) bool {
+	key := r.Name + "\x00" + t.Device
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if last, ok := m.lastFire[key]; ok && t.Time.Sub(last) < r.holdoff {
+		return false
+	}
+	m.lastFire[key] = t.Time
+	return true
+}
+
+func (m *Manager) rule(name string) (compiledRule, bool) {
+	for _, r := range m.rules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return compiledRule{}, false
+}
+
+func (m *Manager) report(device, level, message string) {
+	m.mu.Lock()
+	b := m.bus
+	m.mu.Unlock()
+	if b != nil {
+		b.Publisher(device).LogEvent(level, message)
+	}
+}
+
+// save cuts the window around t from ring and annotates the trigger sample.
+// It returns the metadata file path.
+func (m *Manager) save(r compiledRule, ring string, t Trigger) (string, error) {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return "", err
+	}
+	reader, err := sdr.OpenIQRing(ring)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	base := filepath.Join(m.dir, captureName(r.Name, t))
+	meta, err := reader.ExtractSigMF(t.Time.Add(-r.pre), t.Time.Add(r.post), base, fmt.Sprintf("%s: %s", r.Name, t.Message))
+	if err != nil {
+		return "", err
+	}
+	meta.Annotations = append(meta.Annotations, sdr.SigMFAnnotation{
+		SampleStart: triggerSample(meta, t.Time),
+		Label:       r.Name,
+		Comment:     t.Message,
+		TrackID:     t.TrackID,
+		AngleDeg:    t.AngleDeg,
+	})
+	if err := sdr.WriteSigMFMeta(base, meta); err != nil {
+		return "", err
+	}
+	metaPath, _ := sdr.SigMFPaths(base)
+	return metaPath, nil
+}
+
+// captureName builds "<rule>[-<device>]-<UTC time>[-<track>]" from
+// filename-safe characters.
+func captureName(rule string, t Trigger) string {
+	parts := []string{rule}
+	if t.Device != "" {
+		parts = append(parts, t.Device)
+	}
+	parts = append(parts, t.Time.UTC().Format("20060102T150405.000Z"))
+	if t.TrackID != "" {
+		parts = append(parts, t.TrackID)
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, strings.Join(parts, "-"))
+}
+
+// triggerSample returns the sample index of at in the extracted recording:
+// the offset into the last capture segment starting at or before it.
+func triggerSample(meta sdr.SigMFMeta, at time.Time) int64 {
+	var index int64
+	for _, c := range meta.Captures {
+		start, err := time.Parse(time.RFC3339Nano, c.Datetime)
+		if err != nil || start.After(at) {
+			break
+		}
+		index = c.SampleStart + int64(math.Round(at.Sub(start).Seconds()*meta.Global.SampleRate))
+	}
+	return index
+}
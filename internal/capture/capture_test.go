@@ -0,0 +1,122 @@
+package capture
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/bus"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestCompileRuleValidates(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		ok   bool
+	}{
+		{"defaults", Rule{Name: "new", On: "track_new"}, true},
+		{"event", Rule{Name: "ovl", On: "event", Match: "overload", Pre: "2s", Post: "0s"}, true},
+		{"no name", Rule{On: "track_new"}, false},
+		{"unknown trigger", Rule{Name: "x", On: "sunrise"}, false},
+		{"event without match", Rule{Name: "x", On: "event"}, false},
+		{"bad duration", Rule{Name: "x", On: "track_new", Pre: "soon"}, false},
+		{"empty window", Rule{Name: "x", On: "track_new", Pre: "0s", Post: "0s"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileRule(tt.rule); (err == nil) != tt.ok {
+				t.Fatalf("compileRule(%+v) error = %v, want ok=%v", tt.rule, err, tt.ok)
+			}
+		})
+	}
+	if _, err := New([]Rule{{Name: "a", On: "track_new"}, {Name: "a", On: "track_confirmed"}}, "", nil); err == nil {
+		t.Fatal("expected an error for duplicate rule names")
+	}
+}
+
+func TestManagerSavesTriggeredCapture(t *testing.T) {
+	dir := t.TempDir()
+	ringPath := filepath.Join(dir, "capture.ring")
+	ring, err := sdr.CreateIQRing(ringPath, 1<<20, 1000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The ring covers two seconds either side of t0; event triggers are
+	// stamped with the wall clock, so t0 is recent.
+	t0 := time.Now().UTC().Truncate(time.Millisecond).Add(-500 * time.Millisecond)
+	buf := make([]complex64, 1000)
+	for i := 0; i < 4; i++ {
+		if err := ring.Append(t0.Add(time.Duration(i-2)*time.Second), 2.4e9, 1000, buf, buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ring.Close()
+
+	m, err := New([]Rule{
+		{Name: "confirmed", On: "track_confirmed", Pre: "1s", Post: "500ms"},
+		{Name: "overload", On: "event", Match: "overload", Pre: "1s", Post: "0s"},
+	}, filepath.Join(dir, "out"), map[string]string{"": ringPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.settle = 0
+	b := bus.New()
+	defer m.Attach(b)()
+	var mu sync.Mutex
+	var saved []string
+	b.Subscribe(bus.TopicEvent, func(msg bus.Message) {
+		mu.Lock()
+		saved = append(saved, msg.Payload.(bus.Event).Message)
+		mu.Unlock()
+	})
+
+	pub := b.Publisher("")
+	tentative := telemetry.MultiTrackSample{Timestamp: t0.Add(-time.Second), Tracks: []telemetry.TrackSample{{ID: "T1", State: "tentative", AngleDeg: 12}}}
+	confirmed := telemetry.MultiTrackSample{Timestamp: t0, Tracks: []telemetry.TrackSample{{ID: "T1", State: "confirmed", AngleDeg: 12.5}}}
+	pub.ReportMultiTrack(tentative)
+	pub.ReportMultiTrack(confirmed)
+	pub.ReportMultiTrack(confirmed) // still confirmed: no new trigger
+	pub.LogEvent("warn", "RX overload on channel 0")
+	m.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(saved) != 3 || !strings.HasPrefix(saved[1], "capture saved") || !strings.HasPrefix(saved[2], "capture saved") {
+		t.Fatalf("events = %q, want two saved captures", saved)
+	}
+	meta, err := sdr.ReadSigMFMeta(filepath.Join(dir, "out", captureName("confirmed", Trigger{Time: t0, TrackID: "T1"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Start().Equal(t0.Add(-time.Second)) || len(meta.Annotations) != 1 {
+		t.Fatalf("meta = %+v", meta)
+	}
+	a := meta.Annotations[0]
+	if a.SampleStart != 1000 || a.TrackID != "T1" || a.AngleDeg == nil || *a.AngleDeg != 12.5 || a.Label != "confirmed" {
+		t.Fatalf("annotation = %+v, want T1 at sample 1000", a)
+	}
+}
+
+func TestManagerHoldoff(t *testing.T) {
+	m, err := New([]Rule{{Name: "new", On: "track_new", Pre: "1s", Post: "1s", Holdoff: "10s"}}, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Now()
+	var fired []string
+	for i, id := range []string{"A", "B", "C"} {
+		sample := telemetry.MultiTrackSample{Timestamp: t0.Add(time.Duration(i*6) * time.Second), Tracks: []telemetry.TrackSample{{ID: id}}}
+		for _, trig := range m.trackTriggers("", sample) {
+			if m.admit(m.rules[0], trig) {
+				fired = append(fired, trig.TrackID)
+			}
+		}
+	}
+	if strings.Join(fired, ",") != "A,C" {
+		t.Fatalf("fired for %v, want A and C", fired)
+	}
+}
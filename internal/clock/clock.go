@@ -0,0 +1,139 @@
+// Package clock abstracts the current time and tickers, so the tracker and
+// the telemetry hub can run on simulated time: tests step track timeouts and
+// lock-state hysteresis deterministically, and simulations run faster than
+// real time.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes tickers.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks on C until stopped. Like time.Ticker it drops ticks
+// for slow receivers.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the system clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// Func adapts a time source, such as the recording time of a replay, to a
+// Clock. Its tickers run on real time.
+type Func func() time.Time
+
+// Now calls f.
+func (f Func) Now() time.Time { return f() }
+
+// NewTicker returns a real ticker.
+func (Func) NewTicker(d time.Duration) Ticker { return Real.NewTicker(d) }
+
+// Scaled is a clock that runs factor times faster than real time from the
+// moment it is created. Tickers fire factor times as often, so a tracker
+// polling every 10 ms of scaled time runs the whole pipeline faster.
+type Scaled struct {
+	start  time.Time
+	origin time.Time
+	factor float64
+}
+
+// NewScaled returns a clock that starts at the current time and runs factor
+// times faster than real time. A non-positive factor means real time.
+func NewScaled(factor float64) *Scaled {
+	if factor <= 0 {
+		factor = 1
+	}
+	now := time.Now()
+	return &Scaled{start: now, origin: now.Round(0), factor: factor}
+}
+
+// Now returns the scaled time.
+func (s *Scaled) Now() time.Time {
+	return s.origin.Add(time.Duration(float64(time.Since(s.start)) * s.factor))
+}
+
+// NewTicker returns a real ticker with the period divided by the factor.
+func (s *Scaled) NewTicker(d time.Duration) Ticker {
+	return Real.NewTicker(max(time.Duration(float64(d)/s.factor), time.Microsecond))
+}
+
+// Fake is a clock that only moves when told to. Tickers fire from Advance.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers map[*fakeTicker]struct{}
+}
+
+// NewFake returns a fake clock set to start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, tickers: make(map[*fakeTicker]struct{})}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d and fires every ticker whose period
+// has elapsed, at most one pending tick per ticker.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for t := range f.tickers {
+		for !t.next.After(f.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// NewTicker returns a ticker driven by Advance. It panics on a non-positive
+// period, as time.NewTicker does.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, c: make(chan time.Time, 1), period: d, next: f.now.Add(d)}
+	f.tickers[t] = struct{}{}
+	return t
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	delete(t.clock.tickers, t)
+	t.clock.mu.Unlock()
+}
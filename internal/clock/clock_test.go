@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeAdvanceFiresTickers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	ticker := c.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	c.Advance(5 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("tick before the period elapsed")
+	default:
+	}
+
+	c.Advance(5 * time.Millisecond)
+	select {
+	case got := <-ticker.C():
+		if want := start.Add(10 * time.Millisecond); !got.Equal(want) {
+			t.Fatalf("tick at %s, want %s", got, want)
+		}
+	default:
+		t.Fatal("no tick after the period elapsed")
+	}
+
+	// Like time.Ticker, a slow receiver gets one pending tick, not a backlog.
+	c.Advance(time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("ticks were queued for a slow receiver")
+	default:
+	}
+	if got, want := c.Now(), start.Add(1010*time.Millisecond); !got.Equal(want) {
+		t.Fatalf("Now = %s, want %s", got, want)
+	}
+}
+
+func TestFakeTickerStop(t *testing.T) {
+	c := NewFake(time.Unix(0, 0))
+	ticker := c.NewTicker(time.Second)
+	ticker.Stop()
+	c.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+func TestScaledRunsFaster(t *testing.T) {
+	c := NewScaled(1000)
+	before := c.Now()
+	time.Sleep(5 * time.Millisecond)
+	if elapsed := c.Now().Sub(before); elapsed < 5*time.Second {
+		t.Fatalf("scaled clock advanced %s in 5ms, want at least 5s", elapsed)
+	}
+
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+	case <-time.After(time.Second):
+		t.Fatal("scaled ticker did not fire within a real second")
+	}
+}
+
+func TestFunc(t *testing.T) {
+	at := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := Func(func() time.Time { return at })
+	if got := c.Now(); !got.Equal(at) {
+		t.Fatalf("Now = %s, want %s", got, at)
+	}
+}
@@ -2,39 +2,18 @@ package connectionmgr
 
 import (
 	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
 )
 
-// readInteger reads a single ASCII integer terminated by '\n'. The call
-// leverages readLine's fixed-length strategy to avoid per-byte socket reads
-// while preserving protocol semantics.
-//
-// Uses regex to extract the integer from the response, handling cases where
-// the server may include extra text or formatting.
+// readInteger reads a single ASCII integer terminated by '\n'. Extra text the
+// server appends after the integer is ignored.
 func (m *Manager) readInteger() (int, error) {
 	line, err := m.readLine(64, false)
 	if err != nil {
 		return 0, err
 	}
-
-	// Use regex to extract integer from the line
-	// Pattern: optional whitespace, optional minus sign, one or more digits
-	re := regexp.MustCompile(`^\s*(-?\d+)`)
-	matches := re.FindStringSubmatch(string(line))
-
-	if len(matches) < 2 {
-		trimmed := strings.TrimSpace(string(line))
-		trimmed = strings.Trim(trimmed, "\x00")
-		return 0, fmt.Errorf("no integer found in response %q", trimmed)
-	}
-
-	val, convErr := strconv.Atoi(matches[1])
-	if convErr != nil {
-		return 0, fmt.Errorf("parse integer %q: %w", matches[1], convErr)
-	}
-	return val, nil
+	return iiodwire.ParseInteger(line)
 }
 
 // ExecCommand writes an ASCII command line (adding CRLF if missing), then
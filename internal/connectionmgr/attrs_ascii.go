@@ -151,6 +151,45 @@ func (m *Manager) ReadBufferAttrASCII(devID, attr string) (string, error) {
 	return value, nil
 }
 
+// ReadDebugAttrASCII reads a debug attribute through the ASCII protocol.
+//
+// Parameters:
+//   - devID: device identifier string.
+//   - attr: debug attribute name to read (for example "bist_prbs").
+//
+// Protocol:
+//   - issues "READ <devID> DEBUG <attr>\r\n" and expects the next line to
+//     contain the attribute value.
+//
+// Debug attributes live in the driver's debugfs namespace and are not listed
+// with the regular device attributes. Returns the trimmed attribute string or
+// an error if validation, write, or read fails.
+func (m *Manager) ReadDebugAttrASCII(devID, attr string) (string, error) {
+	if m == nil || m.conn == nil {
+		return "", errors.New("not connected")
+	}
+	if devID == "" || attr == "" {
+		return "", errors.New("devID and attr are required")
+	}
+
+	cmd := fmt.Sprintf("READ %s DEBUG %s", devID, attr)
+	log.Printf("[attr][READ][dbg] -> %q", cmd)
+
+	length, err := m.ExecASCII(cmd)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 {
+		return "", fmt.Errorf("READ DEBUG returned negative length %d", length)
+	}
+
+	payload, err := m.readASCIIPayload(length)
+	if err != nil {
+		return "", fmt.Errorf("READ DEBUG payload read failed: %w", err)
+	}
+	return strings.Trim(string(payload), "\x00"), nil
+}
+
 // ReadChannelAttrASCII2 mirrors ReadChannelAttrASCII but also returns the raw
 // status code. This helper is retained for callers that need to differentiate
 // transport errors from device-side errno returns until they migrate to the
@@ -247,6 +247,54 @@ func TestReadBufferAttrASCIINegativeLength(t *testing.T) {
 	}
 }
 
+func TestReadDebugAttrASCII(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		payload string
+		want    string
+		wantErr string
+	}{
+		{name: "value", length: 1, payload: "1\n", want: "1"},
+		{name: "errno", length: -19, wantErr: "negative length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+
+			mgr := &Manager{Mode: ModeASCII}
+			mgr.SetConn(client)
+			received := make(chan string, 1)
+			go func() {
+				buf := make([]byte, 64)
+				n, _ := server.Read(buf)
+				received <- string(buf[:n])
+				writeIntegerLine(t, server, tt.length)
+				server.Write([]byte(tt.payload))
+			}()
+
+			value, err := mgr.ReadDebugAttrASCII("ad9361-phy", "bist_prbs")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadDebugAttrASCII returned error: %v", err)
+			}
+			if cmd := <-received; !strings.HasPrefix(cmd, "READ ad9361-phy DEBUG bist_prbs") {
+				t.Fatalf("unexpected command sent: %q", cmd)
+			}
+			if value != tt.want {
+				t.Fatalf("value = %q, want %q", value, tt.want)
+			}
+		})
+	}
+}
+
 func TestWriteDeviceAttrASCIIPayloadOrdering(t *testing.T) {
 	client, server := net.Pipe()
 	defer client.Close()
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
 )
 
 func TestIntegratedSDRTEST(test *testing.T) {
@@ -17,8 +19,8 @@ func TestIntegratedSDRTEST(test *testing.T) {
 	addr := "192.168.3.1:30431"
 
 	m := &Manager{
-		Address: addr,
-		Timeout: 5 * time.Second,
+		Address:  addr,
+		Timeouts: TimeoutPolicy{Connect: 5 * time.Second},
 	}
 
 	// ------------------------------------------------------------
@@ -105,22 +107,11 @@ func TestIntegratedSDRTEST(test *testing.T) {
 	// 4) Read some device attributes (direct base-function usage)
 	// ------------------------------------------------------------
 	readDevAttr := func(name string) (string, error) {
-		hdr, plan, err := m.sendBinaryCommand(opReadAttr, 0, 0, lpString(name))
-		if err != nil {
-			return "", err
-		}
-		if hdr == nil || hdr.Opcode != opResponse {
-			return "", fmt.Errorf("readDevAttr(%s): unexpected response opcode", name)
-		}
-
-		status, _, data, err := m.readResponse(plan)
+		resp, err := m.Call(iiodwire.OpReadAttr, 0, 0, iiodwire.LPString(name))
 		if err != nil {
-			return "", err
-		}
-		if status != 0 {
-			return "", fmt.Errorf("readDevAttr(%s): status=%d", name, status)
+			return "", fmt.Errorf("readDevAttr(%s): %w", name, err)
 		}
-		return strings.TrimSpace(string(data)), nil
+		return strings.TrimSpace(string(resp.Data)), nil
 	}
 
 	log.Printf("[TEST] Reading common device attributes")
@@ -143,22 +134,11 @@ func TestIntegratedSDRTEST(test *testing.T) {
 	// 5) Read channel-indexed attributes (code = channel index)
 	// ------------------------------------------------------------
 	readChnAttr := func(chIdx int32, name string) (string, error) {
-		hdr, plan, err := m.sendBinaryCommand(opReadChnAttr, 0, chIdx, lpString(name))
+		resp, err := m.Call(iiodwire.OpReadChnAttr, 0, chIdx, iiodwire.LPString(name))
 		if err != nil {
-			return "", err
-		}
-		if hdr == nil || hdr.Opcode != opResponse {
-			return "", fmt.Errorf("readChnAttr(%d,%s): unexpected response opcode", chIdx, name)
-		}
-
-		status, _, data, err := m.readResponse(plan)
-		if err != nil {
-			return "", err
-		}
-		if status != 0 {
-			return "", fmt.Errorf("readChnAttr(%d,%s): status=%d", chIdx, name, status)
+			return "", fmt.Errorf("readChnAttr(%d,%s): %w", chIdx, name, err)
 		}
-		return strings.TrimSpace(string(data)), nil
+		return strings.TrimSpace(string(resp.Data)), nil
 	}
 
 	log.Printf("[TEST] Reading channel attributes (hardwaregain)")
@@ -180,23 +160,11 @@ func TestIntegratedSDRTEST(test *testing.T) {
 	// ------------------------------------------------------------
 	/*
 		log.Printf("[TEST] Setting gain_control_mode=manual")
-		hdr, plan, err := m.sendBinaryCommand(
-			opWriteAttr,
-			0,
-			0,
-			nameValue("gain_control_mode", "manual"),
-		)
+		resp, err := m.Call(iiodwire.OpWriteAttr, 0, 0, iiodwire.NameValue("gain_control_mode", "manual"))
 		if err != nil {
 			log.Printf("[TEST] write failed: %v", err)
-		} else if hdr == nil || hdr.Opcode != opResponse {
-			log.Printf("[TEST] unexpected response opcode on write")
 		} else {
-			status, _, _, err := m.readResponse(plan)
-			if err != nil {
-				log.Printf("[TEST] write response error: %v", err)
-			} else {
-				log.Printf("[TEST] write status=%d", status)
-			}
+			log.Printf("[TEST] write status=%d", resp.Status)
 		}
 	*/
 
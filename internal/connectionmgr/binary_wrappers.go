@@ -2,194 +2,105 @@ package connectionmgr
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
 )
 
+// GetXML returns the context XML over the binary protocol.
 func (m *Manager) GetXML(dev uint8) ([]byte, error) {
-
-	log.Printf("GetXML function: %v", dev)
-	hdr, plan, err := m.sendBinaryCommand(opPrint, dev, 0, []byte{})
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("GetXML function: %v", hdr)
-
-	if hdr == nil || hdr.Opcode != opResponse {
-		fmt.Println("unexpected response opcode")
-	}
-
-	status, _, xml, err := m.readResponse(plan)
+	resp, err := m.Call(iiodwire.OpPrint, dev, 0)
 	if err != nil {
-		return nil, err
-	}
-	if status != 0 {
-		return nil, fmt.Errorf("GetXML failed: status=%d", status)
+		return nil, fmt.Errorf("GetXML: %w", err)
 	}
-	return xml, nil
+	return resp.Data, nil
 }
 
+// Print is GetXML under the name of the PRINT command.
 func (m *Manager) Print(dev uint8) ([]byte, error) {
-
-	log.Printf("Print function start device: %v", dev)
-	hdr, plan, err := m.sendBinaryCommand(opPrint, dev, 0, []byte{})
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("Print function hdr: %v", hdr)
-
-	if hdr == nil || hdr.Opcode != opResponse {
-		log.Printf("unexpected response opcode: %v", hdr)
-	}
-
-	status, u32, data, err := m.readResponse(plan)
-	log.Printf("Print function u32: %v\n", u32)
-	log.Printf("Print function status: %v\n", status)
-	log.Printf("Print function data: %v\n", data)
-	if err != nil {
-		return nil, err
-	}
-	if status != 0 {
-		return nil, fmt.Errorf("Print failed: status=%d", status)
-	}
-	return data, nil
+	return m.GetXML(dev)
 }
 
+// PrimeCTX reads a device attribute to make the server set up its context
+// for the client before streaming.
 func (m *Manager) PrimeCTX(dev uint8) ([]byte, error) {
-
-	log.Printf("PrimeCTX function start device: %v", dev)
-	hdr, plan, err := m.sendBinaryCommand(opReadAttr, 1, 0, lpString("sampling_frequency"))
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("PrimeCTX function hdr: %v", hdr)
-
-	if hdr == nil || hdr.Opcode != opResponse {
-		log.Printf("unexpected response opcode: %v", hdr)
-	}
-
-	status, u32, data, err := m.readResponse(plan)
-	log.Printf("PrimeCTX function u32: %v\n", u32)
-	log.Printf("PrimeCTX function status: %v\n", status)
-	log.Printf("PrimeCTX function data: %v\n", data)
+	resp, err := m.Call(iiodwire.OpReadAttr, 1, 0, iiodwire.LPString("sampling_frequency"))
 	if err != nil {
-		return nil, err
-	}
-	if status != 0 {
-		return nil, fmt.Errorf("PrimeCTX failed: status=%d", status)
+		return nil, fmt.Errorf("PrimeCTX: %w", err)
 	}
-	return data, nil
+	return resp.Data, nil
 }
 
 func (m *Manager) GetSamplingFrequency(dev uint8, idx uint8) (int64, error) {
-	hdr, plan, err := m.sendBinaryCommand(opReadAttr, dev, 0, lpString(fmt.Sprintf("sampling_frequency%d", idx)))
+	value, err := m.readAttr(iiodwire.OpReadAttr, dev, 0, fmt.Sprintf("sampling_frequency%d", idx))
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("GetSamplingFrequency: %w", err)
 	}
-	if hdr == nil || hdr.Opcode != opResponse {
-		return 0, fmt.Errorf("unexpected response opcode: hdr:%v", hdr)
-	}
-	status, _, value, err := m.readResponse(plan)
-	if err != nil {
-		return 0, err
-	}
-	if status != 0 {
-		return 0, fmt.Errorf("GetSamplingFrequency failed: status=%d", status)
-	}
-	return strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+	return strconv.ParseInt(value, 10, 64)
 }
 
 func (m *Manager) GetRFBandwidth(dev uint8, idx uint8) (int64, error) {
-	hdr, plan, err := m.sendBinaryCommand(opReadAttr, dev, 0, lpString(fmt.Sprintf("rf_bandwidth%d", idx)))
-	if err != nil {
-		return 0, err
-	}
-	if hdr == nil || hdr.Opcode != opResponse {
-		return 0, fmt.Errorf("unexpected response opcode")
-	}
-	status, _, value, err := m.readResponse(plan)
+	value, err := m.readAttr(iiodwire.OpReadAttr, dev, 0, fmt.Sprintf("rf_bandwidth%d", idx))
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("GetRFBandwidth: %w", err)
 	}
-	if status != 0 {
-		return 0, fmt.Errorf("GetRFBandwidth failed: status=%d", status)
-	}
-	return strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+	return strconv.ParseInt(value, 10, 64)
 }
 
 func (m *Manager) GetGainControlMode(dev uint8, idx uint8) (string, error) {
-	hdr, plan, err := m.sendBinaryCommand(opReadAttr, dev, 0, lpString(fmt.Sprintf("gain_control_mode%d", idx)))
-	if err != nil {
-		return "", err
-	}
-	if hdr == nil || hdr.Opcode != opResponse {
-		return "", fmt.Errorf("unexpected response opcode")
-	}
-	status, _, value, err := m.readResponse(plan)
+	value, err := m.readAttr(iiodwire.OpReadAttr, dev, 0, fmt.Sprintf("gain_control_mode%d", idx))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("GetGainControlMode: %w", err)
 	}
-	if status != 0 {
-		return "", fmt.Errorf("GetGainControlMode failed: status=%d", status)
-	}
-	return strings.TrimSpace(string(value)), nil
+	return value, nil
 }
 
 func (m *Manager) GetChnAttrIdx(dev uint8, chIdx int32, attr string) (string, error) {
-	hdr, plan, err := m.sendBinaryCommand(opReadChnAttr, dev, chIdx, lpString(attr))
-	if err != nil {
-		return "", err
-	}
-	if hdr == nil || hdr.Opcode != opResponse {
-		return "", fmt.Errorf("GetChnAttrIdx(%d,%s): unexpected response opcode", chIdx, attr)
-	}
-
-	status, _, value, err := m.readResponse(plan)
+	value, err := m.readAttr(iiodwire.OpReadChnAttr, dev, chIdx, attr)
 	if err != nil {
-		return "", err
-	}
-	if status != 0 {
-		return "", fmt.Errorf("GetChnAttrIdx(%d,%s) failed: status=%d", chIdx, attr, status)
+		return "", fmt.Errorf("GetChnAttrIdx(%d,%s): %w", chIdx, attr, err)
 	}
-	return strings.TrimSpace(string(value)), nil
+	return value, nil
 }
 
 func (m *Manager) GetBufAttr(dev uint8, name string) (string, error) {
-	hdr, plan, err := m.sendBinaryCommand(opReadBufAttr, dev, 0, lpString(name))
+	value, err := m.readAttr(iiodwire.OpReadBufAttr, dev, 0, name)
 	if err != nil {
-		return "", err
-	}
-	if hdr == nil || hdr.Opcode != opResponse {
-		return "", fmt.Errorf("GetBufAttr(%s): unexpected response opcode", name)
+		return "", fmt.Errorf("GetBufAttr(%s): %w", name, err)
 	}
-
-	status, _, value, err := m.readResponse(plan)
-	if err != nil {
-		return "", err
-	}
-	if status != 0 {
-		return "", fmt.Errorf("GetBufAttr(%s) failed: status=%d", name, status)
-	}
-	return strings.TrimSpace(string(value)), nil
+	return value, nil
 }
 
 func (m *Manager) SetBufAttr(dev uint8, name, value string) error {
-	hdr, plan, err := m.sendBinaryCommand(opWriteBufAttr, dev, 0, nameValue(name, value))
-	if err != nil {
-		return err
-	}
-	if hdr == nil || hdr.Opcode != opResponse {
-		return fmt.Errorf("SetBufAttr(%s): unexpected response opcode", name)
+	if _, err := m.Call(iiodwire.OpWriteBufAttr, dev, 0, iiodwire.NameValue(name, value)); err != nil {
+		return fmt.Errorf("SetBufAttr(%s): %w", name, err)
 	}
+	return nil
+}
 
-	status, _, _, err := m.readResponse(plan)
+// GetDbgAttr reads a debug attribute of device dev over the binary protocol.
+func (m *Manager) GetDbgAttr(dev uint8, name string) (string, error) {
+	value, err := m.readAttr(iiodwire.OpReadDbgAttr, dev, 0, name)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("GetDbgAttr(%s): %w", name, err)
 	}
+	return value, nil
+}
 
-	if status != 0 {
-		return fmt.Errorf("SetBufAttr(%s) failed: status=%d", name, status)
+// SetDbgAttr writes a debug attribute of device dev over the binary protocol.
+func (m *Manager) SetDbgAttr(dev uint8, name, value string) error {
+	if _, err := m.Call(iiodwire.OpWriteDbgAttr, dev, 0, iiodwire.NameValue(name, value)); err != nil {
+		return fmt.Errorf("SetDbgAttr(%s): %w", name, err)
 	}
 	return nil
 }
+
+// readAttr issues an attribute read and returns the trimmed value.
+func (m *Manager) readAttr(opcode, dev uint8, code int32, name string) (string, error) {
+	resp, err := m.Call(opcode, dev, code, iiodwire.LPString(name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(resp.Data)), nil
+}
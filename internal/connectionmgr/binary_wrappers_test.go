@@ -0,0 +1,32 @@
+package connectionmgr
+
+import (
+	"net"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+func TestDbgAttrBinary(t *testing.T) {
+	name, value := "loopback", "1"
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	errCh := runBinaryMock(t, server, []binaryStep{
+		{op: iiodwire.OpWriteDbgAttr, payloadLen: len(iiodwire.NameValue(name, value)), response: iiodwire.I32(0)},
+		{op: iiodwire.OpReadDbgAttr, payloadLen: len(iiodwire.LPString(name)), response: append(iiodwire.I32(0), iiodwire.LPString(value+"\n")...)},
+	})
+	mgr := &Manager{Mode: ModeBinary}
+	mgr.SetConn(client)
+
+	if err := mgr.SetDbgAttr(0, name, value); err != nil {
+		t.Fatalf("SetDbgAttr: %v", err)
+	}
+	got, err := mgr.GetDbgAttr(0, name)
+	if err != nil || got != value {
+		t.Fatalf("GetDbgAttr = %q, %v; want %q", got, err, value)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,199 @@
+package connectionmgr
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// BufferConfig describes a sample buffer on one IIO device.
+type BufferConfig struct {
+	// DeviceID names the device for the ASCII protocol (for example
+	// "cf-ad9361-lpc").
+	DeviceID string
+	// DeviceIndex selects the device for the binary protocol, which
+	// addresses devices by their index in the context XML.
+	DeviceIndex uint8
+	// Samples is the number of samples per buffer.
+	Samples int
+	// Mask selects the enabled scan elements (bit i enables channel i).
+	Mask uint32
+	// Cyclic requests a cyclic (repeating) TX buffer. Its samples are
+	// written once and repeated by the device until the buffer is closed.
+	Cyclic bool
+	// ElementBytes is the size of one channel's sample; 0 selects 2
+	// (int16, as on the AD9361).
+	ElementBytes int
+}
+
+// size validates cfg and returns the bytes per buffer across the enabled
+// channels.
+func (cfg BufferConfig) size() (int, error) {
+	if cfg.Samples <= 0 {
+		return 0, fmt.Errorf("samples must be > 0")
+	}
+	channels := bits.OnesCount32(cfg.Mask)
+	if channels == 0 {
+		return 0, fmt.Errorf("no channels enabled (mask=0x%x)", cfg.Mask)
+	}
+	elem := cfg.ElementBytes
+	if elem <= 0 {
+		elem = 2
+	}
+	return cfg.Samples * channels * elem, nil
+}
+
+// bufferTransport moves buffer contents over one wire protocol. The ASCII and
+// binary protocols implement it; Buffer holds the protocol-independent logic.
+type bufferTransport interface {
+	open(cfg BufferConfig, bytes int) error
+	read(dst []byte) (int, error)
+	write(p []byte) (int, error)
+	close() error
+}
+
+// Buffer is an open sample buffer, independent of the protocol the Manager
+// speaks. It has the same ReadSamples/WriteSamples/Close surface as the
+// legacy iiod.Buffer, so the SDR backends can use either.
+type Buffer struct {
+	m         *Manager
+	cfg       BufferConfig
+	bytes     int // bytes per buffer across the enabled channels
+	transport bufferTransport
+	open      bool
+	pushed    bool // a cyclic buffer has been written
+}
+
+// OpenBuffer allocates a buffer on the device using the protocol of the
+// current mode (ModeASCII or ModeBinary).
+//
+// Returns the open buffer or an error for an invalid configuration, a
+// transport failure or a negative device response.
+func (m *Manager) OpenBuffer(cfg BufferConfig) (*Buffer, error) {
+	defer m.exclusive()()
+	return m.openBuffer(cfg)
+}
+
+func (m *Manager) openBuffer(cfg BufferConfig) (*Buffer, error) {
+	if m == nil || m.conn == nil {
+		return nil, errors.New("OpenBuffer: not connected")
+	}
+	size, err := cfg.size()
+	if err != nil {
+		return nil, fmt.Errorf("OpenBuffer: %w", err)
+	}
+
+	var transport bufferTransport
+	switch m.Mode {
+	case ModeASCII:
+		if cfg.DeviceID == "" {
+			return nil, fmt.Errorf("OpenBuffer: deviceID is required")
+		}
+		transport = &asciiBuffer{m: m}
+	case ModeBinary:
+		transport = &binaryBuffer{m: m}
+	default:
+		return nil, fmt.Errorf("OpenBuffer: unknown mode %d", m.Mode)
+	}
+
+	b := &Buffer{m: m, cfg: cfg, bytes: size, transport: transport}
+	if err := transport.open(cfg, b.bytes); err != nil {
+		return nil, err
+	}
+	b.open = true
+	return b, nil
+}
+
+// Size returns the buffer size in bytes.
+func (b *Buffer) Size() int {
+	return b.bytes
+}
+
+// ReadSamples reads one full buffer of raw interleaved samples.
+func (b *Buffer) ReadSamples() ([]byte, error) {
+	if b == nil || !b.open {
+		return nil, fmt.Errorf("buffer not open")
+	}
+	defer b.m.exclusive()()
+	return b.readSamples()
+}
+
+func (b *Buffer) readSamples() ([]byte, error) {
+	out := make([]byte, b.bytes)
+	for filled := 0; filled < len(out); {
+		n, err := b.transport.read(out[filled:])
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("buffer read ended after %d of %d bytes", filled, len(out))
+		}
+		filled += n
+	}
+	return out, nil
+}
+
+// WriteSamples writes raw interleaved samples to the buffer. A cyclic buffer
+// accepts a single write; close and reopen it to change the waveform.
+func (b *Buffer) WriteSamples(data []byte) error {
+	if b == nil || !b.open {
+		return fmt.Errorf("buffer not open")
+	}
+	defer b.m.exclusive()()
+	return b.writeSamples(data)
+}
+
+func (b *Buffer) writeSamples(data []byte) error {
+	if b.cfg.Cyclic && b.pushed {
+		return fmt.Errorf("cyclic buffer already written; reopen it to change the waveform")
+	}
+	if len(data) > b.bytes {
+		return fmt.Errorf("buffer write of %d bytes exceeds buffer size %d", len(data), b.bytes)
+	}
+	n, err := b.transport.write(data)
+	if n > 0 {
+		b.pushed = true
+	}
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("buffer wrote %d of %d bytes", n, len(data))
+	}
+	return nil
+}
+
+// Close releases the buffer on the device. Closing a closed or nil buffer is
+// a no-op.
+func (b *Buffer) Close() error {
+	if b == nil || !b.open {
+		return nil
+	}
+	defer b.m.exclusive()()
+	b.open = false
+	return b.transport.close()
+}
+
+// asciiBuffer implements bufferTransport with the OPEN, READBUF, WRITEBUF and
+// CLOSE commands of the ASCII protocol.
+type asciiBuffer struct {
+	m      *Manager
+	device string
+}
+
+func (a *asciiBuffer) open(cfg BufferConfig, _ int) error {
+	a.device = cfg.DeviceID
+	return a.m.OpenBufferASCII(cfg.DeviceID, uint64(cfg.Samples), fmt.Sprintf("%08x", cfg.Mask), cfg.Cyclic)
+}
+
+func (a *asciiBuffer) read(dst []byte) (int, error) {
+	return a.m.ReadBufferASCII(a.device, dst)
+}
+
+func (a *asciiBuffer) write(p []byte) (int, error) {
+	return a.m.WriteBufferASCII(a.device, p)
+}
+
+func (a *asciiBuffer) close() error {
+	return a.m.CloseBufferASCII(a.device)
+}
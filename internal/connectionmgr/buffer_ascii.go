@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"strings"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
 )
 
 // SetKernelBuffersCountASCII configures the number of kernel buffers for a
@@ -102,70 +104,29 @@ func (m *Manager) OpenBufferASCII(
 // (erroring when dst is too small), and then drains the trailing newline to keep
 // the stream aligned. It returns the number of bytes copied into dst or an
 // error when the mode is incorrect, IO fails, or the server returns a negative
-// errno.
+// errno. The transfer runs under the stream budget of the timeout policy.
 func (m *Manager) ReadBufferASCII(deviceID string, dst []byte) (int, error) {
-	n, _, err := m.ReadBufferASCIIWithMask(deviceID, dst)
-	return n, err
-}
-
-// ReadBufferASCIIWithMask reads raw bytes from an open buffer using the READBUF
-// command and returns the channel mask string announced by the server.
-func (m *Manager) ReadBufferASCIIWithMask(deviceID string, dst []byte) (int, string, error) {
 	if m.Mode != ModeASCII {
-		return 0, "", fmt.Errorf("ReadBufferASCII: not in ASCII mode")
+		return 0, fmt.Errorf("ReadBufferASCII: not in ASCII mode")
+	}
+	if m.br == nil {
+		return 0, errors.New("ReadBufferASCII: not connected")
 	}
+	defer m.withBudget(m.timeouts().Stream)()
 
 	cmd := fmt.Sprintf("READBUF %s %d", deviceID, len(dst))
 	log.Printf("[READBUF] -> %q", cmd)
-
 	if err := m.writeAll([]byte(cmd + "\r\n")); err != nil {
-		return 0, "", err
-	}
-
-	log.Printf("[READBUF] waiting for size integer")
-	n, err := m.readInteger()
-	if err != nil {
-		return 0, "", err
-	}
-
-	log.Printf("[READBUF] announced bytes=%d", n)
-
-	if n < 0 {
-		return 0, "", fmt.Errorf("READBUF error: %d", n)
-	}
-	if n == 0 {
-		return 0, "", nil
-	}
-
-	if n > len(dst) {
-		return 0, "", fmt.Errorf("READBUF announced %d bytes but destination capacity is %d", n, len(dst))
+		return 0, err
 	}
 
-	maskLine, err := m.readLine(64, true)
+	m.applyReadDeadline()
+	n, mask, err := iiodwire.ReadBufFrame(m.br, dst)
 	if err != nil {
-		return 0, "", fmt.Errorf("READBUF: failed to consume mask line: %w", err)
-	}
-	log.Printf("[READBUF] raw mask line=%q", maskLine)
-	mask := strings.TrimSpace(string(maskLine))
-
-	// Read payload
-	if err := m.readAll(dst[:n]); err != nil {
-		return 0, "", err
-	}
-
-	// Consume the trailing newline to keep the socket aligned for the next
-	// command.
-	var newline [1]byte
-	if err := m.readAll(newline[:]); err != nil {
-		return 0, "", err
-	}
-	log.Printf("[READBUF] trailing delimiter byte=%q", newline[0])
-	if newline[0] != '\n' {
-		return 0, "", fmt.Errorf("READBUF: expected trailing newline, got %q", newline[0])
+		return 0, err
 	}
-
 	log.Printf("[READBUF] completed: total=%d bytes mask=%s", n, mask)
-	return n, mask, nil
+	return n, nil
 }
 
 // WriteBufferASCII writes raw bytes to an open buffer using the WRITEBUF command.
@@ -183,7 +144,7 @@ func (m *Manager) ReadBufferASCIIWithMask(deviceID string, dst []byte) (int, str
 // status. Negative statuses are surfaced as errors. If the server reports a
 // positive byte count that does not match the payload length, the partial write
 // count is returned alongside an error to keep the stream aligned for the next
-// command.
+// command. The transfer runs under the stream budget of the timeout policy.
 func (m *Manager) WriteBufferASCII(deviceID string, payload []byte) (int, error) {
 	if m == nil || m.conn == nil {
 		return 0, errors.New("not connected")
@@ -195,6 +156,8 @@ func (m *Manager) WriteBufferASCII(deviceID string, payload []byte) (int, error)
 		return 0, errors.New("deviceID is required")
 	}
 
+	defer m.withBudget(m.timeouts().Stream)()
+
 	cmd := fmt.Sprintf("WRITEBUF %s %d", deviceID, len(payload))
 	log.Printf("[WRITEBUF] -> %q", cmd)
 
@@ -219,26 +182,6 @@ func (m *Manager) WriteBufferASCII(deviceID string, payload []byte) (int, error)
 	return written, nil
 }
 
-// drainBytes reads and discards exactly n bytes from the socket.
-// Keeps the protocol stream aligned when the server sends more than we can store.
-func (m *Manager) drainBytes(n int) error {
-	const chunk = 16 * 1024
-	var scratch [chunk]byte
-
-	left := n
-	for left > 0 {
-		want := left
-		if want > chunk {
-			want = chunk
-		}
-		if err := m.readAll(scratch[:want]); err != nil {
-			return err
-		}
-		left -= want
-	}
-	return nil
-}
-
 // CloseBufferASCII issues the ASCII CLOSE command to release a buffer.
 // It returns nil on success or an error when the manager is not in ASCII mode,
 // the command fails, or the server replies with a negative errno.
@@ -0,0 +1,127 @@
+package connectionmgr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// binaryBuffer implements bufferTransport with the buffer and block opcodes of
+// the binary protocol. Each buffer has a single block of the full buffer size.
+//
+// Protocol:
+//   - CREATE_BUFFER: code = buffer id, payload = channel mask words.
+//   - CREATE_BLOCK: code = buffer id << 16 | block index, payload = uint64
+//     block size in bytes.
+//   - ENABLE_BUFFER / DISABLE_BUFFER / FREE_BUFFER: code = buffer id.
+//   - TRANSFER_BLOCK: code = block code, payload = uint64 bytes used; TX
+//     data follows the payload, RX data comes back length-prefixed.
+//   - ENQUEUE_BLOCK_CYCLIC: as a TX TRANSFER_BLOCK, but the device repeats
+//     the block until the buffer is disabled.
+//   - FREE_BLOCK: code = block code.
+//
+// A cyclic buffer is enabled only once its block is enqueued, so the DMA
+// starts with the waveform rather than an empty block.
+type binaryBuffer struct {
+	m       *Manager
+	dev     uint8
+	id      uint16
+	block   int32
+	cyclic  bool
+	enabled bool
+}
+
+func (b *binaryBuffer) open(cfg BufferConfig, bytes int) error {
+	b.dev = cfg.DeviceIndex
+	b.id = b.m.nextBufferID
+	b.m.nextBufferID++
+	b.block = int32(b.id) << 16
+	b.cyclic = cfg.Cyclic
+
+	if _, err := b.m.Call(iiodwire.OpCreateBuffer, b.dev, int32(b.id), iiodwire.U32SliceWithCount([]uint32{cfg.Mask})); err != nil {
+		return fmt.Errorf("create buffer: %w", err)
+	}
+	if _, err := b.m.Call(iiodwire.OpCreateBlock, b.dev, b.block, iiodwire.U64(uint64(bytes))); err != nil {
+		_, _ = b.m.Call(iiodwire.OpFreeBuffer, b.dev, int32(b.id))
+		return fmt.Errorf("create block: %w", err)
+	}
+	if b.cyclic {
+		return nil
+	}
+	if err := b.enable(); err != nil {
+		return errors.Join(err, b.free())
+	}
+	return nil
+}
+
+func (b *binaryBuffer) enable() error {
+	if _, err := b.m.Call(iiodwire.OpEnableBuffer, b.dev, int32(b.id)); err != nil {
+		return fmt.Errorf("enable buffer: %w", err)
+	}
+	b.enabled = true
+	return nil
+}
+
+func (b *binaryBuffer) read(dst []byte) (int, error) {
+	resp, err := b.m.Call(iiodwire.OpTransferBlock, b.dev, b.block, iiodwire.U64(uint64(len(dst))))
+	if err != nil {
+		return 0, fmt.Errorf("transfer block: %w", err)
+	}
+	if len(resp.Data) > len(dst) {
+		return 0, fmt.Errorf("transfer block returned %d bytes but destination capacity is %d", len(resp.Data), len(dst))
+	}
+	return copy(dst, resp.Data), nil
+}
+
+func (b *binaryBuffer) write(p []byte) (int, error) {
+	if b.cyclic {
+		if err := b.m.EnqueueBlockCyclic(b.dev, b.block, p); err != nil {
+			return 0, err
+		}
+		return len(p), b.enable()
+	}
+	// A TX transfer is answered with the status only.
+	if _, err := b.m.CallShape(iiodwire.ShapeStatus, iiodwire.OpTransferBlock, b.dev, b.block, iiodwire.U64(uint64(len(p))), p); err != nil {
+		return 0, fmt.Errorf("transfer block: %w", err)
+	}
+	return len(p), nil
+}
+
+func (b *binaryBuffer) close() error {
+	var err error
+	if b.enabled {
+		if _, err = b.m.Call(iiodwire.OpDisableBuffer, b.dev, int32(b.id)); err != nil {
+			err = fmt.Errorf("disable buffer: %w", err)
+		}
+	}
+	return errors.Join(err, b.free())
+}
+
+// free releases the block and the buffer.
+func (b *binaryBuffer) free() error {
+	var errs []error
+	if _, err := b.m.Call(iiodwire.OpFreeBlock, b.dev, b.block); err != nil {
+		errs = append(errs, fmt.Errorf("free block: %w", err))
+	}
+	if _, err := b.m.Call(iiodwire.OpFreeBuffer, b.dev, int32(b.id)); err != nil {
+		errs = append(errs, fmt.Errorf("free buffer: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// EnqueueBlockCyclic submits data as the contents of a cyclic TX block, which
+// the device repeats until the buffer is disabled. block is the block code
+// (buffer id << 16 | block index) of a block on an allocated buffer.
+//
+// Returns an error for empty data, a transport failure or a negative device
+// response.
+func (m *Manager) EnqueueBlockCyclic(dev uint8, block int32, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("EnqueueBlockCyclic: no data")
+	}
+	if _, err := m.Call(iiodwire.OpEnqueueBlockCyclic, dev, block, iiodwire.U64(uint64(len(data))), data); err != nil {
+		return fmt.Errorf("EnqueueBlockCyclic: %w", err)
+	}
+	return nil
+}
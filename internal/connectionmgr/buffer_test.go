@@ -0,0 +1,232 @@
+package connectionmgr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+func TestBufferASCII(t *testing.T) {
+	payload := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	client, responder := newASCIIMockResponder(t, []asciiMockStep{
+		{name: "OPEN", expectLine: "OPEN cf-ad9361-lpc 2 0x00000003\r\n", responseStatus: intPtr(0)},
+		newReadbufStep("cf-ad9361-lpc", 8, "00000003", payload),
+		{name: "WRITEBUF", expectLine: "WRITEBUF cf-ad9361-lpc 4\r\n", expectPayloadLen: 4, expectPayload: payload[:4], responseStatus: intPtr(4)},
+		{name: "CLOSE", expectLine: "CLOSE cf-ad9361-lpc\r\n", responseStatus: intPtr(0)},
+	})
+	mgr := &Manager{Mode: ModeASCII}
+	mgr.SetConn(client)
+
+	buf, err := mgr.OpenBuffer(BufferConfig{DeviceID: "cf-ad9361-lpc", Samples: 2, Mask: 0x3})
+	if err != nil {
+		t.Fatalf("OpenBuffer: %v", err)
+	}
+	if buf.Size() != 8 {
+		t.Fatalf("Size = %d, want 8", buf.Size())
+	}
+	got, err := buf.ReadSamples()
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("ReadSamples = %x, %v; want %x", got, err, payload)
+	}
+	if err := buf.WriteSamples(payload[:4]); err != nil {
+		t.Fatalf("WriteSamples: %v", err)
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	responder.wait(t)
+
+	if _, err := buf.ReadSamples(); err == nil {
+		t.Fatal("ReadSamples on a closed buffer succeeded")
+	}
+}
+
+func TestOpenBufferValidation(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	mgr := &Manager{Mode: ModeASCII, conn: client}
+
+	for _, cfg := range []BufferConfig{
+		{DeviceID: "cf-ad9361-lpc", Samples: 0, Mask: 0x3},
+		{DeviceID: "cf-ad9361-lpc", Samples: 16},
+		{Samples: 16, Mask: 0x3},
+	} {
+		if _, err := mgr.OpenBuffer(cfg); err == nil {
+			t.Fatalf("OpenBuffer(%+v) succeeded", cfg)
+		}
+	}
+}
+
+// binaryStep is one request of the binary mock: the opcode and code expected,
+// how many payload bytes follow the header and the response body after the
+// response header.
+type binaryStep struct {
+	op         uint8
+	code       int32
+	payloadLen int
+	response   []byte
+}
+
+func runBinaryMock(t *testing.T, conn net.Conn, steps []binaryStep) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		for i, step := range steps {
+			var hdr [8]byte
+			if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+				errCh <- fmt.Errorf("step %d: read header: %w", i, err)
+				return
+			}
+			code := int32(binary.BigEndian.Uint32(hdr[4:8]))
+			if hdr[2] != step.op || code != step.code {
+				errCh <- fmt.Errorf("step %d: got op 0x%02x code %d, want 0x%02x code %d", i, hdr[2], code, step.op, step.code)
+				return
+			}
+			if _, err := io.ReadFull(conn, make([]byte, step.payloadLen)); err != nil {
+				errCh <- fmt.Errorf("step %d: read payload: %w", i, err)
+				return
+			}
+			resp := append([]byte{0, 0, iiodwire.OpResponse, hdr[3], 0, 0, 0, 0}, step.response...)
+			if _, err := conn.Write(resp); err != nil {
+				errCh <- fmt.Errorf("step %d: write response: %w", i, err)
+				return
+			}
+		}
+	}()
+	return errCh
+}
+
+func TestBufferBinary(t *testing.T) {
+	payload := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	status := func(s int32) []byte { return iiodwire.I32(s) }
+	block := int32(1) << 16 // buffer id 1, block 0
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	errCh := runBinaryMock(t, server, []binaryStep{
+		{op: iiodwire.OpCreateBuffer, code: 1, payloadLen: 8, response: append(status(0), iiodwire.U32(1)...)},
+		{op: iiodwire.OpCreateBlock, code: block, payloadLen: 8, response: append(status(0), iiodwire.U32(0)...)},
+		{op: iiodwire.OpEnableBuffer, code: 1, response: status(0)},
+		{op: iiodwire.OpTransferBlock, code: block, payloadLen: 8, response: append(status(0), iiodwire.LPBytes(payload)...)},
+		{op: iiodwire.OpTransferBlock, code: block, payloadLen: 8 + len(payload), response: status(0)},
+		{op: iiodwire.OpDisableBuffer, code: 1, response: status(0)},
+		{op: iiodwire.OpFreeBlock, code: block, response: status(0)},
+		{op: iiodwire.OpFreeBuffer, code: 1, response: status(0)},
+	})
+	mgr := &Manager{Mode: ModeBinary, nextBufferID: 1}
+	mgr.SetConn(client)
+
+	buf, err := mgr.OpenBuffer(BufferConfig{DeviceIndex: 2, Samples: 2, Mask: 0x3})
+	if err != nil {
+		t.Fatalf("OpenBuffer: %v", err)
+	}
+	got, err := buf.ReadSamples()
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("ReadSamples = %x, %v; want %x", got, err, payload)
+	}
+	if err := buf.WriteSamples(payload); err != nil {
+		t.Fatalf("WriteSamples: %v", err)
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if mgr.nextBufferID != 2 {
+		t.Fatalf("nextBufferID = %d, want 2", mgr.nextBufferID)
+	}
+}
+
+func TestBufferBinaryCyclic(t *testing.T) {
+	payload := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	status := iiodwire.I32(0)
+	block := int32(1) << 16
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	// The buffer is enabled after the block is enqueued, not on open.
+	errCh := runBinaryMock(t, server, []binaryStep{
+		{op: iiodwire.OpCreateBuffer, code: 1, payloadLen: 8, response: append(status, iiodwire.U32(1)...)},
+		{op: iiodwire.OpCreateBlock, code: block, payloadLen: 8, response: append(status, iiodwire.U32(0)...)},
+		{op: iiodwire.OpEnqueueBlockCyclic, code: block, payloadLen: 8 + len(payload), response: status},
+		{op: iiodwire.OpEnableBuffer, code: 1, response: status},
+		{op: iiodwire.OpDisableBuffer, code: 1, response: status},
+		{op: iiodwire.OpFreeBlock, code: block, response: status},
+		{op: iiodwire.OpFreeBuffer, code: 1, response: status},
+	})
+	mgr := &Manager{Mode: ModeBinary, nextBufferID: 1}
+	mgr.SetConn(client)
+
+	buf, err := mgr.OpenBuffer(BufferConfig{Samples: 2, Mask: 0x3, Cyclic: true})
+	if err != nil {
+		t.Fatalf("OpenBuffer: %v", err)
+	}
+	if err := buf.WriteSamples(payload); err != nil {
+		t.Fatalf("WriteSamples: %v", err)
+	}
+	if err := buf.WriteSamples(payload); err == nil {
+		t.Fatal("second WriteSamples on a cyclic buffer succeeded")
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBufferBinaryCyclicClosedUnwritten(t *testing.T) {
+	status := iiodwire.I32(0)
+	block := int32(1) << 16
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	// A cyclic buffer that was never written is not enabled, so close only
+	// frees it.
+	errCh := runBinaryMock(t, server, []binaryStep{
+		{op: iiodwire.OpCreateBuffer, code: 1, payloadLen: 8, response: append(status, iiodwire.U32(1)...)},
+		{op: iiodwire.OpCreateBlock, code: block, payloadLen: 8, response: append(status, iiodwire.U32(0)...)},
+		{op: iiodwire.OpFreeBlock, code: block, response: status},
+		{op: iiodwire.OpFreeBuffer, code: 1, response: status},
+	})
+	mgr := &Manager{Mode: ModeBinary, nextBufferID: 1}
+	mgr.SetConn(client)
+
+	buf, err := mgr.OpenBuffer(BufferConfig{Samples: 2, Mask: 0x3, Cyclic: true})
+	if err != nil {
+		t.Fatalf("OpenBuffer: %v", err)
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBufferBinaryCreateRejected(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	errCh := runBinaryMock(t, server, []binaryStep{
+		{op: iiodwire.OpCreateBuffer, code: 0, payloadLen: 8, response: append(iiodwire.I32(-22), iiodwire.U32(0)...)},
+	})
+	mgr := &Manager{Mode: ModeBinary}
+	mgr.SetConn(client)
+	if _, err := mgr.OpenBuffer(BufferConfig{Samples: 2, Mask: 0x3}); err == nil {
+		t.Fatal("OpenBuffer succeeded despite a rejected CREATE_BUFFER")
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
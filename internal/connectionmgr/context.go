@@ -0,0 +1,284 @@
+package connectionmgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// The ...Context variants run a blocking operation under a context: each
+// read and write of the exchange gets the earlier of its timeout budget and
+// the context's deadline, and cancelling the context breaks a read or write
+// that is already blocked on the socket. The error then wraps the context's
+// error (context.Canceled, context.DeadlineExceeded or the cause).
+//
+// An exchange interrupted halfway leaves the rest of its response on the
+// wire, as a timeout does, so the connection must be closed afterwards.
+
+// withContext makes ctx the context of the reads and writes until the
+// returned function is called. As with withBudget, the context of an
+// enclosing operation takes precedence.
+func (m *Manager) withContext(ctx context.Context) (restore func()) {
+	m.deadlineMu.Lock()
+	defer m.deadlineMu.Unlock()
+	if m.ctx != nil || ctx.Done() == nil {
+		return func() {}
+	}
+	m.ctx = ctx
+	stop := context.AfterFunc(ctx, func() {
+		m.deadlineMu.Lock()
+		defer m.deadlineMu.Unlock()
+		// A late call must not break the next operation.
+		if m.ctx == ctx && m.conn != nil {
+			_ = m.conn.SetDeadline(time.Now())
+		}
+	})
+	return func() {
+		stop()
+		m.deadlineMu.Lock()
+		defer m.deadlineMu.Unlock()
+		m.ctx = nil
+	}
+}
+
+// opDeadline returns the socket deadline of the next read or write: the
+// budget of the operation in progress, cut short by the deadline of its
+// context. A context that has ended gives a deadline in the past. The caller
+// holds deadlineMu.
+func (m *Manager) opDeadline() time.Time {
+	deadline := time.Now().Add(m.opBudget())
+	if m.ctx == nil {
+		return deadline
+	}
+	if m.ctx.Err() != nil {
+		return time.Now()
+	}
+	if d, ok := m.ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// runContext runs op under ctx (see withContext). An error of an operation
+// cut short by ctx wraps the context's error.
+func runContext[T any](ctx context.Context, m *Manager, op func() (T, error)) (T, error) {
+	if m == nil {
+		return op()
+	}
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, context.Cause(ctx)
+	}
+	restore := m.withContext(ctx)
+	v, err := op()
+	restore()
+	if err == nil {
+		return v, nil
+	}
+	if ctx.Err() != nil {
+		return v, fmt.Errorf("%w: %w", context.Cause(ctx), err)
+	}
+	// The socket deadline may fire an instant before the context's timer.
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+		return v, fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return v, err
+}
+
+// Do runs op, a sequence of operations on m, under ctx. It covers the
+// operations without a Context variant of their own.
+func (m *Manager) Do(ctx context.Context, op func() error) error {
+	_, err := runContext(ctx, m, func() (struct{}, error) { return struct{}{}, op() })
+	return err
+}
+
+// ---------- Text protocol ----------
+
+// ExecCommandContext is ExecCommand under ctx.
+func (m *Manager) ExecCommandContext(ctx context.Context, cmd string) (int, error) {
+	return runContext(ctx, m, func() (int, error) { return m.ExecCommand(cmd) })
+}
+
+// ExecASCIIContext is ExecASCII under ctx.
+func (m *Manager) ExecASCIIContext(ctx context.Context, cmd string) (int, error) {
+	return m.ExecCommandContext(ctx, cmd)
+}
+
+// GetVersionASCIIContext is GetVersionASCII under ctx.
+func (m *Manager) GetVersionASCIIContext(ctx context.Context) (string, error) {
+	return runContext(ctx, m, m.GetVersionASCII)
+}
+
+// GetContextXMLASCIIContext is GetContextXMLASCII under ctx.
+func (m *Manager) GetContextXMLASCIIContext(ctx context.Context) ([]byte, error) {
+	return runContext(ctx, m, m.GetContextXMLASCII)
+}
+
+// PrintASCIIContext is PrintASCII under ctx.
+func (m *Manager) PrintASCIIContext(ctx context.Context) ([]byte, error) {
+	return runContext(ctx, m, m.PrintASCII)
+}
+
+// ReadDeviceAttrASCIIContext is ReadDeviceAttrASCII under ctx.
+func (m *Manager) ReadDeviceAttrASCIIContext(ctx context.Context, devID, attr string) (string, error) {
+	return runContext(ctx, m, func() (string, error) { return m.ReadDeviceAttrASCII(devID, attr) })
+}
+
+// ReadChannelAttrASCIIContext is ReadChannelAttrASCII under ctx.
+func (m *Manager) ReadChannelAttrASCIIContext(ctx context.Context, devID string, isOutput bool, chanID, attr string) (string, error) {
+	return runContext(ctx, m, func() (string, error) { return m.ReadChannelAttrASCII(devID, isOutput, chanID, attr) })
+}
+
+// ReadBufferAttrASCIIContext is ReadBufferAttrASCII under ctx.
+func (m *Manager) ReadBufferAttrASCIIContext(ctx context.Context, devID, attr string) (string, error) {
+	return runContext(ctx, m, func() (string, error) { return m.ReadBufferAttrASCII(devID, attr) })
+}
+
+// ReadDebugAttrASCIIContext is ReadDebugAttrASCII under ctx.
+func (m *Manager) ReadDebugAttrASCIIContext(ctx context.Context, devID, attr string) (string, error) {
+	return runContext(ctx, m, func() (string, error) { return m.ReadDebugAttrASCII(devID, attr) })
+}
+
+// WriteDeviceAttrASCIIContext is WriteDeviceAttrASCII under ctx.
+func (m *Manager) WriteDeviceAttrASCIIContext(ctx context.Context, devID, attr, value string) (int, error) {
+	return runContext(ctx, m, func() (int, error) { return m.WriteDeviceAttrASCII(devID, attr, value) })
+}
+
+// WriteChannelAttrASCIIContext is WriteChannelAttrASCII under ctx.
+func (m *Manager) WriteChannelAttrASCIIContext(ctx context.Context, devID string, isOutput bool, chanID, attr, value string) (int, error) {
+	return runContext(ctx, m, func() (int, error) { return m.WriteChannelAttrASCII(devID, isOutput, chanID, attr, value) })
+}
+
+// WriteBufferAttrASCIIContext is WriteBufferAttrASCII under ctx.
+func (m *Manager) WriteBufferAttrASCIIContext(ctx context.Context, devID, attr string, payload []byte) (int, error) {
+	return runContext(ctx, m, func() (int, error) { return m.WriteBufferAttrASCII(devID, attr, payload) })
+}
+
+// WriteDebugAttrASCIIContext is WriteDebugAttrASCII under ctx.
+func (m *Manager) WriteDebugAttrASCIIContext(ctx context.Context, devID, attr string, payload []byte) (int, error) {
+	return runContext(ctx, m, func() (int, error) { return m.WriteDebugAttrASCII(devID, attr, payload) })
+}
+
+// OpenBufferASCIIContext is OpenBufferASCII under ctx.
+func (m *Manager) OpenBufferASCIIContext(ctx context.Context, deviceID string, samples uint64, maskHex string, cyclic bool) error {
+	return m.Do(ctx, func() error { return m.OpenBufferASCII(deviceID, samples, maskHex, cyclic) })
+}
+
+// ReadBufferASCIIContext is ReadBufferASCII under ctx.
+func (m *Manager) ReadBufferASCIIContext(ctx context.Context, deviceID string, dst []byte) (int, error) {
+	return runContext(ctx, m, func() (int, error) { return m.ReadBufferASCII(deviceID, dst) })
+}
+
+// WriteBufferASCIIContext is WriteBufferASCII under ctx.
+func (m *Manager) WriteBufferASCIIContext(ctx context.Context, deviceID string, payload []byte) (int, error) {
+	return runContext(ctx, m, func() (int, error) { return m.WriteBufferASCII(deviceID, payload) })
+}
+
+// CloseBufferASCIIContext is CloseBufferASCII under ctx.
+func (m *Manager) CloseBufferASCIIContext(ctx context.Context, deviceID string) error {
+	return m.Do(ctx, func() error { return m.CloseBufferASCII(deviceID) })
+}
+
+// ---------- Binary protocol ----------
+
+// CallContext is Call under ctx.
+func (m *Manager) CallContext(ctx context.Context, opcode, dev uint8, code int32, payloads ...[]byte) (iiodwire.Response, error) {
+	return runContext(ctx, m, func() (iiodwire.Response, error) { return m.Call(opcode, dev, code, payloads...) })
+}
+
+// CallShapeContext is CallShape under ctx.
+func (m *Manager) CallShapeContext(ctx context.Context, shape iiodwire.Shape, opcode, dev uint8, code int32, payloads ...[]byte) (iiodwire.Response, error) {
+	return runContext(ctx, m, func() (iiodwire.Response, error) { return m.CallShape(shape, opcode, dev, code, payloads...) })
+}
+
+// CallBatchContext is CallBatch under ctx.
+func (m *Manager) CallBatchContext(ctx context.Context, cmds []Command) ([]iiodwire.Response, []error) {
+	if ctx.Err() != nil {
+		errs := make([]error, len(cmds))
+		for i := range errs {
+			errs[i] = context.Cause(ctx)
+		}
+		return make([]iiodwire.Response, len(cmds)), errs
+	}
+	if m == nil {
+		return m.CallBatch(cmds)
+	}
+	restore := m.withContext(ctx)
+	resps, errs := m.CallBatch(cmds)
+	restore()
+	if ctx.Err() != nil {
+		for i, err := range errs {
+			if err != nil {
+				errs[i] = fmt.Errorf("%w: %w", context.Cause(ctx), err)
+			}
+		}
+	}
+	return resps, errs
+}
+
+// GetXMLContext is GetXML under ctx.
+func (m *Manager) GetXMLContext(ctx context.Context, dev uint8) ([]byte, error) {
+	return runContext(ctx, m, func() ([]byte, error) { return m.GetXML(dev) })
+}
+
+// ---------- Session, buffers and events ----------
+
+// DialContext is Dial with a connect and negotiation that end with ctx.
+func DialContext(ctx context.Context, addr string) (*Manager, error) {
+	m := New(addr)
+	if err := m.ConnectContext(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := m.NegotiateContext(ctx); err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// NegotiateContext is Negotiate under ctx.
+func (m *Manager) NegotiateContext(ctx context.Context) (Mode, error) {
+	return runContext(ctx, m, m.Negotiate)
+}
+
+// OpenBufferContext is OpenBuffer under ctx.
+func (m *Manager) OpenBufferContext(ctx context.Context, cfg BufferConfig) (*Buffer, error) {
+	defer m.exclusive()()
+	return runContext(ctx, m, func() (*Buffer, error) { return m.openBuffer(cfg) })
+}
+
+// ReadSamplesContext is ReadSamples under ctx.
+func (b *Buffer) ReadSamplesContext(ctx context.Context) ([]byte, error) {
+	if b == nil || !b.open {
+		return b.ReadSamples()
+	}
+	defer b.m.exclusive()()
+	return runContext(ctx, b.m, b.readSamples)
+}
+
+// WriteSamplesContext is WriteSamples under ctx.
+func (b *Buffer) WriteSamplesContext(ctx context.Context, data []byte) error {
+	if b == nil || !b.open {
+		return b.WriteSamples(data)
+	}
+	defer b.m.exclusive()()
+	return b.m.Do(ctx, func() error { return b.writeSamples(data) })
+}
+
+// ReadContext is Read under ctx, for a caller that waits on a quiet device
+// longer than the stream budget allows only until it stops listening.
+func (s *EventStream) ReadContext(ctx context.Context) (Event, error) {
+	if s == nil || s.m == nil {
+		return s.Read()
+	}
+	defer s.m.exclusive()()
+	return runContext(ctx, s.m, s.read)
+}
+
+// OpenEventStreamContext is OpenEventStream under ctx.
+func (m *Manager) OpenEventStreamContext(ctx context.Context, dev uint8) (*EventStream, error) {
+	defer m.exclusive()()
+	return runContext(ctx, m, func() (*EventStream, error) { return m.openEventStream(dev) })
+}
@@ -0,0 +1,117 @@
+package connectionmgr
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// TestContextInterruptsExchange runs exchanges against a server that reads
+// the command and never answers: the context, not the 5 s control budget,
+// must end them.
+func TestContextInterruptsExchange(t *testing.T) {
+	ops := []struct {
+		name string
+		mode Mode
+		run  func(ctx context.Context, m *Manager) error
+	}{
+		{name: "text", mode: ModeASCII, run: func(ctx context.Context, m *Manager) error {
+			_, err := m.ExecCommandContext(ctx, "VERSION")
+			return err
+		}},
+		{name: "binary", mode: ModeBinary, run: func(ctx context.Context, m *Manager) error {
+			_, err := m.CallContext(ctx, iiodwire.OpPrint, 0, 0)
+			return err
+		}},
+	}
+	ends := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want error
+	}{
+		{name: "cancel", want: context.Canceled, ctx: func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(30*time.Millisecond, cancel)
+			return ctx, cancel
+		}},
+		{name: "deadline", want: context.DeadlineExceeded, ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 30*time.Millisecond)
+		}},
+	}
+	for _, op := range ops {
+		for _, end := range ends {
+			t.Run(op.name+"/"+end.name, func(t *testing.T) {
+				client, server := net.Pipe()
+				defer client.Close()
+				defer server.Close()
+				go func() { _, _ = io.Copy(io.Discard, server) }()
+				m := &Manager{Mode: op.mode, Timeouts: TimeoutPolicy{Control: 5 * time.Second}}
+				m.SetConn(client)
+
+				ctx, cancel := end.ctx()
+				defer cancel()
+				start := time.Now()
+				err := op.run(ctx, m)
+				if !errors.Is(err, end.want) {
+					t.Fatalf("err = %v, want %v", err, end.want)
+				}
+				if elapsed := time.Since(start); elapsed > time.Second {
+					t.Fatalf("exchange ended after %v", elapsed)
+				}
+			})
+		}
+	}
+}
+
+func TestContextEndedBeforeExchange(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	m := &Manager{Timeouts: TimeoutPolicy{Control: 5 * time.Second}}
+	m.SetConn(client)
+
+	// Nothing reads the pipe, so a write would block until the budget ends.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.ExecCommandContext(ctx, "VERSION"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecCommandContext err = %v, want context.Canceled", err)
+	}
+	if err := New("127.0.0.1:1").ConnectContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ConnectContext err = %v, want context.Canceled", err)
+	}
+}
+
+// TestContextReleasedAfterExchange cancels the context of a finished
+// exchange: the next exchange, without a context, must not be interrupted.
+func TestContextReleasedAfterExchange(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		br := bufio.NewReader(server)
+		for {
+			if _, err := br.ReadString('\n'); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+			_, _ = server.Write([]byte("0\n"))
+		}
+	}()
+	m := &Manager{Timeouts: TimeoutPolicy{Control: time.Second}}
+	m.SetConn(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if ret, err := m.ExecCommandContext(ctx, "TIMEOUT 1"); err != nil || ret != 0 {
+		t.Fatalf("ExecCommandContext = %d, %v", ret, err)
+	}
+	cancel()
+	if ret, err := m.ExecCommand("TIMEOUT 1"); err != nil || ret != 0 {
+		t.Fatalf("ExecCommand after cancel = %d, %v", ret, err)
+	}
+}
@@ -0,0 +1,84 @@
+package connectionmgr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// eventSize is the size of a struct iio_event.
+const eventSize = iiodwire.EventSize
+
+// Event is one IIO event, such as a threshold crossing reported by a driver.
+type Event = iiodwire.Event
+
+// EventStream is an open event stream of one device on the binary protocol.
+//
+// Protocol:
+//   - CREATE_EVSTREAM: code = stream id.
+//   - READ_EVENT: code = stream id; the event comes back length-prefixed.
+//   - FREE_EVSTREAM: code = stream id.
+type EventStream struct {
+	m   *Manager
+	dev uint8
+	id  int32
+}
+
+// OpenEventStream opens the event stream of device index dev. Stream ids
+// share the counter of the binary buffers.
+//
+// Returns the stream or an error outside binary mode, for a transport failure
+// or a negative device response.
+func (m *Manager) OpenEventStream(dev uint8) (*EventStream, error) {
+	defer m.exclusive()()
+	return m.openEventStream(dev)
+}
+
+func (m *Manager) openEventStream(dev uint8) (*EventStream, error) {
+	if m == nil || m.conn == nil {
+		return nil, errors.New("OpenEventStream: not connected")
+	}
+	if m.Mode != ModeBinary {
+		return nil, errors.New("OpenEventStream: event streams need the binary protocol")
+	}
+	s := &EventStream{m: m, dev: dev, id: int32(m.nextBufferID)}
+	m.nextBufferID++
+	if _, err := m.Call(iiodwire.OpCreateEvStream, dev, s.id); err != nil {
+		return nil, fmt.Errorf("OpenEventStream: %w", err)
+	}
+	return s, nil
+}
+
+// Read waits for the next event. It runs under the stream budget of the
+// timeout policy; a quiet device makes it fail with a timeout, after which
+// the connection must be closed.
+func (s *EventStream) Read() (Event, error) {
+	if s == nil || s.m == nil {
+		return Event{}, errors.New("event stream not open")
+	}
+	defer s.m.exclusive()()
+	return s.read()
+}
+
+func (s *EventStream) read() (Event, error) {
+	resp, err := s.m.Call(iiodwire.OpReadEvent, s.dev, s.id)
+	if err != nil {
+		return Event{}, fmt.Errorf("read event: %w", err)
+	}
+	return iiodwire.DecodeEvent(resp.Data)
+}
+
+// Close frees the stream on the server. Closing a closed stream is a no-op.
+func (s *EventStream) Close() error {
+	if s == nil || s.m == nil {
+		return nil
+	}
+	m := s.m
+	s.m = nil
+	defer m.exclusive()()
+	if _, err := m.Call(iiodwire.OpFreeEvStream, s.dev, s.id); err != nil {
+		return fmt.Errorf("free event stream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package connectionmgr
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+func TestEventStreamBinary(t *testing.T) {
+	// A rising threshold event on voltage channel 1.
+	id := uint64(0)<<56 | uint64(1)<<48 | uint64(0)<<32 | 1
+	raw := make([]byte, eventSize)
+	binary.LittleEndian.PutUint64(raw[0:8], id)
+	binary.LittleEndian.PutUint64(raw[8:16], 1234567890)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	errCh := runBinaryMock(t, server, []binaryStep{
+		{op: iiodwire.OpCreateEvStream, code: 3, response: append(iiodwire.I32(0), iiodwire.U32(0)...)},
+		{op: iiodwire.OpReadEvent, code: 3, response: append(iiodwire.I32(0), iiodwire.LPBytes(raw)...)},
+		{op: iiodwire.OpFreeEvStream, code: 3, response: iiodwire.I32(0)},
+	})
+	mgr := &Manager{Mode: ModeBinary, nextBufferID: 3}
+	mgr.SetConn(client)
+
+	s, err := mgr.OpenEventStream(2)
+	if err != nil {
+		t.Fatalf("OpenEventStream: %v", err)
+	}
+	ev, err := s.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if ev.ID != id || ev.Timestamp != 1234567890 || ev.Direction() != 1 || ev.Channel() != 1 || ev.Type() != 0 {
+		t.Fatalf("event = %+v", ev)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenEventStreamNeedsBinary(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	mgr := &Manager{Mode: ModeASCII}
+	mgr.SetConn(client)
+	if _, err := mgr.OpenEventStream(0); err == nil {
+		t.Fatal("OpenEventStream in ASCII mode succeeded")
+	}
+}
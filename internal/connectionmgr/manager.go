@@ -2,11 +2,13 @@ package connectionmgr
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/rjboer/GoSDR/internal/sdrxml"
@@ -23,12 +25,25 @@ type Manager struct {
 	Address    string
 	Mode       Mode
 	byteStream chan []byte
-	Timeout    time.Duration
+	// Timeouts is the timeout policy; zero fields take the defaults.
+	Timeouts   TimeoutPolicy
 	Logger     *log.Logger
 	ClientInfo ClientInfo_type
 	clientID   uint16 // libiio client identifier (0 unless multiplexing is added)
 	// nextBufferID increments for each newly created binary buffer.
 	nextBufferID uint16
+	// budget is the socket deadline of the operation in progress (see
+	// withBudget); 0 selects the control budget.
+	budget time.Duration
+	// ctx is the context of the operation in progress (see withContext);
+	// nil when the operation runs without one.
+	ctx context.Context
+	// deadlineMu orders the deadlines of the operation against the one set
+	// when its context ends.
+	deadlineMu sync.Mutex
+	// owner is the lock of the client whose connection the Manager is
+	// attached to (see Attach); nil for a Manager with its own connection.
+	owner sync.Locker
 
 	conn net.Conn
 	br   *bufio.Reader
@@ -45,14 +60,20 @@ var errBinaryRejected = errors.New("BINARY command rejected by server")
 
 func New(addr string) *Manager {
 	return &Manager{
-		Address: addr,
-		Mode:    ModeASCII,
-		Timeout: 5 * time.Second,
+		Address:  addr,
+		Mode:     ModeASCII,
+		Timeouts: DefaultTimeoutPolicy(),
 	}
 }
 
 func (m *Manager) Connect() error {
-	c, err := net.DialTimeout("tcp", m.Address, m.Timeout)
+	return m.ConnectContext(context.Background())
+}
+
+// ConnectContext is Connect with a dial that ends with ctx.
+func (m *Manager) ConnectContext(ctx context.Context) error {
+	d := net.Dialer{Timeout: m.timeouts().Connect}
+	c, err := d.DialContext(ctx, "tcp", m.Address)
 	if err != nil {
 		return fmt.Errorf("connect failed: %w", err)
 	}
@@ -74,6 +95,39 @@ func (m *Manager) ReadByte() error {
 	return nil
 }
 
+// Attach returns a Manager speaking mode over conn, a connection that another
+// client dialed and keeps using. Reads go through br, that client's reader,
+// so the bytes it has buffered stay in order.
+//
+// The buffer and event stream methods hold owner, the client's lock, for
+// each exchange and clear the socket deadlines when they are done; around the
+// other methods the client holds owner itself.
+func Attach(conn net.Conn, br *bufio.Reader, mode Mode, owner sync.Locker) *Manager {
+	return &Manager{
+		Mode:     mode,
+		Timeouts: DefaultTimeoutPolicy(),
+		owner:    owner,
+		conn:     conn,
+		br:       br,
+	}
+}
+
+// exclusive holds the owner's lock of an attached Manager until the returned
+// function is called, which also clears the deadlines the exchange left on
+// the socket. It does nothing for a Manager with its own connection.
+func (m *Manager) exclusive() (release func()) {
+	if m == nil || m.owner == nil {
+		return func() {}
+	}
+	m.owner.Lock()
+	return func() {
+		if m.conn != nil {
+			_ = m.conn.SetDeadline(time.Time{})
+		}
+		m.owner.Unlock()
+	}
+}
+
 // Safe reinjection (tests, SSH tunnels, etc.)
 func (m *Manager) SetConn(conn net.Conn) {
 	m.conn = conn
@@ -87,11 +141,13 @@ func (m *Manager) Close() error {
 	return nil
 }
 
+// SetTimeout sets the connect, control and stream budgets of the timeout
+// policy to d, for callers that want a single value. The remote TIMEOUT
+// follows from the stream budget unless Timeouts.Remote is set.
 func (m *Manager) SetTimeout(d time.Duration) {
-	m.Timeout = d
-	if m.conn != nil && d > 0 {
-		_ = m.conn.SetDeadline(time.Now().Add(d))
-	}
+	m.Timeouts.Connect = d
+	m.Timeouts.Control = d
+	m.Timeouts.Stream = d
 }
 
 // ---------- Logging ----------
@@ -122,17 +178,23 @@ func (m *Manager) SetClientID(id uint16) {
 
 // ---------- Raw I/O (NO BUFFERING) ----------
 
-// applyReadDeadline applies the configured read timeout to the socket.
+// applyReadDeadline applies the budget of the operation in progress to the
+// socket's reads.
 func (m *Manager) applyReadDeadline() {
 	if m.conn != nil {
-		_ = m.conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		m.deadlineMu.Lock()
+		defer m.deadlineMu.Unlock()
+		_ = m.conn.SetReadDeadline(m.opDeadline())
 	}
 }
 
-// applyWriteDeadline applies the configured write timeout to the socket.
+// applyWriteDeadline applies the budget of the operation in progress to the
+// socket's writes.
 func (m *Manager) applyWriteDeadline() {
-	if m.conn != nil && m.Timeout > 0 {
-		_ = m.conn.SetWriteDeadline(time.Now().Add(m.Timeout))
+	if m.conn != nil {
+		m.deadlineMu.Lock()
+		defer m.deadlineMu.Unlock()
+		_ = m.conn.SetWriteDeadline(m.opDeadline())
 	}
 }
 
@@ -251,13 +313,10 @@ func (m *Manager) TryUpgradeToBinary() (bool, error) {
 }
 
 // EnterBinaryMode sends the BINARY command and marks the Manager as binary-only.
-// After a successful switch, ASCII helpers must not be used.
+// After a successful switch, ASCII helpers must not be used. libiio 1.x
+// servers answer 0; older servers reject the command with a negative errno,
+// which is returned wrapping errBinaryRejected.
 func (m *Manager) EnterBinaryMode() error {
-	fmt.Println("EnterBinaryMode Command")
-	fmt.Println(`This command is obsolete,The server starts in ASCII
-It implicitly switches to binary when it receives a binary command header
-There is no ASCII “BINARY” state transition anymore`)
-	fmt.Println("It will work for very old servers")
 	if m == nil {
 		return fmt.Errorf("nil Manager")
 	}
@@ -274,7 +333,7 @@ There is no ASCII “BINARY” state transition anymore`)
 	}
 
 	if ret != 0 {
-		return fmt.Errorf("BINARY command rejected:  %w, returncode:%d", err, ret)
+		return fmt.Errorf("%w: returncode:%d", errBinaryRejected, ret)
 	}
 
 	m.Mode = ModeBinary
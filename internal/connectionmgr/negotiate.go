@@ -0,0 +1,70 @@
+package connectionmgr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// ServerVersion is the IIOD version a server reports to VERSION.
+type ServerVersion = iiodwire.ServerVersion
+
+// ParseServerVersion parses the reply to VERSION (see
+// iiodwire.ParseServerVersion).
+func ParseServerVersion(s string) (ServerVersion, error) {
+	return iiodwire.ParseServerVersion(s)
+}
+
+// Dial connects to addr and negotiates the protocol (see Negotiate).
+func Dial(addr string) (*Manager, error) {
+	m := New(addr)
+	if err := m.Connect(); err != nil {
+		return nil, err
+	}
+	if _, err := m.Negotiate(); err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Negotiate selects the protocol of a fresh connection. It asks for the
+// server version over the text protocol, which every IIOD server speaks, and
+// switches a libiio 1.x server to the binary protocol with BINARY. Older
+// servers, and servers that refuse BINARY, stay on the text protocol. The
+// version is kept in ClientInfo.Version.
+//
+// Returns the selected mode, or an error for a transport failure or a
+// malformed version.
+func (m *Manager) Negotiate() (Mode, error) {
+	if m == nil || m.conn == nil {
+		return ModeASCII, errors.New("Negotiate: not connected")
+	}
+	if m.Mode == ModeBinary {
+		return ModeBinary, nil
+	}
+	raw, err := m.GetVersionASCII()
+	if err != nil {
+		return ModeASCII, fmt.Errorf("Negotiate: %w", err)
+	}
+	version, err := ParseServerVersion(raw)
+	if err != nil {
+		return ModeASCII, fmt.Errorf("Negotiate: %w", err)
+	}
+	m.ClientInfo.Version = raw
+	if !version.SupportsBinary() {
+		m.logf("IIOD %s: text protocol", version)
+		return ModeASCII, nil
+	}
+	ok, err := m.TryUpgradeToBinary()
+	if err != nil {
+		return ModeASCII, fmt.Errorf("Negotiate: %w", err)
+	}
+	if !ok {
+		m.logf("IIOD %s refused BINARY: text protocol", version)
+		return ModeASCII, nil
+	}
+	m.logf("IIOD %s: binary protocol", version)
+	return ModeBinary, nil
+}
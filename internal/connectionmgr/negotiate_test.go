@@ -0,0 +1,91 @@
+package connectionmgr
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ServerVersion
+		binary  bool
+		wantErr bool
+	}{
+		{in: "0.25.gb6028fd\n", want: ServerVersion{Major: 0, Minor: 25, Git: "gb6028fd"}},
+		{in: "1.0.abc1234", want: ServerVersion{Major: 1, Minor: 0, Git: "abc1234"}, binary: true},
+		{in: "v1.1", want: ServerVersion{Major: 1, Minor: 1}, binary: true},
+		{in: "garbage", wantErr: true},
+		{in: "1.x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseServerVersion(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && (got != tt.want || got.SupportsBinary() != tt.binary) {
+				t.Fatalf("ParseServerVersion(%q) = %+v, want %+v (binary %t)", tt.in, got, tt.want, tt.binary)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		binary   string // reply to BINARY; empty expects no BINARY
+		want     Mode
+		wantErr  bool
+		wantCmds []string
+	}{
+		{name: "legacy", version: "0.25.gb6028fd", want: ModeASCII, wantCmds: []string{"VERSION"}},
+		{name: "libiio 1.x", version: "1.0.abc1234", binary: "0", want: ModeBinary, wantCmds: []string{"VERSION", "BINARY"}},
+		{name: "refused", version: "1.0.abc1234", binary: "-22", want: ModeASCII, wantCmds: []string{"VERSION", "BINARY"}},
+		{name: "malformed", version: "iiod", want: ModeASCII, wantErr: true, wantCmds: []string{"VERSION"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			cmds := make(chan []string, 1)
+			go func() {
+				defer server.Close()
+				var got []string
+				br := bufio.NewReader(server)
+				for range tt.wantCmds {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						break
+					}
+					cmd := strings.TrimSpace(line)
+					got = append(got, cmd)
+					reply := tt.version
+					if cmd == "BINARY" {
+						reply = tt.binary
+					}
+					if _, err := server.Write([]byte(reply + "\n")); err != nil {
+						break
+					}
+				}
+				cmds <- got
+			}()
+			m := New("pipe")
+			m.SetConn(client)
+
+			mode, err := m.Negotiate()
+			if (err != nil) != tt.wantErr || mode != tt.want || m.Mode != tt.want {
+				t.Fatalf("Negotiate = %v, %v (Mode %v); want %v, error %t", mode, err, m.Mode, tt.want, tt.wantErr)
+			}
+			if got := <-cmds; strings.Join(got, ",") != strings.Join(tt.wantCmds, ",") {
+				t.Fatalf("commands = %v, want %v", got, tt.wantCmds)
+			}
+			if !tt.wantErr && m.ClientInfo.Version != tt.version {
+				t.Fatalf("ClientInfo.Version = %q, want %q", m.ClientInfo.Version, tt.version)
+			}
+		})
+	}
+}
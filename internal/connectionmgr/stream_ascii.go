@@ -29,8 +29,8 @@ type StreamASCIIConfig struct {
 	// In practice, keep this true unless you implement a pool in the consumer.
 	CopyOut bool
 
-	// ReadTimeoutPerChunk overrides socket read deadline per READBUF transaction (optional).
-	// If zero, Manager.Timeout is used.
+	// ReadTimeoutPerChunk overrides the socket deadline per READBUF transaction (optional).
+	// If zero, the stream budget of Manager.Timeouts is used.
 	ReadTimeoutPerChunk time.Duration
 
 	// LogPrefix is included in logs for correlation.
@@ -109,6 +109,9 @@ func (m *Manager) StartStreamASCII(parent context.Context, cfg StreamASCIIConfig
 	h.wg.Add(1)
 	go func() {
 		defer h.wg.Done()
+		// The per-chunk override takes precedence over the stream budget
+		// ReadBufferASCII would apply.
+		defer m.withBudget(cfg.ReadTimeoutPerChunk)()
 
 		pfx := cfg.LogPrefix
 		if pfx == "" {
@@ -130,13 +133,6 @@ func (m *Manager) StartStreamASCII(parent context.Context, cfg StreamASCIIConfig
 			default:
 			}
 
-			// Optional per-transaction deadline tightening.
-			if cfg.ReadTimeoutPerChunk > 0 {
-				_ = m.conn.SetReadDeadline(time.Now().Add(cfg.ReadTimeoutPerChunk))
-			} else if m.Timeout > 0 {
-				_ = m.conn.SetReadDeadline(time.Now().Add(m.Timeout))
-			}
-
 			// Perform one READBUF transaction.
 			// IMPORTANT: ReadBufferASCII must stop when it has read the requested length
 			// (do NOT wait for a trailing "0" chunk, because servers may keep streaming).
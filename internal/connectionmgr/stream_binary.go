@@ -0,0 +1,412 @@
+package connectionmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// DefaultStreamDepth is the number of blocks a binary stream keeps in flight
+// when StreamConfig.Depth is zero. One block serialises transfer and network
+// round trip; a few overlap them.
+const DefaultStreamDepth = 4
+
+// streamClientBase is the client ID of a stream's first block. Block i sends
+// its transfers as client streamClientBase+i; the server echoes the ID in the
+// response, which matches replies to blocks.
+const streamClientBase = 0x100
+
+// StreamConfig controls a pipelined binary stream (StartRXStream,
+// StartTXStream).
+type StreamConfig struct {
+	// Buffer selects the device and channels. Samples is per block.
+	Buffer BufferConfig
+
+	// Depth is the number of blocks rotated through the device, all of
+	// them in flight at once. 0 selects DefaultStreamDepth.
+	Depth int
+
+	// Out receives RX blocks; each is a fresh slice the caller may retain.
+	Out chan<- []byte
+
+	// DropIfFull drops an RX block when Out is full instead of blocking,
+	// which would stall the pipeline.
+	DropIfFull bool
+
+	// In supplies TX blocks of at most one block size each. The stream
+	// ends when In is closed.
+	In <-chan []byte
+
+	// LogPrefix is included in logs for correlation.
+	LogPrefix string
+}
+
+// StreamStats counts the traffic of a binary stream.
+type StreamStats struct {
+	Blocks  uint64        // completed block transfers
+	Bytes   uint64        // payload bytes transferred
+	Dropped uint64        // RX blocks dropped because Out was full
+	Elapsed time.Duration // since the stream started, until it ended
+}
+
+// BytesPerSecond returns the average payload throughput.
+func (s StreamStats) BytesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// StreamHandle controls a running binary stream.
+type StreamHandle struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	errMu  sync.Mutex
+	err    error
+
+	start   time.Time
+	end     atomic.Int64 // UnixNano when the stream ended, 0 while running
+	blocks  atomic.Uint64
+	bytes   atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// Stop ends the stream, waits for the blocks in flight and frees the buffer.
+func (h *StreamHandle) Stop() {
+	if h == nil {
+		return
+	}
+	h.cancel()
+	h.wg.Wait()
+}
+
+// Wait blocks until the stream has ended, through Stop, the end of its
+// parent context, a closed In channel or a transport error, and its buffer
+// is freed, and returns the stream's error. An owner that ends the stream by
+// canceling the parent context uses Wait to keep the stream from outliving
+// it.
+func (h *StreamHandle) Wait() error {
+	if h == nil {
+		return nil
+	}
+	h.wg.Wait()
+	return h.Err()
+}
+
+// Err returns the first error of the stream, if any.
+func (h *StreamHandle) Err() error {
+	if h == nil {
+		return nil
+	}
+	h.errMu.Lock()
+	defer h.errMu.Unlock()
+	return h.err
+}
+
+func (h *StreamHandle) setErr(err error) {
+	h.errMu.Lock()
+	defer h.errMu.Unlock()
+	if h.err == nil {
+		h.err = err
+	}
+}
+
+// Stats returns the traffic counters of the stream.
+func (h *StreamHandle) Stats() StreamStats {
+	end := time.Now()
+	if ns := h.end.Load(); ns != 0 {
+		end = time.Unix(0, ns)
+	}
+	return StreamStats{
+		Blocks:  h.blocks.Load(),
+		Bytes:   h.bytes.Load(),
+		Dropped: h.dropped.Load(),
+		Elapsed: end.Sub(h.start),
+	}
+}
+
+// blockStream is the state of one binary stream: a buffer with a pool of
+// blocks, each of which is either idle or has a transfer in flight.
+type blockStream struct {
+	m          *Manager
+	cfg        StreamConfig
+	h          *StreamHandle
+	dev        uint8
+	id         uint16
+	blockBytes int
+	codes      []int32 // block code per pool index
+	lens       []int   // bytes of the TX transfer in flight per pool index
+	inFlight   int
+	broken     bool // transport failed; the stream is no longer aligned
+	pfx        string
+}
+
+// StartRXStream opens a buffer with a pool of cfg.Depth blocks and keeps a
+// TRANSFER_BLOCK in flight for each, delivering completed blocks to cfg.Out
+// and resubmitting them, so the device never waits for a network round trip.
+//
+// The stream owns the connection until Stop; the Manager must be in
+// ModeBinary. Returns an error for an invalid configuration or when the
+// buffer cannot be allocated.
+func (m *Manager) StartRXStream(parent context.Context, cfg StreamConfig) (*StreamHandle, error) {
+	if cfg.Out == nil {
+		return nil, errors.New("StartRXStream: Out channel is required")
+	}
+	s, err := m.openBlockStream(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("StartRXStream: %w", err)
+	}
+	return s.run(parent, s.rx), nil
+}
+
+// StartTXStream opens a buffer with a pool of cfg.Depth blocks and submits
+// each block read from cfg.In on an idle block, waiting for a block to
+// complete only when all of them are in flight. The stream ends when cfg.In
+// is closed or on Stop.
+//
+// The stream owns the connection until it ends; the Manager must be in
+// ModeBinary. Returns an error for an invalid configuration or when the
+// buffer cannot be allocated.
+func (m *Manager) StartTXStream(parent context.Context, cfg StreamConfig) (*StreamHandle, error) {
+	if cfg.In == nil {
+		return nil, errors.New("StartTXStream: In channel is required")
+	}
+	s, err := m.openBlockStream(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("StartTXStream: %w", err)
+	}
+	return s.run(parent, s.tx), nil
+}
+
+// openBlockStream validates cfg and allocates the buffer and its blocks.
+func (m *Manager) openBlockStream(cfg StreamConfig) (*blockStream, error) {
+	if m == nil || m.conn == nil {
+		return nil, errors.New("not connected")
+	}
+	if m.Mode != ModeBinary {
+		return nil, errors.New("streams require ModeBinary")
+	}
+	if cfg.Depth < 0 || cfg.Depth > 0xff {
+		return nil, fmt.Errorf("depth %d out of range", cfg.Depth)
+	}
+	if cfg.Depth == 0 {
+		cfg.Depth = DefaultStreamDepth
+	}
+	size, err := cfg.Buffer.size()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &blockStream{m: m, cfg: cfg, dev: cfg.Buffer.DeviceIndex, id: m.nextBufferID, blockBytes: size, pfx: cfg.LogPrefix}
+	if s.pfx == "" {
+		s.pfx = "stream"
+	}
+	m.nextBufferID++
+
+	if _, err := m.Call(iiodwire.OpCreateBuffer, s.dev, int32(s.id), iiodwire.U32SliceWithCount([]uint32{cfg.Buffer.Mask})); err != nil {
+		return nil, fmt.Errorf("create buffer: %w", err)
+	}
+	for i := 0; i < cfg.Depth; i++ {
+		code := int32(s.id)<<16 | int32(i)
+		if _, err := m.Call(iiodwire.OpCreateBlock, s.dev, code, iiodwire.U64(uint64(size))); err != nil {
+			return nil, errors.Join(fmt.Errorf("create block %d: %w", i, err), s.free())
+		}
+		s.codes = append(s.codes, code)
+		s.lens = append(s.lens, 0)
+	}
+	if _, err := m.Call(iiodwire.OpEnableBuffer, s.dev, int32(s.id)); err != nil {
+		return nil, errors.Join(fmt.Errorf("enable buffer: %w", err), s.free())
+	}
+	return s, nil
+}
+
+// run starts loop in a goroutine that tears the buffer down when it returns.
+func (s *blockStream) run(parent context.Context, loop func(ctx context.Context)) *StreamHandle {
+	ctx, cancel := context.WithCancel(parent)
+	s.h = &StreamHandle{cancel: cancel, start: time.Now()}
+	s.h.wg.Add(1)
+	go func() {
+		defer s.h.wg.Done()
+		defer cancel()
+		defer s.m.withBudget(s.m.timeouts().Stream)()
+		log.Printf("[%s] start: dev=%d depth=%d blockBytes=%d", s.pfx, s.dev, len(s.codes), s.blockBytes)
+		loop(ctx)
+		s.h.end.Store(time.Now().UnixNano())
+		if err := s.teardown(); err != nil {
+			s.h.setErr(err)
+		}
+		st := s.h.Stats()
+		log.Printf("[%s] stop: blocks=%d bytes=%d dropped=%d rate=%.1f MB/s", s.pfx, st.Blocks, st.Bytes, st.Dropped, st.BytesPerSecond()/1e6)
+	}()
+	return s.h
+}
+
+// rx keeps every block's transfer in flight until ctx ends.
+func (s *blockStream) rx(ctx context.Context) {
+	for i := range s.codes {
+		if err := s.submit(i, iiodwire.U64(uint64(s.blockBytes))); err != nil {
+			s.h.setErr(err)
+			return
+		}
+	}
+	for ctx.Err() == nil {
+		i, resp, err := s.reply(iiodwire.ShapeStatusBytes)
+		if err != nil {
+			s.h.setErr(err)
+			return
+		}
+		if !s.deliver(ctx, resp.Data) {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.submit(i, iiodwire.U64(uint64(s.blockBytes))); err != nil {
+			s.h.setErr(err)
+			return
+		}
+	}
+}
+
+// deliver sends data to Out, dropping it if Out is full and DropIfFull is
+// set. Returns false if ctx ended first.
+func (s *blockStream) deliver(ctx context.Context, data []byte) bool {
+	if s.cfg.DropIfFull {
+		select {
+		case s.cfg.Out <- data:
+		default:
+			s.h.dropped.Add(1)
+		}
+		return true
+	}
+	select {
+	case s.cfg.Out <- data:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// tx submits blocks from In on idle blocks until In closes or ctx ends.
+func (s *blockStream) tx(ctx context.Context) {
+	idle := make([]int, 0, len(s.codes))
+	for i := range s.codes {
+		idle = append(idle, i)
+	}
+	for {
+		if len(idle) == 0 {
+			i, _, err := s.reply(iiodwire.ShapeStatus)
+			if err != nil {
+				s.h.setErr(err)
+				return
+			}
+			idle = append(idle, i)
+			continue
+		}
+		var data []byte
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-s.cfg.In:
+			if !ok {
+				return
+			}
+			data = d
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if len(data) > s.blockBytes {
+			s.h.setErr(fmt.Errorf("TX block of %d bytes exceeds block size %d", len(data), s.blockBytes))
+			return
+		}
+		i := idle[len(idle)-1]
+		idle = idle[:len(idle)-1]
+		s.lens[i] = len(data)
+		if err := s.submit(i, iiodwire.U64(uint64(len(data))), data); err != nil {
+			s.h.setErr(err)
+			return
+		}
+	}
+}
+
+// submit sends a TRANSFER_BLOCK for pool index i.
+func (s *blockStream) submit(i int, payloads ...[]byte) error {
+	if err := s.m.sendCommand(streamClientBase+uint16(i), iiodwire.OpTransferBlock, s.dev, s.codes[i], payloads...); err != nil {
+		s.broken = true
+		return fmt.Errorf("transfer block %d: %w", i, err)
+	}
+	s.inFlight++
+	return nil
+}
+
+// reply reads the next completed transfer and returns its pool index. A
+// negative status leaves the stream aligned; any other error breaks it.
+func (s *blockStream) reply(shape iiodwire.Shape) (int, iiodwire.Response, error) {
+	hdr, resp, err := s.m.readReply(shape)
+	if err != nil && resp.Status >= 0 {
+		s.broken = true
+		return 0, resp, fmt.Errorf("transfer block: %w", err)
+	}
+	s.inFlight--
+	i := int(hdr.ClientID) - streamClientBase
+	if i < 0 || i >= len(s.codes) {
+		s.broken = true
+		return 0, resp, fmt.Errorf("transfer block: reply for unknown client %d", hdr.ClientID)
+	}
+	if err != nil {
+		return i, resp, fmt.Errorf("transfer block %d: %w", i, err)
+	}
+	s.h.blocks.Add(1)
+	if shape == iiodwire.ShapeStatus {
+		s.h.bytes.Add(uint64(s.lens[i]))
+	} else {
+		s.h.bytes.Add(uint64(len(resp.Data)))
+	}
+	return i, resp, nil
+}
+
+// teardown waits for the transfers in flight, then disables and frees the
+// buffer. A broken stream is not torn down, as the replies cannot be matched.
+func (s *blockStream) teardown() error {
+	if s.broken {
+		return nil
+	}
+	shape := iiodwire.ShapeStatus
+	if s.cfg.Out != nil {
+		shape = iiodwire.ShapeStatusBytes
+	}
+	var errs []error
+	for s.inFlight > 0 {
+		if _, _, err := s.reply(shape); err != nil {
+			errs = append(errs, err)
+			if s.broken {
+				return errors.Join(errs...)
+			}
+		}
+	}
+	if _, err := s.m.Call(iiodwire.OpDisableBuffer, s.dev, int32(s.id)); err != nil {
+		errs = append(errs, fmt.Errorf("disable buffer: %w", err))
+	}
+	return errors.Join(append(errs, s.free())...)
+}
+
+// free releases the blocks created so far and the buffer.
+func (s *blockStream) free() error {
+	var errs []error
+	for i, code := range s.codes {
+		if _, err := s.m.Call(iiodwire.OpFreeBlock, s.dev, code); err != nil {
+			errs = append(errs, fmt.Errorf("free block %d: %w", i, err))
+		}
+	}
+	if _, err := s.m.Call(iiodwire.OpFreeBuffer, s.dev, int32(s.id)); err != nil {
+		errs = append(errs, fmt.Errorf("free buffer: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,304 @@
+package connectionmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// streamMockCmd is one command received by streamMock.
+type streamMockCmd struct {
+	hdr  iiodwire.Header
+	data []byte // TX payload of a TRANSFER_BLOCK
+}
+
+// streamMock is an IIOD server over TCP for pipelined streams. It holds
+// TRANSFER_BLOCK replies until depth transfers are outstanding, or briefly
+// when fewer are, which records how many the client keeps in flight.
+type streamMock struct {
+	depth int
+	rx    []byte // RX block contents; nil serves TX transfers
+
+	mu          sync.Mutex
+	ops         []uint8
+	tx          [][]byte
+	maxInFlight int
+}
+
+func (s *streamMock) serve(t *testing.T, conn net.Conn) {
+	t.Helper()
+	cmds := make(chan streamMockCmd, 64)
+	go func() {
+		defer close(cmds)
+		br := bufio.NewReader(conn)
+		for {
+			hdr, err := iiodwire.ReadHeader(br)
+			if err != nil {
+				return
+			}
+			cmd := streamMockCmd{hdr: hdr}
+			switch hdr.Opcode {
+			case iiodwire.OpCreateBuffer, iiodwire.OpCreateBlock:
+				_, err = io.ReadFull(br, make([]byte, 8))
+			case iiodwire.OpTransferBlock:
+				var n [8]byte
+				if _, err = io.ReadFull(br, n[:]); err == nil && s.rx == nil {
+					cmd.data = make([]byte, binary.BigEndian.Uint64(n[:]))
+					_, err = io.ReadFull(br, cmd.data)
+				}
+			}
+			if err != nil {
+				return
+			}
+			cmds <- cmd
+		}
+	}()
+
+	reply := func(hdr iiodwire.Header, body ...[]byte) {
+		resp := iiodwire.Header{ClientID: hdr.ClientID, Opcode: iiodwire.OpResponse, Dev: hdr.Dev}.Marshal()
+		for _, b := range body {
+			resp = append(resp, b...)
+		}
+		_, _ = conn.Write(resp)
+	}
+	replyTransfer := func(cmd streamMockCmd) {
+		if s.rx == nil {
+			s.mu.Lock()
+			s.tx = append(s.tx, cmd.data)
+			s.mu.Unlock()
+			reply(cmd.hdr, iiodwire.I32(int32(len(cmd.data))))
+			return
+		}
+		reply(cmd.hdr, iiodwire.I32(int32(len(s.rx))), iiodwire.LPBytes(s.rx))
+	}
+
+	var pending []streamMockCmd
+	for {
+		var wait <-chan time.Time
+		if len(pending) > 0 {
+			wait = time.After(20 * time.Millisecond)
+		}
+		select {
+		case cmd, ok := <-cmds:
+			if !ok {
+				return
+			}
+			s.mu.Lock()
+			s.ops = append(s.ops, cmd.hdr.Opcode)
+			s.mu.Unlock()
+			switch cmd.hdr.Opcode {
+			case iiodwire.OpTransferBlock:
+				pending = append(pending, cmd)
+				s.mu.Lock()
+				s.maxInFlight = max(s.maxInFlight, len(pending))
+				s.mu.Unlock()
+				if len(pending) == s.depth {
+					replyTransfer(pending[0])
+					pending = pending[1:]
+				}
+			case iiodwire.OpCreateBuffer, iiodwire.OpCreateBlock:
+				reply(cmd.hdr, iiodwire.I32(0), iiodwire.U32(0))
+			default:
+				reply(cmd.hdr, iiodwire.I32(0))
+			}
+		case <-wait:
+			replyTransfer(pending[0])
+			pending = pending[1:]
+		}
+	}
+}
+
+// snapshot returns the maximum transfers in flight and the TX blocks
+// received.
+func (s *streamMock) snapshot() (int, [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxInFlight, s.tx
+}
+
+// count returns how often op was received.
+func (s *streamMock) count(op uint8) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, o := range s.ops {
+		if o == op {
+			n++
+		}
+	}
+	return n
+}
+
+// dialStreamMock connects a binary-mode Manager to mock over TCP loopback,
+// whose socket buffers let requests and replies cross.
+func dialStreamMock(t *testing.T, mock *streamMock) *Manager {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mock.serve(t, conn)
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	m := &Manager{Mode: ModeBinary, Timeouts: TimeoutPolicy{Control: time.Second, Stream: time.Second}}
+	m.SetConn(conn)
+	return m
+}
+
+func TestRXStreamKeepsDepthInFlight(t *testing.T) {
+	payload := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	mock := &streamMock{depth: 3, rx: payload}
+	m := dialStreamMock(t, mock)
+
+	out := make(chan []byte, 4)
+	h, err := m.StartRXStream(context.Background(), StreamConfig{
+		Buffer: BufferConfig{Samples: 2, Mask: 0x3},
+		Depth:  3,
+		Out:    out,
+	})
+	if err != nil {
+		t.Fatalf("StartRXStream: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if got := <-out; !bytes.Equal(got, payload) {
+			t.Fatalf("block %d = %x, want %x", i, got, payload)
+		}
+	}
+	h.Stop()
+	if err := h.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	if inFlight, _ := mock.snapshot(); inFlight != 3 {
+		t.Fatalf("max transfers in flight = %d, want 3", inFlight)
+	}
+	if n := mock.count(iiodwire.OpCreateBlock); n != 3 {
+		t.Fatalf("CREATE_BLOCK count = %d, want 3", n)
+	}
+	if n := mock.count(iiodwire.OpFreeBlock); n != 3 {
+		t.Fatalf("FREE_BLOCK count = %d, want 3", n)
+	}
+	if mock.count(iiodwire.OpDisableBuffer) != 1 || mock.count(iiodwire.OpFreeBuffer) != 1 {
+		t.Fatal("buffer not disabled and freed")
+	}
+	// Every transfer submitted was also completed before the teardown.
+	st := h.Stats()
+	if transfers := uint64(mock.count(iiodwire.OpTransferBlock)); st.Blocks != transfers || st.Bytes != transfers*uint64(len(payload)) {
+		t.Fatalf("stats = %+v, transfers = %d", st, transfers)
+	}
+}
+
+func TestRXStreamWaitAfterParentCancel(t *testing.T) {
+	mock := &streamMock{depth: 2, rx: []byte{1, 0, 2, 0, 3, 0, 4, 0}}
+	m := dialStreamMock(t, mock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan []byte)
+	h, err := m.StartRXStream(ctx, StreamConfig{Buffer: BufferConfig{Samples: 2, Mask: 0x3}, Depth: 2, Out: out})
+	if err != nil {
+		t.Fatalf("StartRXStream: %v", err)
+	}
+	<-out
+	cancel()
+	if err := h.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if mock.count(iiodwire.OpFreeBuffer) != 1 {
+		t.Fatal("buffer not freed when Wait returned")
+	}
+}
+
+func TestTXStreamPipelinesBlocks(t *testing.T) {
+	mock := &streamMock{depth: 2}
+	m := dialStreamMock(t, mock)
+
+	in := make(chan []byte)
+	h, err := m.StartTXStream(context.Background(), StreamConfig{
+		Buffer: BufferConfig{Samples: 2, Mask: 0x3},
+		Depth:  2,
+		In:     in,
+	})
+	if err != nil {
+		t.Fatalf("StartTXStream: %v", err)
+	}
+	var want [][]byte
+	for i := byte(0); i < 6; i++ {
+		block := []byte{i, 0, i, 0, i, 0, i, 0}
+		want = append(want, block)
+		in <- block
+	}
+	close(in)
+	h.Stop()
+	if err := h.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	inFlight, got := mock.snapshot()
+	if inFlight != 2 {
+		t.Fatalf("max transfers in flight = %d, want 2", inFlight)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("device received %d blocks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("block %d = %x, want %x", i, got[i], want[i])
+		}
+	}
+	if st := h.Stats(); st.Blocks != 6 || st.Bytes != 48 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestStartStreamValidation(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	out := make(chan []byte)
+	valid := BufferConfig{Samples: 2, Mask: 0x3}
+
+	tests := []struct {
+		name string
+		mode Mode
+		cfg  StreamConfig
+		tx   bool
+	}{
+		{"ascii mode", ModeASCII, StreamConfig{Buffer: valid, Out: out}, false},
+		{"no out", ModeBinary, StreamConfig{Buffer: valid}, false},
+		{"no in", ModeBinary, StreamConfig{Buffer: valid}, true},
+		{"negative depth", ModeBinary, StreamConfig{Buffer: valid, Depth: -1, Out: out}, false},
+		{"no channels", ModeBinary, StreamConfig{Buffer: BufferConfig{Samples: 2}, Out: out}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{Mode: tt.mode}
+			m.SetConn(client)
+			start := m.StartRXStream
+			if tt.tx {
+				start = m.StartTXStream
+			}
+			if _, err := start(context.Background(), tt.cfg); err == nil {
+				t.Fatal("stream started")
+			}
+		})
+	}
+}
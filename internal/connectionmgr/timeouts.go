@@ -0,0 +1,120 @@
+package connectionmgr
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+// TimeoutPolicy is the single source of a Manager's timeouts: the dial
+// timeout, the socket deadlines of control and streaming operations, and the
+// TIMEOUT the server applies to its own blocking operations. Zero fields take
+// the values of DefaultTimeoutPolicy.
+type TimeoutPolicy struct {
+	// Connect bounds the TCP dial.
+	Connect time.Duration
+	// Control bounds each read and write of a control operation: commands,
+	// attribute access, PRINT and buffer setup.
+	Control time.Duration
+	// Stream bounds each read and write of a buffer transfer (READBUF,
+	// WRITEBUF, TRANSFER_BLOCK, ENQUEUE_BLOCK_CYCLIC), which waits for the
+	// device to fill or drain the buffer.
+	Stream time.Duration
+	// Remote is the TIMEOUT sent to the server; 0 derives it from Stream
+	// (see RemoteTimeout).
+	Remote time.Duration
+}
+
+// DefaultTimeoutPolicy returns the timeouts of a new Manager.
+func DefaultTimeoutPolicy() TimeoutPolicy {
+	return TimeoutPolicy{
+		Connect: 5 * time.Second,
+		Control: 5 * time.Second,
+		Stream:  10 * time.Second,
+	}
+}
+
+// withDefaults fills the zero fields from DefaultTimeoutPolicy.
+func (p TimeoutPolicy) withDefaults() TimeoutPolicy {
+	def := DefaultTimeoutPolicy()
+	if p.Connect <= 0 {
+		p.Connect = def.Connect
+	}
+	if p.Control <= 0 {
+		p.Control = def.Control
+	}
+	if p.Stream <= 0 {
+		p.Stream = def.Stream
+	}
+	return p
+}
+
+// RemoteTimeout returns the TIMEOUT for the server: Remote if set, otherwise
+// three quarters of the stream budget. The server then answers a stalled
+// transfer with -ETIMEDOUT, which keeps the protocol aligned, before the
+// client's own deadline breaks the connection.
+func (p TimeoutPolicy) RemoteTimeout() time.Duration {
+	p = p.withDefaults()
+	if p.Remote > 0 {
+		return p.Remote
+	}
+	return p.Stream * 3 / 4
+}
+
+// streamOpcode reports whether a binary command moves buffer data, or waits
+// for an event, and so runs under the stream budget.
+func streamOpcode(opcode uint8) bool {
+	switch opcode {
+	case iiodwire.OpTransferBlock, iiodwire.OpEnqueueBlockCyclic, iiodwire.OpRetryDequeueBlock, iiodwire.OpReadEvent:
+		return true
+	}
+	return false
+}
+
+// timeouts returns the Manager's policy with the defaults applied.
+func (m *Manager) timeouts() TimeoutPolicy {
+	return m.Timeouts.withDefaults()
+}
+
+// withBudget makes d the socket deadline of the reads and writes until the
+// returned function is called. The budget of an enclosing operation takes
+// precedence, so a stream loop's per-chunk budget is not replaced by that of
+// the transfers it runs.
+func (m *Manager) withBudget(d time.Duration) (restore func()) {
+	if m.budget > 0 || d <= 0 {
+		return func() {}
+	}
+	m.budget = d
+	return func() { m.budget = 0 }
+}
+
+// opBudget returns the deadline of the operation in progress: the budget set
+// by withBudget, or the control budget.
+func (m *Manager) opBudget() time.Duration {
+	if m.budget > 0 {
+		return m.budget
+	}
+	return m.timeouts().Control
+}
+
+// ApplyRemoteTimeout sends the policy's RemoteTimeout to the server: the
+// TIMEOUT command in ModeASCII, or the TIMEOUT opcode, which has no response,
+// in ModeBinary.
+//
+// Returns an error if the manager is not connected, the transport fails or
+// the server rejects the value.
+func (m *Manager) ApplyRemoteTimeout() error {
+	if m == nil || m.conn == nil {
+		return errors.New("ApplyRemoteTimeout: not connected")
+	}
+	ms := m.Timeouts.RemoteTimeout().Milliseconds()
+	if m.Mode == ModeBinary {
+		if _, err := m.Call(iiodwire.OpTimeout, 0, int32(ms)); err != nil {
+			return fmt.Errorf("ApplyRemoteTimeout: %w", err)
+		}
+		return nil
+	}
+	return m.SetTimeoutASCII(int(ms))
+}
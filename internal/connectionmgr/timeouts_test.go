@@ -0,0 +1,128 @@
+package connectionmgr
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/iiodwire"
+)
+
+func TestTimeoutPolicyRemote(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy TimeoutPolicy
+		want   time.Duration
+	}{
+		{"defaults", TimeoutPolicy{}, 7500 * time.Millisecond},
+		{"from stream", TimeoutPolicy{Stream: 4 * time.Second}, 3 * time.Second},
+		{"explicit", TimeoutPolicy{Stream: 4 * time.Second, Remote: time.Second}, time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.RemoteTimeout(); got != tt.want {
+			t.Errorf("%s: RemoteTimeout = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := (TimeoutPolicy{Control: time.Second}).withDefaults(); got.Connect != 5*time.Second || got.Control != time.Second || got.Stream != 10*time.Second {
+		t.Fatalf("withDefaults = %+v", got)
+	}
+}
+
+func TestWithBudgetOuterWins(t *testing.T) {
+	m := &Manager{Timeouts: TimeoutPolicy{Control: time.Second}}
+	if got := m.opBudget(); got != time.Second {
+		t.Fatalf("control budget = %v", got)
+	}
+	restore := m.withBudget(3 * time.Second)
+	inner := m.withBudget(10 * time.Second)
+	if got := m.opBudget(); got != 3*time.Second {
+		t.Fatalf("nested budget = %v, want the outer 3s", got)
+	}
+	inner()
+	if got := m.opBudget(); got != 3*time.Second {
+		t.Fatalf("budget after inner restore = %v", got)
+	}
+	restore()
+	if got := m.opBudget(); got != time.Second {
+		t.Fatalf("budget after restore = %v", got)
+	}
+}
+
+func TestApplyRemoteTimeout(t *testing.T) {
+	t.Run("ascii", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		got := make(chan string, 1)
+		go func() {
+			line, _ := bufio.NewReader(server).ReadString('\n')
+			got <- line
+			_, _ = server.Write([]byte("0\n"))
+		}()
+		m := &Manager{Timeouts: TimeoutPolicy{Stream: 2 * time.Second}}
+		m.SetConn(client)
+		if err := m.ApplyRemoteTimeout(); err != nil {
+			t.Fatalf("ApplyRemoteTimeout: %v", err)
+		}
+		if line := <-got; line != "TIMEOUT 1500\r\n" {
+			t.Fatalf("command = %q", line)
+		}
+	})
+	t.Run("binary", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		got := make(chan iiodwire.Header, 1)
+		go func() {
+			hdr, _ := iiodwire.ReadHeader(server)
+			got <- hdr
+		}()
+		m := &Manager{Mode: ModeBinary, Timeouts: TimeoutPolicy{Remote: 800 * time.Millisecond}}
+		m.SetConn(client)
+		if err := m.ApplyRemoteTimeout(); err != nil {
+			t.Fatalf("ApplyRemoteTimeout: %v", err)
+		}
+		if hdr := <-got; hdr.Opcode != iiodwire.OpTimeout || hdr.Code != 800 {
+			t.Fatalf("header = %+v", hdr)
+		}
+	})
+}
+
+// TestTransfersUseStreamBudget answers both a control command and a READBUF
+// after a delay longer than the control budget: only the transfer, which runs
+// under the stream budget, may wait that long.
+func TestTransfersUseStreamBudget(t *testing.T) {
+	const delay = 150 * time.Millisecond
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		br := bufio.NewReader(server)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			time.Sleep(delay)
+			if line == "READBUF dev 4\r\n" {
+				_, _ = server.Write([]byte("4\n00000003\n\x01\x02\x03\x04\n"))
+			} else {
+				_, _ = server.Write([]byte("0\n"))
+			}
+		}
+	}()
+
+	m := &Manager{Timeouts: TimeoutPolicy{Control: 50 * time.Millisecond, Stream: time.Second}}
+	m.SetConn(client)
+	dst := make([]byte, 4)
+	if n, err := m.ReadBufferASCII("dev", dst); err != nil || n != 4 {
+		t.Fatalf("ReadBufferASCII = %d, %v", n, err)
+	}
+	_, err := m.ExecCommand("TIMEOUT 1")
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Fatalf("ExecCommand err = %v, want a timeout of the control budget", err)
+	}
+}
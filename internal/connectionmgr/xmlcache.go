@@ -0,0 +1,267 @@
+package connectionmgr
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdrxml"
+)
+
+// DefaultXMLChunkSize is the read size of FetchContextXML when none is
+// given.
+const DefaultXMLChunkSize = 16 << 10
+
+// XMLProgress reports a context XML fetch: Received of Total bytes, or the
+// whole document from the cache.
+type XMLProgress struct {
+	Received int
+	Total    int
+	Cached   bool
+}
+
+// XMLFetchOptions configure FetchContextXML. The zero value fetches in
+// DefaultXMLChunkSize reads without a cache.
+type XMLFetchOptions struct {
+	// ChunkSize is the size of each read of the PRINT payload.
+	ChunkSize int
+	// Progress, if set, is called after each chunk and for a cache hit.
+	Progress func(XMLProgress)
+	// Cache, if set, is consulted before the transfer and updated after it.
+	Cache *XMLCache
+	// Serial keys the cache when the caller knows the device's hw_serial
+	// beforehand, for example from its mDNS announcement. Empty keys it by
+	// address and server version.
+	Serial string
+}
+
+// FetchContextXML returns the context XML of the server, from opts.Cache
+// when the cached document still matches the server, otherwise with PRINT.
+//
+// The text protocol payload is read in chunks, each with its own socket
+// deadline, so a slow link that keeps delivering is not cut off by the
+// control budget, and the caller can show progress. IIOD cannot restart a
+// PRINT at an offset: an interrupted transfer starts over on the next call,
+// and the cache is what spares reconnects the transfer. The binary protocol
+// returns the document in one response, reported as a single chunk.
+//
+// The parsed document is kept in ClientInfo.XMLcontext.
+func (m *Manager) FetchContextXML(ctx context.Context, opts XMLFetchOptions) ([]byte, error) {
+	if m == nil || m.conn == nil {
+		return nil, errors.New("FetchContextXML: not connected")
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(XMLProgress) {}
+	}
+
+	var key xmlCacheKey
+	if opts.Cache != nil {
+		version, err := m.serverVersion(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("FetchContextXML: %w", err)
+		}
+		key = xmlCacheKey{Address: m.Address, Version: version, Serial: opts.Serial}
+		if data, ok := opts.Cache.load(key); ok {
+			progress(XMLProgress{Received: len(data), Total: len(data), Cached: true})
+			m.parseContext(data)
+			return data, nil
+		}
+	}
+
+	var data []byte
+	var err error
+	if m.Mode == ModeBinary {
+		data, err = m.GetXMLContext(ctx, 0)
+		if err == nil {
+			progress(XMLProgress{Received: len(data), Total: len(data)})
+		}
+	} else {
+		data, err = runContext(ctx, m, func() ([]byte, error) { return m.readXMLChunked(opts.ChunkSize, progress) })
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FetchContextXML: %w", err)
+	}
+
+	serial := m.parseContext(data)
+	if opts.Cache != nil {
+		if err := opts.Cache.store(key, serial, data); err != nil {
+			m.logf("context XML not cached: %v", err)
+		}
+	}
+	return data, nil
+}
+
+// serverVersion returns the VERSION reply kept by Negotiate, asking the
+// server if the connection was not negotiated.
+func (m *Manager) serverVersion(ctx context.Context) (string, error) {
+	if m.ClientInfo.Version != "" {
+		return m.ClientInfo.Version, nil
+	}
+	if m.Mode == ModeBinary {
+		return "", errors.New("server version unknown; negotiate the connection first")
+	}
+	version, err := m.GetVersionASCIIContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	m.ClientInfo.Version = version
+	return version, nil
+}
+
+// readXMLChunked sends PRINT and reads its payload chunk by chunk.
+func (m *Manager) readXMLChunked(chunk int, progress func(XMLProgress)) ([]byte, error) {
+	if chunk <= 0 {
+		chunk = DefaultXMLChunkSize
+	}
+	n, err := m.ExecCommand("PRINT")
+	if err != nil {
+		return nil, fmt.Errorf("PRINT failed: %w", err)
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("PRINT returned non-positive length %d", n)
+	}
+
+	buf := make([]byte, n+1) // +1 for trailing '\n'
+	for off := 0; off < len(buf); {
+		end := min(off+chunk, len(buf))
+		if err := m.readAll(buf[off:end]); err != nil {
+			return nil, fmt.Errorf("read xml at byte %d of %d: %w", off, n, err)
+		}
+		off = end
+		progress(XMLProgress{Received: min(off, n), Total: n})
+	}
+	return buf[:n], nil
+}
+
+// parseContext parses data into ClientInfo.XMLcontext and returns the
+// device's hw_serial, if any. A document that does not parse is still
+// returned to the caller, so it is only logged.
+func (m *Manager) parseContext(data []byte) string {
+	var xmlCtx sdrxml.SDRContext
+	if err := xmlCtx.Parse(data); err != nil {
+		m.logf("context XML: %v", err)
+		return ""
+	}
+	m.ClientInfo.XMLcontext = xmlCtx
+	for _, attr := range xmlCtx.ContextAttribute {
+		if attr.Name == "hw_serial" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// XMLCache keeps fetched context XML documents in a directory, so a
+// reconnect to the same device skips the transfer.
+//
+// An entry is keyed by the device's serial when the caller knows it, and by
+// the address and VERSION reply of the server otherwise. It is reused while
+// the server reports the same version, which changes with a firmware update,
+// and its checksum still matches; MaxAge additionally bounds its age.
+type XMLCache struct {
+	Dir string
+	// MaxAge forces a refetch of older entries; 0 keeps them until the
+	// server version changes.
+	MaxAge time.Duration
+}
+
+// xmlCacheKey identifies the server a document was fetched from.
+type xmlCacheKey struct {
+	Address string
+	Version string
+	Serial  string
+}
+
+// name returns the file name stem of the entry.
+func (k xmlCacheKey) name() string {
+	if k.Serial != "" {
+		return "serial-" + strings.Map(func(r rune) rune {
+			if r == '/' || r == '\\' || r == '.' || r == ':' {
+				return '_'
+			}
+			return r
+		}, k.Serial)
+	}
+	sum := sha256.Sum256([]byte(k.Address + "\x00" + k.Version))
+	return "addr-" + hex.EncodeToString(sum[:8])
+}
+
+// xmlCacheEntry is the metadata stored next to a cached document.
+type xmlCacheEntry struct {
+	Address  string    `json:"address"`
+	Version  string    `json:"version"`
+	Serial   string    `json:"serial,omitempty"`
+	Checksum string    `json:"sha256"`
+	Size     int       `json:"size"`
+	Fetched  time.Time `json:"fetched"`
+}
+
+func (c *XMLCache) paths(key xmlCacheKey) (xmlPath, metaPath string) {
+	stem := filepath.Join(c.Dir, key.name())
+	return stem + ".xml", stem + ".json"
+}
+
+// load returns the cached document of key if it is still valid.
+func (c *XMLCache) load(key xmlCacheKey) ([]byte, bool) {
+	xmlPath, metaPath := c.paths(key)
+	raw, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, false
+	}
+	var entry xmlCacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || entry.Version != key.Version {
+		return nil, false
+	}
+	if c.MaxAge > 0 && time.Since(entry.Fetched) > c.MaxAge {
+		return nil, false
+	}
+	data, err := os.ReadFile(xmlPath)
+	if err != nil || len(data) != entry.Size {
+		return nil, false
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != entry.Checksum {
+		return nil, false
+	}
+	return data, true
+}
+
+// store saves data as the document of key. serial is the hw_serial read
+// from the document, recorded for inspection.
+func (c *XMLCache) store(key xmlCacheKey, serial string, data []byte) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	if key.Serial != "" && serial != "" && key.Serial != serial {
+		return fmt.Errorf("device reports serial %q, not %q", serial, key.Serial)
+	}
+	sum := sha256.Sum256(data)
+	entry := xmlCacheEntry{
+		Address:  key.Address,
+		Version:  key.Version,
+		Serial:   cmp.Or(key.Serial, serial),
+		Checksum: hex.EncodeToString(sum[:]),
+		Size:     len(data),
+		Fetched:  time.Now().UTC(),
+	}
+	meta, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	xmlPath, metaPath := c.paths(key)
+	// The document goes first: a crash in between leaves metadata that no
+	// longer matches it, which load rejects.
+	if err := os.WriteFile(xmlPath, data, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath, append(meta, '\n'), 0o644)
+}
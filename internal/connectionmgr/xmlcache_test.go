@@ -0,0 +1,137 @@
+package connectionmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// servePrint answers each PRINT on a fresh pipe with doc and counts the
+// commands it received.
+func servePrint(t *testing.T, doc []byte, version string) (*Manager, func() int) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	prints := make(chan int, 1)
+	go func() {
+		defer server.Close()
+		n := 0
+		defer func() { prints <- n }()
+		br := bufio.NewReader(server)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line != "PRINT\r\n" {
+				t.Errorf("unexpected command %q", line)
+				return
+			}
+			n++
+			fmt.Fprintf(server, "%d\n%s\n", len(doc), doc)
+		}
+	}()
+	m := &Manager{Address: "192.168.2.1:30431", Timeouts: TimeoutPolicy{Control: time.Second}}
+	m.SetConn(client)
+	m.ClientInfo.Version = version
+	return m, func() int {
+		client.Close()
+		return <-prints
+	}
+}
+
+func TestFetchContextXMLCache(t *testing.T) {
+	doc, err := os.ReadFile("pluto.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = bytes.TrimSpace(doc)
+	cache := &XMLCache{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	m, done := servePrint(t, doc, "0.25.gb6028fd")
+	var chunks []XMLProgress
+	got, err := m.FetchContextXML(ctx, XMLFetchOptions{
+		ChunkSize: 4096,
+		Cache:     cache,
+		Progress:  func(p XMLProgress) { chunks = append(chunks, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, doc) {
+		t.Fatalf("fetched %d bytes, want %d", len(got), len(doc))
+	}
+	if want := len(doc)/4096 + 1; len(chunks) != want {
+		t.Fatalf("%d progress reports, want %d", len(chunks), want)
+	}
+	if last := chunks[len(chunks)-1]; last.Received != len(doc) || last.Total != len(doc) || last.Cached {
+		t.Fatalf("last progress = %+v", last)
+	}
+	if len(m.ClientInfo.XMLcontext.Device) == 0 {
+		t.Fatal("ClientInfo.XMLcontext not parsed")
+	}
+	if n := done(); n != 1 {
+		t.Fatalf("%d PRINT commands, want 1", n)
+	}
+
+	// A reconnect to the same server is served from the cache.
+	m, done = servePrint(t, doc, "0.25.gb6028fd")
+	chunks = nil
+	got, err = m.FetchContextXML(ctx, XMLFetchOptions{Cache: cache, Progress: func(p XMLProgress) { chunks = append(chunks, p) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, doc) || len(chunks) != 1 || !chunks[0].Cached {
+		t.Fatalf("cache hit returned %d bytes, progress %+v", len(got), chunks)
+	}
+	if n := done(); n != 0 {
+		t.Fatalf("%d PRINT commands on a cache hit", n)
+	}
+
+	// New firmware reports a new version and is fetched again.
+	m, done = servePrint(t, doc, "0.38.g1234567")
+	if _, err := m.FetchContextXML(ctx, XMLFetchOptions{Cache: cache}); err != nil {
+		t.Fatal(err)
+	}
+	if n := done(); n != 1 {
+		t.Fatalf("%d PRINT commands after a version change, want 1", n)
+	}
+}
+
+func TestXMLCacheRejectsCorruptEntry(t *testing.T) {
+	cache := &XMLCache{Dir: t.TempDir()}
+	key := xmlCacheKey{Address: "pluto.local:30431", Version: "0.25"}
+	if err := cache.store(key, "", []byte("<context/>")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.load(key); !ok {
+		t.Fatal("fresh entry not loaded")
+	}
+	xmlPath, _ := cache.paths(key)
+	if err := os.WriteFile(xmlPath, []byte("<context />"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.load(key); ok {
+		t.Fatal("entry with a wrong checksum loaded")
+	}
+
+	cache.MaxAge = time.Nanosecond
+	if err := cache.store(key, "", []byte("<context/>")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.load(key); ok {
+		t.Fatal("expired entry loaded")
+	}
+
+	serialKey := xmlCacheKey{Address: key.Address, Version: key.Version, Serial: "1044730"}
+	if err := cache.store(serialKey, "999", []byte("<context/>")); err == nil {
+		t.Fatal("entry stored under another device's serial")
+	}
+}
@@ -0,0 +1,293 @@
+// Package control serves a JSON-RPC 2.0 control plane on a Unix domain
+// socket, so supervisory processes on the same host can drive GoSDR without
+// opening a network port. Messages are JSON objects (or batch arrays), one
+// per line in both directions. Access is governed by the permissions of the
+// socket file, which is created readable and writable by its owner only.
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// maxMessageBytes bounds one request line.
+const maxMessageBytes = 1 << 20
+
+// Error is a JSON-RPC error. Handlers return one to choose the code and
+// attach data; any other error is reported as CodeInternalError.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s (code %d)", e.Message, e.Code) }
+
+// InvalidParams returns a CodeInvalidParams error.
+func InvalidParams(format string, args ...any) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler implements a method. params is the raw "params" member, nil when
+// absent; the result is marshalled into the response.
+type Handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Server dispatches JSON-RPC requests to registered methods.
+type Server struct {
+	path   string
+	logger logging.Logger
+
+	mu      sync.RWMutex
+	methods map[string]Handler
+}
+
+// NewServer creates a server that will listen on the Unix socket at path.
+func NewServer(path string, logger logging.Logger) *Server {
+	if logger == nil {
+		logger = logging.Default()
+	}
+	s := &Server{path: path, logger: logger, methods: make(map[string]Handler)}
+	s.Handle("rpc.methods", func(context.Context, json.RawMessage) (any, error) {
+		return s.Methods(), nil
+	})
+	return s
+}
+
+// Handle registers h for method, replacing an earlier registration.
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = h
+}
+
+// Methods returns the registered method names, sorted.
+func (s *Server) Methods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListenAndServe listens on the socket until ctx is done and removes it
+// afterwards. A stale socket left by a crashed process is replaced; a socket
+// another process still serves is not.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if conn, err := net.Dial("unix", s.path); err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is in use by another process", s.path)
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove stale control socket: %w", err)
+	}
+	ln, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("control socket: %w", err)
+	}
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		ln.Close()
+		return fmt.Errorf("control socket: %w", err)
+	}
+	s.logger.Info("control socket listening", logging.Field{Key: "subsystem", Value: "control"}, logging.Field{Key: "path", Value: s.path})
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is done, then closes ln and
+// the open connections.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	var wg sync.WaitGroup
+	var conns sync.Map
+	var closing atomic.Bool
+	go func() {
+		<-ctx.Done()
+		closing.Store(true)
+		ln.Close()
+		conns.Range(func(c, _ any) bool {
+			c.(net.Conn).Close()
+			return true
+		})
+	}()
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if closing.Load() {
+				return nil
+			}
+			return fmt.Errorf("control socket: %w", err)
+		}
+		conns.Store(conn, nil)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conns.Delete(conn)
+			defer conn.Close()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// serveConn answers the requests of one connection in order.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageBytes)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if reply := s.HandleMessage(ctx, line); reply != nil {
+			if err := enc.Encode(reply); err != nil {
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.logger.Warn("control connection", logging.Field{Key: "subsystem", Value: "control"}, logging.Field{Key: "error", Value: err})
+	}
+}
+
+// HandleMessage answers one JSON-RPC message, a request or a batch, and
+// returns the response to send, or nil when there is none because the
+// message held only notifications.
+func (s *Server) HandleMessage(ctx context.Context, msg []byte) any {
+	if msg[0] != '[' {
+		var req request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			return response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}}
+		}
+		return s.call(ctx, req)
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(msg, &batch); err != nil {
+		return response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}}
+	}
+	if len(batch) == 0 {
+		return response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeInvalidRequest, Message: "empty batch"}}
+	}
+	var replies []any
+	for _, raw := range batch {
+		var req request
+		if err := json.Unmarshal(raw, &req); err != nil {
+			replies = append(replies, response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeInvalidRequest, Message: err.Error()}})
+			continue
+		}
+		if reply := s.call(ctx, req); reply != nil {
+			replies = append(replies, reply)
+		}
+	}
+	if replies == nil {
+		return nil
+	}
+	return replies
+}
+
+// call runs one request. Requests without an id are notifications and get
+// no response, not even for errors.
+func (s *Server) call(ctx context.Context, req request) any {
+	notification := req.ID == nil
+	reply := response{JSONRPC: "2.0", ID: req.ID}
+	if notification {
+		reply.ID = json.RawMessage("null")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		reply.Error = &Error{Code: CodeInvalidRequest, Message: `want "jsonrpc": "2.0" and a method`}
+		return reply
+	}
+	s.mu.RLock()
+	h, ok := s.methods[req.Method]
+	s.mu.RUnlock()
+	if !ok {
+		reply.Error = &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	} else if result, err := h(ctx, req.Params); err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		reply.Error = rpcErr
+	} else {
+		reply.Result = result
+		if result == nil {
+			reply.Result = struct{}{}
+		}
+	}
+	if notification {
+		return nil
+	}
+	return reply
+}
+
+// Call sends one request to the server on the Unix socket at path and
+// decodes its result into result, which may be nil. A JSON-RPC error is
+// returned as *Error.
+func Call(ctx context.Context, path, method string, params, result any) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("control socket: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	req := map[string]any{"jsonrpc": "2.0", "id": 1, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("control socket: %w", err)
+	}
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+		return fmt.Errorf("control socket: %w", err)
+	}
+	if reply.Error != nil {
+		return reply.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
@@ -0,0 +1,104 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+func newTestServer(path string) *Server {
+	s := NewServer(path, logging.New(logging.Info, logging.Text, io.Discard))
+	s.Handle("echo", func(_ context.Context, params json.RawMessage) (any, error) {
+		return params, nil
+	})
+	s.Handle("fail", func(context.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("backend gone")
+	})
+	s.Handle("picky", func(context.Context, json.RawMessage) (any, error) {
+		return nil, InvalidParams("want a device")
+	})
+	return s
+}
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string // "" means no response
+	}{
+		{name: "call", msg: `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"a":1}}`, want: `{"jsonrpc":"2.0","id":1,"result":{"a":1}}`},
+		{name: "string id", msg: `{"jsonrpc":"2.0","id":"x","method":"echo","params":[2]}`, want: `{"jsonrpc":"2.0","id":"x","result":[2]}`},
+		{name: "notification", msg: `{"jsonrpc":"2.0","method":"echo"}`},
+		{name: "unknown method", msg: `{"jsonrpc":"2.0","id":2,"method":"reboot"}`, want: `{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"method not found: reboot"}}`},
+		{name: "handler error", msg: `{"jsonrpc":"2.0","id":3,"method":"fail"}`, want: `{"jsonrpc":"2.0","id":3,"error":{"code":-32603,"message":"backend gone"}}`},
+		{name: "rpc error", msg: `{"jsonrpc":"2.0","id":4,"method":"picky"}`, want: `{"jsonrpc":"2.0","id":4,"error":{"code":-32602,"message":"want a device"}}`},
+		{name: "wrong version", msg: `{"jsonrpc":"1.0","id":5,"method":"echo"}`, want: `{"jsonrpc":"2.0","id":5,"error":{"code":-32600,"message":"want \"jsonrpc\": \"2.0\" and a method"}}`},
+		{name: "parse error", msg: `{"jsonrpc":`, want: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}`},
+		{name: "batch", msg: `[{"jsonrpc":"2.0","id":1,"method":"echo","params":1},{"jsonrpc":"2.0","method":"echo"},{"jsonrpc":"2.0","id":2,"method":"rpc.methods"}]`,
+			want: `[{"jsonrpc":"2.0","id":1,"result":1},{"jsonrpc":"2.0","id":2,"result":["echo","fail","picky","rpc.methods"]}]`},
+		{name: "notification batch", msg: `[{"jsonrpc":"2.0","method":"echo"}]`},
+		{name: "empty batch", msg: `[]`, want: `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"empty batch"}}`},
+	}
+	s := newTestServer("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := s.HandleMessage(context.Background(), []byte(tt.msg))
+			if tt.want == "" {
+				if reply != nil {
+					t.Fatalf("reply %v, want none", reply)
+				}
+				return
+			}
+			got, err := json.Marshal(reply)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("reply\n %s\nwant\n %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.sock")
+	s := newTestServer(path)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ListenAndServe(ctx) }()
+
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+	var got map[string]string
+	var err error
+	for i := 0; i < 100; i++ {
+		if err = Call(callCtx, path, "echo", map[string]string{"device": "north"}, &got); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil || got["device"] != "north" {
+		t.Fatalf("echo = %v, %v", got, err)
+	}
+	var rpcErr *Error
+	if err := Call(callCtx, path, "picky", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Fatalf("picky err = %v, want invalid params", err)
+	}
+	if err := newTestServer(path).ListenAndServe(callCtx); err == nil {
+		t.Fatal("second server took over a live socket")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := Call(callCtx, path, "echo", nil, nil); err == nil {
+		t.Fatal("socket still answers after shutdown")
+	}
+}
@@ -0,0 +1,72 @@
+package dsp
+
+import "math"
+
+// BurstDetector classifies buffers as inside or outside a burst of an
+// intermittent (TDMA, push-to-talk) emitter from their energy. A burst starts
+// when the buffer power exceeds the noise floor by onDB and ends when it falls
+// below floor+offDB; the gap between the two thresholds is the hysteresis.
+// The noise floor follows idle buffers slowly and drops immediately to any
+// quieter buffer, so a recording that starts mid-burst recovers.
+type BurstDetector struct {
+	onDB, offDB float64
+	floorDB     float64
+	primed      bool
+	active      bool
+	lastDB      float64
+}
+
+// floorAlpha is the smoothing factor applied to idle buffers.
+const floorAlpha = 0.05
+
+// NewBurstDetector creates a detector. Non-positive thresholds select 10 dB
+// on and 6 dB off; offDB is clamped to at most onDB.
+func NewBurstDetector(onDB, offDB float64) *BurstDetector {
+	if onDB <= 0 {
+		onDB = 10
+	}
+	if offDB <= 0 {
+		offDB = 6
+	}
+	return &BurstDetector{onDB: onDB, offDB: math.Min(offDB, onDB)}
+}
+
+// Update classifies the next buffer pair. It reports whether the buffer is
+// part of a burst and whether a burst ended with the previous buffer.
+func (d *BurstDetector) Update(rx0, rx1 []complex64) (active, ended bool) {
+	d.lastDB = 10 * math.Log10(math.Max(meanPower(rx0)+meanPower(rx1), 1e-20))
+	if !d.primed || d.lastDB < d.floorDB {
+		d.floorDB, d.primed = d.lastDB, true
+	}
+	wasActive := d.active
+	switch {
+	case !d.active && d.lastDB > d.floorDB+d.onDB:
+		d.active = true
+	case d.active && d.lastDB < d.floorDB+d.offDB:
+		d.active = false
+	}
+	if !d.active {
+		d.floorDB += floorAlpha * (d.lastDB - d.floorDB)
+	}
+	return d.active, wasActive && !d.active
+}
+
+// Active reports whether the last buffer was inside a burst.
+func (d *BurstDetector) Active() bool { return d.active }
+
+// FloorDB returns the current noise floor estimate in dB.
+func (d *BurstDetector) FloorDB() float64 { return d.floorDB }
+
+// LastDB returns the power of the last buffer in dB.
+func (d *BurstDetector) LastDB() float64 { return d.lastDB }
+
+func meanPower(samples []complex64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += float64(real(s)*real(s) + imag(s)*imag(s))
+	}
+	return sum / float64(len(samples))
+}
@@ -0,0 +1,35 @@
+package dsp
+
+import "testing"
+
+func constant(n int, amp float32) []complex64 {
+	out := make([]complex64, n)
+	for i := range out {
+		out[i] = complex(amp, 0)
+	}
+	return out
+}
+
+func TestBurstDetectorHysteresis(t *testing.T) {
+	d := NewBurstDetector(10, 6)
+	steps := []struct {
+		amp           float32 // 20*log10 steps: 0.01 is the floor
+		active, ended bool
+	}{
+		{amp: 0.01},
+		{amp: 0.01},
+		{amp: 0.1, active: true},   // +20 dB: burst starts
+		{amp: 0.025, active: true}, // +8 dB: above the off threshold
+		{amp: 0.015, ended: true},  // +3.5 dB: burst ends
+		{amp: 0.01},
+		{amp: 0.005},              // quieter buffer lowers the floor at once
+		{amp: 0.02, active: true}, // +12 dB over the new floor
+	}
+	for i, s := range steps {
+		buf := constant(64, s.amp)
+		active, ended := d.Update(buf, buf)
+		if active != s.active || ended != s.ended {
+			t.Fatalf("step %d (amp %.3f): active=%v ended=%v, want %v/%v (floor %.1f dB)", i, s.amp, active, ended, s.active, s.ended, d.FloorDB())
+		}
+	}
+}
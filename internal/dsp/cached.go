@@ -1,85 +1,65 @@
 package dsp
 
 import (
-	"math"
-	"math/cmplx"
 	"sync"
 
 	"gonum.org/v1/gonum/dsp/fourier"
 )
 
 // CachedDSP pre-computes and caches expensive DSP resources to improve performance.
-// It stores a Hamming window and FFT instance that can be reused across multiple calls,
+// It stores the window coefficients and FFT instance that can be reused across multiple calls,
 // avoiding the overhead of recreating these resources on every operation.
 type CachedDSP struct {
-	mu            sync.RWMutex
-	hammingWindow []float64
-	windowSum     float64 // Pre-computed sum for normalization
-	fftSize       int
-	fft           *fourier.CmplxFFT
+	mu        sync.RWMutex
+	win       Window
+	window    []float64 // coefficients of win, shared with windowCache
+	windowSum float64   // Pre-computed sum for normalization
+	fftSize   int
+	fft       *fourier.CmplxFFT
 }
 
 // NewCachedDSP creates a DSP processor with pre-computed cached resources.
-// The Hamming window and FFT instance are created once and reused for all operations.
+// The Hamming window and FFT instance are created once and reused for all
+// operations; SetWindow selects another window.
 func NewCachedDSP(size int) *CachedDSP {
-	window := Hamming(size)
-
-	// Pre-compute window sum for normalization
-	sum := 0.0
-	for _, v := range window {
-		sum += v
-	}
-
+	c := Window{}.coefficients(size)
 	return &CachedDSP{
-		hammingWindow: window,
-		windowSum:     sum,
-		fftSize:       size,
-		fft:           fourier.NewCmplxFFT(size),
+		window:    c.coeffs,
+		windowSum: c.sum,
+		fftSize:   size,
+		fft:       fourier.NewCmplxFFT(size),
 	}
 }
 
+// SetWindow selects the window applied before every FFT. A rectangular
+// window leaks a strong tone, such as the TX carrier, across the whole
+// spectrum and inflates the noise floor the SNR is estimated from.
+func (c *CachedDSP) SetWindow(w Window) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.win = w
+	coeffs := w.coefficients(c.fftSize)
+	c.window, c.windowSum = coeffs.coeffs, coeffs.sum
+}
+
+// Window returns the selected window.
+func (c *CachedDSP) Window() Window {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.win
+}
+
 // FFTAndDBFS performs FFT using cached window and FFT instance.
 // This is significantly faster than the non-cached version as it avoids:
-// - Recreating the Hamming window on every call
+// - Recreating the window on every call
 // - Creating a new FFT instance on every call
 // - Recalculating the window sum for normalization
 func (c *CachedDSP) FFTAndDBFS(samples []complex64) ([]complex128, []float64) {
 	if len(samples) == 0 {
 		return []complex128{}, []float64{}
 	}
-
-	// Verify size matches cached resources
-	if len(samples) != c.fftSize {
-		// Fallback to non-cached version for mismatched sizes
-		return FFTAndDBFS(samples)
-	}
-
-	// Apply cached Hamming window
-	windowed := ApplyWindow(samples, c.hammingWindow)
-
-	// Reuse FFT instance (thread-safe with mutex)
-	c.mu.Lock()
-	fft := c.fft.Coefficients(nil, windowed)
-	c.mu.Unlock()
-
-	// Normalize by pre-computed window sum
-	for i := range fft {
-		fft[i] /= complex(c.windowSum, 0)
-	}
-
-	// Shift and convert to dBFS
-	shifted := FFTShift(fft)
-	dbfs := make([]float64, len(shifted))
-	for i, v := range shifted {
-		mag := cmplx.Abs(v)
-		if mag == 0 {
-			dbfs[i] = -math.Inf(1)
-			continue
-		}
-		dbfs[i] = 20 * math.Log10(mag/adcScale)
-	}
-
-	return shifted, dbfs
+	shifted := c.ShiftedFFT(samples)
+	return shifted, shiftedToDBFS(shifted)
 }
 
 // ShiftedFFT performs a windowed FFT using cached resources and returns the
@@ -91,22 +71,28 @@ func (c *CachedDSP) ShiftedFFT(samples []complex64) []complex128 {
 		return nil
 	}
 
-	// If the size does not match the cached FFT, fall back to the standard
-	// path. This retains correctness even when callers pass unexpected
-	// buffer sizes at the cost of extra allocations.
-	if len(samples) != c.fftSize {
-		fft, _ := FFTAndDBFS(samples)
+	c.mu.RLock()
+	win, window, windowSum, size := c.win, c.window, c.windowSum, c.fftSize
+	c.mu.RUnlock()
+
+	// If the size does not match the cached FFT, fall back to an uncached
+	// FFT with the same window. This retains correctness even when callers
+	// pass unexpected buffer sizes at the cost of extra allocations.
+	if len(samples) != size {
+		fft, _ := windowedFFTAndDBFS(samples, win.coefficients(len(samples)))
 		return fft
 	}
 
-	windowed := ApplyWindow(samples, c.hammingWindow)
+	windowed := ApplyWindow(samples, window)
 
+	// Reuse FFT instance (thread-safe with mutex)
 	c.mu.Lock()
 	fft := c.fft.Coefficients(nil, windowed)
 	c.mu.Unlock()
 
+	// Normalize by pre-computed window sum
 	for i := range fft {
-		fft[i] /= complex(c.windowSum, 0)
+		fft[i] /= complex(windowSum, 0)
 	}
 
 	return FFTShift(fft)
@@ -119,15 +105,8 @@ func (c *CachedDSP) UpdateSize(size int) {
 	defer c.mu.Unlock()
 
 	c.fftSize = size
-	c.hammingWindow = Hamming(size)
-
-	// Recompute window sum
-	sum := 0.0
-	for _, v := range c.hammingWindow {
-		sum += v
-	}
-	c.windowSum = sum
-
+	coeffs := c.win.coefficients(size)
+	c.window, c.windowSum = coeffs.coeffs, coeffs.sum
 	c.fft = fourier.NewCmplxFFT(size)
 }
 
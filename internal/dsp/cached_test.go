@@ -1,6 +1,7 @@
 package dsp
 
 import (
+	"math"
 	"math/cmplx"
 	"testing"
 )
@@ -151,3 +152,48 @@ func BenchmarkCachedDSP_Parallel(b *testing.B) {
 		}
 	})
 }
+
+func TestCachedDSP_WindowLeakage(t *testing.T) {
+	const size = 1024
+	// A strong tone between two bins, as the TX carrier usually is.
+	samples := make([]complex64, size)
+	for i := range samples {
+		samples[i] = complex64(cmplx.Rect(2000, 2*math.Pi*100.5*float64(i)/size))
+	}
+	// farLeakage is the strongest bin well away from the tone.
+	farLeakage := func(w Window) float64 {
+		c := NewCachedDSP(size)
+		c.SetWindow(w)
+		if c.Window() != w {
+			t.Fatalf("window %v, want %v", c.Window(), w)
+		}
+		_, dbfs := c.FFTAndDBFS(samples)
+		worst := math.Inf(-1)
+		for i, v := range dbfs {
+			if bin := i - size/2; math.Abs(float64(bin)-100.5) > 20 {
+				worst = math.Max(worst, v)
+			}
+		}
+		return worst
+	}
+	rect := farLeakage(Window{Kind: WindowRectangular})
+	bh := farLeakage(Window{Kind: WindowBlackmanHarris})
+	kaiser := farLeakage(Window{Kind: WindowKaiser, Beta: 12})
+	if rect < -50 || bh > rect-50 || kaiser > rect-50 {
+		t.Fatalf("leakage 20 bins off: rectangular %.1f, Blackman-Harris %.1f, Kaiser %.1f dBFS", rect, bh, kaiser)
+	}
+
+	// The window survives a size change and mismatched buffers use it too.
+	c := NewCachedDSP(512)
+	c.SetWindow(Window{Kind: WindowHann})
+	c.UpdateSize(size)
+	want, _ := windowedFFTAndDBFS(samples, Window{Kind: WindowHann}.coefficients(size))
+	got := c.ShiftedFFT(samples)
+	c.UpdateSize(256)
+	fallback := c.ShiftedFFT(samples)
+	for i := range want {
+		if cmplx.Abs(got[i]-want[i]) > 1e-9 || cmplx.Abs(fallback[i]-want[i]) > 1e-9 {
+			t.Fatalf("bin %d: cached %v, fallback %v, want %v", i, got[i], fallback[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package dsp
+
+import (
+	"math"
+	"sync"
+)
+
+// CFOTracker is a first-order frequency-locked loop that keeps the tone at
+// targetHz by continuously removing residual carrier frequency offset. Both
+// channels receive the same correction so their phase difference (and thus the
+// monopulse angle) is preserved.
+type CFOTracker struct {
+	mu         sync.Mutex
+	sampleRate float64
+	targetHz   float64
+	gain       float64 // loop gain applied to each residual estimate (0..1]
+	maxHz      float64 // absolute correction limit
+	correction float64 // current frequency shift in Hz
+	residual   float64 // last measured residual offset in Hz
+	phase      float64 // mixer phase carried across buffers
+}
+
+// NewCFOTracker creates a tracker for a tone expected at targetHz. gain sets the
+// loop bandwidth (0.1–0.5 is a reasonable range); maxHz bounds the correction,
+// with zero meaning a quarter of the sample rate.
+func NewCFOTracker(sampleRate, targetHz, gain, maxHz float64) *CFOTracker {
+	if gain <= 0 || gain > 1 {
+		gain = 0.25
+	}
+	if maxHz <= 0 {
+		maxHz = sampleRate / 4
+	}
+	return &CFOTracker{sampleRate: sampleRate, targetHz: targetHz, gain: gain, maxHz: maxHz}
+}
+
+// Process applies the current correction to rx0 and rx1 in place, measures the
+// remaining offset on rx0 and updates the loop for the next buffer.
+func (c *CFOTracker) Process(rx0, rx1 []complex64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(rx0) < 2 || c.sampleRate <= 0 {
+		return
+	}
+	start := c.phase
+	c.phase = MixInPlace(rx0, c.correction, c.sampleRate, start)
+	MixInPlace(rx1, c.correction, c.sampleRate, start)
+
+	c.residual = c.discriminate(rx0)
+	c.correction -= c.gain * c.residual
+	c.correction = math.Max(-c.maxHz, math.Min(c.maxHz, c.correction))
+}
+
+// discriminate estimates the tone frequency error from the lag-1
+// autocorrelation of the samples de-rotated by the target frequency.
+func (c *CFOTracker) discriminate(samples []complex64) float64 {
+	step := -2 * math.Pi * c.targetHz / c.sampleRate
+	var acc complex128
+	prev := complex128(samples[0])
+	for i := 1; i < len(samples); i++ {
+		cur := complex128(samples[i]) * complex(math.Cos(step*float64(i)), math.Sin(step*float64(i)))
+		acc += cur * complex(real(prev), -imag(prev))
+		prev = cur
+	}
+	return math.Atan2(imag(acc), real(acc)) * c.sampleRate / (2 * math.Pi)
+}
+
+// Correction returns the frequency shift (Hz) currently applied.
+func (c *CFOTracker) Correction() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.correction
+}
+
+// Residual returns the offset (Hz) measured on the last processed buffer.
+func (c *CFOTracker) Residual() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.residual
+}
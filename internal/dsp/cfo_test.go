@@ -0,0 +1,60 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestCFOTrackerConverges(t *testing.T) {
+	const (
+		fs     = 2e6
+		target = 200e3
+		n      = 1024
+	)
+	tests := []struct {
+		name   string
+		offset float64
+	}{
+		{name: "positive_offset", offset: 3e3},
+		{name: "negative_offset", offset: -12e3},
+		{name: "no_offset", offset: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfo := NewCFOTracker(fs, target, 0.5, 0)
+			freq := target + tt.offset
+			var rx0, rx1 []complex64
+			for buf := 0; buf < 40; buf++ {
+				rx0 = make([]complex64, n)
+				rx1 = make([]complex64, n)
+				for i := range rx0 {
+					p := 2 * math.Pi * freq * float64(buf*n+i) / fs
+					rx0[i] = complex64(cmplx.Rect(1, p))
+					rx1[i] = complex64(cmplx.Rect(1, p+math.Pi/3))
+				}
+				cfo.Process(rx0, rx1)
+			}
+			if math.Abs(cfo.Residual()) > 10 {
+				t.Fatalf("residual %.2f Hz did not converge", cfo.Residual())
+			}
+			if math.Abs(cfo.Correction()+tt.offset) > 10 {
+				t.Fatalf("correction %.2f Hz, want %.2f", cfo.Correction(), -tt.offset)
+			}
+			diff := cmplx.Phase(complex128(rx1[100]) * cmplx.Conj(complex128(rx0[100])))
+			if math.Abs(diff-math.Pi/3) > 1e-3 {
+				t.Fatalf("channel phase difference changed to %.4f rad", diff)
+			}
+		})
+	}
+}
+
+func TestCFOTrackerClampsCorrection(t *testing.T) {
+	cfo := NewCFOTracker(1e6, 0, 1, 100)
+	rx0 := tone(256, 5e3, 1e6)
+	rx1 := tone(256, 5e3, 1e6)
+	cfo.Process(rx0, rx1)
+	if got := cfo.Correction(); got != -100 {
+		t.Fatalf("correction %.1f, want clamp at -100", got)
+	}
+}
@@ -0,0 +1,20 @@
+package dsp
+
+import "math"
+
+// ClipFraction returns the fraction of samples whose I or Q component reaches
+// fullScale (within 1%), i.e. samples at or beyond the ADC limits. A
+// non-positive fullScale disables the check and returns 0.
+func ClipFraction(samples []complex64, fullScale float64) float64 {
+	if len(samples) == 0 || fullScale <= 0 {
+		return 0
+	}
+	limit := 0.99 * fullScale
+	clipped := 0
+	for _, s := range samples {
+		if math.Abs(float64(real(s))) >= limit || math.Abs(float64(imag(s))) >= limit {
+			clipped++
+		}
+	}
+	return float64(clipped) / float64(len(samples))
+}
@@ -0,0 +1,25 @@
+package dsp
+
+import "testing"
+
+func TestClipFraction(t *testing.T) {
+	samples := []complex64{complex(0.1, 0.2), complex(1, 0), complex(0, -1), complex(0.5, 0.5)}
+	tests := []struct {
+		name      string
+		samples   []complex64
+		fullScale float64
+		want      float64
+	}{
+		{name: "half clipped", samples: samples, fullScale: 1, want: 0.5},
+		{name: "headroom", samples: samples, fullScale: 2, want: 0},
+		{name: "unknown scale", samples: samples, fullScale: 0, want: 0},
+		{name: "empty", samples: nil, fullScale: 1, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClipFraction(tt.samples, tt.fullScale); got != tt.want {
+				t.Fatalf("ClipFraction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package dsp
+
+// CrossSpectrum returns X0[k]·conj(X1[k]) at the strongest channel 0 bin in
+// [startBin, endBin). Its argument is the phase of rx0 relative to rx1 at the
+// signal, and sums over several buffers average coherently.
+func CrossSpectrum(rx0, rx1 []complex64, startBin, endBin int) (complex128, bool) {
+	n := min(len(rx0), len(rx1))
+	if n == 0 {
+		return 0, false
+	}
+	fft0, db0 := FFTAndDBFS(rx0[:n])
+	fft1, _ := FFTAndDBFS(rx1[:n])
+	_, bin, ok := peakInBand(db0, startBin, endBin)
+	if !ok {
+		return 0, false
+	}
+	x1 := fft1[bin]
+	return fft0[bin] * complex(real(x1), -imag(x1)), true
+}
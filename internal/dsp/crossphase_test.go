@@ -0,0 +1,28 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestCrossSpectrumPhase(t *testing.T) {
+	const n, bin = 256, 20
+	for _, deltaDeg := range []float64{0, 35, -120, 180} {
+		rx0 := make([]complex64, n)
+		rx1 := make([]complex64, n)
+		for i := range rx0 {
+			phase := 2 * math.Pi * bin * float64(i) / n
+			rx0[i] = complex64(cmplx.Exp(complex(0, phase)))
+			rx1[i] = complex64(cmplx.Exp(complex(0, phase+deltaDeg*math.Pi/180)))
+		}
+		x, ok := CrossSpectrum(rx0, rx1, 0, n)
+		if !ok {
+			t.Fatal("no peak found")
+		}
+		got := cmplx.Phase(x) * 180 / math.Pi
+		if diff := math.Mod(got+deltaDeg+540, 360) - 180; math.Abs(diff) > 0.01 {
+			t.Fatalf("delta %v: phase = %v, want %v", deltaDeg, got, -deltaDeg)
+		}
+	}
+}
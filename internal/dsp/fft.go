@@ -26,17 +26,23 @@ func FFTAndDBFS(samples []complex64) ([]complex128, []float64) {
 	if len(samples) == 0 {
 		return []complex128{}, []float64{}
 	}
-	win := Hamming(len(samples))
-	windowed := ApplyWindow(samples, win)
+	return windowedFFTAndDBFS(samples, Window{}.coefficients(len(samples)))
+}
+
+// windowedFFTAndDBFS is FFTAndDBFS with the window win, which must match the
+// sample count.
+func windowedFFTAndDBFS(samples []complex64, win windowCoeffs) ([]complex128, []float64) {
+	windowed := ApplyWindow(samples, win.coeffs)
 	fft := fourier.NewCmplxFFT(len(samples)).Coefficients(nil, windowed)
-	sumWin := 0.0
-	for _, v := range win {
-		sumWin += v
-	}
 	for i := range fft {
-		fft[i] /= complex(sumWin, 0)
+		fft[i] /= complex(win.sum, 0)
 	}
 	shifted := FFTShift(fft)
+	return shifted, shiftedToDBFS(shifted)
+}
+
+// shiftedToDBFS converts a normalized spectrum to dBFS.
+func shiftedToDBFS(shifted []complex128) []float64 {
 	dbfs := make([]float64, len(shifted))
 	for i, v := range shifted {
 		mag := cmplx.Abs(v)
@@ -46,5 +52,5 @@ func FFTAndDBFS(samples []complex64) ([]complex128, []float64) {
 		}
 		dbfs[i] = 20 * math.Log10(mag/adcScale)
 	}
-	return shifted, dbfs
+	return dbfs
 }
@@ -0,0 +1,66 @@
+package dsp
+
+import (
+	"math"
+)
+
+// EstimateToneFrequency returns the baseband frequency (Hz) of the strongest
+// tone in samples. The FFT peak is refined with parabolic interpolation over
+// the neighbouring dB bins, giving sub-bin resolution.
+func EstimateToneFrequency(samples []complex64, sampleRate float64) float64 {
+	n := len(samples)
+	if n < 3 || sampleRate <= 0 {
+		return 0
+	}
+	_, dbfs := FFTAndDBFS(samples)
+	peak := 0
+	for i := 1; i < n; i++ {
+		if dbfs[i] > dbfs[peak] {
+			peak = i
+		}
+	}
+
+	offset := 0.0
+	if peak > 0 && peak < n-1 {
+		a, b, c := dbfs[peak-1], dbfs[peak], dbfs[peak+1]
+		if denom := a - 2*b + c; denom != 0 && !math.IsInf(a, 0) && !math.IsInf(c, 0) {
+			offset = 0.5 * (a - c) / denom
+		}
+	}
+	// FFTAndDBFS returns a shifted spectrum: bin n/2 is DC.
+	return (float64(peak-n/2) + offset) * sampleRate / float64(n)
+}
+
+// FrequencyErrorPPM converts a tone offset (Hz) measured at carrierHz into
+// parts per million. A positive value means the tone appears higher than
+// expected, i.e. the receiver LO runs low relative to the transmitter.
+func FrequencyErrorPPM(offsetHz, carrierHz float64) float64 {
+	if carrierHz == 0 {
+		return 0
+	}
+	return offsetHz / carrierHz * 1e6
+}
+
+// CorrectedXO returns the reference oscillator frequency to program so that a
+// measured tone error of ppm (see FrequencyErrorPPM) is cancelled. On the
+// AD9361 this is the value for the xo_correction attribute.
+func CorrectedXO(xoHz, ppm float64) float64 {
+	return xoHz * (1 - ppm/1e6)
+}
+
+// MixInPlace multiplies samples by a complex exponential of shiftHz starting at
+// phase (radians) and returns the phase to continue with on the next buffer.
+// Apply the same starting phase to both channels to keep their phase
+// difference intact.
+func MixInPlace(samples []complex64, shiftHz, sampleRate, phase float64) float64 {
+	if sampleRate <= 0 {
+		return phase
+	}
+	step := 2 * math.Pi * shiftHz / sampleRate
+	for i, s := range samples {
+		p := phase + step*float64(i)
+		rot := complex64(complex(math.Cos(p), math.Sin(p)))
+		samples[i] = s * rot
+	}
+	return math.Mod(phase+step*float64(len(samples)), 2*math.Pi)
+}
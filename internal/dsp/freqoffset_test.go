@@ -0,0 +1,73 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func tone(n int, freqHz, sampleRate float64) []complex64 {
+	out := make([]complex64, n)
+	for i := range out {
+		phase := 2 * math.Pi * freqHz * float64(i) / sampleRate
+		out[i] = complex64(complex(math.Cos(phase), math.Sin(phase)))
+	}
+	return out
+}
+
+func TestEstimateToneFrequency(t *testing.T) {
+	const fs = 2e6
+	tests := []struct {
+		name string
+		freq float64
+	}{
+		{name: "on_bin", freq: 200e3},
+		{name: "between_bins", freq: 200e3 + 700},
+		{name: "negative", freq: -150e3 - 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateToneFrequency(tone(4096, tt.freq, fs), fs)
+			binWidth := fs / 4096
+			if math.Abs(got-tt.freq) > binWidth/4 {
+				t.Fatalf("estimate %.1f Hz, want %.1f Hz (±%.1f)", got, tt.freq, binWidth/4)
+			}
+		})
+	}
+}
+
+func TestFrequencyErrorPPMAndCorrectedXO(t *testing.T) {
+	ppm := FrequencyErrorPPM(23e3, 2.3e9)
+	if math.Abs(ppm-10) > 1e-9 {
+		t.Fatalf("ppm = %v, want 10", ppm)
+	}
+	if got := CorrectedXO(40e6, ppm); math.Abs(got-(40e6-400)) > 1e-6 {
+		t.Fatalf("corrected xo = %v, want %v", got, 40e6-400)
+	}
+	if FrequencyErrorPPM(1, 0) != 0 {
+		t.Fatalf("expected zero ppm for zero carrier")
+	}
+}
+
+func TestMixInPlacePreservesChannelPhase(t *testing.T) {
+	const fs = 1e6
+	ch0 := tone(1024, 50e3, fs)
+	ch1 := tone(1024, 50e3, fs)
+	for i := range ch1 {
+		ch1[i] *= complex64(cmplx.Rect(1, math.Pi/4))
+	}
+
+	next := MixInPlace(ch0, -50e3, fs, 0)
+	MixInPlace(ch1, -50e3, fs, 0)
+
+	if got := EstimateToneFrequency(ch0, fs); math.Abs(got) > fs/1024 {
+		t.Fatalf("tone not shifted to DC: %.1f Hz", got)
+	}
+	diff := cmplx.Phase(complex128(ch1[500] * complex(real(ch0[500]), -imag(ch0[500]))))
+	if math.Abs(diff-math.Pi/4) > 1e-3 {
+		t.Fatalf("channel phase difference changed: %.4f rad", diff)
+	}
+	if next < -2*math.Pi || next > 2*math.Pi {
+		t.Fatalf("returned phase not wrapped: %v", next)
+	}
+}
@@ -0,0 +1,180 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// logMeanBiasDB is the mean of 10·log10 of exponentially distributed noise
+// power relative to 10·log10 of its mean (−10·γ/ln 10, γ the Euler–Mascheroni
+// constant). noiseFloor averages dB values, so a noise power mean is shifted
+// by this much to match it.
+const logMeanBiasDB = -2.5068
+
+// Goertzel returns bin k of the N-point DFT of x, N = len(x), using the
+// Goertzel recurrence: a few real operations per sample and bin instead of a
+// full FFT.
+func Goertzel(x []complex128, k int) complex128 {
+	n := len(x)
+	if n == 0 {
+		return 0
+	}
+	w := 2 * math.Pi * float64(k) / float64(n)
+	coeff := 2 * math.Cos(w)
+	var r1, r2, i1, i2 float64
+	for _, v := range x {
+		r1, r2 = real(v)+coeff*r1-r2, r1
+		i1, i2 = imag(v)+coeff*i1-i2, i1
+	}
+	// X[k] = e^{jw}·s[N-1] − s[N-2]
+	return cmplx.Exp(complex(0, w))*complex(r1, i1) - complex(r2, i2)
+}
+
+// ShiftedBins returns the given bins of the spectrum ShiftedFFT would return
+// for samples (same window, normalization and bin order), computing only
+// those bins. ok is false when samples do not match the cached size.
+func (c *CachedDSP) ShiftedBins(samples []complex64, bins []int) (out []complex128, ok bool) {
+	c.mu.RLock()
+	window, windowSum, size := c.window, c.windowSum, c.fftSize
+	c.mu.RUnlock()
+	if len(samples) != size || size == 0 {
+		return nil, false
+	}
+	windowed := ApplyWindow(samples, window)
+	out = make([]complex128, len(bins))
+	for i, bin := range bins {
+		out[i] = Goertzel(windowed, (bin+size/2)%size) / complex(windowSum, 0)
+	}
+	return out, true
+}
+
+// goertzelPair holds the Goertzel coefficient and states of one bin on both
+// channels.
+type goertzelPair struct {
+	coeff            float64
+	r0, r0p, i0, i0p float64 // channel 0: s[n-1], s[n-2]
+	r1, r1p, i1, i1p float64 // channel 1
+}
+
+// windowedBand runs the Goertzel recurrences for every bin over both
+// windowed channels in a single pass and returns the bins, normalized like
+// ShiftedFFT, with the channel powers and cross power conj(x0)·x1 of the
+// windowed samples collected on the way.
+func windowedBand(rx0, rx1 []complex64, window []float64, windowSum float64, bins []int) (a, b []complex128, p0, p1 float64, cross complex128) {
+	n := len(window)
+	omega := make([]float64, len(bins))
+	states := make([]goertzelPair, len(bins))
+	for j, bin := range bins {
+		omega[j] = 2 * math.Pi * float64((bin+n/2)%n) / float64(n)
+		states[j].coeff = 2 * math.Cos(omega[j])
+	}
+	var crossRe, crossIm float64
+	for i, w := range window {
+		xr, xi := float64(real(rx0[i]))*w, float64(imag(rx0[i]))*w
+		yr, yi := float64(real(rx1[i]))*w, float64(imag(rx1[i]))*w
+		p0 += xr*xr + xi*xi
+		p1 += yr*yr + yi*yi
+		crossRe += xr*yr + xi*yi
+		crossIm += xr*yi - xi*yr
+		for j := range states {
+			g := &states[j]
+			g.r0, g.r0p = xr+g.coeff*g.r0-g.r0p, g.r0
+			g.i0, g.i0p = xi+g.coeff*g.i0-g.i0p, g.i0
+			g.r1, g.r1p = yr+g.coeff*g.r1-g.r1p, g.r1
+			g.i1, g.i1p = yi+g.coeff*g.i1-g.i1p, g.i1
+		}
+	}
+	a, b = make([]complex128, len(bins)), make([]complex128, len(bins))
+	norm := complex(windowSum, 0)
+	for j, g := range states {
+		// X[k] = e^{jw}·s[N-1] − s[N-2]
+		rot := cmplx.Exp(complex(0, omega[j]))
+		a[j] = (rot*complex(g.r0, g.i0) - complex(g.r0p, g.i0p)) / norm
+		b[j] = (rot*complex(g.r1, g.i1) - complex(g.r1p, g.i1p)) / norm
+	}
+	return a, b, p0, p1, complex(crossRe, crossIm)
+}
+
+// MonopulseTrackBins is the steady-state counterpart of MonopulseTrackParallel
+// for a target whose tone sits near centerBin (in ShiftedFFT order). It
+// computes only the bins within halfWidth of centerBin on each channel and
+// takes the monopulse phase and peak from them. The noise floor comes from
+// Parseval's theorem: the sum channel's total spectral energy follows from
+// the time-domain channel powers and cross power for each steering
+// hypothesis, and the energy outside the computed bins, spread over the
+// remaining bins, is the noise. That covers the whole spectrum rather than
+// the signal band, so interferers elsewhere raise it.
+//
+// It falls back to MonopulseTrackParallel when the buffer size does not
+// match dsp.
+func MonopulseTrackBins(
+	targets []TrackTarget,
+	rx0, rx1 []complex64,
+	phaseCal float64,
+	centerBin, halfWidth int,
+	phaseStep float64,
+	dsp *CachedDSP,
+) []TrackMeasurement {
+	n := min(len(rx0), len(rx1))
+	if n == 0 || len(targets) == 0 {
+		return nil
+	}
+	dsp.mu.RLock()
+	window, windowSum, size := dsp.window, dsp.windowSum, dsp.fftSize
+	dsp.mu.RUnlock()
+	if n != size {
+		return MonopulseTrackParallel(targets, rx0, rx1, phaseCal, 0, n, phaseStep, dsp)
+	}
+
+	lo, hi := max(centerBin-halfWidth, 0), min(centerBin+halfWidth+1, n)
+	if lo >= hi {
+		return MonopulseTrackParallel(targets, rx0, rx1, phaseCal, 0, n, phaseStep, dsp)
+	}
+	bins := make([]int, 0, hi-lo)
+	for b := lo; b < hi; b++ {
+		bins = append(bins, b)
+	}
+
+	a, b, p0, p1, cross := windowedBand(rx0[:n], rx1[:n], window, windowSum, bins)
+	// Time-domain powers scale to the normalized spectrum by Parseval:
+	// Σ|X|² = N·Σ|x·w|² / windowSum².
+	scale := float64(n) / (windowSum * windowSum)
+
+	results := make([]TrackMeasurement, 0, len(targets))
+	for _, target := range targets {
+		phaseFactor := cmplx.Exp(complex(0, (target.Delay+phaseCal)*degToRad))
+
+		var corr complex128
+		var signal float64
+		peakPower, peakBin := -1.0, lo
+		for i := range bins {
+			shifted := phaseFactor * b[i]
+			s, d := a[i]+shifted, a[i]-shifted
+			corr += cmplx.Conj(s) * d
+			power := real(s)*real(s) + imag(s)*imag(s)
+			signal += power
+			if power > peakPower {
+				peakPower, peakBin = power, bins[i]
+			}
+		}
+		monoPhase := cmplx.Phase(corr)
+
+		m := TrackMeasurement{ID: target.ID, Delay: target.Delay, MonoPhase: monoPhase, PeakBin: peakBin}
+		if peakPower > 0 {
+			m.Peak = 10 * math.Log10(peakPower/(adcScale*adcScale))
+			total := scale * (p0 + p1 + 2*real(phaseFactor*cross))
+			if rest := n - len(bins); rest > 0 && total > signal {
+				noise := 10*math.Log10((total-signal)/float64(rest)/(adcScale*adcScale)) + logMeanBiasDB
+				m.SNR = m.Peak - noise
+			}
+		}
+
+		if monoPhase > monoDeadbandRad {
+			m.Delay = target.Delay + phaseStep
+		} else if monoPhase < -monoDeadbandRad {
+			m.Delay = target.Delay - phaseStep
+		}
+		results = append(results, m)
+	}
+	return results
+}
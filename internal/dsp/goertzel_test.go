@@ -0,0 +1,118 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// simulateToneArray returns a tone at toneBin (cycles per buffer) arriving
+// with the given inter-element phase, plus complex noise of std dev sigma,
+// scaled to ADC counts.
+func simulateToneArray(n int, toneBin, phaseDeg, sigma float64) ([]complex64, []complex64) {
+	rng := rand.New(rand.NewSource(7))
+	rx0 := make([]complex64, n)
+	rx1 := make([]complex64, n)
+	for i := range rx0 {
+		s := cmplx.Exp(complex(0, 2*math.Pi*toneBin*float64(i)/float64(n))) * 1000
+		noise := func() complex128 { return complex(rng.NormFloat64()*sigma, rng.NormFloat64()*sigma) }
+		rx0[i] = complex64(s + noise())
+		rx1[i] = complex64(s*cmplx.Exp(complex(0, phaseDeg*degToRad)) + noise())
+	}
+	return rx0, rx1
+}
+
+func TestShiftedBinsMatchesShiftedFFT(t *testing.T) {
+	const n = 1024
+	rx0, _ := simulateToneArray(n, 100.3, 0, 20)
+	c := NewCachedDSP(n)
+	full := c.ShiftedFFT(rx0)
+	bins := []int{0, 1, n/2 - 1, n / 2, n/2 + 100, n - 1}
+	got, ok := c.ShiftedBins(rx0, bins)
+	if !ok {
+		t.Fatal("ShiftedBins rejected a matching buffer")
+	}
+	for i, bin := range bins {
+		if d := cmplx.Abs(got[i] - full[bin]); d > 1e-9*(1+cmplx.Abs(full[bin])) {
+			t.Errorf("bin %d = %v, want %v", bin, got[i], full[bin])
+		}
+	}
+	if _, ok := c.ShiftedBins(rx0[:n/2], bins); ok {
+		t.Error("ShiftedBins accepted a buffer of the wrong size")
+	}
+}
+
+func TestMonopulseTrackBinsMatchesFullFFT(t *testing.T) {
+	const (
+		n         = 4096
+		toneBin   = 409.6 // 0.1 of the sample rate
+		phaseStep = 1.0
+	)
+	start, end := SignalBinRange(n, 1, 0.1)
+	c := NewCachedDSP(n)
+	tests := []struct {
+		name     string
+		phaseDeg float64
+		delay    float64
+		sigma    float64
+	}{
+		{name: "on target", phaseDeg: 30, delay: -30, sigma: 30},
+		{name: "steer up", phaseDeg: 30, delay: -40, sigma: 30},
+		{name: "steer down", phaseDeg: -50, delay: 40, sigma: 30},
+		{name: "low snr", phaseDeg: 10, delay: -25, sigma: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rx0, rx1 := simulateToneArray(n, toneBin, tt.phaseDeg, tt.sigma)
+			targets := []TrackTarget{{ID: 1, Delay: tt.delay}}
+			want := MonopulseTrackParallel(targets, rx0, rx1, 0, start, end, phaseStep, c)[0]
+			got := MonopulseTrackBins(targets, rx0, rx1, 0, want.PeakBin, 2, phaseStep, c)[0]
+
+			if got.Delay != want.Delay {
+				t.Errorf("delay = %v, want %v", got.Delay, want.Delay)
+			}
+			if got.PeakBin != want.PeakBin {
+				t.Errorf("peak bin = %d, want %d", got.PeakBin, want.PeakBin)
+			}
+			if math.Abs(got.Peak-want.Peak) > 1e-6 {
+				t.Errorf("peak = %.6f dBFS, want %.6f", got.Peak, want.Peak)
+			}
+			if math.Signbit(got.MonoPhase) != math.Signbit(want.MonoPhase) {
+				t.Errorf("monopulse phase = %v, want the sign of %v", got.MonoPhase, want.MonoPhase)
+			}
+			if math.Abs(got.SNR-want.SNR) > 1.5 {
+				t.Errorf("snr = %.2f dB, want %.2f ± 1.5", got.SNR, want.SNR)
+			}
+		})
+	}
+}
+
+func TestMonopulseTrackBinsSizeMismatch(t *testing.T) {
+	rx0, rx1 := simulateToneArray(512, 50, 0, 10)
+	targets := []TrackTarget{{ID: 1}}
+	got := MonopulseTrackBins(targets, rx0, rx1, 0, 300, 2, 1, NewCachedDSP(1024))
+	if len(got) != 1 || got[0].Peak == 0 {
+		t.Fatalf("fallback measurements = %+v", got)
+	}
+}
+
+func BenchmarkMonopulseTrack(b *testing.B) {
+	const n = 4096
+	rx0, rx1 := simulateToneArray(n, 409.6, 30, 30)
+	start, end := SignalBinRange(n, 1, 0.1)
+	c := NewCachedDSP(n)
+	targets := []TrackTarget{{ID: 1, Delay: -30}, {ID: 2, Delay: 20}, {ID: 3, Delay: 60}}
+	center := MonopulseTrackParallel(targets[:1], rx0, rx1, 0, start, end, 1, c)[0].PeakBin
+
+	b.Run("full-fft", func(b *testing.B) {
+		for b.Loop() {
+			_ = MonopulseTrackParallel(targets, rx0, rx1, 0, start, end, 1, c)
+		}
+	})
+	b.Run("goertzel", func(b *testing.B) {
+		for b.Loop() {
+			_ = MonopulseTrackBins(targets, rx0, rx1, 0, center, 2, 1, c)
+		}
+	})
+}
@@ -547,20 +547,56 @@ func CoarseScanParallel(
 		stepDeg = 2
 	}
 
-	n := len(rx0)
-	if len(rx1) < n {
-		n = len(rx1)
-	}
-	if n == 0 {
-		return nil
-	}
-
 	// Build the phase grid.
 	var phases []float64
 	for phase := -180.0; phase < 180.0; phase += stepDeg {
 		phases = append(phases, phase)
 	}
-	if len(phases) == 0 {
+	return scanPhaseGrid(phases, rx0, rx1, phaseCal, startBin, endBin, freqHz, spacingWavelength, dsp)
+}
+
+// CoarseScanWindow is CoarseScanParallel restricted to the phase hypotheses
+// within spanDeg/2 of centerDeg, wrapped into [-180, 180). It confirms a
+// target near a known steering phase at a fraction of the cost of the full
+// scan.
+func CoarseScanWindow(
+	rx0, rx1 []complex64,
+	phaseCal float64,
+	startBin, endBin int,
+	centerDeg, spanDeg float64,
+	stepDeg float64,
+	freqHz float64,
+	spacingWavelength float64,
+	dsp *CachedDSP,
+) []PeakInfo {
+	if stepDeg == 0 {
+		stepDeg = 2
+	}
+	spanDeg = math.Min(spanDeg, 360)
+
+	var phases []float64
+	for offset := -spanDeg / 2; offset <= spanDeg/2; offset += stepDeg {
+		phases = append(phases, math.Mod(math.Mod(centerDeg+offset+180, 360)+360, 360)-180)
+	}
+	return scanPhaseGrid(phases, rx0, rx1, phaseCal, startBin, endBin, freqHz, spacingWavelength, dsp)
+}
+
+// scanPhaseGrid evaluates the phase hypotheses on a worker pool and returns
+// the peaks of the SNR trace across them, best first.
+func scanPhaseGrid(
+	phases []float64,
+	rx0, rx1 []complex64,
+	phaseCal float64,
+	startBin, endBin int,
+	freqHz float64,
+	spacingWavelength float64,
+	dsp *CachedDSP,
+) []PeakInfo {
+	n := len(rx0)
+	if len(rx1) < n {
+		n = len(rx1)
+	}
+	if n == 0 || len(phases) == 0 {
 		return nil
 	}
 
@@ -100,6 +100,39 @@ func TestCoarseScanParallel_SingleTarget(t *testing.T) {
 	}
 }
 
+func TestCoarseScanWindow(t *testing.T) {
+	const (
+		nSamples          = 1024
+		spacingWavelength = 0.5
+		stepDeg           = 1.0
+	)
+	rx0, rx1 := simulateTwoElementArray(20, nSamples, 20, spacingWavelength)
+	dsp := NewCachedDSP(nSamples)
+	full := CoarseScanParallel(rx0, rx1, 0, 0, 0, stepDeg, 1.0, spacingWavelength, dsp)
+	if len(full) == 0 {
+		t.Fatal("full scan returned no peaks")
+	}
+
+	tests := []struct {
+		name   string
+		center float64
+	}{
+		{"centered", full[0].Phase},
+		{"offset", full[0].Phase + 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peaks := CoarseScanWindow(rx0, rx1, 0, 0, 0, tt.center, 30, stepDeg, 1.0, spacingWavelength, dsp)
+			if len(peaks) == 0 {
+				t.Fatal("window scan returned no peaks")
+			}
+			if math.Abs(peaks[0].Phase-full[0].Phase) > stepDeg {
+				t.Fatalf("window scan peak at %.1f°, full scan at %.1f°", peaks[0].Phase, full[0].Phase)
+			}
+		})
+	}
+}
+
 func TestMonopulseTrackParallelMultipleDelays(t *testing.T) {
 	const (
 		nSamples          = 1024
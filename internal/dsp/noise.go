@@ -0,0 +1,37 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+)
+
+// NoisePowerDB returns the mean per-bin power (dBFS) of samples, averaged in
+// the linear domain and skipping bins in [excludeStart, excludeEnd) so a tone
+// in the signal band does not bias the result.
+func NoisePowerDB(samples []complex64, excludeStart, excludeEnd int) (float64, bool) {
+	_, dbfs := FFTAndDBFS(samples)
+	var sum float64
+	var count int
+	for i, v := range dbfs {
+		if (i >= excludeStart && i < excludeEnd) || math.IsInf(v, 0) || math.IsNaN(v) {
+			continue
+		}
+		sum += math.Pow(10, v/10)
+		count++
+	}
+	if count == 0 || sum == 0 {
+		return 0, false
+	}
+	return 10 * math.Log10(sum/float64(count)), true
+}
+
+// YFactorNoiseFigure returns the receiver noise figure (dB) from the noise
+// power measured with the noise source on (hotDB) and off (coldDB), given the
+// source's excess noise ratio enrDB: NF = ENR - 10·log10(Y - 1).
+func YFactorNoiseFigure(hotDB, coldDB, enrDB float64) (float64, error) {
+	y := math.Pow(10, (hotDB-coldDB)/10)
+	if y <= 1 {
+		return 0, fmt.Errorf("y-factor %.3f dB is not positive; is the noise source connected?", hotDB-coldDB)
+	}
+	return enrDB - 10*math.Log10(y-1), nil
+}
@@ -0,0 +1,59 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestYFactorNoiseFigure(t *testing.T) {
+	tests := []struct {
+		name    string
+		hot     float64
+		cold    float64
+		enr     float64
+		want    float64
+		wantErr bool
+	}{
+		// Y = 2 (3.01 dB) gives NF = ENR exactly.
+		{name: "y equals two", hot: -60 + 10*math.Log10(2), cold: -60, enr: 15, want: 15},
+		// Y = 11 (10.41 dB) gives NF = ENR - 10 dB.
+		{name: "y equals eleven", hot: -50 + 10*math.Log10(11), cold: -50, enr: 15, want: 5},
+		{name: "no rise", hot: -60, cold: -60, enr: 15, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := YFactorNoiseFigure(tt.hot, tt.cold, tt.enr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("NF = %.6f, want %.6f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoisePowerDBIgnoresExcludedTone(t *testing.T) {
+	const n = 1024
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]complex64, n)
+	for i := range noise {
+		noise[i] = complex64(complex(rng.NormFloat64()*10, rng.NormFloat64()*10))
+	}
+	withTone := make([]complex64, n)
+	tn := tone(n, 100e3, 1e6)
+	for i := range withTone {
+		withTone[i] = noise[i] + 1000*tn[i]
+	}
+	start, end := SignalBinRange(n, 1e6, 100e3)
+
+	base, ok := NoisePowerDB(noise, start, end)
+	if !ok {
+		t.Fatal("expected a noise estimate")
+	}
+	got, _ := NoisePowerDB(withTone, start, end)
+	if math.Abs(got-base) > 1 {
+		t.Fatalf("tone leaked into noise estimate: %.2f vs %.2f dB", got, base)
+	}
+}
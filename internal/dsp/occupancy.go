@@ -0,0 +1,129 @@
+package dsp
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BandOccupancy summarizes one sub-band of the capture bandwidth since the
+// monitor was created or reset. Frequencies are offsets from the LO in Hz.
+type BandOccupancy struct {
+	LowHz       float64 `json:"lowHz"`
+	HighHz      float64 `json:"highHz"`
+	DutyCycle   float64 `json:"dutyCycle"` // fraction of frames above the occupancy threshold
+	AvgPowerDB  float64 `json:"avgPowerDb"`
+	PeakPowerDB float64 `json:"peakPowerDb"`
+	LastPowerDB float64 `json:"lastPowerDb"`
+}
+
+// OccupancyMonitor splits FFT power spectra into equal sub-bands and keeps
+// per-band duty cycle and power statistics. A band counts as occupied in a
+// frame when its mean power exceeds the frame's noise floor, the median band
+// power, by the threshold. The median adapts to gain changes and stays
+// valid as long as fewer than half the bands are occupied.
+type OccupancyMonitor struct {
+	mu          sync.Mutex
+	bands       int
+	thresholdDB float64
+	sampleRate  float64
+
+	frames   int
+	occupied []int
+	linSum   []float64
+	peak     []float64
+	last     []float64
+}
+
+// NewOccupancyMonitor creates a monitor with the given number of sub-bands
+// across sampleRate Hz of bandwidth. Non-positive arguments select 16 bands
+// and a 6 dB threshold.
+func NewOccupancyMonitor(bands int, sampleRate, thresholdDB float64) *OccupancyMonitor {
+	if bands <= 0 {
+		bands = 16
+	}
+	if thresholdDB <= 0 {
+		thresholdDB = 6
+	}
+	m := &OccupancyMonitor{bands: bands, thresholdDB: thresholdDB, sampleRate: sampleRate}
+	m.reset()
+	return m
+}
+
+// Update adds one zero-centred dBFS spectrum, as returned by FFTAndDBFS.
+// Spectra with fewer bins than bands are ignored.
+func (m *OccupancyMonitor) Update(spectrumDB []float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(spectrumDB) < m.bands {
+		return
+	}
+	power := make([]float64, m.bands)
+	for b := range power {
+		lo, hi := b*len(spectrumDB)/m.bands, (b+1)*len(spectrumDB)/m.bands
+		var sum float64
+		for _, db := range spectrumDB[lo:hi] {
+			sum += math.Pow(10, db/10)
+		}
+		// Floor at -200 dB so silent bands stay finite.
+		power[b] = math.Max(sum/float64(hi-lo), 1e-20)
+	}
+	floor := medianPower(power)
+
+	m.frames++
+	for b, p := range power {
+		db := 10 * math.Log10(p)
+		if 10*math.Log10(p/floor) > m.thresholdDB {
+			m.occupied[b]++
+		}
+		m.linSum[b] += p
+		if m.frames == 1 || db > m.peak[b] {
+			m.peak[b] = db
+		}
+		m.last[b] = db
+	}
+}
+
+// Stats returns the per-band statistics, lowest band first, and the number
+// of frames they cover.
+func (m *OccupancyMonitor) Stats() ([]BandOccupancy, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	width := m.sampleRate / float64(m.bands)
+	out := make([]BandOccupancy, m.bands)
+	for b := range out {
+		out[b] = BandOccupancy{
+			LowHz:  -m.sampleRate/2 + float64(b)*width,
+			HighHz: -m.sampleRate/2 + float64(b+1)*width,
+		}
+		if m.frames == 0 {
+			continue
+		}
+		out[b].DutyCycle = float64(m.occupied[b]) / float64(m.frames)
+		out[b].AvgPowerDB = 10 * math.Log10(m.linSum[b]/float64(m.frames))
+		out[b].PeakPowerDB = m.peak[b]
+		out[b].LastPowerDB = m.last[b]
+	}
+	return out, m.frames
+}
+
+// Reset clears the accumulated statistics.
+func (m *OccupancyMonitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset()
+}
+
+func (m *OccupancyMonitor) reset() {
+	m.frames = 0
+	m.occupied = make([]int, m.bands)
+	m.linSum = make([]float64, m.bands)
+	m.peak = make([]float64, m.bands)
+	m.last = make([]float64, m.bands)
+}
+
+func medianPower(power []float64) float64 {
+	sorted := append([]float64(nil), power...)
+	sort.Float64s(sorted)
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,50 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOccupancyMonitor(t *testing.T) {
+	m := NewOccupancyMonitor(4, 1e6, 6)
+	quiet := []float64{-90, -90, -90, -90, -90, -90, -90, -90}
+	busy := []float64{-90, -90, -90, -90, -90, -90, -30, -30} // last band occupied
+
+	for i := 0; i < 4; i++ {
+		spectrum := quiet
+		if i%2 == 0 {
+			spectrum = busy
+		}
+		m.Update(spectrum)
+	}
+	m.Update([]float64{-90}) // too short, ignored
+
+	bands, frames := m.Stats()
+	if frames != 4 || len(bands) != 4 {
+		t.Fatalf("got %d frames, %d bands", frames, len(bands))
+	}
+	if bands[0].LowHz != -500e3 || bands[3].HighHz != 500e3 {
+		t.Fatalf("unexpected band edges %+v", bands)
+	}
+	tests := []struct {
+		band            int
+		duty, avg, peak float64
+	}{
+		{band: 0, duty: 0, avg: -90, peak: -90},
+		{band: 3, duty: 0.5, avg: 10 * math.Log10((1e-3+1e-9)/2), peak: -30},
+	}
+	for _, tt := range tests {
+		b := bands[tt.band]
+		if b.DutyCycle != tt.duty || math.Abs(b.AvgPowerDB-tt.avg) > 1e-9 || b.PeakPowerDB != tt.peak {
+			t.Fatalf("band %d: %+v, want duty %v avg %.2f peak %v", tt.band, b, tt.duty, tt.avg, tt.peak)
+		}
+	}
+	if bands[3].LastPowerDB != -90 {
+		t.Fatalf("last power %v, want -90", bands[3].LastPowerDB)
+	}
+
+	m.Reset()
+	if _, frames := m.Stats(); frames != 0 {
+		t.Fatalf("expected reset stats, got %d frames", frames)
+	}
+}
@@ -0,0 +1,67 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// patternFloorDB replaces -Inf levels (exact nulls) so results stay printable.
+const patternFloorDB = -200.0
+
+// SumDeltaResponse steers rx1 by phaseDeg (plus phaseCal) and returns the sum
+// peak level in the signal band, the delta level at the same bin (both dBFS)
+// and the monopulse phase. Sweeping phaseDeg traces the array's monopulse
+// response curve.
+func SumDeltaResponse(rx0, rx1 []complex64, phaseDeg, phaseCal float64, startBin, endBin int) (sumDB, deltaDB, monoPhase float64, ok bool) {
+	n := min(len(rx0), len(rx1))
+	if n == 0 {
+		return 0, 0, 0, false
+	}
+	adjusted := make([]complex64, n)
+	sumBuf := make([]complex64, n)
+	deltaBuf := make([]complex64, n)
+
+	phaseFactor := complex64(cmplx.Exp(complex(0, (phaseDeg+phaseCal)*degToRad)))
+	complexScale(adjusted, rx1[:n], phaseFactor)
+	sumDeltaForms(sumBuf, deltaBuf, rx0[:n], adjusted)
+
+	sumFFT, sumDBFS := FFTAndDBFS(sumBuf)
+	deltaFFT, deltaDBFS := FFTAndDBFS(deltaBuf)
+	sumDB, bin, ok := peakInBand(sumDBFS, startBin, endBin)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	deltaDB = math.Max(deltaDBFS[bin], patternFloorDB)
+	return sumDB, deltaDB, MonopulsePhase(sumFFT, deltaFFT, startBin, endBin), true
+}
+
+// MonopulseError steers rx1 by phaseDeg (plus phaseCal) like
+// SumDeltaResponse and returns the signed monopulse error
+// Im(Σ conj(S)·Δ) / Σ |S|² over the signal band, together with the mean sum
+// power per bin. For a single source the error is -tan(φ/2), φ being the
+// phase left between the steered channels, so it is zero when the steering
+// matches the source and changes sign across it.
+func MonopulseError(rx0, rx1 []complex64, phaseDeg, phaseCal float64, startBin, endBin int) (errValue, sumPower float64, ok bool) {
+	n := min(len(rx0), len(rx1))
+	if n == 0 {
+		return 0, 0, false
+	}
+	adjusted := make([]complex64, n)
+	sumBuf := make([]complex64, n)
+	deltaBuf := make([]complex64, n)
+	complexScale(adjusted, rx1[:n], complex64(cmplx.Exp(complex(0, (phaseDeg+phaseCal)*degToRad))))
+	sumDeltaForms(sumBuf, deltaBuf, rx0[:n], adjusted)
+
+	sumFFT, _ := FFTAndDBFS(sumBuf)
+	deltaFFT, _ := FFTAndDBFS(deltaBuf)
+	s, e := binRange(n, startBin, endBin)
+	var corr complex128
+	for i := s; i < e; i++ {
+		corr += cmplx.Conj(sumFFT[i]) * deltaFFT[i]
+		sumPower += real(sumFFT[i])*real(sumFFT[i]) + imag(sumFFT[i])*imag(sumFFT[i])
+	}
+	if sumPower == 0 {
+		return 0, 0, false
+	}
+	return imag(corr) / sumPower, sumPower / float64(e-s), true
+}
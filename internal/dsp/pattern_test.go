@@ -0,0 +1,69 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestSumDeltaResponseNullAtMatchedPhase(t *testing.T) {
+	const (
+		n     = 1024
+		fs    = 1e6
+		freq  = 100e3
+		delta = 30.0
+	)
+	rx0 := tone(n, freq, fs)
+	rx1 := make([]complex64, n)
+	shift := complex64(cmplx.Exp(complex(0, delta*math.Pi/180)))
+	for i := range rx0 {
+		rx1[i] = rx0[i] * shift
+	}
+	start, end := SignalBinRange(n, fs, freq)
+
+	matchedSum, matchedDelta, _, ok := SumDeltaResponse(rx0, rx1, -delta, 0, start, end)
+	if !ok {
+		t.Fatal("expected a response at the matched phase")
+	}
+	offSum, offDelta, _, ok := SumDeltaResponse(rx0, rx1, -delta+60, 0, start, end)
+	if !ok {
+		t.Fatal("expected a response off the matched phase")
+	}
+	if matchedSum <= offSum {
+		t.Fatalf("sum should peak at matched phase: matched %.1f off %.1f", matchedSum, offSum)
+	}
+	if matchedDelta >= offDelta-40 {
+		t.Fatalf("delta should null at matched phase: matched %.1f off %.1f", matchedDelta, offDelta)
+	}
+}
+
+func TestSumDeltaResponseEmpty(t *testing.T) {
+	if _, _, _, ok := SumDeltaResponse(nil, nil, 0, 0, 0, 0); ok {
+		t.Fatal("expected ok=false for empty input")
+	}
+}
+
+func TestMonopulseError(t *testing.T) {
+	const (
+		n    = 1024
+		fs   = 1e6
+		freq = 100e3
+	)
+	rx0 := tone(n, freq, fs)
+	rx1 := make([]complex64, n)
+	shift := complex64(cmplx.Exp(complex(0, 40*math.Pi/180)))
+	for i := range rx0 {
+		rx1[i] = rx0[i] * shift
+	}
+	start, end := SignalBinRange(n, fs, freq)
+	for _, cal := range []float64{-40, -60, 0, 100} {
+		got, _, ok := MonopulseError(rx0, rx1, 0, cal, start, end)
+		want := -math.Tan((40 + cal) * math.Pi / 360)
+		if !ok || math.Abs(got-want) > 1e-3 {
+			t.Fatalf("phase cal %v°: error %v, want %v", cal, got, want)
+		}
+	}
+	if _, _, ok := MonopulseError(nil, nil, 0, 0, 0, 0); ok {
+		t.Fatal("expected ok=false for empty input")
+	}
+}
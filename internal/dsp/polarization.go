@@ -0,0 +1,77 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// PolarizationResponse returns the spectra of the horizontal and vertical
+// channels of one dual-polarized element at the signal: the bin in
+// [startBin, endBin) with the most total power |H|²+|V|².
+func PolarizationResponse(h, v []complex64, startBin, endBin int) (hk, vk complex128, ok bool) {
+	n := min(len(h), len(v))
+	if n == 0 {
+		return 0, 0, false
+	}
+	fftH, _ := FFTAndDBFS(h[:n])
+	fftV, _ := FFTAndDBFS(v[:n])
+	s, e := binRange(n, startBin, endBin)
+	best, bin := -1.0, 0
+	for i := s; i < e; i++ {
+		p := sqAbs(fftH[i]) + sqAbs(fftV[i])
+		if p > best {
+			best, bin = p, i
+		}
+	}
+	if best <= 0 {
+		return 0, 0, false
+	}
+	return fftH[bin], fftV[bin], true
+}
+
+// PolarizationRatioDB returns the horizontal to vertical power ratio in dB of
+// the summed element responses; positive means horizontal dominates.
+func PolarizationRatioDB(h, v []complex128) float64 {
+	var ph, pv float64
+	for _, x := range h {
+		ph += sqAbs(x)
+	}
+	for _, x := range v {
+		pv += sqAbs(x)
+	}
+	switch {
+	case pv == 0 && ph == 0:
+		return 0
+	case pv == 0:
+		return math.Inf(1)
+	case ph == 0:
+		return math.Inf(-1)
+	}
+	return 10 * math.Log10(ph/pv)
+}
+
+// PolarizationWeights returns maximal-ratio combining weights for an element
+// response: each polarization is weighted by the conjugate of its response,
+// so both add in phase, and the weights are normalised to unit power. Apply
+// the same weights to every element to keep the inter-element phase.
+func PolarizationWeights(hk, vk complex128) (wH, wV complex128) {
+	norm := math.Sqrt(sqAbs(hk) + sqAbs(vk))
+	if norm == 0 {
+		return 1, 0
+	}
+	return cmplx.Conj(hk) / complex(norm, 0), cmplx.Conj(vk) / complex(norm, 0)
+}
+
+// CombinePolarizations returns wH·h + wV·v.
+func CombinePolarizations(h, v []complex64, wH, wV complex128) []complex64 {
+	n := min(len(h), len(v))
+	out := make([]complex64, n)
+	for i := 0; i < n; i++ {
+		out[i] = complex64(wH*complex128(h[i]) + wV*complex128(v[i]))
+	}
+	return out
+}
+
+func sqAbs(x complex128) float64 {
+	return real(x)*real(x) + imag(x)*imag(x)
+}
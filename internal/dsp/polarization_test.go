@@ -0,0 +1,59 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestPolarizationCombining(t *testing.T) {
+	const n, bin = 256, 20
+	tests := []struct {
+		name      string
+		tiltDeg   float64 // share of the tone on H (cos) and V (sin)
+		vPhaseDeg float64 // phase of V relative to H, e.g. circular polarization
+		wantRatio float64
+	}{
+		{"horizontal", 0, 0, maxRatioForTest},
+		{"slant", 45, 0, 0},
+		{"mostly vertical", 80, 0, 20 * math.Log10(math.Cos(80*math.Pi/180)/math.Sin(80*math.Pi/180))},
+		{"circular", 45, 90, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tilt := tt.tiltDeg * math.Pi / 180
+			h := make([]complex64, n)
+			v := make([]complex64, n)
+			for i := range h {
+				phase := 2 * math.Pi * bin * float64(i) / n
+				h[i] = complex64(complex(math.Cos(tilt), 0) * cmplx.Exp(complex(0, phase)))
+				v[i] = complex64(complex(math.Sin(tilt), 0) * cmplx.Exp(complex(0, phase+tt.vPhaseDeg*math.Pi/180)))
+			}
+			hk, vk, ok := PolarizationResponse(h, v, 0, n)
+			if !ok {
+				t.Fatal("no signal found")
+			}
+			ratio := math.Min(PolarizationRatioDB([]complex128{hk}, []complex128{vk}), maxRatioForTest)
+			if math.Abs(ratio-tt.wantRatio) > 0.01 {
+				t.Fatalf("ratio = %v dB, want %v", ratio, tt.wantRatio)
+			}
+
+			// Maximal-ratio combining recovers the full tone power whatever
+			// the polarization.
+			wH, wV := PolarizationWeights(hk, vk)
+			combined := CombinePolarizations(h, v, wH, wV)
+			_, db := FFTAndDBFS(combined)
+			_, dbH := FFTAndDBFS(h)
+			_, dbV := FFTAndDBFS(v)
+			best := math.Max(dbH[bin+n/2], dbV[bin+n/2])
+			full := best - 10*math.Log10(math.Max(math.Cos(tilt)*math.Cos(tilt), math.Sin(tilt)*math.Sin(tilt)))
+			if math.Abs(db[bin+n/2]-full) > 0.01 {
+				t.Fatalf("combined power = %v dBFS, want %v", db[bin+n/2], full)
+			}
+		})
+	}
+}
+
+// maxRatioForTest stands in for the infinite ratio of a purely horizontal
+// signal.
+const maxRatioForTest = 300
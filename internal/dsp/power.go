@@ -0,0 +1,34 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// PowerDBFS returns the mean power of samples in dB relative to a full-scale
+// I/Q magnitude of fullScale (1 when non-positive). An empty or silent
+// buffer returns -Inf.
+func PowerDBFS(samples []complex64, fullScale float64) float64 {
+	if fullScale <= 0 {
+		fullScale = 1
+	}
+	power := 0.0
+	for _, s := range samples {
+		power += float64(real(s))*float64(real(s)) + float64(imag(s))*float64(imag(s))
+	}
+	if power == 0 {
+		return math.Inf(-1)
+	}
+	return 10 * math.Log10(power/float64(len(samples))/(fullScale*fullScale))
+}
+
+// PhaseDifference returns the phase of rx1 relative to rx0 in radians,
+// averaged over the buffer as the argument of sum(rx1·conj(rx0)). It covers
+// the whole band, so it tracks the signal only when the signal dominates.
+func PhaseDifference(rx0, rx1 []complex64) float64 {
+	var corr complex128
+	for i := range min(len(rx0), len(rx1)) {
+		corr += complex128(rx1[i]) * cmplx.Conj(complex128(rx0[i]))
+	}
+	return cmplx.Phase(corr)
+}
@@ -0,0 +1,49 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestPowerDBFS(t *testing.T) {
+	tone := make([]complex64, 64)
+	for i := range tone {
+		tone[i] = complex64(cmplx.Rect(0.5, float64(i)*0.3))
+	}
+	tests := []struct {
+		name      string
+		samples   []complex64
+		fullScale float64
+		want      float64
+	}{
+		{name: "half scale", samples: tone, fullScale: 1, want: -6.0206},
+		{name: "full scale", samples: tone, fullScale: 0.5, want: 0},
+		{name: "unknown scale", samples: tone, fullScale: 0, want: -6.0206},
+		{name: "silent", samples: make([]complex64, 8), fullScale: 1, want: math.Inf(-1)},
+		{name: "empty", samples: nil, fullScale: 1, want: math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PowerDBFS(tt.samples, tt.fullScale)
+			if math.IsInf(tt.want, -1) != math.IsInf(got, -1) || (!math.IsInf(got, -1) && math.Abs(got-tt.want) > 1e-3) {
+				t.Fatalf("PowerDBFS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhaseDifference(t *testing.T) {
+	rx0 := make([]complex64, 128)
+	rx1 := make([]complex64, 128)
+	for i := range rx0 {
+		rx0[i] = complex64(cmplx.Rect(1, float64(i)*0.2))
+		rx1[i] = complex64(cmplx.Rect(0.3, float64(i)*0.2+0.7))
+	}
+	if got := PhaseDifference(rx0, rx1); math.Abs(got-0.7) > 1e-5 {
+		t.Fatalf("PhaseDifference() = %v, want 0.7", got)
+	}
+	if got := PhaseDifference(rx1, rx0); math.Abs(got+0.7) > 1e-5 {
+		t.Fatalf("swapped PhaseDifference() = %v, want -0.7", got)
+	}
+}
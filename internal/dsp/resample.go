@@ -0,0 +1,100 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+)
+
+// resampleTaps is the filter length per polyphase branch. Longer filters give
+// a sharper anti-alias/anti-image transition at proportionally higher cost.
+const resampleTaps = 16
+
+// maxResampleFactor bounds the interpolation and decimation factors; larger
+// ratios need an impractically large polyphase bank.
+const maxResampleFactor = 1024
+
+// Resampler changes the sample rate of a complex stream by the rational factor
+// up/down using a polyphase windowed-sinc low-pass filter. Filter state
+// carries across Process calls, so a stream may be fed in arbitrary chunks.
+// Use one Resampler per channel; identical resamplers delay every channel
+// equally and so preserve inter-channel phase.
+type Resampler struct {
+	up, down int
+	phases   [][]float32 // phases[p][k] = h[p + k*up]
+	buf      []complex64 // input history, oldest first
+	t        int         // next output position in upsampled units within buf
+}
+
+// NewResampler builds a resampler from inRate to outRate (Hz). The rates are
+// rounded to whole hertz and the ratio reduced; it fails when either factor
+// would exceed 1024.
+func NewResampler(inRate, outRate float64) (*Resampler, error) {
+	in, out := int(math.Round(inRate)), int(math.Round(outRate))
+	if in <= 0 || out <= 0 {
+		return nil, fmt.Errorf("resample %v Hz to %v Hz: rates must be positive", inRate, outRate)
+	}
+	g := gcd(in, out)
+	up, down := out/g, in/g
+	if up > maxResampleFactor || down > maxResampleFactor {
+		return nil, fmt.Errorf("resample %d Hz to %d Hz: ratio %d/%d too large", in, out, up, down)
+	}
+
+	n := resampleTaps * up
+	cutoff := 0.5 / float64(max(up, down)) // cycles per upsampled sample
+	window := Hamming(n)
+	phases := make([][]float32, up)
+	for p := range phases {
+		phases[p] = make([]float32, resampleTaps)
+	}
+	center := float64(n-1) / 2
+	for i := 0; i < n; i++ {
+		x := float64(i) - center
+		h := 2 * cutoff
+		if x != 0 {
+			h = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		phases[i%up][i/up] = float32(h * window[i] * float64(up))
+	}
+	return &Resampler{
+		up:     up,
+		down:   down,
+		phases: phases,
+		buf:    make([]complex64, resampleTaps-1),
+		t:      (resampleTaps - 1) * up,
+	}, nil
+}
+
+// Ratio returns the reduced interpolation and decimation factors.
+func (r *Resampler) Ratio() (up, down int) { return r.up, r.down }
+
+// Process resamples in and returns the output samples that are complete so
+// far, roughly len(in)*up/down of them.
+func (r *Resampler) Process(in []complex64) []complex64 {
+	r.buf = append(r.buf, in...)
+	out := make([]complex64, 0, len(in)*r.up/r.down+1)
+	for ; r.t/r.up < len(r.buf); r.t += r.down {
+		i, taps := r.t/r.up, r.phases[r.t%r.up]
+		var re, im float32
+		for k, h := range taps {
+			s := r.buf[i-k]
+			re += h * real(s)
+			im += h * imag(s)
+		}
+		out = append(out, complex(re, im))
+	}
+	// Keep the taps-1 samples preceding the next output. When decimating
+	// hard the next output may lie beyond the buffer; the shortfall is then
+	// skipped as it arrives.
+	if drop := min(r.t/r.up-(resampleTaps-1), len(r.buf)); drop > 0 {
+		r.buf = append(r.buf[:0], r.buf[drop:]...)
+		r.t -= drop * r.up
+	}
+	return out
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
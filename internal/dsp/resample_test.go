@@ -0,0 +1,84 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestNewResamplerRatio(t *testing.T) {
+	tests := []struct {
+		in, out  float64
+		up, down int
+		wantErr  bool
+	}{
+		{in: 2e6, out: 1e6, up: 1, down: 2},
+		{in: 2.4e6, out: 2e6, up: 5, down: 6},
+		{in: 1e6, out: 3e6, up: 3, down: 1},
+		{in: 1e6, out: 1e6 + 1, wantErr: true},
+		{in: 0, out: 1e6, wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := NewResampler(tt.in, tt.out)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%v->%v: err = %v, wantErr %v", tt.in, tt.out, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+		if up, down := r.Ratio(); up != tt.up || down != tt.down {
+			t.Fatalf("%v->%v: ratio %d/%d, want %d/%d", tt.in, tt.out, up, down, tt.up, tt.down)
+		}
+	}
+}
+
+func TestResamplerPreservesToneAndPhase(t *testing.T) {
+	tests := []struct {
+		name    string
+		in, out float64
+	}{
+		{name: "decimate", in: 2.4e6, out: 2e6},
+		{name: "interpolate", in: 1e6, out: 2e6},
+	}
+	const tone = 100e3
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r0, _ := NewResampler(tt.in, tt.out)
+			r1, _ := NewResampler(tt.in, tt.out)
+			var out0, out1 []complex64
+			// Feed in uneven chunks to exercise the carried state.
+			n := 0
+			for _, chunk := range []int{1000, 37, 4096, 511, 2000} {
+				in0 := make([]complex64, chunk)
+				in1 := make([]complex64, chunk)
+				for i := range in0 {
+					p := 2 * math.Pi * tone * float64(n+i) / tt.in
+					in0[i] = complex64(cmplx.Rect(1, p))
+					in1[i] = complex64(cmplx.Rect(1, p+math.Pi/4))
+				}
+				n += chunk
+				out0 = append(out0, r0.Process(in0)...)
+				out1 = append(out1, r1.Process(in1)...)
+			}
+			want := float64(n) * tt.out / tt.in
+			if math.Abs(float64(len(out0))-want) > resampleTaps {
+				t.Fatalf("got %d samples, want about %.0f", len(out0), want)
+			}
+			// Skip the filter transient, then check amplitude, inter-channel
+			// phase and the tone frequency at the new rate.
+			var lag complex128
+			for i := 2 * resampleTaps; i < len(out0)-1; i++ {
+				if a := cmplx.Abs(complex128(out0[i])); math.Abs(a-1) > 0.02 {
+					t.Fatalf("sample %d amplitude %.3f", i, a)
+				}
+				if d := cmplx.Phase(complex128(out1[i] / out0[i])); math.Abs(d-math.Pi/4) > 0.01 {
+					t.Fatalf("sample %d channel phase %.3f rad", i, d)
+				}
+				lag += complex128(out0[i+1]) * cmplx.Conj(complex128(out0[i]))
+			}
+			if f := cmplx.Phase(lag) / (2 * math.Pi) * tt.out; math.Abs(f-tone) > 10 {
+				t.Fatalf("tone %.1f Hz, want %.0f", f, tone)
+			}
+		})
+	}
+}
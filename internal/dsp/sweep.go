@@ -0,0 +1,54 @@
+package dsp
+
+import (
+	"math"
+	"sort"
+)
+
+// detectionGapBins is the longest run of bins below the threshold that still
+// joins two runs above it into one detection. It bridges the narrow nulls
+// between the window sidelobes of a strong signal.
+const detectionGapBins = 2
+
+// SpectrumDetection is a signal found in one spectrum by DetectSignals.
+type SpectrumDetection struct {
+	OffsetHz float64 // from the LO
+	PowerDB  float64 // peak bin power in dBFS
+	SNRDB    float64 // peak above the noise floor
+}
+
+// DetectSignals finds the signals in a zero-centred dBFS spectrum, as
+// returned by FFTAndDBFS. The noise floor is the median bin power; every run
+// of bins more than thresholdDB above it, with gaps of at most
+// detectionGapBins, is one detection at its strongest bin. Detections are
+// returned strongest first.
+func DetectSignals(spectrumDB []float64, sampleRate, thresholdDB float64) (floorDB float64, detections []SpectrumDetection) {
+	n := len(spectrumDB)
+	if n == 0 {
+		return math.Inf(-1), nil
+	}
+	sorted := append([]float64(nil), spectrumDB...)
+	sort.Float64s(sorted)
+	floorDB = sorted[n/2]
+
+	peak, last := -1, 0
+	for i := 0; i <= n; i++ {
+		if i < n && spectrumDB[i]-floorDB > thresholdDB {
+			if peak < 0 || spectrumDB[i] > spectrumDB[peak] {
+				peak = i
+			}
+			last = i
+			continue
+		}
+		if peak >= 0 && (i == n || i-last > detectionGapBins) {
+			detections = append(detections, SpectrumDetection{
+				OffsetHz: float64(peak-n/2) * sampleRate / float64(n),
+				PowerDB:  spectrumDB[peak],
+				SNRDB:    spectrumDB[peak] - floorDB,
+			})
+			peak = -1
+		}
+	}
+	sort.Slice(detections, func(i, j int) bool { return detections[i].PowerDB > detections[j].PowerDB })
+	return floorDB, detections
+}
@@ -0,0 +1,33 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDetectSignals(t *testing.T) {
+	tests := []struct {
+		name       string
+		spectrum   []float64
+		wantFloor  float64
+		wantOffset []float64
+	}{
+		{name: "quiet", spectrum: []float64{-90, -91, -89, -90, -90, -90, -92, -90}, wantFloor: -90},
+		{name: "one wide signal", spectrum: []float64{-90, -90, -90, -90, -90, -60, -40, -55}, wantFloor: -90, wantOffset: []float64{250e3}},
+		{name: "two signals", spectrum: []float64{-50, -90, -90, -90, -90, -90, -30, -90}, wantFloor: -90, wantOffset: []float64{250e3, -500e3}},
+		{name: "sidelobe null", spectrum: []float64{-90, -90, -90, -90, -90, -60, -95, -40}, wantFloor: -90, wantOffset: []float64{375e3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			floor, got := DetectSignals(tt.spectrum, 1e6, 10)
+			if floor != tt.wantFloor || len(got) != len(tt.wantOffset) {
+				t.Fatalf("floor %v, detections %+v", floor, got)
+			}
+			for i, want := range tt.wantOffset {
+				if math.Abs(got[i].OffsetHz-want) > 1e-9 || got[i].SNRDB != got[i].PowerDB-floor {
+					t.Fatalf("detection %d: %+v, want offset %v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package dsp
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
 
 // Hamming returns a Hamming window of length n.
 // If n is zero or negative, an empty slice is returned.
@@ -15,6 +21,200 @@ func Hamming(n int) []float64 {
 	return win
 }
 
+// Hann returns a Hann window of length n.
+// If n is zero or negative, an empty slice is returned.
+func Hann(n int) []float64 {
+	return cosineWindow(n, 0.5, 0.5)
+}
+
+// BlackmanHarris returns a 4-term Blackman-Harris window of length n, whose
+// sidelobes stay below -92 dB.
+// If n is zero or negative, an empty slice is returned.
+func BlackmanHarris(n int) []float64 {
+	return cosineWindow(n, 0.35875, 0.48829, 0.14128, 0.01168)
+}
+
+// cosineWindow returns the symmetric window a0 - a1·cos(x) + a2·cos(2x) - …
+// over x = 0..2π.
+func cosineWindow(n int, a ...float64) []float64 {
+	if n <= 0 {
+		return []float64{}
+	}
+	win := make([]float64, n)
+	if n == 1 {
+		win[0] = 1
+		return win
+	}
+	for i := range win {
+		x := 2 * math.Pi * float64(i) / float64(n-1)
+		sign := 1.0
+		for k, ak := range a {
+			win[i] += sign * ak * math.Cos(float64(k)*x)
+			sign = -sign
+		}
+	}
+	return win
+}
+
+// Kaiser returns a Kaiser window of length n. beta trades main lobe width
+// for sidelobe level: 0 is rectangular, 5 about Hamming, 8.6 about
+// Blackman-Harris.
+// If n is zero or negative, an empty slice is returned.
+func Kaiser(n int, beta float64) []float64 {
+	if n <= 0 {
+		return []float64{}
+	}
+	win := make([]float64, n)
+	if n == 1 {
+		win[0] = 1
+		return win
+	}
+	norm := besselI0(beta)
+	for i := range win {
+		r := 2*float64(i)/float64(n-1) - 1
+		win[i] = besselI0(beta*math.Sqrt(1-r*r)) / norm
+	}
+	return win
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind,
+// summed from its power series until the terms no longer matter.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	q := x * x / 4
+	for k := 1; k < 500; k++ {
+		term *= q / float64(k*k)
+		sum += term
+		if term < sum*1e-16 {
+			break
+		}
+	}
+	return sum
+}
+
+// WindowKind names a window function.
+type WindowKind string
+
+// Supported window functions.
+const (
+	WindowHamming        WindowKind = "hamming"
+	WindowHann           WindowKind = "hann"
+	WindowBlackmanHarris WindowKind = "blackman-harris"
+	WindowKaiser         WindowKind = "kaiser"
+	WindowRectangular    WindowKind = "rectangular"
+)
+
+// DefaultKaiserBeta is the Kaiser beta used when none is given.
+const DefaultKaiserBeta = 8.6
+
+// maxKaiserBeta bounds beta; beyond it the window is mostly zeros.
+const maxKaiserBeta = 50
+
+// Window selects the window applied before an FFT. The zero value is the
+// Hamming window, which was the only window before they were selectable.
+type Window struct {
+	Kind WindowKind
+	// Beta is the Kaiser window's shape parameter; other kinds ignore it.
+	Beta float64
+}
+
+// ParseWindow parses a window name: hann, hamming, blackman-harris,
+// rectangular, or kaiser with an optional beta as "kaiser:6". An empty
+// string selects Hamming.
+func ParseWindow(s string) (Window, error) {
+	name, param, hasParam := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	switch kind := WindowKind(name); kind {
+	case "", WindowHamming:
+		kind = WindowHamming
+		fallthrough
+	case WindowHann, WindowBlackmanHarris, WindowRectangular:
+		if hasParam {
+			return Window{}, fmt.Errorf("window %q takes no parameter", name)
+		}
+		return Window{Kind: kind}, nil
+	case WindowKaiser:
+		beta := DefaultKaiserBeta
+		if hasParam {
+			var err error
+			if beta, err = strconv.ParseFloat(param, 64); err != nil || beta < 0 || beta > maxKaiserBeta {
+				return Window{}, fmt.Errorf("kaiser beta %q must be a number within 0-%d", param, maxKaiserBeta)
+			}
+		}
+		return Window{Kind: kind, Beta: beta}, nil
+	}
+	return Window{}, fmt.Errorf("unknown window %q (hann|hamming|blackman-harris|kaiser[:beta]|rectangular)", s)
+}
+
+// String returns the name ParseWindow accepts.
+func (w Window) String() string {
+	switch w.Kind {
+	case "":
+		return string(WindowHamming)
+	case WindowKaiser:
+		return fmt.Sprintf("kaiser:%g", w.Beta)
+	}
+	return string(w.Kind)
+}
+
+// windowKey identifies cached coefficients.
+type windowKey struct {
+	w Window
+	n int
+}
+
+// windowCoeffs are a window's coefficients and their sum, the FFT's
+// amplitude normalization.
+type windowCoeffs struct {
+	coeffs []float64
+	sum    float64
+}
+
+// windowCache holds the coefficients per window and size. Trackers use a
+// handful of sizes, so entries are never evicted.
+var windowCache sync.Map // windowKey → windowCoeffs
+
+// coefficients returns the cached coefficients of w for size n. They are
+// shared and must not be modified.
+func (w Window) coefficients(n int) windowCoeffs {
+	if w.Kind == "" {
+		w.Kind = WindowHamming
+	}
+	if w.Kind != WindowKaiser {
+		w.Beta = 0
+	}
+	key := windowKey{w: w, n: n}
+	if c, ok := windowCache.Load(key); ok {
+		return c.(windowCoeffs)
+	}
+	var coeffs []float64
+	switch w.Kind {
+	case WindowHann:
+		coeffs = Hann(n)
+	case WindowBlackmanHarris:
+		coeffs = BlackmanHarris(n)
+	case WindowKaiser:
+		coeffs = Kaiser(n, w.Beta)
+	case WindowRectangular:
+		coeffs = make([]float64, max(n, 0))
+		for i := range coeffs {
+			coeffs[i] = 1
+		}
+	default:
+		coeffs = Hamming(n)
+	}
+	c := windowCoeffs{coeffs: coeffs}
+	for _, v := range coeffs {
+		c.sum += v
+	}
+	windowCache.Store(key, c)
+	return c
+}
+
+// Coefficients returns a copy of the coefficients of w for size n.
+func (w Window) Coefficients(n int) []float64 {
+	return append([]float64(nil), w.coefficients(n).coeffs...)
+}
+
 // ApplyWindow multiplies the input complex samples with the provided window.
 // The window length must match the input length.
 func ApplyWindow(samples []complex64, window []float64) []complex128 {
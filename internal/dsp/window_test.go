@@ -56,3 +56,57 @@ func TestApplyWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Window
+		wantErr bool
+	}{
+		{in: "", want: Window{Kind: WindowHamming}},
+		{in: " Hann ", want: Window{Kind: WindowHann}},
+		{in: "blackman-harris", want: Window{Kind: WindowBlackmanHarris}},
+		{in: "kaiser", want: Window{Kind: WindowKaiser, Beta: DefaultKaiserBeta}},
+		{in: "kaiser:6", want: Window{Kind: WindowKaiser, Beta: 6}},
+		{in: "kaiser:-1", wantErr: true},
+		{in: "hann:2", wantErr: true},
+		{in: "flattop", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWindow(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseWindow(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+			continue
+		}
+		if err == nil {
+			if again, _ := ParseWindow(got.String()); again != got {
+				t.Errorf("%q does not round-trip through %q", tt.in, got.String())
+			}
+		}
+	}
+}
+
+func TestWindowShapes(t *testing.T) {
+	const n = 65
+	for _, w := range []Window{{Kind: WindowHann}, {Kind: WindowBlackmanHarris}, {Kind: WindowKaiser, Beta: 6}, {Kind: WindowRectangular}, {}} {
+		c := w.Coefficients(n)
+		if len(c) != n || math.Abs(c[n/2]-1) > 1e-9 {
+			t.Errorf("%v: center %v, want 1", w, c[n/2])
+		}
+		for i := 0; i < n/2; i++ {
+			if math.Abs(c[i]-c[n-1-i]) > 1e-12 {
+				t.Fatalf("%v is not symmetric at %d", w, i)
+			}
+		}
+	}
+	if c := Hann(n); c[0] != 0 {
+		t.Errorf("Hann edge %v, want 0", c[0])
+	}
+	if c := Kaiser(n, 0); c[0] != 1 {
+		t.Errorf("Kaiser beta 0 edge %v, want 1", c[0])
+	}
+	// I0(6) = 67.2344.
+	if c := Kaiser(n, 6); math.Abs(c[0]-1/67.23440697647798) > 1e-9 {
+		t.Errorf("Kaiser beta 6 edge %v", c[0])
+	}
+}
@@ -0,0 +1,41 @@
+package dsp
+
+import (
+	"math/cmplx"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+// CrossCorrelate returns |Σ x[k+i]·conj(ref[i])| for every lag k at which ref
+// fits inside x, i.e. len(x)-len(ref)+1 values. It is computed with
+// zero-padded FFTs, so long captures stay cheap.
+func CrossCorrelate(x, ref []complex64) []float64 {
+	if len(ref) == 0 || len(x) < len(ref) {
+		return nil
+	}
+	n := 1
+	for n < len(x)+len(ref)-1 {
+		n <<= 1
+	}
+	fft := fourier.NewCmplxFFT(n)
+	xs := make([]complex128, n)
+	for i, v := range x {
+		xs[i] = complex128(v)
+	}
+	rs := make([]complex128, n)
+	for i, v := range ref {
+		rs[i] = complex128(v)
+	}
+	xf := fft.Coefficients(nil, xs)
+	rf := fft.Coefficients(nil, rs)
+	for i := range xf {
+		xf[i] *= cmplx.Conj(rf[i])
+	}
+	corr := fft.Sequence(nil, xf)
+
+	out := make([]float64, len(x)-len(ref)+1)
+	for k := range out {
+		out[k] = cmplx.Abs(corr[k]) / float64(n)
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCrossCorrelateFindsLag(t *testing.T) {
+	ref := []complex64{1, -1, 1, 1, -1, -1, 1}
+	tests := []struct {
+		name string
+		lag  int
+		size int
+	}{
+		{"start", 0, 16},
+		{"middle", 5, 16},
+		{"end", 9, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := make([]complex64, tt.size)
+			for i, v := range ref {
+				x[tt.lag+i] = v * complex(0, 0.5) // phase and gain do not matter
+			}
+			corr := CrossCorrelate(x, ref)
+			if len(corr) != tt.size-len(ref)+1 {
+				t.Fatalf("len = %d", len(corr))
+			}
+			best := 0
+			for k := range corr {
+				if corr[k] > corr[best] {
+					best = k
+				}
+			}
+			if best != tt.lag || math.Abs(corr[best]-3.5) > 1e-6 {
+				t.Fatalf("peak %.3f at %d, want 3.5 at %d", corr[best], best, tt.lag)
+			}
+		})
+	}
+	if CrossCorrelate(ref[:3], ref) != nil {
+		t.Fatal("expected nil when the reference is longer than the input")
+	}
+}
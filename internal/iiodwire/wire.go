@@ -0,0 +1,390 @@
+// Package iiodwire encodes and decodes the IIOD wire protocol: the 8-byte
+// binary command header, the response bodies that follow it, the payload
+// encodings, the ASCII READBUF framing, the VERSION reply and IIO events. It
+// holds no connection state; the iiod client, connectionmgr and the
+// diagnostics binaries share it so that each operation has one
+// implementation.
+package iiodwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HeaderSize is the size of a binary command or response header.
+const HeaderSize = 8
+
+// MaxPayload bounds a length-prefixed response payload.
+const MaxPayload = 20 << 20
+
+// Binary opcodes.
+const (
+	OpResponse uint8 = 0x00
+	OpPrint    uint8 = 0x01
+	OpTimeout  uint8 = 0x02
+
+	// Attribute reads
+	OpReadAttr    uint8 = 0x03
+	OpReadDbgAttr uint8 = 0x04
+	OpReadBufAttr uint8 = 0x05
+	OpReadChnAttr uint8 = 0x06
+
+	// Attribute writes
+	OpWriteAttr    uint8 = 0x07
+	OpWriteDbgAttr uint8 = 0x08
+	OpWriteBufAttr uint8 = 0x09
+	OpWriteChnAttr uint8 = 0x0a
+
+	// Triggers
+	OpGetTrig uint8 = 0x0b
+	OpSetTrig uint8 = 0x0c
+
+	// Buffer lifecycle
+	OpCreateBuffer  uint8 = 0x0d
+	OpFreeBuffer    uint8 = 0x0e
+	OpEnableBuffer  uint8 = 0x0f
+	OpDisableBuffer uint8 = 0x10
+
+	// Block lifecycle / streaming
+	OpCreateBlock        uint8 = 0x11
+	OpFreeBlock          uint8 = 0x12
+	OpTransferBlock      uint8 = 0x13
+	OpEnqueueBlockCyclic uint8 = 0x14
+	OpRetryDequeueBlock  uint8 = 0x15
+
+	// Event streaming
+	OpCreateEvStream uint8 = 0x16
+	OpFreeEvStream   uint8 = 0x17
+	OpReadEvent      uint8 = 0x18
+
+	// OpMax is the highest opcode.
+	OpMax = OpReadEvent
+)
+
+// Header is a binary command or response header.
+//
+// Wire format (network / big-endian):
+//
+//	uint16 client_id
+//	uint8  op
+//	uint8  dev
+//	int32  code
+type Header struct {
+	ClientID uint16
+	Opcode   uint8
+	Dev      uint8
+	Code     int32
+}
+
+// Marshal encodes the header.
+func (h Header) Marshal() []byte {
+	b := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint16(b[0:2], h.ClientID)
+	b[2] = h.Opcode
+	b[3] = h.Dev
+	binary.BigEndian.PutUint32(b[4:8], uint32(h.Code))
+	return b
+}
+
+// ReadHeader reads exactly one header from r.
+func ReadHeader(r io.Reader) (Header, error) {
+	var b [HeaderSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return Header{}, err
+	}
+	return Header{
+		ClientID: binary.BigEndian.Uint16(b[0:2]),
+		Opcode:   b[2],
+		Dev:      b[3],
+		Code:     int32(binary.BigEndian.Uint32(b[4:8])),
+	}, nil
+}
+
+// Shape describes the response body that follows a response header.
+type Shape uint8
+
+const (
+	ShapeNone           Shape = iota // no response; nothing to read
+	ShapeStatus                      // int32 status
+	ShapeStatusBytes                 // int32 status + uint32 len + len bytes
+	ShapeStatusU32                   // int32 status + uint32 id/handle
+	ShapeStatusU32Bytes              // int32 status + uint32 + uint32 len + len bytes
+)
+
+// shapes maps each opcode to the shape of its response. OpResponse and
+// OpTimeout are answered without a response and keep ShapeNone.
+var shapes = [OpMax + 1]Shape{
+	OpPrint: ShapeStatusBytes,
+
+	OpReadAttr:    ShapeStatusBytes,
+	OpReadDbgAttr: ShapeStatusBytes,
+	OpReadBufAttr: ShapeStatusBytes,
+	OpReadChnAttr: ShapeStatusBytes,
+
+	OpWriteAttr:    ShapeStatus,
+	OpWriteDbgAttr: ShapeStatus,
+	OpWriteBufAttr: ShapeStatus,
+	OpWriteChnAttr: ShapeStatus,
+
+	OpGetTrig: ShapeStatusBytes,
+	OpSetTrig: ShapeStatus,
+
+	OpCreateBuffer:  ShapeStatusU32,
+	OpFreeBuffer:    ShapeStatus,
+	OpEnableBuffer:  ShapeStatus,
+	OpDisableBuffer: ShapeStatus,
+
+	// An RX transfer returns data; a TX transfer only a status, which the
+	// caller selects explicitly.
+	OpCreateBlock:        ShapeStatusU32,
+	OpFreeBlock:          ShapeStatus,
+	OpTransferBlock:      ShapeStatusBytes,
+	OpEnqueueBlockCyclic: ShapeStatus,
+	OpRetryDequeueBlock:  ShapeStatus,
+
+	OpCreateEvStream: ShapeStatusU32,
+	OpFreeEvStream:   ShapeStatus,
+	OpReadEvent:      ShapeStatusBytes,
+}
+
+// ResponseShape returns the response shape of opcode.
+func ResponseShape(opcode uint8) Shape {
+	if opcode > OpMax {
+		return ShapeNone
+	}
+	return shapes[opcode]
+}
+
+// Response is a decoded response body.
+type Response struct {
+	Status int32
+	U32    uint32
+	Data   []byte
+}
+
+// ReadResponse reads a response body of the given shape from r.
+func ReadResponse(r io.Reader, shape Shape) (Response, error) {
+	var resp Response
+	if shape == ShapeNone {
+		return resp, nil
+	}
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return resp, fmt.Errorf("read status: %w", err)
+	}
+	resp.Status = int32(binary.BigEndian.Uint32(b[:]))
+
+	switch shape {
+	case ShapeStatus:
+		return resp, nil
+	case ShapeStatusU32, ShapeStatusU32Bytes:
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return resp, fmt.Errorf("read u32: %w", err)
+		}
+		resp.U32 = binary.BigEndian.Uint32(b[:])
+		if shape == ShapeStatusU32 {
+			return resp, nil
+		}
+	case ShapeStatusBytes:
+	default:
+		return resp, fmt.Errorf("unknown response shape %d", shape)
+	}
+
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return resp, fmt.Errorf("read length: %w", err)
+	}
+	n := binary.BigEndian.Uint32(b[:])
+	if n > MaxPayload {
+		return resp, fmt.Errorf("payload too large: %d bytes", n)
+	}
+	resp.Data = make([]byte, n)
+	if _, err := io.ReadFull(r, resp.Data); err != nil {
+		return resp, fmt.Errorf("read payload: %w", err)
+	}
+	return resp, nil
+}
+
+// U32 encodes a uint32 (BE).
+func U32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+// I32 encodes an int32 (BE), using uint32 bits.
+func I32(v int32) []byte {
+	return U32(uint32(v))
+}
+
+// U64 encodes a uint64 (BE).
+func U64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// LPBytes encodes a length-prefixed byte slice: uint32(len) + bytes.
+func LPBytes(p []byte) []byte {
+	b := make([]byte, 4+len(p))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(p)))
+	copy(b[4:], p)
+	return b
+}
+
+// LPString is LPBytes([]byte(s)).
+func LPString(s string) []byte { return LPBytes([]byte(s)) }
+
+// NameValue encodes: lp(name) + lp(value).
+func NameValue(name, value string) []byte {
+	return append(LPString(name), LPString(value)...)
+}
+
+// U32SliceWithCount encodes: uint32(count) + count*uint32(items...).
+func U32SliceWithCount(items []uint32) []byte {
+	out := U32(uint32(len(items)))
+	for _, v := range items {
+		out = append(out, U32(v)...)
+	}
+	return out
+}
+
+// integerRe matches the integer at the start of an ASCII status line.
+var integerRe = regexp.MustCompile(`^\s*(-?\d+)`)
+
+// ParseInteger extracts the integer that starts an ASCII response line,
+// ignoring any text the server appends.
+func ParseInteger(line []byte) (int, error) {
+	matches := integerRe.FindSubmatch(line)
+	if len(matches) < 2 {
+		trimmed := strings.Trim(strings.TrimSpace(string(line)), "\x00")
+		return 0, fmt.Errorf("no integer found in response %q", trimmed)
+	}
+	val, err := strconv.Atoi(string(matches[1]))
+	if err != nil {
+		return 0, fmt.Errorf("parse integer %q: %w", matches[1], err)
+	}
+	return val, nil
+}
+
+// ReadBufFrame reads the reply to an ASCII READBUF command into dst:
+//
+//	<N>\n            bytes that follow; 0 ends the buffer, < 0 is an errno
+//	<mask>\n         channel mask of the data
+//	<N bytes>\n      payload and a trailing newline
+//
+// It returns the payload length and the announced mask.
+func ReadBufFrame(r *bufio.Reader, dst []byte) (int, string, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return 0, "", fmt.Errorf("READBUF: read length: %w", err)
+	}
+	n, err := ParseInteger(line)
+	if err != nil {
+		return 0, "", err
+	}
+	if n < 0 {
+		return 0, "", fmt.Errorf("READBUF error: %d", n)
+	}
+	if n == 0 {
+		return 0, "", nil
+	}
+	if n > len(dst) {
+		return 0, "", fmt.Errorf("READBUF announced %d bytes but destination capacity is %d", n, len(dst))
+	}
+
+	maskLine, err := r.ReadBytes('\n')
+	if err != nil {
+		return 0, "", fmt.Errorf("READBUF: failed to consume mask line: %w", err)
+	}
+	if _, err := io.ReadFull(r, dst[:n]); err != nil {
+		return 0, "", err
+	}
+	// The trailing newline keeps the stream aligned for the next command.
+	newline, err := r.ReadByte()
+	if err != nil {
+		return 0, "", err
+	}
+	if newline != '\n' {
+		return 0, "", fmt.Errorf("READBUF: expected trailing newline, got %q", newline)
+	}
+	return n, strings.TrimSpace(string(maskLine)), nil
+}
+
+// ServerVersion is the IIOD version a server reports to VERSION, for
+// example "0.25.abc1234" (libiio 0.x) or "1.0.abc1234" (libiio 1.x).
+type ServerVersion struct {
+	Major, Minor int
+	Git          string
+}
+
+// SupportsBinary reports whether the server speaks the libiio 1.x binary
+// protocol.
+func (v ServerVersion) SupportsBinary() bool {
+	return v.Major >= 1
+}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// ParseServerVersion parses the reply to VERSION. A leading "v" and a git
+// tag after the minor version are accepted.
+func ParseServerVersion(s string) (ServerVersion, error) {
+	fields := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".", 3)
+	if len(fields) < 2 {
+		return ServerVersion{}, fmt.Errorf("malformed IIOD version %q", s)
+	}
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return ServerVersion{}, fmt.Errorf("malformed IIOD version %q", s)
+	}
+	minor, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return ServerVersion{}, fmt.Errorf("malformed IIOD version %q", s)
+	}
+	v := ServerVersion{Major: major, Minor: minor}
+	if len(fields) == 3 {
+		v.Git = fields[2]
+	}
+	return v, nil
+}
+
+// EventSize is the size of a struct iio_event: uint64 id, int64 timestamp.
+const EventSize = 16
+
+// Event is one IIO event, such as a threshold crossing reported by a driver.
+// ID packs the event code of the kernel's IIO_EVENT_CODE.
+type Event struct {
+	ID uint64
+	// Timestamp is the kernel timestamp in nanoseconds.
+	Timestamp int64
+}
+
+// Type returns the event type (IIO_EV_TYPE_*, e.g. 0 for a threshold).
+func (e Event) Type() uint8 { return uint8(e.ID >> 56) }
+
+// Direction returns the event direction (IIO_EV_DIR_*, e.g. 1 rising).
+func (e Event) Direction() uint8 { return uint8(e.ID>>48) & 0x7f }
+
+// ChannelType returns the channel type (IIO_VOLTAGE, IIO_TEMP, ...).
+func (e Event) ChannelType() uint8 { return uint8(e.ID >> 32) }
+
+// Channel returns the index of the channel the event is about.
+func (e Event) Channel() int16 { return int16(e.ID) }
+
+// DecodeEvent decodes a struct iio_event, which the server sends in the
+// device's little-endian layout rather than in network order.
+func DecodeEvent(data []byte) (Event, error) {
+	if len(data) != EventSize {
+		return Event{}, fmt.Errorf("event of %d bytes, want %d", len(data), EventSize)
+	}
+	return Event{
+		ID:        binary.LittleEndian.Uint64(data[0:8]),
+		Timestamp: int64(binary.LittleEndian.Uint64(data[8:16])),
+	}, nil
+}
@@ -0,0 +1,153 @@
+package iiodwire
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	h := Header{ClientID: 0x1234, Opcode: OpCreateBlock, Dev: 3, Code: -22}
+	b := h.Marshal()
+	want := []byte{0x12, 0x34, 0x11, 0x03, 0xff, 0xff, 0xff, 0xea}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("Marshal = % x, want % x", b, want)
+	}
+	got, err := ReadHeader(bytes.NewReader(b))
+	if err != nil || got != h {
+		t.Fatalf("ReadHeader = %+v, %v; want %+v", got, err, h)
+	}
+	if _, err := ReadHeader(bytes.NewReader(b[:5])); err == nil {
+		t.Fatal("ReadHeader accepted a short header")
+	}
+}
+
+func TestReadResponse(t *testing.T) {
+	cat := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	tests := []struct {
+		name    string
+		shape   Shape
+		wire    []byte
+		want    Response
+		wantErr bool
+	}{
+		{"none", ShapeNone, nil, Response{}, false},
+		{"status", ShapeStatus, I32(-5), Response{Status: -5}, false},
+		{"status u32", ShapeStatusU32, cat(I32(0), U32(7)), Response{U32: 7}, false},
+		{"status bytes", ShapeStatusBytes, cat(I32(3), LPString("abc")), Response{Status: 3, Data: []byte("abc")}, false},
+		{"status u32 bytes", ShapeStatusU32Bytes, cat(I32(0), U32(9), LPString("x")), Response{U32: 9, Data: []byte("x")}, false},
+		{"empty bytes", ShapeStatusBytes, cat(I32(0), U32(0)), Response{Data: []byte{}}, false},
+		{"short payload", ShapeStatusBytes, cat(I32(0), U32(4), []byte("ab")), Response{}, true},
+		{"oversized payload", ShapeStatusBytes, cat(I32(0), U32(MaxPayload+1)), Response{}, true},
+		{"unknown shape", Shape(42), I32(0), Response{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadResponse(bytes.NewReader(tt.wire), tt.shape)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Status != tt.want.Status || got.U32 != tt.want.U32 || !bytes.Equal(got.Data, tt.want.Data) {
+				t.Fatalf("ReadResponse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseShape(t *testing.T) {
+	tests := []struct {
+		op   uint8
+		want Shape
+	}{
+		{OpResponse, ShapeNone},
+		{OpTimeout, ShapeNone},
+		{OpPrint, ShapeStatusBytes},
+		{OpWriteChnAttr, ShapeStatus},
+		{OpCreateBuffer, ShapeStatusU32},
+		{OpTransferBlock, ShapeStatusBytes},
+		{OpMax + 1, ShapeNone},
+	}
+	for _, tt := range tests {
+		if got := ResponseShape(tt.op); got != tt.want {
+			t.Errorf("ResponseShape(0x%02x) = %d, want %d", tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestPayloadEncoders(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{"u64", U64(0x0102), []byte{0, 0, 0, 0, 0, 0, 1, 2}},
+		{"name value", NameValue("a", "bc"), []byte{0, 0, 0, 1, 'a', 0, 0, 0, 2, 'b', 'c'}},
+		{"u32 slice", U32SliceWithCount([]uint32{3}), []byte{0, 0, 0, 1, 0, 0, 0, 3}},
+		{"empty u32 slice", U32SliceWithCount(nil), []byte{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		if !bytes.Equal(tt.got, tt.want) {
+			t.Errorf("%s = % x, want % x", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseInteger(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    int
+		wantErr bool
+	}{
+		{"42\n", 42, false},
+		{"  -19 trailing text\n", -19, false},
+		{"\x00\n", 0, true},
+		{"abc\n", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseInteger([]byte(tt.line))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseInteger(%q) = %d, %v", tt.line, got, err)
+		}
+	}
+}
+
+func TestReadBufFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		wire     string
+		capacity int
+		wantN    int
+		wantMask string
+		wantErr  bool
+	}{
+		{"payload", "4\n00000003\n\xde\xad\xbe\xef\nNEXT", 8, 4, "00000003", false},
+		{"end of buffer", "0\nNEXT", 8, 0, "", false},
+		{"errno", "-110\n", 8, 0, "", true},
+		{"too small", "4\n00000003\n\xde\xad\xbe\xef\n", 2, 0, "", true},
+		{"missing newline", "4\n00000003\n\xde\xad\xbe\xefX", 8, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.wire))
+			dst := make([]byte, tt.capacity)
+			n, mask, err := ReadBufFrame(r, dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != tt.wantN || mask != tt.wantMask {
+				t.Fatalf("ReadBufFrame = %d, %q; want %d, %q", n, mask, tt.wantN, tt.wantMask)
+			}
+			if tt.wantErr {
+				return
+			}
+			// The frame is consumed exactly, leaving the next reply aligned.
+			if rest, _ := r.ReadString(0); rest != "NEXT" {
+				t.Fatalf("remaining stream = %q, want %q", rest, "NEXT")
+			}
+		})
+	}
+}
@@ -0,0 +1,671 @@
+// Package otlp exports OpenTelemetry traces and metrics over OTLP/HTTP with
+// the JSON encoding, using only the standard library. It covers what GoSDR
+// needs: spans with attributes and parent links, W3C trace context on
+// incoming HTTP requests, and cumulative counters and histograms.
+//
+// A nil *Exporter is valid and records nothing, as are the spans and
+// instruments it returns, so instrumented code needs no checks.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// maxQueuedSpans bounds the finished spans held between exports; older spans
+// are dropped first when a collector is unreachable.
+const maxQueuedSpans = 16384
+
+// DefaultBounds are the histogram bucket bounds in seconds, from 0.5 ms to
+// 10 s.
+var DefaultBounds = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Config configures an Exporter.
+type Config struct {
+	// Endpoint is the base URL of the collector, such as
+	// http://collector:4318; /v1/traces and /v1/metrics are appended.
+	Endpoint string
+	// Headers are sent with every export, typically for authentication.
+	Headers map[string]string
+	// ServiceName is the service.name resource attribute, default "gosdr".
+	ServiceName string
+	// Resource holds further resource attributes, such as service.version.
+	Resource []Attr
+	// Interval is the time between exports, default 10 s.
+	Interval time.Duration
+	Client   *http.Client
+	// Logger receives export failures; nil means logging.Default at the
+	// time of the failure.
+	Logger logging.Logger
+}
+
+// Attr is a span or metric attribute. Values are strings, bools, ints,
+// int64s or float64s; anything else is exported as its fmt.Sprint string.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attr { return Attr{key, value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attr { return Attr{key, value} }
+
+// Float returns a floating point attribute.
+func Float(key string, value float64) Attr { return Attr{key, value} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attr { return Attr{key, value} }
+
+// Exporter records spans and metrics and exports them periodically. With
+// returns views that share the recorded data and add attributes.
+type Exporter struct {
+	core  *core
+	attrs []Attr
+}
+
+type core struct {
+	cfg      Config
+	traces   string
+	metrics  string
+	resource []Attr
+	start    time.Time
+
+	mu      sync.Mutex
+	spans   []*Span
+	dropped int
+	byName  map[string]*metric
+	names   []string
+	lastErr string
+}
+
+// New validates cfg and returns an exporter. Call Run to export.
+func New(cfg Config) (*Exporter, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("OTLP endpoint %q: want an http(s) URL such as http://collector:4318", cfg.Endpoint)
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "gosdr"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	base := strings.TrimSuffix(cfg.Endpoint, "/")
+	c := &core{
+		cfg:      cfg,
+		traces:   base + "/v1/traces",
+		metrics:  base + "/v1/metrics",
+		resource: append([]Attr{String("service.name", cfg.ServiceName)}, cfg.Resource...),
+		start:    time.Now(),
+		byName:   make(map[string]*metric),
+	}
+	return &Exporter{core: c}, nil
+}
+
+// With returns a view of e that adds attrs to every span and measurement.
+func (e *Exporter) With(attrs ...Attr) *Exporter {
+	if e == nil {
+		return nil
+	}
+	return &Exporter{core: e.core, attrs: append(append([]Attr(nil), e.attrs...), attrs...)}
+}
+
+// Run exports every Interval until ctx is done, then once more.
+func (e *Exporter) Run(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	ticker := time.NewTicker(e.core.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			e.logFlush(e.Flush(flushCtx))
+			cancel()
+			return
+		case <-ticker.C:
+			e.logFlush(e.Flush(ctx))
+		}
+	}
+}
+
+// logFlush logs export failures once until the error changes or exports
+// succeed again.
+func (e *Exporter) logFlush(err error) {
+	c := e.core
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	c.mu.Lock()
+	changed := msg != c.lastErr
+	c.lastErr = msg
+	c.mu.Unlock()
+	logger := c.cfg.Logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+	switch {
+	case changed && err != nil:
+		logger.Warn("OTLP export failed", logging.Field{Key: "subsystem", Value: "otlp"}, logging.Field{Key: "error", Value: err})
+	case changed:
+		logger.Info("OTLP export recovered", logging.Field{Key: "subsystem", Value: "otlp"})
+	}
+}
+
+// Flush exports the finished spans and the current metric values. Spans are
+// dropped when their export fails; metrics are cumulative and go out again
+// with the next export.
+func (e *Exporter) Flush(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+	c := e.core
+	c.mu.Lock()
+	spans, dropped := c.spans, c.dropped
+	c.spans, c.dropped = nil, 0
+	metrics := c.metricsPayloadLocked(time.Now())
+	c.mu.Unlock()
+
+	var errs []error
+	if dropped > 0 {
+		errs = append(errs, fmt.Errorf("dropped %d spans (queue full)", dropped))
+	}
+	if len(spans) > 0 {
+		errs = append(errs, c.post(ctx, c.traces, c.tracesPayload(spans)))
+	}
+	if metrics != nil {
+		errs = append(errs, c.post(ctx, c.metrics, metrics))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *core) post(ctx context.Context, endpoint string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export to %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("export to %s: %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// ---------- Spans ----------
+
+// Span kinds of the OTLP protocol.
+const (
+	kindInternal = 1
+	kindServer   = 2
+	kindClient   = 3
+)
+
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+type spanContextKey struct{}
+
+// Span is one timed operation. Its methods may be called on a nil Span.
+type Span struct {
+	core   *core
+	sc     spanContext
+	parent [8]byte
+	name   string
+	kind   int
+	start  time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []Attr
+	errMsg string
+}
+
+// Start begins a span that is a child of the span in ctx, if any, and
+// returns a context carrying it.
+func (e *Exporter) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return e.start(ctx, name, kindInternal, time.Now(), attrs)
+}
+
+func (e *Exporter) start(ctx context.Context, name string, kind int, start time.Time, attrs []Attr) (context.Context, *Span) {
+	if e == nil {
+		return ctx, nil
+	}
+	s := &Span{core: e.core, name: name, kind: kind, start: start, attrs: append(append([]Attr(nil), e.attrs...), attrs...)}
+	if parent, ok := ctx.Value(spanContextKey{}).(spanContext); ok {
+		s.sc.traceID, s.parent = parent.traceID, parent.spanID
+	} else {
+		_, _ = rand.Read(s.sc.traceID[:])
+	}
+	_, _ = rand.Read(s.sc.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, s.sc), s
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err; a nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	c := s.core
+	c.mu.Lock()
+	if len(c.spans) >= maxQueuedSpans {
+		c.spans = c.spans[1:]
+		c.dropped++
+	}
+	c.spans = append(c.spans, s)
+	c.mu.Unlock()
+}
+
+// Duration returns how long the span ran, or has run so far.
+func (s *Span) Duration() time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.end.IsZero() {
+		return time.Since(s.start)
+	}
+	return s.end.Sub(s.start)
+}
+
+// ObserveTransport records a finished client operation on a radio
+// transport, such as an IIOD attribute read, as a client span below the
+// span in ctx and in the gosdr.transport.duration histogram by operation.
+func (e *Exporter) ObserveTransport(ctx context.Context, op string, start time.Time, err error) {
+	if e == nil {
+		return
+	}
+	_, span := e.start(ctx, op, kindClient, start, nil)
+	span.RecordError(err)
+	span.End()
+	e.Histogram("gosdr.transport.duration", "s", "Duration of radio transport operations.").Record(span.Duration().Seconds(), String("operation", op), Bool("error", err != nil))
+}
+
+// parseTraceparent decodes a W3C traceparent header.
+func parseTraceparent(h string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(h, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.traceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.spanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	return sc, sc.traceID != [16]byte{} && sc.spanID != [8]byte{}
+}
+
+// statusRecorder captures the response status for the server span. Unwrap
+// lets http.ResponseController reach the flusher of the streaming endpoints.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// Middleware traces every request as a server span, continuing the trace of
+// a W3C traceparent header, and records http.server.request.duration.
+func (e *Exporter) Middleware(next http.Handler) http.Handler {
+	if e == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+			ctx = context.WithValue(ctx, spanContextKey{}, sc)
+		}
+		ctx, span := e.start(ctx, "HTTP "+r.Method, kindServer, time.Now(), []Attr{
+			String("http.request.method", r.Method),
+			String("url.path", r.URL.Path),
+		})
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		span.SetAttributes(Int("http.response.status_code", rec.status))
+		if rec.status >= 500 {
+			span.RecordError(errors.New(http.StatusText(rec.status)))
+		}
+		span.End()
+		e.Histogram("http.server.request.duration", "s", "Duration of HTTP server requests.").Record(span.Duration().Seconds(), String("http.request.method", r.Method), Int("http.response.status_code", rec.status))
+	})
+}
+
+// ---------- Metrics ----------
+
+type metric struct {
+	name, unit, desc string
+	histogram        bool
+	points           map[string]*point
+	keys             []string
+}
+
+type point struct {
+	attrs    []Attr
+	count    uint64
+	sum      float64
+	min, max float64
+	buckets  []uint64
+}
+
+// Histogram records the distribution of values in DefaultBounds buckets.
+type Histogram struct {
+	e *Exporter
+	m *metric
+}
+
+// Counter accumulates a monotonic sum.
+type Counter struct {
+	e *Exporter
+	m *metric
+}
+
+// Histogram returns the histogram called name, creating it on first use.
+func (e *Exporter) Histogram(name, unit, description string) *Histogram {
+	if e == nil {
+		return nil
+	}
+	return &Histogram{e: e, m: e.core.metric(name, unit, description, true)}
+}
+
+// Counter returns the counter called name, creating it on first use.
+func (e *Exporter) Counter(name, unit, description string) *Counter {
+	if e == nil {
+		return nil
+	}
+	return &Counter{e: e, m: e.core.metric(name, unit, description, false)}
+}
+
+func (c *core) metric(name, unit, desc string, histogram bool) *metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m, ok := c.byName[name]; ok {
+		return m
+	}
+	m := &metric{name: name, unit: unit, desc: desc, histogram: histogram, points: make(map[string]*point)}
+	c.byName[name] = m
+	c.names = append(c.names, name)
+	return m
+}
+
+// Record adds v to the histogram.
+func (h *Histogram) Record(v float64, attrs ...Attr) {
+	if h == nil {
+		return
+	}
+	h.e.core.record(h.m, v, append(append([]Attr(nil), h.e.attrs...), attrs...))
+}
+
+// Add adds v, which must not be negative, to the counter.
+func (c *Counter) Add(v float64, attrs ...Attr) {
+	if c == nil || v < 0 {
+		return
+	}
+	c.e.core.record(c.m, v, append(append([]Attr(nil), c.e.attrs...), attrs...))
+}
+
+func (c *core) record(m *metric, v float64, attrs []Attr) {
+	sort.SliceStable(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	var key strings.Builder
+	for _, a := range attrs {
+		fmt.Fprintf(&key, "%s=%v;", a.Key, a.Value)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := m.points[key.String()]
+	if !ok {
+		p = &point{attrs: attrs, min: v, max: v}
+		if m.histogram {
+			p.buckets = make([]uint64, len(DefaultBounds)+1)
+		}
+		m.points[key.String()] = p
+		m.keys = append(m.keys, key.String())
+	}
+	p.count++
+	p.sum += v
+	p.min, p.max = min(p.min, v), max(p.max, v)
+	if m.histogram {
+		p.buckets[sort.SearchFloat64s(DefaultBounds, v)]++
+	}
+}
+
+// ---------- OTLP/JSON encoding ----------
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type jsonSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            jsonStatus `json:"status"`
+}
+
+type jsonStatus struct {
+	Code    int    `json:"code,omitempty"` // 2 is error
+	Message string `json:"message,omitempty"`
+}
+
+func encodeAttrs(attrs []Attr) []keyValue {
+	out := make([]keyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v anyValue
+		switch x := a.Value.(type) {
+		case string:
+			v.StringValue = &x
+		case bool:
+			v.BoolValue = &x
+		case int:
+			s := strconv.Itoa(x)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(x, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &x
+		default:
+			s := fmt.Sprint(x)
+			v.StringValue = &s
+		}
+		out = append(out, keyValue{Key: a.Key, Value: v})
+	}
+	return out
+}
+
+func unixNano(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+func (c *core) scope() map[string]any {
+	return map[string]any{"name": "github.com/rjboer/GoSDR"}
+}
+
+func (c *core) tracesPayload(spans []*Span) any {
+	out := make([]jsonSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		js := jsonSpan{
+			TraceID:           hex.EncodeToString(s.sc.traceID[:]),
+			SpanID:            hex.EncodeToString(s.sc.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: unixNano(s.start),
+			EndTimeUnixNano:   unixNano(s.end),
+			Attributes:        encodeAttrs(s.attrs),
+		}
+		if s.errMsg != "" {
+			js.Status = jsonStatus{Code: 2, Message: s.errMsg}
+		}
+		s.mu.Unlock()
+		if s.parent != [8]byte{} {
+			js.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		out = append(out, js)
+	}
+	return map[string]any{"resourceSpans": []any{map[string]any{
+		"resource":   map[string]any{"attributes": encodeAttrs(c.resource)},
+		"scopeSpans": []any{map[string]any{"scope": c.scope(), "spans": out}},
+	}}}
+}
+
+// metricsPayloadLocked encodes the cumulative metric values, or returns nil
+// when nothing was recorded yet.
+func (c *core) metricsPayloadLocked(now time.Time) any {
+	var metrics []any
+	for _, name := range c.names {
+		m := c.byName[name]
+		if len(m.keys) == 0 {
+			continue
+		}
+		points := make([]map[string]any, 0, len(m.keys))
+		for _, key := range m.keys {
+			p := m.points[key]
+			dp := map[string]any{
+				"attributes":        encodeAttrs(p.attrs),
+				"startTimeUnixNano": unixNano(c.start),
+				"timeUnixNano":      unixNano(now),
+			}
+			if m.histogram {
+				counts := make([]string, len(p.buckets))
+				for i, n := range p.buckets {
+					counts[i] = strconv.FormatUint(n, 10)
+				}
+				dp["count"] = strconv.FormatUint(p.count, 10)
+				dp["sum"], dp["min"], dp["max"] = p.sum, p.min, p.max
+				dp["bucketCounts"], dp["explicitBounds"] = counts, DefaultBounds
+			} else {
+				dp["asDouble"] = p.sum
+			}
+			points = append(points, dp)
+		}
+		entry := map[string]any{"name": m.name, "unit": m.unit, "description": m.desc}
+		if m.histogram {
+			entry["histogram"] = map[string]any{"aggregationTemporality": 2, "dataPoints": points}
+		} else {
+			entry["sum"] = map[string]any{"aggregationTemporality": 2, "isMonotonic": true, "dataPoints": points}
+		}
+		metrics = append(metrics, entry)
+	}
+	if metrics == nil {
+		return nil
+	}
+	return map[string]any{"resourceMetrics": []any{map[string]any{
+		"resource":     map[string]any{"attributes": encodeAttrs(c.resource)},
+		"scopeMetrics": []any{map[string]any{"scope": c.scope(), "metrics": metrics}},
+	}}}
+}
+
+// ParseHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format,
+// "key1=value1,key2=value2" with URL-encoded values.
+func ParseHeaders(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("OTLP header %q: want key=value", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("OTLP header %q: %w", k, err)
+		}
+		out[strings.TrimSpace(k)] = value
+	}
+	return out, nil
+}
@@ -0,0 +1,230 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector records the OTLP requests it receives by path.
+type collector struct {
+	mu       sync.Mutex
+	payloads map[string][]map[string]any
+	headers  http.Header
+}
+
+func newCollector(t *testing.T) (*collector, string) {
+	c := &collector{payloads: make(map[string][]map[string]any)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		c.payloads[r.URL.Path] = append(c.payloads[r.URL.Path], body)
+		c.headers = r.Header.Clone()
+		c.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv.URL
+}
+
+// spans returns the exported spans by name.
+func (c *collector) spans() map[string]map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]map[string]any)
+	for _, p := range c.payloads["/v1/traces"] {
+		for _, rs := range p["resourceSpans"].([]any) {
+			for _, ss := range rs.(map[string]any)["scopeSpans"].([]any) {
+				for _, s := range ss.(map[string]any)["spans"].([]any) {
+					span := s.(map[string]any)
+					out[span["name"].(string)] = span
+				}
+			}
+		}
+	}
+	return out
+}
+
+// metric returns the last exported data points of the named metric.
+func (c *collector) metric(name string) map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var found map[string]any
+	for _, p := range c.payloads["/v1/metrics"] {
+		for _, rm := range p["resourceMetrics"].([]any) {
+			for _, sm := range rm.(map[string]any)["scopeMetrics"].([]any) {
+				for _, m := range sm.(map[string]any)["metrics"].([]any) {
+					if m.(map[string]any)["name"] == name {
+						found = m.(map[string]any)
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+func TestExporterFlush(t *testing.T) {
+	c, endpoint := newCollector(t)
+	exp, err := New(Config{Endpoint: endpoint + "/", Headers: map[string]string{"Api-Key": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := exp.With(String("device", "north"))
+	ctx, parent := dev.Start(context.Background(), "tracker.iteration", Int("iteration", 3))
+	dev.ObserveTransport(ctx, "iiod.read_buffer", time.Now().Add(-3*time.Millisecond), errors.New("timeout"))
+	parent.End()
+	parent.End()
+	dev.Counter("gosdr.test.count", "1", "").Add(2)
+	dev.Counter("gosdr.test.count", "1", "").Add(1)
+
+	if err := exp.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := c.spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2 (End twice must not duplicate)", len(spans))
+	}
+	p, child := spans["tracker.iteration"], spans["iiod.read_buffer"]
+	if child["traceId"] != p["traceId"] || child["parentSpanId"] != p["spanId"] || p["parentSpanId"] != nil {
+		t.Fatalf("child %v is not below parent %v", child, p)
+	}
+	if status := child["status"].(map[string]any); status["code"] != 2.0 || status["message"] != "timeout" {
+		t.Fatalf("child status %v, want error", status)
+	}
+	if c.headers.Get("Api-Key") != "secret" || c.headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("headers %v", c.headers)
+	}
+
+	hist := c.metric("gosdr.transport.duration")["histogram"].(map[string]any)
+	point := hist["dataPoints"].([]any)[0].(map[string]any)
+	// 3 ms falls in the (2.5 ms, 5 ms] bucket.
+	if point["count"] != "1" || point["bucketCounts"].([]any)[3] != "1" || hist["aggregationTemporality"] != 2.0 {
+		t.Fatalf("histogram %v", hist)
+	}
+	sum := c.metric("gosdr.test.count")["sum"].(map[string]any)
+	if v := sum["dataPoints"].([]any)[0].(map[string]any)["asDouble"]; v != 3.0 {
+		t.Fatalf("counter = %v, want 3", v)
+	}
+
+	// Spans go out once; metrics are cumulative and go out again.
+	if err := exp.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(c.payloads["/v1/traces"]); n != 1 {
+		t.Fatalf("%d trace exports, want 1", n)
+	}
+	if n := len(c.payloads["/v1/metrics"]); n != 2 {
+		t.Fatalf("%d metric exports, want 2", n)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	tests := []struct {
+		name        string
+		traceparent string
+		status      int
+		wantTrace   bool
+		wantError   bool
+	}{
+		{name: "new trace", status: http.StatusOK},
+		{name: "continued trace", traceparent: "00-" + traceID + "-" + parentID + "-01", status: http.StatusOK, wantTrace: true},
+		{name: "invalid traceparent", traceparent: "00-" + traceID + "-0000000000000000-01", status: http.StatusOK},
+		{name: "server error", status: http.StatusServiceUnavailable, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, endpoint := newCollector(t)
+			exp, err := New(Config{Endpoint: endpoint})
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := exp.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := r.Context().Value(spanContextKey{}).(spanContext); !ok {
+					t.Error("handler context carries no span")
+				}
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = io.WriteString(w, "ok")
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if err := exp.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			span := c.spans()["HTTP GET"]
+			if span == nil {
+				t.Fatal("no server span exported")
+			}
+			continued := span["traceId"] == traceID && span["parentSpanId"] == parentID
+			if continued != tt.wantTrace || span["kind"] != 2.0 {
+				t.Fatalf("span %v, want continued trace %t", span, tt.wantTrace)
+			}
+			if failed := span["status"].(map[string]any)["code"] == 2.0; failed != tt.wantError {
+				t.Fatalf("span status %v, want error %t", span["status"], tt.wantError)
+			}
+			if c.metric("http.server.request.duration") == nil {
+				t.Fatal("no request duration histogram")
+			}
+		})
+	}
+}
+
+func TestNilExporter(t *testing.T) {
+	var exp *Exporter
+	ctx, span := exp.With(String("device", "a")).Start(context.Background(), "noop")
+	span.SetAttributes(Bool("ok", true))
+	span.RecordError(errors.New("ignored"))
+	span.End()
+	exp.ObserveTransport(ctx, "iiod.read_attr", time.Now(), nil)
+	exp.Histogram("h", "s", "").Record(1)
+	exp.Counter("c", "1", "").Add(1)
+	next := http.NotFoundHandler()
+	if exp.Middleware(next) == nil {
+		t.Fatal("nil exporter dropped the handler")
+	}
+	if err := exp.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{in: "", want: map[string]string{}},
+		{in: "api-key=abc, x-team=radio%20lab", want: map[string]string{"api-key": "abc", "x-team": "radio lab"}},
+		{in: "api-key", wantErr: true},
+		{in: "=abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHeaders(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseHeaders(%q) err = %v", tt.in, err)
+		}
+		if err == nil && len(got) != len(tt.want) {
+			t.Fatalf("ParseHeaders(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Fatalf("ParseHeaders(%q)[%q] = %q, want %q", tt.in, k, got[k], v)
+			}
+		}
+	}
+}
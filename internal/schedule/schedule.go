@@ -0,0 +1,291 @@
+// Package schedule switches actions such as the TX reference tone, history
+// recording or the tracker itself on and off by time of day. Each rule
+// enables an action during daily time windows or during windows that start at
+// the times matched by a cron expression.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions that can be scheduled.
+const (
+	ActionTX        = "tx"
+	ActionRecording = "recording"
+	ActionTracker   = "tracker"
+)
+
+// maxCronDuration bounds the window length of cron rules.
+const maxCronDuration = 7 * 24 * time.Hour
+
+// Rule enables Action while the time of day lies in one of Windows
+// ("HH:MM-HH:MM", wrapping past midnight when the end is earlier) or within
+// Duration of a minute matched by Cron (minute hour day-of-month month
+// day-of-week). An action with rules is disabled outside all of them; an
+// action without rules is always enabled.
+type Rule struct {
+	Action   string   `json:"action"`
+	Windows  []string `json:"windows,omitempty"`
+	Cron     string   `json:"cron,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+}
+
+// Schedule is a validated set of rules.
+type Schedule struct {
+	rules    []compiledRule
+	location *time.Location
+}
+
+type compiledRule struct {
+	action   string
+	windows  [][2]int // minutes since midnight, end exclusive
+	cron     *cronExpr
+	duration time.Duration
+}
+
+// New validates rules. Times are evaluated in loc; nil means local time.
+func New(rules []Rule, loc *time.Location) (*Schedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	s := &Schedule{location: loc}
+	for i, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("schedule rule %d: %w", i, err)
+		}
+		s.rules = append(s.rules, c)
+	}
+	return s, nil
+}
+
+func compileRule(r Rule) (compiledRule, error) {
+	c := compiledRule{action: strings.ToLower(strings.TrimSpace(r.Action))}
+	switch c.action {
+	case ActionTX, ActionRecording, ActionTracker:
+	default:
+		return c, fmt.Errorf("action must be tx, recording or tracker, got %q", r.Action)
+	}
+	if len(r.Windows) == 0 && r.Cron == "" {
+		return c, fmt.Errorf("%s: windows or cron required", c.action)
+	}
+	for _, w := range r.Windows {
+		window, err := parseWindow(w)
+		if err != nil {
+			return c, fmt.Errorf("%s: %w", c.action, err)
+		}
+		c.windows = append(c.windows, window)
+	}
+	if r.Cron != "" {
+		expr, err := parseCron(r.Cron)
+		if err != nil {
+			return c, fmt.Errorf("%s: %w", c.action, err)
+		}
+		d, err := time.ParseDuration(r.Duration)
+		if err != nil || d < time.Minute || d > maxCronDuration {
+			return c, fmt.Errorf("%s: cron rules need a duration between 1m and 168h, got %q", c.action, r.Duration)
+		}
+		c.cron, c.duration = expr, d
+	}
+	return c, nil
+}
+
+// Actions returns the scheduled actions, in rule order without duplicates.
+func (s *Schedule) Actions() []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, r := range s.rules {
+		if !seen[r.action] {
+			seen[r.action] = true
+			out = append(out, r.action)
+		}
+	}
+	return out
+}
+
+// Enabled reports whether action is enabled at t.
+func (s *Schedule) Enabled(action string, t time.Time) bool {
+	t = t.In(s.location)
+	scheduled := false
+	for _, r := range s.rules {
+		if r.action != action {
+			continue
+		}
+		scheduled = true
+		if r.contains(t) {
+			return true
+		}
+	}
+	return !scheduled
+}
+
+func (r compiledRule) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range r.windows {
+		if w[0] <= w[1] && minute >= w[0] && minute < w[1] {
+			return true
+		}
+		if w[0] > w[1] && (minute >= w[0] || minute < w[1]) {
+			return true
+		}
+	}
+	if r.cron == nil {
+		return false
+	}
+	// A window started at a matching minute no longer ago than duration.
+	start := t.Truncate(time.Minute)
+	for back := time.Duration(0); back < r.duration; back += time.Minute {
+		if r.cron.matches(start.Add(-back)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run calls apply with the state of every scheduled action, first
+// immediately and then whenever it changes, checking every interval until ctx
+// is done.
+func (s *Schedule) Run(ctx context.Context, interval time.Duration, apply func(action string, enabled bool)) {
+	state := make(map[string]bool)
+	check := func() {
+		now := time.Now()
+		for _, action := range s.Actions() {
+			enabled := s.Enabled(action, now)
+			if prev, ok := state[action]; !ok || prev != enabled {
+				state[action] = enabled
+				apply(action, enabled)
+			}
+		}
+	}
+	check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+func parseWindow(s string) ([2]int, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return [2]int{}, fmt.Errorf("window %q must be HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return [2]int{}, fmt.Errorf("window %q: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return [2]int{}, fmt.Errorf("window %q: %w", s, err)
+	}
+	if start == end {
+		return [2]int{}, fmt.Errorf("window %q is empty", s)
+	}
+	return [2]int{start, end}, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight; 24:00 is the end of
+// the day.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if strings.TrimSpace(s) == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
+
+// cronExpr is a parsed five-field cron expression.
+type cronExpr struct {
+	minute, hour, dom, month, dow []bool
+	domAny, dowAny                bool
+}
+
+func parseCron(s string) (*cronExpr, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q must have 5 fields", s)
+	}
+	var c cronExpr
+	var err error
+	for i, f := range []struct {
+		dst      *[]bool
+		min, max int
+	}{{&c.minute, 0, 59}, {&c.hour, 0, 23}, {&c.dom, 1, 31}, {&c.month, 1, 12}, {&c.dow, 0, 7}} {
+		if *f.dst, err = parseCronField(fields[i], f.min, f.max); err != nil {
+			return nil, fmt.Errorf("cron %q: %w", s, err)
+		}
+	}
+	c.dow[0] = c.dow[0] || c.dow[7] // 7 is Sunday too
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+// parseCronField parses a comma-separated list of *, N, N-M and step (/S)
+// terms into a set indexed by value.
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, term := range strings.Split(field, ",") {
+		spec, stepStr, hasStep := strings.Cut(term, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", term)
+			}
+		}
+		lo, hi := min, max
+		if spec != "*" {
+			from, to, isRange := strings.Cut(spec, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value in %q", term)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid value in %q", term)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q out of range %d-%d", term, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// matches reports whether t falls in a matching minute. As in cron, when both
+// day fields are restricted either may match.
+func (c *cronExpr) matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
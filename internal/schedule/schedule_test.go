@@ -0,0 +1,82 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnabled(t *testing.T) {
+	// Wednesday 2025-01-15.
+	at := func(hhmm string) time.Time {
+		clock, _ := time.Parse("15:04", hhmm)
+		return time.Date(2025, 1, 15, clock.Hour(), clock.Minute(), 30, 0, time.UTC)
+	}
+	s, err := New([]Rule{
+		{Action: "tx", Windows: []string{"08:00-12:00", "22:00-02:00"}},
+		{Action: "recording", Cron: "0 */6 * * *", Duration: "90m"},
+		{Action: "tracker", Cron: "30 9 * * 1-5", Duration: "1h"},
+		{Action: "tracker", Cron: "0 0 1 * *", Duration: "24h"},
+	}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		action string
+		at     string
+		want   bool
+	}{
+		{"tx", "07:59", false},
+		{"tx", "08:00", true},
+		{"tx", "11:59", true},
+		{"tx", "12:00", false},
+		{"tx", "23:30", true},
+		{"tx", "01:59", true},
+		{"tx", "02:00", false},
+		{"recording", "06:00", true},
+		{"recording", "07:29", true},
+		{"recording", "07:30", false},
+		{"recording", "05:59", false},
+		{"tracker", "09:29", false},
+		{"tracker", "09:30", true},
+		{"tracker", "10:29", true},
+		{"tracker", "10:30", false},
+		{"unscheduled", "03:00", true},
+	}
+	for _, tt := range tests {
+		if got := s.Enabled(tt.action, at(tt.at)); got != tt.want {
+			t.Errorf("Enabled(%s, %s) = %v, want %v", tt.action, tt.at, got, tt.want)
+		}
+	}
+	// Saturday: the weekday rule is off, the first-of-month rule on.
+	if s.Enabled("tracker", time.Date(2025, 1, 18, 9, 45, 0, 0, time.UTC)) {
+		t.Error("weekday rule matched a Saturday")
+	}
+	if !s.Enabled("tracker", time.Date(2025, 2, 1, 23, 0, 0, 0, time.UTC)) {
+		t.Error("first-of-month rule did not match")
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"unknown action", Rule{Action: "rx", Windows: []string{"08:00-09:00"}}},
+		{"no windows or cron", Rule{Action: "tx"}},
+		{"bad window", Rule{Action: "tx", Windows: []string{"08:00"}}},
+		{"empty window", Rule{Action: "tx", Windows: []string{"08:00-08:00"}}},
+		{"bad clock", Rule{Action: "tx", Windows: []string{"25:00-26:00"}}},
+		{"cron fields", Rule{Action: "tx", Cron: "0 8 * *", Duration: "1h"}},
+		{"cron range", Rule{Action: "tx", Cron: "0 24 * * *", Duration: "1h"}},
+		{"cron step", Rule{Action: "tx", Cron: "*/0 8 * * *", Duration: "1h"}},
+		{"cron without duration", Rule{Action: "tx", Cron: "0 8 * * *"}},
+		{"cron duration too long", Rule{Action: "tx", Cron: "0 8 * * *", Duration: "200h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]Rule{tt.rule}, time.UTC); err == nil {
+				t.Fatalf("New(%+v) succeeded", tt.rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package sdr
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Writable attribute names accepted by AttributeAccessor.WriteAttribute. The
+// names match the JSON keys of HardwareAttributes.
+const (
+	AttrSampleRate = "sampleRateHz"
+	AttrRxLO       = "rxLoHz"
+	AttrTxLO       = "txLoHz"
+	AttrRxGain0    = "rxGain0Db"
+	AttrRxGain1    = "rxGain1Db"
+	AttrTxGain     = "txGainDb"
+)
+
+// HardwareAttributes is a snapshot of the radio state as read back from the
+// device, as opposed to the values that were requested in Config.
+type HardwareAttributes struct {
+	Backend      string  `json:"backend"`
+	SampleRateHz float64 `json:"sampleRateHz"`
+	RxLOHz       float64 `json:"rxLoHz"`
+	TxLOHz       float64 `json:"txLoHz"`
+	RxGain0DB    float64 `json:"rxGain0Db"`
+	RxGain1DB    float64 `json:"rxGain1Db"`
+	TxGainDB     float64 `json:"txGainDb"`
+	RSSI0DB      float64 `json:"rssi0Db,omitempty"`
+	RSSI1DB      float64 `json:"rssi1Db,omitempty"`
+	TemperatureC float64 `json:"temperatureC,omitempty"`
+	// Errors lists attributes that could not be read, keyed by attribute name.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// AttributeAccessor is implemented by backends that can read and write live
+// hardware attributes while streaming.
+type AttributeAccessor interface {
+	ReadAttributes(ctx context.Context) (HardwareAttributes, error)
+	WriteAttribute(ctx context.Context, name string, value float64) error
+}
+
+// XOCorrector is implemented by backends whose reference oscillator frequency
+// can be trimmed at runtime (the AD9361 xo_correction attribute).
+type XOCorrector interface {
+	XOCorrection(ctx context.Context) (float64, error)
+	SetXOCorrection(ctx context.Context, xoHz float64) error
+}
+
+// ValidateAttribute checks a write request against the backend capabilities.
+// Unknown names are rejected; ranges are only enforced when the backend
+// reports them.
+func ValidateAttribute(caps Capabilities, name string, value float64) error {
+	var lo, hi float64
+	switch name {
+	case AttrSampleRate:
+		lo, hi = caps.MinSampleRateHz, caps.MaxSampleRateHz
+	case AttrRxLO, AttrTxLO:
+		lo, hi = caps.MinFrequencyHz, caps.MaxFrequencyHz
+	case AttrRxGain0, AttrRxGain1:
+		lo, hi = caps.MinRxGainDB, caps.MaxRxGainDB
+	case AttrTxGain:
+		if !caps.SupportsTX {
+			return fmt.Errorf("%s: backend %q does not support TX", name, caps.Backend)
+		}
+		lo, hi = caps.MinTxGainDB, caps.MaxTxGainDB
+	default:
+		return fmt.Errorf("unknown attribute %q", name)
+	}
+	if name == AttrSampleRate || name == AttrRxLO || name == AttrTxLO {
+		if value <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	if hi > lo && (value < lo || value > hi) {
+		return fmt.Errorf("%s must be between %g and %g", name, lo, hi)
+	}
+	return nil
+}
+
+// parseAttrFloat extracts the leading number from an IIO attribute value such
+// as "20.000000 dB" or "2400000000".
+func parseAttrFloat(raw string) (float64, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty attribute value")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
@@ -0,0 +1,53 @@
+package sdr
+
+import "testing"
+
+func TestValidateAttribute(t *testing.T) {
+	caps := NewPluto().Capabilities()
+	tests := []struct {
+		name    string
+		attr    string
+		value   float64
+		wantErr bool
+	}{
+		{"valid rx gain", AttrRxGain0, 30, false},
+		{"rx gain too high", AttrRxGain1, 90, true},
+		{"valid lo", AttrRxLO, 2.4e9, false},
+		{"lo out of range", AttrTxLO, 10e9, true},
+		{"negative sample rate", AttrSampleRate, -1, true},
+		{"valid tx gain", AttrTxGain, -10, false},
+		{"unknown", "bogus", 1, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateAttribute(caps, tc.attr, tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateAttribute(%s, %g) error = %v, wantErr %v", tc.attr, tc.value, err, tc.wantErr)
+			}
+		})
+	}
+
+	if err := ValidateAttribute(NewMock().Capabilities(), AttrTxGain, -10); err == nil {
+		t.Fatalf("expected TX gain write to be rejected for receive-only backend")
+	}
+}
+
+func TestParseAttrFloat(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want float64
+	}{
+		{"20.000000 dB", 20},
+		{"2400000000", 2.4e9},
+		{" 105.25 dB\n", 105.25},
+	}
+	for _, tc := range tests {
+		got, err := parseAttrFloat(tc.raw)
+		if err != nil || got != tc.want {
+			t.Fatalf("parseAttrFloat(%q) = %v, %v; want %v", tc.raw, got, err, tc.want)
+		}
+	}
+	if _, err := parseAttrFloat(""); err == nil {
+		t.Fatalf("expected error for empty value")
+	}
+}
@@ -0,0 +1,277 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+)
+
+// BIST test signals.
+const (
+	BISTOff  = "off"
+	BISTTone = "tone"
+	BISTPRBS = "prbs"
+)
+
+// AD9361 BIST injection points: the first field of bist_tone and the value
+// of bist_prbs. Zero switches the generator off.
+const (
+	bistInjectTX = 1
+	bistInjectRX = 2
+)
+
+const (
+	// bistMinToneRatioDB is how far the tone must stand above the mean of the
+	// spectrum.
+	bistMinToneRatioDB = 20
+	// bistMaxPRBSRatioDB is the largest spectral peak over the mean accepted
+	// for the PRBS, which is white.
+	bistMaxPRBSRatioDB = 15
+	// bistMinLevelDBFS is the lowest RX level accepted, relative to the
+	// requested attenuation.
+	bistMinLevelDBFS = -30
+)
+
+// BISTConfig selects the built-in self test signal of an AD9361. The tone is
+// generated at (k+1)/32 of the RX sample rate, k = 0..3 (see BISTToneHz),
+// and attenuated in 6 dB steps.
+type BISTConfig struct {
+	Mode string `json:"mode"` // off, tone or prbs
+	// InjectTX injects into the TX data path instead of the RX data path.
+	InjectTX bool    `json:"injectTX,omitempty"`
+	ToneHz   float64 `json:"toneHz,omitempty"`
+	LevelDB  int     `json:"levelDB,omitempty"` // 0, 6, 12 or 18 dB below full scale
+	// ChannelMask silences tone components: bit 0 RX1 I, bit 1 RX1 Q, bit 2
+	// RX2 I, bit 3 RX2 Q.
+	ChannelMask uint `json:"channelMask,omitempty"`
+}
+
+// BISTController is implemented by backends that can drive the BIST
+// generators of their transceiver.
+type BISTController interface {
+	SetBIST(ctx context.Context, cfg BISTConfig) error
+}
+
+// debugWrites returns the bist_prbs and bist_tone debug attribute writes for
+// c, the generator being switched off first.
+func (c BISTConfig) debugWrites() ([][2]string, error) {
+	if c.LevelDB < 0 || c.LevelDB > 18 || c.LevelDB%6 != 0 {
+		return nil, fmt.Errorf("bist: level %d dB not one of 0, 6, 12, 18", c.LevelDB)
+	}
+	if c.ToneHz < 0 || c.ChannelMask > 0xf {
+		return nil, errors.New("bist: tone frequency must be positive and the channel mask 4 bits")
+	}
+	inject := bistInjectRX
+	if c.InjectTX {
+		inject = bistInjectTX
+	}
+	toneOff := [2]string{"bist_tone", "0 0 0 0"}
+	prbsOff := [2]string{"bist_prbs", "0"}
+	switch c.Mode {
+	case BISTOff, "":
+		return [][2]string{toneOff, prbsOff}, nil
+	case BISTTone:
+		tone := fmt.Sprintf("%d %d %d %d", inject, int64(c.ToneHz), c.LevelDB, c.ChannelMask)
+		return [][2]string{prbsOff, {"bist_tone", tone}}, nil
+	case BISTPRBS:
+		return [][2]string{toneOff, {"bist_prbs", fmt.Sprint(inject)}}, nil
+	default:
+		return nil, fmt.Errorf("bist: unknown mode %q (want off, tone or prbs)", c.Mode)
+	}
+}
+
+// BISTToneHz returns the BIST tone frequency nearest to requestedHz at the
+// given sample rate, as the AD9361 driver selects it. Zero selects fs/16.
+func BISTToneHz(requestedHz, sampleRate float64) float64 {
+	if requestedHz <= 0 {
+		requestedHz = sampleRate / 16
+	}
+	best := sampleRate / 32
+	for k := 2.0; k <= 4; k++ {
+		if f := k * sampleRate / 32; math.Abs(f-requestedHz) < math.Abs(best-requestedHz) {
+			best = f
+		}
+	}
+	return best
+}
+
+// BISTOptions tunes a self test run. Zero values select the defaults in
+// parentheses.
+type BISTOptions struct {
+	Mode    string  `json:"mode,omitempty"`    // tone (tone) or prbs
+	ToneHz  float64 `json:"toneHz,omitempty"`  // snapped by BISTToneHz (fs/16)
+	LevelDB int     `json:"levelDB,omitempty"` // attenuation (0)
+	// Settle is the number of RX buffers discarded after enabling the
+	// generator (2).
+	Settle int `json:"settle,omitempty"`
+}
+
+// BISTChannel is the analysis of one RX channel.
+type BISTChannel struct {
+	FreqHz     float64 `json:"freqHz,omitempty"`
+	LevelDBFS  float64 `json:"levelDBFS"`
+	PeakMeanDB float64 `json:"peakMeanDB"`
+	Pass       bool    `json:"pass"`
+}
+
+// BISTResult reports a self test. For the tone, each channel must show it
+// at the expected frequency (within two FFT bins, either sign) well above
+// the rest of the spectrum; for the PRBS, each channel must be loud and
+// spectrally flat.
+type BISTResult struct {
+	Time       time.Time      `json:"time"`
+	Mode       string         `json:"mode"`
+	ExpectedHz float64        `json:"expectedHz,omitempty"`
+	Channels   [2]BISTChannel `json:"channels"`
+	Pass       bool           `json:"pass"`
+}
+
+// RunBIST injects the BIST signal at the RX data path, captures one buffer
+// per channel and checks it, then switches the generator off again. No
+// external signal is needed, so it verifies the digital RX chain from the
+// transceiver to the host. The caller must keep other readers off the
+// backend meanwhile; BISTRunner does that.
+func RunBIST(ctx context.Context, backend SDR, opts BISTOptions, sampleRate float64) (result BISTResult, err error) {
+	ctrl, ok := As[BISTController](backend)
+	if !ok {
+		return BISTResult{}, errors.New("bist: backend has no built-in self test")
+	}
+	if opts.Mode == "" {
+		opts.Mode = BISTTone
+	}
+	if opts.Mode != BISTTone && opts.Mode != BISTPRBS {
+		return BISTResult{}, fmt.Errorf("bist: unknown mode %q (want tone or prbs)", opts.Mode)
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 2
+	}
+	if sampleRate <= 0 {
+		return BISTResult{}, errors.New("bist: sample rate unknown")
+	}
+
+	result = BISTResult{Time: time.Now(), Mode: opts.Mode}
+	cfg := BISTConfig{Mode: opts.Mode, LevelDB: opts.LevelDB}
+	if opts.Mode == BISTTone {
+		result.ExpectedHz = BISTToneHz(opts.ToneHz, sampleRate)
+		cfg.ToneHz = result.ExpectedHz
+	}
+	if err := ctrl.SetBIST(ctx, cfg); err != nil {
+		return BISTResult{}, err
+	}
+	defer func() {
+		// Always restore live RX data, even after a failed capture.
+		if offErr := ctrl.SetBIST(context.WithoutCancel(ctx), BISTConfig{Mode: BISTOff}); offErr != nil && err == nil {
+			err = offErr
+		}
+	}()
+
+	var rx [2][]complex64
+	for range opts.Settle + 1 {
+		if rx[0], rx[1], err = backend.RX(ctx); err != nil {
+			return BISTResult{}, fmt.Errorf("bist: %w", err)
+		}
+	}
+	fullScale := backend.Capabilities().FullScale
+	if fullScale <= 0 {
+		fullScale = 1
+	}
+	result.Pass = true
+	for ch, samples := range rx {
+		result.Channels[ch] = analyzeBIST(samples, opts, result.ExpectedHz, sampleRate, fullScale)
+		result.Pass = result.Pass && result.Channels[ch].Pass
+	}
+	return result, nil
+}
+
+// analyzeBIST measures one channel of a BIST capture against the checks
+// documented on BISTResult.
+func analyzeBIST(samples []complex64, opts BISTOptions, expectedHz, sampleRate, fullScale float64) BISTChannel {
+	if len(samples) < 3 {
+		return BISTChannel{LevelDBFS: math.Inf(-1)}
+	}
+	power := 0.0
+	for _, v := range samples {
+		power += float64(real(v))*float64(real(v)) + float64(imag(v))*float64(imag(v))
+	}
+	_, dbfs := dsp.FFTAndDBFS(samples)
+	mean := 0.0
+	for _, db := range dbfs {
+		mean += math.Pow(10, db/10)
+	}
+	out := BISTChannel{
+		LevelDBFS:  10 * math.Log10(power/float64(len(samples))/(fullScale*fullScale)),
+		PeakMeanDB: slices.Max(dbfs) - 10*math.Log10(mean/float64(len(dbfs))),
+	}
+	if opts.Mode == BISTPRBS {
+		out.Pass = out.LevelDBFS >= bistMinLevelDBFS && out.PeakMeanDB <= bistMaxPRBSRatioDB
+		return out
+	}
+	out.FreqHz = dsp.EstimateToneFrequency(samples, sampleRate)
+	tolerance := 2 * sampleRate / float64(len(samples))
+	out.Pass = math.Abs(math.Abs(out.FreqHz)-expectedHz) <= tolerance &&
+		out.LevelDBFS >= bistMinLevelDBFS-float64(opts.LevelDB) &&
+		out.PeakMeanDB >= bistMinToneRatioDB
+	return out
+}
+
+// BISTRunner wraps a backend so self tests can run while a tracker owns it:
+// RX calls are serialized and a test holds the backend until the generator
+// is off again, so the tracker never sees BIST data.
+type BISTRunner struct {
+	SDR
+
+	mu         sync.Mutex
+	sampleRate float64
+	last       *BISTResult
+}
+
+// NewBISTRunner wraps backend.
+func NewBISTRunner(backend SDR) *BISTRunner {
+	return &BISTRunner{SDR: backend}
+}
+
+// Unwrap returns the wrapped backend.
+func (r *BISTRunner) Unwrap() SDR { return r.SDR }
+
+// Init records the sample rate and initializes the wrapped backend.
+func (r *BISTRunner) Init(ctx context.Context, cfg Config) error {
+	r.mu.Lock()
+	r.sampleRate = cfg.SampleRate
+	r.mu.Unlock()
+	return r.SDR.Init(ctx, cfg)
+}
+
+// RX reads from the wrapped backend unless a test is running.
+func (r *BISTRunner) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.SDR.RX(ctx)
+}
+
+// Run runs RunBIST with exclusive use of the backend and keeps the result.
+func (r *BISTRunner) Run(ctx context.Context, opts BISTOptions) (BISTResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, err := RunBIST(ctx, r.SDR, opts, r.sampleRate)
+	if err != nil {
+		return BISTResult{}, err
+	}
+	r.last = &result
+	return result, nil
+}
+
+// Last returns the most recent completed test.
+func (r *BISTRunner) Last() (BISTResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last == nil {
+		return BISTResult{}, false
+	}
+	return *r.last, true
+}
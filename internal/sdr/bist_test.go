@@ -0,0 +1,97 @@
+package sdr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBISTDebugWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BISTConfig
+		want    [][2]string
+		wantErr bool
+	}{
+		{name: "off", cfg: BISTConfig{}, want: [][2]string{{"bist_tone", "0 0 0 0"}, {"bist_prbs", "0"}}},
+		{name: "tone rx", cfg: BISTConfig{Mode: BISTTone, ToneHz: 250e3, LevelDB: 6, ChannelMask: 0xc},
+			want: [][2]string{{"bist_prbs", "0"}, {"bist_tone", "2 250000 6 12"}}},
+		{name: "prbs tx", cfg: BISTConfig{Mode: BISTPRBS, InjectTX: true},
+			want: [][2]string{{"bist_tone", "0 0 0 0"}, {"bist_prbs", "1"}}},
+		{name: "bad level", cfg: BISTConfig{Mode: BISTTone, LevelDB: 5}, wantErr: true},
+		{name: "bad mask", cfg: BISTConfig{Mode: BISTTone, ChannelMask: 0x10}, wantErr: true},
+		{name: "bad mode", cfg: BISTConfig{Mode: "sweep"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.cfg.debugWrites()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v", tt.name, err)
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: writes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBISTToneHz(t *testing.T) {
+	tests := []struct{ requested, want float64 }{
+		{0, 2e6 / 16},
+		{10e3, 2e6 / 32},
+		{200e3, 2e6 * 3 / 32},
+		{1e6, 2e6 / 8},
+	}
+	for _, tt := range tests {
+		if got := BISTToneHz(tt.requested, 2e6); got != tt.want {
+			t.Errorf("BISTToneHz(%g) = %g, want %g", tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestBISTRunnerOnMock(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMock()
+	runner := NewBISTRunner(mock)
+	if err := runner.Init(ctx, Config{SampleRate: 2e6, NumSamples: 1024, ToneOffset: 100e3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []BISTOptions{{}, {Mode: BISTTone, ToneHz: 200e3, LevelDB: 12}, {Mode: BISTPRBS}} {
+		result, err := runner.Run(ctx, opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		if !result.Pass {
+			t.Fatalf("%+v: result = %+v", opts, result)
+		}
+	}
+	if last, ok := runner.Last(); !ok || last.Mode != BISTPRBS {
+		t.Fatalf("Last() = %+v, %v", last, ok)
+	}
+	// The generator is off again: the tracker sees the emitter tone.
+	if mock.bist.Mode != BISTOff {
+		t.Fatalf("BIST left in mode %q", mock.bist.Mode)
+	}
+}
+
+// brokenRX accepts BIST settings but keeps receiving the emitter, like a
+// receive chain that does not carry the injected data to the host.
+type brokenRX struct{ *MockSDR }
+
+func (brokenRX) SetBIST(context.Context, BISTConfig) error { return nil }
+
+func TestRunBISTDetectsBrokenChain(t *testing.T) {
+	ctx := context.Background()
+	backend := brokenRX{NewMock()}
+	if err := backend.Init(ctx, Config{SampleRate: 2e6, NumSamples: 1024, ToneOffset: 100e3}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := RunBIST(ctx, backend, BISTOptions{}, 2e6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pass || result.Channels[0].Pass {
+		t.Fatalf("broken chain passed: %+v", result)
+	}
+	if _, err := RunBIST(ctx, backend, BISTOptions{Mode: "sweep"}, 2e6); err == nil {
+		t.Fatal("unknown mode accepted")
+	}
+}
@@ -0,0 +1,60 @@
+package sdr
+
+import (
+	"context"
+	"sync"
+)
+
+// ChannelMapper wraps a backend and corrects cabling differences on RX: Swap
+// exchanges the two channels and Invert negates channel 1 (a 180° polarity
+// flip, e.g. a reversed balun or hybrid port). The mapping can change at
+// runtime, e.g. after a polarity check. Gains and other attributes still
+// address the hardware channels.
+type ChannelMapper struct {
+	SDR
+
+	mu     sync.Mutex
+	swap   bool
+	invert bool
+}
+
+// NewChannelMapper wraps backend with the given mapping.
+func NewChannelMapper(backend SDR, swap, invert bool) *ChannelMapper {
+	return &ChannelMapper{SDR: backend, swap: swap, invert: invert}
+}
+
+// Unwrap returns the wrapped backend.
+func (m *ChannelMapper) Unwrap() SDR { return m.SDR }
+
+// Mapping returns the current swap and invert settings.
+func (m *ChannelMapper) Mapping() (swap, invert bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.swap, m.invert
+}
+
+// SetMapping replaces the swap and invert settings.
+func (m *ChannelMapper) SetMapping(swap, invert bool) {
+	m.mu.Lock()
+	m.swap, m.invert = swap, invert
+	m.mu.Unlock()
+}
+
+// RX reads a buffer pair from the wrapped backend and applies the mapping.
+// Inversion happens in place; backends return freshly allocated buffers.
+func (m *ChannelMapper) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := m.SDR.RX(ctx)
+	if err != nil {
+		return rx0, rx1, err
+	}
+	swap, invert := m.Mapping()
+	if swap {
+		rx0, rx1 = rx1, rx0
+	}
+	if invert {
+		for i := range rx1 {
+			rx1[i] = -rx1[i]
+		}
+	}
+	return rx0, rx1, nil
+}
@@ -0,0 +1,50 @@
+package sdr
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChannelMapper(t *testing.T) {
+	mock := NewMock()
+	if err := mock.Init(context.Background(), Config{SampleRate: 2e6, ToneOffset: 200e3, NumSamples: 16, PhaseDelta: 45}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		swap, invert bool
+		want         func(a0, a1, b0, b1 complex64) bool
+	}{
+		{"identity", false, false, func(a0, a1, b0, b1 complex64) bool { return b0 == a0 && b1 == a1 }},
+		{"swap", true, false, func(a0, a1, b0, b1 complex64) bool { return b0 == a1 && b1 == a0 }},
+		{"invert", false, true, func(a0, a1, b0, b1 complex64) bool { return b0 == a0 && b1 == -a1 }},
+		{"both", true, true, func(a0, a1, b0, b1 complex64) bool { return b0 == a1 && b1 == -a0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Replay a fixed buffer pair through the mapper.
+			src := &fixedRX{SDR: mock}
+			src.rx0, src.rx1, _ = mock.RX(context.Background())
+			m := NewChannelMapper(src, tt.swap, tt.invert)
+			rx0, rx1, err := m.RX(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want(src.rx0[3], src.orig1[3], rx0[3], rx1[3]) {
+				t.Fatalf("unexpected mapping: in (%v, %v), out (%v, %v)", src.rx0[3], src.orig1[3], rx0[3], rx1[3])
+			}
+		})
+	}
+}
+
+// fixedRX returns copies of one buffer pair, keeping the originals.
+type fixedRX struct {
+	SDR
+	rx0, rx1 []complex64
+	orig1    []complex64
+}
+
+func (f *fixedRX) RX(context.Context) ([]complex64, []complex64, error) {
+	f.orig1 = f.rx1
+	return append([]complex64(nil), f.rx0...), append([]complex64(nil), f.rx1...), nil
+}
@@ -0,0 +1,129 @@
+package sdr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+
+	"github.com/rjboer/GoSDR/internal/sdrxml"
+)
+
+// ContextReader is implemented by backends that can return the IIOD context
+// XML of their live connection.
+type ContextReader interface {
+	ContextXML(ctx context.Context) (string, error)
+}
+
+// Defaults are the acquisition settings derived for a radio by
+// DeriveDefaults.
+type Defaults struct {
+	SampleRate float64
+	NumSamples int
+	ToneOffset float64
+}
+
+const (
+	// preferredSampleRate is the rate the defaults aim for, within the
+	// device's range.
+	preferredSampleRate = 2e6
+	// defaultsBufferSpan is the span of received data one buffer of the
+	// defaults holds.
+	defaultsBufferSpan = 0.01 // seconds
+	minDefaultSamples  = 1 << 10
+	maxDefaultSamples  = 1 << 16
+)
+
+// DeriveDefaults derives sample rate, buffer size and tone offset suited to
+// the connected radio. Backends with a ContextReader have their static
+// Capabilities narrowed by the context XML (see CapabilitiesFromContext)
+// first; the others use Capabilities as is.
+func DeriveDefaults(ctx context.Context, backend SDR) (Defaults, Capabilities, error) {
+	caps := backend.Capabilities()
+	if reader, ok := As[ContextReader](backend); ok {
+		raw, err := reader.ContextXML(ctx)
+		if err != nil {
+			return Defaults{}, caps, fmt.Errorf("read context XML: %w", err)
+		}
+		var xmlCtx sdrxml.SDRContext
+		if err := xmlCtx.Parse([]byte(raw)); err != nil {
+			return Defaults{}, caps, err
+		}
+		caps = CapabilitiesFromContext(caps, &xmlCtx)
+	}
+	return DefaultsFor(caps), caps, nil
+}
+
+// DefaultsFor returns the defaults for a radio with caps: the preferred
+// 2 MS/s clamped to the device's sample rate range, the power of two number
+// of samples nearest to 10 ms of data, and a tone a tenth of the sample rate
+// above DC, well clear of the LO leakage and below Nyquist.
+func DefaultsFor(caps Capabilities) Defaults {
+	rate := preferredSampleRate
+	if caps.MaxSampleRateHz > caps.MinSampleRateHz {
+		rate = math.Max(caps.MinSampleRateHz, math.Min(caps.MaxSampleRateHz, rate))
+	}
+	return Defaults{
+		SampleRate: rate,
+		NumSamples: nearestPowerOfTwo(rate*defaultsBufferSpan, minDefaultSamples, maxDefaultSamples),
+		ToneOffset: rate / 10,
+	}
+}
+
+// nearestPowerOfTwo returns the power of two nearest to n within [lo, hi].
+func nearestPowerOfTwo(n float64, lo, hi int) int {
+	if n <= float64(lo) {
+		return lo
+	}
+	if n >= float64(hi) {
+		return hi
+	}
+	below := 1 << (bits.Len(uint(n)) - 1)
+	if n-float64(below) < float64(2*below)-n {
+		return below
+	}
+	return 2 * below
+}
+
+// CapabilitiesFromContext narrows the static caps of an AD936x backend to
+// what the context XML reports: the tuning range of an AD9363 and the number
+// of RX and TX channels the streaming cores expose. Other radios are
+// returned unchanged.
+func CapabilitiesFromContext(caps Capabilities, xmlCtx *sdrxml.SDRContext) Capabilities {
+	for _, attr := range xmlCtx.ContextAttribute {
+		if attr.Name != "ad9361-phy,model" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(attr.Value), "ad9363") {
+			caps.MinFrequencyHz = math.Max(caps.MinFrequencyHz, 325e6)
+			caps.MaxFrequencyHz = math.Min(caps.MaxFrequencyHz, 3.8e9)
+		}
+	}
+	for _, dev := range xmlCtx.Device {
+		name := strings.ToLower(dev.Name)
+		switch {
+		case strings.Contains(name, "cf-ad9361-lpc"):
+			if n := iqChannels(dev, "input"); n > 0 {
+				caps.RXChannels = n
+			}
+		case strings.Contains(name, "cf-ad9361-dds"):
+			if n := iqChannels(dev, "output"); n > 0 {
+				caps.TXChannels = n
+			}
+		}
+	}
+	return caps
+}
+
+// iqChannels counts the I/Q pairs among the streamed voltage channels of dev
+// in direction typ.
+func iqChannels(dev sdrxml.DeviceEntry, typ string) int {
+	n := 0
+	for _, ch := range dev.Channel {
+		if ch.Type == typ && ch.ScanElementRaw != nil && strings.HasPrefix(ch.ID, "voltage") {
+			n++
+		}
+	}
+	return n / 2
+}
@@ -0,0 +1,84 @@
+package sdr
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/sdrxml"
+)
+
+func TestDefaultsFor(t *testing.T) {
+	tests := []struct {
+		name string
+		caps Capabilities
+		want Defaults
+	}{
+		{
+			name: "pluto",
+			caps: (&PlutoSDR{}).Capabilities(),
+			want: Defaults{SampleRate: 2e6, NumSamples: 16384, ToneOffset: 200e3},
+		},
+		{
+			name: "rate floor above preferred",
+			caps: Capabilities{MinSampleRateHz: 5e6, MaxSampleRateHz: 20e6},
+			want: Defaults{SampleRate: 5e6, NumSamples: 65536, ToneOffset: 500e3},
+		},
+		{
+			name: "slow device",
+			caps: Capabilities{MinSampleRateHz: 48e3, MaxSampleRateHz: 192e3},
+			want: Defaults{SampleRate: 192e3, NumSamples: 2048, ToneOffset: 19.2e3},
+		},
+		{
+			name: "no limits",
+			caps: Capabilities{},
+			want: Defaults{SampleRate: 2e6, NumSamples: 16384, ToneOffset: 200e3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultsFor(tt.caps); got != tt.want {
+				t.Fatalf("DefaultsFor = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesFromContext(t *testing.T) {
+	raw, err := os.ReadFile("../sdrxml/pluto.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var xmlCtx sdrxml.SDRContext
+	if err := xmlCtx.Parse(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	caps := CapabilitiesFromContext((&PlutoSDR{}).Capabilities(), &xmlCtx)
+	if caps.RXChannels != 2 || caps.TXChannels != 2 {
+		t.Fatalf("channels = %d RX, %d TX, want 2, 2", caps.RXChannels, caps.TXChannels)
+	}
+	if caps.MinFrequencyHz != 70e6 || caps.MaxFrequencyHz != 6e9 {
+		t.Fatalf("AD9361 range narrowed to %g..%g Hz", caps.MinFrequencyHz, caps.MaxFrequencyHz)
+	}
+
+	for i, attr := range xmlCtx.ContextAttribute {
+		if attr.Name == "ad9361-phy,model" {
+			xmlCtx.ContextAttribute[i].Value = "ad9363a"
+		}
+	}
+	caps = CapabilitiesFromContext((&PlutoSDR{}).Capabilities(), &xmlCtx)
+	if caps.MinFrequencyHz != 325e6 || caps.MaxFrequencyHz != 3.8e9 {
+		t.Fatalf("AD9363 range = %g..%g Hz, want 325e6..3.8e9", caps.MinFrequencyHz, caps.MaxFrequencyHz)
+	}
+}
+
+func TestDeriveDefaultsWithoutContext(t *testing.T) {
+	got, caps, err := DeriveDefaults(context.Background(), NewMock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.Backend != "mock" || got.SampleRate != 2e6 {
+		t.Fatalf("DeriveDefaults = %+v for %s backend", got, caps.Backend)
+	}
+}
@@ -0,0 +1,17 @@
+package sdr
+
+import "context"
+
+// DualPolBuffers holds one RX buffer per element and polarization: H0 and V0
+// are the horizontal and vertical ports of element 0, H1 and V1 those of
+// element 1.
+type DualPolBuffers struct {
+	H0, V0, H1, V1 []complex64
+}
+
+// DualPolRX is implemented by backends whose array elements are dual
+// polarized (Capabilities.DualPol), with four RX channels: two per element.
+// RX keeps returning one polarization per element.
+type DualPolRX interface {
+	RXDualPol(ctx context.Context) (DualPolBuffers, error)
+}
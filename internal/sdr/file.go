@@ -0,0 +1,331 @@
+package sdr
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+)
+
+// SigMFMeta is the subset of a SigMF metadata file that GoSDR reads and
+// writes.
+type SigMFMeta struct {
+	Global struct {
+		Datatype    string  `json:"core:datatype"`
+		SampleRate  float64 `json:"core:sample_rate"`
+		Version     string  `json:"core:version,omitempty"`
+		NumChannels int     `json:"core:num_channels,omitempty"`
+		Description string  `json:"core:description,omitempty"`
+		Recorder    string  `json:"core:recorder,omitempty"`
+		HW          string  `json:"core:hw,omitempty"`
+	} `json:"global"`
+	Captures    []SigMFCapture    `json:"captures"`
+	Annotations []SigMFAnnotation `json:"annotations,omitempty"`
+}
+
+// SigMFCapture is one entry of the SigMF captures array. RxGainDB lists the
+// RX gain of each channel when the recorder knows it.
+type SigMFCapture struct {
+	SampleStart int64     `json:"core:sample_start"`
+	Frequency   float64   `json:"core:frequency,omitempty"`
+	Datetime    string    `json:"core:datetime,omitempty"`
+	RxGainDB    []float64 `json:"gosdr:rx_gain_db,omitempty"`
+}
+
+// SigMFAnnotation is one entry of the SigMF annotations array. The gosdr
+// fields link a triggered capture to the track that caused it.
+type SigMFAnnotation struct {
+	SampleStart int64    `json:"core:sample_start"`
+	SampleCount int64    `json:"core:sample_count,omitempty"`
+	Label       string   `json:"core:label,omitempty"`
+	Comment     string   `json:"core:comment,omitempty"`
+	TrackID     string   `json:"gosdr:track_id,omitempty"`
+	AngleDeg    *float64 `json:"gosdr:angle_deg,omitempty"`
+}
+
+// Frequency returns the centre frequency of the first capture, or zero.
+func (m SigMFMeta) Frequency() float64 {
+	if len(m.Captures) == 0 {
+		return 0
+	}
+	return m.Captures[0].Frequency
+}
+
+// Start returns the recording start time of the first capture, or the zero
+// time when the recording does not carry one.
+func (m SigMFMeta) Start() time.Time {
+	if len(m.Captures) == 0 {
+		return time.Time{}
+	}
+	start, _ := time.Parse(time.RFC3339Nano, m.Captures[0].Datetime)
+	return start
+}
+
+// SigMFPaths returns the metadata and data file of the recording named by
+// path, which may be either file or their common base name.
+func SigMFPaths(path string) (meta, data string) {
+	base := strings.TrimSuffix(strings.TrimSuffix(path, ".sigmf-meta"), ".sigmf-data")
+	return base + ".sigmf-meta", base + ".sigmf-data"
+}
+
+// WriteSigMFMeta writes meta as the metadata file of the recording named by
+// path.
+func WriteSigMFMeta(path string, meta SigMFMeta) error {
+	metaPath, _ := SigMFPaths(path)
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath, append(data, '\n'), 0o644)
+}
+
+// ReadSigMFMeta loads and checks the metadata of the recording at path.
+func ReadSigMFMeta(path string) (SigMFMeta, error) {
+	metaPath, _ := SigMFPaths(path)
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return SigMFMeta{}, err
+	}
+	var meta SigMFMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return SigMFMeta{}, fmt.Errorf("decode %s: %w", metaPath, err)
+	}
+	if meta.Global.NumChannels == 0 {
+		meta.Global.NumChannels = 1
+	}
+	if meta.Global.NumChannels != 2 {
+		return SigMFMeta{}, fmt.Errorf("%s: %d channels, monopulse needs 2", metaPath, meta.Global.NumChannels)
+	}
+	if meta.Global.SampleRate <= 0 {
+		return SigMFMeta{}, fmt.Errorf("%s: missing core:sample_rate", metaPath)
+	}
+	if _, ok := sigmfSampleSize[meta.Global.Datatype]; !ok {
+		return SigMFMeta{}, fmt.Errorf("%s: unsupported datatype %q", metaPath, meta.Global.Datatype)
+	}
+	return meta, nil
+}
+
+// sigmfSampleSize maps the supported datatypes to the size of one I or Q
+// component in bytes.
+var sigmfSampleSize = map[string]int{
+	"cf32_le": 4,
+	"ci16_le": 2,
+	"ci8":     1,
+}
+
+// FileSDR replays a two-channel SigMF recording, with the channels
+// interleaved sample by sample as core:num_channels describes. RX returns
+// io.EOF once fewer than NumSamples samples remain. Integer datatypes are
+// scaled to ±1 full scale like the hardware backends. A recording captured at
+// a different rate than configured is resampled to the configured rate.
+type FileSDR struct {
+	mu         sync.Mutex
+	path       string
+	meta       SigMFMeta
+	file       *os.File
+	reader     *bufio.Reader
+	numSamples int
+	position   int64 // recording samples consumed per channel
+	phaseDelta float64
+
+	// Set when resampling: one resampler per channel and the output samples
+	// not yet returned.
+	resample [2]*dsp.Resampler
+	pending  [2][]complex64
+}
+
+// NewFile creates a backend replaying the recording at path (either SigMF
+// file or their base name). An empty path uses Config.URI at Init.
+func NewFile(path string) *FileSDR { return &FileSDR{path: path} }
+
+// Init opens the recording. A zero Config.SampleRate replays at the
+// recording's own rate.
+func (f *FileSDR) Init(_ context.Context, cfg Config) error {
+	path := f.path
+	if path == "" {
+		path = cfg.URI
+	}
+	meta, err := ReadSigMFMeta(path)
+	if err != nil {
+		return err
+	}
+	if cfg.NumSamples <= 0 {
+		return fmt.Errorf("num samples must be positive")
+	}
+	var resample [2]*dsp.Resampler
+	if cfg.SampleRate != 0 && cfg.SampleRate != meta.Global.SampleRate {
+		for i := range resample {
+			if resample[i], err = dsp.NewResampler(meta.Global.SampleRate, cfg.SampleRate); err != nil {
+				return err
+			}
+		}
+	}
+	_, dataPath := SigMFPaths(path)
+	file, err := os.Open(dataPath)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.path = path
+	f.meta = meta
+	f.file = file
+	f.reader = bufio.NewReaderSize(file, 1<<16)
+	f.numSamples = cfg.NumSamples
+	f.position = 0
+	f.resample = resample
+	f.pending = [2][]complex64{}
+	return nil
+}
+
+// Meta returns the metadata of the open recording.
+func (f *FileSDR) Meta() SigMFMeta {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.meta
+}
+
+// Elapsed returns the recording time consumed so far.
+func (f *FileSDR) Elapsed() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.elapsedLocked()
+}
+
+// Time returns the recording time of the next sample: the capture start plus
+// the samples consumed so far. Recordings without a start time count from
+// the Unix epoch.
+func (f *FileSDR) Time() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	start := f.meta.Start()
+	if start.IsZero() {
+		start = time.Unix(0, 0).UTC()
+	}
+	return start.Add(f.elapsedLocked())
+}
+
+func (f *FileSDR) elapsedLocked() time.Duration {
+	if f.meta.Global.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(f.position) / f.meta.Global.SampleRate * float64(time.Second))
+}
+
+// RX returns the next NumSamples samples of each channel, at the configured
+// rate.
+func (f *FileSDR) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reader == nil {
+		return nil, nil, fmt.Errorf("recording not open")
+	}
+	if f.resample[0] == nil {
+		return f.read(f.numSamples)
+	}
+
+	up, down := f.resample[0].Ratio()
+	chunk := (f.numSamples*down + up - 1) / up
+	for len(f.pending[0]) < f.numSamples {
+		rx0, rx1, err := f.read(chunk)
+		if err != nil {
+			return nil, nil, err
+		}
+		f.pending[0] = append(f.pending[0], f.resample[0].Process(rx0)...)
+		f.pending[1] = append(f.pending[1], f.resample[1].Process(rx1)...)
+	}
+	rx0 := append([]complex64(nil), f.pending[0][:f.numSamples]...)
+	rx1 := append([]complex64(nil), f.pending[1][:f.numSamples]...)
+	f.pending[0] = f.pending[0][f.numSamples:]
+	f.pending[1] = f.pending[1][f.numSamples:]
+	return rx0, rx1, nil
+}
+
+// read decodes the next n recorded samples of each channel.
+func (f *FileSDR) read(n int) ([]complex64, []complex64, error) {
+	size := sigmfSampleSize[f.meta.Global.Datatype]
+	buf := make([]byte, n*2*2*size)
+	if _, err := io.ReadFull(f.reader, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, nil, err
+	}
+	rx0 := make([]complex64, n)
+	rx1 := make([]complex64, n)
+	for i := 0; i < n; i++ {
+		rx0[i] = decodeSigMF(f.meta.Global.Datatype, buf[(4*i)*size:])
+		rx1[i] = decodeSigMF(f.meta.Global.Datatype, buf[(4*i+2)*size:])
+	}
+	f.position += int64(n)
+	return rx0, rx1, nil
+}
+
+// decodeSigMF decodes one complex sample at the start of b.
+func decodeSigMF(datatype string, b []byte) complex64 {
+	switch datatype {
+	case "cf32_le":
+		return complex(math.Float32frombits(binary.LittleEndian.Uint32(b)), math.Float32frombits(binary.LittleEndian.Uint32(b[4:])))
+	case "ci16_le":
+		return complex(float32(int16(binary.LittleEndian.Uint16(b)))/32768, float32(int16(binary.LittleEndian.Uint16(b[2:])))/32768)
+	default:
+		return complex(float32(int8(b[0]))/128, float32(int8(b[1]))/128)
+	}
+}
+
+// TX is not supported on recordings and is ignored.
+func (f *FileSDR) TX(_ context.Context, _, _ []complex64) error { return nil }
+
+// Close closes the recording.
+func (f *FileSDR) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file, f.reader = nil, nil
+	return err
+}
+
+// SetPhaseDelta is stored but has no effect on the recorded signal.
+func (f *FileSDR) SetPhaseDelta(phaseDeltaDeg float64) {
+	f.mu.Lock()
+	f.phaseDelta = phaseDeltaDeg
+	f.mu.Unlock()
+}
+
+// GetPhaseDelta returns the value last passed to SetPhaseDelta.
+func (f *FileSDR) GetPhaseDelta() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.phaseDelta
+}
+
+// Capabilities describes a replayed recording: two RX channels at whatever
+// rate and frequency it was captured with.
+func (f *FileSDR) Capabilities() Capabilities {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	caps := Capabilities{Backend: "file", RXChannels: 2}
+	if f.meta.Global.Datatype != "" && f.meta.Global.Datatype != "cf32_le" {
+		caps.FullScale = 1
+	}
+	return caps
+}
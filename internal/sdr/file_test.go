@@ -0,0 +1,104 @@
+package sdr
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRecording(t *testing.T, datatype string, samples [][2]complex64) string {
+	t.Helper()
+	base := filepath.Join(t.TempDir(), "capture")
+	meta := `{"global":{"core:datatype":"` + datatype + `","core:sample_rate":1000,"core:num_channels":2},` +
+		`"captures":[{"core:sample_start":0,"core:frequency":2.4e9,"core:datetime":"2024-05-01T12:00:00Z"}]}`
+	if err := os.WriteFile(base+".sigmf-meta", []byte(meta), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	for _, pair := range samples {
+		for _, s := range pair {
+			for _, v := range []float32{real(s), imag(s)} {
+				switch datatype {
+				case "cf32_le":
+					data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+				case "ci16_le":
+					data = binary.LittleEndian.AppendUint16(data, uint16(int16(v*32768)))
+				default:
+					data = append(data, byte(int8(v*128)))
+				}
+			}
+		}
+	}
+	if err := os.WriteFile(base+".sigmf-data", data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return base + ".sigmf-meta"
+}
+
+func TestFileSDRReplaysRecording(t *testing.T) {
+	samples := [][2]complex64{{0.5 + 0.25i, -0.5}, {0.25i, 0.125}, {-0.25, 0.5i}}
+	for _, datatype := range []string{"cf32_le", "ci16_le", "ci8"} {
+		f := NewFile(writeRecording(t, datatype, samples))
+		ctx := context.Background()
+		if err := f.Init(ctx, Config{SampleRate: 1000, NumSamples: 2}); err != nil {
+			t.Fatalf("%s: init: %v", datatype, err)
+		}
+		rx0, rx1, err := f.RX(ctx)
+		if err != nil {
+			t.Fatalf("%s: rx: %v", datatype, err)
+		}
+		if rx0[0] != samples[0][0] || rx1[0] != samples[0][1] || rx0[1] != samples[1][0] || rx1[1] != samples[1][1] {
+			t.Fatalf("%s: got %v / %v", datatype, rx0, rx1)
+		}
+		if want := time.Date(2024, 5, 1, 12, 0, 0, 2e6, time.UTC); !f.Time().Equal(want) {
+			t.Fatalf("%s: time %v, want %v", datatype, f.Time(), want)
+		}
+		if _, _, err := f.RX(ctx); !errors.Is(err, io.EOF) {
+			t.Fatalf("%s: expected EOF on short tail, got %v", datatype, err)
+		}
+		f.Close()
+	}
+}
+
+func TestFileSDRResamplesToConfiguredRate(t *testing.T) {
+	samples := make([][2]complex64, 4000)
+	for i := range samples {
+		s := complex64(cmplx.Rect(0.5, 2*math.Pi*50*float64(i)/1000))
+		samples[i] = [2]complex64{s, s * complex64(cmplx.Rect(1, math.Pi/3))}
+	}
+	path := writeRecording(t, "cf32_le", samples)
+	ctx := context.Background()
+
+	f := NewFile(path)
+	if err := f.Init(ctx, Config{SampleRate: 1031, NumSamples: 256}); err == nil {
+		t.Fatal("expected an error for an unreduceable ratio")
+	}
+	if err := f.Init(ctx, Config{SampleRate: 500, NumSamples: 256}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	buffers := 0
+	for {
+		rx0, rx1, err := f.RX(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil || len(rx0) != 256 || len(rx1) != 256 {
+			t.Fatalf("rx: %d/%d samples, %v", len(rx0), len(rx1), err)
+		}
+		if d := cmplx.Phase(complex128(rx1[200] / rx0[200])); math.Abs(d-math.Pi/3) > 0.01 {
+			t.Fatalf("channel phase %.3f rad, want %.3f", d, math.Pi/3)
+		}
+		buffers++
+	}
+	// 4000 samples at 1 kHz are 2000 at 500 Hz: seven full buffers.
+	if buffers != 7 {
+		t.Fatalf("got %d buffers, want 7", buffers)
+	}
+}
@@ -0,0 +1,161 @@
+package sdr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// maxGainPoints bounds the size of a gain schedule.
+const maxGainPoints = 256
+
+// GainPoint is one entry of a gain schedule: the RX gains to use when the LO
+// is tuned to FrequencyHz.
+type GainPoint struct {
+	FrequencyHz float64 `json:"frequency_hz"`
+	RxGain0DB   float64 `json:"rx_gain0_db"`
+	RxGain1DB   float64 `json:"rx_gain1_db"`
+}
+
+// GainSchedule maps LO frequency to RX gain, compensating frontend gain
+// flatness. Points are kept sorted by frequency.
+type GainSchedule []GainPoint
+
+// NewGainSchedule validates and sorts points.
+func NewGainSchedule(points []GainPoint) (GainSchedule, error) {
+	if len(points) > maxGainPoints {
+		return nil, fmt.Errorf("gain schedule supports at most %d points", maxGainPoints)
+	}
+	s := append(GainSchedule(nil), points...)
+	sort.Slice(s, func(i, j int) bool { return s[i].FrequencyHz < s[j].FrequencyHz })
+	for i, p := range s {
+		if p.FrequencyHz <= 0 {
+			return nil, fmt.Errorf("gain schedule point %d: frequency must be positive", i)
+		}
+		if i > 0 && p.FrequencyHz == s[i-1].FrequencyHz {
+			return nil, fmt.Errorf("gain schedule: duplicate frequency %.0f Hz", p.FrequencyHz)
+		}
+	}
+	return s, nil
+}
+
+// Lookup returns the gains for freqHz, interpolating linearly between points
+// and holding the end values outside the table. ok is false for an empty
+// schedule.
+func (s GainSchedule) Lookup(freqHz float64) (gain0, gain1 float64, ok bool) {
+	if len(s) == 0 {
+		return 0, 0, false
+	}
+	i := sort.Search(len(s), func(i int) bool { return s[i].FrequencyHz >= freqHz })
+	switch {
+	case i == 0:
+		return s[0].RxGain0DB, s[0].RxGain1DB, true
+	case i == len(s):
+		last := s[len(s)-1]
+		return last.RxGain0DB, last.RxGain1DB, true
+	}
+	lo, hi := s[i-1], s[i]
+	f := (freqHz - lo.FrequencyHz) / (hi.FrequencyHz - lo.FrequencyHz)
+	return lo.RxGain0DB + f*(hi.RxGain0DB-lo.RxGain0DB), lo.RxGain1DB + f*(hi.RxGain1DB-lo.RxGain1DB), true
+}
+
+// GainScheduler wraps a backend and re-applies the scheduled RX gains whenever
+// the RX LO is set, either at Init or through WriteAttribute (e.g. during
+// frequency hopping).
+type GainScheduler struct {
+	SDR
+
+	mu       sync.Mutex
+	schedule GainSchedule
+}
+
+// NewGainScheduler wraps backend with schedule.
+func NewGainScheduler(backend SDR, schedule GainSchedule) *GainScheduler {
+	return &GainScheduler{SDR: backend, schedule: schedule}
+}
+
+// Unwrap returns the wrapped backend.
+func (g *GainScheduler) Unwrap() SDR { return g.SDR }
+
+// Init replaces the configured RX gains with the scheduled ones for cfg.RxLO.
+func (g *GainScheduler) Init(ctx context.Context, cfg Config) error {
+	if g0, g1, ok := g.Schedule().Lookup(cfg.RxLO); ok {
+		cfg.RxGain0, cfg.RxGain1 = int(math.Round(g0)), int(math.Round(g1))
+	}
+	return g.SDR.Init(ctx, cfg)
+}
+
+// ReadAttributes delegates to the wrapped backend.
+func (g *GainScheduler) ReadAttributes(ctx context.Context) (HardwareAttributes, error) {
+	accessor, err := g.accessor()
+	if err != nil {
+		return HardwareAttributes{}, err
+	}
+	return accessor.ReadAttributes(ctx)
+}
+
+// WriteAttribute writes through to the backend and applies the schedule after
+// an RX LO change.
+func (g *GainScheduler) WriteAttribute(ctx context.Context, name string, value float64) error {
+	accessor, err := g.accessor()
+	if err != nil {
+		return err
+	}
+	if err := accessor.WriteAttribute(ctx, name, value); err != nil {
+		return err
+	}
+	if name != AttrRxLO {
+		return nil
+	}
+	return g.apply(ctx, accessor, value)
+}
+
+// Schedule returns the active schedule.
+func (g *GainScheduler) Schedule() GainSchedule {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append(GainSchedule(nil), g.schedule...)
+}
+
+// SetSchedule replaces the schedule and applies it at the current RX LO when
+// the backend supports attribute access.
+func (g *GainScheduler) SetSchedule(ctx context.Context, schedule GainSchedule) error {
+	g.mu.Lock()
+	g.schedule = schedule
+	g.mu.Unlock()
+
+	accessor, err := g.accessor()
+	if err != nil {
+		return nil // applied at the next Init instead
+	}
+	attrs, err := accessor.ReadAttributes(ctx)
+	if err != nil {
+		return fmt.Errorf("read RX LO: %w", err)
+	}
+	return g.apply(ctx, accessor, attrs.RxLOHz)
+}
+
+// apply writes the scheduled gains for freqHz.
+func (g *GainScheduler) apply(ctx context.Context, accessor AttributeAccessor, freqHz float64) error {
+	g0, g1, ok := g.Schedule().Lookup(freqHz)
+	if !ok {
+		return nil
+	}
+	if err := accessor.WriteAttribute(ctx, AttrRxGain0, math.Round(g0)); err != nil {
+		return fmt.Errorf("apply scheduled gain: %w", err)
+	}
+	if err := accessor.WriteAttribute(ctx, AttrRxGain1, math.Round(g1)); err != nil {
+		return fmt.Errorf("apply scheduled gain: %w", err)
+	}
+	return nil
+}
+
+func (g *GainScheduler) accessor() (AttributeAccessor, error) {
+	accessor, ok := As[AttributeAccessor](g.SDR)
+	if !ok {
+		return nil, fmt.Errorf("backend does not support attribute access")
+	}
+	return accessor, nil
+}
@@ -0,0 +1,96 @@
+package sdr
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGainScheduleLookup(t *testing.T) {
+	schedule, err := NewGainSchedule([]GainPoint{
+		{FrequencyHz: 2.4e9, RxGain0DB: 50, RxGain1DB: 52},
+		{FrequencyHz: 2.2e9, RxGain0DB: 40, RxGain1DB: 42},
+	})
+	if err != nil {
+		t.Fatalf("NewGainSchedule: %v", err)
+	}
+	tests := []struct {
+		name         string
+		freq         float64
+		want0, want1 float64
+	}{
+		{name: "below table", freq: 1e9, want0: 40, want1: 42},
+		{name: "first point", freq: 2.2e9, want0: 40, want1: 42},
+		{name: "midpoint", freq: 2.3e9, want0: 45, want1: 47},
+		{name: "above table", freq: 5e9, want0: 50, want1: 52},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g0, g1, ok := schedule.Lookup(tt.freq)
+			if !ok || g0 != tt.want0 || g1 != tt.want1 {
+				t.Fatalf("Lookup(%g) = %v, %v, %v; want %v, %v", tt.freq, g0, g1, ok, tt.want0, tt.want1)
+			}
+		})
+	}
+	if _, _, ok := GainSchedule(nil).Lookup(2e9); ok {
+		t.Fatal("empty schedule should not match")
+	}
+}
+
+func TestNewGainScheduleRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []GainPoint
+	}{
+		{name: "zero frequency", points: []GainPoint{{FrequencyHz: 0}}},
+		{name: "duplicate", points: []GainPoint{{FrequencyHz: 1e9}, {FrequencyHz: 1e9}}},
+		{name: "too many", points: make([]GainPoint, maxGainPoints+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGainSchedule(tt.points); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestGainSchedulerAppliesOnRetune(t *testing.T) {
+	schedule, _ := NewGainSchedule([]GainPoint{
+		{FrequencyHz: 2.0e9, RxGain0DB: 30, RxGain1DB: 31},
+		{FrequencyHz: 2.4e9, RxGain0DB: 50, RxGain1DB: 51},
+	})
+	sched := NewGainScheduler(NewMock(), schedule)
+	ctx := context.Background()
+	if err := sched.Init(ctx, Config{RxLO: 2.0e9, RxGain0: 60, RxGain1: 60, SampleRate: 2e6, NumSamples: 64}); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	attrs, _ := sched.ReadAttributes(ctx)
+	if attrs.RxGain0DB != 30 || attrs.RxGain1DB != 31 {
+		t.Fatalf("expected scheduled gains at init, got %.0f/%.0f", attrs.RxGain0DB, attrs.RxGain1DB)
+	}
+
+	if err := sched.WriteAttribute(ctx, AttrRxLO, 2.3e9); err != nil {
+		t.Fatalf("retune: %v", err)
+	}
+	attrs, _ = sched.ReadAttributes(ctx)
+	if attrs.RxLOHz != 2.3e9 || attrs.RxGain0DB != 45 || attrs.RxGain1DB != 46 {
+		t.Fatalf("expected interpolated gains after retune, got %+v", attrs)
+	}
+
+	// A manual gain write is not overridden.
+	if err := sched.WriteAttribute(ctx, AttrRxGain0, 20); err != nil {
+		t.Fatalf("gain write: %v", err)
+	}
+	if attrs, _ = sched.ReadAttributes(ctx); attrs.RxGain0DB != 20 {
+		t.Fatalf("manual gain overridden: %.0f", attrs.RxGain0DB)
+	}
+
+	// Replacing the schedule applies it at the current LO.
+	flat, _ := NewGainSchedule([]GainPoint{{FrequencyHz: 1e9, RxGain0DB: 10, RxGain1DB: 11}})
+	if err := sched.SetSchedule(ctx, flat); err != nil {
+		t.Fatalf("SetSchedule: %v", err)
+	}
+	if attrs, _ = sched.ReadAttributes(ctx); attrs.RxGain0DB != 10 || attrs.RxGain1DB != 11 {
+		t.Fatalf("expected new schedule applied, got %.0f/%.0f", attrs.RxGain0DB, attrs.RxGain1DB)
+	}
+}
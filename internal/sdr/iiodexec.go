@@ -0,0 +1,84 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CommandExecutor is implemented by backends that can run a single IIOD
+// text-protocol command on their live connection, for interactive debugging.
+type CommandExecutor interface {
+	ExecIIOD(ctx context.Context, command string) (string, error)
+}
+
+// IIODCommand is a parsed console command. Only commands that leave the
+// stream untouched are accepted: VERSION, PRINT, READ and WRITE.
+type IIODCommand struct {
+	Op      string
+	Device  string
+	Debug   bool
+	Output  bool
+	Channel string
+	Attr    string
+	Value   string
+}
+
+// ParseIIODCommand parses one of
+//
+//	VERSION
+//	PRINT
+//	READ <device> [DEBUG | INPUT <channel> | OUTPUT <channel>] <attr>
+//	WRITE <device> [DEBUG | INPUT <channel> | OUTPUT <channel>] <attr> <value>
+//
+// WRITE takes the value inline rather than as a length-prefixed payload.
+// Buffer, trigger and timeout commands are rejected because they would
+// disturb the running tracker.
+func ParseIIODCommand(line string) (IIODCommand, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return IIODCommand{}, errors.New("empty command")
+	}
+	cmd := IIODCommand{Op: strings.ToUpper(fields[0])}
+	args := fields[1:]
+	switch cmd.Op {
+	case "VERSION", "PRINT":
+		if len(args) != 0 {
+			return IIODCommand{}, fmt.Errorf("%s takes no arguments", cmd.Op)
+		}
+		return cmd, nil
+	case "READ", "WRITE":
+	default:
+		return IIODCommand{}, fmt.Errorf("command %s is not allowed on the live connection", cmd.Op)
+	}
+
+	usage := errors.New("usage: READ <device> [DEBUG|INPUT <channel>|OUTPUT <channel>] <attr>")
+	want := 1
+	if cmd.Op == "WRITE" {
+		usage = errors.New("usage: WRITE <device> [DEBUG|INPUT <channel>|OUTPUT <channel>] <attr> <value>")
+		want = 2
+	}
+	if len(args) < 1+want {
+		return IIODCommand{}, usage
+	}
+	cmd.Device, args = args[0], args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "DEBUG":
+		cmd.Debug, args = true, args[1:]
+	case "INPUT", "OUTPUT":
+		if len(args) < 2 {
+			return IIODCommand{}, usage
+		}
+		cmd.Output = strings.EqualFold(args[0], "OUTPUT")
+		cmd.Channel, args = args[1], args[2:]
+	}
+	if len(args) != want {
+		return IIODCommand{}, usage
+	}
+	cmd.Attr = args[0]
+	if cmd.Op == "WRITE" {
+		cmd.Value = args[1]
+	}
+	return cmd, nil
+}
@@ -0,0 +1,48 @@
+package sdr
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseIIODCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    IIODCommand
+		wantErr bool
+	}{
+		{line: "version", want: IIODCommand{Op: "VERSION"}},
+		{line: "PRINT", want: IIODCommand{Op: "PRINT"}},
+		{line: "READ ad9361-phy ensm_mode", want: IIODCommand{Op: "READ", Device: "ad9361-phy", Attr: "ensm_mode"}},
+		{line: "READ ad9361-phy DEBUG loopback", want: IIODCommand{Op: "READ", Device: "ad9361-phy", Debug: true, Attr: "loopback"}},
+		{line: "READ ad9361-phy INPUT voltage0 hardwaregain", want: IIODCommand{Op: "READ", Device: "ad9361-phy", Channel: "voltage0", Attr: "hardwaregain"}},
+		{line: "WRITE ad9361-phy OUTPUT altvoltage0 frequency 2400000000", want: IIODCommand{Op: "WRITE", Device: "ad9361-phy", Output: true, Channel: "altvoltage0", Attr: "frequency", Value: "2400000000"}},
+		{line: "", wantErr: true},
+		{line: "PRINT extra", wantErr: true},
+		{line: "OPEN cf-ad9361-lpc 4096 00000003", wantErr: true},
+		{line: "READ ad9361-phy", wantErr: true},
+		{line: "READ ad9361-phy INPUT voltage0", wantErr: true},
+		{line: "WRITE ad9361-phy loopback", wantErr: true},
+		{line: "WRITE ad9361-phy loopback 1 2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIIODCommand(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: err = %v, wantErr %v", tt.line, err, tt.wantErr)
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Fatalf("%q: got %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestMockExecIIOD(t *testing.T) {
+	m := NewMock()
+	ctx := context.Background()
+	if _, err := m.ExecIIOD(ctx, "WRITE ad9361-phy DEBUG loopback 1"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, err := m.ExecIIOD(ctx, "READ ad9361-phy DEBUG loopback"); err != nil || got != "1" {
+		t.Fatalf("read = %q, %v; want 1", got, err)
+	}
+}
@@ -0,0 +1,97 @@
+package sdr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rjboer/GoSDR/internal/sdrxml"
+)
+
+// attrWrite is one attribute Init programs on the AD9361.
+type attrWrite struct {
+	action  string
+	device  string // name, for IIOD
+	devID   string // sysfs id, for the SSH fallback
+	channel string
+	attr    string
+	value   string
+	// optional writes only log a failure, e.g. TX gain, which some firmware
+	// exposes per channel.
+	optional bool
+}
+
+// initStages returns the attribute writes of Init in dependency order.
+// Writes within a stage are independent and are sent together; each stage
+// needs the ones before it: the sample rate and reference clock settle the
+// clock tree before the LOs are tuned, and manual gain mode must be set
+// before a gain is accepted. The buffers are created after the last stage.
+func initStages(cfg Config, phyName, phyID string, noTX bool) [][]attrWrite {
+	phy := func(action, channel, attr, value string) attrWrite {
+		return attrWrite{action: action, device: phyName, devID: phyID, channel: channel, attr: attr, value: value}
+	}
+	clock := []attrWrite{phy("set sample rate", "", "sampling_frequency", fmt.Sprintf("%.0f", cfg.SampleRate))}
+	if cfg.ClockSource == "external" {
+		ref := cfg.RefClockHz
+		if ref == 0 {
+			ref = plutoRefClockHz
+		}
+		clock = append(clock, phy("set reference clock", "", "xo_correction", fmt.Sprintf("%.0f", ref)))
+	}
+
+	tune := []attrWrite{
+		phy("set rx0 gain mode", "voltage0", "gain_control_mode", "manual"),
+		phy("set rx1 gain mode", "voltage1", "gain_control_mode", "manual"),
+	}
+	if cfg.RxLO > 0 {
+		tune = append(tune, phy("set RX LO", "altvoltage1", "frequency", fmt.Sprintf("%.0f", cfg.RxLO)))
+		if !noTX {
+			tune = append(tune, phy("set TX LO", "altvoltage0", "frequency", fmt.Sprintf("%.0f", cfg.RxLO)))
+		}
+	}
+
+	gains := []attrWrite{
+		phy("set rx0 gain", "voltage0", "hardwaregain", fmt.Sprintf("%d", cfg.RxGain0)),
+		phy("set rx1 gain", "voltage1", "hardwaregain", fmt.Sprintf("%d", cfg.RxGain1)),
+	}
+	if !noTX {
+		txGain := phy("set tx gain", "out", "hardwaregain", fmt.Sprintf("%d", cfg.TxGain))
+		txGain.optional = true
+		gains = append(gains, txGain)
+	}
+	return [][]attrWrite{clock, tune, gains}
+}
+
+// InitAttributes returns the attributes Init writes for cfg on the PHY device
+// phyName, in write order, so a radio's context XML can be checked for them
+// before a firmware update breaks Init.
+func InitAttributes(cfg Config, phyName string) []sdrxml.AttrRef {
+	var refs []sdrxml.AttrRef
+	for _, stage := range initStages(cfg, phyName, "", cfg.DisableTX) {
+		for _, w := range stage {
+			refs = append(refs, sdrxml.AttrRef{Device: w.device, Channel: w.channel, Attr: w.attr, Optional: w.optional})
+		}
+	}
+	return refs
+}
+
+// programStages runs the stages in order, handing each stage to write as one
+// batch; write returns the error of each attribute. Init pipelines a batch
+// on the IIOD connection, so a high-latency link costs one round trip per
+// stage rather than per attribute. A failed optional write goes to warn. The
+// first stage with failed writes ends the run; its errors are returned
+// together.
+func programStages(stages [][]attrWrite, write func([]attrWrite) []error, warn func(attrWrite, error)) error {
+	for _, stage := range stages {
+		errs := write(stage)
+		for i, err := range errs {
+			if err != nil && stage[i].optional {
+				warn(stage[i], err)
+				errs[i] = nil
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package sdr
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/sdrxml"
+)
+
+func TestInitStages(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		noTX bool
+		want [][]string // actions per stage
+	}{
+		{
+			name: "default",
+			cfg:  Config{SampleRate: 2e6, RxLO: 2.3e9},
+			want: [][]string{
+				{"set sample rate"},
+				{"set rx0 gain mode", "set rx1 gain mode", "set RX LO", "set TX LO"},
+				{"set rx0 gain", "set rx1 gain", "set tx gain"},
+			},
+		},
+		{
+			name: "external clock without TX",
+			cfg:  Config{SampleRate: 2e6, RxLO: 2.3e9, ClockSource: "external"},
+			noTX: true,
+			want: [][]string{
+				{"set sample rate", "set reference clock"},
+				{"set rx0 gain mode", "set rx1 gain mode", "set RX LO"},
+				{"set rx0 gain", "set rx1 gain"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages := initStages(tt.cfg, "ad9361-phy", "iio:device0", tt.noTX)
+			if len(stages) != len(tt.want) {
+				t.Fatalf("%d stages, want %d", len(stages), len(tt.want))
+			}
+			for i, stage := range stages {
+				var got []string
+				for _, w := range stage {
+					got = append(got, w.action)
+				}
+				if strings.Join(got, ",") != strings.Join(tt.want[i], ",") {
+					t.Fatalf("stage %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProgramStages(t *testing.T) {
+	stages := [][]attrWrite{
+		{{action: "a"}, {action: "b"}},
+		{{action: "c"}, {action: "d", optional: true}, {action: "e"}},
+		{{action: "f"}},
+	}
+	tests := []struct {
+		name     string
+		fail     map[string]bool
+		wantErr  []string
+		wantWarn []string
+		want     []string // batches written
+	}{
+		{name: "all succeed", want: []string{"a,b", "c,d,e", "f"}},
+		{name: "optional fails", fail: map[string]bool{"d": true}, wantWarn: []string{"d"}, want: []string{"a,b", "c,d,e", "f"}},
+		{name: "errors joined", fail: map[string]bool{"c": true, "e": true}, wantErr: []string{"c failed", "e failed"}, want: []string{"a,b", "c,d,e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches, warned []string
+			err := programStages(stages, func(stage []attrWrite) []error {
+				var actions []string
+				errs := make([]error, len(stage))
+				for i, w := range stage {
+					actions = append(actions, w.action)
+					if tt.fail[w.action] {
+						errs[i] = errors.New(w.action + " failed")
+					}
+				}
+				batches = append(batches, strings.Join(actions, ","))
+				return errs
+			}, func(w attrWrite, err error) { warned = append(warned, w.action) })
+
+			for _, want := range tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Fatalf("err = %v, want it to contain %q", err, want)
+				}
+			}
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if strings.Join(warned, ",") != strings.Join(tt.wantWarn, ",") {
+				t.Fatalf("warned = %v, want %v", warned, tt.wantWarn)
+			}
+			if strings.Join(batches, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("batches = %v, want %v", batches, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitAttributesMatchPlutoContext(t *testing.T) {
+	raw, err := os.ReadFile("../sdrxml/pluto.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ctx sdrxml.SDRContext
+	if err := ctx.Parse(raw); err != nil {
+		t.Fatal(err)
+	}
+	refs := InitAttributes(Config{SampleRate: 2e6, RxLO: 2.3e9, ClockSource: "external"}, "ad9361-phy")
+	if len(refs) != 9 {
+		t.Fatalf("%d attributes, want 9: %v", len(refs), refs)
+	}
+	// This firmware exposes the TX gain per channel only, which Init
+	// tolerates.
+	missing := ctx.MissingAttributes(refs)
+	if len(missing) != 1 || missing[0].Attr != "hardwaregain" || !missing[0].Optional {
+		t.Fatalf("missing = %v", missing)
+	}
+}
@@ -0,0 +1,77 @@
+package sdr
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+)
+
+func TestInjectorAddsKnownTarget(t *testing.T) {
+	injector := NewInjector(NewMock())
+	cfg := Config{SampleRate: 2e6, ToneOffset: 200e3, NumSamples: 1024, PhaseDelta: 0}
+	if err := injector.Init(context.Background(), cfg); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	// A strong injected target at a different offset and phase dominates the mock tone.
+	if err := injector.SetSignals([]InjectedSignal{{FrequencyHz: -300e3, Amplitude: 1, PhaseDeltaDeg: 40}}); err != nil {
+		t.Fatalf("set signals failed: %v", err)
+	}
+
+	ch0, ch1, err := injector.RX(context.Background())
+	if err != nil {
+		t.Fatalf("rx failed: %v", err)
+	}
+	if got := dsp.EstimateToneFrequency(ch0, cfg.SampleRate); math.Abs(got+300e3) > cfg.SampleRate/1024 {
+		t.Fatalf("expected injected tone near -300 kHz, got %.0f Hz", got)
+	}
+	start, end := dsp.SignalBinRange(len(ch0), cfg.SampleRate, -300e3)
+	if start > end {
+		start, end = end, start
+	}
+	delay, _, _ := dsp.CoarseScan(ch0, ch1, 0, start, end, 2, 2.3e9, 0.5)
+	if math.Abs(delay+40) > 5 {
+		t.Fatalf("expected phase delay near -40, got %.1f", delay)
+	}
+}
+
+func TestInjectorValidatesSignals(t *testing.T) {
+	injector := NewInjector(NewMock())
+	if err := injector.Init(context.Background(), Config{SampleRate: 1e6}); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	tests := []struct {
+		name string
+		sig  InjectedSignal
+	}{
+		{"zero amplitude", InjectedSignal{FrequencyHz: 1e3}},
+		{"amplitude too large", InjectedSignal{FrequencyHz: 1e3, Amplitude: 2}},
+		{"beyond nyquist", InjectedSignal{FrequencyHz: 600e3, Amplitude: 0.5}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := injector.SetSignals([]InjectedSignal{tc.sig}); err == nil {
+				t.Fatalf("expected validation error")
+			}
+		})
+	}
+	if len(injector.Signals()) != 0 {
+		t.Fatalf("invalid signals must not be stored")
+	}
+}
+
+func TestAsUnwrapsInjector(t *testing.T) {
+	mock := NewMock()
+	wrapped := NewInjector(mock)
+	got, ok := As[*MockSDR](wrapped)
+	if !ok || got != mock {
+		t.Fatalf("As did not find wrapped mock")
+	}
+	if _, ok := As[*PlutoSDR](wrapped); ok {
+		t.Fatalf("As matched an unrelated backend")
+	}
+	if _, ok := As[AttributeAccessor](nil); ok {
+		t.Fatalf("As matched nil backend")
+	}
+}
@@ -0,0 +1,169 @@
+package sdr
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+)
+
+const (
+	// saturatedGlitchFraction is the share of clipped samples above which a
+	// buffer is treated as a glitch rather than ordinary overload.
+	saturatedGlitchFraction = 0.5
+	// integrityEventInterval rate-limits events per anomaly kind.
+	integrityEventInterval = 5 * time.Second
+)
+
+// IntegrityStats counts RX buffer anomalies seen by an IntegrityChecker.
+type IntegrityStats struct {
+	Buffers        uint64    `json:"buffers"`
+	LengthMismatch uint64    `json:"lengthMismatch"`
+	Repeated       uint64    `json:"repeated"`
+	AllZero        uint64    `json:"allZero"`
+	Saturated      uint64    `json:"saturated"`
+	LastIssue      string    `json:"lastIssue,omitempty"`
+	LastIssueAt    time.Time `json:"lastIssueAt,omitempty"`
+}
+
+// IntegrityChecker wraps a backend and validates every RX buffer: lengths must
+// match the configured buffer size, consecutive buffers must differ (a stale
+// DMA buffer hashes identically), and buffers must not be all zero or pinned
+// at full scale. Buffers are passed through unchanged; anomalies only update
+// counters and raise rate-limited events.
+type IntegrityChecker struct {
+	SDR
+
+	mu        sync.Mutex
+	expected  int
+	fullScale float64
+	lastHash  uint64
+	haveHash  bool
+	stats     IntegrityStats
+	events    EventLogger
+	lastEvent map[string]time.Time
+}
+
+// NewIntegrityChecker wraps backend.
+func NewIntegrityChecker(backend SDR) *IntegrityChecker {
+	return &IntegrityChecker{SDR: backend, lastEvent: make(map[string]time.Time)}
+}
+
+// Unwrap returns the wrapped backend.
+func (c *IntegrityChecker) Unwrap() SDR { return c.SDR }
+
+// SetEventLogger configures where anomaly events are reported.
+func (c *IntegrityChecker) SetEventLogger(logger EventLogger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = logger
+}
+
+// Init records the expected buffer size and clipping level, then initializes
+// the wrapped backend.
+func (c *IntegrityChecker) Init(ctx context.Context, cfg Config) error {
+	c.mu.Lock()
+	c.expected = cfg.NumSamples
+	c.fullScale = c.SDR.Capabilities().FullScale
+	c.haveHash = false
+	c.mu.Unlock()
+	return c.SDR.Init(ctx, cfg)
+}
+
+// RX reads from the wrapped backend and checks the buffers.
+func (c *IntegrityChecker) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := c.SDR.RX(ctx)
+	if err != nil {
+		return rx0, rx1, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Buffers++
+	for _, issue := range c.inspect(rx0, rx1) {
+		c.record(issue)
+	}
+	return rx0, rx1, nil
+}
+
+// IntegrityStats returns a snapshot of the anomaly counters.
+func (c *IntegrityChecker) IntegrityStats() IntegrityStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// integrityIssue is one anomaly found in a buffer pair.
+type integrityIssue struct {
+	counter *uint64
+	kind    string
+	detail  string
+}
+
+// inspect returns the anomalies found in one buffer pair and updates the
+// stale-buffer hash.
+func (c *IntegrityChecker) inspect(rx0, rx1 []complex64) []integrityIssue {
+	var issues []integrityIssue
+	if len(rx0) != len(rx1) || (c.expected > 0 && len(rx0) != c.expected) {
+		issues = append(issues, integrityIssue{&c.stats.LengthMismatch, "length mismatch",
+			fmt.Sprintf("got %d/%d samples, want %d", len(rx0), len(rx1), c.expected)})
+	}
+	if allZero(rx0) || allZero(rx1) {
+		c.haveHash = false
+		return append(issues, integrityIssue{&c.stats.AllZero, "all-zero buffer", ""})
+	}
+	if dsp.ClipFraction(rx0, c.fullScale) > saturatedGlitchFraction || dsp.ClipFraction(rx1, c.fullScale) > saturatedGlitchFraction {
+		issues = append(issues, integrityIssue{&c.stats.Saturated, "saturated buffer", ""})
+	}
+	hash := hashBuffers(rx0, rx1)
+	if c.haveHash && hash == c.lastHash {
+		issues = append(issues, integrityIssue{&c.stats.Repeated, "repeated buffer", ""})
+	}
+	c.lastHash, c.haveHash = hash, true
+	return issues
+}
+
+// record bumps the issue counter and emits an event unless one of the same
+// kind was raised recently.
+func (c *IntegrityChecker) record(issue integrityIssue) {
+	*issue.counter++
+	msg := issue.kind
+	if issue.detail != "" {
+		msg += ": " + issue.detail
+	}
+	now := time.Now()
+	c.stats.LastIssue, c.stats.LastIssueAt = msg, now
+	if c.events == nil || now.Sub(c.lastEvent[issue.kind]) < integrityEventInterval {
+		return
+	}
+	c.lastEvent[issue.kind] = now
+	c.events.LogEvent("warn", fmt.Sprintf("RX integrity: %s (buffer %d)", msg, c.stats.Buffers))
+}
+
+func allZero(samples []complex64) bool {
+	for _, s := range samples {
+		if s != 0 {
+			return false
+		}
+	}
+	return len(samples) > 0
+}
+
+// hashBuffers returns an FNV-1a hash over the raw sample bits of both channels.
+func hashBuffers(rx0, rx1 []complex64) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	for _, buf := range [][]complex64{rx0, rx1} {
+		for _, s := range buf {
+			binary.LittleEndian.PutUint32(b[:4], math.Float32bits(real(s)))
+			binary.LittleEndian.PutUint32(b[4:], math.Float32bits(imag(s)))
+			h.Write(b[:])
+		}
+	}
+	return h.Sum64()
+}
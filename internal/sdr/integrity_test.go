@@ -0,0 +1,90 @@
+package sdr
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// scriptedSDR replays fixed buffers from RX.
+type scriptedSDR struct {
+	*MockSDR
+	buffers [][2][]complex64
+	next    int
+}
+
+func (s *scriptedSDR) RX(context.Context) ([]complex64, []complex64, error) {
+	b := s.buffers[s.next%len(s.buffers)]
+	s.next++
+	return b[0], b[1], nil
+}
+
+func (s *scriptedSDR) Capabilities() Capabilities {
+	caps := s.MockSDR.Capabilities()
+	caps.FullScale = 1
+	return caps
+}
+
+type eventRecorder struct{ messages []string }
+
+func (e *eventRecorder) LogEvent(_, message string) { e.messages = append(e.messages, message) }
+
+func TestIntegrityCheckerCountsAnomalies(t *testing.T) {
+	good := []complex64{0.1, 0.2, 0.3, 0.4}
+	other := []complex64{0.4, 0.3, 0.2, 0.1}
+	zero := make([]complex64, 4)
+	pinned := []complex64{1, 1, -1, 0.1}
+	short := []complex64{0.5, 0.6}
+
+	backend := &scriptedSDR{MockSDR: NewMock(), buffers: [][2][]complex64{
+		{good, other},
+		{good, other}, // repeated
+		{zero, other}, // all zero
+		{pinned, other},
+		{short, short},
+	}}
+	checker := NewIntegrityChecker(backend)
+	events := &eventRecorder{}
+	checker.SetEventLogger(events)
+	if err := checker.Init(context.Background(), Config{NumSamples: 4, SampleRate: 2e6}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	for range backend.buffers {
+		if _, _, err := checker.RX(context.Background()); err != nil {
+			t.Fatalf("RX: %v", err)
+		}
+	}
+
+	got := checker.IntegrityStats()
+	want := IntegrityStats{Buffers: 5, LengthMismatch: 1, Repeated: 1, AllZero: 1, Saturated: 1}
+	if got.Buffers != want.Buffers || got.LengthMismatch != want.LengthMismatch || got.Repeated != want.Repeated ||
+		got.AllZero != want.AllZero || got.Saturated != want.Saturated {
+		t.Fatalf("stats = %+v, want %+v", got, want)
+	}
+	if !strings.HasPrefix(got.LastIssue, "length mismatch") {
+		t.Fatalf("unexpected last issue %q", got.LastIssue)
+	}
+	if len(events.messages) != 4 {
+		t.Fatalf("expected 4 events, got %v", events.messages)
+	}
+}
+
+func TestIntegrityCheckerRateLimitsEvents(t *testing.T) {
+	zero := make([]complex64, 4)
+	backend := &scriptedSDR{MockSDR: NewMock(), buffers: [][2][]complex64{{zero, zero}}}
+	checker := NewIntegrityChecker(backend)
+	events := &eventRecorder{}
+	checker.SetEventLogger(events)
+	if err := checker.Init(context.Background(), Config{NumSamples: 4}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		_, _, _ = checker.RX(context.Background())
+	}
+	if stats := checker.IntegrityStats(); stats.AllZero != 10 {
+		t.Fatalf("expected 10 all-zero buffers, got %d", stats.AllZero)
+	}
+	if len(events.messages) != 1 {
+		t.Fatalf("expected a single rate-limited event, got %d", len(events.messages))
+	}
+}
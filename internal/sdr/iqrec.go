@@ -0,0 +1,291 @@
+package sdr
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sigmfVersion is the SigMF specification version written to core:version.
+const sigmfVersion = "1.0.0"
+
+// IQRecording describes the current or last recording of an IQRecorder.
+type IQRecording struct {
+	Active bool `json:"active"`
+	// Path is the recording base name; the files are Path.sigmf-meta and
+	// Path.sigmf-data.
+	Path        string    `json:"path,omitempty"`
+	Started     time.Time `json:"started,omitempty"`
+	Samples     int64     `json:"samples"`
+	Annotations int       `json:"annotations"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// IQRecorder wraps a backend and, while a recording runs, writes every RX
+// buffer pair to a two-channel cf32_le SigMF recording in dir that the file
+// backend can replay. A live RX LO or gain change starts a new capture
+// segment; a sample rate change ends the recording. A failure to write stops
+// the recording but does not fail RX.
+type IQRecorder struct {
+	SDR
+
+	mu     sync.Mutex
+	dir    string
+	cfg    Config
+	events EventLogger
+
+	file      *os.File
+	w         *bufio.Writer
+	buf       []byte
+	meta      SigMFMeta
+	status    IQRecording
+	lastStart int64 // first sample of the latest buffer
+	lastLen   int64
+	segment   bool // start a capture segment with the next buffer
+}
+
+// NewIQRecorder wraps backend, writing recordings into dir.
+func NewIQRecorder(backend SDR, dir string) *IQRecorder {
+	return &IQRecorder{SDR: backend, dir: dir}
+}
+
+// Unwrap returns the wrapped backend.
+func (r *IQRecorder) Unwrap() SDR { return r.SDR }
+
+// Dir returns the recording directory.
+func (r *IQRecorder) Dir() string { return r.dir }
+
+// SetEventLogger configures where recording starts, stops and failures are
+// reported.
+func (r *IQRecorder) SetEventLogger(logger EventLogger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = logger
+}
+
+// Init initializes the wrapped backend and records the radio settings for
+// the metadata. A running recording is ended first.
+func (r *IQRecorder) Init(ctx context.Context, cfg Config) error {
+	r.mu.Lock()
+	if r.file != nil {
+		r.stopLocked("backend reinitialized")
+	}
+	r.cfg = cfg
+	r.mu.Unlock()
+	return r.SDR.Init(ctx, cfg)
+}
+
+// Start begins a recording named name (a file base name without directory;
+// empty selects iq-<UTC time>). Existing recordings are never overwritten.
+func (r *IQRecorder) Start(name string) (IQRecording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file != nil {
+		return r.status, fmt.Errorf("recording %s already running", r.status.Path)
+	}
+	if r.cfg.SampleRate <= 0 {
+		return r.status, errors.New("recording needs an initialized backend")
+	}
+	now := time.Now()
+	if name = strings.TrimSpace(name); name == "" {
+		name = "iq-" + now.UTC().Format("20060102T150405Z")
+	}
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return r.status, fmt.Errorf("invalid recording name %q", name)
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return r.status, err
+	}
+	base := filepath.Join(r.dir, strings.TrimSuffix(strings.TrimSuffix(name, ".sigmf-meta"), ".sigmf-data"))
+	_, dataPath := SigMFPaths(base)
+	file, err := os.OpenFile(dataPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return r.status, err
+	}
+
+	var meta SigMFMeta
+	meta.Global.Datatype = "cf32_le"
+	meta.Global.SampleRate = r.cfg.SampleRate
+	meta.Global.NumChannels = 2
+	meta.Global.Version = sigmfVersion
+	meta.Global.Recorder = "GoSDR"
+	meta.Global.HW = r.SDR.Capabilities().Backend
+	meta.Global.Description = "monopulse RX0/RX1"
+	r.file, r.w, r.meta = file, bufio.NewWriterSize(file, 1<<20), meta
+	r.status = IQRecording{Active: true, Path: base, Started: now}
+	r.lastStart, r.lastLen, r.segment = 0, 0, true
+	if err := WriteSigMFMeta(base, meta); err != nil {
+		r.stopLocked(err.Error())
+		return r.status, err
+	}
+	r.logLocked("info", "IQ recording started: "+base)
+	return r.status, nil
+}
+
+// Stop ends the running recording and writes its metadata.
+func (r *IQRecorder) Stop() (IQRecording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return r.status, errors.New("no recording running")
+	}
+	return r.status, r.stopLocked("")
+}
+
+// Status returns the current or last recording.
+func (r *IQRecorder) Status() IQRecording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Annotate labels the latest RX buffer of the running recording, the one the
+// tracker has just processed. It is a no-op when nothing is recording.
+func (r *IQRecorder) Annotate(a SigMFAnnotation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil || r.lastLen == 0 {
+		return
+	}
+	a.SampleStart, a.SampleCount = r.lastStart, r.lastLen
+	r.meta.Annotations = append(r.meta.Annotations, a)
+	r.status.Annotations = len(r.meta.Annotations)
+}
+
+// RX reads from the wrapped backend and appends the buffers to the running
+// recording. The capture time is estimated as the arrival time less the
+// buffer duration.
+func (r *IQRecorder) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := r.SDR.RX(ctx)
+	if err != nil {
+		return rx0, rx1, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return rx0, rx1, nil
+	}
+	if r.segment {
+		start := time.Now().Add(-time.Duration(float64(len(rx0)) / r.cfg.SampleRate * float64(time.Second)))
+		r.meta.Captures = append(r.meta.Captures, SigMFCapture{
+			SampleStart: r.status.Samples,
+			Frequency:   r.cfg.RxLO,
+			Datetime:    start.UTC().Format(time.RFC3339Nano),
+			RxGainDB:    []float64{float64(r.cfg.RxGain0), float64(r.cfg.RxGain1)},
+		})
+		r.segment = false
+	}
+	if werr := r.writeLocked(rx0, rx1); werr != nil {
+		r.stopLocked("write failed: " + werr.Error())
+	}
+	return rx0, rx1, nil
+}
+
+// writeLocked appends one buffer pair, the channels interleaved sample by
+// sample.
+func (r *IQRecorder) writeLocked(rx0, rx1 []complex64) error {
+	n := min(len(rx0), len(rx1))
+	if cap(r.buf) < n*16 {
+		r.buf = make([]byte, n*16)
+	}
+	buf := r.buf[:n*16]
+	for i := range n {
+		b := buf[i*16:]
+		binary.LittleEndian.PutUint32(b, math.Float32bits(real(rx0[i])))
+		binary.LittleEndian.PutUint32(b[4:], math.Float32bits(imag(rx0[i])))
+		binary.LittleEndian.PutUint32(b[8:], math.Float32bits(real(rx1[i])))
+		binary.LittleEndian.PutUint32(b[12:], math.Float32bits(imag(rx1[i])))
+	}
+	if _, err := r.w.Write(buf); err != nil {
+		return err
+	}
+	r.lastStart, r.lastLen = r.status.Samples, int64(n)
+	r.status.Samples += int64(n)
+	return nil
+}
+
+// stopLocked closes the data file and writes the metadata. A non-empty
+// reason marks an abnormal stop and is reported as a warning.
+func (r *IQRecorder) stopLocked(reason string) error {
+	err := errors.Join(r.w.Flush(), r.file.Close())
+	err = errors.Join(err, WriteSigMFMeta(r.status.Path, r.meta))
+	r.file, r.w = nil, nil
+	r.status.Active = false
+	if reason != "" {
+		r.status.Error = reason
+		r.logLocked("warn", fmt.Sprintf("IQ recording %s stopped: %s", r.status.Path, reason))
+		return err
+	}
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+	r.logLocked("info", fmt.Sprintf("IQ recording stopped: %s (%d samples)", r.status.Path, r.status.Samples))
+	return err
+}
+
+func (r *IQRecorder) logLocked(level, message string) {
+	if r.events != nil {
+		r.events.LogEvent(level, message)
+	}
+}
+
+// ReadAttributes delegates to the wrapped backend.
+func (r *IQRecorder) ReadAttributes(ctx context.Context) (HardwareAttributes, error) {
+	accessor, ok := As[AttributeAccessor](r.SDR)
+	if !ok {
+		return HardwareAttributes{}, fmt.Errorf("backend does not support attribute access")
+	}
+	return accessor.ReadAttributes(ctx)
+}
+
+// WriteAttribute writes through to the backend and keeps the recording
+// metadata in step: RX LO and gain changes start a new capture segment, a
+// sample rate change ends the recording.
+func (r *IQRecorder) WriteAttribute(ctx context.Context, name string, value float64) error {
+	accessor, ok := As[AttributeAccessor](r.SDR)
+	if !ok {
+		return fmt.Errorf("backend does not support attribute access")
+	}
+	if err := accessor.WriteAttribute(ctx, name, value); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch name {
+	case AttrSampleRate:
+		r.cfg.SampleRate = value
+		if r.file != nil {
+			r.stopLocked("sample rate changed")
+		}
+		return nil
+	case AttrRxLO:
+		r.cfg.RxLO = value
+	case AttrRxGain0:
+		r.cfg.RxGain0 = int(math.Round(value))
+	case AttrRxGain1:
+		r.cfg.RxGain1 = int(math.Round(value))
+	default:
+		return nil
+	}
+	r.segment = r.file != nil
+	return nil
+}
+
+// Close ends the running recording and closes the wrapped backend.
+func (r *IQRecorder) Close() error {
+	r.mu.Lock()
+	var recErr error
+	if r.file != nil {
+		recErr = r.stopLocked("")
+	}
+	r.mu.Unlock()
+	return errors.Join(r.SDR.Close(), recErr)
+}
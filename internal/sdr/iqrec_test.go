@@ -0,0 +1,113 @@
+package sdr
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestIQRecorderWritesReplayableSigMF(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	rec := NewIQRecorder(NewMock(), dir)
+	if _, err := rec.Start("early"); err == nil {
+		t.Fatal("recording started before Init")
+	}
+	cfg := Config{SampleRate: 1e6, RxLO: 2.4e9, RxGain0: 30, RxGain1: 31, NumSamples: 256, ToneOffset: 50e3}
+	if err := rec.Init(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"../escape", ".hidden"} {
+		if _, err := rec.Start(name); err == nil {
+			t.Fatalf("name %q accepted", name)
+		}
+	}
+	status, err := rec.Start("run1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Active || status.Path != filepath.Join(dir, "run1") {
+		t.Fatalf("status = %+v", status)
+	}
+	if _, err := rec.Start("run2"); err == nil {
+		t.Fatal("second recording started")
+	}
+
+	var want [2][]complex64
+	rxOnce := func() {
+		rx0, rx1, err := rec.RX(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[0], want[1] = append(want[0], rx0...), append(want[1], rx1...)
+	}
+	rxOnce()
+	if err := rec.WriteAttribute(ctx, AttrRxLO, 2.45e9); err != nil {
+		t.Fatal(err)
+	}
+	rxOnce()
+	angle := 12.5
+	rec.Annotate(SigMFAnnotation{Label: "detection", TrackID: "T1", AngleDeg: &angle})
+	if status, err = rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if status.Active || status.Samples != 512 || status.Annotations != 1 {
+		t.Fatalf("status after stop = %+v", status)
+	}
+	if _, err := rec.Stop(); err == nil {
+		t.Fatal("Stop without recording succeeded")
+	}
+
+	meta, err := ReadSigMFMeta(status.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Global.Version != sigmfVersion || meta.Global.Datatype != "cf32_le" || meta.Global.HW != "mock" {
+		t.Fatalf("global = %+v", meta.Global)
+	}
+	if len(meta.Captures) != 2 || meta.Captures[1].SampleStart != 256 || meta.Captures[1].Frequency != 2.45e9 ||
+		len(meta.Captures[0].RxGainDB) != 2 || meta.Captures[0].RxGainDB[1] != 31 {
+		t.Fatalf("captures = %+v", meta.Captures)
+	}
+	if a := meta.Annotations; len(a) != 1 || a[0].SampleStart != 256 || a[0].SampleCount != 256 || a[0].TrackID != "T1" {
+		t.Fatalf("annotations = %+v", a)
+	}
+
+	replay := NewFile(status.Path)
+	if err := replay.Init(ctx, Config{NumSamples: 512}); err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	rx0, rx1, err := replay.RX(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rx0 {
+		if rx0[i] != want[0][i] || rx1[i] != want[1][i] {
+			t.Fatalf("sample %d = %v %v, want %v %v", i, rx0[i], rx1[i], want[0][i], want[1][i])
+		}
+	}
+}
+
+func TestIQRecorderStopsOnSampleRateChange(t *testing.T) {
+	ctx := context.Background()
+	rec := NewIQRecorder(NewMock(), t.TempDir())
+	if err := rec.Init(ctx, Config{SampleRate: 1e6, NumSamples: 128}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Start(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rec.RX(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.WriteAttribute(ctx, AttrSampleRate, 2e6); err != nil {
+		t.Fatal(err)
+	}
+	if status := rec.Status(); status.Active || status.Error == "" || status.Samples != 128 {
+		t.Fatalf("status = %+v", status)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
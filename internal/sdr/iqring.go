@@ -0,0 +1,438 @@
+package sdr
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IQ ring file layout, little endian throughout:
+//
+//	header  magic, block samples, block count, full scale
+//	index   from ringPageSize: one ringEntrySize record per block
+//	data    page aligned: blocks × block samples × (rx0 I, Q, rx1 I, Q) as int16
+//
+// A block is written by clearing its index record, copying the samples and
+// then writing the record, so a crash leaves at most the block being written
+// unreadable.
+const (
+	ringMagic      = "GOSDRIQ1"
+	ringPageSize   = 4096
+	ringEntrySize  = 40
+	ringSampleSize = 8 // ci16_le I/Q for two channels
+	// ringFlushInterval is how often dirty ring pages are synced to disk.
+	ringFlushInterval = time.Second
+)
+
+// RingBlock describes one block stored in an IQ ring.
+type RingBlock struct {
+	Seq         uint64    `json:"seq"`
+	Start       time.Time `json:"start"`
+	Samples     int       `json:"samples"`
+	FrequencyHz float64   `json:"frequencyHz"`
+	SampleRate  float64   `json:"sampleRate"`
+	slot        int
+}
+
+// End returns the time just after the last sample of the block.
+func (b RingBlock) End() time.Time {
+	return b.Start.Add(b.duration(b.Samples))
+}
+
+func (b RingBlock) duration(samples int) time.Duration {
+	if b.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(samples) / b.SampleRate * float64(time.Second))
+}
+
+// ringLayout is the geometry of a ring file.
+type ringLayout struct {
+	blockSamples int
+	blocks       int
+	fullScale    float64
+}
+
+func (l ringLayout) dataOffset() int64 {
+	index := int64(ringPageSize + l.blocks*ringEntrySize)
+	return (index + ringPageSize - 1) / ringPageSize * ringPageSize
+}
+
+func (l ringLayout) blockOffset(slot int) int64 {
+	return l.dataOffset() + int64(slot)*int64(l.blockSamples*ringSampleSize)
+}
+
+func (l ringLayout) size() int64 { return l.blockOffset(l.blocks) }
+
+func (l ringLayout) header() []byte {
+	b := make([]byte, 32)
+	copy(b, ringMagic)
+	binary.LittleEndian.PutUint32(b[8:], uint32(l.blockSamples))
+	binary.LittleEndian.PutUint32(b[12:], uint32(l.blocks))
+	binary.LittleEndian.PutUint64(b[16:], math.Float64bits(l.fullScale))
+	return b
+}
+
+func parseRingHeader(b []byte) (ringLayout, error) {
+	if len(b) < 32 || string(b[:8]) != ringMagic {
+		return ringLayout{}, fmt.Errorf("not an IQ ring file")
+	}
+	l := ringLayout{
+		blockSamples: int(binary.LittleEndian.Uint32(b[8:])),
+		blocks:       int(binary.LittleEndian.Uint32(b[12:])),
+		fullScale:    math.Float64frombits(binary.LittleEndian.Uint64(b[16:])),
+	}
+	if l.blockSamples <= 0 || l.blocks <= 0 {
+		return ringLayout{}, fmt.Errorf("corrupt IQ ring header")
+	}
+	return l, nil
+}
+
+// IQRing records two-channel IQ into a pre-allocated ring file, overwriting
+// the oldest blocks once full. Samples are stored as ci16_le scaled to the
+// backend's full scale. Where the platform allows, the file is memory mapped
+// so appending costs a copy; a background goroutine syncs dirty pages every
+// second, keeping slow storage (SD cards) out of the RX path.
+type IQRing struct {
+	mu     sync.Mutex
+	file   *os.File
+	mem    []byte // mapped file; nil when writing through the file
+	layout ringLayout
+	seq    uint64
+	err    error
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// CreateIQRing opens the ring at path with room for about size bytes in
+// blocks of blockSamples samples. An existing ring with the same geometry is
+// continued, so blocks recorded before a restart stay available; otherwise
+// the file is recreated and filled with zeros up front, which can take a
+// while for large rings. fullScale is the backend's clipping level (zero
+// selects 1).
+func CreateIQRing(path string, size int64, blockSamples int, fullScale float64) (*IQRing, error) {
+	if blockSamples <= 0 {
+		return nil, fmt.Errorf("ring block size must be positive")
+	}
+	if fullScale <= 0 {
+		fullScale = 1
+	}
+	layout := ringLayout{blockSamples: blockSamples, fullScale: fullScale}
+	layout.blocks = int((size - 2*ringPageSize) / int64(blockSamples*ringSampleSize+ringEntrySize))
+	if layout.blocks < 2 {
+		return nil, fmt.Errorf("ring size %d bytes holds fewer than 2 blocks of %d samples", size, blockSamples)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open ring: %w", err)
+	}
+	r := &IQRing{file: file, layout: layout, stop: make(chan struct{}), done: make(chan struct{})}
+	if err := r.prepare(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if mem, err := mapFile(file, layout.size()); err == nil {
+		r.mem = mem
+	}
+	go r.flushLoop()
+	return r, nil
+}
+
+// prepare reuses a ring file with matching geometry and resumes its sequence
+// numbers, or recreates it.
+func (r *IQRing) prepare() error {
+	head := make([]byte, 32)
+	if _, err := r.file.ReadAt(head, 0); err == nil {
+		info, statErr := r.file.Stat()
+		if l, err := parseRingHeader(head); err == nil && l == r.layout && statErr == nil && info.Size() == l.size() {
+			blocks, err := readRingIndex(r.file, l)
+			if err != nil {
+				return err
+			}
+			for _, b := range blocks {
+				r.seq = max(r.seq, b.Seq)
+			}
+			return nil
+		}
+	}
+	if err := r.file.Truncate(0); err != nil {
+		return fmt.Errorf("create ring: %w", err)
+	}
+	w := bufio.NewWriterSize(r.file, 1<<20)
+	zeros := make([]byte, 1<<20)
+	for left := r.layout.size(); left > 0; left -= int64(len(zeros)) {
+		if _, err := w.Write(zeros[:min(left, int64(len(zeros)))]); err != nil {
+			return fmt.Errorf("allocate ring: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("allocate ring: %w", err)
+	}
+	if _, err := r.file.WriteAt(r.layout.header(), 0); err != nil {
+		return fmt.Errorf("create ring: %w", err)
+	}
+	return r.file.Sync()
+}
+
+// Append stores one buffer pair whose first sample was taken at start,
+// split over as many blocks as needed.
+func (r *IQRing) Append(start time.Time, frequencyHz, sampleRate float64, rx0, rx1 []complex64) error {
+	n := min(len(rx0), len(rx1))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return fmt.Errorf("ring closed")
+	}
+	block := RingBlock{FrequencyHz: frequencyHz, SampleRate: sampleRate}
+	for off := 0; off < n; off += r.layout.blockSamples {
+		end := min(off+r.layout.blockSamples, n)
+		r.seq++
+		block.Seq, block.Start, block.Samples = r.seq, start.Add(block.duration(off)), end-off
+		block.slot = int((r.seq - 1) % uint64(r.layout.blocks))
+		if err := r.writeBlock(block, rx0[off:end], rx1[off:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *IQRing) writeBlock(block RingBlock, rx0, rx1 []complex64) error {
+	entryAt := int64(ringPageSize + block.slot*ringEntrySize)
+	if err := r.writeAt(make([]byte, ringEntrySize), entryAt); err != nil {
+		return err
+	}
+	data := make([]byte, len(rx0)*ringSampleSize)
+	scale := 32767 / r.layout.fullScale
+	for i := range rx0 {
+		for k, v := range [4]float32{real(rx0[i]), imag(rx0[i]), real(rx1[i]), imag(rx1[i])} {
+			q := math.Round(math.Max(-32768, math.Min(32767, float64(v)*scale)))
+			binary.LittleEndian.PutUint16(data[i*ringSampleSize+2*k:], uint16(int16(q)))
+		}
+	}
+	if err := r.writeAt(data, r.layout.blockOffset(block.slot)); err != nil {
+		return err
+	}
+	return r.writeAt(encodeRingEntry(block), entryAt)
+}
+
+func (r *IQRing) writeAt(p []byte, off int64) error {
+	if r.mem != nil {
+		copy(r.mem[off:], p)
+		return nil
+	}
+	if _, err := r.file.WriteAt(p, off); err != nil {
+		return fmt.Errorf("write ring: %w", err)
+	}
+	return nil
+}
+
+// flushLoop syncs the ring to disk until Close.
+func (r *IQRing) flushLoop() {
+	defer close(r.done)
+	ticker := time.NewTicker(ringFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			if err := r.file.Sync(); err != nil && r.err == nil {
+				r.err = fmt.Errorf("sync ring: %w", err)
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// Close syncs and closes the ring. It returns the first background sync
+// error, if any.
+func (r *IQRing) Close() error {
+	r.mu.Lock()
+	if r.file == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+	close(r.stop)
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := errors.Join(r.err, r.file.Sync())
+	if r.mem != nil {
+		err = errors.Join(err, unmapFile(r.mem))
+		r.mem = nil
+	}
+	err = errors.Join(err, r.file.Close())
+	r.file = nil
+	return err
+}
+
+func encodeRingEntry(b RingBlock) []byte {
+	e := make([]byte, ringEntrySize)
+	binary.LittleEndian.PutUint64(e[0:], b.Seq)
+	binary.LittleEndian.PutUint64(e[8:], uint64(b.Start.UnixNano()))
+	binary.LittleEndian.PutUint32(e[16:], uint32(b.Samples))
+	binary.LittleEndian.PutUint64(e[24:], math.Float64bits(b.FrequencyHz))
+	binary.LittleEndian.PutUint64(e[32:], math.Float64bits(b.SampleRate))
+	return e
+}
+
+// readRingIndex returns the valid blocks of a ring, oldest first.
+func readRingIndex(f io.ReaderAt, l ringLayout) ([]RingBlock, error) {
+	index := make([]byte, l.blocks*ringEntrySize)
+	if _, err := f.ReadAt(index, ringPageSize); err != nil {
+		return nil, fmt.Errorf("read ring index: %w", err)
+	}
+	var blocks []RingBlock
+	for slot := 0; slot < l.blocks; slot++ {
+		e := index[slot*ringEntrySize:]
+		b := RingBlock{
+			Seq:         binary.LittleEndian.Uint64(e[0:]),
+			Start:       time.Unix(0, int64(binary.LittleEndian.Uint64(e[8:]))).UTC(),
+			Samples:     int(binary.LittleEndian.Uint32(e[16:])),
+			FrequencyHz: math.Float64frombits(binary.LittleEndian.Uint64(e[24:])),
+			SampleRate:  math.Float64frombits(binary.LittleEndian.Uint64(e[32:])),
+			slot:        slot,
+		}
+		if b.Seq == 0 || b.Samples <= 0 || b.Samples > l.blockSamples || b.SampleRate <= 0 {
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Seq < blocks[j].Seq })
+	return blocks, nil
+}
+
+// IQRingReader reads blocks from a ring file, also while another process is
+// recording into it.
+type IQRingReader struct {
+	file   *os.File
+	layout ringLayout
+}
+
+// OpenIQRing opens the ring at path for reading.
+func OpenIQRing(path string) (*IQRingReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	head := make([]byte, 32)
+	if _, err := file.ReadAt(head, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	layout, err := parseRingHeader(head)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &IQRingReader{file: file, layout: layout}, nil
+}
+
+// Blocks returns the blocks currently in the ring, oldest first.
+func (r *IQRingReader) Blocks() ([]RingBlock, error) {
+	return readRingIndex(r.file, r.layout)
+}
+
+// Close closes the ring file.
+func (r *IQRingReader) Close() error { return r.file.Close() }
+
+// ExtractSigMF writes the samples recorded in [from, to) as a two-channel
+// ci16_le SigMF recording at base (.sigmf-meta and .sigmf-data) that the file
+// backend can replay. Each stretch of contiguous blocks becomes one capture
+// segment with its own start time. It fails when the range holds no samples
+// or spans a sample rate change.
+func (r *IQRingReader) ExtractSigMF(from, to time.Time, base, description string) (SigMFMeta, error) {
+	blocks, err := r.Blocks()
+	if err != nil {
+		return SigMFMeta{}, err
+	}
+	_, dataPath := SigMFPaths(base)
+	out, err := os.Create(dataPath)
+	if err != nil {
+		return SigMFMeta{}, err
+	}
+	w := bufio.NewWriter(out)
+
+	var meta SigMFMeta
+	var written int64
+	var prev RingBlock
+	for _, b := range blocks {
+		if !b.End().After(from) || !b.Start.Before(to) {
+			continue
+		}
+		if meta.Global.SampleRate == 0 {
+			meta.Global.SampleRate = b.SampleRate
+		} else if b.SampleRate != meta.Global.SampleRate {
+			err = fmt.Errorf("sample rate changes from %g to %g Hz at %s", meta.Global.SampleRate, b.SampleRate, b.Start.Format(time.RFC3339Nano))
+			break
+		}
+		first, last := ringSampleRange(b, from, to)
+		if first >= last {
+			continue
+		}
+		start := b.Start.Add(b.duration(first))
+		if prev.Seq == 0 || !ringContiguous(prev, b) {
+			meta.Captures = append(meta.Captures, SigMFCapture{
+				SampleStart: written,
+				Frequency:   b.FrequencyHz,
+				Datetime:    start.UTC().Format(time.RFC3339Nano),
+			})
+		}
+		data := make([]byte, (last-first)*ringSampleSize)
+		if _, err = r.file.ReadAt(data, r.layout.blockOffset(b.slot)+int64(first*ringSampleSize)); err != nil {
+			break
+		}
+		if _, err = w.Write(data); err != nil {
+			break
+		}
+		written += int64(last - first)
+		prev = b
+	}
+	if err == nil && written == 0 {
+		err = fmt.Errorf("no samples recorded between %s and %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	err = errors.Join(err, w.Flush(), out.Close())
+	if err != nil {
+		os.Remove(dataPath)
+		return SigMFMeta{}, err
+	}
+
+	meta.Global.Datatype = "ci16_le"
+	meta.Global.Version = sigmfVersion
+	meta.Global.NumChannels = 2
+	meta.Global.Description = description
+	return meta, WriteSigMFMeta(base, meta)
+}
+
+// ringSampleRange returns the samples of b that fall in [from, to).
+func ringSampleRange(b RingBlock, from, to time.Time) (first, last int) {
+	last = b.Samples
+	if from.After(b.Start) {
+		first = int(math.Ceil(from.Sub(b.Start).Seconds() * b.SampleRate))
+	}
+	if to.Before(b.End()) {
+		last = int(math.Ceil(to.Sub(b.Start).Seconds() * b.SampleRate))
+	}
+	return max(first, 0), min(last, b.Samples)
+}
+
+// ringContiguous reports whether b directly continues prev. Block times are
+// taken from the host clock, so a quarter block of jitter is tolerated.
+func ringContiguous(prev, b RingBlock) bool {
+	if b.Seq != prev.Seq+1 || b.FrequencyHz != prev.FrequencyHz {
+		return false
+	}
+	gap := b.Start.Sub(prev.End())
+	return gap.Abs() <= b.duration(b.Samples)/4
+}
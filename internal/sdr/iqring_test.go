@@ -0,0 +1,138 @@
+package sdr
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func rampBuffers(n int, base float32) ([]complex64, []complex64) {
+	rx0 := make([]complex64, n)
+	rx1 := make([]complex64, n)
+	for i := range rx0 {
+		v := base + float32(i)/1024
+		rx0[i] = complex(v, -v)
+		rx1[i] = complex(-v/2, v/2)
+	}
+	return rx0, rx1
+}
+
+func TestIQRingExtractsTimeRange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capture.ring")
+	// Room for four blocks of 100 samples.
+	ring, err := CreateIQRing(path, 2*ringPageSize+4*(100*ringSampleSize+ringEntrySize), 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// 1 kHz: each 100-sample block covers 100 ms. The third buffer follows a
+	// 500 ms gap and the fourth overwrites the oldest block.
+	starts := []time.Time{t0, t0.Add(100 * time.Millisecond), t0.Add(700 * time.Millisecond)}
+	for i, start := range starts {
+		rx0, rx1 := rampBuffers(100+100*(i/2), float32(i)/8)
+		if err := ring.Append(start, 2.4e9, 1000, rx0, rx1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ring.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := OpenIQRing(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	blocks, err := reader.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 4 || blocks[0].Seq != 1 || blocks[3].Seq != 4 || !blocks[3].Start.Equal(t0.Add(800*time.Millisecond)) {
+		t.Fatalf("blocks = %+v", blocks)
+	}
+
+	base := filepath.Join(dir, "cut")
+	meta, err := reader.ExtractSigMF(t0.Add(50*time.Millisecond), t0.Add(750*time.Millisecond), base, "test cut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Captures) != 2 || meta.Captures[1].SampleStart != 150 || meta.Captures[1].Datetime != "2024-05-01T12:00:00.7Z" {
+		t.Fatalf("captures = %+v, want a second segment after the gap at sample 150", meta.Captures)
+	}
+
+	f := NewFile(base)
+	ctx := context.Background()
+	if err := f.Init(ctx, Config{NumSamples: 200}); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rx0, rx1, err := f.RX(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want0, want1 := rampBuffers(100, 0)
+	if d := math.Abs(float64(real(rx0[0]) - real(want0[50]))); d > 1e-4 || math.Abs(float64(imag(rx1[0])-imag(want1[50]))) > 1e-4 {
+		t.Fatalf("first extracted sample %v/%v, want %v/%v", rx0[0], rx1[0], want0[50], want1[50])
+	}
+	if !f.Meta().Start().Equal(t0.Add(50 * time.Millisecond)) {
+		t.Fatalf("extract starts at %v", f.Meta().Start())
+	}
+
+	if _, err := reader.ExtractSigMF(t0.Add(time.Hour), t0.Add(2*time.Hour), base, ""); err == nil {
+		t.Fatal("expected an error for a range without samples")
+	}
+}
+
+func TestIQRingResumesExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.ring")
+	size := int64(2*ringPageSize + 8*(64*ringSampleSize+ringEntrySize))
+	rx0, rx1 := rampBuffers(64, 0)
+	for run := 0; run < 2; run++ {
+		ring, err := CreateIQRing(path, size, 64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ring.Append(time.Now(), 1e9, 1e6, rx0, rx1); err != nil {
+			t.Fatal(err)
+		}
+		ring.Close()
+	}
+	reader, err := OpenIQRing(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	blocks, _ := reader.Blocks()
+	if len(blocks) != 2 || blocks[1].Seq != 2 {
+		t.Fatalf("blocks after restart = %+v, want both runs kept", blocks)
+	}
+}
+
+func TestRingRecorderRecordsRX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.ring")
+	rec := NewRingRecorder(NewMock(), path, 1<<20)
+	ctx := context.Background()
+	if err := rec.Init(ctx, Config{SampleRate: 1e6, RxLO: 2.4e9, NumSamples: 256, ToneOffset: 100e3}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := rec.RX(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := OpenIQRing(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	blocks, _ := reader.Blocks()
+	if len(blocks) != 3 || blocks[0].FrequencyHz != 2.4e9 || blocks[0].Samples != 256 {
+		t.Fatalf("blocks = %+v", blocks)
+	}
+}
@@ -0,0 +1,141 @@
+package sdr
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LifecycleKind names a backend state transition.
+type LifecycleKind string
+
+// Lifecycle event kinds, shared by all backends.
+const (
+	LifecycleConnecting    LifecycleKind = "connecting"
+	LifecycleConnected     LifecycleKind = "connected"
+	LifecycleBufferCreated LifecycleKind = "buffer_created"
+	LifecycleUnderrun      LifecycleKind = "underrun"
+	LifecycleDisconnected  LifecycleKind = "disconnected"
+	LifecycleReconnecting  LifecycleKind = "reconnecting"
+	LifecycleClosed        LifecycleKind = "closed"
+)
+
+// LifecycleEvent is one typed backend state transition. Err is set when a
+// connection attempt failed (Kind is then LifecycleClosed) or a read failed.
+type LifecycleEvent struct {
+	Kind    LifecycleKind `json:"kind"`
+	Backend string        `json:"backend"`
+	Time    time.Time     `json:"time"`
+	Detail  string        `json:"detail,omitempty"`
+	Err     string        `json:"error,omitempty"`
+}
+
+// LifecycleObserver receives lifecycle events.
+type LifecycleObserver interface {
+	ObserveLifecycle(ev LifecycleEvent)
+}
+
+// lifecycleSource is implemented by backends that emit events the monitor
+// cannot see from outside, such as buffer creation.
+type lifecycleSource interface {
+	SetLifecycleObserver(obs LifecycleObserver)
+}
+
+// LifecycleMonitor wraps a backend and emits the connect, underrun and close
+// transitions every backend goes through, so consumers see a uniform state
+// regardless of the hardware. Initializing an already connected backend is
+// reported as a reconnect.
+type LifecycleMonitor struct {
+	SDR
+
+	mu        sync.Mutex
+	name      string
+	observer  LifecycleObserver
+	connected bool
+	last      LifecycleEvent
+}
+
+// NewLifecycleMonitor wraps backend, labelling its events with name.
+func NewLifecycleMonitor(backend SDR, name string) *LifecycleMonitor {
+	return &LifecycleMonitor{SDR: backend, name: name}
+}
+
+// Unwrap returns the wrapped backend.
+func (m *LifecycleMonitor) Unwrap() SDR { return m.SDR }
+
+// SetLifecycleObserver configures where events are delivered, including
+// events emitted by the wrapped backend itself.
+func (m *LifecycleMonitor) SetLifecycleObserver(obs LifecycleObserver) {
+	m.mu.Lock()
+	m.observer = obs
+	m.mu.Unlock()
+	if src, ok := As[lifecycleSource](m.SDR); ok {
+		src.SetLifecycleObserver(obs)
+	}
+}
+
+// State returns the most recent event emitted by the monitor.
+func (m *LifecycleMonitor) State() LifecycleEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Init reports connecting (or reconnecting), then connected or a failed close.
+func (m *LifecycleMonitor) Init(ctx context.Context, cfg Config) error {
+	m.mu.Lock()
+	kind := LifecycleConnecting
+	if m.connected {
+		kind = LifecycleReconnecting
+	}
+	m.mu.Unlock()
+	m.emit(LifecycleEvent{Kind: kind, Detail: cfg.URI})
+
+	if err := m.SDR.Init(ctx, cfg); err != nil {
+		m.setConnected(false)
+		m.emit(LifecycleEvent{Kind: LifecycleClosed, Detail: cfg.URI, Err: err.Error()})
+		return err
+	}
+	m.setConnected(true)
+	m.emit(LifecycleEvent{Kind: LifecycleConnected, Detail: cfg.URI})
+	return nil
+}
+
+// RX reports failed reads as underruns.
+func (m *LifecycleMonitor) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := m.SDR.RX(ctx)
+	if err != nil && ctx.Err() == nil {
+		m.emit(LifecycleEvent{Kind: LifecycleUnderrun, Err: err.Error()})
+	}
+	return rx0, rx1, err
+}
+
+// Close reports the backend as closed.
+func (m *LifecycleMonitor) Close() error {
+	err := m.SDR.Close()
+	m.setConnected(false)
+	ev := LifecycleEvent{Kind: LifecycleClosed}
+	if err != nil {
+		ev.Err = err.Error()
+	}
+	m.emit(ev)
+	return err
+}
+
+func (m *LifecycleMonitor) setConnected(connected bool) {
+	m.mu.Lock()
+	m.connected = connected
+	m.mu.Unlock()
+}
+
+func (m *LifecycleMonitor) emit(ev LifecycleEvent) {
+	ev.Backend = m.name
+	ev.Time = time.Now()
+	m.mu.Lock()
+	m.last = ev
+	obs := m.observer
+	m.mu.Unlock()
+	if obs != nil {
+		obs.ObserveLifecycle(ev)
+	}
+}
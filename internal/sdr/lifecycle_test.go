@@ -0,0 +1,88 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// flakySDR fails Init and RX on demand.
+type flakySDR struct {
+	*MockSDR
+	initErr error
+	rxErr   error
+}
+
+func (f *flakySDR) Init(ctx context.Context, cfg Config) error {
+	if f.initErr != nil {
+		return f.initErr
+	}
+	return f.MockSDR.Init(ctx, cfg)
+}
+
+func (f *flakySDR) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	if f.rxErr != nil {
+		return nil, nil, f.rxErr
+	}
+	return f.MockSDR.RX(ctx)
+}
+
+type lifecycleRecorder struct{ events []LifecycleEvent }
+
+func (r *lifecycleRecorder) ObserveLifecycle(ev LifecycleEvent) { r.events = append(r.events, ev) }
+
+func TestLifecycleMonitorEmitsTransitions(t *testing.T) {
+	backend := &flakySDR{MockSDR: NewMock()}
+	monitor := NewLifecycleMonitor(backend, "mock")
+	rec := &lifecycleRecorder{}
+	monitor.SetLifecycleObserver(rec)
+	ctx := context.Background()
+	cfg := Config{SampleRate: 1e6, NumSamples: 64}
+
+	if err := monitor.Init(ctx, cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	backend.rxErr = errors.New("timeout")
+	if _, _, err := monitor.RX(ctx); err == nil {
+		t.Fatal("expected RX error")
+	}
+	backend.initErr = errors.New("refused")
+	if err := monitor.Init(ctx, cfg); err == nil {
+		t.Fatal("expected Init error")
+	}
+	backend.initErr = nil
+	_ = monitor.Init(ctx, cfg)
+	_ = monitor.Close()
+
+	want := []LifecycleKind{
+		LifecycleConnecting, LifecycleConnected,
+		LifecycleUnderrun,
+		LifecycleReconnecting, LifecycleClosed,
+		LifecycleConnecting, LifecycleConnected,
+		LifecycleClosed,
+	}
+	if len(rec.events) != len(want) {
+		t.Fatalf("got %+v, want kinds %v", rec.events, want)
+	}
+	for i, kind := range want {
+		if rec.events[i].Kind != kind || rec.events[i].Backend != "mock" {
+			t.Fatalf("event %d = %+v, want %s", i, rec.events[i], kind)
+		}
+	}
+	if rec.events[4].Err != "refused" {
+		t.Fatalf("failed connect should carry the error, got %+v", rec.events[4])
+	}
+	if got := monitor.State(); got.Kind != LifecycleClosed || got.Err != "" {
+		t.Fatalf("unexpected final state %+v", got)
+	}
+}
+
+func TestLifecycleMonitorForwardsObserver(t *testing.T) {
+	pluto := NewPluto()
+	monitor := NewLifecycleMonitor(NewIntegrityChecker(pluto), "pluto")
+	rec := &lifecycleRecorder{}
+	monitor.SetLifecycleObserver(rec)
+	if pluto.lifecycle != rec {
+		t.Fatal("expected observer to reach the Pluto backend")
+	}
+}
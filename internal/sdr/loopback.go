@@ -0,0 +1,208 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+)
+
+const (
+	// loopbackAmplitude is the TX level of the pattern relative to full scale.
+	loopbackAmplitude = 0.5
+	// loopbackMinPeakRatio is how far the correlation peak must stand above
+	// the mean correlation to count as a detection.
+	loopbackMinPeakRatio = 8
+)
+
+// LoopbackOptions tunes a loopback delay measurement. Zero values select the
+// defaults in parentheses.
+type LoopbackOptions struct {
+	// PatternLength is the length of the pseudo-random BPSK pattern (1023),
+	// capped at half an RX buffer.
+	PatternLength int `json:"patternLength,omitempty"`
+	// Buffers is the number of RX buffers searched after each TX (4).
+	Buffers int `json:"buffers,omitempty"`
+	// Trials is the number of repeated measurements (5).
+	Trials int `json:"trials,omitempty"`
+}
+
+// LoopbackResult reports the TX to RX delay, counted in samples from the
+// first RX sample read after TX returned to the start of the received
+// pattern. Backends with cyclic TX buffers, such as the Pluto, repeat the
+// burst, so the delay is only known modulo BufferSamples there. Identical
+// trials show that buffer plumbing adds a deterministic latency.
+type LoopbackResult struct {
+	Time          time.Time `json:"time"`
+	DelaySamples  int       `json:"delaySamples"`
+	DelaySeconds  float64   `json:"delaySeconds,omitempty"`
+	Trials        []int     `json:"trials"`
+	JitterSamples int       `json:"jitterSamples"`
+	Deterministic bool      `json:"deterministic"`
+	PeakRatio     float64   `json:"peakRatio"`
+	BufferSamples int       `json:"bufferSamples"`
+}
+
+// LoopbackPattern returns n samples of a maximal-length pseudo-random BPSK
+// sequence (10-bit LFSR, period 1023) at the given amplitude. Its sharp
+// autocorrelation makes the arrival time unambiguous within one period.
+func LoopbackPattern(n int, amplitude float32) []complex64 {
+	out := make([]complex64, n)
+	state := uint16(0x3ff)
+	for i := range out {
+		bit := (state ^ state>>3) & 1 // taps 10 and 7
+		state = state>>1 | bit<<9
+		if state&1 == 1 {
+			out[i] = complex(amplitude, 0)
+		} else {
+			out[i] = complex(-amplitude, 0)
+		}
+	}
+	return out
+}
+
+// MeasureLoopback transmits a known pattern on both TX channels and locates
+// it in the following RX buffers of channel 0, repeating for opts.Trials.
+// TX must be connected to RX through a cable and attenuator. The caller must
+// keep other readers off the backend meanwhile; LoopbackMeter does that.
+// sampleRate, when known, converts the delay to seconds.
+func MeasureLoopback(ctx context.Context, backend SDR, opts LoopbackOptions, sampleRate float64) (LoopbackResult, error) {
+	if opts.PatternLength <= 0 {
+		opts.PatternLength = 1023
+	}
+	if opts.Buffers <= 0 {
+		opts.Buffers = 4
+	}
+	if opts.Trials <= 0 {
+		opts.Trials = 5
+	}
+
+	result := LoopbackResult{Time: time.Now()}
+	for range opts.Trials {
+		delay, ratio, n, err := loopbackTrial(ctx, backend, opts)
+		if err != nil {
+			return LoopbackResult{}, err
+		}
+		result.Trials = append(result.Trials, delay)
+		result.BufferSamples = n
+		if result.PeakRatio == 0 || ratio < result.PeakRatio {
+			result.PeakRatio = ratio
+		}
+	}
+	sorted := slices.Sorted(slices.Values(result.Trials))
+	result.DelaySamples = sorted[len(sorted)/2]
+	result.JitterSamples = sorted[len(sorted)-1] - sorted[0]
+	result.Deterministic = result.JitterSamples == 0
+	if sampleRate > 0 {
+		result.DelaySeconds = float64(result.DelaySamples) / sampleRate
+	}
+	return result, nil
+}
+
+// loopbackTrial runs one measurement and returns the delay, the peak ratio
+// and the RX buffer size. The TX buffer is silenced again afterwards.
+func loopbackTrial(ctx context.Context, backend SDR, opts LoopbackOptions) (int, float64, int, error) {
+	rx0, _, err := backend.RX(ctx)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("loopback: %w", err)
+	}
+	n := len(rx0)
+	if n < 2 {
+		return 0, 0, 0, errors.New("loopback: RX buffer too short")
+	}
+	pattern := LoopbackPattern(min(opts.PatternLength, n/2), loopbackAmplitude)
+	burst := make([]complex64, n)
+	copy(burst, pattern)
+	if err := backend.TX(ctx, burst, burst); err != nil {
+		return 0, 0, 0, fmt.Errorf("loopback: %w", err)
+	}
+
+	capture := make([]complex64, 0, opts.Buffers*n)
+	for range opts.Buffers {
+		rx0, _, err := backend.RX(ctx)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("loopback: %w", err)
+		}
+		capture = append(capture, rx0...)
+	}
+	silence := make([]complex64, n)
+	if err := backend.TX(ctx, silence, silence); err != nil {
+		return 0, 0, 0, fmt.Errorf("loopback: %w", err)
+	}
+
+	corr := dsp.CrossCorrelate(capture, pattern)
+	peak, mean := 0.0, 0.0
+	for _, v := range corr {
+		peak = max(peak, v)
+		mean += v
+	}
+	mean /= float64(len(corr))
+	if mean == 0 || peak/mean < loopbackMinPeakRatio {
+		return 0, 0, 0, errors.New("loopback: pattern not received; is TX connected to RX?")
+	}
+	// The earliest strong peak is the first arrival; later ones are repeats
+	// of a cyclic TX buffer.
+	delay := slices.IndexFunc(corr, func(v float64) bool { return v >= peak/2 })
+	return delay, peak / mean, n, nil
+}
+
+// LoopbackMeter wraps a backend so loopback measurements can run while a
+// tracker owns it: RX calls are serialized and a measurement holds the
+// backend for its whole duration, so it sees every buffer in order.
+type LoopbackMeter struct {
+	SDR
+
+	mu         sync.Mutex
+	sampleRate float64
+	last       *LoopbackResult
+}
+
+// NewLoopbackMeter wraps backend.
+func NewLoopbackMeter(backend SDR) *LoopbackMeter {
+	return &LoopbackMeter{SDR: backend}
+}
+
+// Unwrap returns the wrapped backend.
+func (m *LoopbackMeter) Unwrap() SDR { return m.SDR }
+
+// Init records the sample rate and initializes the wrapped backend.
+func (m *LoopbackMeter) Init(ctx context.Context, cfg Config) error {
+	m.mu.Lock()
+	m.sampleRate = cfg.SampleRate
+	m.mu.Unlock()
+	return m.SDR.Init(ctx, cfg)
+}
+
+// RX reads from the wrapped backend unless a measurement is running.
+func (m *LoopbackMeter) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.SDR.RX(ctx)
+}
+
+// Measure runs MeasureLoopback with exclusive use of the backend and keeps
+// the result.
+func (m *LoopbackMeter) Measure(ctx context.Context, opts LoopbackOptions) (LoopbackResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, err := MeasureLoopback(ctx, m.SDR, opts, m.sampleRate)
+	if err != nil {
+		return LoopbackResult{}, err
+	}
+	m.last = &result
+	return result, nil
+}
+
+// Last returns the most recent successful measurement.
+func (m *LoopbackMeter) Last() (LoopbackResult, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.last == nil {
+		return LoopbackResult{}, false
+	}
+	return *m.last, true
+}
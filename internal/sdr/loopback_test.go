@@ -0,0 +1,67 @@
+package sdr
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLoopbackPatternIsMaximalLength(t *testing.T) {
+	p := LoopbackPattern(2046, 1)
+	ones := 0
+	for i := range 1023 {
+		if p[i] != p[i+1023] {
+			t.Fatalf("pattern does not repeat with period 1023 at %d", i)
+		}
+		if real(p[i]) > 0 {
+			ones++
+		}
+	}
+	if ones != 512 {
+		t.Fatalf("%d ones in one period, want 512 for an m-sequence", ones)
+	}
+}
+
+func TestLoopbackMeterMeasuresMockDelay(t *testing.T) {
+	ctx := context.Background()
+	meter := NewLoopbackMeter(NewMock())
+	if err := meter.Init(ctx, Config{SampleRate: 1e6, NumSamples: 1024, ToneOffset: 100e3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := meter.Last(); ok {
+		t.Fatal("result before any measurement")
+	}
+	result, err := meter.Measure(ctx, LoopbackOptions{Trials: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DelaySamples != mockLoopbackDelay || !result.Deterministic || len(result.Trials) != 3 || result.BufferSamples != 1024 {
+		t.Fatalf("result = %+v, want a deterministic %d-sample delay", result, mockLoopbackDelay)
+	}
+	if result.DelaySeconds != float64(mockLoopbackDelay)/1e6 {
+		t.Fatalf("delay %g s", result.DelaySeconds)
+	}
+	if last, ok := meter.Last(); !ok || last.DelaySamples != result.DelaySamples {
+		t.Fatalf("Last() = %+v, %v", last, ok)
+	}
+	// The silence burst leaves nothing in flight for the tracker.
+	rx0, _, _ := meter.RX(ctx)
+	if real(rx0[mockLoopbackDelay]) > 1.1 || real(rx0[mockLoopbackDelay]) < -1.1 {
+		t.Fatalf("pattern still received after the measurement: %v", rx0[mockLoopbackDelay])
+	}
+}
+
+// silentTX drops transmitted samples, like a radio without a loopback cable.
+type silentTX struct{ *MockSDR }
+
+func (silentTX) TX(context.Context, []complex64, []complex64) error { return nil }
+
+func TestMeasureLoopbackWithoutCable(t *testing.T) {
+	ctx := context.Background()
+	backend := silentTX{NewMock()}
+	if err := backend.Init(ctx, Config{SampleRate: 1e6, NumSamples: 1024, ToneOffset: 100e3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MeasureLoopback(ctx, backend, LoopbackOptions{Trials: 1}, 0); err == nil {
+		t.Fatal("expected an error when the pattern is not received")
+	}
+}
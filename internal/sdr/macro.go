@@ -0,0 +1,96 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// RawAttributeAccessor is implemented by backends that expose raw IIO-style
+// device/channel attributes, for setups GoSDR does not model natively.
+// Channel is empty for device attributes.
+type RawAttributeAccessor interface {
+	ReadRawAttribute(ctx context.Context, device, channel, attr string) (string, error)
+	WriteRawAttribute(ctx context.Context, device, channel, attr, value string) error
+}
+
+// MacroStep is one attribute write of an attribute macro.
+type MacroStep struct {
+	Device  string `json:"device"`
+	Channel string `json:"channel,omitempty"`
+	Attr    string `json:"attr"`
+	Value   string `json:"value"`
+}
+
+func (s MacroStep) String() string {
+	if s.Channel == "" {
+		return s.Device + "/" + s.Attr
+	}
+	return s.Device + "/" + s.Channel + "/" + s.Attr
+}
+
+// Macros maps macro names to their steps, executed in order.
+type Macros map[string][]MacroStep
+
+// Validate checks that every macro has steps naming a device and attribute.
+func (m Macros) Validate() error {
+	for _, name := range m.Names() {
+		if len(m[name]) == 0 {
+			return fmt.Errorf("macro %q: no steps", name)
+		}
+		for i, step := range m[name] {
+			if step.Device == "" || step.Attr == "" {
+				return fmt.Errorf("macro %q step %d: device and attr are required", name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// Names returns the macro names sorted.
+func (m Macros) Names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MacroResult reports one executed step with the value it replaced.
+type MacroResult struct {
+	Step MacroStep `json:"step"`
+	Old  string    `json:"old"`
+}
+
+// RunMacro reads each attribute, then writes the new value, in order. When a
+// step fails, the steps already applied are restored in reverse order and
+// the error (joined with any rollback failures) is returned together with the
+// steps that had been applied.
+func RunMacro(ctx context.Context, acc RawAttributeAccessor, steps []MacroStep) ([]MacroResult, error) {
+	applied := make([]MacroResult, 0, len(steps))
+	for _, step := range steps {
+		old, err := acc.ReadRawAttribute(ctx, step.Device, step.Channel, step.Attr)
+		if err == nil {
+			err = acc.WriteRawAttribute(ctx, step.Device, step.Channel, step.Attr, step.Value)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", step, err)
+			return applied, errors.Join(err, rollbackMacro(ctx, acc, applied))
+		}
+		applied = append(applied, MacroResult{Step: step, Old: old})
+	}
+	return applied, nil
+}
+
+func rollbackMacro(ctx context.Context, acc RawAttributeAccessor, applied []MacroResult) error {
+	var errs []error
+	for i := len(applied) - 1; i >= 0; i-- {
+		r := applied[i]
+		if err := acc.WriteRawAttribute(ctx, r.Step.Device, r.Step.Channel, r.Step.Attr, r.Old); err != nil {
+			errs = append(errs, fmt.Errorf("rollback %s: %w", r.Step, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,89 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// rawAttrs is an in-memory RawAttributeAccessor that rejects writes to
+// attributes listed in fail.
+type rawAttrs struct {
+	values map[string]string
+	fail   map[string]bool
+	writes []string
+}
+
+func (r *rawAttrs) ReadRawAttribute(_ context.Context, device, channel, attr string) (string, error) {
+	return r.values[MacroStep{Device: device, Channel: channel, Attr: attr}.String()], nil
+}
+
+func (r *rawAttrs) WriteRawAttribute(_ context.Context, device, channel, attr, value string) error {
+	key := MacroStep{Device: device, Channel: channel, Attr: attr}.String()
+	if r.fail[key] {
+		return errors.New("permission denied")
+	}
+	r.writes = append(r.writes, key+"="+value)
+	r.values[key] = value
+	return nil
+}
+
+func TestRunMacroAppliesInOrder(t *testing.T) {
+	attrs := &rawAttrs{values: map[string]string{"phy/loopback": "0"}}
+	steps := []MacroStep{
+		{Device: "phy", Attr: "loopback", Value: "1"},
+		{Device: "phy", Channel: "voltage2", Attr: "hardwaregain", Value: "10"},
+	}
+	results, err := RunMacro(context.Background(), attrs, steps)
+	if err != nil {
+		t.Fatalf("RunMacro: %v", err)
+	}
+	if len(results) != 2 || results[0].Old != "0" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	want := []string{"phy/loopback=1", "phy/voltage2/hardwaregain=10"}
+	if strings.Join(attrs.writes, ",") != strings.Join(want, ",") {
+		t.Fatalf("writes %v, want %v", attrs.writes, want)
+	}
+}
+
+func TestRunMacroRollsBackOnFailure(t *testing.T) {
+	attrs := &rawAttrs{
+		values: map[string]string{"phy/a": "1", "phy/b": "2", "phy/c": "3"},
+		fail:   map[string]bool{"phy/c": true},
+	}
+	steps := []MacroStep{
+		{Device: "phy", Attr: "a", Value: "10"},
+		{Device: "phy", Attr: "b", Value: "20"},
+		{Device: "phy", Attr: "c", Value: "30"},
+	}
+	results, err := RunMacro(context.Background(), attrs, steps)
+	if err == nil || !strings.Contains(err.Error(), "phy/c: permission denied") {
+		t.Fatalf("expected step error, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected two applied steps, got %+v", results)
+	}
+	want := []string{"phy/a=10", "phy/b=20", "phy/b=2", "phy/a=1"}
+	if strings.Join(attrs.writes, ",") != strings.Join(want, ",") {
+		t.Fatalf("writes %v, want %v", attrs.writes, want)
+	}
+}
+
+func TestMacrosValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		macros  Macros
+		wantErr bool
+	}{
+		{name: "valid", macros: Macros{"obs": {{Device: "phy", Attr: "x", Value: "1"}}}},
+		{name: "empty", macros: Macros{"obs": nil}, wantErr: true},
+		{name: "missing attr", macros: Macros{"obs": {{Device: "phy", Value: "1"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.macros.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -2,29 +2,77 @@ package sdr
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"math/rand"
 	"sync"
+	"time"
 )
 
+// mockLoopbackDelay is the simulated TX to RX latency in samples.
+const mockLoopbackDelay = 100
+
 // MockSDR synthesizes two-channel IQ data with a controllable phase offset.
+// It also simulates dual-polarized elements (RXDualPol), splitting the tone
+// between the H and V ports by Config.MockPolarizationDeg. Transmitted samples are looped back into both RX channels after
+// mockLoopbackDelay samples, as through a cable.
 type MockSDR struct {
-	mu  sync.RWMutex
-	cfg Config
+	mu       sync.RWMutex
+	cfg      Config
+	txLO     float64
+	raw      map[string]string
+	loopback []complex64 // TX samples not yet received
+	bist     BISTConfig
+	started  time.Time // Init time, for the simulated warm-up
+
+	rngMu sync.Mutex
+	rng   *rand.Rand // noise source; nil uses the global source
 }
 
 func NewMock() *MockSDR { return &MockSDR{} }
 
+// Init stores cfg and places the simulated emitter at the TX LO, RxLO plus
+// the tone offset.
 func (m *MockSDR) Init(_ context.Context, cfg Config) error {
 	m.mu.Lock()
 	m.cfg = cfg
+	m.txLO = cfg.RxLO + cfg.ToneOffset
+	m.started = time.Now()
 	m.mu.Unlock()
 	return nil
 }
 
 func (m *MockSDR) Close() error { return nil }
 
-func (m *MockSDR) TX(_ context.Context, _, _ []complex64) error { return nil }
+// TX queues the sum of iq0 and iq1, as through a combiner, for loopback
+// into the following RX buffers, replacing any burst still in flight.
+func (m *MockSDR) TX(_ context.Context, iq0, iq1 []complex64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loopback = append(make([]complex64, mockLoopbackDelay), iq0...)
+	for i, v := range iq1[:min(len(iq0), len(iq1))] {
+		m.loopback[mockLoopbackDelay+i] += v
+	}
+	return nil
+}
+
+// SetNoiseSource makes the receiver noise come from r instead of the global
+// source, so tests get reproducible samples without reseeding math/rand.
+func (m *MockSDR) SetNoiseSource(r *rand.Rand) {
+	m.rngMu.Lock()
+	m.rng = r
+	m.rngMu.Unlock()
+}
+
+// noise returns one complex receiver noise sample.
+func (m *MockSDR) noise() complex128 {
+	m.rngMu.Lock()
+	defer m.rngMu.Unlock()
+	if m.rng == nil {
+		return complex(rand.NormFloat64()*1e-4, rand.NormFloat64()*1e-4)
+	}
+	return complex(m.rng.NormFloat64()*1e-4, m.rng.NormFloat64()*1e-4)
+}
 
 // SetPhaseDelta updates the simulated phase delta in degrees, allowing
 // real-time angle changes during operation.
@@ -41,30 +89,247 @@ func (m *MockSDR) GetPhaseDelta() float64 {
 	return m.cfg.PhaseDelta
 }
 
-func (m *MockSDR) RX(_ context.Context) ([]complex64, []complex64, error) {
+// Capabilities reports the simulated radio limits. The mock accepts any
+// tuning, loops TX back to RX, and honours SetPhaseDelta.
+func (m *MockSDR) Capabilities() Capabilities {
+	return Capabilities{
+		Backend:         "mock",
+		RXChannels:      2,
+		FullDuplex:      true,
+		MinFrequencyHz:  70e6,
+		MaxFrequencyHz:  6e9,
+		MinSampleRateHz: 1e3,
+		MaxSampleRateHz: 61.44e6,
+		MinRxGainDB:     -10,
+		MaxRxGainDB:     73,
+		MinTxGainDB:     -89,
+		MaxTxGainDB:     0,
+		SimulatedAngle:  true,
+		ClockSources:    []string{"internal", "external"},
+		TimeSources:     []string{"none", "external"},
+		DualPol:         true,
+	}
+}
+
+// ReadAttributes returns the simulated radio state.
+func (m *MockSDR) ReadAttributes(_ context.Context) (HardwareAttributes, error) {
 	m.mu.RLock()
-	cfg := m.cfg
-	m.mu.RUnlock()
+	defer m.mu.RUnlock()
+	txLO := m.txLO
+	return HardwareAttributes{
+		Backend:      "mock",
+		SampleRateHz: m.cfg.SampleRate,
+		RxLOHz:       m.cfg.RxLO,
+		TxLOHz:       txLO,
+		RxGain0DB:    float64(m.cfg.RxGain0),
+		RxGain1DB:    float64(m.cfg.RxGain1),
+		TxGainDB:     float64(m.cfg.TxGain),
+	}, nil
+}
 
-	if cfg.NumSamples == 0 {
-		cfg.NumSamples = 1024
+// mockWarmup is the time constant of the simulated die warm-up from 35 °C
+// to 45 °C.
+const mockWarmup = 10 * time.Minute
+
+// ReadSensors returns a simulated die temperature that warms up after Init.
+// The mock has no RSSI.
+func (m *MockSDR) ReadSensors(_ context.Context) (Sensors, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	warm := 1 - math.Exp(-now.Sub(m.started).Seconds()/mockWarmup.Seconds())
+	return Sensors{Time: now, TemperatureC: 35 + 10*warm}, nil
+}
+
+// WriteAttribute updates the simulated radio state.
+func (m *MockSDR) WriteAttribute(_ context.Context, name string, value float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch name {
+	case AttrSampleRate:
+		m.cfg.SampleRate = value
+	case AttrRxLO:
+		m.cfg.RxLO = value
+	case AttrTxLO:
+		m.txLO = value
+	case AttrRxGain0:
+		m.cfg.RxGain0 = int(value)
+	case AttrRxGain1:
+		m.cfg.RxGain1 = int(value)
+	case AttrTxGain:
+		m.cfg.TxGain = int(value)
+	default:
+		return fmt.Errorf("unknown attribute %q", name)
 	}
-	if cfg.SampleRate == 0 {
-		cfg.SampleRate = 2e6
+	return nil
+}
+
+// ReadRawAttribute returns a simulated raw attribute; unset attributes read
+// as "0".
+func (m *MockSDR) ReadRawAttribute(_ context.Context, device, channel, attr string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if v, ok := m.raw[MacroStep{Device: device, Channel: channel, Attr: attr}.String()]; ok {
+		return v, nil
+	}
+	return "0", nil
+}
+
+// WriteRawAttribute stores a simulated raw attribute.
+func (m *MockSDR) WriteRawAttribute(_ context.Context, device, channel, attr, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.raw == nil {
+		m.raw = make(map[string]string)
+	}
+	m.raw[MacroStep{Device: device, Channel: channel, Attr: attr}.String()] = value
+	return nil
+}
+
+// ExecIIOD answers console commands from the simulated raw attributes.
+func (m *MockSDR) ExecIIOD(ctx context.Context, command string) (string, error) {
+	cmd, err := ParseIIODCommand(command)
+	if err != nil {
+		return "", err
+	}
+	switch cmd.Op {
+	case "VERSION":
+		return "0.0", nil
+	case "PRINT":
+		return `<context name="mock"/>`, nil
+	case "READ":
+		return m.ReadRawAttribute(ctx, cmd.Device, cmd.Channel, cmd.Attr)
+	default:
+		return "", m.WriteRawAttribute(ctx, cmd.Device, cmd.Channel, cmd.Attr, cmd.Value)
 	}
+}
+
+// SetBIST simulates the AD9361 self test: while a generator injects at RX,
+// RX returns its signal instead of the simulated emitter.
+func (m *MockSDR) SetBIST(_ context.Context, cfg BISTConfig) error {
+	if _, err := cfg.debugWrites(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bist = cfg
+	return nil
+}
+
+func (m *MockSDR) RX(_ context.Context) ([]complex64, []complex64, error) {
+	cfg := m.rxConfig()
 	n := cfg.NumSamples
+	m.mu.RLock()
+	bist := m.bist
+	m.mu.RUnlock()
+	if (bist.Mode == BISTTone || bist.Mode == BISTPRBS) && !bist.InjectTX {
+		ch0, ch1 := mockBIST(bist, n, cfg.SampleRate)
+		return ch0, ch1, nil
+	}
 	ch0 := make([]complex64, n)
 	ch1 := make([]complex64, n)
-	phaseStep := 2 * math.Pi * cfg.ToneOffset / cfg.SampleRate
+	offset, amp := m.emitterOffset(cfg)
+	phaseStep := 2 * math.Pi * offset / cfg.SampleRate
 	phaseDelta := cfg.PhaseDelta * math.Pi / 180
 	for i := 0; i < n; i++ {
 		phase := phaseStep * float64(i)
-		val := complex64(complex(math.Cos(phase), math.Sin(phase)))
-		noiseI := rand.NormFloat64() * 1e-4
-		noiseQ := rand.NormFloat64() * 1e-4
-		ch0[i] = val + complex64(complex(noiseI, noiseQ))
+		val := complex64(complex(amp*math.Cos(phase), amp*math.Sin(phase)))
+		noise := complex64(m.noise())
+		ch0[i] = val + noise
 		shifted := phase + phaseDelta
-		ch1[i] = complex64(complex(math.Cos(shifted), math.Sin(shifted))) + complex64(complex(noiseI, noiseQ))
+		ch1[i] = complex64(complex(amp*math.Cos(shifted), amp*math.Sin(shifted))) + noise
 	}
+
+	m.mu.Lock()
+	k := min(n, len(m.loopback))
+	for i, v := range m.loopback[:k] {
+		ch0[i] += v
+		ch1[i] += v
+	}
+	m.loopback = m.loopback[k:]
+	m.mu.Unlock()
 	return ch0, ch1, nil
 }
+
+// RXDualPol synthesizes the four channels of two dual-polarized elements.
+// The tone reaches the H ports with cos and the V ports with sin of the
+// polarization tilt; every port has its own noise. TX loopback is not
+// simulated here.
+func (m *MockSDR) RXDualPol(_ context.Context) (DualPolBuffers, error) {
+	cfg := m.rxConfig()
+	n := cfg.NumSamples
+	tilt := cfg.MockPolarizationDeg * math.Pi / 180
+	offset, amp := m.emitterOffset(cfg)
+	gainH, gainV := amp*math.Cos(tilt), amp*math.Sin(tilt)
+	phaseStep := 2 * math.Pi * offset / cfg.SampleRate
+	phaseDelta := cfg.PhaseDelta * math.Pi / 180
+	out := DualPolBuffers{
+		H0: make([]complex64, n), V0: make([]complex64, n),
+		H1: make([]complex64, n), V1: make([]complex64, n),
+	}
+	noise := m.noise
+	for i := 0; i < n; i++ {
+		phase := phaseStep * float64(i)
+		tone0 := complex(math.Cos(phase), math.Sin(phase))
+		tone1 := complex(math.Cos(phase+phaseDelta), math.Sin(phase+phaseDelta))
+		out.H0[i] = complex64(complex(gainH, 0)*tone0 + noise())
+		out.V0[i] = complex64(complex(gainV, 0)*tone0 + noise())
+		out.H1[i] = complex64(complex(gainH, 0)*tone1 + noise())
+		out.V1[i] = complex64(complex(gainV, 0)*tone1 + noise())
+	}
+	return out, nil
+}
+
+// mockBIST synthesizes the BIST tone or PRBS on both channels, honouring the
+// channel mask.
+func mockBIST(cfg BISTConfig, n int, sampleRate float64) ([]complex64, []complex64) {
+	amp := 0.9 * math.Pow(10, -float64(cfg.LevelDB)/20)
+	step := 2 * math.Pi * BISTToneHz(cfg.ToneHz, sampleRate) / sampleRate
+	out := [2][]complex64{make([]complex64, n), make([]complex64, n)}
+	for i := 0; i < n; i++ {
+		re, im := amp*math.Cos(step*float64(i)), amp*math.Sin(step*float64(i))
+		if cfg.Mode == BISTPRBS {
+			re, im = amp*float64(rand.Intn(2)*2-1), amp*float64(rand.Intn(2)*2-1)
+		}
+		for ch := range out {
+			i0, q0 := re, im
+			mask := cfg.ChannelMask >> (2 * ch)
+			if mask&1 != 0 {
+				i0 = 0
+			}
+			if mask&2 != 0 {
+				q0 = 0
+			}
+			out[ch][i] = complex64(complex(i0, q0))
+		}
+	}
+	return out[0], out[1]
+}
+
+// rxConfig returns the configuration with defaults for an unset buffer size
+// and sample rate.
+// emitterOffset returns the baseband frequency of the simulated emitter, the
+// TX LO minus the RX LO, and its amplitude: 1, or 0 once retuning the RX LO
+// has moved it outside the captured bandwidth.
+func (m *MockSDR) emitterOffset(cfg Config) (offsetHz, amp float64) {
+	m.mu.RLock()
+	offsetHz = m.txLO - cfg.RxLO
+	m.mu.RUnlock()
+	if math.Abs(offsetHz) > cfg.SampleRate/2 {
+		return 0, 0
+	}
+	return offsetHz, 1
+}
+
+func (m *MockSDR) rxConfig() Config {
+	m.mu.RLock()
+	cfg := m.cfg
+	m.mu.RUnlock()
+	if cfg.NumSamples == 0 {
+		cfg.NumSamples = 1024
+	}
+	if cfg.SampleRate == 0 {
+		cfg.SampleRate = 2e6
+	}
+	return cfg
+}
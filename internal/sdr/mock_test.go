@@ -4,6 +4,7 @@ import (
 	"context"
 	"math"
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/rjboer/GoSDR/internal/dsp"
@@ -50,3 +51,62 @@ func TestMockDefaulting(t *testing.T) {
 		t.Fatalf("expected default buffer")
 	}
 }
+
+func TestCapabilitiesClampGain(t *testing.T) {
+	caps := NewMock().Capabilities()
+	tests := []struct {
+		name string
+		in   int
+		rx   int
+		tx   int
+	}{
+		{"rx in range, tx clamped", 10, 10, 0},
+		{"tx in range, rx clamped", -20, -10, -20},
+		{"above max", 100, 73, 0},
+		{"below min", -100, -10, -89},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := caps.ClampRxGain(tc.in); got != tc.rx {
+				t.Fatalf("rx clamp: got %d want %d", got, tc.rx)
+			}
+			if got := caps.ClampTxGain(tc.in); got != tc.tx {
+				t.Fatalf("tx clamp: got %d want %d", got, tc.tx)
+			}
+		})
+	}
+
+	if got := (Capabilities{}).ClampRxGain(500); got != 500 {
+		t.Fatalf("empty capabilities should not clamp, got %d", got)
+	}
+}
+
+func TestCapabilitiesValidateSync(t *testing.T) {
+	tests := []struct {
+		name    string
+		caps    Capabilities
+		cfg     Config
+		wantErr string
+	}{
+		{"defaults", NewPluto().Capabilities(), Config{}, ""},
+		{"pluto external clock", NewPluto().Capabilities(), Config{ClockSource: "external", RefClockHz: 10e6}, ""},
+		{"pluto gpsdo", NewPluto().Capabilities(), Config{ClockSource: "gpsdo"}, `clock source "gpsdo" not supported by pluto backend`},
+		{"pluto pps", NewPluto().Capabilities(), Config{TimeSource: "external"}, "pluto backend cannot select a time source"},
+		{"usrp gpsdo", NewUSRP().Capabilities(), Config{ClockSource: "gpsdo", TimeSource: "gpsdo"}, ""},
+		{"negative reference", NewMock().Capabilities(), Config{ClockSource: "external", RefClockHz: -1}, "must not be negative"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.caps.ValidateSync(tc.cfg)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
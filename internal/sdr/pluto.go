@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,13 +14,9 @@ import (
 	"time"
 
 	"github.com/rjboer/GoSDR/iiod"
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
 )
 
-// EventLogger defines the interface for logging events to the telemetry system.
-type EventLogger interface {
-	LogEvent(level, message string)
-}
-
 // PlutoSDR implements a minimal AD9361/Pluto backend using the IIOD client.
 // It configures sample rate, LO, and gain attributes on initialization and
 // provides dual-channel RX/TX streaming helpers.
@@ -31,16 +29,39 @@ type PlutoSDR struct {
 	rxName     string
 	txID       string
 	txName     string
-	rxBuffer   *iiod.Buffer
-	txBuffer   *iiod.Buffer
+	rxBuffer   sampleBuffer
+	txBuffer   sampleBuffer
 	numSamples int
 
 	// Debug and monitoring
 	eventLogger EventLogger
+	lifecycle   LifecycleObserver
+	transport   TransportObserver
 	rxUnderruns uint64
 	txOverruns  uint64
 	debugMode   bool
 	sshWriter   *SSHAttributeWriter
+	noTX        bool
+	noSSH       bool
+
+	// Session recovery: cfg and the live attribute writes are replayed on a
+	// new connection; gen counts sessions so concurrent RX and TX failures
+	// recover once.
+	cfg       Config
+	writes    []rawWrite
+	gen       uint64
+	closed    bool
+	recoverMu sync.Mutex
+	dial      func(ctx context.Context) error // replaces redialLocked in tests
+}
+
+// sampleBuffer is a streaming IIO buffer of raw interleaved int16 samples.
+// The protocol-independent connectionmgr.Buffer the IIOD client opens
+// implements it, so PlutoSDR does not depend on the wire protocol below.
+type sampleBuffer interface {
+	ReadSamples() ([]byte, error)
+	WriteSamples(data []byte) error
+	Close() error
 }
 
 func NewPluto() *PlutoSDR { return &PlutoSDR{} }
@@ -52,6 +73,30 @@ func (p *PlutoSDR) SetEventLogger(logger EventLogger) {
 	p.eventLogger = logger
 }
 
+// SetLifecycleObserver configures where buffer lifecycle events are sent.
+func (p *PlutoSDR) SetLifecycleObserver(obs LifecycleObserver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lifecycle = obs
+}
+
+// SetTransportObserver configures where the timing of IIOD operations is
+// sent: "iiod.read_attr", "iiod.write_attr", "iiod.read_buffer",
+// "iiod.write_buffer" and "iiod.exec".
+func (p *PlutoSDR) SetTransportObserver(obs TransportObserver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transport = obs
+}
+
+// observe reports an IIOD operation that began at start. Like logEvent it
+// reads the observer without locking; it is set before Init.
+func (p *PlutoSDR) observe(ctx context.Context, op string, start time.Time, err error) {
+	if p.transport != nil {
+		p.transport.ObserveTransport(ctx, op, start, err)
+	}
+}
+
 // SetDebugMode enables or disables debug logging.
 func (p *PlutoSDR) SetDebugMode(enabled bool) {
 	p.mu.Lock()
@@ -77,8 +122,18 @@ type DebugInfo struct {
 	SampleRate  string
 	RxLO        string
 	TxLO        string
+	// CalibMode is the AD9361 calibration mode (auto, manual, ...).
+	CalibMode string
+	// DebugAttrs holds the PHY debug-namespace attributes listed in
+	// plutoDebugAttrs that the driver exposes, keyed by name.
+	DebugAttrs map[string]string
 }
 
+// plutoDebugAttrs are AD9361 debug attributes reported by GetDebugInfo: the
+// loopback and built-in self test (BIST) controls, which only exist in the
+// debug namespace.
+var plutoDebugAttrs = []string{"loopback", "bist_prbs", "bist_tone"}
+
 // GetDebugInfo retrieves hardware debug information from the Pluto SDR.
 // Only works when debug mode is enabled.
 func (p *PlutoSDR) GetDebugInfo() (*DebugInfo, error) {
@@ -132,6 +187,19 @@ func (p *PlutoSDR) GetDebugInfo() (*DebugInfo, error) {
 		info.TxLO = txLO
 	}
 
+	if mode, err := client.ReadAttr(phyName, "", "calib_mode"); err == nil {
+		info.CalibMode = mode
+	}
+
+	// Debug attributes are absent on kernels without debugfs; skip those.
+	info.DebugAttrs = make(map[string]string, len(plutoDebugAttrs))
+	for _, attr := range plutoDebugAttrs {
+		if v, err := client.ReadDebugAttr(phyName, attr); err == nil {
+			info.DebugAttrs[attr] = v
+			p.logEvent("debug", fmt.Sprintf("IIO: debug %s = %s", attr, v))
+		}
+	}
+
 	// Log buffer health
 	if info.RxUnderruns > 0 {
 		p.logEvent("warn", fmt.Sprintf("IIO: RX buffer underruns detected: %d", info.RxUnderruns))
@@ -140,6 +208,262 @@ func (p *PlutoSDR) GetDebugInfo() (*DebugInfo, error) {
 	return info, nil
 }
 
+// plutoAttr maps a HardwareAttributes name onto an AD9361 PHY channel/attribute.
+// The LO channel assignment mirrors the writes performed in Init.
+type plutoAttr struct {
+	channel string
+	attr    string
+}
+
+// plutoRefClockHz is the nominal AD9361 reference, used for an external
+// clock when Config.RefClockHz is zero.
+const plutoRefClockHz = 40e6
+
+var plutoAttrTargets = map[string]plutoAttr{
+	AttrSampleRate: {"", "sampling_frequency"},
+	AttrRxLO:       {"altvoltage1", "frequency"},
+	AttrTxLO:       {"altvoltage0", "frequency"},
+	AttrRxGain0:    {"voltage0", "hardwaregain"},
+	AttrRxGain1:    {"voltage1", "hardwaregain"},
+	AttrTxGain:     {"out", "hardwaregain"},
+}
+
+// ReadAttributes queries the PHY for the live radio state. Individual read
+// failures are collected in Errors rather than failing the whole snapshot.
+func (p *PlutoSDR) ReadAttributes(ctx context.Context) (HardwareAttributes, error) {
+	p.mu.Lock()
+	client := p.client
+	phyName := p.phyName
+	p.mu.Unlock()
+
+	if client == nil {
+		return HardwareAttributes{}, fmt.Errorf("not connected")
+	}
+
+	attrs := HardwareAttributes{Backend: "pluto"}
+	targets := map[string]*float64{
+		AttrSampleRate: &attrs.SampleRateHz,
+		AttrRxLO:       &attrs.RxLOHz,
+		AttrTxLO:       &attrs.TxLOHz,
+		AttrRxGain0:    &attrs.RxGain0DB,
+		AttrRxGain1:    &attrs.RxGain1DB,
+		AttrTxGain:     &attrs.TxGainDB,
+		"rssi0Db":      &attrs.RSSI0DB,
+		"rssi1Db":      &attrs.RSSI1DB,
+		"temperatureC": &attrs.TemperatureC,
+	}
+	sources := maps.Clone(plutoSensorAttrs)
+	maps.Copy(sources, plutoAttrTargets)
+	attrs.Errors = readPhyFloats(ctx, client, phyName, sources, targets)
+	// The kernel reports the die temperature in millidegrees Celsius.
+	attrs.TemperatureC /= 1000
+	return attrs, nil
+}
+
+// plutoSensorAttrs are the AD9361 health sensors, keyed by Sensors JSON name.
+var plutoSensorAttrs = map[string]plutoAttr{
+	"rssi0Db":      {"voltage0", "rssi"},
+	"rssi1Db":      {"voltage1", "rssi"},
+	"temperatureC": {"", "in_temp0_input"},
+}
+
+// ReadSensors reads the die temperature and the RSSI of both RX channels. It
+// does not need debug mode.
+func (p *PlutoSDR) ReadSensors(ctx context.Context) (Sensors, error) {
+	p.mu.Lock()
+	client := p.client
+	phyName := p.phyName
+	p.mu.Unlock()
+
+	if client == nil {
+		return Sensors{}, fmt.Errorf("not connected")
+	}
+	s := Sensors{Time: time.Now()}
+	targets := map[string]*float64{"rssi0Db": &s.RSSI0DB, "rssi1Db": &s.RSSI1DB, "temperatureC": &s.TemperatureC}
+	s.Errors = readPhyFloats(ctx, client, phyName, plutoSensorAttrs, targets)
+	s.TemperatureC /= 1000
+	return s, nil
+}
+
+// readPhyFloats reads the PHY attributes of sources into targets, both keyed
+// by name, and returns the failed reads by name, or nil.
+func readPhyFloats(ctx context.Context, client *iiod.Client, phyName string, sources map[string]plutoAttr, targets map[string]*float64) map[string]string {
+	var errs map[string]string
+	for name, src := range sources {
+		raw, err := client.ReadAttrWithContext(ctx, phyName, src.channel, src.attr)
+		if err == nil {
+			*targets[name], err = parseAttrFloat(raw)
+		}
+		if err != nil {
+			if errs == nil {
+				errs = map[string]string{}
+			}
+			errs[name] = err.Error()
+		}
+	}
+	return errs
+}
+
+// WriteAttribute programs a single PHY attribute, using the SSH sysfs fallback
+// when it was set up during Init and IIOD refuses the write.
+func (p *PlutoSDR) WriteAttribute(ctx context.Context, name string, value float64) error {
+	target, ok := plutoAttrTargets[name]
+	if !ok {
+		return fmt.Errorf("unknown attribute %q", name)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		return fmt.Errorf("not connected")
+	}
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	if name == AttrTxGain && p.noTX {
+		return fmt.Errorf("write %s: %w", name, ErrTXDisabled)
+	}
+	p.logEvent("info", fmt.Sprintf("IIO: Live write %s/%s = %s", target.channel, target.attr, formatted))
+
+	err := p.setAttr(ctx, p.phyName, target.channel, target.attr, formatted)
+	if errors.Is(err, iiod.ErrWriteNotSupported) && p.sshWriter != nil {
+		err = p.sshWriter.WriteAttribute(ctx, p.phyID, target.channel, target.attr, formatted)
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	p.recordWriteLocked(p.phyName, target.channel, target.attr, formatted)
+	return nil
+}
+
+// SetBIST drives the AD9361 BIST tone and PRBS generators through the PHY
+// debug attributes bist_tone and bist_prbs.
+func (p *PlutoSDR) SetBIST(ctx context.Context, cfg BISTConfig) error {
+	writes, err := cfg.debugWrites()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client == nil {
+		return fmt.Errorf("not connected")
+	}
+	for _, w := range writes {
+		if err := p.client.WriteDebugAttrWithContext(ctx, p.phyName, w[0], w[1]); err != nil {
+			return fmt.Errorf("bist: write %s: %w", w[0], err)
+		}
+	}
+	p.logEvent("info", fmt.Sprintf("IIO: BIST %s (%s)", cfg.Mode, writes[1][1]))
+	return nil
+}
+
+// ReadRawAttribute reads an IIO attribute by device name.
+func (p *PlutoSDR) ReadRawAttribute(ctx context.Context, device, channel, attr string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.getAttr(ctx, device, channel, attr)
+}
+
+// WriteRawAttribute writes an IIO attribute by device name, falling back to
+// sysfs over SSH for the known devices when IIOD rejects the write.
+func (p *PlutoSDR) WriteRawAttribute(ctx context.Context, device, channel, attr, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.writeRawLocked(ctx, device, channel, attr, value)
+}
+
+func (p *PlutoSDR) writeRawLocked(ctx context.Context, device, channel, attr, value string) error {
+	err := p.setAttr(ctx, device, channel, attr, value)
+	if errors.Is(err, iiod.ErrWriteNotSupported) && p.sshWriter != nil {
+		ids := map[string]string{p.phyName: p.phyID, p.rxName: p.rxID, p.txName: p.txID}
+		if id := ids[device]; id != "" {
+			err = p.sshWriter.WriteAttribute(ctx, id, channel, attr, value)
+		}
+	}
+	if err == nil {
+		p.recordWriteLocked(device, channel, attr, value)
+	}
+	return err
+}
+
+// ExecIIOD runs a console command (see ParseIIODCommand) on the live IIOD
+// connection. The client addresses channels by name only, so the INPUT or
+// OUTPUT direction is not forwarded.
+func (p *PlutoSDR) ExecIIOD(ctx context.Context, command string) (out string, err error) {
+	defer func(start time.Time) { p.observe(ctx, "iiod.exec", start, err) }(time.Now())
+	cmd, err := ParseIIODCommand(command)
+	if err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client == nil {
+		return "", fmt.Errorf("not connected")
+	}
+	switch {
+	case cmd.Op == "VERSION":
+		v := p.client.ProtocolVersion
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor), nil
+	case cmd.Op == "PRINT":
+		return p.client.GetXMLContextWithContext(ctx)
+	case cmd.Op == "READ" && cmd.Debug:
+		return p.client.ReadDebugAttrWithContext(ctx, cmd.Device, cmd.Attr)
+	case cmd.Op == "READ":
+		return p.getAttr(ctx, cmd.Device, cmd.Channel, cmd.Attr)
+	case cmd.Debug:
+		return "", p.client.WriteDebugAttrWithContext(ctx, cmd.Device, cmd.Attr, cmd.Value)
+	default:
+		return "", p.writeRawLocked(ctx, cmd.Device, cmd.Channel, cmd.Attr, cmd.Value)
+	}
+}
+
+// ContextXML returns the IIOD context XML of the live connection.
+func (p *PlutoSDR) ContextXML(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client == nil {
+		return "", fmt.Errorf("not connected")
+	}
+	return p.client.GetXMLContextWithContext(ctx)
+}
+
+// XOCorrection returns the reference clock frequency (Hz) the AD9361 driver
+// currently assumes.
+func (p *PlutoSDR) XOCorrection(ctx context.Context) (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		return 0, fmt.Errorf("not connected")
+	}
+	raw, err := p.getAttr(ctx, p.phyName, "", "xo_correction")
+	if err != nil {
+		return 0, fmt.Errorf("read xo_correction: %w", err)
+	}
+	return parseAttrFloat(raw)
+}
+
+// SetXOCorrection programs the reference clock frequency (Hz). The driver
+// retunes the synthesizers so LO and sample clock errors are cancelled.
+func (p *PlutoSDR) SetXOCorrection(ctx context.Context, xoHz float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		return fmt.Errorf("not connected")
+	}
+	value := fmt.Sprintf("%.0f", xoHz)
+	p.logEvent("info", fmt.Sprintf("IIO: Setting xo_correction to %s Hz", value))
+	err := p.setAttr(ctx, p.phyName, "", "xo_correction", value)
+	if errors.Is(err, iiod.ErrWriteNotSupported) && p.sshWriter != nil {
+		err = p.sshWriter.WriteAttribute(ctx, p.phyID, "", "xo_correction", value)
+	}
+	if err != nil {
+		return fmt.Errorf("write xo_correction: %w", err)
+	}
+	p.recordWriteLocked(p.phyName, "", "xo_correction", value)
+	return nil
+}
+
 // Init connects to the IIOD server, discovers the AD9361 devices, programs
 // key attributes, and prepares RX/TX buffers for dual-channel streaming.
 func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
@@ -147,18 +471,15 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if cfg.URI == "" {
-		cfg.URI = "192.168.2.1:30431"
-	}
-
-	sshHost := cfg.SSHHost
-	if sshHost == "" {
-		sshHost = extractHostFromURI(cfg.URI)
+	addr, err := plutoAddress(cfg.URI)
+	if err != nil {
+		return err
 	}
-
-	// Add default IIOD port if not specified
-	if !strings.Contains(cfg.URI, ":") {
-		cfg.URI = cfg.URI + ":30431"
+	cfg.URI = addr
+	if cfg.SSHHost == "" && isPlutoUSB(addr) {
+		cfg.SSHHost = plutoUSBHost
+	} else if cfg.SSHHost == "" {
+		cfg.SSHHost, _, _ = net.SplitHostPort(addr)
 	}
 
 	if cfg.NumSamples <= 0 {
@@ -168,6 +489,18 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("sample rate must be positive")
 	}
 
+	p.cfg, p.writes, p.closed = cfg, nil, false
+	if err := p.connectLocked(ctx, cfg); err != nil {
+		return err
+	}
+	p.gen++
+	return nil
+}
+
+// connectLocked dials IIOD, programs the attributes of cfg and creates the
+// buffers. Init and session recovery share it.
+func (p *PlutoSDR) connectLocked(ctx context.Context, cfg Config) error {
+	sshHost := cfg.SSHHost
 	p.logEvent("info", fmt.Sprintf("IIO: Connecting to %s", cfg.URI))
 	fmt.Printf("[PLUTO DEBUG] Attempting to connect to %s...\n", cfg.URI)
 	fmt.Printf("[PLUTO DEBUG] About to call iiod.Dial()...\n")
@@ -183,7 +516,9 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 		defer dialCancel()
 	}
 
-	client, err := iiod.DialWithContext(dialCtx, cfg.URI, nil)
+	client, err := iiod.DialWithOptions(dialCtx, cfg.URI, iiod.DialOptions{
+		XML: connectionmgr.XMLFetchOptions{Cache: cfg.XMLCache, Progress: cfg.XMLProgress},
+	})
 
 	fmt.Printf("[PLUTO DEBUG] iiod.Dial() returned, err=%v\n", err)
 	if err != nil {
@@ -192,9 +527,13 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("connect to IIOD: %w", err)
 	}
 
-	// Hard-lock the client into text mode for legacy Pluto firmware (IIOD v0.25).
-	client.SetProtocolMode(iiod.ProtocolText)
-	p.logEvent("debug", "IIO: Forcing text-only protocol mode for Pluto")
+	// Dial negotiated the protocol: firmware with IIOD v0.25 stays on text,
+	// libiio 1.x firmware switches to binary.
+	mode := "text"
+	if client.ProtocolMode() == iiod.ProtocolBinary {
+		mode = "binary"
+	}
+	p.logEvent("debug", "IIO: Using the "+mode+" protocol")
 
 	p.logEvent("info", "IIO: Connected successfully")
 	fmt.Printf("[PLUTO DEBUG] Connected successfully!\n")
@@ -221,8 +560,12 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("unable to locate AD9361 devices (phy=%q rx=%q tx=%q)", phyName, rxName, txName)
 	}
 
+	p.noTX, p.noSSH = cfg.DisableTX, cfg.DisableSSH
 	iiodWriteSupported := client.SupportsWrite()
-	if !iiodWriteSupported {
+	switch {
+	case !iiodWriteSupported && p.noSSH:
+		p.logEvent("warn", fmt.Sprintf("IIO: Remote IIOD protocol v0.%d does not support attribute writes and the SSH sysfs fallback is disabled", client.ProtocolVersion.Minor))
+	case !iiodWriteSupported:
 		p.logEvent("warn", fmt.Sprintf("IIO: Remote IIOD protocol v0.%d does not support attribute writes; enabling SSH sysfs fallback", client.ProtocolVersion.Minor))
 	}
 
@@ -235,17 +578,16 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 		SysfsRoot: cfg.SysfsRoot,
 	}
 
-	if sshCfg.Password == "" && sshCfg.KeyPath == "" {
+	if !p.noSSH && sshCfg.Password == "" && sshCfg.KeyPath == "" {
 		p.logEvent("warn", fmt.Sprintf("IIO: SSH fallback configured for %s:%d but no password or key provided", sshCfg.Host, sshCfg.Port))
 	}
 
+	// finishWrite reports the IIOD result of a write and retries a write the
+	// server does not support over the SSH sysfs fallback.
 	var warnedFallback bool
-	writeAttr := func(action, deviceName, deviceID, channel, attr, value string) error {
-		target := fmt.Sprintf("%s/%s/%s", deviceName, channel, attr)
-		p.logEvent("debug", fmt.Sprintf("IIO: %s via IIOD text mode -> %s = %s", action, target, value))
-		fmt.Printf("[PLUTO DEBUG] writeAttr %s -> %s (value=%s) using IIOD text\n", action, target, value)
-
-		if err := client.WriteAttrCompatWithContext(ctx, deviceName, channel, attr, value); err != nil {
+	finishWrite := func(w attrWrite, err error) error {
+		action, target := w.action, fmt.Sprintf("%s/%s/%s", w.device, w.channel, w.attr)
+		if err != nil {
 			if errors.Is(err, iiod.ErrWriteNotSupported) {
 				credsPresent := sshCfg.Password != "" || sshCfg.KeyPath != ""
 				p.logEvent("debug", fmt.Sprintf("IIO: IIOD write unsupported for %s; SSH fallback host=%s user=%s password_set=%t key_set=%t", target, sshCfg.Host, sshCfg.User, sshCfg.Password != "", sshCfg.KeyPath != ""))
@@ -261,7 +603,7 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 					p.logEvent("warn", fmt.Sprintf("IIO: %s unsupported via IIOD; using SSH sysfs fallback to %s", action, sshHost))
 					warnedFallback = true
 				}
-				if sshErr := writer.WriteAttribute(ctx, deviceID, channel, attr, value); sshErr != nil {
+				if sshErr := writer.WriteAttribute(ctx, w.devID, w.channel, w.attr, w.value); sshErr != nil {
 					p.logEvent("error", fmt.Sprintf("IIO: SSH sysfs %s failed: %v", action, sshErr))
 					fmt.Printf("[PLUTO DEBUG] SSH write failed for %s: %v\n", target, sshErr)
 					return fmt.Errorf("%s via ssh: %w", action, sshErr)
@@ -281,51 +623,34 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 		return nil
 	}
 
-	p.logEvent("info", fmt.Sprintf("IIO: Found AD9361 devices - PHY: %s, RX: %s, TX: %s", phyName, rxName, txName))
-	fmt.Printf("[PLUTO DEBUG] Found AD9361: PHY=%s, RX=%s, TX=%s\n", phyName, rxName, txName)
-
-	// Program sample rate and LOs.
-	p.logEvent("debug", fmt.Sprintf("IIO: Setting sample rate to %.0f Hz", cfg.SampleRate))
-	if err := writeAttr("set sample rate", phyName, phyID, "", "sampling_frequency", fmt.Sprintf("%.0f", cfg.SampleRate)); err != nil {
-		_ = client.Close()
-		return err
-	}
-
-	if cfg.RxLO > 0 {
-		p.logEvent("debug", fmt.Sprintf("IIO: Setting RX LO to %.0f Hz", cfg.RxLO))
-		if err := writeAttr("set RX LO", phyName, phyID, "altvoltage1", "frequency", fmt.Sprintf("%.0f", cfg.RxLO)); err != nil {
-			_ = client.Close()
-			return err
+	// writeStage pipelines the writes of a stage on the IIOD connection.
+	writeStage := func(stage []attrWrite) []error {
+		ops := make([]iiod.AttrOperation, len(stage))
+		for i, w := range stage {
+			p.logEvent("debug", fmt.Sprintf("IIO: %s via IIOD -> %s/%s/%s = %s", w.action, w.device, w.channel, w.attr, w.value))
+			ops[i] = iiod.AttrOperation{Device: w.device, Channel: w.channel, Attr: w.attr, Value: w.value, IsWrite: true}
 		}
-
-		p.logEvent("debug", fmt.Sprintf("IIO: Setting TX LO to %.0f Hz", cfg.RxLO))
-		if err := writeAttr("set TX LO", phyName, phyID, "altvoltage0", "frequency", fmt.Sprintf("%.0f", cfg.RxLO)); err != nil {
-			_ = client.Close()
-			return err
+		errs := client.WriteAttrsWithContext(ctx, ops)
+		for i, w := range stage {
+			errs[i] = finishWrite(w, errs[i])
 		}
+		return errs
 	}
 
-	// Configure RX gains.
-	p.logEvent("debug", "IIO: Configuring RX gains")
-	if err := writeAttr("set rx0 gain mode", phyName, phyID, "voltage0", "gain_control_mode", "manual"); err != nil {
-		_ = client.Close()
-		return err
-	}
-	if err := writeAttr("set rx1 gain mode", phyName, phyID, "voltage1", "gain_control_mode", "manual"); err != nil {
-		_ = client.Close()
-		return err
-	}
-	if err := writeAttr("set rx0 gain", phyName, phyID, "voltage0", "hardwaregain", fmt.Sprintf("%d", cfg.RxGain0)); err != nil {
-		_ = client.Close()
-		return err
-	}
-	if err := writeAttr("set rx1 gain", phyName, phyID, "voltage1", "hardwaregain", fmt.Sprintf("%d", cfg.RxGain1)); err != nil {
+	p.logEvent("info", fmt.Sprintf("IIO: Found AD9361 devices - PHY: %s, RX: %s, TX: %s", phyName, rxName, txName))
+	fmt.Printf("[PLUTO DEBUG] Found AD9361: PHY=%s, RX=%s, TX=%s\n", phyName, rxName, txName)
+
+	// Program the clock, LOs and gains, the independent writes of a stage
+	// in one round trip.
+	err = programStages(initStages(cfg, phyName, phyID, p.noTX), writeStage, func(w attrWrite, err error) {
+		p.logEvent("warn", fmt.Sprintf("IIO: %s not applied: %v", w.action, err))
+	})
+	if err != nil {
 		_ = client.Close()
-		return err
+		return fmt.Errorf("program attributes: %w", err)
 	}
-	if err := writeAttr("set tx gain", phyName, phyID, "out", "hardwaregain", fmt.Sprintf("%d", cfg.TxGain)); err != nil {
-		// Some firmware exposes TX gain per-channel; fall back without failing hard.
-		p.logEvent("warn", fmt.Sprintf("IIO: TX gain not applied: %v", err))
+	if p.noTX {
+		p.logEvent("info", "IIO: Transmit disabled; TX gain and buffer left unconfigured")
 	}
 
 	p.logEvent("info", fmt.Sprintf("IIO: Creating RX buffer (%d samples)", cfg.NumSamples))
@@ -336,13 +661,16 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("create RX buffer: %w", err)
 	}
 
-	p.logEvent("info", fmt.Sprintf("IIO: Creating TX buffer (%d samples)", cfg.NumSamples))
-	txBuf, err := client.CreateStreamBuffer(ctx, txName, cfg.NumSamples, 0x3)
-	if err != nil {
-		_ = rxBuf.Close()
-		_ = client.Close()
-		p.logEvent("error", fmt.Sprintf("IIO: Failed to create TX buffer: %v", err))
-		return fmt.Errorf("create TX buffer: %w", err)
+	var txBuf *connectionmgr.Buffer
+	if !p.noTX {
+		p.logEvent("info", fmt.Sprintf("IIO: Creating TX buffer (%d samples)", cfg.NumSamples))
+		txBuf, err = client.CreateStreamBuffer(ctx, txName, cfg.NumSamples, 0x3)
+		if err != nil {
+			_ = rxBuf.Close()
+			_ = client.Close()
+			p.logEvent("error", fmt.Sprintf("IIO: Failed to create TX buffer: %v", err))
+			return fmt.Errorf("create TX buffer: %w", err)
+		}
 	}
 
 	p.client = client
@@ -353,9 +681,20 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 	p.txID = txID
 	p.txName = txName
 	p.rxBuffer = rxBuf
-	p.txBuffer = txBuf
+	p.txBuffer = nil
+	if txBuf != nil {
+		p.txBuffer = txBuf
+	}
 	p.numSamples = cfg.NumSamples
 
+	if p.lifecycle != nil {
+		p.lifecycle.ObserveLifecycle(LifecycleEvent{
+			Kind:    LifecycleBufferCreated,
+			Backend: "pluto",
+			Time:    time.Now(),
+			Detail:  fmt.Sprintf("rx/tx %d samples", cfg.NumSamples),
+		})
+	}
 	p.logEvent("info", "IIO: Pluto SDR initialized successfully")
 
 	return nil
@@ -363,20 +702,30 @@ func (p *PlutoSDR) Init(ctx context.Context, cfg Config) error {
 
 // RX reads a buffer from the SDR and returns deinterleaved complex64 slices for
 // channels 0 and 1.
-func (p *PlutoSDR) RX(_ context.Context) ([]complex64, []complex64, error) {
-	p.mu.Lock()
-	buf := p.rxBuffer
-	p.mu.Unlock()
-
-	if buf == nil {
-		return nil, nil, fmt.Errorf("RX buffer not initialized")
-	}
-
-	data, err := buf.ReadSamples()
-	if err != nil {
-		atomic.AddUint64(&p.rxUnderruns, 1)
-		p.logEvent("warn", fmt.Sprintf("IIO: RX buffer read failed: %v", err))
-		return nil, nil, fmt.Errorf("read RX buffer: %w", err)
+// With Config.Reconnect enabled a failed read recovers the session and
+// reads again instead of returning the error.
+func (p *PlutoSDR) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	var data []byte
+	for {
+		p.mu.Lock()
+		buf, gen := p.rxBuffer, p.gen
+		p.mu.Unlock()
+
+		err := errors.New("RX buffer not initialized")
+		if buf != nil {
+			start := time.Now()
+			data, err = buf.ReadSamples()
+			p.observe(ctx, "iiod.read_buffer", start, err)
+			if err == nil {
+				break
+			}
+			atomic.AddUint64(&p.rxUnderruns, 1)
+			p.logEvent("warn", fmt.Sprintf("IIO: RX buffer read failed: %v", err))
+			err = fmt.Errorf("read RX buffer: %w", err)
+		}
+		if rerr := p.recoverSession(ctx, gen, err); rerr != nil {
+			return nil, nil, rerr
+		}
 	}
 
 	samples, err := iiod.ParseInt16Samples(data)
@@ -397,13 +746,15 @@ func (p *PlutoSDR) RX(_ context.Context) ([]complex64, []complex64, error) {
 }
 
 // TX writes interleaved complex samples for both channels to the SDR.
-func (p *PlutoSDR) TX(_ context.Context, iq0, iq1 []complex64) error {
+// With Config.Reconnect enabled a failed write recovers the session and
+// writes again.
+func (p *PlutoSDR) TX(ctx context.Context, iq0, iq1 []complex64) error {
 	p.mu.Lock()
-	buf := p.txBuffer
+	noTX := p.noTX
 	p.mu.Unlock()
 
-	if buf == nil {
-		return fmt.Errorf("TX buffer not initialized")
+	if noTX {
+		return ErrTXDisabled
 	}
 	if len(iq0) != len(iq1) {
 		return fmt.Errorf("TX channel lengths differ: %d vs %d", len(iq0), len(iq1))
@@ -417,13 +768,27 @@ func (p *PlutoSDR) TX(_ context.Context, iq0, iq1 []complex64) error {
 	}
 
 	data := iiod.FormatInt16Samples(interleaved)
-	if err := buf.WriteSamples(data); err != nil {
-		atomic.AddUint64(&p.txOverruns, 1)
-		p.logEvent("warn", fmt.Sprintf("IIO: TX buffer write failed: %v", err))
-		return fmt.Errorf("write TX buffer: %w", err)
+	for {
+		p.mu.Lock()
+		buf, gen := p.txBuffer, p.gen
+		p.mu.Unlock()
+
+		err := errors.New("TX buffer not initialized")
+		if buf != nil {
+			start := time.Now()
+			err = buf.WriteSamples(data)
+			p.observe(ctx, "iiod.write_buffer", start, err)
+			if err == nil {
+				return nil
+			}
+			atomic.AddUint64(&p.txOverruns, 1)
+			p.logEvent("warn", fmt.Sprintf("IIO: TX buffer write failed: %v", err))
+			err = fmt.Errorf("write TX buffer: %w", err)
+		}
+		if rerr := p.recoverSession(ctx, gen, err); rerr != nil {
+			return rerr
+		}
 	}
-
-	return nil
 }
 
 // Close releases buffers and the underlying IIOD connection.
@@ -432,6 +797,7 @@ func (p *PlutoSDR) Close() error {
 	defer p.mu.Unlock()
 
 	p.logEvent("info", "IIO: Closing Pluto SDR")
+	p.closed = true
 
 	var firstErr error
 	if p.rxBuffer != nil {
@@ -468,10 +834,39 @@ func (p *PlutoSDR) SetPhaseDelta(phaseDeltaDeg float64) {}
 // GetPhaseDelta returns 0 for hardware backends.
 func (p *PlutoSDR) GetPhaseDelta() float64 { return 0 }
 
+// Capabilities reports the AD9361/AD9363 limits exposed by the Pluto (with the
+// dual-channel, extended-range firmware configuration used by this project).
+func (p *PlutoSDR) Capabilities() Capabilities {
+	return Capabilities{
+		Backend:         "pluto",
+		RXChannels:      2,
+		TXChannels:      2,
+		FullDuplex:      true,
+		MinFrequencyHz:  70e6,
+		MaxFrequencyHz:  6e9,
+		MinSampleRateHz: 521e3,
+		MaxSampleRateHz: 61.44e6,
+		MinRxGainDB:     -3,
+		MaxRxGainDB:     71,
+		MinTxGainDB:     -89,
+		MaxTxGainDB:     0,
+		SupportsTX:      true,
+		// 12-bit ADC codes, sign-extended to int16 and scaled by 1/32768.
+		FullScale: 2048.0 / 32768.0,
+		// "external" needs the external clock hardware mod (or a Rev C/D
+		// with refclk_source set in the firmware environment); Init then
+		// tells the driver the reference frequency.
+		ClockSources: []string{"internal", "external"},
+	}
+}
+
 func (p *PlutoSDR) ensureSSHFallbackLocked(cfg SSHConfig) (*SSHAttributeWriter, error) {
 	if p.sshWriter != nil {
 		return p.sshWriter, nil
 	}
+	if p.noSSH {
+		return nil, ErrSSHDisabled
+	}
 
 	writer, err := NewSSHAttributeWriter(cfg)
 	if err != nil {
@@ -481,20 +876,6 @@ func (p *PlutoSDR) ensureSSHFallbackLocked(cfg SSHConfig) (*SSHAttributeWriter,
 	return p.sshWriter, nil
 }
 
-func extractHostFromURI(uri string) string {
-	parts := strings.Split(uri, ":")
-	if len(parts) == 0 {
-		return ""
-	}
-	last := parts[len(parts)-1]
-	if len(parts) >= 2 {
-		if _, err := strconv.Atoi(last); err == nil {
-			return parts[len(parts)-2]
-		}
-	}
-	return last
-}
-
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
@@ -565,14 +946,20 @@ func (p *PlutoSDR) getAttr(ctx context.Context, dev, channel, attr string) (stri
 	if p.client == nil {
 		return "", fmt.Errorf("client not initialized")
 	}
-	return p.client.ReadAttrWithContext(ctx, dev, channel, attr)
+	start := time.Now()
+	value, err := p.client.ReadAttrWithContext(ctx, dev, channel, attr)
+	p.observe(ctx, "iiod.read_attr", start, err)
+	return value, err
 }
 
 func (p *PlutoSDR) setAttr(ctx context.Context, dev, channel, attr, value string) error {
 	if p.client == nil {
 		return fmt.Errorf("client not initialized")
 	}
-	return p.client.WriteAttrCompatWithContext(ctx, dev, channel, attr, value)
+	start := time.Now()
+	err := p.client.WriteAttrCompatWithContext(ctx, dev, channel, attr, value)
+	p.observe(ctx, "iiod.write_attr", start, err)
+	return err
 }
 
 //
@@ -12,8 +12,12 @@ import (
 	"testing"
 
 	"github.com/rjboer/GoSDR/iiod"
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
 )
 
+// The buffer the IIOD client opens backs a PlutoSDR.
+var _ sampleBuffer = (*connectionmgr.Buffer)(nil)
+
 type plutoMockOp struct {
 	cmd           string
 	status        int
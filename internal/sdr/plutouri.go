@@ -0,0 +1,49 @@
+package sdr
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// plutoDefaultURI is the IIOD address of a Pluto on its USB network
+// interface (RNDIS/CDC-ECM), which it brings up when plugged in.
+const plutoDefaultURI = "192.168.2.1:30431"
+
+// plutoUSBHost is the Pluto's address on its USB network interface, used for
+// SSH when IIOD is reached over a usb: URI.
+const plutoUSBHost = "192.168.2.1"
+
+// PlutoAddress resolves a Pluto URI (-sdr-uri) to its IIOD TCP address, for
+// tools that talk to IIOD directly over TCP.
+func PlutoAddress(uri string) (string, error) {
+	if isPlutoUSB(uri) {
+		return "", fmt.Errorf("pluto: %q is a USB URI: %w (use the USB network interface, ip:%s)", uri, ErrBackendUnavailable, plutoUSBHost)
+	}
+	return plutoAddress(uri)
+}
+
+// plutoAddress resolves a Pluto URI to the address iiod.Dial takes. It
+// accepts the libiio form "ip:host[:port]" and a bare "host[:port]"; the port
+// defaults to 30431. libiio "usb:[bus.address[.interface]]" URIs are passed
+// through for the iiod USB transport.
+func plutoAddress(uri string) (string, error) {
+	if uri == "" {
+		return plutoDefaultURI, nil
+	}
+	if isPlutoUSB(uri) {
+		return uri, nil
+	}
+	host := strings.TrimPrefix(uri, "ip:")
+	if host == "" {
+		return plutoDefaultURI, nil
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host, nil
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), "30431"), nil
+}
+
+func isPlutoUSB(uri string) bool {
+	return strings.HasPrefix(uri, "usb:")
+}
@@ -0,0 +1,38 @@
+package sdr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlutoAddress(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr error
+	}{
+		{uri: "", want: "192.168.2.1:30431"},
+		{uri: "192.168.2.1", want: "192.168.2.1:30431"},
+		{uri: "pluto.local:1234", want: "pluto.local:1234"},
+		{uri: "ip:192.168.3.1", want: "192.168.3.1:30431"},
+		{uri: "ip:192.168.3.1:30432", want: "192.168.3.1:30432"},
+		{uri: "ip:", want: "192.168.2.1:30431"},
+		{uri: "ip:fe80::1", want: "[fe80::1]:30431"},
+		{uri: "usb:1.2.5", want: "usb:1.2.5"},
+		{uri: "usb:", want: "usb:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			got, err := plutoAddress(tt.uri)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("plutoAddress(%q) = %q, %v; want %q, %v", tt.uri, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlutoAddressRejectsUSBForTCPTools(t *testing.T) {
+	if _, err := PlutoAddress("usb:1.2.5"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("PlutoAddress(usb) error = %v, want ErrBackendUnavailable", err)
+	}
+}
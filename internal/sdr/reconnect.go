@@ -0,0 +1,173 @@
+package sdr
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errBackendClosed stops session recovery once Close was called.
+var errBackendClosed = errors.New("backend closed")
+
+// ReconnectPolicy configures how a network backend recovers a dropped
+// connection. The zero value disables recovery: a failed buffer transfer is
+// returned to the caller.
+type ReconnectPolicy struct {
+	Enabled bool
+	// InitialDelay is the wait before the first attempt, doubled after each
+	// failed attempt up to MaxDelay. The defaults are 500 ms and 30 s.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// MaxAttempts gives up after that many failed attempts; zero keeps
+	// trying until the caller's context ends.
+	MaxAttempts int
+}
+
+// backoff returns the wait before attempt n, counted from 1.
+func (r ReconnectPolicy) backoff(n int) time.Duration {
+	d := cmp.Or(r.InitialDelay, 500*time.Millisecond)
+	limit := cmp.Or(r.MaxDelay, 30*time.Second)
+	for i := 1; i < n && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// rawWrite is a live attribute write that a new session replays.
+type rawWrite struct {
+	device, channel, attr, value string
+}
+
+// recordWriteLocked remembers a successful live attribute write. A later
+// write of the same attribute replaces it and moves it to the end, so the
+// replay keeps the order in which the values were last set.
+func (p *PlutoSDR) recordWriteLocked(device, channel, attr, value string) {
+	for i, w := range p.writes {
+		if w.device == device && w.channel == channel && w.attr == attr {
+			p.writes = append(p.writes[:i], p.writes[i+1:]...)
+			break
+		}
+	}
+	p.writes = append(p.writes, rawWrite{device: device, channel: channel, attr: attr, value: value})
+}
+
+// recoverSession handles a buffer transfer of session gen that failed with
+// cause. With reconnection enabled it drops the session and re-dials with
+// exponential backoff, re-applying the configuration, the live attribute
+// writes and the buffers, and returns nil once a new session is up; a
+// concurrent caller that already recovered gen counts as well. Otherwise it
+// returns cause, or the error that ended recovery.
+func (p *PlutoSDR) recoverSession(ctx context.Context, gen uint64, cause error) error {
+	p.mu.Lock()
+	policy := p.cfg.Reconnect
+	p.mu.Unlock()
+	if !policy.Enabled || gen == 0 || ctx.Err() != nil {
+		return cause
+	}
+
+	p.recoverMu.Lock()
+	defer p.recoverMu.Unlock()
+	p.mu.Lock()
+	if p.gen != gen {
+		p.mu.Unlock()
+		return nil
+	}
+	if p.closed {
+		p.mu.Unlock()
+		return cause
+	}
+	p.dropSessionLocked()
+	p.mu.Unlock()
+	p.emitLifecycle(LifecycleEvent{Kind: LifecycleDisconnected, Err: cause.Error()})
+	p.logEvent("warn", fmt.Sprintf("IIO: Connection lost, reconnecting: %v", cause))
+
+	var err error
+	for n := 1; policy.MaxAttempts == 0 || n <= policy.MaxAttempts; n++ {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("reconnect: %w (after %v)", ctx.Err(), cause)
+		case <-time.After(policy.backoff(n)):
+		}
+		ev := LifecycleEvent{Kind: LifecycleReconnecting, Detail: fmt.Sprintf("attempt %d", n)}
+		if err != nil {
+			ev.Err = err.Error()
+		}
+		p.emitLifecycle(ev)
+		if err = p.redial(ctx); err == nil {
+			p.emitLifecycle(LifecycleEvent{Kind: LifecycleConnected, Detail: fmt.Sprintf("session restored after %d attempts", n)})
+			p.logEvent("info", fmt.Sprintf("IIO: Session restored after %d attempts", n))
+			return nil
+		}
+		if errors.Is(err, errBackendClosed) {
+			return cause
+		}
+	}
+	p.emitLifecycle(LifecycleEvent{Kind: LifecycleClosed, Detail: "reconnect gave up", Err: err.Error()})
+	return fmt.Errorf("reconnect gave up after %d attempts: %w", policy.MaxAttempts, err)
+}
+
+// redial opens a new session and counts it.
+func (p *PlutoSDR) redial(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errBackendClosed
+	}
+	var err error
+	if p.dial != nil {
+		err = p.dial(ctx)
+	} else {
+		err = p.redialLocked(ctx)
+	}
+	if err != nil {
+		p.dropSessionLocked()
+		return err
+	}
+	p.gen++
+	return nil
+}
+
+// redialLocked connects with the configuration of Init and replays the live
+// attribute writes. A write the new session refuses is logged, not retried.
+func (p *PlutoSDR) redialLocked(ctx context.Context) error {
+	if err := p.connectLocked(ctx, p.cfg); err != nil {
+		return err
+	}
+	for _, w := range append([]rawWrite(nil), p.writes...) {
+		if err := p.writeRawLocked(ctx, w.device, w.channel, w.attr, w.value); err != nil {
+			p.logEvent("warn", fmt.Sprintf("IIO: Re-applying %s/%s/%s = %s failed: %v", w.device, w.channel, w.attr, w.value, err))
+		}
+	}
+	return nil
+}
+
+// dropSessionLocked releases the buffers and connection of a broken session,
+// ignoring errors from the dead link.
+func (p *PlutoSDR) dropSessionLocked() {
+	if p.rxBuffer != nil {
+		_ = p.rxBuffer.Close()
+		p.rxBuffer = nil
+	}
+	if p.txBuffer != nil {
+		_ = p.txBuffer.Close()
+		p.txBuffer = nil
+	}
+	if p.client != nil {
+		_ = p.client.Close()
+		p.client = nil
+	}
+}
+
+// emitLifecycle sends ev to the lifecycle observer, if any.
+func (p *PlutoSDR) emitLifecycle(ev LifecycleEvent) {
+	p.mu.Lock()
+	obs := p.lifecycle
+	p.mu.Unlock()
+	if obs == nil {
+		return
+	}
+	ev.Backend, ev.Time = "pluto", time.Now()
+	obs.ObserveLifecycle(ev)
+}
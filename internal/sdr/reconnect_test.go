@@ -0,0 +1,141 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBuffer is a sampleBuffer returning fixed data or a fixed error.
+type fakeBuffer struct {
+	data []byte
+	err  error
+}
+
+func (b *fakeBuffer) ReadSamples() ([]byte, error)   { return b.data, b.err }
+func (b *fakeBuffer) WriteSamples(data []byte) error { return b.err }
+func (b *fakeBuffer) Close() error                   { return nil }
+
+func TestReconnectPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ReconnectPolicy
+		n      int
+		want   time.Duration
+	}{
+		{"default first", ReconnectPolicy{}, 1, 500 * time.Millisecond},
+		{"default doubles", ReconnectPolicy{}, 3, 2 * time.Second},
+		{"default cap", ReconnectPolicy{}, 20, 30 * time.Second},
+		{"custom", ReconnectPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second}, 3, 4 * time.Second},
+		{"custom cap", ReconnectPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second}, 4, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.n); got != tt.want {
+				t.Fatalf("backoff(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlutoRXRecoversSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		attempts  int // MaxAttempts
+		failDials int
+		wantErr   string
+		wantKinds []LifecycleKind
+	}{
+		{
+			name: "recovers", enabled: true, failDials: 2,
+			wantKinds: []LifecycleKind{LifecycleDisconnected, LifecycleReconnecting, LifecycleReconnecting, LifecycleReconnecting, LifecycleConnected},
+		},
+		{
+			name: "gives up", enabled: true, attempts: 2, failDials: 5,
+			wantErr:   "reconnect gave up after 2 attempts",
+			wantKinds: []LifecycleKind{LifecycleDisconnected, LifecycleReconnecting, LifecycleReconnecting, LifecycleClosed},
+		},
+		{
+			name: "disabled", failDials: 0,
+			wantErr: "read RX buffer: link down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &lifecycleRecorder{}
+			p := NewPluto()
+			p.cfg.Reconnect = ReconnectPolicy{Enabled: tt.enabled, InitialDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond, MaxAttempts: tt.attempts}
+			p.gen = 1
+			p.rxBuffer = &fakeBuffer{err: errors.New("link down")}
+			p.lifecycle = rec
+			dials := 0
+			p.dial = func(context.Context) error {
+				dials++
+				if dials <= tt.failDials {
+					return errors.New("connection refused")
+				}
+				// One sample on each of the two channels: I0 Q0 I1 Q1.
+				p.rxBuffer = &fakeBuffer{data: []byte{1, 0, 2, 0, 3, 0, 4, 0}}
+				return nil
+			}
+
+			rx0, rx1, err := p.RX(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RX error = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("RX: %v", err)
+				}
+				if len(rx0) != 1 || len(rx1) != 1 || rx0[0] != iqToComplex([]int16{1}, []int16{2})[0] || rx1[0] != iqToComplex([]int16{3}, []int16{4})[0] {
+					t.Fatalf("RX samples = %v %v", rx0, rx1)
+				}
+				if p.gen != 2 {
+					t.Fatalf("gen = %d, want 2", p.gen)
+				}
+			}
+			if len(rec.events) != len(tt.wantKinds) {
+				t.Fatalf("events = %+v, want kinds %v", rec.events, tt.wantKinds)
+			}
+			for i, ev := range rec.events {
+				if ev.Kind != tt.wantKinds[i] || ev.Backend != "pluto" {
+					t.Fatalf("event %d = %+v, want kind %s", i, ev, tt.wantKinds[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlutoRecoverSessionClosed(t *testing.T) {
+	p := NewPluto()
+	p.cfg.Reconnect = ReconnectPolicy{Enabled: true, InitialDelay: time.Millisecond}
+	p.gen = 1
+	p.closed = true
+	cause := errors.New("read RX buffer: link down")
+	if err := p.recoverSession(context.Background(), 1, cause); err != cause {
+		t.Fatalf("recoverSession after Close = %v, want the cause", err)
+	}
+}
+
+func TestRecordWriteReplaysInLastSetOrder(t *testing.T) {
+	p := NewPluto()
+	p.recordWriteLocked("ad9361-phy", "altvoltage0", "frequency", "2400000000")
+	p.recordWriteLocked("ad9361-phy", "voltage0", "hardwaregain", "10")
+	p.recordWriteLocked("ad9361-phy", "altvoltage0", "frequency", "2450000000")
+	want := []rawWrite{
+		{"ad9361-phy", "voltage0", "hardwaregain", "10"},
+		{"ad9361-phy", "altvoltage0", "frequency", "2450000000"},
+	}
+	if len(p.writes) != len(want) {
+		t.Fatalf("writes = %+v, want %+v", p.writes, want)
+	}
+	for i := range want {
+		if p.writes[i] != want[i] {
+			t.Fatalf("writes = %+v, want %+v", p.writes, want)
+		}
+	}
+}
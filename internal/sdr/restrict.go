@@ -0,0 +1,56 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTXDisabled is returned by TX on a backend restricted to receive only.
+var ErrTXDisabled = errors.New("transmit disabled")
+
+// ErrSSHDisabled is returned where a backend would open the SSH sysfs
+// fallback although it was disabled.
+var ErrSSHDisabled = errors.New("SSH sysfs fallback disabled")
+
+// Restricted wraps a backend for deployments that must not transmit or open
+// SSH sessions to the radio. The restrictions are passed on in Config at
+// Init, so backends that honour them (Pluto) never create a TX buffer or SSH
+// client; TX is refused here for every backend.
+type Restricted struct {
+	SDR
+
+	noTX  bool
+	noSSH bool
+}
+
+// NewRestricted wraps backend, disabling transmit and/or the SSH fallback.
+func NewRestricted(backend SDR, noTX, noSSH bool) *Restricted {
+	return &Restricted{SDR: backend, noTX: noTX, noSSH: noSSH}
+}
+
+// Unwrap returns the wrapped backend.
+func (r *Restricted) Unwrap() SDR { return r.SDR }
+
+// Init initializes the wrapped backend with the restrictions applied.
+func (r *Restricted) Init(ctx context.Context, cfg Config) error {
+	cfg.DisableTX = cfg.DisableTX || r.noTX
+	cfg.DisableSSH = cfg.DisableSSH || r.noSSH
+	return r.SDR.Init(ctx, cfg)
+}
+
+// TX returns ErrTXDisabled when transmit is disabled.
+func (r *Restricted) TX(ctx context.Context, iq0, iq1 []complex64) error {
+	if r.noTX {
+		return ErrTXDisabled
+	}
+	return r.SDR.TX(ctx, iq0, iq1)
+}
+
+// Capabilities reports no TX channels when transmit is disabled.
+func (r *Restricted) Capabilities() Capabilities {
+	caps := r.SDR.Capabilities()
+	if r.noTX {
+		caps.TXChannels = 0
+	}
+	return caps
+}
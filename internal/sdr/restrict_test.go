@@ -0,0 +1,59 @@
+package sdr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// initRecorder remembers the config it was initialized with and reports
+// two TX channels.
+type initRecorder struct {
+	SDR
+	cfg Config
+}
+
+func (r *initRecorder) Capabilities() Capabilities { return Capabilities{TXChannels: 2} }
+
+func (r *initRecorder) Init(_ context.Context, cfg Config) error {
+	r.cfg = cfg
+	return nil
+}
+
+func TestRestricted(t *testing.T) {
+	tests := []struct {
+		name         string
+		noTX, noSSH  bool
+		wantTXErr    error
+		wantChannels int
+	}{
+		{"unrestricted", false, false, nil, 2},
+		{"receive only", true, false, ErrTXDisabled, 0},
+		{"no ssh", false, true, nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &initRecorder{SDR: NewMock()}
+			r := NewRestricted(inner, tt.noTX, tt.noSSH)
+			if err := r.Init(context.Background(), Config{SampleRate: 2e6}); err != nil {
+				t.Fatal(err)
+			}
+			if inner.cfg.DisableTX != tt.noTX || inner.cfg.DisableSSH != tt.noSSH {
+				t.Fatalf("inner config DisableTX=%t DisableSSH=%t", inner.cfg.DisableTX, inner.cfg.DisableSSH)
+			}
+			if err := r.TX(context.Background(), make([]complex64, 4), make([]complex64, 4)); !errors.Is(err, tt.wantTXErr) {
+				t.Fatalf("TX error = %v, want %v", err, tt.wantTXErr)
+			}
+			if got := r.Capabilities().TXChannels; got != tt.wantChannels {
+				t.Fatalf("TXChannels = %d, want %d", got, tt.wantChannels)
+			}
+		})
+	}
+}
+
+func TestPlutoSSHFallbackDisabled(t *testing.T) {
+	p := &PlutoSDR{noSSH: true}
+	if _, err := p.ensureSSHFallbackLocked(SSHConfig{Host: "pluto.local", Password: "analog"}); !errors.Is(err, ErrSSHDisabled) {
+		t.Fatalf("err = %v, want ErrSSHDisabled", err)
+	}
+}
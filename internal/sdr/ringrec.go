@@ -0,0 +1,98 @@
+package sdr
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RingRecorder wraps a backend and appends every RX buffer pair to an IQ
+// ring, so recent IQ can be cut out after the fact with an IQRingReader.
+// The ring is created at Init, when the buffer size and clipping level are
+// known; a failure to write it does not fail RX.
+type RingRecorder struct {
+	SDR
+
+	mu     sync.Mutex
+	path   string
+	size   int64
+	ring   *IQRing
+	cfg    Config
+	err    error
+	events EventLogger
+}
+
+// NewRingRecorder wraps backend, recording into a ring of about size bytes at
+// path.
+func NewRingRecorder(backend SDR, path string, size int64) *RingRecorder {
+	return &RingRecorder{SDR: backend, path: path, size: size}
+}
+
+// Unwrap returns the wrapped backend.
+func (r *RingRecorder) Unwrap() SDR { return r.SDR }
+
+// Path returns the ring file path.
+func (r *RingRecorder) Path() string { return r.path }
+
+// SetEventLogger configures where ring write failures are reported.
+func (r *RingRecorder) SetEventLogger(logger EventLogger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = logger
+}
+
+// Init initializes the wrapped backend and opens the ring with one block per
+// RX buffer.
+func (r *RingRecorder) Init(ctx context.Context, cfg Config) error {
+	if err := r.SDR.Init(ctx, cfg); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ring != nil {
+		r.ring.Close()
+		r.ring = nil
+	}
+	ring, err := CreateIQRing(r.path, r.size, cfg.NumSamples, r.SDR.Capabilities().FullScale)
+	if err != nil {
+		return err
+	}
+	r.ring, r.cfg, r.err = ring, cfg, nil
+	return nil
+}
+
+// RX reads from the wrapped backend and records the buffers. The capture
+// time is estimated as the arrival time less the buffer duration.
+func (r *RingRecorder) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	rx0, rx1, err := r.SDR.RX(ctx)
+	if err != nil {
+		return rx0, rx1, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ring == nil || r.cfg.SampleRate <= 0 {
+		return rx0, rx1, nil
+	}
+	start := time.Now().Add(-time.Duration(float64(len(rx0)) / r.cfg.SampleRate * float64(time.Second)))
+	werr := r.ring.Append(start, r.cfg.RxLO, r.cfg.SampleRate, rx0, rx1)
+	if werr != nil && r.err == nil && r.events != nil {
+		r.events.LogEvent("warn", "IQ ring recording failed: "+werr.Error())
+	}
+	r.err = werr
+	return rx0, rx1, nil
+}
+
+// Close closes the ring and the wrapped backend.
+func (r *RingRecorder) Close() error {
+	r.mu.Lock()
+	var ringErr error
+	if r.ring != nil {
+		ringErr = r.ring.Close()
+		r.ring = nil
+	}
+	r.mu.Unlock()
+	if err := r.SDR.Close(); err != nil {
+		return err
+	}
+	return ringErr
+}
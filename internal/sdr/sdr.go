@@ -2,8 +2,20 @@ package sdr
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/connectionmgr"
 )
 
+// ErrBackendUnavailable is returned by backends that were compiled out of the
+// current binary (for example cgo backends built without their build tag).
+var ErrBackendUnavailable = errors.New("sdr backend not available in this build")
+
 // Config carries parameters required to initialize an SDR backend.
 type Config struct {
 	SampleRate  float64
@@ -21,6 +33,103 @@ type Config struct {
 	SSHKeyPath  string
 	SSHPort     int
 	SysfsRoot   string
+	// LOSource selects the LO source on backends that can share an LO between
+	// channels or boards (for example USRP "internal", "external", "companion").
+	LOSource string
+	// LOExport exports the channel 0 LO to the remaining channels when supported.
+	LOExport bool
+	// ClockSource selects the reference clock ("internal", "external",
+	// "gpsdo", ...) and TimeSource the PPS/time source on backends that list
+	// them in Capabilities. Empty leaves the hardware default.
+	ClockSource string
+	TimeSource  string
+	// RefClockHz is the frequency of an external reference; zero selects the
+	// backend's nominal reference.
+	RefClockHz float64
+	// DisableTX leaves the transmitter unconfigured and DisableSSH forbids
+	// the SSH sysfs fallback (see Restricted).
+	DisableTX  bool
+	DisableSSH bool
+	// MockPolarizationDeg is the polarization tilt of the simulated emitter
+	// seen by the mock's dual-polarized elements: 0 is horizontal, 90
+	// vertical.
+	MockPolarizationDeg float64
+	// Reconnect lets network backends (Pluto) recover a dropped connection
+	// transparently instead of failing RX and TX.
+	Reconnect ReconnectPolicy
+	// XMLCache, if set, keeps the IIOD context XML between connections so a
+	// known device is not asked for it again; XMLProgress is called as the
+	// document arrives.
+	XMLCache    *connectionmgr.XMLCache
+	XMLProgress func(connectionmgr.XMLProgress)
+}
+
+// Capabilities describes what a backend supports so callers (tracker, web UI)
+// can hide or clamp features the hardware cannot provide.
+type Capabilities struct {
+	Backend            string  `json:"backend"`
+	RXChannels         int     `json:"rxChannels"`
+	TXChannels         int     `json:"txChannels"`
+	FullDuplex         bool    `json:"fullDuplex"`
+	MinFrequencyHz     float64 `json:"minFrequencyHz"`
+	MaxFrequencyHz     float64 `json:"maxFrequencyHz"`
+	MinSampleRateHz    float64 `json:"minSampleRateHz"`
+	MaxSampleRateHz    float64 `json:"maxSampleRateHz"`
+	MinRxGainDB        float64 `json:"minRxGainDb"`
+	MaxRxGainDB        float64 `json:"maxRxGainDb"`
+	MinTxGainDB        float64 `json:"minTxGainDb"`
+	MaxTxGainDB        float64 `json:"maxTxGainDb"`
+	SupportsTX         bool    `json:"supportsTx"`
+	SupportsTimestamps bool    `json:"supportsTimestamps"`
+	// SimulatedAngle reports whether SetPhaseDelta changes the received signal.
+	SimulatedAngle bool `json:"simulatedAngle"`
+	// FullScale is the largest I or Q magnitude RX can return, i.e. the ADC
+	// clipping level in sample units. Zero means unknown.
+	FullScale float64 `json:"fullScale,omitempty"`
+	// ClockSources and TimeSources list the accepted Config.ClockSource and
+	// Config.TimeSource values; empty means the backend cannot select them.
+	ClockSources []string `json:"clockSources,omitempty"`
+	TimeSources  []string `json:"timeSources,omitempty"`
+	// DualPol reports dual-polarized elements, read with DualPolRX.
+	DualPol bool `json:"dualPol,omitempty"`
+}
+
+// ValidateSync checks the clock and time source of cfg against the sources
+// the backend supports.
+func (c Capabilities) ValidateSync(cfg Config) error {
+	for _, s := range []struct {
+		name, value string
+		allowed     []string
+	}{{"clock source", cfg.ClockSource, c.ClockSources}, {"time source", cfg.TimeSource, c.TimeSources}} {
+		if s.value == "" || slices.Contains(s.allowed, s.value) {
+			continue
+		}
+		if len(s.allowed) == 0 {
+			return fmt.Errorf("%s backend cannot select a %s", c.Backend, s.name)
+		}
+		return fmt.Errorf("%s %q not supported by %s backend (want %s)", s.name, s.value, c.Backend, strings.Join(s.allowed, "|"))
+	}
+	if cfg.RefClockHz < 0 {
+		return fmt.Errorf("reference clock frequency must not be negative")
+	}
+	return nil
+}
+
+// ClampRxGain limits an RX gain to the advertised range. A zero-width range
+// means the backend did not report limits and the value is returned unchanged.
+func (c Capabilities) ClampRxGain(gain int) int {
+	if c.MaxRxGainDB <= c.MinRxGainDB {
+		return gain
+	}
+	return int(math.Max(c.MinRxGainDB, math.Min(c.MaxRxGainDB, float64(gain))))
+}
+
+// ClampTxGain limits a TX gain to the advertised range.
+func (c Capabilities) ClampTxGain(gain int) int {
+	if c.MaxTxGainDB <= c.MinTxGainDB {
+		return gain
+	}
+	return int(math.Max(c.MinTxGainDB, math.Min(c.MaxTxGainDB, float64(gain))))
 }
 
 // SDR captures the minimal radio operations required by the tracker.
@@ -34,4 +143,21 @@ type SDR interface {
 	SetPhaseDelta(phaseDeltaDeg float64)
 	// GetPhaseDelta returns the current phase delta setting.
 	GetPhaseDelta() float64
+	// Capabilities reports static backend limits and feature support.
+	Capabilities() Capabilities
+}
+
+// EventLogger receives operator-facing events from backends and wrappers.
+// Levels are "debug", "info", "warn" and "error". The telemetry hub
+// implements it and keeps a filtered ring of recent events.
+type EventLogger interface {
+	LogEvent(level, message string)
+}
+
+// TransportObserver receives the timing of every operation a backend
+// performs on its radio transport, such as an IIOD attribute read or buffer
+// refill. ctx is the caller's context, so tracing observers can attach the
+// operation to the caller's span. otlp.Exporter implements it.
+type TransportObserver interface {
+	ObserveTransport(ctx context.Context, op string, start time.Time, err error)
 }
@@ -0,0 +1,64 @@
+package sdr
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultSensorInterval is the period of SampleSensors when none is given.
+const DefaultSensorInterval = 10 * time.Second
+
+// Sensors is one reading of a radio's health sensors. Zero values mean the
+// backend has no such sensor; failed reads are listed in Errors.
+type Sensors struct {
+	Time         time.Time `json:"time"`
+	TemperatureC float64   `json:"temperatureC,omitempty"`
+	RSSI0DB      float64   `json:"rssi0Db,omitempty"`
+	RSSI1DB      float64   `json:"rssi1Db,omitempty"`
+	// Errors lists sensors that could not be read, keyed by JSON name.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// SensorReader is implemented by backends with health sensors. Reading them
+// must be cheap and safe while the tracker streams.
+type SensorReader interface {
+	ReadSensors(ctx context.Context) (Sensors, error)
+}
+
+// SensorObserver receives sensor readings.
+type SensorObserver interface {
+	ObserveSensors(s Sensors)
+}
+
+// SensorObserverFunc adapts a function to a SensorObserver.
+type SensorObserverFunc func(s Sensors)
+
+// ObserveSensors calls f(s).
+func (f SensorObserverFunc) ObserveSensors(s Sensors) { f(s) }
+
+// SampleSensors reads the sensors of backend every interval (default
+// DefaultSensorInterval) and passes each reading to obs until ctx ends,
+// independently of the tracking loop. It returns at once when the backend
+// has no sensors. A failed reading, such as while the backend reconnects, is
+// skipped.
+func SampleSensors(ctx context.Context, backend SDR, interval time.Duration, obs SensorObserver) {
+	reader, ok := As[SensorReader](backend)
+	if !ok {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultSensorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if s, err := reader.ReadSensors(ctx); err == nil {
+			obs.ObserveSensors(s)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package sdr
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sensorRecorder collects the readings of SampleSensors.
+type sensorRecorder struct {
+	mu       sync.Mutex
+	readings []Sensors
+}
+
+func (r *sensorRecorder) ObserveSensors(s Sensors) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readings = append(r.readings, s)
+}
+
+func (r *sensorRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.readings)
+}
+
+func TestSampleSensors(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend SDR
+		want    bool // readings expected
+	}{
+		{name: "mock", backend: NewMock(), want: true},
+		{name: "wrapped mock", backend: NewLifecycleMonitor(NewMock(), "mock"), want: true},
+		{name: "no sensors", backend: NewFile(""), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.backend.Init(context.Background(), Config{SampleRate: 1e6}); err != nil && tt.want {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			rec := &sensorRecorder{}
+			SampleSensors(ctx, tt.backend, 5*time.Millisecond, rec)
+			if got := rec.count(); (got >= 3) != tt.want {
+				t.Fatalf("%d readings, want readings %t", got, tt.want)
+			}
+			if tt.want {
+				if temp := rec.readings[0].TemperatureC; temp < 35 || temp > 36 {
+					t.Fatalf("mock temperature right after Init = %.2f °C, want about 35", temp)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+//go:build soapy
+
+package sdr
+
+/*
+#cgo LDFLAGS: -lSoapySDR
+#include <stdlib.h>
+#include <SoapySDR/Device.h>
+#include <SoapySDR/Errors.h>
+#include <SoapySDR/Formats.h>
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// soapyTimeoutUs bounds a single readStream/writeStream call in microseconds.
+const soapyTimeoutUs = 1000000
+
+// SoapySDR implements a dual-channel coherent backend for any device with a
+// SoapySDR driver and two RX channels (LimeSDR, USRP B210, bladeRF 2.0, ...)
+// through the SoapySDR C API. The URI is passed verbatim as the Soapy device
+// argument string (for example "driver=lime" or "driver=uhd,type=b200").
+type SoapySDR struct {
+	mu         sync.Mutex
+	dev        *C.SoapySDRDevice
+	rxStream   *C.SoapySDRStream
+	txStream   *C.SoapySDRStream
+	txChannels int
+	rxBuf      [2]unsafe.Pointer
+	numSamples int
+	phaseDelta float64
+	streaming  bool
+}
+
+func NewSoapy() *SoapySDR { return &SoapySDR{} }
+
+// Init opens the device, configures both RX channels and the transmitter
+// when the device has one, and starts continuous streaming.
+func (s *SoapySDR) Init(_ context.Context, cfg Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cfg.NumSamples <= 0 {
+		cfg.NumSamples = 1024
+	}
+	s.numSamples = cfg.NumSamples
+	s.phaseDelta = cfg.PhaseDelta
+
+	args := C.CString(cfg.URI)
+	defer C.free(unsafe.Pointer(args))
+	s.dev = C.SoapySDRDevice_makeStrArgs(args)
+	if s.dev == nil {
+		return fmt.Errorf("soapy make device %q: %s", cfg.URI, C.GoString(C.SoapySDRDevice_lastError()))
+	}
+	if n := int(C.SoapySDRDevice_getNumChannels(s.dev, C.SOAPY_SDR_RX)); n < 2 {
+		s.closeLocked()
+		return fmt.Errorf("soapy: device has %d RX channel(s), monopulse needs 2 coherent channels", n)
+	}
+
+	if err := s.configureSync(cfg); err != nil {
+		s.closeLocked()
+		return err
+	}
+	if err := s.configureRX(cfg); err != nil {
+		s.closeLocked()
+		return err
+	}
+	if err := s.configureTX(cfg); err != nil {
+		s.closeLocked()
+		return err
+	}
+	if err := s.startStreaming(); err != nil {
+		s.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// configureSync selects the reference clock and time source. It runs before
+// tuning so the synthesizers lock to the chosen reference.
+func (s *SoapySDR) configureSync(cfg Config) error {
+	if cfg.ClockSource != "" {
+		src := C.CString(cfg.ClockSource)
+		defer C.free(unsafe.Pointer(src))
+		if err := soapyCheck("set clock source", C.SoapySDRDevice_setClockSource(s.dev, src)); err != nil {
+			return err
+		}
+	}
+	if cfg.TimeSource != "" {
+		src := C.CString(cfg.TimeSource)
+		defer C.free(unsafe.Pointer(src))
+		if err := soapyCheck("set time source", C.SoapySDRDevice_setTimeSource(s.dev, src)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureRX applies rate, manual gain and frequency to RX channels 0 and 1
+// and sets up a CF32 stream over both.
+func (s *SoapySDR) configureRX(cfg Config) error {
+	gains := [2]int{cfg.RxGain0, cfg.RxGain1}
+	for ch := C.size_t(0); ch < 2; ch++ {
+		if err := soapyCheck("set rx rate", C.SoapySDRDevice_setSampleRate(s.dev, C.SOAPY_SDR_RX, ch, C.double(cfg.SampleRate))); err != nil {
+			return err
+		}
+		// Drivers without AGC reject the call; manual gain is the default there.
+		C.SoapySDRDevice_setGainMode(s.dev, C.SOAPY_SDR_RX, ch, C.bool(false))
+		if err := soapyCheck("set rx gain", C.SoapySDRDevice_setGain(s.dev, C.SOAPY_SDR_RX, ch, C.double(gains[ch]))); err != nil {
+			return err
+		}
+		if err := soapyCheck("set rx freq", C.SoapySDRDevice_setFrequency(s.dev, C.SOAPY_SDR_RX, ch, C.double(cfg.RxLO), nil)); err != nil {
+			return err
+		}
+	}
+
+	stream, err := s.setupStream(C.SOAPY_SDR_RX, 2)
+	if err != nil {
+		return err
+	}
+	s.rxStream = stream
+
+	bytes := C.size_t(s.numSamples) * C.size_t(unsafe.Sizeof(complex64(0)))
+	s.rxBuf[0] = C.malloc(bytes)
+	s.rxBuf[1] = C.malloc(bytes)
+	return nil
+}
+
+// configureTX applies rate, gain and frequency to the TX channels and sets up
+// a stream over up to two of them. Receive-only devices are left without a
+// TX stream.
+func (s *SoapySDR) configureTX(cfg Config) error {
+	s.txChannels = min(int(C.SoapySDRDevice_getNumChannels(s.dev, C.SOAPY_SDR_TX)), 2)
+	if s.txChannels == 0 || cfg.DisableTX {
+		s.txChannels = 0
+		return nil
+	}
+	for ch := C.size_t(0); ch < C.size_t(s.txChannels); ch++ {
+		if err := soapyCheck("set tx rate", C.SoapySDRDevice_setSampleRate(s.dev, C.SOAPY_SDR_TX, ch, C.double(cfg.SampleRate))); err != nil {
+			return err
+		}
+		if err := soapyCheck("set tx gain", C.SoapySDRDevice_setGain(s.dev, C.SOAPY_SDR_TX, ch, C.double(cfg.TxGain))); err != nil {
+			return err
+		}
+		if err := soapyCheck("set tx freq", C.SoapySDRDevice_setFrequency(s.dev, C.SOAPY_SDR_TX, ch, C.double(cfg.RxLO+cfg.ToneOffset), nil)); err != nil {
+			return err
+		}
+	}
+	stream, err := s.setupStream(C.SOAPY_SDR_TX, s.txChannels)
+	if err != nil {
+		return err
+	}
+	s.txStream = stream
+	return nil
+}
+
+// setupStream creates a CF32 stream (layout-compatible with complex64) over
+// channels 0..n-1.
+func (s *SoapySDR) setupStream(direction C.int, n int) (*C.SoapySDRStream, error) {
+	format := C.CString("CF32") // SOAPY_SDR_CF32
+	defer C.free(unsafe.Pointer(format))
+
+	channels := (*[2]C.size_t)(C.malloc(C.size_t(unsafe.Sizeof(C.size_t(0))) * 2))
+	defer C.free(unsafe.Pointer(channels))
+	channels[0], channels[1] = 0, 1
+
+	stream := C.SoapySDRDevice_setupStream(s.dev, direction, format, &channels[0], C.size_t(n), nil)
+	if stream == nil {
+		return nil, fmt.Errorf("soapy setup stream: %s", C.GoString(C.SoapySDRDevice_lastError()))
+	}
+	return stream, nil
+}
+
+func (s *SoapySDR) startStreaming() error {
+	if err := soapyCheck("activate rx stream", C.SoapySDRDevice_activateStream(s.dev, s.rxStream, 0, 0, 0)); err != nil {
+		return err
+	}
+	s.streaming = true
+	if s.txStream != nil {
+		return soapyCheck("activate tx stream", C.SoapySDRDevice_activateStream(s.dev, s.txStream, 0, 0, 0))
+	}
+	return nil
+}
+
+// RX receives one buffer per channel. Samples are already scaled to [-1, 1].
+func (s *SoapySDR) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.streaming {
+		return nil, nil, errors.New("soapy not initialized")
+	}
+
+	n := s.numSamples
+	buffs := (*[2]unsafe.Pointer)(C.malloc(C.size_t(unsafe.Sizeof(unsafe.Pointer(nil))) * 2))
+	defer C.free(unsafe.Pointer(buffs))
+	for total := 0; total < n; {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		offset := uintptr(total) * unsafe.Sizeof(complex64(0))
+		buffs[0] = unsafe.Add(s.rxBuf[0], offset)
+		buffs[1] = unsafe.Add(s.rxBuf[1], offset)
+		var flags C.int
+		var timeNs C.longlong
+		got := C.SoapySDRDevice_readStream(s.dev, s.rxStream, &buffs[0], C.size_t(n-total), &flags, &timeNs, C.long(soapyTimeoutUs))
+		if got == C.SOAPY_SDR_OVERFLOW {
+			// Overflows drop samples but the stream continues.
+			continue
+		}
+		if got < 0 {
+			return nil, nil, fmt.Errorf("soapy read stream: %s", C.GoString(C.SoapySDR_errToStr(got)))
+		}
+		total += int(got)
+	}
+
+	ch0 := make([]complex64, n)
+	ch1 := make([]complex64, n)
+	copy(ch0, unsafe.Slice((*complex64)(s.rxBuf[0]), n))
+	copy(ch1, unsafe.Slice((*complex64)(s.rxBuf[1]), n))
+	return ch0, ch1, nil
+}
+
+// TX sends one buffer per channel. Both slices must have equal length; iq1
+// is dropped on devices with a single TX channel.
+func (s *SoapySDR) TX(_ context.Context, iq0, iq1 []complex64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dev == nil {
+		return errors.New("soapy not initialized")
+	}
+	if s.txStream == nil {
+		return errors.New("soapy: device has no TX stream")
+	}
+	if len(iq0) != len(iq1) {
+		return fmt.Errorf("tx length mismatch: %d vs %d", len(iq0), len(iq1))
+	}
+	if len(iq0) == 0 {
+		return nil
+	}
+
+	bytes := C.size_t(len(iq0)) * C.size_t(unsafe.Sizeof(complex64(0)))
+	buffs := (*[2]unsafe.Pointer)(C.malloc(C.size_t(unsafe.Sizeof(unsafe.Pointer(nil))) * 2))
+	defer C.free(unsafe.Pointer(buffs))
+	src := [2][]complex64{iq0, iq1}
+	var base [2]unsafe.Pointer
+	for ch := 0; ch < s.txChannels; ch++ {
+		base[ch] = C.malloc(bytes)
+		defer C.free(base[ch])
+		copy(unsafe.Slice((*complex64)(base[ch]), len(src[ch])), src[ch])
+	}
+
+	for sent := 0; sent < len(iq0); {
+		offset := uintptr(sent) * unsafe.Sizeof(complex64(0))
+		for ch := 0; ch < s.txChannels; ch++ {
+			buffs[ch] = unsafe.Add(base[ch], offset)
+		}
+		var flags C.int
+		got := C.SoapySDRDevice_writeStream(s.dev, s.txStream, &buffs[0], C.size_t(len(iq0)-sent), &flags, 0, C.long(soapyTimeoutUs))
+		if got < 0 {
+			return fmt.Errorf("soapy write stream: %s", C.GoString(C.SoapySDR_errToStr(got)))
+		}
+		sent += int(got)
+	}
+	return nil
+}
+
+// ReadAttributes reads the tuned rate, frequencies and gains back from the
+// driver.
+func (s *SoapySDR) ReadAttributes(_ context.Context) (HardwareAttributes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dev == nil {
+		return HardwareAttributes{}, errors.New("soapy not initialized")
+	}
+	attrs := HardwareAttributes{
+		Backend:      "soapy",
+		SampleRateHz: float64(C.SoapySDRDevice_getSampleRate(s.dev, C.SOAPY_SDR_RX, 0)),
+		RxLOHz:       float64(C.SoapySDRDevice_getFrequency(s.dev, C.SOAPY_SDR_RX, 0)),
+		RxGain0DB:    float64(C.SoapySDRDevice_getGain(s.dev, C.SOAPY_SDR_RX, 0)),
+		RxGain1DB:    float64(C.SoapySDRDevice_getGain(s.dev, C.SOAPY_SDR_RX, 1)),
+	}
+	if s.txChannels > 0 {
+		attrs.TxLOHz = float64(C.SoapySDRDevice_getFrequency(s.dev, C.SOAPY_SDR_TX, 0))
+		attrs.TxGainDB = float64(C.SoapySDRDevice_getGain(s.dev, C.SOAPY_SDR_TX, 0))
+	}
+	return attrs, nil
+}
+
+// WriteAttribute applies a single live setting through the driver. Rates
+// and RX frequencies are applied to both channels to keep them coherent.
+func (s *SoapySDR) WriteAttribute(_ context.Context, name string, value float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dev == nil {
+		return errors.New("soapy not initialized")
+	}
+	v := C.double(value)
+	switch name {
+	case AttrSampleRate:
+		if err := soapyCheck("set rx rate", C.SoapySDRDevice_setSampleRate(s.dev, C.SOAPY_SDR_RX, 0, v)); err != nil {
+			return err
+		}
+		return soapyCheck("set rx rate", C.SoapySDRDevice_setSampleRate(s.dev, C.SOAPY_SDR_RX, 1, v))
+	case AttrRxLO:
+		if err := soapyCheck("set rx freq", C.SoapySDRDevice_setFrequency(s.dev, C.SOAPY_SDR_RX, 0, v, nil)); err != nil {
+			return err
+		}
+		return soapyCheck("set rx freq", C.SoapySDRDevice_setFrequency(s.dev, C.SOAPY_SDR_RX, 1, v, nil))
+	case AttrTxLO:
+		if s.txChannels == 0 {
+			return errors.New("soapy: device has no TX stream")
+		}
+		return soapyCheck("set tx freq", C.SoapySDRDevice_setFrequency(s.dev, C.SOAPY_SDR_TX, 0, v, nil))
+	case AttrRxGain0:
+		return soapyCheck("set rx gain", C.SoapySDRDevice_setGain(s.dev, C.SOAPY_SDR_RX, 0, v))
+	case AttrRxGain1:
+		return soapyCheck("set rx gain", C.SoapySDRDevice_setGain(s.dev, C.SOAPY_SDR_RX, 1, v))
+	case AttrTxGain:
+		if s.txChannels == 0 {
+			return errors.New("soapy: device has no TX stream")
+		}
+		return soapyCheck("set tx gain", C.SoapySDRDevice_setGain(s.dev, C.SOAPY_SDR_TX, 0, v))
+	default:
+		return fmt.Errorf("unknown attribute %q", name)
+	}
+}
+
+// SetPhaseDelta stores the requested phase delta; the hardware backend does
+// not synthesize signals, so the value is informational only.
+func (s *SoapySDR) SetPhaseDelta(phaseDeltaDeg float64) {
+	s.mu.Lock()
+	s.phaseDelta = phaseDeltaDeg
+	s.mu.Unlock()
+}
+
+// GetPhaseDelta returns the stored phase delta setting.
+func (s *SoapySDR) GetPhaseDelta() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.phaseDelta
+}
+
+// Close stops streaming and releases the streams and the device.
+func (s *SoapySDR) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+	return nil
+}
+
+func (s *SoapySDR) closeLocked() {
+	if s.dev == nil {
+		return
+	}
+	for _, stream := range []*C.SoapySDRStream{s.rxStream, s.txStream} {
+		if stream == nil {
+			continue
+		}
+		if s.streaming {
+			C.SoapySDRDevice_deactivateStream(s.dev, stream, 0, 0)
+		}
+		C.SoapySDRDevice_closeStream(s.dev, stream)
+	}
+	s.rxStream, s.txStream, s.streaming = nil, nil, false
+	for i := range s.rxBuf {
+		if s.rxBuf[i] != nil {
+			C.free(s.rxBuf[i])
+			s.rxBuf[i] = nil
+		}
+	}
+	C.SoapySDRDevice_unmake(s.dev)
+	s.dev = nil
+}
+
+// soapyCheck converts a SoapySDR return code into a Go error including the
+// driver's last error string.
+func soapyCheck(op string, code C.int) error {
+	if code == 0 {
+		return nil
+	}
+	return fmt.Errorf("soapy %s: %s (%s)", op, C.GoString(C.SoapySDR_errToStr(code)), C.GoString(C.SoapySDRDevice_lastError()))
+}
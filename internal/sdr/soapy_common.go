@@ -0,0 +1,26 @@
+package sdr
+
+// Capabilities reports limits wide enough for the two-channel devices
+// SoapySDR drives (LimeSDR, B210, bladeRF 2.0). Each driver clamps
+// out-of-range requests to what its hardware supports.
+func (s *SoapySDR) Capabilities() Capabilities {
+	return Capabilities{
+		Backend:            "soapy",
+		RXChannels:         2,
+		TXChannels:         2,
+		FullDuplex:         true,
+		MinFrequencyHz:     1e6,
+		MaxFrequencyHz:     6e9,
+		MinSampleRateHz:    100e3,
+		MaxSampleRateHz:    61.44e6,
+		MinRxGainDB:        0,
+		MaxRxGainDB:        76,
+		MinTxGainDB:        0,
+		MaxTxGainDB:        89,
+		SupportsTX:         true,
+		SupportsTimestamps: true,
+		FullScale:          1.0, // CF32 host samples
+		ClockSources:       []string{"internal", "external", "gpsdo"},
+		TimeSources:        []string{"none", "internal", "external", "gpsdo"},
+	}
+}
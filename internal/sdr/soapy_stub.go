@@ -0,0 +1,53 @@
+//go:build !soapy
+
+package sdr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SoapySDR is a placeholder used when the binary is built without the
+// "soapy" build tag. Rebuild with `go build -tags soapy` (libSoapySDR 0.8
+// required) to enable SoapySDR devices.
+type SoapySDR struct {
+	mu         sync.Mutex
+	phaseDelta float64
+}
+
+func NewSoapy() *SoapySDR { return &SoapySDR{} }
+
+func (s *SoapySDR) Init(_ context.Context, _ Config) error {
+	return fmt.Errorf("soapy: %w (rebuild with -tags soapy)", ErrBackendUnavailable)
+}
+
+func (s *SoapySDR) RX(_ context.Context) ([]complex64, []complex64, error) {
+	return nil, nil, fmt.Errorf("soapy: %w", ErrBackendUnavailable)
+}
+
+func (s *SoapySDR) TX(_ context.Context, _, _ []complex64) error {
+	return fmt.Errorf("soapy: %w", ErrBackendUnavailable)
+}
+
+func (s *SoapySDR) Close() error { return nil }
+
+func (s *SoapySDR) ReadAttributes(_ context.Context) (HardwareAttributes, error) {
+	return HardwareAttributes{}, fmt.Errorf("soapy: %w", ErrBackendUnavailable)
+}
+
+func (s *SoapySDR) WriteAttribute(_ context.Context, _ string, _ float64) error {
+	return fmt.Errorf("soapy: %w", ErrBackendUnavailable)
+}
+
+func (s *SoapySDR) SetPhaseDelta(phaseDeltaDeg float64) {
+	s.mu.Lock()
+	s.phaseDelta = phaseDeltaDeg
+	s.mu.Unlock()
+}
+
+func (s *SoapySDR) GetPhaseDelta() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.phaseDelta
+}
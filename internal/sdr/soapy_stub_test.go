@@ -0,0 +1,26 @@
+//go:build !soapy
+
+package sdr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSoapyStubReportsUnavailable(t *testing.T) {
+	soapy := NewSoapy()
+	if err := soapy.Init(context.Background(), Config{URI: "driver=lime"}); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("expected ErrBackendUnavailable, got %v", err)
+	}
+	if _, _, err := soapy.RX(context.Background()); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("expected ErrBackendUnavailable from RX, got %v", err)
+	}
+	if caps := soapy.Capabilities(); caps.Backend != "soapy" || caps.RXChannels != 2 {
+		t.Fatalf("capabilities = %+v", caps)
+	}
+	soapy.SetPhaseDelta(12)
+	if soapy.GetPhaseDelta() != 12 {
+		t.Fatalf("phase delta not stored")
+	}
+}
@@ -0,0 +1,502 @@
+//go:build uhd
+
+package sdr
+
+/*
+#cgo LDFLAGS: -luhd
+#include <stdlib.h>
+#include <uhd.h>
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// usrpRecvTimeout is the UHD receive timeout in seconds for a single RX call.
+const usrpRecvTimeout = 1.0
+
+// USRPSDR implements a dual-channel coherent backend for Ettus USRP devices
+// (B210 and friends) through the UHD C API. The URI is passed verbatim as the
+// UHD device argument string (for example "type=b200" or "serial=3141592").
+//
+// Both RX channels run in one streamer. The device time is reset during Init,
+// both channels are retuned with one timed command, and streaming starts at a
+// future device time, so the channels start on the same sample with a
+// repeatable phase relation.
+type USRPSDR struct {
+	mu         sync.Mutex
+	usrp       C.uhd_usrp_handle
+	rxStreamer C.uhd_rx_streamer_handle
+	txStreamer C.uhd_tx_streamer_handle
+	rxMeta     C.uhd_rx_metadata_handle
+	txMeta     C.uhd_tx_metadata_handle
+	rxBuf      [2]unsafe.Pointer
+	numSamples int
+	phaseDelta float64
+	open       bool
+	streaming  bool
+}
+
+func NewUSRP() *USRPSDR { return &USRPSDR{} }
+
+// Init opens the USRP, configures both RX channels with a shared LO and starts
+// continuous streaming.
+func (u *USRPSDR) Init(_ context.Context, cfg Config) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if cfg.NumSamples <= 0 {
+		cfg.NumSamples = 1024
+	}
+	u.numSamples = cfg.NumSamples
+	u.phaseDelta = cfg.PhaseDelta
+
+	args := C.CString(cfg.URI)
+	defer C.free(unsafe.Pointer(args))
+	if err := uhdCheck("make usrp", C.uhd_usrp_make(&u.usrp, args)); err != nil {
+		return err
+	}
+	u.open = true
+
+	var channels C.size_t
+	if err := uhdCheck("get rx channels", C.uhd_usrp_get_rx_num_channels(u.usrp, &channels)); err != nil {
+		u.closeLocked()
+		return err
+	}
+	if channels < 2 {
+		u.closeLocked()
+		return fmt.Errorf("usrp: device has %d RX channel(s), monopulse needs 2 coherent channels (B210, not B200)", int(channels))
+	}
+
+	if err := u.configureSync(cfg); err != nil {
+		u.closeLocked()
+		return err
+	}
+	if err := u.configureRX(cfg); err != nil {
+		u.closeLocked()
+		return err
+	}
+	if err := u.configureTX(cfg); err != nil {
+		u.closeLocked()
+		return err
+	}
+	if err := u.startStreaming(); err != nil {
+		u.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// configureRX applies rate, LO sharing, gain and frequency to RX channels 0
+// and 1.
+func (u *USRPSDR) configureRX(cfg Config) error {
+	if err := u.configureLO(cfg); err != nil {
+		return err
+	}
+	gains := [2]int{cfg.RxGain0, cfg.RxGain1}
+	for ch := C.size_t(0); ch < 2; ch++ {
+		if err := uhdCheck("set rx rate", C.uhd_usrp_set_rx_rate(u.usrp, C.double(cfg.SampleRate), ch)); err != nil {
+			return err
+		}
+		if err := uhdCheck("set rx gain", C.uhd_usrp_set_rx_gain(u.usrp, C.double(gains[ch]), ch, emptyCString)); err != nil {
+			return err
+		}
+	}
+	if err := u.tuneRX(C.double(cfg.RxLO)); err != nil {
+		return err
+	}
+
+	if err := uhdCheck("make rx streamer", C.uhd_rx_streamer_make(&u.rxStreamer)); err != nil {
+		return err
+	}
+	if err := uhdCheck("make rx metadata", C.uhd_rx_metadata_make(&u.rxMeta)); err != nil {
+		return err
+	}
+	if err := u.getStream(true); err != nil {
+		return err
+	}
+
+	bytes := C.size_t(u.numSamples) * C.size_t(unsafe.Sizeof(complex64(0)))
+	u.rxBuf[0] = C.malloc(bytes)
+	u.rxBuf[1] = C.malloc(bytes)
+	return nil
+}
+
+// configureSync selects the reference clock and PPS source of motherboard 0
+// and resets the device time. It runs before tuning so the synthesizers lock
+// to the chosen reference. UHD expects a 10 MHz external reference;
+// RefClockHz is not used.
+func (u *USRPSDR) configureSync(cfg Config) error {
+	if cfg.ClockSource != "" {
+		src := C.CString(cfg.ClockSource)
+		defer C.free(unsafe.Pointer(src))
+		if err := uhdCheck("set clock source", C.uhd_usrp_set_clock_source(u.usrp, src, 0)); err != nil {
+			return err
+		}
+	}
+	if cfg.TimeSource != "" {
+		src := C.CString(cfg.TimeSource)
+		defer C.free(unsafe.Pointer(src))
+		if err := uhdCheck("set time source", C.uhd_usrp_set_time_source(u.usrp, src, 0)); err != nil {
+			return err
+		}
+	}
+	// With a PPS the time is latched on the next edge, so several devices
+	// sharing the PPS agree; otherwise it is set immediately.
+	if cfg.TimeSource != "" && cfg.TimeSource != "none" && cfg.TimeSource != "internal" {
+		return uhdCheck("set time unknown pps", C.uhd_usrp_set_time_unknown_pps(u.usrp, 0, 0))
+	}
+	return uhdCheck("set time now", C.uhd_usrp_set_time_now(u.usrp, 0, 0, 0))
+}
+
+// tuneRX retunes both RX channels with one timed command, so their DDCs
+// switch on the same clock edge and the channel phase difference stays
+// repeatable across retunes.
+func (u *USRPSDR) tuneRX(freq C.double) error {
+	var full C.int64_t
+	var frac C.double
+	if err := uhdCheck("get time", C.uhd_usrp_get_time_now(u.usrp, 0, &full, &frac)); err != nil {
+		return err
+	}
+	at, atFrac := usrpTimeAfter(int64(full), float64(frac), usrpStartDelay)
+	if err := uhdCheck("set command time", C.uhd_usrp_set_command_time(u.usrp, C.int64_t(at), C.double(atFrac), 0)); err != nil {
+		return err
+	}
+	defer C.uhd_usrp_clear_command_time(u.usrp, 0)
+
+	tune := C.uhd_tune_request_t{
+		target_freq:     freq,
+		rf_freq_policy:  C.UHD_TUNE_REQUEST_POLICY_AUTO,
+		dsp_freq_policy: C.UHD_TUNE_REQUEST_POLICY_AUTO,
+	}
+	var result C.uhd_tune_result_t
+	for ch := C.size_t(0); ch < 2; ch++ {
+		if err := uhdCheck("set rx freq", C.uhd_usrp_set_rx_freq(u.usrp, &tune, ch, &result)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureLO sets the LO source and export flags so both RX channels share a
+// single synthesizer, which keeps the channel phase difference stable.
+func (u *USRPSDR) configureLO(cfg Config) error {
+	if cfg.LOSource == "" && !cfg.LOExport {
+		return nil
+	}
+	name := C.CString("all")
+	defer C.free(unsafe.Pointer(name))
+	if cfg.LOSource != "" {
+		src := C.CString(cfg.LOSource)
+		defer C.free(unsafe.Pointer(src))
+		for ch := C.size_t(0); ch < 2; ch++ {
+			if err := uhdCheck("set rx lo source", C.uhd_usrp_set_rx_lo_source(u.usrp, src, name, ch)); err != nil {
+				return err
+			}
+		}
+	}
+	if cfg.LOExport {
+		if err := uhdCheck("set rx lo export", C.uhd_usrp_set_rx_lo_export_enabled(u.usrp, C.bool(true), name, 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureTX applies rate, frequency and gain to TX channel 0 and creates a
+// TX streamer for both channels.
+func (u *USRPSDR) configureTX(cfg Config) error {
+	if err := uhdCheck("set tx rate", C.uhd_usrp_set_tx_rate(u.usrp, C.double(cfg.SampleRate), 0)); err != nil {
+		return err
+	}
+	if err := uhdCheck("set tx gain", C.uhd_usrp_set_tx_gain(u.usrp, C.double(cfg.TxGain), 0, emptyCString)); err != nil {
+		return err
+	}
+	tune := C.uhd_tune_request_t{
+		target_freq:     C.double(cfg.RxLO + cfg.ToneOffset),
+		rf_freq_policy:  C.UHD_TUNE_REQUEST_POLICY_AUTO,
+		dsp_freq_policy: C.UHD_TUNE_REQUEST_POLICY_AUTO,
+	}
+	var result C.uhd_tune_result_t
+	if err := uhdCheck("set tx freq", C.uhd_usrp_set_tx_freq(u.usrp, &tune, 0, &result)); err != nil {
+		return err
+	}
+	if err := uhdCheck("make tx streamer", C.uhd_tx_streamer_make(&u.txStreamer)); err != nil {
+		return err
+	}
+	if err := uhdCheck("make tx metadata", C.uhd_tx_metadata_make(&u.txMeta, C.bool(false), 0, 0, C.bool(true), C.bool(false))); err != nil {
+		return err
+	}
+	return u.getStream(false)
+}
+
+// getStream binds the RX or TX streamer to channels 0 and 1 using fc32 host
+// samples (layout-compatible with complex64) and sc16 over the wire.
+func (u *USRPSDR) getStream(rx bool) error {
+	cpu := C.CString("fc32")
+	otw := C.CString("sc16")
+	defer C.free(unsafe.Pointer(cpu))
+	defer C.free(unsafe.Pointer(otw))
+
+	channels := (*[2]C.size_t)(C.malloc(C.size_t(unsafe.Sizeof(C.size_t(0))) * 2))
+	defer C.free(unsafe.Pointer(channels))
+	channels[0], channels[1] = 0, 1
+
+	args := C.uhd_stream_args_t{
+		cpu_format:   cpu,
+		otw_format:   otw,
+		args:         emptyCString,
+		channel_list: &channels[0],
+		n_channels:   2,
+	}
+	if rx {
+		return uhdCheck("get rx stream", C.uhd_usrp_get_rx_stream(u.usrp, &args, u.rxStreamer))
+	}
+	return uhdCheck("get tx stream", C.uhd_usrp_get_tx_stream(u.usrp, &args, u.txStreamer))
+}
+
+// startStreaming starts both channels at a future device time, after the
+// timed tune has taken effect, so the first samples are already aligned.
+func (u *USRPSDR) startStreaming() error {
+	var full C.int64_t
+	var frac C.double
+	if err := uhdCheck("get time", C.uhd_usrp_get_time_now(u.usrp, 0, &full, &frac)); err != nil {
+		return err
+	}
+	at, atFrac := usrpTimeAfter(int64(full), float64(frac), usrpStartDelay)
+	cmd := C.uhd_stream_cmd_t{
+		stream_mode:         C.UHD_STREAM_MODE_START_CONTINUOUS,
+		stream_now:          C.bool(false),
+		time_spec_full_secs: C.int64_t(at),
+		time_spec_frac_secs: C.double(atFrac),
+	}
+	if err := uhdCheck("start rx stream", C.uhd_rx_streamer_issue_stream_cmd(u.rxStreamer, &cmd)); err != nil {
+		return err
+	}
+	u.streaming = true
+	return nil
+}
+
+// RX receives one buffer per channel. Samples are already scaled to [-1, 1].
+// An overflow drops samples on both channels alike; the buffer is refilled
+// from the next sample so it is contiguous in time.
+func (u *USRPSDR) RX(ctx context.Context) ([]complex64, []complex64, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.streaming {
+		return nil, nil, errors.New("usrp not initialized")
+	}
+
+	n := u.numSamples
+	total := 0
+	for total < n {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		offset := uintptr(total) * unsafe.Sizeof(complex64(0))
+		buffs := (*[2]unsafe.Pointer)(C.malloc(C.size_t(unsafe.Sizeof(unsafe.Pointer(nil))) * 2))
+		buffs[0] = unsafe.Add(u.rxBuf[0], offset)
+		buffs[1] = unsafe.Add(u.rxBuf[1], offset)
+		var got C.size_t
+		status := C.uhd_rx_streamer_recv(u.rxStreamer, &buffs[0], C.size_t(n-total), &u.rxMeta, C.double(usrpRecvTimeout), C.bool(false), &got)
+		C.free(unsafe.Pointer(buffs))
+		if err := uhdCheck("recv", status); err != nil {
+			return nil, nil, err
+		}
+		var code C.uhd_rx_metadata_error_code_t
+		C.uhd_rx_metadata_error_code(u.rxMeta, &code)
+		switch code {
+		case C.UHD_RX_METADATA_ERROR_CODE_NONE:
+		case C.UHD_RX_METADATA_ERROR_CODE_OVERFLOW:
+			total = 0
+			continue
+		case C.UHD_RX_METADATA_ERROR_CODE_ALIGNMENT:
+			return nil, nil, errors.New("usrp recv: RX channels lost alignment")
+		default:
+			return nil, nil, fmt.Errorf("usrp recv: metadata error code %d", int(code))
+		}
+		total += int(got)
+	}
+
+	ch0 := make([]complex64, n)
+	ch1 := make([]complex64, n)
+	copy(ch0, unsafe.Slice((*complex64)(u.rxBuf[0]), n))
+	copy(ch1, unsafe.Slice((*complex64)(u.rxBuf[1]), n))
+	return ch0, ch1, nil
+}
+
+// TX sends one buffer per channel. Both slices must have equal length.
+func (u *USRPSDR) TX(_ context.Context, iq0, iq1 []complex64) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.open {
+		return errors.New("usrp not initialized")
+	}
+	if len(iq0) != len(iq1) {
+		return fmt.Errorf("tx length mismatch: %d vs %d", len(iq0), len(iq1))
+	}
+	if len(iq0) == 0 {
+		return nil
+	}
+
+	bytes := C.size_t(len(iq0)) * C.size_t(unsafe.Sizeof(complex64(0)))
+	buffs := (*[2]unsafe.Pointer)(C.malloc(C.size_t(unsafe.Sizeof(unsafe.Pointer(nil))) * 2))
+	defer C.free(unsafe.Pointer(buffs))
+	buffs[0] = C.malloc(bytes)
+	buffs[1] = C.malloc(bytes)
+	defer C.free(buffs[0])
+	defer C.free(buffs[1])
+	copy(unsafe.Slice((*complex64)(buffs[0]), len(iq0)), iq0)
+	copy(unsafe.Slice((*complex64)(buffs[1]), len(iq1)), iq1)
+
+	var sent C.size_t
+	return uhdCheck("send", C.uhd_tx_streamer_send(u.txStreamer, &buffs[0], C.size_t(len(iq0)), &u.txMeta, C.double(usrpRecvTimeout), &sent))
+}
+
+// ReadAttributes reads the tuned rate, frequencies and gains back from UHD.
+func (u *USRPSDR) ReadAttributes(_ context.Context) (HardwareAttributes, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.open {
+		return HardwareAttributes{}, errors.New("usrp not initialized")
+	}
+	var rate, rxFreq, txFreq, gain0, gain1, txGain C.double
+	reads := []struct {
+		op   string
+		code C.uhd_error
+	}{
+		{"get rx rate", C.uhd_usrp_get_rx_rate(u.usrp, 0, &rate)},
+		{"get rx freq", C.uhd_usrp_get_rx_freq(u.usrp, 0, &rxFreq)},
+		{"get tx freq", C.uhd_usrp_get_tx_freq(u.usrp, 0, &txFreq)},
+		{"get rx0 gain", C.uhd_usrp_get_rx_gain(u.usrp, 0, emptyCString, &gain0)},
+		{"get rx1 gain", C.uhd_usrp_get_rx_gain(u.usrp, 1, emptyCString, &gain1)},
+		{"get tx gain", C.uhd_usrp_get_tx_gain(u.usrp, 0, emptyCString, &txGain)},
+	}
+	attrs := HardwareAttributes{Backend: "usrp"}
+	for _, r := range reads {
+		if err := uhdCheck(r.op, r.code); err != nil {
+			if attrs.Errors == nil {
+				attrs.Errors = map[string]string{}
+			}
+			attrs.Errors[r.op] = err.Error()
+		}
+	}
+	attrs.SampleRateHz = float64(rate)
+	attrs.RxLOHz = float64(rxFreq)
+	attrs.TxLOHz = float64(txFreq)
+	attrs.RxGain0DB = float64(gain0)
+	attrs.RxGain1DB = float64(gain1)
+	attrs.TxGainDB = float64(txGain)
+	return attrs, nil
+}
+
+// WriteAttribute applies a single live setting through UHD.
+func (u *USRPSDR) WriteAttribute(_ context.Context, name string, value float64) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.open {
+		return errors.New("usrp not initialized")
+	}
+	v := C.double(value)
+	tune := C.uhd_tune_request_t{
+		target_freq:     v,
+		rf_freq_policy:  C.UHD_TUNE_REQUEST_POLICY_AUTO,
+		dsp_freq_policy: C.UHD_TUNE_REQUEST_POLICY_AUTO,
+	}
+	var result C.uhd_tune_result_t
+	switch name {
+	case AttrSampleRate:
+		if err := uhdCheck("set rx rate", C.uhd_usrp_set_rx_rate(u.usrp, v, 0)); err != nil {
+			return err
+		}
+		return uhdCheck("set rx rate", C.uhd_usrp_set_rx_rate(u.usrp, v, 1))
+	case AttrRxLO:
+		return u.tuneRX(v)
+	case AttrTxLO:
+		return uhdCheck("set tx freq", C.uhd_usrp_set_tx_freq(u.usrp, &tune, 0, &result))
+	case AttrRxGain0:
+		return uhdCheck("set rx gain", C.uhd_usrp_set_rx_gain(u.usrp, v, 0, emptyCString))
+	case AttrRxGain1:
+		return uhdCheck("set rx gain", C.uhd_usrp_set_rx_gain(u.usrp, v, 1, emptyCString))
+	case AttrTxGain:
+		return uhdCheck("set tx gain", C.uhd_usrp_set_tx_gain(u.usrp, v, 0, emptyCString))
+	default:
+		return fmt.Errorf("unknown attribute %q", name)
+	}
+}
+
+// SetPhaseDelta stores the requested phase delta; the hardware backend does
+// not synthesize signals, so the value is informational only.
+func (u *USRPSDR) SetPhaseDelta(phaseDeltaDeg float64) {
+	u.mu.Lock()
+	u.phaseDelta = phaseDeltaDeg
+	u.mu.Unlock()
+}
+
+// GetPhaseDelta returns the stored phase delta setting.
+func (u *USRPSDR) GetPhaseDelta() float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.phaseDelta
+}
+
+// Close stops streaming and releases all UHD handles.
+func (u *USRPSDR) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.closeLocked()
+	return nil
+}
+
+func (u *USRPSDR) closeLocked() {
+	if u.streaming {
+		cmd := C.uhd_stream_cmd_t{stream_mode: C.UHD_STREAM_MODE_STOP_CONTINUOUS, stream_now: C.bool(true)}
+		C.uhd_rx_streamer_issue_stream_cmd(u.rxStreamer, &cmd)
+		u.streaming = false
+	}
+	for i := range u.rxBuf {
+		if u.rxBuf[i] != nil {
+			C.free(u.rxBuf[i])
+			u.rxBuf[i] = nil
+		}
+	}
+	if u.rxMeta != nil {
+		C.uhd_rx_metadata_free(&u.rxMeta)
+	}
+	if u.txMeta != nil {
+		C.uhd_tx_metadata_free(&u.txMeta)
+	}
+	if u.rxStreamer != nil {
+		C.uhd_rx_streamer_free(&u.rxStreamer)
+	}
+	if u.txStreamer != nil {
+		C.uhd_tx_streamer_free(&u.txStreamer)
+	}
+	if u.open {
+		C.uhd_usrp_free(&u.usrp)
+		u.open = false
+	}
+}
+
+// emptyCString is a shared "" used for optional UHD name/args parameters.
+var emptyCString = C.CString("")
+
+// uhdCheck converts a UHD error code into a Go error including UHD's last
+// error string.
+func uhdCheck(op string, code C.uhd_error) error {
+	if code == C.UHD_ERROR_NONE {
+		return nil
+	}
+	buf := make([]byte, 512)
+	C.uhd_get_last_error((*C.char)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
+	return fmt.Errorf("usrp %s: uhd error %d: %s", op, int(code), C.GoString((*C.char)(unsafe.Pointer(&buf[0]))))
+}
@@ -0,0 +1,39 @@
+package sdr
+
+import "math"
+
+// usrpStartDelay is how far ahead of the device time, in seconds, the timed
+// retune and the stream start of both RX channels are scheduled.
+const usrpStartDelay = 0.1
+
+// Capabilities reports the limits of a B210-class USRP. Other USRP models are
+// narrower or wider in places; UHD clamps out-of-range requests itself.
+func (u *USRPSDR) Capabilities() Capabilities {
+	return Capabilities{
+		Backend:            "usrp",
+		RXChannels:         2,
+		TXChannels:         2,
+		FullDuplex:         true,
+		MinFrequencyHz:     70e6,
+		MaxFrequencyHz:     6e9,
+		MinSampleRateHz:    200e3,
+		MaxSampleRateHz:    61.44e6,
+		MinRxGainDB:        0,
+		MaxRxGainDB:        76,
+		MinTxGainDB:        0,
+		MaxTxGainDB:        89,
+		SupportsTX:         true,
+		SupportsTimestamps: true,
+		FullScale:          1.0, // fc32 host samples
+		ClockSources:       []string{"internal", "external", "gpsdo", "mimo"},
+		TimeSources:        []string{"none", "internal", "external", "gpsdo", "mimo"},
+	}
+}
+
+// usrpTimeAfter adds delay seconds to the UHD time spec full+frac and returns
+// it with the fractional part normalized to [0, 1).
+func usrpTimeAfter(full int64, frac, delay float64) (int64, float64) {
+	frac += delay
+	whole := math.Floor(frac)
+	return full + int64(whole), frac - whole
+}
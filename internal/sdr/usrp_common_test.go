@@ -0,0 +1,27 @@
+package sdr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUSRPTimeAfter(t *testing.T) {
+	tests := []struct {
+		full     int64
+		frac     float64
+		delay    float64
+		wantFull int64
+		wantFrac float64
+	}{
+		{0, 0, 0.1, 0, 0.1},
+		{5, 0.95, 0.1, 6, 0.05},
+		{7, 0.5, 2.25, 9, 0.75},
+		{3, 0, 1, 4, 0},
+	}
+	for _, tt := range tests {
+		full, frac := usrpTimeAfter(tt.full, tt.frac, tt.delay)
+		if full != tt.wantFull || math.Abs(frac-tt.wantFrac) > 1e-12 {
+			t.Errorf("usrpTimeAfter(%d, %g, %g) = %d, %g, want %d, %g", tt.full, tt.frac, tt.delay, full, frac, tt.wantFull, tt.wantFrac)
+		}
+	}
+}
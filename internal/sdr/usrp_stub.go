@@ -0,0 +1,53 @@
+//go:build !uhd
+
+package sdr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// USRPSDR is a placeholder used when the binary is built without the "uhd"
+// build tag. Rebuild with `go build -tags uhd` (libuhd required) to enable
+// Ettus USRP support.
+type USRPSDR struct {
+	mu         sync.Mutex
+	phaseDelta float64
+}
+
+func NewUSRP() *USRPSDR { return &USRPSDR{} }
+
+func (u *USRPSDR) Init(_ context.Context, _ Config) error {
+	return fmt.Errorf("usrp: %w (rebuild with -tags uhd)", ErrBackendUnavailable)
+}
+
+func (u *USRPSDR) RX(_ context.Context) ([]complex64, []complex64, error) {
+	return nil, nil, fmt.Errorf("usrp: %w", ErrBackendUnavailable)
+}
+
+func (u *USRPSDR) TX(_ context.Context, _, _ []complex64) error {
+	return fmt.Errorf("usrp: %w", ErrBackendUnavailable)
+}
+
+func (u *USRPSDR) Close() error { return nil }
+
+func (u *USRPSDR) ReadAttributes(_ context.Context) (HardwareAttributes, error) {
+	return HardwareAttributes{}, fmt.Errorf("usrp: %w", ErrBackendUnavailable)
+}
+
+func (u *USRPSDR) WriteAttribute(_ context.Context, _ string, _ float64) error {
+	return fmt.Errorf("usrp: %w", ErrBackendUnavailable)
+}
+
+func (u *USRPSDR) SetPhaseDelta(phaseDeltaDeg float64) {
+	u.mu.Lock()
+	u.phaseDelta = phaseDeltaDeg
+	u.mu.Unlock()
+}
+
+func (u *USRPSDR) GetPhaseDelta() float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.phaseDelta
+}
@@ -0,0 +1,23 @@
+//go:build !uhd
+
+package sdr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUSRPStubReportsUnavailable(t *testing.T) {
+	usrp := NewUSRP()
+	if err := usrp.Init(context.Background(), Config{}); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("expected ErrBackendUnavailable, got %v", err)
+	}
+	if _, _, err := usrp.RX(context.Background()); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("expected ErrBackendUnavailable from RX, got %v", err)
+	}
+	usrp.SetPhaseDelta(12)
+	if usrp.GetPhaseDelta() != 12 {
+		t.Fatalf("phase delta not stored")
+	}
+}
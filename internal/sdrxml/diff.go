@@ -0,0 +1,271 @@
+package sdrxml
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChangeKind classifies a difference between two context XML documents.
+type ChangeKind string
+
+const (
+	DeviceAdded       ChangeKind = "device-added"
+	DeviceRemoved     ChangeKind = "device-removed"
+	ChannelAdded      ChangeKind = "channel-added"
+	ChannelRemoved    ChangeKind = "channel-removed"
+	ChannelRenamed    ChangeKind = "channel-renamed"
+	AttributeAdded    ChangeKind = "attribute-added"
+	AttributeRemoved  ChangeKind = "attribute-removed"
+	AttributeRenamed  ChangeKind = "attribute-renamed"
+	ScanFormatChanged ChangeKind = "scan-format-changed"
+	VersionChanged    ChangeKind = "version-changed"
+)
+
+// Change is one difference found by Diff. Device is the device name (the
+// ID when it has none) and Channel the channel ID with its direction, e.g.
+// "voltage0(input)"; both are empty for context-level changes. Old and New
+// hold the renamed names, scan formats or versions.
+type Change struct {
+	Kind      ChangeKind `json:"kind"`
+	Device    string     `json:"device,omitempty"`
+	Channel   string     `json:"channel,omitempty"`
+	Attribute string     `json:"attribute,omitempty"`
+	// Debug marks a debug attribute.
+	Debug bool   `json:"debug,omitempty"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// Breaking reports whether a client that worked against the old document
+// may fail against the new one: something it used is gone, renamed or
+// decoded differently. Additions and version changes are not breaking.
+func (c Change) Breaking() bool {
+	switch c.Kind {
+	case DeviceAdded, ChannelAdded, AttributeAdded, VersionChanged:
+		return false
+	}
+	return true
+}
+
+func (c Change) String() string {
+	path := strings.Join(slices.DeleteFunc([]string{c.Device, c.Channel, c.Attribute}, func(s string) bool { return s == "" }), "/")
+	if c.Debug {
+		path += " (debug)"
+	}
+	out := string(c.Kind)
+	if path != "" {
+		out += " " + path
+	}
+	if c.Old != "" || c.New != "" {
+		out += fmt.Sprintf(": %q -> %q", c.Old, c.New)
+	}
+	return out
+}
+
+// Diff compares a context XML document against a baseline of the same
+// device and returns the differences in document order, baseline changes
+// first. Devices are matched by name, since firmware may renumber the
+// iio:deviceN IDs, and channels by ID and direction.
+//
+// IIO has no identity for attributes beyond their name, so a rename is a
+// guess: when exactly one attribute of an element disappeared and exactly
+// one appeared, the pair is reported as AttributeRenamed. Either way the
+// old name is gone, which is what breaks a write sequence.
+func Diff(baseline, current *SDRContext) []Change {
+	var changes []Change
+	if old, cur := contextVersion(baseline), contextVersion(current); old != cur {
+		changes = append(changes, Change{Kind: VersionChanged, Old: old, New: cur})
+	}
+
+	newDevices := make(map[string]*DeviceEntry, len(current.Device))
+	for i := range current.Device {
+		newDevices[devicePath(&current.Device[i])] = &current.Device[i]
+	}
+	oldDevices := make(map[string]bool, len(baseline.Device))
+	for i := range baseline.Device {
+		old := &baseline.Device[i]
+		name := devicePath(old)
+		oldDevices[name] = true
+		dev, ok := newDevices[name]
+		if !ok {
+			changes = append(changes, Change{Kind: DeviceRemoved, Device: name})
+			continue
+		}
+		changes = append(changes, diffDevice(name, old, dev)...)
+	}
+	for i := range current.Device {
+		if name := devicePath(&current.Device[i]); !oldDevices[name] {
+			changes = append(changes, Change{Kind: DeviceAdded, Device: name})
+		}
+	}
+	return changes
+}
+
+// contextVersion formats the IIOD version of a document, e.g. "0.25 v0.25".
+func contextVersion(ctx *SDRContext) string {
+	if ctx.VersionMajor == "" && ctx.VersionMinor == "" {
+		return ctx.VersionGit
+	}
+	return strings.TrimSpace(ctx.VersionMajor + "." + ctx.VersionMinor + " " + ctx.VersionGit)
+}
+
+// diffDevice compares the attributes and channels of one device.
+func diffDevice(name string, old, dev *DeviceEntry) []Change {
+	changes := diffNames(Change{Device: name}, attrNames(old.Attribute), attrNames(dev.Attribute))
+	changes = append(changes, diffNames(Change{Device: name, Debug: true}, debugNames(old.DebugAttribute), debugNames(dev.DebugAttribute))...)
+
+	channels := make(map[string]*ChannelEntry, len(dev.Channel))
+	for i := range dev.Channel {
+		channels[channelKey(&dev.Channel[i])] = &dev.Channel[i]
+	}
+	oldChannels := make(map[string]bool, len(old.Channel))
+	for i := range old.Channel {
+		oc := &old.Channel[i]
+		key := channelKey(oc)
+		oldChannels[key] = true
+		ch, ok := channels[key]
+		if !ok {
+			changes = append(changes, Change{Kind: ChannelRemoved, Device: name, Channel: key})
+			continue
+		}
+		if oc.Name != ch.Name {
+			changes = append(changes, Change{Kind: ChannelRenamed, Device: name, Channel: key, Old: oc.Name, New: ch.Name})
+		}
+		if oldFmt, newFmt := scanFormat(oc), scanFormat(ch); oldFmt != newFmt {
+			changes = append(changes, Change{Kind: ScanFormatChanged, Device: name, Channel: key, Old: oldFmt, New: newFmt})
+		}
+		changes = append(changes, diffNames(Change{Device: name, Channel: key}, channelAttrNames(oc.Attribute), channelAttrNames(ch.Attribute))...)
+	}
+	for i := range dev.Channel {
+		if key := channelKey(&dev.Channel[i]); !oldChannels[key] {
+			changes = append(changes, Change{Kind: ChannelAdded, Device: name, Channel: key})
+		}
+	}
+	return changes
+}
+
+// diffNames compares the attribute names of one element; scope carries the
+// element's location into every change.
+func diffNames(scope Change, old, cur []string) []Change {
+	var removed, added []string
+	for _, n := range old {
+		if !slices.Contains(cur, n) {
+			removed = append(removed, n)
+		}
+	}
+	for _, n := range cur {
+		if !slices.Contains(old, n) {
+			added = append(added, n)
+		}
+	}
+	if len(removed) == 1 && len(added) == 1 {
+		c := scope
+		c.Kind, c.Attribute, c.Old, c.New = AttributeRenamed, removed[0], removed[0], added[0]
+		return []Change{c}
+	}
+	changes := make([]Change, 0, len(removed)+len(added))
+	for _, n := range removed {
+		c := scope
+		c.Kind, c.Attribute = AttributeRemoved, n
+		changes = append(changes, c)
+	}
+	for _, n := range added {
+		c := scope
+		c.Kind, c.Attribute = AttributeAdded, n
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// scanFormat returns the raw scan element of a channel with its index, or
+// "" for channels without one.
+func scanFormat(ch *ChannelEntry) string {
+	if ch.ScanElementRaw == nil {
+		return ""
+	}
+	s := ch.ScanElementRaw
+	out := "index " + s.Index + " " + s.Format
+	if s.Scale != "" {
+		out += " scale " + s.Scale
+	}
+	return out
+}
+
+func attrNames(attrs []DevAttribute) []string {
+	names := make([]string, len(attrs))
+	for i, a := range attrs {
+		names[i] = a.Name
+	}
+	return names
+}
+
+func debugNames(attrs []DebugAttribute) []string {
+	names := make([]string, len(attrs))
+	for i, a := range attrs {
+		names[i] = a.Name
+	}
+	return names
+}
+
+func channelAttrNames(attrs []ChannelAttr) []string {
+	names := make([]string, len(attrs))
+	for i, a := range attrs {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// AttrRef names an attribute a client writes. Device matches a device name
+// or ID, Channel a channel ID or name in either direction, as the IIOD WRITE
+// command does. An empty Channel names a device attribute, or the attribute
+// of any of the device's channels: the compatibility writes resolve
+// channel-less names such as the AD9361 sampling_frequency that way.
+type AttrRef struct {
+	Device  string `json:"device"`
+	Channel string `json:"channel,omitempty"`
+	Attr    string `json:"attr"`
+	// Optional marks writes whose failure the client tolerates.
+	Optional bool `json:"optional,omitempty"`
+}
+
+func (r AttrRef) String() string {
+	if r.Channel == "" {
+		return r.Device + "/" + r.Attr
+	}
+	return r.Device + "/" + r.Channel + "/" + r.Attr
+}
+
+// HasAttribute reports whether the context offers the attribute ref names.
+func (ctx *SDRContext) HasAttribute(ref AttrRef) bool {
+	for i := range ctx.Device {
+		dev := &ctx.Device[i]
+		if dev.Name != ref.Device && dev.ID != ref.Device {
+			continue
+		}
+		if ref.Channel == "" && slices.ContainsFunc(dev.Attribute, func(a DevAttribute) bool { return a.Name == ref.Attr }) {
+			return true
+		}
+		for j := range dev.Channel {
+			ch := &dev.Channel[j]
+			if ref.Channel != "" && ch.ID != ref.Channel && ch.Name != ref.Channel {
+				continue
+			}
+			if slices.ContainsFunc(ch.Attribute, func(a ChannelAttr) bool { return a.Name == ref.Attr }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MissingAttributes returns the refs the context does not offer, in order.
+func (ctx *SDRContext) MissingAttributes(refs []AttrRef) []AttrRef {
+	var missing []AttrRef
+	for _, ref := range refs {
+		if !ctx.HasAttribute(ref) {
+			missing = append(missing, ref)
+		}
+	}
+	return missing
+}
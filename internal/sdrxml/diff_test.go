@@ -0,0 +1,87 @@
+package sdrxml
+
+import (
+	"slices"
+	"testing"
+)
+
+func parsePluto(t *testing.T) *SDRContext {
+	t.Helper()
+	var ctx SDRContext
+	if err := ctx.Parse(loadExampleXML(t, "pluto.xml")); err != nil {
+		t.Fatal(err)
+	}
+	return &ctx
+}
+
+func TestDiffFlagsFirmwareChanges(t *testing.T) {
+	baseline := parsePluto(t)
+	if got := Diff(baseline, parsePluto(t)); len(got) != 0 {
+		t.Fatalf("identical documents differ: %v", got)
+	}
+
+	current := parsePluto(t)
+	current.VersionMinor = "38"
+	phy, err := current.Index.LookupDevice("ad9361-phy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range phy.Channel {
+		ch := &phy.Channel[i]
+		switch channelKey(ch) {
+		case "voltage0(input)":
+			for j := range ch.Attribute {
+				if ch.Attribute[j].Name == "hardwaregain" {
+					ch.Attribute[j].Name = "hardware_gain"
+				}
+			}
+		case "altvoltage1(output)":
+			ch.Name = "TX_LO1"
+		}
+	}
+	phy.Channel = slices.DeleteFunc(phy.Channel, func(ch ChannelEntry) bool { return channelKey(&ch) == "temp0(input)" })
+	current.Device = slices.DeleteFunc(current.Device, func(d DeviceEntry) bool { return d.Name == "xadc" })
+	current.BuildIndex()
+
+	changes := Diff(baseline, current)
+	want := []string{
+		`version-changed: "0.25 ` + baseline.VersionGit + `" -> "0.38 ` + baseline.VersionGit + `"`,
+		`channel-renamed ad9361-phy/altvoltage1(output): "TX_LO" -> "TX_LO1"`,
+		`attribute-renamed ad9361-phy/voltage0(input)/hardwaregain: "hardwaregain" -> "hardware_gain"`,
+		"channel-removed ad9361-phy/temp0(input)",
+		"device-removed xadc",
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	for _, w := range want {
+		if !slices.Contains(got, w) {
+			t.Errorf("missing %q in %q", w, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d changes, want %d: %q", len(got), len(want), got)
+	}
+	for _, c := range changes {
+		if c.Breaking() == (c.Kind == VersionChanged) {
+			t.Errorf("%v: Breaking() = %v", c, c.Breaking())
+		}
+	}
+}
+
+func TestMissingAttributes(t *testing.T) {
+	ctx := parsePluto(t)
+	refs := []AttrRef{
+		{Device: "ad9361-phy", Attr: "xo_correction"},
+		{Device: "iio:device0", Channel: "RX_LO", Attr: "frequency"},
+		{Device: "ad9361-phy", Channel: "voltage0", Attr: "hardwaregain"},
+		{Device: "ad9361-phy", Channel: "voltage0", Attr: "no_such_attr"},
+		{Device: "ad9361-phy", Attr: "sampling_frequency"},
+		{Device: "ad9361-phy", Attr: "no_such_attr"},
+	}
+	got := ctx.MissingAttributes(refs)
+	if len(got) != 2 || got[0] != refs[3] || got[1] != refs[5] {
+		t.Fatalf("missing = %v", got)
+	}
+}
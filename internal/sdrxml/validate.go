@@ -0,0 +1,131 @@
+package sdrxml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SchemaError is one violation of the IIO context schema found by Validate.
+// Path locates the element, e.g. "ad9361-phy/voltage0(input)/hardwaregain".
+type SchemaError struct {
+	Path string
+	Msg  string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return e.Path + ": " + e.Msg
+}
+
+// Validate checks the parsed context against the structure libiio expects
+// from IIOD: a named context with a numeric version, if it has one, devices
+// with unique IDs, channels with an ID and a direction that are unique per
+// direction, named attributes that are unique within their element, and
+// scan elements with a parseable format and a unique index per device. All violations are
+// returned together as *SchemaError values joined with errors.Join.
+//
+// The receiver is not modified.
+func (ctx *SDRContext) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, &SchemaError{Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if ctx.Name == "" {
+		fail("", "context has no name")
+	}
+	// Documents written by older IIOD releases carry no version at all.
+	for _, v := range []struct{ attr, value string }{{"version-major", ctx.VersionMajor}, {"version-minor", ctx.VersionMinor}} {
+		if _, err := strconv.Atoi(v.value); v.value != "" && err != nil {
+			fail("", "context %s %q is not a number", v.attr, v.value)
+		}
+	}
+	if len(ctx.Device) == 0 {
+		fail("", "context has no devices")
+	}
+	uniqueNames(ctx.ContextAttribute, func(a ContextAttribute) string { return a.Name }, "context attribute", "", fail)
+
+	ids := make(map[string]bool)
+	for i := range ctx.Device {
+		dev := &ctx.Device[i]
+		path := devicePath(dev)
+		switch {
+		case dev.ID == "":
+			fail(path, "device has no id")
+		case ids[dev.ID]:
+			fail(path, "duplicate device id %q", dev.ID)
+		}
+		ids[dev.ID] = true
+
+		uniqueNames(dev.Attribute, func(a DevAttribute) string { return a.Name }, "attribute", path, fail)
+		uniqueNames(dev.DebugAttribute, func(a DebugAttribute) string { return a.Name }, "debug attribute", path, fail)
+		uniqueNames(dev.BufferAttribute, func(a BufferAttribute) string { return a.Name }, "buffer attribute", path, fail)
+
+		channels := make(map[string]bool)
+		scanIndex := make(map[uint32]string)
+		for j := range dev.Channel {
+			ch := &dev.Channel[j]
+			chPath := path + "/" + channelKey(ch)
+			if ch.ID == "" {
+				fail(chPath, "channel has no id")
+			}
+			if ch.Type != "input" && ch.Type != "output" {
+				fail(chPath, "channel type %q is neither input nor output", ch.Type)
+			}
+			if channels[channelKey(ch)] {
+				fail(chPath, "duplicate channel")
+			}
+			channels[channelKey(ch)] = true
+			uniqueNames(ch.Attribute, func(a ChannelAttr) string { return a.Name }, "attribute", chPath, fail)
+
+			if ch.ScanElementRaw == nil {
+				continue
+			}
+			// ParseScanFormat stores its result in the channel; parse a
+			// copy to keep the receiver untouched.
+			probe := ChannelEntry{ScanElementRaw: ch.ScanElementRaw}
+			if err := probe.ParseScanFormat(); err != nil {
+				fail(chPath, "scan element: %v", err)
+				continue
+			}
+			if other, ok := scanIndex[probe.ParsedFormat.Index]; ok {
+				fail(chPath, "scan index %d already used by %s", probe.ParsedFormat.Index, other)
+			}
+			scanIndex[probe.ParsedFormat.Index] = channelKey(ch)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// uniqueNames reports attributes without a name or with a name used twice.
+func uniqueNames[T any](attrs []T, name func(T) string, kind, path string, fail func(path, format string, args ...any)) {
+	seen := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		n := name(a)
+		switch {
+		case n == "":
+			fail(path, "%s has no name", kind)
+		case seen[n]:
+			fail(path, "duplicate %s %q", kind, n)
+		}
+		seen[n] = true
+	}
+}
+
+// devicePath names a device in SchemaError and Change paths: by name, or
+// by ID when it has none.
+func devicePath(dev *DeviceEntry) string {
+	if dev.Name != "" {
+		return dev.Name
+	}
+	return dev.ID
+}
+
+// channelKey identifies a channel within its device. IIO channel IDs are
+// only unique per direction: voltage0 is both an RX input and a TX output.
+func channelKey(ch *ChannelEntry) string {
+	return ch.ID + "(" + ch.Type + ")"
+}
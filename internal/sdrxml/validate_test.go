@@ -0,0 +1,59 @@
+package sdrxml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateExamples(t *testing.T) {
+	for _, name := range []string{"pluto.xml", "ad5541a.xml", "ad5628-1.xml", "ad7091r.xml", "adis16488.xml"} {
+		var ctx SDRContext
+		if err := ctx.Parse(loadExampleXML(t, name)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := ctx.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	raw := `<context name="local" version-major="0" version-minor="x">
+<device id="iio:device0" name="phy">
+<channel id="voltage0" type="input"><attribute name="gain"/><attribute name="gain"/></channel>
+<channel id="voltage0" type="input"/>
+<channel id="voltage1" type="sideways"/>
+</device>
+<device id="iio:device0" name="adc">
+<channel id="voltage0" type="input"><scan-element index="0" format="le:S12/16&gt;&gt;0"/></channel>
+<channel id="voltage1" type="input"><scan-element index="0" format="le:S12/16&gt;&gt;0"/></channel>
+<channel id="voltage2" type="input"><scan-element index="2" format="bogus"/></channel>
+</device>
+</context>`
+	var ctx SDRContext
+	if err := ctx.Parse([]byte(raw)); err != nil {
+		t.Fatal(err)
+	}
+	err := ctx.Validate()
+	for _, want := range []string{
+		`context version-minor "x" is not a number`,
+		`phy/voltage0(input): duplicate attribute "gain"`,
+		"phy/voltage0(input): duplicate channel",
+		`phy/voltage1(sideways): channel type "sideways" is neither input nor output`,
+		`adc: duplicate device id "iio:device0"`,
+		"adc/voltage1(input): scan index 0 already used by voltage0(input)",
+		`adc/voltage2(input): scan element: invalid scan format: "bogus"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("%T is not a *SchemaError", err)
+	}
+	if ctx.Device[1].Channel[0].ParsedFormat == nil || ctx.Device[1].Channel[2].ParsedFormat != nil {
+		t.Fatal("Validate changed the parsed formats")
+	}
+}
@@ -0,0 +1,101 @@
+// Package secrets resolves credentials referenced from configuration instead
+// of storing them in plaintext, and redacts them for logs and API output.
+//
+// A configured value is either the secret itself (kept for compatibility)
+// or a reference:
+//
+//	env:NAME     the environment variable NAME
+//	file:PATH    the contents of PATH, trailing newline removed (e.g. Docker secrets)
+//	secret:NAME  entry NAME of the encrypted secrets store (see Store)
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Reference prefixes.
+const (
+	envPrefix    = "env:"
+	filePrefix   = "file:"
+	secretPrefix = "secret:"
+)
+
+// DefaultStorePath is the encrypted store used by secret: references when
+// no path is configured.
+const DefaultStorePath = "secrets.json"
+
+// Redacted replaces plaintext secrets in logs and printed configs.
+const Redacted = "***"
+
+// IsReference reports whether value refers to a secret rather than holding it.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, envPrefix) || strings.HasPrefix(value, filePrefix) || strings.HasPrefix(value, secretPrefix)
+}
+
+// Redact returns value for display: empty values and references unchanged,
+// anything else as Redacted.
+func Redact(value string) string {
+	if value == "" || IsReference(value) {
+		return value
+	}
+	return Redacted
+}
+
+// Resolver resolves references. The encrypted store at StorePath (default
+// DefaultStorePath) is opened on the first secret: reference, with the key
+// from KeyPath or $GOSDR_SECRETS_KEY.
+type Resolver struct {
+	StorePath string
+	KeyPath   string
+
+	store *Store
+}
+
+// Resolve returns the secret value refers to, or value itself when it is
+// not a reference. A reference that cannot be resolved is an error, so a
+// missing variable or file is caught at startup.
+func (r *Resolver) Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envPrefix):
+		name := strings.TrimPrefix(value, envPrefix)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret %s: environment variable not set", value)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, filePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(value, filePrefix))
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", value, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, secretPrefix):
+		if r.store == nil {
+			store, err := r.open()
+			if err != nil {
+				return "", fmt.Errorf("secret %s: %w", value, err)
+			}
+			r.store = store
+		}
+		secret, err := r.store.Get(strings.TrimPrefix(value, secretPrefix))
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", value, err)
+		}
+		return secret, nil
+	}
+	return value, nil
+}
+
+func (r *Resolver) open() (*Store, error) {
+	key, err := LoadKey(r.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	path := r.StorePath
+	if path == "" {
+		path = DefaultStorePath
+	}
+	return Open(path, key)
+}
@@ -0,0 +1,68 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(KeyEnvVar, key)
+	t.Setenv("GOSDR_TEST_SECRET", "from-env")
+	storePath := filepath.Join(dir, "secrets.json")
+	rawKey, _ := LoadKey("")
+	store, _ := Open(storePath, rawKey)
+	if err := store.Set("pluto", "from-store"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"plaintext", "plaintext", false},
+		{"env:GOSDR_TEST_SECRET", "from-env", false},
+		{"env:GOSDR_TEST_UNSET", "", true},
+		{"file:" + file, "from-file", false},
+		{"file:" + filepath.Join(dir, "missing"), "", true},
+		{"secret:pluto", "from-store", false},
+		{"secret:other", "", true},
+	}
+	r := &Resolver{StorePath: storePath}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := r.Resolve(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("Resolve(%q) = %q, %v; want %q, error %t", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"analog":            Redacted,
+		"env:PLUTO_PASS":    "env:PLUTO_PASS",
+		"secret:pluto-root": "secret:pluto-root",
+	}
+	for in, want := range tests {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// KeyEnvVar holds the base64 store key when no key file is given.
+const KeyEnvVar = "GOSDR_SECRETS_KEY"
+
+const (
+	keySize      = 32 // AES-256
+	storeVersion = 1
+)
+
+// ErrNotFound is returned by Store.Get for an unknown entry.
+var ErrNotFound = errors.New("not in secrets store")
+
+// Store is a JSON file of named secrets, each sealed with AES-256-GCM under
+// a key kept outside the file (a key file or $GOSDR_SECRETS_KEY). The entry
+// name is authenticated with the value, so entries cannot be swapped.
+type Store struct {
+	path    string
+	aead    cipher.AEAD
+	entries map[string]string // name -> base64(nonce || ciphertext)
+}
+
+type storeFile struct {
+	Version int               `json:"version"`
+	Secrets map[string]string `json:"secrets"`
+}
+
+// GenerateKey returns a new random store key, base64 encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadKey reads the base64 store key from path, or from $GOSDR_SECRETS_KEY
+// when path is empty.
+func LoadKey(path string) ([]byte, error) {
+	encoded := os.Getenv(KeyEnvVar)
+	source := "$" + KeyEnvVar
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read secrets key: %w", err)
+		}
+		encoded, source = string(data), path
+	}
+	if strings.TrimSpace(encoded) == "" {
+		return nil, fmt.Errorf("no secrets key: set $%s or give a key file", KeyEnvVar)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("secrets key from %s must be %d base64-encoded bytes", source, keySize)
+	}
+	return key, nil
+}
+
+// Open loads the store at path. A missing file is an empty store, created
+// by the first Save.
+func Open(path string, key []byte) (*Store, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, aead: aead, entries: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read secrets store: %w", err)
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode secrets store %s: %w", path, err)
+	}
+	if file.Version != storeVersion {
+		return nil, fmt.Errorf("secrets store %s: unsupported version %d", path, file.Version)
+	}
+	for name, sealed := range file.Secrets {
+		s.entries[name] = sealed
+	}
+	return s, nil
+}
+
+// Get decrypts the entry name.
+func (s *Store) Get(name string) (string, error) {
+	sealed, ok := s.entries[name]
+	if !ok {
+		return "", fmt.Errorf("%q %w", name, ErrNotFound)
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return "", fmt.Errorf("entry %q is corrupt", name)
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("entry %q: wrong key or tampered value", name)
+	}
+	return string(plain), nil
+}
+
+// Set encrypts value as entry name. Call Save to write the file.
+func (s *Store) Set(name, value string) error {
+	if name == "" {
+		return errors.New("secret name is required")
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	s.entries[name] = base64.StdEncoding.EncodeToString(sealed)
+	return nil
+}
+
+// Delete removes entry name and reports whether it existed.
+func (s *Store) Delete(name string) bool {
+	_, ok := s.entries[name]
+	delete(s.entries, name)
+	return ok
+}
+
+// Names returns the entry names, sorted.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the store, readable by the owner only. The file is replaced
+// atomically.
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(storeFile{Version: storeVersion, Secrets: s.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+	return nil
+}
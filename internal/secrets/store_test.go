@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := base64.StdEncoding.DecodeString(encoded)
+	return key
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	key := newKey(t)
+	store, err := Open(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("ssh", "analog"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "analog") {
+		t.Fatalf("plaintext in store file: %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("store mode %v, want 0600", info.Mode().Perm())
+	}
+
+	reopened, err := Open(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Get("ssh"); err != nil || got != "analog" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	if _, err := reopened.Get("web"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(web) error = %v, want ErrNotFound", err)
+	}
+
+	wrong, _ := Open(path, newKey(t))
+	if _, err := wrong.Get("ssh"); err == nil {
+		t.Fatal("decrypted with the wrong key")
+	}
+}
+
+func TestStoreRejectsSwappedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	key := newKey(t)
+	store, _ := Open(path, key)
+	_ = store.Set("a", "one")
+	_ = store.Set("b", "two")
+	_ = store.Save()
+
+	var file storeFile
+	data, _ := os.ReadFile(path)
+	_ = json.Unmarshal(data, &file)
+	file.Secrets["a"], file.Secrets["b"] = file.Secrets["b"], file.Secrets["a"]
+	data, _ = json.Marshal(file)
+	_ = os.WriteFile(path, data, 0o600)
+
+	swapped, _ := Open(path, key)
+	if _, err := swapped.Get("a"); err == nil {
+		t.Fatal("swapped entry accepted")
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+	good, _ := GenerateKey()
+	goodPath := filepath.Join(dir, "good.key")
+	_ = os.WriteFile(goodPath, []byte(good+"\n"), 0o600)
+	shortPath := filepath.Join(dir, "short.key")
+	_ = os.WriteFile(shortPath, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0o600)
+
+	tests := []struct {
+		name    string
+		path    string
+		env     string
+		wantErr bool
+	}{
+		{"file", goodPath, "", false},
+		{"env", "", good, false},
+		{"none", "", "", true},
+		{"short", shortPath, "", true},
+		{"missing file", filepath.Join(dir, "missing"), good, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(KeyEnvVar, tt.env)
+			key, err := LoadKey(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(key) != keySize {
+				t.Fatalf("key length %d", len(key))
+			}
+		})
+	}
+}
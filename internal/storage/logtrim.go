@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// trimLog drops JSON-lines entries older than the policy's age and then the
+// oldest entries until the log is within logTrimTarget of its quota. Entries
+// are dated by their "timestamp" or "time" field; undated entries only go to
+// meet the quota. The log is rewritten through a temporary file and renamed
+// into place, so a reader never sees a partial log. Writers must reopen the
+// file for each entry and hold the artifact's Lock while appending.
+func trimLog(path string, p Policy, now time.Time) (lines int, removed int64, err error) {
+	if p.MaxAge <= 0 && p.MaxBytes <= 0 {
+		return 0, 0, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if p.MaxBytes > 0 && int64(len(data)) <= p.MaxBytes && (p.MaxAge <= 0 || !oldestBefore(data, now.Add(-p.MaxAge))) {
+		return 0, 0, nil
+	}
+
+	entries := splitLines(data)
+	start := 0
+	if p.MaxAge > 0 {
+		cutoff := now.Add(-p.MaxAge)
+		for start < len(entries) {
+			ts, ok := entryTime(entries[start])
+			if !ok || !ts.Before(cutoff) {
+				break
+			}
+			start++
+		}
+	}
+	size := int64(len(data))
+	for _, e := range entries[:start] {
+		size -= int64(len(e))
+	}
+	if p.MaxBytes > 0 && size > p.MaxBytes {
+		target := int64(float64(p.MaxBytes) * logTrimTarget)
+		for start < len(entries) && size > target {
+			size -= int64(len(entries[start]))
+			start++
+		}
+	}
+	if start == 0 {
+		return 0, 0, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".trim-*")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(tmp.Name())
+	for _, e := range entries[start:] {
+		if _, err := tmp.Write(e); err != nil {
+			tmp.Close()
+			return 0, 0, err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, 0, err
+	}
+	if info, statErr := os.Stat(path); statErr == nil {
+		_ = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, 0, err
+	}
+	return start, int64(len(data)) - size, nil
+}
+
+// splitLines splits data after each newline, keeping the newlines.
+func splitLines(data []byte) [][]byte {
+	var out [][]byte
+	r := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			out = append(out, line)
+		}
+		if err == io.EOF {
+			return out
+		}
+	}
+}
+
+// oldestBefore reports whether the first dated entry of data is older than
+// cutoff.
+func oldestBefore(data []byte, cutoff time.Time) bool {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	ts, ok := entryTime(line)
+	return ok && ts.Before(cutoff)
+}
+
+// entryTime returns the time of a JSON-lines entry.
+func entryTime(line []byte) (time.Time, bool) {
+	var entry struct {
+		Timestamp time.Time `json:"timestamp"`
+		Time      time.Time `json:"time"`
+	}
+	if json.Unmarshal(line, &entry) != nil {
+		return time.Time{}, false
+	}
+	if !entry.Timestamp.IsZero() {
+		return entry.Timestamp, true
+	}
+	return entry.Time, !entry.Time.IsZero()
+}
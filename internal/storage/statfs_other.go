@@ -0,0 +1,14 @@
+//go:build !(linux || darwin)
+
+package storage
+
+type fsUsage struct {
+	key         uint64
+	total, free uint64
+}
+
+// filesystemUsage is not available on this platform; /api/storage lists no
+// filesystems.
+func filesystemUsage(string) (fsUsage, bool) {
+	return fsUsage{}, false
+}
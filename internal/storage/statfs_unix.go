@@ -0,0 +1,29 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+type fsUsage struct {
+	key         uint64 // filesystem identity, to report each once
+	total, free uint64
+}
+
+// filesystemUsage returns the capacity of the filesystem holding path.
+func filesystemUsage(path string) (fsUsage, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return fsUsage{}, false
+	}
+	var key uint64
+	if info, err := os.Stat(path); err == nil {
+		if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+			key = uint64(sys.Dev)
+		}
+	}
+	bsize := uint64(st.Bsize)
+	return fsUsage{key: key, total: uint64(st.Blocks) * bsize, free: uint64(st.Bavail) * bsize}, true
+}
@@ -0,0 +1,315 @@
+// Package storage keeps persistent artifacts (IQ captures, logs, exports)
+// within a retention policy, so a headless unit does not fill its SD card,
+// and reports their disk usage for /api/storage.
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// Kinds of artifact.
+const (
+	KindDir   = "dir"   // directory of files, pruned oldest first
+	KindLog   = "log"   // JSON-lines log, trimmed from the oldest line
+	KindFixed = "fixed" // self-bounded file (IQ ring, journal); reported only
+)
+
+// logTrimTarget is the share of MaxBytes a log is trimmed to, so it is not
+// rewritten on every pass once it reaches the quota.
+const logTrimTarget = 0.75
+
+// Policy bounds an artifact. Zero values do not limit.
+type Policy struct {
+	MaxAge   time.Duration
+	MaxBytes int64
+}
+
+// Artifact is one managed path.
+type Artifact struct {
+	Name   string
+	Path   string
+	Kind   string
+	Policy Policy
+	// Lock, when set, is held while a log is trimmed. A writer in this
+	// process that appends under the same lock cannot have its entries
+	// dropped by the rewrite.
+	Lock sync.Locker
+}
+
+// Usage is the state of one artifact, as returned by /api/storage.
+type Usage struct {
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	Kind          string    `json:"kind"`
+	Bytes         int64     `json:"bytes"`
+	Files         int       `json:"files"`
+	Oldest        time.Time `json:"oldest,omitzero"`
+	MaxAgeSeconds float64   `json:"maxAgeSeconds,omitempty"`
+	MaxBytes      int64     `json:"maxBytes,omitempty"`
+	PrunedFiles   int       `json:"prunedFiles,omitempty"` // files or log lines removed since start
+	PrunedBytes   int64     `json:"prunedBytes,omitempty"`
+	LastPrune     time.Time `json:"lastPrune,omitzero"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Filesystem is the capacity of a filesystem holding artifacts.
+type Filesystem struct {
+	Path       string `json:"path"`
+	TotalBytes uint64 `json:"totalBytes"`
+	FreeBytes  uint64 `json:"freeBytes"`
+}
+
+// Report is the /api/storage response.
+type Report struct {
+	Timestamp   time.Time    `json:"timestamp"`
+	Artifacts   []Usage      `json:"artifacts"`
+	Filesystems []Filesystem `json:"filesystems"`
+}
+
+// Manager applies retention policies to artifacts.
+type Manager struct {
+	logger    logging.Logger
+	now       func() time.Time
+	artifacts []Artifact
+
+	mu    sync.Mutex
+	stats map[string]*pruneStats
+}
+
+type pruneStats struct {
+	files int
+	bytes int64
+	last  time.Time
+	err   error
+}
+
+// New manages artifacts. Artifacts with an empty path are skipped.
+func New(artifacts []Artifact, logger logging.Logger) *Manager {
+	if logger == nil {
+		logger = logging.Default()
+	}
+	m := &Manager{
+		logger: logger.With(logging.Field{Key: "subsystem", Value: "storage"}),
+		now:    time.Now,
+		stats:  make(map[string]*pruneStats),
+	}
+	for _, a := range artifacts {
+		if a.Path == "" {
+			continue
+		}
+		m.artifacts = append(m.artifacts, a)
+		m.stats[a.Name] = &pruneStats{}
+	}
+	return m
+}
+
+// Run prunes at startup and then every interval until ctx is done.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	m.Prune()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Prune()
+		}
+	}
+}
+
+// Prune applies every policy once. Errors are logged and kept for the
+// usage report.
+func (m *Manager) Prune() {
+	now := m.now()
+	for _, a := range m.artifacts {
+		var files int
+		var bytes int64
+		var err error
+		switch a.Kind {
+		case KindDir:
+			files, bytes, err = pruneDir(a.Path, a.Policy, now)
+		case KindLog:
+			if a.Lock != nil {
+				a.Lock.Lock()
+			}
+			files, bytes, err = trimLog(a.Path, a.Policy, now)
+			if a.Lock != nil {
+				a.Lock.Unlock()
+			}
+		default:
+			continue
+		}
+		if files > 0 {
+			m.logger.Info("pruned", logging.Field{Key: "artifact", Value: a.Name},
+				logging.Field{Key: "removed", Value: files}, logging.Field{Key: "bytes", Value: bytes})
+		}
+		if err != nil {
+			m.logger.Warn("prune failed", logging.Field{Key: "artifact", Value: a.Name}, logging.Field{Key: "error", Value: err})
+		}
+		m.mu.Lock()
+		s := m.stats[a.Name]
+		s.files += files
+		s.bytes += bytes
+		s.last, s.err = now, err
+		m.mu.Unlock()
+	}
+}
+
+// Usage measures every artifact and the filesystems holding them.
+func (m *Manager) Usage() Report {
+	report := Report{Timestamp: m.now(), Artifacts: make([]Usage, 0, len(m.artifacts)), Filesystems: make([]Filesystem, 0)}
+	seen := make(map[uint64]bool)
+	for _, a := range m.artifacts {
+		u := Usage{Name: a.Name, Path: a.Path, Kind: a.Kind, MaxAgeSeconds: a.Policy.MaxAge.Seconds(), MaxBytes: a.Policy.MaxBytes}
+		files, err := listFiles(a.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			u.Error = err.Error()
+		}
+		for _, f := range files {
+			u.Bytes += f.size
+			u.Files++
+			if u.Oldest.IsZero() || f.mod.Before(u.Oldest) {
+				u.Oldest = f.mod
+			}
+		}
+		m.mu.Lock()
+		s := m.stats[a.Name]
+		u.PrunedFiles, u.PrunedBytes, u.LastPrune = s.files, s.bytes, s.last
+		if s.err != nil && u.Error == "" {
+			u.Error = s.err.Error()
+		}
+		m.mu.Unlock()
+		report.Artifacts = append(report.Artifacts, u)
+
+		dir := a.Path
+		if a.Kind != KindDir {
+			dir = filepath.Dir(a.Path)
+		}
+		if fsys, ok := filesystemUsage(dir); ok && !seen[fsys.key] {
+			seen[fsys.key] = true
+			report.Filesystems = append(report.Filesystems, Filesystem{Path: dir, TotalBytes: fsys.total, FreeBytes: fsys.free})
+		}
+	}
+	return report
+}
+
+// ServeHTTP serves the usage report.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m.Usage())
+}
+
+type fileInfo struct {
+	path string
+	size int64
+	mod  time.Time
+}
+
+// listFiles returns the regular files at path: the file itself, or every
+// file below a directory.
+func listFiles(path string) ([]fileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []fileInfo{{path, info.Size(), info.ModTime()}}, nil
+	}
+	var out []fileInfo
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed meanwhile
+		}
+		out = append(out, fileInfo{p, info.Size(), info.ModTime()})
+		return nil
+	})
+	return out, err
+}
+
+// fileGroup is a set of files that belong together, such as the
+// .sigmf-meta and .sigmf-data halves of a capture; they are pruned together.
+type fileGroup struct {
+	files []fileInfo
+	size  int64
+	mod   time.Time // newest member
+}
+
+// pruneDir removes file groups older than the policy's age and then the
+// oldest groups until the directory fits its quota.
+func pruneDir(dir string, p Policy, now time.Time) (files int, bytes int64, err error) {
+	if p.MaxAge <= 0 && p.MaxBytes <= 0 {
+		return 0, 0, nil
+	}
+	list, err := listFiles(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	groups := make(map[string]*fileGroup)
+	var total int64
+	for _, f := range list {
+		key := strings.TrimSuffix(f.path, filepath.Ext(f.path))
+		g := groups[key]
+		if g == nil {
+			g = &fileGroup{}
+			groups[key] = g
+		}
+		g.files = append(g.files, f)
+		g.size += f.size
+		if f.mod.After(g.mod) {
+			g.mod = f.mod
+		}
+		total += f.size
+	}
+	ordered := make([]*fileGroup, 0, len(groups))
+	for _, g := range groups {
+		ordered = append(ordered, g)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].mod.Before(ordered[j].mod) })
+
+	for _, g := range ordered {
+		expired := p.MaxAge > 0 && now.Sub(g.mod) > p.MaxAge
+		over := p.MaxBytes > 0 && total > p.MaxBytes
+		if !expired && !over {
+			break
+		}
+		for _, f := range g.files {
+			if rmErr := os.Remove(f.path); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) {
+				err = errors.Join(err, rmErr)
+				continue
+			}
+			files++
+			bytes += f.size
+			total -= f.size
+		}
+	}
+	return files, bytes, err
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, size int, mod time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func remaining(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestPruneDir(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"no policy", Policy{}, "a.2026-02-01T10.sigmf-data a.2026-02-01T10.sigmf-meta b.sigmf-data b.sigmf-meta c.csv"},
+		{"max age", Policy{MaxAge: 48 * time.Hour}, "b.sigmf-data b.sigmf-meta c.csv"},
+		{"quota keeps newest", Policy{MaxBytes: 250}, "c.csv"},
+		{"quota fits", Policy{MaxBytes: 1000}, "a.2026-02-01T10.sigmf-data a.2026-02-01T10.sigmf-meta b.sigmf-data b.sigmf-meta c.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// Capture halves are pruned together even when their times differ.
+			writeFile(t, filepath.Join(dir, "a.2026-02-01T10.sigmf-meta"), 10, now.Add(-30*24*time.Hour))
+			writeFile(t, filepath.Join(dir, "a.2026-02-01T10.sigmf-data"), 100, now.Add(-29*24*time.Hour))
+			writeFile(t, filepath.Join(dir, "b.sigmf-meta"), 10, now.Add(-time.Hour))
+			writeFile(t, filepath.Join(dir, "b.sigmf-data"), 100, now.Add(-time.Hour))
+			writeFile(t, filepath.Join(dir, "c.csv"), 200, now.Add(-time.Minute))
+
+			if _, _, err := pruneDir(dir, tt.policy, now); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(remaining(t, dir), " "); got != tt.want {
+				t.Fatalf("remaining = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneDirMissing(t *testing.T) {
+	files, _, err := pruneDir(filepath.Join(t.TempDir(), "missing"), Policy{MaxAge: time.Hour}, time.Now())
+	if err != nil || files != 0 {
+		t.Fatalf("pruneDir(missing) = %d, %v", files, err)
+	}
+}
+
+func TestTrimLog(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var sb strings.Builder
+	for i := 10; i > 0; i-- {
+		fmt.Fprintf(&sb, `{"timestamp":%q,"n":%d}`+"\n", now.Add(-time.Duration(i)*time.Hour).Format(time.RFC3339), 10-i)
+	}
+	log := sb.String()
+	lineLen := int64(len(strings.SplitAfter(log, "\n")[0]))
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		wantLines int
+		wantFirst int
+	}{
+		{"no policy", Policy{}, 10, 0},
+		{"max age", Policy{MaxAge: 3*time.Hour + time.Minute}, 3, 7},
+		{"within quota", Policy{MaxBytes: 10 * lineLen}, 10, 0},
+		{"quota trims to target", Policy{MaxBytes: 8 * lineLen}, 6, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			if err := os.WriteFile(path, []byte(log), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := trimLog(path, tt.policy, now); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if len(lines) != tt.wantLines {
+				t.Fatalf("lines = %d, want %d", len(lines), tt.wantLines)
+			}
+			var first struct{ N int }
+			if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.N != tt.wantFirst {
+				t.Fatalf("first entry = %d (%v), want %d", first.N, err, tt.wantFirst)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+				t.Fatalf("mode = %v, want 0600", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+// countingLocker counts how often Prune took it.
+type countingLocker struct {
+	sync.Mutex
+	locks int
+}
+
+func (l *countingLocker) Lock() {
+	l.Mutex.Lock()
+	l.locks++
+}
+
+func TestPruneLogHoldsLock(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	entry := fmt.Sprintf(`{"timestamp":%q}`+"\n", now.Add(-48*time.Hour).Format(time.RFC3339))
+	if err := os.WriteFile(path, []byte(entry), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var lock countingLocker
+	New([]Artifact{{Name: "audit", Path: path, Kind: KindLog, Policy: Policy{MaxAge: 24 * time.Hour}, Lock: &lock}}, nil).Prune()
+	if lock.locks != 1 || !lock.TryLock() {
+		t.Fatalf("lock taken %d times or left held", lock.locks)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Fatalf("log not trimmed: %q", data)
+	}
+}
+
+func TestManagerUsage(t *testing.T) {
+	dir := t.TempDir()
+	captures := filepath.Join(dir, "captures")
+	if err := os.Mkdir(captures, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	writeFile(t, filepath.Join(captures, "old.sigmf-data"), 100, now.Add(-48*time.Hour))
+	writeFile(t, filepath.Join(captures, "new.sigmf-data"), 50, now)
+
+	m := New([]Artifact{
+		{Name: "captures", Path: captures, Kind: KindDir, Policy: Policy{MaxAge: 24 * time.Hour}},
+		{Name: "ring", Path: filepath.Join(dir, "missing.iq"), Kind: KindFixed},
+		{Name: "unset", Kind: KindLog},
+	}, nil)
+	m.Prune()
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/storage", nil))
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Artifacts) != 2 {
+		t.Fatalf("artifacts = %d, want 2", len(report.Artifacts))
+	}
+	got := report.Artifacts[0]
+	if got.Files != 1 || got.Bytes != 50 || got.PrunedFiles != 1 || got.PrunedBytes != 100 || got.MaxAgeSeconds != 86400 {
+		t.Fatalf("captures usage = %+v", got)
+	}
+	if ring := report.Artifacts[1]; ring.Files != 0 || ring.Error != "" {
+		t.Fatalf("missing artifact usage = %+v", ring)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/storage", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d", rec.Code)
+	}
+}
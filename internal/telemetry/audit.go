@@ -0,0 +1,238 @@
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// auditMemoryLimit is the number of audit entries kept in memory and served
+// by /api/audit. The file keeps every entry unless a retention policy trims
+// it under AuditLocker.
+const auditMemoryLimit = 500
+
+// AuditEntry records one configuration change made through the API.
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Setting   string    `json:"setting"`
+	Device    string    `json:"device,omitempty"`
+	Old       any       `json:"old"`
+	New       any       `json:"new"`
+	Source    string    `json:"source"`
+	User      string    `json:"user,omitempty"`
+}
+
+// auditLog keeps recent entries and appends every entry to a JSON-lines file
+// when a path is configured.
+type auditLog struct {
+	mu      sync.Mutex
+	path    string
+	entries []AuditEntry
+}
+
+// SetAuditLog enables persistent auditing to path (one JSON object per line)
+// and loads the most recent existing entries. An empty path keeps the log in
+// memory only.
+func (h *Hub) SetAuditLog(path string) error {
+	entries, err := loadAuditEntries(path)
+	if err != nil {
+		return err
+	}
+	h.audit.mu.Lock()
+	h.audit.path = path
+	h.audit.entries = append(entries, h.audit.entries...)
+	h.audit.trim()
+	h.audit.mu.Unlock()
+	return nil
+}
+
+// AuditLocker returns the lock entries are appended to the audit file under.
+// Hold it while rewriting the file, as retention does when trimming it; each
+// entry reopens the file, so it may be replaced meanwhile.
+func (h *Hub) AuditLocker() sync.Locker {
+	return &h.audit.mu
+}
+
+// AuditLog returns the most recent audit entries, oldest first.
+func (h *Hub) AuditLog() []AuditEntry {
+	h.audit.mu.Lock()
+	defer h.audit.mu.Unlock()
+	return append([]AuditEntry(nil), h.audit.entries...)
+}
+
+// recordAudit logs a change of setting made by the client behind r. Changes
+// that leave the value untouched are ignored.
+func (h *Hub) recordAudit(r *http.Request, setting string, before, after any) {
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	source, user := requestIdentity(r)
+	entry := AuditEntry{
+		Timestamp: h.now(),
+		Setting:   setting,
+		Device:    auditDevice(r),
+		Old:       before,
+		New:       after,
+		Source:    source,
+		User:      user,
+	}
+
+	h.audit.mu.Lock()
+	h.audit.entries = append(h.audit.entries, entry)
+	h.audit.trim()
+	path := h.audit.path
+	var err error
+	if path != "" {
+		err = appendAuditEntry(path, entry)
+	}
+	h.audit.mu.Unlock()
+
+	if err != nil {
+		h.logger.Warn("failed to write audit log", logging.Field{Key: "error", Value: err})
+	}
+}
+
+func (a *auditLog) trim() {
+	if len(a.entries) > auditMemoryLimit {
+		a.entries = a.entries[len(a.entries)-auditMemoryLimit:]
+	}
+}
+
+// recordConfigAudit adds one audit entry per changed Config field, keyed by
+// its JSON name.
+func (h *Hub) recordConfigAudit(r *http.Request, before, after Config) {
+	oldFields, newFields := configFields(before), configFields(after)
+	keys := make([]string, 0, len(newFields))
+	for key := range newFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h.recordAudit(r, "config."+key, oldFields[key], newFields[key])
+	}
+}
+
+func configFields(cfg Config) map[string]any {
+	data, _ := json.Marshal(cfg)
+	fields := make(map[string]any)
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// auditDevice returns the ?device= of r, or "" for changes made outside HTTP.
+func auditDevice(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return parseDevice(r)
+}
+
+// requestIdentity returns the client address (honouring X-Forwarded-For set
+// by a reverse proxy) and the authenticated user, if any.
+func requestIdentity(r *http.Request) (source, user string) {
+	if r == nil {
+		return "local", ""
+	}
+	source = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		first, _, _ := strings.Cut(fwd, ",")
+		source = strings.TrimSpace(first)
+	}
+	if name, _, ok := r.BasicAuth(); ok {
+		user = name
+	} else {
+		user = r.Header.Get("X-Forwarded-User")
+	}
+	return source, user
+}
+
+func appendAuditEntry(path string, entry AuditEntry) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		_, err = f.Write(append(data, '\n'))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// loadAuditEntries reads the tail of an audit file. Malformed lines (for
+// example a partial write at shutdown) are skipped.
+func loadAuditEntries(path string) ([]AuditEntry, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	if len(entries) > auditMemoryLimit {
+		entries = entries[len(entries)-auditMemoryLimit:]
+	}
+	return entries, nil
+}
+
+// handleAudit returns recent audit entries, oldest first. ?setting= filters by
+// setting prefix and ?limit= keeps only the newest entries.
+func (h *Hub) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	limit, err := parseNonNegative(r.URL.Query().Get("limit"), "limit")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	prefix := r.URL.Query().Get("setting")
+	device := parseDevice(r)
+
+	out := make([]AuditEntry, 0)
+	for _, entry := range h.AuditLog() {
+		if strings.HasPrefix(entry.Setting, prefix) && (device == "" || entry.Device == device) {
+			out = append(out, entry)
+		}
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestRequestIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(*http.Request)
+		wantSource string
+		wantUser   string
+	}{
+		{name: "remote addr", setup: func(*http.Request) {}, wantSource: "192.0.2.1"},
+		{name: "forwarded", setup: func(r *http.Request) {
+			r.Header.Set("X-Forwarded-For", "198.51.100.7, 10.0.0.1")
+			r.Header.Set("X-Forwarded-User", "alice")
+		}, wantSource: "198.51.100.7", wantUser: "alice"},
+		{name: "basic auth", setup: func(r *http.Request) { r.SetBasicAuth("bob", "secret") }, wantSource: "192.0.2.1", wantUser: "bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/config/update", nil)
+			tt.setup(r)
+			source, user := requestIdentity(r)
+			if source != tt.wantSource || user != tt.wantUser {
+				t.Fatalf("got %q/%q, want %q/%q", source, user, tt.wantSource, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestConfigChangesAreAuditedAndPersisted(t *testing.T) {
+	t.Chdir(t.TempDir())
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	hub := newTestHub()
+	if err := hub.SetAuditLog(path); err != nil {
+		t.Fatalf("SetAuditLog: %v", err)
+	}
+
+	cfg := hub.ConfigDocument()
+	oldLimit := cfg.HistoryLimit
+	cfg.HistoryLimit = oldLimit + 10
+	body, _ := json.Marshal(cfg)
+	req := httptest.NewRequest(http.MethodPost, "/api/config/update", strings.NewReader(string(body)))
+	req.SetBasicAuth("alice", "pw")
+	rr := httptest.NewRecorder()
+	hub.handleSetConfig(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("config update failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	entries := hub.AuditLog()
+	if len(entries) != 1 {
+		t.Fatalf("expected one audit entry, got %+v", entries)
+	}
+	entry := entries[0]
+	if entry.Setting != "config.historyLimit" || entry.User != "alice" || entry.Source != "192.0.2.1" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if entry.Old != float64(oldLimit) || entry.New != float64(oldLimit+10) {
+		t.Fatalf("unexpected old/new values %+v", entry)
+	}
+
+	reloaded := newTestHub()
+	if err := reloaded.SetAuditLog(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.AuditLog(); len(got) != 1 || got[0].Setting != entry.Setting {
+		t.Fatalf("expected persisted entry, got %+v", got)
+	}
+}
+
+func TestHandleAuditFilters(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), sdr.NewMock(), nil)
+	for _, delta := range []string{"10", "20"} {
+		rr := httptest.NewRecorder()
+		ws.handleMockAngle(rr, httptest.NewRequest(http.MethodPost, "/api/mock/angle", strings.NewReader(`{"phaseDelta":`+delta+`}`)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("mock angle: %d", rr.Code)
+		}
+	}
+	ws.hub.recordAudit(httptest.NewRequest(http.MethodPost, "/", nil), "config.debugMode", false, true)
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{url: "/api/audit", want: []string{"mock.phaseDelta", "mock.phaseDelta", "config.debugMode"}},
+		{url: "/api/audit?setting=mock.", want: []string{"mock.phaseDelta", "mock.phaseDelta"}},
+		{url: "/api/audit?limit=1", want: []string{"config.debugMode"}},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		ws.hub.handleAudit(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		var out []AuditEntry
+		if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+			t.Fatalf("%s: decode: %v", tt.url, err)
+		}
+		if len(out) != len(tt.want) {
+			t.Fatalf("%s: got %+v, want settings %v", tt.url, out, tt.want)
+		}
+		for i := range out {
+			if out[i].Setting != tt.want[i] {
+				t.Fatalf("%s: got %+v, want settings %v", tt.url, out, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltSamples = []byte("samples")
+	boltEvents  = []byte("events")
+)
+
+// boltStore keeps samples and events in a bbolt file, one bucket per kind,
+// keyed by timestamp and a sequence so that equal timestamps keep their
+// append order. Values are the JSON of the sample or event. Each Append is
+// one transaction, synced before it returns.
+type boltStore struct {
+	db *bolt.DB
+}
+
+func openBoltStore(dsn string) (Store, error) {
+	path := strings.TrimPrefix(dsn, "bolt:")
+	if path == "" {
+		return nil, fmt.Errorf("store %q: want bolt:<file>", dsn)
+	}
+	return NewBoltStore(path)
+}
+
+// NewBoltStore returns a store in the bbolt file at path, creating it if
+// needed. The file is locked while the store is open; a second process
+// opening it fails after a second instead of waiting.
+func NewBoltStore(path string) (Store, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltSamples, boltEvents} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("open bolt store: %w", err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+// boltTimeKey encodes t so that keys sort in time order, including times
+// before 1970.
+func boltTimeKey(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano())^(1<<63))
+}
+
+// boltKeyTime decodes the time of a key written by Append.
+func boltKeyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])^(1<<63))).UTC()
+}
+
+func (b *boltStore) Append(samples []MultiTrackSample, events []DiagnosticEvent) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		put := func(bucket *bolt.Bucket, t time.Time, v any) error {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			return bucket.Put(binary.BigEndian.AppendUint64(boltTimeKey(t), seq), data)
+		}
+		bucket := tx.Bucket(boltSamples)
+		for _, s := range samples {
+			if err := put(bucket, s.Timestamp, s); err != nil {
+				return err
+			}
+		}
+		bucket = tx.Bucket(boltEvents)
+		for _, e := range events {
+			if err := put(bucket, e.Timestamp, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// scan calls fn with the values of bucket in q's time range, oldest first,
+// until it returns false or an error.
+func (b *boltStore) scan(bucket []byte, q StoreQuery, fn func([]byte) (bool, error)) error {
+	var until []byte
+	if !q.Until.IsZero() {
+		until = boltTimeKey(q.Until)
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		k, v := c.First()
+		if !q.Since.IsZero() {
+			k, v = c.Seek(boltTimeKey(q.Since))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if until != nil && bytes.Compare(k[:8], until) >= 0 {
+				return nil
+			}
+			more, err := fn(v)
+			if err != nil || !more {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltStore) Samples(q StoreQuery) ([]MultiTrackSample, error) {
+	var out []MultiTrackSample
+	err := b.scan(boltSamples, q, func(data []byte) (bool, error) {
+		var s MultiTrackSample
+		if err := json.Unmarshal(data, &s); err != nil {
+			return false, err
+		}
+		if s, ok := q.sample(s); ok {
+			out = append(out, s)
+		}
+		return !q.full(len(out)), nil
+	})
+	return out, err
+}
+
+func (b *boltStore) Events(q StoreQuery) ([]DiagnosticEvent, error) {
+	var out []DiagnosticEvent
+	err := b.scan(boltEvents, q, func(data []byte) (bool, error) {
+		var e DiagnosticEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return false, err
+		}
+		out = append(out, e)
+		return !q.full(len(out)), nil
+	})
+	return out, err
+}
+
+// Span reads the first and last key of each bucket.
+func (b *boltStore) Span() (first, last time.Time, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltSamples, boltEvents} {
+			c := tx.Bucket(name).Cursor()
+			if k, _ := c.First(); k != nil {
+				first, last = widenSpan(first, last, boltKeyTime(k))
+			}
+			if k, _ := c.Last(); k != nil {
+				first, last = widenSpan(first, last, boltKeyTime(k))
+			}
+		}
+		return nil
+	})
+	return first, last, err
+}
+
+func (b *boltStore) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/clock"
+)
+
+// clockStepThreshold is how far wall-clock time may drift from monotonic time
+// between two observations before the wall clock counts as stepped, as when
+// NTP corrects a unit that booted with a wrong RTC.
+const clockStepThreshold = time.Second
+
+// processStart is the origin of MonotonicMs.
+var processStart = time.Now()
+
+// MonotonicMs returns the milliseconds from process start to t on the
+// monotonic clock, which wall-clock steps do not affect. t must carry a
+// monotonic reading, as values from time.Now in this process do; otherwise
+// the wall-clock difference is returned.
+func MonotonicMs(t time.Time) float64 {
+	return float64(t.Sub(processStart)) / float64(time.Millisecond)
+}
+
+// monotonicAt returns MonotonicMs of t, dated by its age at now. This holds
+// for timestamps from another host or decoded from JSON, which carry no
+// monotonic reading, as long as the wall clock did not step between t and now.
+func monotonicAt(t, now time.Time) float64 {
+	return MonotonicMs(now) - float64(now.Sub(t))/float64(time.Millisecond)
+}
+
+// clockWatch detects wall-clock steps between successive time.Now readings.
+type clockWatch struct {
+	last     time.Time
+	steps    int
+	lastStep time.Duration
+}
+
+// observe returns how far the wall clock was stepped since the previous
+// observation, or 0 if it kept pace with the monotonic clock.
+func (c *clockWatch) observe(now time.Time) time.Duration {
+	prev := c.last
+	c.last = now
+	if prev.IsZero() {
+		return 0
+	}
+	// Round(0) strips the monotonic reading, so the first difference is
+	// taken on the wall clock.
+	return c.check(now.Round(0).Sub(prev.Round(0)), now.Sub(prev))
+}
+
+// check compares the wall-clock and monotonic time elapsed between two
+// observations and records a step.
+func (c *clockWatch) check(wall, mono time.Duration) time.Duration {
+	step := wall - mono
+	if step.Abs() < clockStepThreshold {
+		return 0
+	}
+	c.steps++
+	c.lastStep = step
+	return step
+}
+
+// SetClock replaces the clock that dates samples, events, journal, config
+// and audit entries, ages tracks, sectors, heading fixes and health checks,
+// and measures uptime, so tests and simulations can run the hub on simulated
+// time. Uptime restarts at c's current time. Process CPU figures and the
+// config, leak and journal tickers keep real time. Call it before the hub is
+// shared.
+func (h *Hub) SetClock(c clock.Clock) {
+	if c == nil {
+		c = clock.Real
+	}
+	h.timeSource = c
+	h.startTime = c.Now()
+}
+
+// now returns the current time on the hub's clock.
+func (h *Hub) now() time.Time {
+	return h.timeSource.Now()
+}
+
+// observeClockLocked checks the wall clock at now and logs a step in the event
+// log. The caller holds h.mu.
+func (h *Hub) observeClockLocked(now time.Time) bool {
+	step := h.clock.observe(now)
+	if step == 0 {
+		return false
+	}
+	sign := "+"
+	if step < 0 {
+		sign = "-"
+	}
+	h.recordEventLocked("warn", fmt.Sprintf("wall clock stepped by %s%s; earlier samples keep their old timestamps", sign, step.Abs().Round(time.Millisecond)))
+	return true
+}
+
+// inWindow reports whether sample is less than window old at now.
+// Samples recorded by this process are aged on the monotonic clock, so a
+// wall-clock step does not shift the window; restored samples by their
+// timestamp.
+func inWindow(sample MultiTrackSample, now time.Time, window time.Duration) bool {
+	if sample.MonotonicMs != 0 {
+		return MonotonicMs(now)-sample.MonotonicMs <= float64(window)/float64(time.Millisecond)
+	}
+	return !sample.Timestamp.Before(now.Add(-window))
+}
@@ -0,0 +1,149 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/clock"
+)
+
+func TestClockWatchCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		wall, mono time.Duration
+		want       time.Duration
+	}{
+		{"in step", time.Second, time.Second, 0},
+		{"drift below threshold", 1500 * time.Millisecond, time.Second, 0},
+		{"NTP step forward", 3*time.Hour + time.Second, time.Second, 3 * time.Hour},
+		{"step back", -time.Minute, time.Second, -time.Minute - time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c clockWatch
+			if got := c.check(tt.wall, tt.mono); got != tt.want {
+				t.Fatalf("check(%s, %s) = %s, want %s", tt.wall, tt.mono, got, tt.want)
+			}
+			if steps := c.steps; (steps == 1) != (tt.want != 0) {
+				t.Fatalf("steps = %d", steps)
+			}
+		})
+	}
+}
+
+func TestClockWatchObserve(t *testing.T) {
+	var c clockWatch
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if step := c.observe(now.Add(time.Duration(i) * time.Second)); step != 0 {
+			t.Fatalf("observation %d reported step %s", i, step)
+		}
+	}
+}
+
+func TestMonotonicAt(t *testing.T) {
+	now := time.Now()
+	// A remote timestamp without monotonic reading, 2 s before receipt.
+	remote := now.Add(-2 * time.Second).Round(0)
+	got := monotonicAt(remote, now)
+	if want := MonotonicMs(now) - 2000; got < want-1 || got > want+1 {
+		t.Fatalf("monotonicAt = %.1f, want %.1f", got, want)
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		sample MultiTrackSample
+		want   bool
+	}{
+		// Monotonic age wins over a wall clock stepped since the sample.
+		{"recent, wall clock stepped", MultiTrackSample{Timestamp: now.Add(-3 * time.Hour), MonotonicMs: MonotonicMs(now) - 1000}, true},
+		{"old by monotonic", MultiTrackSample{Timestamp: now, MonotonicMs: MonotonicMs(now) - 120000}, false},
+		{"restored, recent", MultiTrackSample{Timestamp: now.Add(-30 * time.Second)}, true},
+		{"restored, old", MultiTrackSample{Timestamp: now.Add(-2 * time.Minute)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inWindow(tt.sample, now, time.Minute); got != tt.want {
+				t.Fatalf("inWindow = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHubStampsMonotonicTime(t *testing.T) {
+	hub := newTestHub()
+	hub.Report(1, -10, 12, 0.9, LockStateTracking, nil)
+	hub.Report(2, -10, 12, 0.9, LockStateTracking, nil)
+	history := hub.History()
+	if len(history) != 2 || history[0].MonotonicMs <= 0 || history[1].MonotonicMs < history[0].MonotonicMs {
+		t.Fatalf("monotonic times = %+v", history)
+	}
+	if history[0].ClockStep || history[1].ClockStep {
+		t.Fatal("clock step reported without a step")
+	}
+
+	hub.mu.Lock()
+	hub.clock.check(time.Hour, 0)
+	hub.mu.Unlock()
+	if m := hub.collectProcessMetrics(); m.ClockSteps != 1 || m.LastClockStep != time.Hour {
+		t.Fatalf("clock steps = %d, last %s", m.ClockSteps, m.LastClockStep)
+	}
+}
+
+func TestHubSetClock(t *testing.T) {
+	hub := newTestHub()
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	hub.SetClock(fake)
+
+	hub.Report(10, 1, 20, 0.9, LockStateTracking, nil)
+	fake.Advance(250 * time.Millisecond)
+	hub.Report(11, 1, 20, 0.9, LockStateTracking, nil)
+	hub.LogEvent("warn", "simulated")
+
+	history := hub.History()
+	if len(history) != 2 {
+		t.Fatalf("history has %d samples, want 2", len(history))
+	}
+	if !history[0].Timestamp.Equal(start) || !history[1].Timestamp.Equal(start.Add(250*time.Millisecond)) {
+		t.Fatalf("sample times %s, %s do not follow the fake clock", history[0].Timestamp, history[1].Timestamp)
+	}
+	events, err := hub.Events("warn", 0)
+	if err != nil || len(events) == 0 {
+		t.Fatalf("events = %v, %v", events, err)
+	}
+	if got := events[len(events)-1].Timestamp; !got.Equal(start.Add(250 * time.Millisecond)) {
+		t.Fatalf("event time %s does not follow the fake clock", got)
+	}
+	hub.recordAudit(httptest.NewRequest(http.MethodPost, "/", nil), "config.debugMode", false, true)
+	if audit := hub.AuditLog(); len(audit) != 1 || !audit[0].Timestamp.Equal(fake.Now()) {
+		t.Fatalf("audit entries %+v do not follow the fake clock", audit)
+	}
+	if m := hub.collectProcessMetrics(); !m.StartTime.Equal(start) || m.Uptime != 250*time.Millisecond {
+		t.Fatalf("start %s, uptime %s do not follow the fake clock", m.StartTime, m.Uptime)
+	}
+
+	// The journal is dated and its restore window measured on the same clock.
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if err := hub.OpenJournal(path, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loadJournal(path)
+	if err != nil || len(entries) != 3 || !entries[0].Time.Equal(fake.Now()) {
+		t.Fatalf("journal entries = %+v, %v", entries, err)
+	}
+	restarted := newTestHub()
+	restarted.SetClock(fake)
+	if err := restarted.OpenJournal(path, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(restarted.History()); got != 2 {
+		t.Fatalf("restored %d samples on the fake clock, want 2", got)
+	}
+}
@@ -0,0 +1,195 @@
+package telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// minCompressBytes is the smallest response body worth compressing.
+const minCompressBytes = 1024
+
+// compressHandler buffers GET/HEAD responses so it can tag them with an ETag,
+// answer If-None-Match with 304 and gzip/deflate the body when the client
+// accepts it. Handlers that flush (SSE streams) switch the writer to
+// pass-through and are sent uncompressed.
+func compressHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		bw := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(bw, r)
+		if bw.streaming {
+			return
+		}
+		bw.finish(r)
+	})
+}
+
+// bufferedResponse collects a response until the handler returns or flushes.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	streaming   bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.streaming {
+		b.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !b.wroteHeader {
+		b.status, b.wroteHeader = status, true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.streaming {
+		return b.ResponseWriter.Write(p)
+	}
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// Flush commits the buffered response and streams everything after it.
+func (b *bufferedResponse) Flush() {
+	flusher, ok := b.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	if !b.streaming {
+		b.streaming = true
+		b.ResponseWriter.WriteHeader(b.status)
+		_, _ = b.ResponseWriter.Write(b.body.Bytes())
+		b.body.Reset()
+	}
+	flusher.Flush()
+}
+
+// finish writes the buffered response, applying ETag and compression to
+// successful responses.
+func (b *bufferedResponse) finish(r *http.Request) {
+	w := b.ResponseWriter
+	body := b.body.Bytes()
+	if b.status != http.StatusOK {
+		w.WriteHeader(b.status)
+		_, _ = w.Write(body)
+		return
+	}
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(body))
+	}
+	if header.Get("ETag") == "" {
+		header.Set("ETag", bodyETag(body))
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), header.Get("ETag")) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	encoding := ""
+	if len(body) >= minCompressBytes && compressible(header.Get("Content-Type")) && header.Get("Content-Encoding") == "" {
+		encoding = acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if encoding == "" {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+		return
+	}
+	compressed, err := compressBody(body, encoding)
+	if err != nil {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+		return
+	}
+	header.Set("Content-Encoding", encoding)
+	header.Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(compressed)
+}
+
+// bodyETag returns a weak validator over the uncompressed body; weak because
+// the encoded bytes differ per content coding.
+func bodyETag(body []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(body)
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// etagMatches applies the weak comparison required for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range []string{"application/json", "text/", "application/javascript", "image/svg+xml"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip and honouring q=0 exclusions.
+func acceptedEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		ok := true
+		if v, found := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); found {
+			q, err := strconv.ParseFloat(v, 64)
+			ok = err == nil && q > 0
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = ok
+	}
+	for _, enc := range []string{"gzip", "deflate"} {
+		if accepted[enc] {
+			return enc
+		}
+	}
+	return ""
+}
+
+func compressBody(body []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	if encoding == "gzip" {
+		zw = gzip.NewWriter(&buf)
+	} else {
+		zw = zlib.NewWriter(&buf)
+	}
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,134 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func jsonHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	})
+}
+
+func TestCompressHandlerEncodings(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("x", 4*minCompressBytes) + `"}`
+	tests := []struct {
+		name     string
+		body     string
+		accept   string
+		wantEnc  string
+		decoder  func(io.Reader) (io.Reader, error)
+		wantVary bool
+	}{
+		{name: "gzip", body: large, accept: "gzip, deflate", wantEnc: "gzip", wantVary: true,
+			decoder: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{name: "deflate", body: large, accept: "deflate, gzip;q=0", wantEnc: "deflate", wantVary: true,
+			decoder: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+		{name: "identity", body: large, accept: "", wantVary: true},
+		{name: "small body", body: `{"ok":true}`, accept: "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/history", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			compressHandler(jsonHandler(tt.body)).ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Content-Encoding"); got != tt.wantEnc {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEnc)
+			}
+			if got := rr.Header().Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Fatalf("Vary = %q", rr.Header().Get("Vary"))
+			}
+			var body io.Reader = rr.Body
+			if tt.decoder != nil {
+				var err error
+				if body, err = tt.decoder(rr.Body); err != nil {
+					t.Fatalf("decoder: %v", err)
+				}
+			}
+			got, err := io.ReadAll(body)
+			if err != nil || string(got) != tt.body {
+				t.Fatalf("body mismatch (err %v)", err)
+			}
+		})
+	}
+}
+
+func TestCompressHandlerETag(t *testing.T) {
+	handler := compressHandler(jsonHandler(`{"angle":12}`))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with ETag, got %d %q", rr.Code, etag)
+	}
+
+	for _, inm := range []string{etag, strings.TrimPrefix(etag, "W/"), `"other", ` + etag, "*"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+		req.Header.Set("If-None-Match", inm)
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+			t.Fatalf("If-None-Match %q: expected empty 304, got %d", inm, rr.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+	req.Header.Set("If-None-Match", `W/"stale"`)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("stale ETag: expected 200, got %d", rr.Code)
+	}
+}
+
+func TestCompressHandlerPassesThroughErrorsAndPosts(t *testing.T) {
+	failing := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSONError(w, http.StatusNotFound, strings.Repeat("missing ", minCompressBytes))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/tracks/x", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	compressHandler(failing).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound || rr.Header().Get("ETag") != "" || rr.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected untouched 404, got %d %v", rr.Code, rr.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/config/update", nil)
+	rr = httptest.NewRecorder()
+	compressHandler(jsonHandler(`{}`)).ServeHTTP(rr, req)
+	if rr.Header().Get("ETag") != "" {
+		t.Fatal("POST responses must not be tagged")
+	}
+}
+
+func TestCompressHandlerStreamsAfterFlush(t *testing.T) {
+	hub := newTestHub()
+	srv := httptest.NewServer(compressHandler(http.HandlerFunc(hub.handleLive)))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if resp.Header.Get("Content-Encoding") != "" || resp.Header.Get("ETag") != "" {
+		t.Fatalf("stream must pass through untouched: %v", resp.Header)
+	}
+}
@@ -0,0 +1,102 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// configRevisionKeep is how many past config revisions are kept to explain a
+// conflicting update.
+const configRevisionKeep = 16
+
+// ConfigDocument is the config as served by GET /api/config: the settings
+// plus the revision an update must name.
+type ConfigDocument struct {
+	Config
+	Revision uint64 `json:"revision"`
+}
+
+// configUpdate is the body of POST /api/config/update. Revision is required
+// and must match the current revision, so two editors cannot silently
+// overwrite each other.
+type configUpdate struct {
+	Config
+	Revision *uint64 `json:"revision"`
+}
+
+// ConfigConflict is the 409 body returned when an update names a stale
+// revision.
+type ConfigConflict struct {
+	Error    string         `json:"error"`
+	Revision uint64         `json:"revision"`
+	Current  Config         `json:"current"`
+	Diff     []ConfigChange `json:"diff"`
+}
+
+// ConfigChange describes one setting of a conflict: its value at the
+// revision the client edited (when still known), now, and in the rejected
+// update.
+type ConfigChange struct {
+	Key     string `json:"key"`
+	Base    any    `json:"base,omitempty"`
+	Current any    `json:"current"`
+	Yours   any    `json:"yours"`
+}
+
+type revisionedConfig struct {
+	revision uint64
+	config   Config
+}
+
+// recordRevisionLocked makes cfg a new config revision. h.mu must be held.
+func (h *Hub) recordRevisionLocked(cfg Config) {
+	h.configRevision++
+	h.configRevisions = append(h.configRevisions, revisionedConfig{revision: h.configRevision, config: cfg})
+	if over := len(h.configRevisions) - configRevisionKeep; over > 0 {
+		h.configRevisions = h.configRevisions[over:]
+	}
+}
+
+// ConfigDocument returns the current config with its revision.
+func (h *Hub) ConfigDocument() ConfigDocument {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return ConfigDocument{Config: h.config, Revision: h.configRevision}
+}
+
+// configConflictLocked describes why the update payload, based on revision
+// base, conflicts with the current config. The diff lists the settings
+// changed since base, with the value the update would have given them. When
+// base is too old to be known it lists every setting the update would change
+// instead. h.mu must be held.
+func (h *Hub) configConflictLocked(base uint64, payload []byte) ConfigConflict {
+	conflict := ConfigConflict{
+		Error:    fmt.Sprintf("config changed since revision %d (now %d); reload and reapply your changes", base, h.configRevision),
+		Revision: h.configRevision,
+		Current:  h.config,
+		Diff:     []ConfigChange{},
+	}
+	var baseFields map[string]any
+	yours := h.config
+	for _, rev := range h.configRevisions {
+		if rev.revision == base {
+			baseFields = configFields(rev.config)
+			yours = rev.config
+		}
+	}
+	_ = json.Unmarshal(payload, &yours)
+	current, mine := configFields(h.config), configFields(yours)
+	for key, value := range current {
+		changed := !reflect.DeepEqual(mine[key], value)
+		if baseFields != nil {
+			changed = !reflect.DeepEqual(baseFields[key], value)
+		}
+		if changed {
+			conflict.Diff = append(conflict.Diff, ConfigChange{Key: key, Base: baseFields[key], Current: value, Yours: mine[key]})
+		}
+	}
+	sort.Slice(conflict.Diff, func(i, j int) bool { return conflict.Diff[i].Key < conflict.Diff[j].Key })
+	return conflict
+}
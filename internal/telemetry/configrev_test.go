@@ -0,0 +1,147 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func postConfig(t *testing.T, hub *Hub, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	rr := httptest.NewRecorder()
+	hub.handleSetConfig(rr, httptest.NewRequest(http.MethodPost, "/api/config/update", strings.NewReader(string(data))))
+	return rr
+}
+
+func TestConfigUpdateRequiresCurrentRevision(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hub := newTestHub()
+
+	rr := httptest.NewRecorder()
+	hub.handleGetConfig(rr, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	var doc ConfigDocument
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil || doc.Revision == 0 {
+		t.Fatalf("GET should return a revision, got %+v (%v)", doc, err)
+	}
+
+	if rr := postConfig(t, hub, doc.Config); rr.Code != http.StatusPreconditionRequired {
+		t.Fatalf("update without revision: got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Two users edit the same revision; the first wins.
+	alice, bob := doc, doc
+	alice.HistoryLimit += 10
+	bob.MaxTracks++
+	rr = postConfig(t, hub, alice)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("first update: got %d %s", rr.Code, rr.Body.String())
+	}
+	var applied ConfigDocument
+	_ = json.NewDecoder(rr.Body).Decode(&applied)
+	if applied.Revision != doc.Revision+1 || applied.HistoryLimit != alice.HistoryLimit {
+		t.Fatalf("unexpected applied document %+v", applied)
+	}
+
+	rr = postConfig(t, hub, bob)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("stale update: got %d %s", rr.Code, rr.Body.String())
+	}
+	var conflict ConfigConflict
+	if err := json.NewDecoder(rr.Body).Decode(&conflict); err != nil {
+		t.Fatalf("decode conflict: %v", err)
+	}
+	if conflict.Revision != applied.Revision || conflict.Current.HistoryLimit != alice.HistoryLimit {
+		t.Fatalf("unexpected conflict %+v", conflict)
+	}
+	if len(conflict.Diff) != 1 || conflict.Diff[0].Key != "historyLimit" {
+		t.Fatalf("diff should list the other user's change, got %+v", conflict.Diff)
+	}
+	if got := hub.ConfigSnapshot(); got.MaxTracks != doc.MaxTracks {
+		t.Fatalf("stale update was applied: %+v", got)
+	}
+
+	bob.Revision = conflict.Revision
+	rr = postConfig(t, hub, bob)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("rebased update: got %d %s", rr.Code, rr.Body.String())
+	}
+	_ = json.NewDecoder(rr.Body).Decode(&applied)
+
+	// A partial update keeps every setting it leaves out.
+	before := hub.ConfigSnapshot()
+	rr = postConfig(t, hub, map[string]any{"revision": applied.Revision, "historyLimit": 42})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("partial update: got %d %s", rr.Code, rr.Body.String())
+	}
+	after := hub.ConfigSnapshot()
+	before.HistoryLimit = 42
+	if after != before {
+		t.Fatalf("partial update changed other settings:\n got %+v\nwant %+v", after, before)
+	}
+}
+
+func TestConfigConflictWithForgottenRevision(t *testing.T) {
+	hub := newTestHub()
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	payload, _ := json.Marshal(map[string]int{"historyLimit": hub.config.HistoryLimit + 1})
+	conflict := hub.configConflictLocked(0, payload)
+	if len(conflict.Diff) != 1 || conflict.Diff[0].Key != "historyLimit" || conflict.Diff[0].Base != nil {
+		t.Fatalf("expected the update's own change without a base, got %+v", conflict.Diff)
+	}
+}
+
+func TestConcurrentConfigUpdatesPersistLatest(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hub := newTestHub()
+	var wg sync.WaitGroup
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				doc := hub.ConfigDocument()
+				rr := postConfig(t, hub, map[string]any{"revision": doc.Revision, "historyLimit": 100 + 10*c + i})
+				if rr.Code != http.StatusOK && rr.Code != http.StatusConflict {
+					t.Errorf("update: got %d %s", rr.Code, rr.Body.String())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, err := loadPersistentConfig(configFilePath)
+	if err != nil {
+		t.Fatalf("read config file: %v", err)
+	}
+	if want := hub.ConfigSnapshot().HistoryLimit; stored.HistoryLimit != want {
+		t.Fatalf("config file has history limit %d, hub %d", stored.HistoryLimit, want)
+	}
+}
+
+func TestConfigUpdateLeavesStateOnSaveFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hub := newTestHub()
+	// A directory in place of the config file makes every save fail.
+	if err := os.Mkdir(configFilePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	before := hub.ConfigDocument()
+
+	rr := postConfig(t, hub, map[string]any{"revision": before.Revision, "historyLimit": before.HistoryLimit + 1})
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("update: got %d %s", rr.Code, rr.Body.String())
+	}
+	if after := hub.ConfigDocument(); after.Revision != before.Revision || after.HistoryLimit != before.HistoryLimit {
+		t.Fatalf("failed save changed the config: %+v -> %+v", before, after)
+	}
+	if entries := hub.AuditLog(); len(entries) != 0 {
+		t.Fatalf("failed save was audited: %+v", entries)
+	}
+}
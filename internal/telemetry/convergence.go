@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Convergence is the acquisition state of one device's tracker, as returned
+// by /api/convergence. A coarse scan starts an acquisition; it converges when
+// the angle spread over the last iterations drops below the threshold.
+type Convergence struct {
+	Device        string    `json:"device,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	Converged     bool      `json:"converged"`
+	ConvergenceMs float64   `json:"convergenceMs,omitempty"` // coarse scan to convergence
+	Iterations    int       `json:"iterations"`              // tracking iterations since the scan
+	StdDeg        float64   `json:"stdDeg"`                  // angle spread over the last iterations
+	Scans         int       `json:"scans"`                   // coarse scans since start
+	ScanReason    string    `json:"scanReason,omitempty"`    // why the last coarse scan ran
+}
+
+// ReportConvergence stores the convergence state of a single-device setup.
+func (h *Hub) ReportConvergence(c Convergence) {
+	h.reportConvergence("", c)
+}
+
+// ReportConvergence stores the convergence state of one device.
+func (d *deviceReporter) ReportConvergence(c Convergence) {
+	d.hub.reportConvergence(d.id, c)
+}
+
+// reportConvergence stores c and logs converging and re-scanning in the event
+// log.
+func (h *Hub) reportConvergence(device string, c Convergence) {
+	c.Device = device
+	prefix := ""
+	if device != "" {
+		prefix = device + ": "
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev, seen := h.convergence[device]
+	h.convergence[device] = c
+	switch {
+	case c.Converged && !prev.Converged:
+		h.recordEventLocked("info", fmt.Sprintf("%stracking converged in %.0f ms", prefix, c.ConvergenceMs))
+	case seen && c.Scans > prev.Scans:
+		h.recordEventLocked("warn", fmt.Sprintf("%scoarse scan: %s", prefix, c.ScanReason))
+	}
+}
+
+// ConvergenceSnapshots returns the latest convergence state per device,
+// sorted by device ID.
+func (h *Hub) ConvergenceSnapshots() []Convergence {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]Convergence, 0, len(h.convergence))
+	for _, c := range h.convergence {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+// handleConvergence returns the convergence state of every device, or of the
+// one selected with ?device=.
+func (h *Hub) handleConvergence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	device := parseDevice(r)
+	out := make([]Convergence, 0)
+	for _, c := range h.ConvergenceSnapshots() {
+		if device == "" || c.Device == device {
+			out = append(out, c)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// writeConvergenceMetrics appends the convergence state in the Prometheus
+// text format.
+func (h *Hub) writeConvergenceMetrics(sb *strings.Builder) {
+	snapshots := h.ConvergenceSnapshots()
+	if len(snapshots) == 0 {
+		return
+	}
+	sb.WriteString("# HELP gosdr_converged Whether tracking has converged since the last coarse scan.\n# TYPE gosdr_converged gauge\n")
+	for _, c := range snapshots {
+		converged := 0
+		if c.Converged {
+			converged = 1
+		}
+		fmt.Fprintf(sb, "gosdr_converged{device=%q} %d\n", c.Device, converged)
+	}
+	sb.WriteString("# HELP gosdr_convergence_seconds Time from the last coarse scan to convergence.\n# TYPE gosdr_convergence_seconds gauge\n")
+	for _, c := range snapshots {
+		if c.Converged {
+			fmt.Fprintf(sb, "gosdr_convergence_seconds{device=%q} %g\n", c.Device, c.ConvergenceMs/1000)
+		}
+	}
+	sb.WriteString("# HELP gosdr_coarse_scans_total Coarse scans since start.\n# TYPE gosdr_coarse_scans_total counter\n")
+	for _, c := range snapshots {
+		fmt.Fprintf(sb, "gosdr_coarse_scans_total{device=%q} %d\n", c.Device, c.Scans)
+	}
+}
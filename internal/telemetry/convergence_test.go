@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConvergenceEndpoints(t *testing.T) {
+	hub := newTestHub()
+	north := hub.ForDevice("north", "mock").(*deviceReporter)
+	north.ReportConvergence(Convergence{Scans: 1, ScanReason: "startup"})
+	north.ReportConvergence(Convergence{Scans: 1, ScanReason: "startup", Converged: true, ConvergenceMs: 250, Iterations: 12})
+	north.ReportConvergence(Convergence{Scans: 2, ScanReason: "lock lost"})
+	north.ReportConvergence(Convergence{Scans: 2, ScanReason: "lock lost", Converged: true, ConvergenceMs: 1500})
+	hub.ReportConvergence(Convergence{Scans: 1})
+
+	rr := httptest.NewRecorder()
+	hub.handleConvergence(rr, httptest.NewRequest(http.MethodGet, "/api/convergence?device=north", nil))
+	var got []Convergence
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Device != "north" || !got[0].Converged || got[0].Scans != 2 {
+		t.Fatalf("unexpected convergence %+v", got)
+	}
+
+	var events []string
+	for _, ev := range hub.recentEvents() {
+		if strings.HasPrefix(ev.Message, "north: ") {
+			events = append(events, ev.Level+":"+ev.Message)
+		}
+	}
+	want := []string{
+		"info:north: tracking converged in 250 ms",
+		"warn:north: coarse scan: lock lost",
+		"info:north: tracking converged in 1500 ms",
+	}
+	if strings.Join(events, "|") != strings.Join(want, "|") {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+
+	rr = httptest.NewRecorder()
+	hub.handlePrometheus(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`gosdr_converged{device="north"} 1`,
+		`gosdr_converged{device=""} 0`,
+		`gosdr_convergence_seconds{device="north"} 1.5`,
+		`gosdr_coarse_scans_total{device="north"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package telemetry
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DeviceInfo describes an SDR instance reporting into a shared hub.
+type DeviceInfo struct {
+	ID          string    `json:"id"`
+	Backend     string    `json:"backend,omitempty"`
+	Samples     int64     `json:"samples"`
+	LastUpdated time.Time `json:"lastUpdated,omitempty"`
+	// State is the latest backend lifecycle event kind, if any.
+	State string `json:"state,omitempty"`
+}
+
+// deviceTrackSeparator joins a device ID and a tracker-local track ID.
+const deviceTrackSeparator = ":"
+
+// deviceReporter tags telemetry from one tracker with its device ID.
+type deviceReporter struct {
+	hub *Hub
+	id  string
+}
+
+// ForDevice registers a device and returns a Reporter that tags every track
+// with the device ID and qualifies track IDs as "<device>:<id>", so several
+// trackers can share one hub without track ID collisions. Untagged
+// single-target reports are recorded under the bare device ID.
+func (h *Hub) ForDevice(id, backend string) Reporter {
+	h.mu.Lock()
+	if h.devices == nil {
+		h.devices = make(map[string]*DeviceInfo)
+	}
+	if _, ok := h.devices[id]; !ok {
+		h.devices[id] = &DeviceInfo{ID: id, Backend: backend}
+		h.recordEventLocked("info", "device "+id+" registered ("+backend+")")
+	}
+	h.mu.Unlock()
+	return &deviceReporter{hub: h, id: id}
+}
+
+// Report implements Reporter for a single-track update.
+func (d *deviceReporter) Report(angleDeg float64, peak float64, snr float64, confidence float64, state LockState, debug *DebugInfo) {
+	d.ReportMultiTrack(MultiTrackSample{
+		Timestamp: d.hub.now(),
+		Tracks: []TrackSample{{
+			AngleDeg:   angleDeg,
+			Peak:       peak,
+			SNR:        snr,
+			Confidence: confidence,
+			LockState:  state,
+			Debug:      debug,
+		}},
+	})
+}
+
+// ReportMultiTrack tags the tracks and forwards them to the hub.
+func (d *deviceReporter) ReportMultiTrack(sample MultiTrackSample) {
+	tagged := cloneMultiTrackSample(sample)
+	for i := range tagged.Tracks {
+		tagged.Tracks[i].Device = d.id
+		if tagged.Tracks[i].ID == "" {
+			tagged.Tracks[i].ID = d.id
+		} else {
+			tagged.Tracks[i].ID = d.id + deviceTrackSeparator + tagged.Tracks[i].ID
+		}
+	}
+
+	d.hub.mu.Lock()
+	if info, ok := d.hub.devices[d.id]; ok {
+		info.Samples++
+		info.LastUpdated = tagged.Timestamp
+	}
+	d.hub.mu.Unlock()
+
+	d.hub.ReportMultiTrack(tagged)
+	d.hub.updateSectors(tagged.Timestamp)
+}
+
+// LogEvent records message in the hub event log, prefixed with the device ID.
+func (d *deviceReporter) LogEvent(level, message string) {
+	d.hub.LogEvent(level, d.id+": "+message)
+}
+
+// Devices lists the registered devices sorted by ID.
+func (h *Hub) Devices() []DeviceInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	out := make([]DeviceInfo, 0, len(h.devices))
+	for id, info := range h.devices {
+		device := *info
+		if ev, ok := h.backendStates[id]; ok {
+			device.State = string(ev.Kind)
+		}
+		out = append(out, device)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// filterDevice keeps only tracks reported by device. An empty device matches
+// everything.
+func filterDevice(sample MultiTrackSample, device string) (MultiTrackSample, bool) {
+	if device == "" {
+		return sample, len(sample.Tracks) > 0
+	}
+	filtered := sample
+	filtered.Tracks = nil
+	for _, track := range sample.Tracks {
+		if track.Device == device {
+			filtered.Tracks = append(filtered.Tracks, track)
+		}
+	}
+	return filtered, len(filtered.Tracks) > 0
+}
+
+func parseDevice(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("device"))
+}
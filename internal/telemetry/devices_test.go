@@ -0,0 +1,66 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestForDeviceTagsTracks(t *testing.T) {
+	hub := newTestHub()
+	north := hub.ForDevice("north", "pluto")
+	south := hub.ForDevice("south", "mock")
+
+	north.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{ID: "1", AngleDeg: 10}}})
+	south.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{ID: "1", AngleDeg: -20}}})
+
+	devices := hub.Devices()
+	if len(devices) != 2 || devices[0].ID != "north" || devices[0].Samples != 1 || devices[1].Backend != "mock" {
+		t.Fatalf("unexpected devices: %+v", devices)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/history?device=south", nil)
+	rr := httptest.NewRecorder()
+	hub.handleHistory(rr, req)
+
+	var history []MultiTrackSample
+	if err := json.NewDecoder(rr.Body).Decode(&history); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	if len(history) != 1 || len(history[0].Tracks) != 1 {
+		t.Fatalf("expected one south sample, got %+v", history)
+	}
+	if track := history[0].Tracks[0]; track.ID != "south:1" || track.Device != "south" || track.AngleDeg != -20 {
+		t.Fatalf("unexpected track: %+v", track)
+	}
+}
+
+func TestHandleDeviceScoped(t *testing.T) {
+	hub := newTestHub()
+	ws := NewWebServer(":0", hub, nil, nil)
+	ws.AddDevice("north", sdr.NewMock())
+	hub.ForDevice("north", "mock").ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{ID: "1"}}})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/api/devices/north/tracks", want: http.StatusOK},
+		{path: "/api/devices/north/sdr/capabilities", want: http.StatusOK},
+		{path: "/api/devices/north/unknown", want: http.StatusNotFound},
+		{path: "/api/devices/south/tracks", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ws.handleDeviceScoped(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rr.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", rr.Code, tt.want, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// The Hub is the event sink handed to backends, wrappers and the tracker.
+var _ sdr.EventLogger = (*Hub)(nil)
+
+// Event severities, lowest first.
+const (
+	EventDebug = "debug"
+	EventInfo  = "info"
+	EventWarn  = "warn"
+	EventError = "error"
+)
+
+// defaultEventLimit is the number of events the hub keeps.
+const defaultEventLimit = 256
+
+// eventSeverity ranks level. Common aliases are accepted; ok is false for
+// anything else.
+func eventSeverity(level string) (rank int, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case EventDebug, "trace":
+		return 0, true
+	case EventInfo, "":
+		return 1, true
+	case EventWarn, "warning":
+		return 2, true
+	case EventError, "critical", "fatal":
+		return 3, true
+	default:
+		return 0, false
+	}
+}
+
+// eventLevelName is the canonical name of a severity rank.
+var eventLevelName = [...]string{EventDebug, EventInfo, EventWarn, EventError}
+
+// eventRank is eventSeverity for user input, where unknown levels are an
+// error.
+func eventRank(level string) (int, error) {
+	rank, ok := eventSeverity(level)
+	if !ok {
+		return 0, fmt.Errorf("unknown event level %q (want debug, info, warn or error)", level)
+	}
+	return rank, nil
+}
+
+// ParseEventLevel returns the canonical severity for level, accepting the
+// same aliases as LogEvent ("warning", "critical", ...).
+func ParseEventLevel(level string) (string, error) {
+	rank, err := eventRank(level)
+	if err != nil {
+		return "", err
+	}
+	return eventLevelName[rank], nil
+}
+
+// eventLog is a fixed-size ring of diagnostic events. Events get increasing
+// IDs so clients can resume a stream. It is guarded by Hub.mu.
+type eventLog struct {
+	ring    []DiagnosticEvent
+	next    int // slot written next
+	count   int
+	lastID  uint64
+	minRank int // events below are dropped
+	subs    map[chan DiagnosticEvent]int
+}
+
+func newEventLog(limit int) eventLog {
+	return eventLog{
+		ring: make([]DiagnosticEvent, limit),
+		subs: make(map[chan DiagnosticEvent]int),
+	}
+}
+
+// add stores event, unless it is below the minimum level, and sends it to
+// the subscribers that want it. Slow subscribers miss events rather than
+// block the caller. It returns the stored event with its ID.
+func (l *eventLog) add(event DiagnosticEvent) (DiagnosticEvent, bool) {
+	rank, ok := eventSeverity(event.Level)
+	if !ok {
+		rank = 1
+	}
+	if rank < l.minRank {
+		return DiagnosticEvent{}, false
+	}
+	event.Level = eventLevelName[rank]
+	l.lastID++
+	event.ID = l.lastID
+	l.ring[l.next] = event
+	l.next = (l.next + 1) % len(l.ring)
+	if l.count < len(l.ring) {
+		l.count++
+	}
+	for ch, min := range l.subs {
+		if rank < min {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return event, true
+}
+
+// since returns the stored events with an ID above afterID and a severity of
+// at least minRank, oldest first.
+func (l *eventLog) since(afterID uint64, minRank int) []DiagnosticEvent {
+	out := make([]DiagnosticEvent, 0, l.count)
+	start := (l.next - l.count + len(l.ring)) % len(l.ring)
+	for i := range l.count {
+		event := l.ring[(start+i)%len(l.ring)]
+		if rank, _ := eventSeverity(event.Level); event.ID > afterID && rank >= minRank {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+// SetEventLevel drops events below level from now on. The default keeps
+// everything, including debug events.
+func (h *Hub) SetEventLevel(level string) error {
+	rank, err := eventRank(level)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.events.minRank = rank
+	h.mu.Unlock()
+	return nil
+}
+
+// Events returns the stored events at or above level with an ID above
+// afterID, oldest first. An empty level returns every stored event.
+func (h *Hub) Events(level string, afterID uint64) ([]DiagnosticEvent, error) {
+	rank := 0
+	if level != "" {
+		var err error
+		if rank, err = eventRank(level); err != nil {
+			return nil, err
+		}
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.events.since(afterID, rank), nil
+}
+
+// subscribeEvents registers a listener for new events at or above rank.
+func (h *Hub) subscribeEvents(rank int) (chan DiagnosticEvent, func()) {
+	ch := make(chan DiagnosticEvent, 64)
+	h.mu.Lock()
+	h.events.subs[ch] = rank
+	h.mu.Unlock()
+	cancel := func() {
+		h.mu.Lock()
+		delete(h.events.subs, ch)
+		close(ch)
+		h.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// parseEventQuery reads ?level= and the resume point, which is ?after= or
+// the Last-Event-ID header of a reconnecting event stream.
+func parseEventQuery(r *http.Request) (rank int, afterID uint64, err error) {
+	if level := r.URL.Query().Get("level"); level != "" {
+		if rank, err = eventRank(level); err != nil {
+			return 0, 0, err
+		}
+	}
+	after := r.URL.Query().Get("after")
+	if after == "" {
+		after = r.Header.Get("Last-Event-ID")
+	}
+	if after != "" {
+		if afterID, err = strconv.ParseUint(after, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid event id %q", after)
+		}
+	}
+	return rank, afterID, nil
+}
+
+// handleEvents returns the stored events, filtered with ?level= (minimum
+// severity) and ?after= (last event ID seen).
+func (h *Hub) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rank, afterID, err := parseEventQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.mu.RLock()
+	events := h.events.since(afterID, rank)
+	h.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
+
+// handleEventStream streams events as server-sent events, starting with the
+// stored events that pass the same filters as handleEvents. Each event
+// carries its ID, so a reconnecting EventSource resumes without gaps as long
+// as the missed events are still stored.
+func (h *Hub) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	rank, afterID, err := parseEventQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// Subscribe before taking the backlog so no event falls in between.
+	ch, cancel := h.subscribeEvents(rank)
+	defer cancel()
+	h.mu.RLock()
+	backlog := h.events.since(afterID, rank)
+	h.mu.RUnlock()
+
+	write := func(event DiagnosticEvent) {
+		payload, _ := json.Marshal(event)
+		fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, payload)
+	}
+	for _, event := range backlog {
+		write(event)
+		afterID = event.ID
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if event.ID <= afterID {
+				continue // already sent with the backlog
+			}
+			write(event)
+			flusher.Flush()
+		case <-keepAlive.C:
+			_, _ = w.Write([]byte(": keep-alive\n\n"))
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
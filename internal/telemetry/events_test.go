@@ -0,0 +1,132 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLogRingAndLevels(t *testing.T) {
+	hub := newTestHub()
+	for i := range defaultEventLimit + 10 {
+		hub.LogEvent("debug", fmt.Sprintf("event %d", i))
+	}
+	hub.LogEvent("warning", "alias")
+	hub.LogEvent("loud", "unknown level")
+
+	all, err := hub.Events("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != defaultEventLimit {
+		t.Fatalf("kept %d events, want %d", len(all), defaultEventLimit)
+	}
+	last := all[len(all)-1]
+	if last.Level != EventInfo || last.ID != all[len(all)-2].ID+1 {
+		t.Fatalf("unexpected newest events %+v", all[len(all)-2:])
+	}
+
+	tests := []struct {
+		level string
+		after uint64
+		want  []string
+	}{
+		{level: "warn", want: []string{"alias"}},
+		{level: "info", want: []string{"alias", "unknown level"}},
+		{level: "debug", after: last.ID - 1, want: []string{"unknown level"}},
+	}
+	for _, tt := range tests {
+		got, err := hub.Events(tt.level, tt.after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var messages []string
+		for _, ev := range got {
+			messages = append(messages, ev.Message)
+		}
+		if strings.Join(messages, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Events(%q, %d) = %v, want %v", tt.level, tt.after, messages, tt.want)
+		}
+	}
+	if _, err := hub.Events("loud", 0); err == nil {
+		t.Fatal("unknown filter level accepted")
+	}
+
+	if err := hub.SetEventLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	hub.LogEvent("info", "dropped")
+	if got, _ := hub.Events("", last.ID); len(got) != 0 {
+		t.Fatalf("event below the minimum level stored: %+v", got)
+	}
+	if err := hub.SetEventLevel("loud"); err == nil {
+		t.Fatal("unknown minimum level accepted")
+	}
+}
+
+func TestHandleEvents(t *testing.T) {
+	hub := newTestHub()
+	hub.LogEvent("error", "connection failed")
+
+	rr := httptest.NewRecorder()
+	hub.handleEvents(rr, httptest.NewRequest(http.MethodGet, "/api/events?level=error", nil))
+	var got []DiagnosticEvent
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Message != "connection failed" || got[0].ID == 0 {
+		t.Fatalf("unexpected events %+v", got)
+	}
+
+	for _, query := range []string{"?level=loud", "?after=x"} {
+		rr = httptest.NewRecorder()
+		hub.handleEvents(rr, httptest.NewRequest(http.MethodGet, "/api/events"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d", query, rr.Code)
+		}
+	}
+}
+
+func TestEventStreamResumesAndFilters(t *testing.T) {
+	hub := newTestHub()
+	hub.LogEvent("warn", "before")
+	backlog, _ := hub.Events("warn", 0)
+	resume := backlog[0].ID
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/api/events/stream?level=warn", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", fmt.Sprint(resume-1))
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		hub.handleEventStream(rr, req)
+		close(done)
+	}()
+
+	// Wait for the handler to subscribe.
+	for i := 0; i < 100; i++ {
+		hub.mu.RLock()
+		n := len(hub.events.subs)
+		hub.mu.RUnlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	hub.LogEvent("info", "filtered")
+	hub.LogEvent("error", "after")
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	body := rr.Body.String()
+	if strings.Count(body, "data: ") != 2 || !strings.Contains(body, `"message":"before"`) ||
+		!strings.Contains(body, `"message":"after"`) || !strings.Contains(body, fmt.Sprintf("id: %d\n", resume)) {
+		t.Fatalf("unexpected stream:\n%s", body)
+	}
+}
@@ -0,0 +1,212 @@
+package telemetry
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// npyArray is one array of an .npz archive in NumPy's .npy layout.
+type npyArray struct {
+	name  string
+	descr string // NumPy dtype string, e.g. "<f8"
+	shape int
+	data  []byte
+}
+
+// WriteNPZ writes history and the spectrum snapshot as a NumPy .npz archive,
+// loadable with numpy.load. History is flattened to one row per track
+// observation in the parallel arrays time (Unix seconds), seq, gap, device,
+// track_id, angle_deg, bearing_deg, peak_dbfs, snr_db, confidence and
+// lock_state. The spectrum adds spectrum_time and spectrum_dbfs when it has
+// bins.
+func WriteNPZ(w io.Writer, history []MultiTrackSample, spectrum SpectrumSnapshot) error {
+	var rows []historyRow
+	for _, sample := range history {
+		for _, track := range sample.Tracks {
+			rows = append(rows, historyRow{sample: sample, track: track})
+		}
+	}
+	arrays := []npyArray{
+		floatArray("time", rows, func(r historyRow) float64 { return unixSeconds(r.sample.Timestamp) }),
+		uintArray("seq", rows, func(r historyRow) uint64 { return r.sample.Seq }),
+		boolArray("gap", rows, func(r historyRow) bool { return r.sample.Gap }),
+		stringArray("device", rows, func(r historyRow) string { return r.track.Device }),
+		stringArray("track_id", rows, func(r historyRow) string { return r.track.ID }),
+		floatArray("angle_deg", rows, func(r historyRow) float64 { return r.track.AngleDeg }),
+		floatArray("bearing_deg", rows, func(r historyRow) float64 { return r.track.BearingDeg }),
+		floatArray("peak_dbfs", rows, func(r historyRow) float64 { return r.track.Peak }),
+		floatArray("snr_db", rows, func(r historyRow) float64 { return r.track.SNR }),
+		floatArray("confidence", rows, func(r historyRow) float64 { return r.track.Confidence }),
+		stringArray("lock_state", rows, func(r historyRow) string { return string(r.track.LockState) }),
+	}
+	if len(spectrum.Bins) > 0 {
+		arrays = append(arrays,
+			floatArray("spectrum_time", []float64{unixSeconds(spectrum.Timestamp)}, func(v float64) float64 { return v }),
+			floatArray("spectrum_dbfs", spectrum.Bins, func(v float64) float64 { return v }),
+		)
+	}
+
+	zw := zip.NewWriter(w)
+	for _, a := range arrays {
+		f, err := zw.Create(a.name + ".npy")
+		if err != nil {
+			return fmt.Errorf("export npz: %w", err)
+		}
+		if _, err := f.Write(a.encode()); err != nil {
+			return fmt.Errorf("export npz: %w", err)
+		}
+	}
+	return zw.Close()
+}
+
+// historyRow is one track observation of a history sample.
+type historyRow struct {
+	sample MultiTrackSample
+	track  TrackSample
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return math.NaN()
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+func floatArray[T any](name string, rows []T, value func(T) float64) npyArray {
+	data := make([]byte, 0, 8*len(rows))
+	for _, r := range rows {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(value(r)))
+	}
+	return npyArray{name: name, descr: "<f8", shape: len(rows), data: data}
+}
+
+func uintArray[T any](name string, rows []T, value func(T) uint64) npyArray {
+	data := make([]byte, 0, 8*len(rows))
+	for _, r := range rows {
+		data = binary.LittleEndian.AppendUint64(data, value(r))
+	}
+	return npyArray{name: name, descr: "<u8", shape: len(rows), data: data}
+}
+
+func boolArray[T any](name string, rows []T, value func(T) bool) npyArray {
+	data := make([]byte, len(rows))
+	for i, r := range rows {
+		if value(r) {
+			data[i] = 1
+		}
+	}
+	return npyArray{name: name, descr: "|b1", shape: len(rows), data: data}
+}
+
+// stringArray stores strings as fixed-width UTF-32 ("<U<n>"), NumPy's native
+// string dtype, sized to the longest value.
+func stringArray[T any](name string, rows []T, value func(T) string) npyArray {
+	width := 1
+	for _, r := range rows {
+		width = max(width, utf8.RuneCountInString(value(r)))
+	}
+	data := make([]byte, 0, 4*width*len(rows))
+	for _, r := range rows {
+		n := 0
+		for _, c := range value(r) {
+			data = binary.LittleEndian.AppendUint32(data, uint32(c))
+			n++
+		}
+		data = append(data, make([]byte, 4*(width-n))...)
+	}
+	return npyArray{name: name, descr: fmt.Sprintf("<U%d", width), shape: len(rows), data: data}
+}
+
+// encode returns the array in .npy format version 1.0: magic, version, the
+// header dictionary padded so the data starts on a 64-byte boundary, then
+// the raw data.
+func (a npyArray) encode() []byte {
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d,), }", a.descr, a.shape)
+	const prefix = 10 // magic (6), version (2), header length (2)
+	pad := 64 - (prefix+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += strings.Repeat(" ", pad) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString("\x93NUMPY\x01\x00")
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	buf.Write(a.data)
+	return buf.Bytes()
+}
+
+// handleHistoryExport serves the history, filtered like /api/history, and
+// the latest spectrum as a download. format=npz (the default) is the only
+// format; HDF5 would need a native library and is answered with 501.
+func (h *Hub) handleHistoryExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "npz":
+	case "hdf5", "h5":
+		writeJSONError(w, http.StatusNotImplemented, "hdf5 export is not available; use format=npz (numpy.load)")
+		return
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown export format %q", format))
+		return
+	}
+	after, err := parseSeqCursor(r.URL.Query().Get("after"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	device := parseDevice(r)
+	var out []MultiTrackSample
+	for _, sample := range h.History(parseTrackIDs(r)...) {
+		if sample.Seq <= after {
+			continue
+		}
+		if filtered, ok := filterDevice(sample, device); ok {
+			out = append(out, filtered)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNPZ(&buf, out, h.spectrumSnapshot()); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	name := "gosdr-history-" + time.Now().UTC().Format("20060102T150405Z") + ".npz"
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// ReadJournalHistory returns the history samples stored in a state journal
+// file (see Hub.OpenJournal), oldest first, for offline export.
+func ReadJournalHistory(path string) ([]MultiTrackSample, error) {
+	// loadJournal treats a missing file as empty, which suits a restart but
+	// would silently export nothing here.
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	entries, err := loadJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []MultiTrackSample
+	for _, entry := range entries {
+		if entry.Kind == journalSample && entry.Sample != nil {
+			out = append(out, *entry.Sample)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,181 @@
+package telemetry
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readNPZ returns the header and data of every array in an .npz archive.
+func readNPZ(t *testing.T, raw []byte) (map[string]string, map[string][]byte) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, data := map[string]string{}, map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(b, []byte("\x93NUMPY\x01\x00")) {
+			t.Fatalf("%s: bad magic % x", f.Name, b[:8])
+		}
+		n := int(binary.LittleEndian.Uint16(b[8:10]))
+		if (10+n)%64 != 0 || b[10+n-1] != '\n' {
+			t.Fatalf("%s: header length %d not aligned or not newline terminated", f.Name, n)
+		}
+		name := strings.TrimSuffix(f.Name, ".npy")
+		headers[name] = string(b[10 : 10+n])
+		data[name] = b[10+n:]
+	}
+	return headers, data
+}
+
+func float64s(b []byte) []float64 {
+	out := make([]float64, len(b)/8)
+	for i := range out {
+		out[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	return out
+}
+
+func TestWriteNPZ(t *testing.T) {
+	at := time.Unix(1700000000, 500000000)
+	history := []MultiTrackSample{
+		{Seq: 1, Timestamp: at, Tracks: []TrackSample{{ID: "t1", Device: "north", AngleDeg: 12.5, LockState: LockStateTracking}}},
+		{Seq: 2, Timestamp: at.Add(time.Second), Gap: true, Tracks: []TrackSample{
+			{ID: "t1", Device: "north", AngleDeg: 13},
+			{ID: "track-22", Device: "north", AngleDeg: -4, SNR: 9},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := WriteNPZ(&buf, history, SpectrumSnapshot{Timestamp: at, Bins: []float64{-80, -20, -75}}); err != nil {
+		t.Fatal(err)
+	}
+	headers, data := readNPZ(t, buf.Bytes())
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"time", "{'descr': '<f8', 'fortran_order': False, 'shape': (3,), }"},
+		{"seq", "{'descr': '<u8', 'fortran_order': False, 'shape': (3,), }"},
+		{"gap", "{'descr': '|b1', 'fortran_order': False, 'shape': (3,), }"},
+		{"track_id", "{'descr': '<U8', 'fortran_order': False, 'shape': (3,), }"},
+		{"lock_state", "{'descr': '<U8', 'fortran_order': False, 'shape': (3,), }"},
+		{"spectrum_dbfs", "{'descr': '<f8', 'fortran_order': False, 'shape': (3,), }"},
+		{"spectrum_time", "{'descr': '<f8', 'fortran_order': False, 'shape': (1,), }"},
+	}
+	for _, tt := range tests {
+		if got := strings.TrimRight(headers[tt.name], " \n"); got != tt.header {
+			t.Errorf("%s header = %q, want %q", tt.name, got, tt.header)
+		}
+	}
+
+	if got := float64s(data["time"]); got[0] != 1700000000.5 || got[2] != 1700000001.5 {
+		t.Fatalf("time = %v", got)
+	}
+	if got := float64s(data["angle_deg"]); got[0] != 12.5 || got[1] != 13 || got[2] != -4 {
+		t.Fatalf("angle_deg = %v", got)
+	}
+	if got := data["gap"]; !bytes.Equal(got, []byte{0, 1, 1}) {
+		t.Fatalf("gap = %v", got)
+	}
+	// "t1" padded to 8 UTF-32 code units.
+	ids := data["track_id"]
+	if len(ids) != 3*8*4 || ids[0] != 't' || ids[4] != '1' || ids[8] != 0 || ids[64] != 't' {
+		t.Fatalf("track_id data = % x", ids)
+	}
+	if got := float64s(data["spectrum_dbfs"]); got[1] != -20 {
+		t.Fatalf("spectrum_dbfs = %v", got)
+	}
+}
+
+func TestWriteNPZEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteNPZ(&buf, nil, SpectrumSnapshot{}); err != nil {
+		t.Fatal(err)
+	}
+	headers, _ := readNPZ(t, buf.Bytes())
+	if !strings.Contains(headers["time"], "'shape': (0,)") {
+		t.Fatalf("time header = %q", headers["time"])
+	}
+	if _, ok := headers["spectrum_dbfs"]; ok {
+		t.Fatal("spectrum written without bins")
+	}
+}
+
+func TestHandleHistoryExport(t *testing.T) {
+	hub := newTestHub()
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{ID: "a", Device: "north"}, {ID: "b", Device: "south"}}})
+	hub.UpdateSpectrumSnapshot([]float64{-70, -30}, "test")
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantRows   string
+	}{
+		{name: "default npz", wantStatus: http.StatusOK, wantRows: "(2,)"},
+		{name: "device filter", query: "?format=npz&device=north", wantStatus: http.StatusOK, wantRows: "(1,)"},
+		{name: "hdf5", query: "?format=hdf5", wantStatus: http.StatusNotImplemented},
+		{name: "unknown format", query: "?format=csv", wantStatus: http.StatusBadRequest},
+		{name: "bad cursor", query: "?after=x", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			hub.handleHistoryExport(rec, httptest.NewRequest(http.MethodGet, "/api/history/export"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantRows == "" {
+				return
+			}
+			if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, ".npz") {
+				t.Fatalf("Content-Disposition = %q", cd)
+			}
+			headers, _ := readNPZ(t, rec.Body.Bytes())
+			if !strings.Contains(headers["device"], tt.wantRows) {
+				t.Fatalf("device header = %q, want shape %s", headers["device"], tt.wantRows)
+			}
+			if _, ok := headers["spectrum_dbfs"]; !ok {
+				t.Fatal("spectrum missing")
+			}
+		})
+	}
+}
+
+func TestReadJournalHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	hub := newTestHub()
+	if err := hub.OpenJournal(path, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{ID: "t1", AngleDeg: 7}}})
+	if err := hub.journal.sync(); err != nil {
+		t.Fatal(err)
+	}
+	history, err := ReadJournalHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Tracks[0].AngleDeg != 7 {
+		t.Fatalf("history = %+v", history)
+	}
+}
@@ -0,0 +1,272 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fileStoreSamples = "samples"
+	fileStoreEvents  = "events"
+	fileStoreDay     = "2006-01-02"
+)
+
+// fileStore keeps samples and events as JSON lines in one file per kind and
+// UTC day, such as samples-2024-05-01.jsonl, so old days can be archived or
+// deleted with ordinary tools. Each Append is synced before it returns; a
+// line torn by a crash is skipped when read.
+type fileStore struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*os.File // open files by name, the current days only
+}
+
+func openFileStore(dsn string) (Store, error) {
+	dir := strings.TrimPrefix(dsn, "file:")
+	if dir == "" {
+		return nil, fmt.Errorf("store %q: want file:<directory>", dsn)
+	}
+	return NewFileStore(dir)
+}
+
+// NewFileStore returns a store that keeps its files in dir, creating it if
+// needed.
+func NewFileStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("open file store: %w", err)
+	}
+	return &fileStore{dir: dir, files: make(map[string]*os.File)}, nil
+}
+
+func fileStoreName(kind string, t time.Time) string {
+	return kind + "-" + t.UTC().Format(fileStoreDay) + ".jsonl"
+}
+
+func (f *fileStore) Append(samples []MultiTrackSample, events []DiagnosticEvent) error {
+	batches := make(map[string]*bytes.Buffer)
+	add := func(name string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf := batches[name]
+		if buf == nil {
+			buf = new(bytes.Buffer)
+			batches[name] = buf
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+		return nil
+	}
+	for _, s := range samples {
+		if err := add(fileStoreName(fileStoreSamples, s.Timestamp), s); err != nil {
+			return err
+		}
+	}
+	for _, e := range events {
+		if err := add(fileStoreName(fileStoreEvents, e.Timestamp), e); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, 0, len(batches))
+	for name := range batches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		file, err := f.fileLocked(name)
+		if err == nil {
+			if _, err = file.Write(batches[name].Bytes()); err == nil {
+				err = file.Sync()
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	f.closeOldLocked(names)
+	return errors.Join(errs...)
+}
+
+// fileLocked returns the open file name, opening it for append. The caller
+// holds f.mu.
+func (f *fileStore) fileLocked(name string) (*os.File, error) {
+	if file, ok := f.files[name]; ok {
+		return file, nil
+	}
+	file, err := os.OpenFile(filepath.Join(f.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f.files[name] = file
+	return file, nil
+}
+
+// closeOldLocked closes the files of earlier days than the newest written
+// in this batch, which will not be appended to again. The caller holds f.mu.
+func (f *fileStore) closeOldLocked(written []string) {
+	newest := make(map[string]string) // kind -> newest name
+	for _, name := range written {
+		kind, _, _ := strings.Cut(name, "-")
+		newest[kind] = max(newest[kind], name)
+	}
+	for name, file := range f.files {
+		kind, _, _ := strings.Cut(name, "-")
+		if n, ok := newest[kind]; ok && name < n {
+			_ = file.Close()
+			delete(f.files, name)
+		}
+	}
+}
+
+// days returns the files of kind whose day can hold entries of q, oldest
+// first.
+func (f *fileStore) days(kind string, q StoreQuery) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		day, ok := strings.CutPrefix(name, kind+"-")
+		if !ok || !strings.HasSuffix(day, ".jsonl") {
+			continue
+		}
+		start, err := time.Parse(fileStoreDay, strings.TrimSuffix(day, ".jsonl"))
+		if err != nil {
+			continue
+		}
+		if (!q.Until.IsZero() && !start.Before(q.Until)) || (!q.Since.IsZero() && !start.Add(24*time.Hour).After(q.Since)) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// scan decodes the lines of the files of kind for q, oldest file first,
+// until fn returns false.
+func (f *fileStore) scan(kind string, q StoreQuery, fn func([]byte) bool) error {
+	names, err := f.days(kind, q)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		more, err := scanLines(filepath.Join(f.dir, name), fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// scanLines calls fn for each line of path until it returns false, and
+// reports whether it did not.
+func scanLines(path string, fn func([]byte) bool) (bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if !fn(scanner.Bytes()) {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
+
+func (f *fileStore) Samples(q StoreQuery) ([]MultiTrackSample, error) {
+	var out []MultiTrackSample
+	err := f.scan(fileStoreSamples, q, func(line []byte) bool {
+		var s MultiTrackSample
+		if json.Unmarshal(line, &s) != nil {
+			return true
+		}
+		if s, ok := q.sample(s); ok {
+			out = append(out, s)
+		}
+		return !q.full(len(out))
+	})
+	return out, err
+}
+
+func (f *fileStore) Events(q StoreQuery) ([]DiagnosticEvent, error) {
+	var out []DiagnosticEvent
+	err := f.scan(fileStoreEvents, q, func(line []byte) bool {
+		var e DiagnosticEvent
+		if json.Unmarshal(line, &e) != nil {
+			return true
+		}
+		if q.includes(e.Timestamp) {
+			out = append(out, e)
+		}
+		return !q.full(len(out))
+	})
+	return out, err
+}
+
+// Span reads the first and last day of each kind; entries within a day are
+// in append order, which is time order.
+func (f *fileStore) Span() (first, last time.Time, err error) {
+	for _, kind := range []string{fileStoreSamples, fileStoreEvents} {
+		names, err := f.days(kind, StoreQuery{})
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if len(names) == 0 {
+			continue
+		}
+		for _, name := range []string{names[0], names[len(names)-1]} {
+			_, err := scanLines(filepath.Join(f.dir, name), func(line []byte) bool {
+				var entry struct {
+					Timestamp time.Time `json:"timestamp"`
+				}
+				if json.Unmarshal(line, &entry) == nil && !entry.Timestamp.IsZero() {
+					first, last = widenSpan(first, last, entry.Timestamp)
+				}
+				return true
+			})
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+		}
+	}
+	return first, last, nil
+}
+
+func (f *fileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for name, file := range f.files {
+		errs = append(errs, file.Close())
+		delete(f.files, name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+const (
+	// followRetryMin and followRetryMax bound the reconnect backoff of Follow.
+	followRetryMin = time.Second
+	followRetryMax = 30 * time.Second
+)
+
+// Follow mirrors the tracks of a remote tracker into h by reading the live
+// stream at source, the base URL of its web server (for example
+// "http://pluto-host:8080"). On a dropped connection it reconnects with
+// backoff and resumes after the last sample received, marking the next one as
+// following a gap. It returns when ctx is done. client may be nil.
+func (h *Hub) Follow(ctx context.Context, source string, client *http.Client) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	url := strings.TrimRight(source, "/") + "/api/live"
+	var cursor uint64
+	retry := followRetryMin
+	for ctx.Err() == nil {
+		received, err := h.followOnce(ctx, client, url, &cursor)
+		if ctx.Err() != nil {
+			return
+		}
+		if received > 0 {
+			retry = followRetryMin
+		}
+		h.mu.Lock()
+		h.gapPending = true
+		h.recordEventLocked("warn", fmt.Sprintf("lost live stream from %s: %v; retrying in %s", source, err, retry))
+		h.mu.Unlock()
+		h.logger.Warn("follow remote tracker", logging.Field{Key: "source", Value: source}, logging.Field{Key: "error", Value: err})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(2*retry, followRetryMax)
+	}
+}
+
+// followOnce reads one live stream connection until it fails, reporting each
+// sample and advancing cursor. It returns the number of samples received.
+func (h *Hub) followOnce(ctx context.Context, client *http.Client, url string, cursor *uint64) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *cursor > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(*cursor, 10))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("live stream: %s", resp.Status)
+	}
+
+	received := 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var sample MultiTrackSample
+		if err := json.Unmarshal([]byte(data), &sample); err != nil {
+			continue
+		}
+		*cursor = max(*cursor, sample.Seq)
+		h.ReportMultiTrack(sample)
+		received++
+	}
+	if err := scanner.Err(); err != nil {
+		return received, err
+	}
+	return received, fmt.Errorf("live stream closed")
+}
@@ -0,0 +1,52 @@
+package telemetry
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitHistory polls hub until it holds n samples or the deadline passes.
+func waitHistory(t *testing.T, hub *Hub, n int) []MultiTrackSample {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if history := hub.History(); len(history) >= n {
+			return history
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("history has %d samples, want %d", len(hub.History()), n)
+	return nil
+}
+
+func TestFollowMirrorsAndResumesLiveStream(t *testing.T) {
+	remote := newTestHub()
+	srv := httptest.NewServer(NewWebServer("", remote, nil, nil).Handler())
+	defer srv.Close()
+	remote.Report(10, -10, 12, 0.9, LockStateTracking, nil)
+	remote.Report(11, -10, 12, 0.9, LockStateTracking, nil)
+
+	local := newTestHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		local.Follow(ctx, srv.URL+"/", srv.Client())
+		close(done)
+	}()
+	waitHistory(t, local, 2)
+
+	srv.CloseClientConnections()
+	remote.Report(12, -10, 12, 0.9, LockStateTracking, nil)
+	history := waitHistory(t, local, 3)
+	if len(history) != 3 {
+		t.Fatalf("history = %+v, want the missed sample once after reconnect", history)
+	}
+	if history[2].Tracks[0].AngleDeg != 12 || !history[2].Gap || history[1].Gap {
+		t.Fatalf("after reconnect got angle %v gap %v", history[2].Tracks[0].AngleDeg, history[2].Gap)
+	}
+
+	cancel()
+	<-done
+}
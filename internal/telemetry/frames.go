@@ -0,0 +1,260 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reference frames for reported bearings.
+const (
+	// FrameArray reports the raw angle relative to the array boresight.
+	FrameArray = "array"
+	// FrameVehicle reports azimuth clockwise from the vehicle's forward axis.
+	FrameVehicle = "vehicle"
+	// FrameTrue reports azimuth clockwise from true north using the latest
+	// heading.
+	FrameTrue = "true"
+)
+
+// headingMaxAge is how long a pushed heading stays valid. Older headings fall
+// back to the vehicle frame rather than reporting a stale true bearing.
+const headingMaxAge = 10 * time.Second
+
+// Mount describes how an array is installed on the vehicle (or site).
+// Angles follow the aircraft convention: azimuth clockwise from the forward
+// axis, roll positive right side down, tilt positive boresight up.
+type Mount struct {
+	AzimuthDeg float64 `json:"azimuthDeg"`
+	RollDeg    float64 `json:"rollDeg"`
+	TiltDeg    float64 `json:"tiltDeg"`
+}
+
+// FrameStatus is the frame configuration returned by /api/frame.
+type FrameStatus struct {
+	Frame        string           `json:"frame"`
+	Mounts       map[string]Mount `json:"mounts"`
+	HeadingDeg   *float64         `json:"headingDeg,omitempty"`
+	HeadingAge   float64          `json:"headingAgeSeconds,omitempty"`
+	HeadingFixed bool             `json:"headingFixed,omitempty"`
+	Position     *Position        `json:"position,omitempty"`
+	PositionAge  float64          `json:"positionAgeSeconds,omitempty"`
+}
+
+// Position is a WGS84 sensor position.
+type Position struct {
+	LatitudeDeg  float64 `json:"latitudeDeg"`
+	LongitudeDeg float64 `json:"longitudeDeg"`
+}
+
+// Valid reports whether p is a finite position on the globe.
+func (p Position) Valid() bool {
+	return p.LatitudeDeg >= -90 && p.LatitudeDeg <= 90 && p.LongitudeDeg >= -180 && p.LongitudeDeg <= 180
+}
+
+// frameState holds the frame selection, array mounts keyed by device ("" for
+// the single-device case) and the latest heading and position.
+type frameState struct {
+	mu            sync.Mutex
+	frame         string
+	mounts        map[string]Mount
+	heading       float64
+	headingAt     time.Time
+	headingFixed  bool
+	position      Position
+	positionAt    time.Time
+	positionFixed bool
+}
+
+func newFrameState() *frameState {
+	return &frameState{frame: FrameArray, mounts: make(map[string]Mount)}
+}
+
+// ParseFrame returns the canonical frame name for s; empty selects the array
+// frame.
+func ParseFrame(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", FrameArray:
+		return FrameArray, nil
+	case FrameVehicle:
+		return FrameVehicle, nil
+	case FrameTrue, "north", "true-north":
+		return FrameTrue, nil
+	}
+	return "", fmt.Errorf("frame must be array, vehicle or true, got %q", s)
+}
+
+// SetFrame selects the reference frame for reported bearings.
+func (h *Hub) SetFrame(frame string) error {
+	frame, err := ParseFrame(frame)
+	if err != nil {
+		return err
+	}
+	h.frames.mu.Lock()
+	h.frames.frame = frame
+	h.frames.mu.Unlock()
+	return nil
+}
+
+// SetMount records the installation of device's array. Use "" for a
+// single-device setup.
+func (h *Hub) SetMount(device string, m Mount) {
+	h.frames.mu.Lock()
+	h.frames.mounts[device] = m
+	h.frames.mu.Unlock()
+}
+
+// SetHeading updates the vehicle heading (degrees clockwise from true north),
+// typically from a GNSS receiver. A fixed heading never expires.
+func (h *Hub) SetHeading(deg float64, at time.Time, fixed bool) {
+	h.frames.mu.Lock()
+	h.frames.heading = wrapBearing(deg)
+	h.frames.headingAt = at
+	h.frames.headingFixed = fixed
+	h.frames.mu.Unlock()
+}
+
+// SetPosition updates the sensor position, typically from a GNSS receiver. A
+// fixed position never expires.
+func (h *Hub) SetPosition(p Position, at time.Time, fixed bool) {
+	h.frames.mu.Lock()
+	h.frames.position = p
+	h.frames.positionAt = at
+	h.frames.positionFixed = fixed
+	h.frames.mu.Unlock()
+}
+
+// FrameStatus returns the current frame configuration.
+func (h *Hub) FrameStatus() FrameStatus {
+	f := h.frames
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := FrameStatus{Frame: f.frame, Mounts: make(map[string]Mount, len(f.mounts)), HeadingFixed: f.headingFixed}
+	for id, m := range f.mounts {
+		status.Mounts[id] = m
+	}
+	if !f.headingAt.IsZero() {
+		heading := f.heading
+		status.HeadingDeg = &heading
+		if !f.headingFixed {
+			status.HeadingAge = time.Since(f.headingAt).Seconds()
+		}
+	}
+	if !f.positionAt.IsZero() {
+		position := f.position
+		status.Position = &position
+		if !f.positionFixed {
+			status.PositionAge = time.Since(f.positionAt).Seconds()
+		}
+	}
+	return status
+}
+
+// apply sets Frame and BearingDeg on every track. True bearings need a
+// current heading; without one the vehicle frame is reported instead.
+func (f *frameState) apply(tracks []TrackSample, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	frame := f.frame
+	headingValid := f.headingValid(now)
+	if frame == FrameTrue && !headingValid {
+		frame = FrameVehicle
+	}
+	for i := range tracks {
+		tracks[i].Frame = frame
+		switch frame {
+		case FrameArray:
+			tracks[i].BearingDeg = tracks[i].AngleDeg
+		case FrameVehicle:
+			tracks[i].BearingDeg = VehicleAzimuth(tracks[i].AngleDeg, f.mounts[tracks[i].Device])
+		case FrameTrue:
+			tracks[i].BearingDeg = wrapBearing(VehicleAzimuth(tracks[i].AngleDeg, f.mounts[tracks[i].Device]) + f.heading)
+		}
+	}
+}
+
+// headingValid reports whether the heading is fixed or recent enough to use.
+func (f *frameState) headingValid(now time.Time) bool {
+	return f.headingFixed || (!f.headingAt.IsZero() && now.Sub(f.headingAt) <= headingMaxAge)
+}
+
+// positionValid reports whether the position is fixed or recent enough to
+// use. Positions age out like headings.
+func (f *frameState) positionValid(now time.Time) bool {
+	return f.positionFixed || (!f.positionAt.IsZero() && now.Sub(f.positionAt) <= headingMaxAge)
+}
+
+// VehicleAzimuth converts an array angle to an azimuth in [0, 360) in the
+// vehicle frame, assuming the emitter lies in the vehicle's horizontal plane.
+// The array measures the cone angle around its baseline; rotating the
+// baseline by the mount gives two horizontal solutions, and the one in front
+// of the array is returned. With the baseline vertical (roll 90°) azimuth is
+// unobservable and the boresight azimuth is returned.
+func VehicleAzimuth(angleDeg float64, m Mount) float64 {
+	rad := math.Pi / 180
+	roll, tilt, az := m.RollDeg*rad, m.TiltDeg*rad, m.AzimuthDeg*rad
+	// Baseline (array +angle direction) in the vehicle frame: x forward,
+	// y right, z down.
+	ax := math.Sin(roll)*math.Sin(tilt)*math.Cos(az) - math.Cos(roll)*math.Sin(az)
+	ay := math.Sin(roll)*math.Sin(tilt)*math.Sin(az) + math.Cos(roll)*math.Cos(az)
+	r := math.Hypot(ax, ay)
+	if r < 1e-6 {
+		return wrapBearing(m.AzimuthDeg)
+	}
+	base := math.Atan2(ay, ax)
+	offset := math.Acos(math.Max(-1, math.Min(1, math.Sin(angleDeg*rad)/r)))
+	a1, a2 := base+offset, base-offset
+	if math.Cos(a2-az) > math.Cos(a1-az) {
+		a1 = a2
+	}
+	return wrapBearing(a1 / rad)
+}
+
+// handleFrame returns (GET) or updates (POST) the frame configuration. A POST
+// body may set "frame" and/or push a GNSS "headingDeg" and "position".
+func (h *Hub) handleFrame(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var payload struct {
+			Frame      *string   `json:"frame"`
+			HeadingDeg *float64  `json:"headingDeg"`
+			Position   *Position `json:"position"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+			return
+		}
+		if payload.HeadingDeg != nil && (math.IsNaN(*payload.HeadingDeg) || math.IsInf(*payload.HeadingDeg, 0)) {
+			writeJSONError(w, http.StatusBadRequest, "headingDeg must be finite")
+			return
+		}
+		if payload.Position != nil && !payload.Position.Valid() {
+			writeJSONError(w, http.StatusBadRequest, "position must be a latitude in [-90, 90] and longitude in [-180, 180]")
+			return
+		}
+		if payload.Frame != nil {
+			before := h.FrameStatus().Frame
+			if err := h.SetFrame(*payload.Frame); err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			h.recordAudit(r, "frame", before, h.FrameStatus().Frame)
+		}
+		if payload.HeadingDeg != nil {
+			h.SetHeading(*payload.HeadingDeg, h.now(), false)
+		}
+		if payload.Position != nil {
+			h.SetPosition(*payload.Position, h.now(), false)
+		}
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.FrameStatus())
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVehicleAzimuth(t *testing.T) {
+	tests := []struct {
+		name  string
+		angle float64
+		mount Mount
+		want  float64
+	}{
+		{name: "aligned", angle: 20, mount: Mount{}, want: 20},
+		{name: "negative angle", angle: -30, mount: Mount{}, want: 330},
+		{name: "right side mount", angle: 10, mount: Mount{AzimuthDeg: 90}, want: 100},
+		{name: "upside down", angle: 10, mount: Mount{AzimuthDeg: 90, RollDeg: 180}, want: 80},
+		{name: "tilt only", angle: 25, mount: Mount{AzimuthDeg: 180, TiltDeg: 30}, want: 205},
+		{name: "vertical baseline", angle: 40, mount: Mount{AzimuthDeg: 45, RollDeg: 90}, want: 45},
+		// Rolled 60° and tilted 30° the baseline also points forward, so a
+		// positive cone angle maps to an emitter slightly left of boresight.
+		{name: "roll and tilt", angle: 20, mount: Mount{RollDeg: 60, TiltDeg: 30}, want: 350.24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VehicleAzimuth(tt.angle, tt.mount); math.Abs(bearingDiff(got, tt.want)) > 0.05 {
+				t.Fatalf("VehicleAzimuth(%v, %+v) = %v, want %v", tt.angle, tt.mount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrameApplyFallsBackWithoutHeading(t *testing.T) {
+	hub := newTestHub()
+	hub.SetMount("", Mount{AzimuthDeg: 90})
+	if err := hub.SetFrame("true"); err != nil {
+		t.Fatalf("SetFrame: %v", err)
+	}
+
+	now := time.Now()
+	tracks := []TrackSample{{AngleDeg: 10}}
+	hub.frames.apply(tracks, now)
+	if tracks[0].Frame != FrameVehicle || math.Abs(tracks[0].BearingDeg-100) > 1e-9 {
+		t.Fatalf("expected vehicle fallback, got %+v", tracks[0])
+	}
+
+	hub.SetHeading(300, now, false)
+	hub.frames.apply(tracks, now)
+	if tracks[0].Frame != FrameTrue || math.Abs(tracks[0].BearingDeg-40) > 1e-9 {
+		t.Fatalf("expected true bearing 40°, got %+v", tracks[0])
+	}
+
+	hub.frames.apply(tracks, now.Add(2*headingMaxAge))
+	if tracks[0].Frame != FrameVehicle {
+		t.Fatalf("expected stale heading to fall back, got %+v", tracks[0])
+	}
+
+	hub.SetHeading(300, now, true)
+	hub.frames.apply(tracks, now.Add(2*headingMaxAge))
+	if tracks[0].Frame != FrameTrue {
+		t.Fatalf("fixed heading must not expire, got %+v", tracks[0])
+	}
+}
+
+func TestHandleFrame(t *testing.T) {
+	hub := newTestHub()
+	rr := httptest.NewRecorder()
+	hub.handleFrame(rr, httptest.NewRequest(http.MethodPost, "/api/frame", strings.NewReader(`{"frame":"true","headingDeg":370}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rr.Code, rr.Body.String())
+	}
+	status := hub.FrameStatus()
+	if status.Frame != FrameTrue || status.HeadingDeg == nil || *status.HeadingDeg != 10 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if entries := hub.AuditLog(); len(entries) != 1 || entries[0].Setting != "frame" {
+		t.Fatalf("expected frame change to be audited, got %+v", entries)
+	}
+
+	for _, body := range []string{`{"frame":"body"}`, `not json`} {
+		rr = httptest.NewRecorder()
+		hub.handleFrame(rr, httptest.NewRequest(http.MethodPost, "/api/frame", strings.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, rr.Code)
+		}
+	}
+}
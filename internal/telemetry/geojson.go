@@ -0,0 +1,167 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+)
+
+// defaultBearingLineM is the length of exported lines of bearing unless
+// configured otherwise.
+const defaultBearingLineM = 10000
+
+// earthRadiusM is the mean Earth radius used to project bearing lines.
+const earthRadiusM = 6371008.8
+
+// GeoJSONFeatureCollection is the document served by /api/tracks.geojson
+// (RFC 7946).
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+	// Build identifies the producing binary (a GeoJSON foreign member).
+	Build *buildinfo.Info `json:"build,omitempty"`
+}
+
+// GeoJSONFeature is one GeoJSON Feature.
+type GeoJSONFeature struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id,omitempty"`
+	Geometry   GeoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+// GeoJSONGeometry is a Point or LineString; coordinates are [longitude,
+// latitude].
+type GeoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// SetBearingLineLength sets the default length in metres of exported lines of
+// bearing; non-positive values restore 10 km.
+func (h *Hub) SetBearingLineLength(m float64) {
+	if m <= 0 || math.IsInf(m, 0) {
+		m = defaultBearingLineM
+	}
+	h.mu.Lock()
+	h.bearingLineM = m
+	h.mu.Unlock()
+}
+
+// TracksGeoJSON returns the sensor position and one line of bearing per
+// confirmed track, lengthM metres long. Tracks count as confirmed when the
+// track manager says so or, for producers without track states, when locked.
+// Bearings are true azimuths, so a valid position and heading are required.
+// Angles are rounded to the output resolution.
+func (h *Hub) TracksGeoJSON(device string, lengthM float64, now time.Time) (GeoJSONFeatureCollection, error) {
+	f := h.frames
+	f.mu.Lock()
+	position, heading := f.position, f.heading
+	positionValid, headingValid := f.positionValid(now), f.headingValid(now)
+	mounts := make(map[string]Mount, len(f.mounts))
+	for id, m := range f.mounts {
+		mounts[id] = m
+	}
+	f.mu.Unlock()
+	switch {
+	case !positionValid:
+		return GeoJSONFeatureCollection{}, fmt.Errorf("sensor position unknown")
+	case !headingValid:
+		return GeoJSONFeatureCollection{}, fmt.Errorf("heading unknown")
+	}
+
+	output := h.Output()
+
+	sensor := []float64{position.LongitudeDeg, position.LatitudeDeg}
+	build := buildinfo.Get()
+	out := GeoJSONFeatureCollection{Type: "FeatureCollection", Build: &build, Features: []GeoJSONFeature{{
+		Type:       "Feature",
+		ID:         "sensor",
+		Geometry:   GeoJSONGeometry{Type: "Point", Coordinates: sensor},
+		Properties: map[string]any{"kind": "sensor"},
+	}}}
+	snapshots, _ := h.QueryTracks(TrackQuery{Device: device})
+	for _, snap := range snapshots {
+		track := snap.Sample
+		if !track.Confirmed() {
+			continue
+		}
+		bearing := wrapBearing(VehicleAzimuth(track.AngleDeg, mounts[track.Device]) + heading)
+		bearing = wrapBearing(output.Quantize(bearing)) // 359.96 rounds to 0, not 360
+		end := destination(position, bearing, lengthM)
+		out.Features = append(out.Features, GeoJSONFeature{
+			Type: "Feature",
+			ID:   snap.ID,
+			Geometry: GeoJSONGeometry{
+				Type:        "LineString",
+				Coordinates: [][]float64{sensor, {end.LongitudeDeg, end.LatitudeDeg}},
+			},
+			Properties: map[string]any{
+				"kind":               "bearing",
+				"device":             track.Device,
+				"bearingDeg":         bearing,
+				"angleDeg":           output.Quantize(track.AngleDeg),
+				"snr":                track.SNR,
+				"trackingConfidence": track.Confidence,
+				"lockState":          track.LockState,
+				"lengthM":            lengthM,
+				"lastUpdated":        snap.LastUpdated,
+			},
+		})
+	}
+	return out, nil
+}
+
+// Confirmed reports whether the track is confirmed: State "confirmed" from
+// the multi-track manager, or a locked single track.
+func (t TrackSample) Confirmed() bool {
+	if t.State != "" {
+		return strings.EqualFold(t.State, "confirmed")
+	}
+	return t.LockState == LockStateLocked
+}
+
+// destination returns the point distM metres from p along the great circle
+// with initial bearing bearingDeg.
+func destination(p Position, bearingDeg, distM float64) Position {
+	rad := math.Pi / 180
+	lat1, lon1, brg := p.LatitudeDeg*rad, p.LongitudeDeg*rad, bearingDeg*rad
+	d := distM / earthRadiusM
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(brg))
+	lon2 := lon1 + math.Atan2(math.Sin(brg)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	lon := math.Mod(lon2/rad+540, 360) - 180
+	return Position{LatitudeDeg: lat2 / rad, LongitudeDeg: lon}
+}
+
+// handleTracksGeoJSON serves TracksGeoJSON. ?lengthM= overrides the line
+// length and ?device= selects one device.
+func (h *Hub) handleTracksGeoJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	h.mu.RLock()
+	lengthM := h.bearingLineM
+	h.mu.RUnlock()
+	if raw := r.URL.Query().Get("lengthM"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v <= 0 || math.IsInf(v, 0) {
+			writeJSONError(w, http.StatusBadRequest, "lengthM must be a positive number")
+			return
+		}
+		lengthM = v
+	}
+	collection, err := h.TracksGeoJSON(parseDevice(r), lengthM, h.now())
+	if err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	_ = json.NewEncoder(w).Encode(collection)
+}
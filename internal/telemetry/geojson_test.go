@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      Position
+		bearing   float64
+		dist      float64
+		wantLat   float64
+		wantLon   float64
+		tolerance float64
+	}{
+		{name: "north", from: Position{}, bearing: 0, dist: 111195, wantLat: 1, wantLon: 0, tolerance: 1e-3},
+		{name: "east", from: Position{}, bearing: 90, dist: 111195, wantLat: 0, wantLon: 1, tolerance: 1e-3},
+		{name: "antimeridian", from: Position{LongitudeDeg: 179.9}, bearing: 90, dist: 111195, wantLat: 0, wantLon: -179.1, tolerance: 1e-3},
+		{name: "south at latitude", from: Position{LatitudeDeg: 52, LongitudeDeg: 5}, bearing: 180, dist: 222390, wantLat: 50, wantLon: 5, tolerance: 1e-3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := destination(tt.from, tt.bearing, tt.dist)
+			if math.Abs(got.LatitudeDeg-tt.wantLat) > tt.tolerance || math.Abs(got.LongitudeDeg-tt.wantLon) > tt.tolerance {
+				t.Fatalf("destination = %+v, want %v, %v", got, tt.wantLat, tt.wantLon)
+			}
+		})
+	}
+}
+
+func TestHandleTracksGeoJSON(t *testing.T) {
+	hub := newTestHub()
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		hub.handleTracksGeoJSON(rr, httptest.NewRequest(http.MethodGet, "/api/tracks.geojson"+query, nil))
+		return rr
+	}
+	if rr := get(""); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without position: status %d", rr.Code)
+	}
+
+	hub.SetPosition(Position{LatitudeDeg: 52, LongitudeDeg: 5}, time.Now(), true)
+	hub.SetHeading(90, time.Now(), true)
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{
+		{ID: "a", AngleDeg: 0, SNR: 20, Confidence: 0.9, State: "confirmed", LockState: LockStateLocked},
+		{ID: "b", AngleDeg: 30, SNR: 8, State: "tentative", LockState: LockStateTracking},
+	}})
+
+	if rr := get("?lengthM=-1"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("negative length: status %d", rr.Code)
+	}
+	rr := get("?lengthM=111195")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/geo+json" {
+		t.Fatalf("status %d, content type %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var got struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID       string `json:"id"`
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "FeatureCollection" || len(got.Features) != 2 {
+		t.Fatalf("unexpected collection %+v", got)
+	}
+	line := got.Features[1]
+	if line.ID != "a" || line.Geometry.Type != "LineString" || line.Properties["snr"] != 20.0 || line.Properties["bearingDeg"] != 90.0 {
+		t.Fatalf("unexpected feature %+v", line)
+	}
+	var coords [][]float64
+	if err := json.Unmarshal(line.Geometry.Coordinates, &coords); err != nil {
+		t.Fatal(err)
+	}
+	// One degree of arc due east at 52°N.
+	if len(coords) != 2 || coords[0][0] != 5 || math.Abs(coords[1][0]-(5+1/math.Cos(52*math.Pi/180))) > 0.01 {
+		t.Fatalf("unexpected coordinates %v", coords)
+	}
+}
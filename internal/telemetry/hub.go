@@ -8,8 +8,8 @@ import (
 	"io/fs"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
-	"runtime/debug"
 	"runtime/metrics"
 	"sort"
 	"strconv"
@@ -17,7 +17,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rjboer/GoSDR/internal/buildinfo"
+	"github.com/rjboer/GoSDR/internal/clock"
+	"github.com/rjboer/GoSDR/internal/dsp"
 	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
 )
 
 // Config represents the runtime configuration exposed by the telemetry hub.
@@ -29,6 +33,7 @@ type Config struct {
 	ToneOffsetHz      float64 `json:"toneOffsetHz"`
 	SpacingWavelength float64 `json:"spacingWavelength"`
 	NumSamples        int     `json:"numSamples"`
+	FFTWindow         string  `json:"fftWindow"`
 	BufferSize        int     `json:"bufferSize"`
 	HistoryLimit      int     `json:"historyLimit"`
 	TrackingLength    int     `json:"trackingLength"`
@@ -50,6 +55,9 @@ type Config struct {
 	LogLevel          string  `json:"logLevel"`
 	LogFormat         string  `json:"logFormat"`
 	DebugMode         bool    `json:"debugMode"`
+	AngleUnit         string  `json:"angleUnit"`
+	PowerUnit         string  `json:"powerUnit"`
+	PowerOffsetDB     float64 `json:"powerOffsetDb"`
 }
 
 const (
@@ -79,6 +87,7 @@ type persistentConfig struct {
 	TxGain         int     `json:"tx_gain"`
 	ToneOffset     float64 `json:"tone_offset"`
 	NumSamples     int     `json:"num_samples"`
+	FFTWindow      string  `json:"fft_window,omitempty"`
 	TrackingLength int     `json:"tracking_length"`
 	PhaseStep      float64 `json:"phase_step"`
 	PhaseCal       float64 `json:"phase_cal"`
@@ -103,6 +112,9 @@ type persistentConfig struct {
 	SSHKeyPath     string  `json:"ssh_key_path"`
 	SSHPort        int     `json:"ssh_port"`
 	SysfsRoot      string  `json:"sysfs_root"`
+	AngleUnit      string  `json:"angle_unit,omitempty"`
+	PowerUnit      string  `json:"power_unit,omitempty"`
+	PowerOffsetDB  float64 `json:"power_offset_db,omitempty"`
 }
 
 // LockState represents the current tracking lock quality.
@@ -124,6 +136,7 @@ func defaultConfig() Config {
 		ToneOffsetHz:      200_000,
 		SpacingWavelength: 0.5,
 		NumSamples:        512,
+		FFTWindow:         string(dsp.WindowHamming),
 		BufferSize:        4096,
 		HistoryLimit:      500,
 		TrackingLength:    50,
@@ -145,6 +158,8 @@ func defaultConfig() Config {
 		LogLevel:          "warn",
 		LogFormat:         "text",
 		DebugMode:         false,
+		AngleUnit:         AngleUnitDegrees,
+		PowerUnit:         PowerUnitDBFS,
 	}
 }
 
@@ -187,6 +202,7 @@ func configFromPersistent(stored persistentConfig) Config {
 		ToneOffsetHz:      stored.ToneOffset,
 		SpacingWavelength: stored.Spacing,
 		NumSamples:        stored.NumSamples,
+		FFTWindow:         stored.FFTWindow,
 		HistoryLimit:      stored.HistoryLimit,
 		TrackingLength:    stored.TrackingLength,
 		TrackingMode:      stored.TrackingMode,
@@ -207,6 +223,9 @@ func configFromPersistent(stored persistentConfig) Config {
 		LogLevel:          stored.LogLevel,
 		LogFormat:         stored.LogFormat,
 		DebugMode:         stored.DebugMode,
+		AngleUnit:         stored.AngleUnit,
+		PowerUnit:         stored.PowerUnit,
+		PowerOffsetDB:     stored.PowerOffsetDB,
 	}
 }
 
@@ -230,6 +249,9 @@ func validateConfig(cfg Config, base Config) (Config, error) {
 	if cfg.NumSamples == 0 {
 		cfg.NumSamples = base.NumSamples
 	}
+	if cfg.FFTWindow == "" {
+		cfg.FFTWindow = base.FFTWindow
+	}
 	if cfg.BufferSize == 0 {
 		cfg.BufferSize = base.BufferSize
 	}
@@ -281,6 +303,10 @@ func validateConfig(cfg Config, base Config) (Config, error) {
 		if cfg.SDRURI == "" {
 			return Config{}, errors.New("sdr uri required for pluto backend")
 		}
+	case "usrp":
+		// UHD device args may legitimately be empty (first device found).
+	case "soapy":
+		// Empty Soapy device args select the first enumerated device.
 	default:
 		return Config{}, fmt.Errorf("unsupported sdr backend %q", cfg.SDRBackend)
 	}
@@ -294,6 +320,11 @@ func validateConfig(cfg Config, base Config) (Config, error) {
 	if cfg.NumSamples&(cfg.NumSamples-1) != 0 {
 		return Config{}, errors.New("num samples must be a power of two")
 	}
+	window, err := dsp.ParseWindow(cfg.FFTWindow)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.FFTWindow = window.String()
 	if cfg.BufferSize < minBufferSize || cfg.BufferSize > maxBufferSize {
 		return Config{}, fmt.Errorf("buffer size must be between %d and %d", minBufferSize, maxBufferSize)
 	}
@@ -342,6 +373,16 @@ func validateConfig(cfg Config, base Config) (Config, error) {
 	if _, err := logging.ParseFormat(cfg.LogFormat); err != nil {
 		return Config{}, fmt.Errorf("invalid log format: %w", err)
 	}
+	if cfg.AngleUnit == "" {
+		cfg.AngleUnit = base.AngleUnit
+	}
+	if cfg.PowerUnit == "" {
+		cfg.PowerUnit = base.PowerUnit
+	}
+	cfg, err = normalizeUnits(cfg)
+	if err != nil {
+		return Config{}, err
+	}
 
 	return cfg, nil
 }
@@ -360,16 +401,77 @@ func loadPersistentConfig(path string) (persistentConfig, error) {
 	return cfg, nil
 }
 
+// configFileMu serializes read-modify-write cycles on the config file, which
+// concurrent API requests would otherwise interleave.
+var configFileMu sync.Mutex
+
+// savePersistentConfig writes cfg while keeping keys owned by other
+// components (such as the CLI's per-device list) that this struct does not
+// model. configFileMu must be held.
 func savePersistentConfig(path string, cfg persistentConfig) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	own, err := json.Marshal(cfg)
 	if err != nil {
 		return err
 	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(own, &fields); err != nil {
+		return err
+	}
+	return mergeConfigFile(path, fields)
+}
 
-	return os.WriteFile(path, append(data, '\n'), 0o644)
+// persistConfigValue stores a single top-level key in the config file.
+func persistConfigValue(path, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	configFileMu.Lock()
+	defer configFileMu.Unlock()
+	return mergeConfigFile(path, map[string]json.RawMessage{key: raw})
 }
 
+// mergeConfigFile overwrites fields in the JSON object stored at path,
+// leaving all other keys untouched. The file is replaced atomically, so
+// readers never see a partial write. configFileMu must be held.
+func mergeConfigFile(path string, fields map[string]json.RawMessage) error {
+	merged := map[string]json.RawMessage{}
+	if existing, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(existing, &merged)
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// persistConfig writes cfg to the config file. Updates hold h.configMu
+// across persisting and applying, so racing updates cannot leave an older
+// config on disk.
 func (h *Hub) persistConfig(cfg Config) error {
+	configFileMu.Lock()
+	defer configFileMu.Unlock()
 	stored, err := loadPersistentConfig(configFilePath)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
@@ -386,6 +488,7 @@ func (h *Hub) persistConfig(cfg Config) error {
 	stored.TxGain = cfg.TxGain
 	stored.ToneOffset = cfg.ToneOffsetHz
 	stored.NumSamples = cfg.NumSamples
+	stored.FFTWindow = cfg.FFTWindow
 	stored.TrackingLength = cfg.TrackingLength
 	stored.TrackingMode = cfg.TrackingMode
 	stored.MaxTracks = cfg.MaxTracks
@@ -403,6 +506,9 @@ func (h *Hub) persistConfig(cfg Config) error {
 	stored.LogLevel = cfg.LogLevel
 	stored.LogFormat = cfg.LogFormat
 	stored.DebugMode = cfg.DebugMode
+	stored.AngleUnit = cfg.AngleUnit
+	stored.PowerUnit = cfg.PowerUnit
+	stored.PowerOffsetDB = cfg.PowerOffsetDB
 	if stored.LogLevel == "" {
 		stored.LogLevel = "warn"
 	}
@@ -410,21 +516,46 @@ func (h *Hub) persistConfig(cfg Config) error {
 		stored.LogFormat = "text"
 	}
 
-	return savePersistentConfig(configFilePath, stored)
+	if err := savePersistentConfig(configFilePath, stored); err != nil {
+		return err
+	}
+	h.recordConfigWrite()
+	return nil
 }
 
-// TrackSample captures telemetry for a single tracked source.
+// TrackSample captures telemetry for a single tracked source. State and Score
+// are optional track-manager outputs (tentative/confirmed/lost and the 0..1
+// track quality score).
 type TrackSample struct {
-	ID         string     `json:"id,omitempty"`
-	AngleDeg   float64    `json:"angleDeg"`
-	Peak       float64    `json:"peak"`
-	SNR        float64    `json:"snr"`
-	Confidence float64    `json:"trackingConfidence"`
-	LockState  LockState  `json:"lockState"`
-	Range      float64    `json:"range,omitempty"`
-	AgeSeconds float64    `json:"ageSeconds,omitempty"`
-	Debug      *DebugInfo `json:"debug,omitempty"`
-}
+	ID         string    `json:"id,omitempty"`
+	Device     string    `json:"device,omitempty"`
+	AngleDeg   float64   `json:"angleDeg"`
+	Peak       float64   `json:"peak"`
+	SNR        float64   `json:"snr"`
+	Confidence float64   `json:"trackingConfidence"`
+	LockState  LockState `json:"lockState"`
+	// Frame names the reference frame of BearingDeg (array, vehicle or true).
+	Frame      string  `json:"frame,omitempty"`
+	BearingDeg float64 `json:"bearingDeg"`
+	State      string  `json:"state,omitempty"`
+	Score      float64 `json:"score,omitempty"`
+	Range      float64 `json:"range,omitempty"`
+	AgeSeconds float64 `json:"ageSeconds,omitempty"`
+	// Polarization is the dominant polarization of a dual-polarized array
+	// (PolarizationHorizontal or PolarizationVertical) and
+	// PolarizationRatioDB the horizontal to vertical power ratio.
+	Polarization        string  `json:"polarization,omitempty"`
+	PolarizationRatioDB float64 `json:"polarizationRatioDb,omitempty"`
+	// Display repeats the bearing and peak power in the configured display units.
+	Display *DisplayValues `json:"display,omitempty"`
+	Debug   *DebugInfo     `json:"debug,omitempty"`
+}
+
+// Dominant polarizations reported in TrackSample.Polarization.
+const (
+	PolarizationHorizontal = "H"
+	PolarizationVertical   = "V"
+)
 
 // Sample captures a telemetry point for visualization. For multi-track data the
 // top-level fields mirror the first track, while Tracks contains the full
@@ -440,10 +571,22 @@ type Sample struct {
 	Tracks     []TrackSample `json:"tracks,omitempty"`
 }
 
-// MultiTrackSample captures a telemetry update with multiple tracks.
+// MultiTrackSample captures a telemetry update with multiple tracks. Seq is
+// assigned by the Hub and increases by one per recorded update; it serves as
+// the SSE event ID and the resume cursor. Gap is set on the first sample
+// after a restart restored from the journal; plots should not join it to the
+// sample before. MonotonicMs is Timestamp on the monotonic clock (see
+// MonotonicMs), set by the Hub from the sample's age on receipt; it is zero
+// for samples restored from an earlier run. ClockStep marks the first sample
+// after a wall-clock step, whose Timestamp is not comparable with the ones
+// before.
 type MultiTrackSample struct {
-	Timestamp time.Time     `json:"timestamp"`
-	Tracks    []TrackSample `json:"tracks"`
+	Seq         uint64        `json:"seq,omitempty"`
+	Timestamp   time.Time     `json:"timestamp"`
+	MonotonicMs float64       `json:"monotonicMs,omitempty"`
+	Gap         bool          `json:"gap,omitempty"`
+	ClockStep   bool          `json:"clockStep,omitempty"`
+	Tracks      []TrackSample `json:"tracks"`
 }
 
 // TrackHistorySample stores a track observation with its timestamp for per-track
@@ -466,10 +609,6 @@ func sampleFromMultiTrack(multi MultiTrackSample) Sample {
 		Tracks:    cloneTracks(multi.Tracks),
 	}
 
-	if sample.Timestamp.IsZero() {
-		sample.Timestamp = time.Now()
-	}
-
 	if len(sample.Tracks) > 0 {
 		primary := sample.Tracks[0]
 		sample.AngleDeg = primary.AngleDeg
@@ -500,10 +639,8 @@ func cloneSample(sample Sample) Sample {
 }
 
 func cloneMultiTrackSample(sample MultiTrackSample) MultiTrackSample {
-	clone := MultiTrackSample{Timestamp: sample.Timestamp, Tracks: cloneTracks(sample.Tracks)}
-	if clone.Timestamp.IsZero() {
-		clone.Timestamp = time.Now()
-	}
+	clone := sample
+	clone.Tracks = cloneTracks(sample.Tracks)
 	return clone
 }
 
@@ -513,15 +650,13 @@ func filterTracks(sample MultiTrackSample, filter map[string]struct{}) (MultiTra
 		return cloned, len(cloned.Tracks) > 0
 	}
 
-	filtered := MultiTrackSample{Timestamp: sample.Timestamp}
+	filtered := sample
+	filtered.Tracks = nil
 	for _, track := range sample.Tracks {
 		if _, ok := filter[track.ID]; ok {
 			filtered.Tracks = append(filtered.Tracks, track)
 		}
 	}
-	if filtered.Timestamp.IsZero() {
-		filtered.Timestamp = time.Now()
-	}
 	return filtered, len(filtered.Tracks) > 0
 }
 
@@ -545,6 +680,30 @@ type DebugInfo struct {
 	PhaseDelayDeg     float64   `json:"phaseDelayDeg"`
 	MonopulsePhaseRad float64   `json:"monopulsePhaseRad"`
 	Peak              PeakDebug `json:"peak"`
+	// FreqOffsetHz is the residual tone frequency error after correction.
+	FreqOffsetHz float64 `json:"freqOffsetHz,omitempty"`
+	// FreqErrorPPM is the oscillator error measured before correction.
+	FreqErrorPPM float64 `json:"freqErrorPpm,omitempty"`
+	// CFOCorrectionHz is the shift applied by the CFO tracking loop.
+	CFOCorrectionHz float64 `json:"cfoCorrectionHz,omitempty"`
+	// Overload is set while any RX channel clips; ClipFraction holds the
+	// clipped share of samples per channel and RxGainDB the applied gains.
+	Overload     bool      `json:"overload,omitempty"`
+	ClipFraction []float64 `json:"clipFraction,omitempty"`
+	RxGainDB     []int     `json:"rxGainDb,omitempty"`
+	// PatternCorrectionDB is the element pattern correction added to the
+	// reported peak and SNR.
+	PatternCorrectionDB float64 `json:"patternCorrectionDb,omitempty"`
+	// Rx0PowerDBFS and Rx1PowerDBFS are the mean power of each raw channel
+	// buffer. PhaseDiffDeg is the phase of RX1 relative to RX0 over the
+	// buffer before phase calibration, and PhaseDiffVarDeg2 its circular
+	// variance over the last iterations.
+	Rx0PowerDBFS     float64 `json:"rx0PowerDbfs,omitempty"`
+	Rx1PowerDBFS     float64 `json:"rx1PowerDbfs,omitempty"`
+	PhaseDiffDeg     float64 `json:"phaseDiffDeg,omitempty"`
+	PhaseDiffVarDeg2 float64 `json:"phaseDiffVarDeg2,omitempty"`
+	// ScanStepDeg is the phase step of the coarse scan behind the sample.
+	ScanStepDeg float64 `json:"scanStepDeg,omitempty"`
 }
 
 // PeakDebug enriches peak measurements with FFT bin context.
@@ -571,6 +730,8 @@ type ProcessMetrics struct {
 	LastSample       time.Time     `json:"lastSample"`
 	IterationLast    time.Duration `json:"iterationLast"`
 	IterationAvg     time.Duration `json:"iterationAvg"`
+	ClockSteps       int           `json:"clockSteps"`              // wall-clock steps seen since start
+	LastClockStep    time.Duration `json:"lastClockStep,omitempty"` // size of the latest step
 }
 
 // SpectrumSnapshot represents the latest FFT power bins.
@@ -591,9 +752,11 @@ type SignalQuality struct {
 
 // DiagnosticEvent captures a notable runtime change for operator insight.
 type DiagnosticEvent struct {
-	Timestamp time.Time `json:"timestamp"`
-	Level     string    `json:"level"`
-	Message   string    `json:"message"`
+	ID          uint64    `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	MonotonicMs float64   `json:"monotonicMs"`
+	Level       string    `json:"level"`
+	Message     string    `json:"message"`
 }
 
 // Diagnostics bundles runtime metrics and spectrum data.
@@ -613,6 +776,7 @@ type HealthStatus struct {
 	Process ProcessMetrics `json:"process"`
 	Reason  string         `json:"reason,omitempty"`
 	Checks  []HealthCheck  `json:"checks,omitempty"`
+	Leaks   *LeakReport    `json:"leaks,omitempty"` // set once WatchLeaks has run a check
 }
 
 // HealthCheck captures the outcome of a recent health probe.
@@ -625,29 +789,62 @@ type HealthCheck struct {
 
 // Hub collects history and fan-outs telemetry updates to subscribers.
 type Hub struct {
-	mu             sync.RWMutex
-	history        []MultiTrackSample
-	trackHistory   map[string][]TrackHistorySample
-	historyLimit   int
-	subscribers    map[chan MultiTrackSample]struct{}
-	config         Config
-	logger         logging.Logger
-	startTime      time.Time
-	process        ProcessMetrics
-	latestSpectrum *SpectrumSnapshot
-	mockSpectrum   SpectrumSnapshot
-	totalSamples   int64
-	lastSample     *MultiTrackSample
-	lastPrimary    *TrackSample
-	lastReportTime time.Time
-	iterationAvg   time.Duration
-	iterationLast  time.Duration
-	lastCPUSeconds float64
-	lastCPUTick    time.Time
-	events         []DiagnosticEvent
-	eventLimit     int
-	lastLockState  LockState
-	version        string
+	mu           sync.RWMutex
+	history      []MultiTrackSample
+	trackHistory map[string][]TrackHistorySample
+	historyLimit int
+	subscribers  map[chan MultiTrackSample]struct{}
+	config       Config
+	// configRevision counts applied config changes; configRevisions keeps
+	// the latest ones for conflict reports (see configrev.go).
+	configRevision  uint64
+	configRevisions []revisionedConfig
+	configMu        sync.Mutex // serializes config updates; taken before mu
+	leaks           *leakState // nil until the first leak check (see leaks.go)
+	logger          logging.Logger
+	startTime       time.Time
+	process         ProcessMetrics
+	latestSpectrum  *SpectrumSnapshot
+	mockSpectrum    SpectrumSnapshot
+	totalSamples    int64
+	seq             uint64
+	lastSample      *MultiTrackSample
+	lastPrimary     *TrackSample
+	lastReportTime  time.Time // receipt of the last sample, with monotonic reading
+	clock           clockWatch
+	iterationAvg    time.Duration
+	iterationLast   time.Duration
+	lastCPUSeconds  float64
+	lastCPUTick     time.Time
+	events          eventLog // see events.go
+	lastLockState   LockState
+	version         string
+	devices         map[string]*DeviceInfo
+	sectors         *sectorCombiner
+	audit           *auditLog
+	frames          *frameState
+	backendStates   map[string]sdr.LifecycleEvent
+	occupancy       map[string]Occupancy
+	steering        map[string]Steering
+	convergence     map[string]Convergence
+	sensors         map[string][]sdr.Sensors
+	sweeps          map[string]Sweep
+	waterfall       map[string][]WaterfallRow // see waterfall.go
+	waterfallCfg    WaterfallConfig
+	waterfallSubs   map[chan WaterfallRow]struct{}
+	steeringSubs    map[chan Steering]struct{}
+	steeringGates   map[string]*outputGate[Steering]
+	output          OutputConfig // see output.go
+	bearingLineM    float64
+	recordingOff    bool // set by SetRecording; samples are still streamed live
+	journal         *journal
+	store           Store // see store.go
+	storeSamples    []MultiTrackSample
+	storeEvents     []DiagnosticEvent
+	storeDropped    uint64
+	gapPending      bool // mark the next sample as following a restart gap
+	watch           configWatch
+	timeSource      clock.Clock // see SetClock
 }
 
 // NewHub builds a telemetry hub with the provided history limit.
@@ -670,15 +867,33 @@ func NewHub(historyLimit int, logger logging.Logger) *Hub {
 	}
 	cfg, _ = validateConfig(cfg, defaultConfig())
 	h := &Hub{
-		historyLimit: cfg.HistoryLimit,
-		subscribers:  make(map[chan MultiTrackSample]struct{}),
-		trackHistory: make(map[string][]TrackHistorySample),
-		config:       cfg,
-		logger:       logger.With(logging.Field{Key: "subsystem", Value: "telemetry"}),
-		startTime:    time.Now(),
-		eventLimit:   100,
-		version:      resolveVersion(),
-	}
+		historyLimit:  cfg.HistoryLimit,
+		subscribers:   make(map[chan MultiTrackSample]struct{}),
+		trackHistory:  make(map[string][]TrackHistorySample),
+		devices:       make(map[string]*DeviceInfo),
+		sectors:       newSectorCombiner(),
+		audit:         &auditLog{},
+		frames:        newFrameState(),
+		backendStates: make(map[string]sdr.LifecycleEvent),
+		occupancy:     make(map[string]Occupancy),
+		steering:      make(map[string]Steering),
+		convergence:   make(map[string]Convergence),
+		sensors:       make(map[string][]sdr.Sensors),
+		sweeps:        make(map[string]Sweep),
+		waterfall:     make(map[string][]WaterfallRow),
+		waterfallCfg:  DefaultWaterfallConfig(),
+		waterfallSubs: make(map[chan WaterfallRow]struct{}),
+		steeringSubs:  make(map[chan Steering]struct{}),
+		steeringGates: make(map[string]*outputGate[Steering]),
+		bearingLineM:  defaultBearingLineM,
+		config:        cfg,
+		logger:        logger.With(logging.Field{Key: "subsystem", Value: "telemetry"}),
+		timeSource:    clock.Real,
+		events:        newEventLog(defaultEventLimit),
+		version:       buildinfo.Get().Version,
+	}
+	h.startTime = h.now()
+	h.recordRevisionLocked(cfg)
 	h.mockSpectrum = mockSpectrumSnapshot()
 	h.process = h.collectProcessMetrics()
 	h.recordEvent("info", "telemetry hub initialized")
@@ -689,7 +904,7 @@ func NewHub(historyLimit int, logger logging.Logger) *Hub {
 // Report implements Reporter and records a new telemetry sample.
 func (h *Hub) Report(angleDeg float64, peak float64, snr float64, confidence float64, state LockState, debug *DebugInfo) {
 	h.ReportMultiTrack(MultiTrackSample{
-		Timestamp: time.Now(),
+		Timestamp: h.now(),
 		Tracks: []TrackSample{{
 			AngleDeg:   angleDeg,
 			Peak:       peak,
@@ -703,19 +918,24 @@ func (h *Hub) Report(angleDeg float64, peak float64, snr float64, confidence flo
 
 // ReportMultiTrack records a telemetry update that can include multiple tracks.
 func (h *Hub) ReportMultiTrack(multi MultiTrackSample) {
+	if multi.Timestamp.IsZero() {
+		multi.Timestamp = h.now()
+	}
 	sample := cloneMultiTrackSample(multi)
 	if len(sample.Tracks) == 0 {
 		return
 	}
 
 	h.mu.RLock()
-	debugEnabled := h.config.DebugMode
+	cfg := h.config
 	h.mu.RUnlock()
 
-	if !debugEnabled {
-		for i := range sample.Tracks {
+	h.frames.apply(sample.Tracks, sample.Timestamp)
+	for i := range sample.Tracks {
+		if !cfg.DebugMode {
 			sample.Tracks[i].Debug = nil
 		}
+		sample.Tracks[i].Display = displayValues(sample.Tracks[i], cfg)
 	}
 
 	primaryLockState := sample.Tracks[0].LockState
@@ -725,8 +945,14 @@ func (h *Hub) ReportMultiTrack(multi MultiTrackSample) {
 		h.recordEventLocked("info", fmt.Sprintf("lock state changed to %s", primaryLockState))
 	}
 	h.totalSamples++
+	h.seq++
+	sample.Seq = h.seq
+	sample.Gap, h.gapPending = h.gapPending, false
+	now := h.now()
+	sample.MonotonicMs = monotonicAt(sample.Timestamp, now)
+	sample.ClockStep = h.observeClockLocked(now)
 	if !h.lastReportTime.IsZero() {
-		h.iterationLast = sample.Timestamp.Sub(h.lastReportTime)
+		h.iterationLast = now.Sub(h.lastReportTime)
 		if h.iterationAvg == 0 {
 			h.iterationAvg = h.iterationLast
 		} else {
@@ -734,7 +960,7 @@ func (h *Hub) ReportMultiTrack(multi MultiTrackSample) {
 			h.iterationAvg = time.Duration((1-alpha)*float64(h.iterationAvg) + alpha*float64(h.iterationLast))
 		}
 	}
-	h.lastReportTime = sample.Timestamp
+	h.lastReportTime = now
 	h.lastSample = &sample
 	h.lastLockState = primaryLockState
 	h.lastPrimary = nil
@@ -742,6 +968,29 @@ func (h *Hub) ReportMultiTrack(multi MultiTrackSample) {
 		primary := sample.Tracks[0]
 		h.lastPrimary = &primary
 	}
+	if !h.recordingOff {
+		h.appendHistoryLocked(sample)
+		h.journalLocked(journalEntry{Kind: journalSample, Time: sample.Timestamp, Sample: &sample})
+		h.queueStoreSampleLocked(sample)
+	}
+	for ch := range h.subscribers {
+		select {
+		case ch <- sample:
+		default:
+		}
+	}
+	h.mu.Unlock()
+}
+
+// SetRecording enables or disables storing samples in the history served by
+// /api/history and the track history. Live streaming is unaffected.
+func (h *Hub) SetRecording(enabled bool) {
+	h.mu.Lock()
+	h.recordingOff = !enabled
+	h.mu.Unlock()
+}
+
+func (h *Hub) appendHistoryLocked(sample MultiTrackSample) {
 	h.history = append(h.history, cloneMultiTrackSample(sample))
 	if len(h.history) > h.historyLimit {
 		h.history = h.history[len(h.history)-h.historyLimit:]
@@ -756,13 +1005,6 @@ func (h *Hub) ReportMultiTrack(multi MultiTrackSample) {
 			h.trackHistory[track.ID] = h.trackHistory[track.ID][len(h.trackHistory[track.ID])-h.historyLimit:]
 		}
 	}
-	for ch := range h.subscribers {
-		select {
-		case ch <- sample:
-		default:
-		}
-	}
-	h.mu.Unlock()
 }
 
 func (h *Hub) recordEvent(level, message string) {
@@ -772,15 +1014,16 @@ func (h *Hub) recordEvent(level, message string) {
 }
 
 func (h *Hub) recordEventLocked(level, message string) {
-	event := DiagnosticEvent{Timestamp: time.Now(), Level: level, Message: message}
-	h.events = append(h.events, event)
-	if len(h.events) > h.eventLimit {
-		h.events = h.events[len(h.events)-h.eventLimit:]
+	now := h.now()
+	if event, ok := h.events.add(DiagnosticEvent{Timestamp: now, MonotonicMs: MonotonicMs(now), Level: level, Message: message}); ok {
+		h.queueStoreEventLocked(event)
 	}
 }
 
-// LogEvent records an event to the diagnostic event log.
-// This method is thread-safe and can be called from external components like SDR backends.
+// LogEvent records an event to the diagnostic event log and sends it to the
+// /api/events/stream subscribers. Levels are debug, info, warn and error;
+// unknown levels are stored as info. It is safe for concurrent use by
+// backends, wrappers and the tracker (see sdr.EventLogger).
 func (h *Hub) LogEvent(level, message string) {
 	h.recordEvent(level, message)
 }
@@ -847,7 +1090,7 @@ func (h *Hub) trackSnapshots(filter map[string]struct{}) []TrackSnapshot {
 func (h *Hub) UpdateSpectrumSnapshot(bins []float64, source string) {
 	copyBins := append([]float64(nil), bins...)
 	snapshot := &SpectrumSnapshot{
-		Timestamp: time.Now(),
+		Timestamp: h.now(),
 		Bins:      copyBins,
 		Source:    source,
 	}
@@ -905,11 +1148,13 @@ func (m MultiReporter) ReportMultiTrack(sample MultiTrackSample) {
 
 func (h *Hub) applyConfig(cfg Config) {
 	h.config = cfg
+	h.recordRevisionLocked(cfg)
 	h.historyLimit = cfg.HistoryLimit
 	if len(h.history) > h.historyLimit {
 		h.history = h.history[len(h.history)-h.historyLimit:]
 	}
 	h.recordEventLocked("info", "configuration updated")
+	h.journalLocked(journalEntry{Kind: journalConfig, Time: h.now(), Config: &cfg})
 }
 
 func (h *Hub) runProcessSampler(interval time.Duration) {
@@ -937,11 +1182,15 @@ func (h *Hub) collectProcessMetrics() ProcessMetrics {
 	lastSample := h.lastSample
 	iterationAvg := h.iterationAvg
 	iterationLast := h.iterationLast
+	clockSteps, lastClockStep := h.clock.steps, h.clock.lastStep
 	prevCPUSeconds := h.lastCPUSeconds
 	prevCPUTick := h.lastCPUTick
 	h.mu.RUnlock()
 
+	// CPU usage is measured on real time; uptime and the update are on the
+	// hub's clock like the samples it reports.
 	now := time.Now()
+	clockNow := h.now()
 	cpuSeconds := readProcessCPUSeconds()
 	cpuPercent := 0.0
 	if !prevCPUTick.IsZero() {
@@ -958,15 +1207,15 @@ func (h *Hub) collectProcessMetrics() ProcessMetrics {
 	h.mu.Unlock()
 
 	updateRate := 0.0
-	uptimeSeconds := now.Sub(start).Seconds()
+	uptimeSeconds := clockNow.Sub(start).Seconds()
 	if uptimeSeconds > 0 {
 		updateRate = float64(samples) / uptimeSeconds
 	}
 
 	metrics := ProcessMetrics{
 		StartTime:        start,
-		LastUpdated:      now,
-		Uptime:           now.Sub(start),
+		LastUpdated:      clockNow,
+		Uptime:           clockNow.Sub(start),
 		MemoryAlloc:      mem.Alloc,
 		MemoryTotalAlloc: mem.TotalAlloc,
 		MemorySys:        mem.Sys,
@@ -978,6 +1227,8 @@ func (h *Hub) collectProcessMetrics() ProcessMetrics {
 		UpdateRateHz:     updateRate,
 		IterationAvg:     iterationAvg,
 		IterationLast:    iterationLast,
+		ClockSteps:       clockSteps,
+		LastClockStep:    lastClockStep,
 	}
 	if lastSample != nil {
 		metrics.LastSample = lastSample.Timestamp
@@ -1004,7 +1255,7 @@ func (h *Hub) spectrumSnapshot() SpectrumSnapshot {
 
 	if snapshot == nil {
 		return SpectrumSnapshot{
-			Timestamp: mock.Timestamp,
+			Timestamp: h.now(),
 			Bins:      append([]float64(nil), mock.Bins...),
 			Source:    mock.Source,
 		}
@@ -1019,9 +1270,8 @@ func (h *Hub) spectrumSnapshot() SpectrumSnapshot {
 
 func mockSpectrumSnapshot() SpectrumSnapshot {
 	return SpectrumSnapshot{
-		Timestamp: time.Now(),
-		Source:    "mock",
-		Bins:      []float64{-80, -60, -40, -20, 0, -20, -40, -60},
+		Source: "mock",
+		Bins:   []float64{-80, -60, -40, -20, 0, -20, -40, -60},
 	}
 }
 
@@ -1083,18 +1333,6 @@ func readRSSBytes() uint64 {
 	return 0
 }
 
-func resolveVersion() string {
-	if info, ok := debug.ReadBuildInfo(); ok {
-		if info.Main.Version != "" && info.Main.Version != "(devel)" {
-			return info.Main.Version
-		}
-		if info.Main.Path != "" {
-			return info.Main.Path
-		}
-	}
-	return "dev"
-}
-
 func estimateNoiseFloor(bins []float64) float64 {
 	if len(bins) == 0 {
 		return 0
@@ -1134,9 +1372,7 @@ func (h *Hub) signalQuality(snapshot SpectrumSnapshot) SignalQuality {
 func (h *Hub) recentEvents() []DiagnosticEvent {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	out := make([]DiagnosticEvent, len(h.events))
-	copy(out, h.events)
-	return out
+	return h.events.since(0, 0)
 }
 
 func severityRank(status string) int {
@@ -1172,7 +1408,7 @@ func healthSeverity(value float64, warn float64, critical float64) string {
 func (h *Hub) healthStatus() HealthStatus {
 	process := h.collectProcessMetrics()
 	spectrum := h.spectrumSnapshot()
-	now := time.Now()
+	now := h.now()
 	checks := []HealthCheck{}
 	status := "ok"
 	reason := ""
@@ -1213,7 +1449,17 @@ func (h *Hub) healthStatus() HealthStatus {
 	goStatus := healthSeverity(float64(process.NumGoroutine), 500, 1000)
 	addCheck("goroutines", goStatus, fmt.Sprintf("%d goroutines", process.NumGoroutine))
 
-	return HealthStatus{Status: status, Version: h.version, Process: process, Reason: reason, Checks: checks}
+	var leaks *LeakReport
+	if report, ok := h.LeakReport(); ok {
+		detail := "no growth over baseline"
+		if len(report.Anomalies) > 0 {
+			detail = "possible leak: " + strings.Join(report.Anomalies, "; ")
+		}
+		addCheck("leaks", report.Status, detail)
+		leaks = &report
+	}
+
+	return HealthStatus{Status: status, Version: h.version, Process: process, Reason: reason, Checks: checks, Leaks: leaks}
 }
 
 func writeJSONError(w http.ResponseWriter, status int, msg string) {
@@ -1239,9 +1485,25 @@ func parseTrackIDs(r *http.Request) []string {
 }
 
 func (h *Hub) handleHistory(w http.ResponseWriter, r *http.Request) {
+	after, err := h.resumeCursor(r.URL.Query().Get("after"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	tracks := parseTrackIDs(r)
-	_ = json.NewEncoder(w).Encode(h.History(tracks...))
+	device := parseDevice(r)
+	history := h.History(tracks...)
+	out := make([]MultiTrackSample, 0, len(history))
+	for _, sample := range history {
+		if sample.Seq <= after {
+			continue
+		}
+		if filtered, ok := filterDevice(sample, device); ok {
+			out = append(out, filtered)
+		}
+	}
+	_ = json.NewEncoder(w).Encode(out)
 }
 
 func (h *Hub) handleTracks(w http.ResponseWriter, r *http.Request) {
@@ -1250,11 +1512,22 @@ func (h *Hub) handleTracks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	trackIDs := parseTrackIDs(r)
-	filter := trackFilterSet(trackIDs)
+	if r.URL.Query().Get("view") == "unified" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(h.UnifiedTracks())
+		return
+	}
+
+	query, err := parseTrackQuery(r, h.now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	out, total := h.QueryTracks(query)
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(h.trackSnapshots(filter))
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	_ = json.NewEncoder(w).Encode(out)
 }
 
 func (h *Hub) handleTrackHistory(w http.ResponseWriter, r *http.Request) {
@@ -1282,7 +1555,7 @@ func (h *Hub) handleTrackHistory(w http.ResponseWriter, r *http.Request) {
 
 func (h *Hub) handleGetConfig(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(h.ConfigSnapshot())
+	_ = json.NewEncoder(w).Encode(h.ConfigDocument())
 }
 
 func (h *Hub) handleSetConfig(w http.ResponseWriter, r *http.Request) {
@@ -1291,34 +1564,82 @@ func (h *Hub) handleSetConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var incoming Config
-	if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
 		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid config payload: %v", err))
 		return
 	}
+	doc, conflict, status, err := h.updateConfig(r, raw)
+	if conflict != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(conflict)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(doc)
+}
 
+// UpdateConfig applies raw, a partial Config with the revision it was
+// edited from, as POST /api/config/update does, for control planes outside
+// HTTP. A stale revision returns the conflict and an error; the change is
+// audited with the source "local".
+func (h *Hub) UpdateConfig(raw []byte) (ConfigDocument, *ConfigConflict, error) {
+	doc, conflict, _, err := h.updateConfig(nil, raw)
+	return doc, conflict, err
+}
+
+// updateConfig validates, applies, audits and persists a config update made
+// by the client behind r, which may be nil. status is the HTTP status for
+// err.
+func (h *Hub) updateConfig(r *http.Request, raw []byte) (ConfigDocument, *ConfigConflict, int, error) {
+	var update configUpdate
+	if err := json.Unmarshal(raw, &update); err != nil {
+		return ConfigDocument{}, nil, http.StatusBadRequest, fmt.Errorf("invalid config payload: %v", err)
+	}
+	if update.Revision == nil {
+		return ConfigDocument{}, nil, http.StatusPreconditionRequired, errors.New("config revision required: send the revision returned by GET /api/config")
+	}
+
+	// Check the revision, persist and apply under h.configMu, so concurrent
+	// updates naming the same revision cannot both succeed. Fields missing
+	// from the payload keep their current value.
+	h.configMu.Lock()
+	defer h.configMu.Unlock()
 	h.mu.RLock()
 	current := h.config
-	h.mu.RUnlock()
-
-	cfg, err := validateConfig(incoming, current)
+	update.Config = current
+	_ = json.Unmarshal(raw, &update) // already decoded once above
+	cfg, err := validateConfig(update.Config, current)
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
-		return
+		h.mu.RUnlock()
+		return ConfigDocument{}, nil, http.StatusBadRequest, err
 	}
+	if *update.Revision != h.configRevision {
+		conflict := h.configConflictLocked(*update.Revision, raw)
+		h.mu.RUnlock()
+		return ConfigDocument{}, &conflict, http.StatusConflict, errors.New(conflict.Error)
+	}
+	h.mu.RUnlock()
 
-	h.mu.Lock()
-	h.applyConfig(cfg)
-	h.mu.Unlock()
-
+	// Save first: a failed write leaves the config, its revision and the
+	// audit log as they were.
 	if err := h.persistConfig(cfg); err != nil {
 		h.logger.Warn("failed to persist config", logging.Field{Key: "error", Value: err})
-		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to save config: %v", err))
-		return
+		return ConfigDocument{}, nil, http.StatusInternalServerError, fmt.Errorf("failed to save config: %v", err)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(cfg)
+	h.mu.Lock()
+	h.applyConfig(cfg)
+	revision := h.configRevision
+	h.mu.Unlock()
+	h.markConfigUpdated(h.now())
+	h.recordConfigAudit(r, current, cfg)
+	return ConfigDocument{Config: cfg, Revision: revision}, nil, http.StatusOK, nil
 }
 
 func (h *Hub) handleLive(w http.ResponseWriter, r *http.Request) {
@@ -1327,8 +1648,25 @@ func (h *Hub) handleLive(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
 		return
 	}
+	rate, err := parseLiveRate(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// EventSource sends Last-Event-ID on reconnect; ?after= is the same
+	// cursor for clients that cannot set headers.
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("after")
+	}
+	lastSeq, err := h.resumeCursor(cursor)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	trackIDs := parseTrackIDs(r)
 	filter := trackFilterSet(trackIDs)
+	device := parseDevice(r)
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -1336,16 +1674,30 @@ func (h *Hub) handleLive(w http.ResponseWriter, r *http.Request) {
 	ch, cancel := h.Subscribe()
 	defer cancel()
 
-	// send existing history for immediate display
+	// With ?rate= only the latest sample is kept and sent on each tick.
+	var tick <-chan time.Time
+	var pending *MultiTrackSample
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// send existing history for immediate display, or only the samples
+	// missed since the client's cursor when resuming
 	for _, sample := range h.History(trackIDs...) {
+		if sample.Seq <= lastSeq {
+			continue
+		}
+		lastSeq = sample.Seq
 		filtered, ok := filterTracks(sample, filter)
+		if ok {
+			filtered, ok = filterDevice(filtered, device)
+		}
 		if !ok {
 			continue
 		}
-		payload, _ := json.Marshal(filtered)
-		w.Write([]byte("data: "))
-		w.Write(payload)
-		w.Write([]byte("\n\n"))
+		writeLiveSample(w, filtered)
 	}
 	flusher.Flush()
 
@@ -1355,21 +1707,92 @@ func (h *Hub) handleLive(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
+			// skip samples already replayed from history
+			if sample.Seq <= lastSeq {
+				continue
+			}
+			lastSeq = sample.Seq
 			filtered, ok := filterTracks(sample, filter)
+			if ok {
+				filtered, ok = filterDevice(filtered, device)
+			}
 			if !ok {
 				continue
 			}
-			payload, _ := json.Marshal(filtered)
-			w.Write([]byte("data: "))
-			w.Write(payload)
-			w.Write([]byte("\n\n"))
+			if tick != nil {
+				pending = &filtered
+				continue
+			}
+			writeLiveSample(w, filtered)
+			flusher.Flush()
+		case <-tick:
+			if pending == nil {
+				continue
+			}
+			writeLiveSample(w, *pending)
 			flusher.Flush()
+			pending = nil
 		case <-r.Context().Done():
 			return
 		}
 	}
 }
 
+func writeLiveSample(w http.ResponseWriter, sample MultiTrackSample) {
+	payload, _ := json.Marshal(sample)
+	fmt.Fprintf(w, "id: %d\n", sample.Seq)
+	w.Write([]byte("data: "))
+	w.Write(payload)
+	w.Write([]byte("\n\n"))
+}
+
+// parseSeqCursor parses a sample sequence cursor; empty means from the start.
+func parseSeqCursor(raw string) (uint64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sequence cursor %q", raw)
+	}
+	return seq, nil
+}
+
+// resumeCursor parses a client's sequence cursor. A cursor ahead of the hub
+// was issued before a restart that lost or rolled back the sequence; it is
+// treated as a stream reset, so the client gets the history again rather
+// than nothing until the sequence catches up with the stale cursor.
+func (h *Hub) resumeCursor(raw string) (uint64, error) {
+	seq, err := parseSeqCursor(raw)
+	if err != nil {
+		return 0, err
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if seq > h.seq {
+		return 0, nil
+	}
+	return seq, nil
+}
+
+// maxLiveRate bounds ?rate= so the tick period stays positive.
+const maxLiveRate = 1000
+
+// parseLiveRate reads the optional ?rate= update frequency (Hz) of the live
+// stream. Zero means every sample is forwarded.
+func parseLiveRate(r *http.Request) (float64, error) {
+	raw := r.URL.Query().Get("rate")
+	if raw == "" {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(raw, 64)
+	if err != nil || rate <= 0 || rate > maxLiveRate {
+		return 0, fmt.Errorf("invalid rate %q: want 0 < updates per second <= %d", raw, maxLiveRate)
+	}
+	return rate, nil
+}
+
 func (h *Hub) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -1473,6 +1896,16 @@ func (h *Hub) handleHealth(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(h.healthStatus())
 }
 
+// handleVersion returns the build information of the running binary.
+func (h *Hub) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(buildinfo.Get())
+}
+
 func (h *Hub) handleSpectrumSnapshot(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -1,12 +1,17 @@
 package telemetry
 
 import (
+	"bufio"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/rjboer/GoSDR/internal/buildinfo"
 	"github.com/rjboer/GoSDR/internal/logging"
 )
 
@@ -171,3 +176,228 @@ func TestHandleHealthMethodNotAllowed(t *testing.T) {
 		t.Fatalf("expected 405, got %d", rr.Code)
 	}
 }
+
+func TestHandleLiveRateKeepsLatestSample(t *testing.T) {
+	hub := newTestHub()
+	srv := httptest.NewServer(http.HandlerFunc(hub.handleLive))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?rate=2")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	for i := 1; i <= 10; i++ {
+		hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{ID: "1", AngleDeg: float64(i)}}})
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				lines <- line
+			}
+		}
+		close(lines)
+	}()
+
+	select {
+	case line := <-lines:
+		var sample MultiTrackSample
+		if err := json.Unmarshal([]byte(line), &sample); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		if len(sample.Tracks) != 1 || sample.Tracks[0].AngleDeg != 10 {
+			t.Fatalf("expected only the latest sample, got %+v", sample)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+	select {
+	case line := <-lines:
+		t.Fatalf("unexpected extra event %s", line)
+	case <-time.After(700 * time.Millisecond):
+	}
+}
+
+func TestHandleLiveRejectsInvalidRate(t *testing.T) {
+	hub := newTestHub()
+	for _, rate := range []string{"0", "-1", "abc", "1e9"} {
+		rr := httptest.NewRecorder()
+		hub.handleLive(rr, httptest.NewRequest(http.MethodGet, "/api/live?rate="+rate, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("rate=%s: expected 400, got %d", rate, rr.Code)
+		}
+	}
+}
+
+func TestHandleLiveResumesFromLastEventID(t *testing.T) {
+	hub := newTestHub()
+	for i := 1; i <= 3; i++ {
+		hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{AngleDeg: float64(i)}}})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(hub.handleLive))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{AngleDeg: 4}}})
+
+	ids := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if id, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+				ids <- id
+			}
+		}
+		close(ids)
+	}()
+	for _, want := range []string{"2", "3", "4"} {
+		select {
+		case got := <-ids:
+			if got != want {
+				t.Fatalf("event id = %s, want %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %s", want)
+		}
+	}
+}
+
+func TestHandleLiveResetsCursorAfterRestart(t *testing.T) {
+	before := newTestHub()
+	for i := 1; i <= 5; i++ {
+		before.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{AngleDeg: float64(i)}}})
+	}
+
+	// The restarted hub has no journal, so its sequence starts over below
+	// the cursor the client kept from the previous run.
+	hub := newTestHub()
+	for i := 1; i <= 2; i++ {
+		hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{AngleDeg: float64(i)}}})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(hub.handleLive))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(before.History()[4].Seq, 10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now(), Tracks: []TrackSample{{AngleDeg: 3}}})
+
+	ids := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if id, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+				ids <- id
+			}
+		}
+		close(ids)
+	}()
+	for _, want := range []string{"1", "2", "3"} {
+		select {
+		case got := <-ids:
+			if got != want {
+				t.Fatalf("event id = %s, want %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %s", want)
+		}
+	}
+}
+
+func TestHandleHistoryAfterCursor(t *testing.T) {
+	hub := newTestHub()
+	for i := 1; i <= 3; i++ {
+		hub.Report(float64(i), 0, 0, 0, LockStateSearching, nil)
+	}
+
+	rr := httptest.NewRecorder()
+	hub.handleHistory(rr, httptest.NewRequest(http.MethodGet, "/api/history?after=2", nil))
+	var out []MultiTrackSample
+	if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != 1 || out[0].Seq != 3 || out[0].Tracks[0].AngleDeg != 3 {
+		t.Fatalf("expected only sample 3, got %+v", out)
+	}
+
+	// A cursor from before a restart is ahead of the hub and replays all.
+	rr = httptest.NewRecorder()
+	hub.handleHistory(rr, httptest.NewRequest(http.MethodGet, "/api/history?after=99", nil))
+	out = nil
+	if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != 3 {
+		t.Fatalf("expected the full history for a stale cursor, got %+v", out)
+	}
+
+	rr = httptest.NewRecorder()
+	hub.handleHistory(rr, httptest.NewRequest(http.MethodGet, "/api/history?after=x", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad cursor, got %d", rr.Code)
+	}
+}
+
+func TestSetRecordingPausesHistory(t *testing.T) {
+	hub := newTestHub()
+	ch, cancel := hub.Subscribe()
+	defer cancel()
+
+	hub.SetRecording(false)
+	hub.Report(10, -12, 15, 0.8, LockStateTracking, nil)
+	if got := len(hub.History()); got != 0 {
+		t.Fatalf("history has %d samples while recording is off", got)
+	}
+	select {
+	case <-ch:
+	default:
+		t.Fatal("live subscribers should still receive samples")
+	}
+
+	hub.SetRecording(true)
+	hub.Report(11, -12, 15, 0.8, LockStateTracking, nil)
+	if got := len(hub.History()); got != 1 {
+		t.Fatalf("history has %d samples, want 1", got)
+	}
+}
+
+func TestHandleVersion(t *testing.T) {
+	hub := newTestHub()
+	rr := httptest.NewRecorder()
+	hub.handleVersion(rr, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+	var got buildinfo.Info
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != hub.version || got.GoVersion == "" || got.Platform == "" {
+		t.Fatalf("unexpected build info %+v", got)
+	}
+}
+
+func TestValidateConfigFFTWindow(t *testing.T) {
+	base := defaultConfig()
+	cfg, err := validateConfig(Config{}, base)
+	if err != nil || cfg.FFTWindow != "hamming" {
+		t.Fatalf("default window %q, %v", cfg.FFTWindow, err)
+	}
+	if cfg, err = validateConfig(Config{FFTWindow: "Blackman-Harris"}, base); err != nil || cfg.FFTWindow != "blackman-harris" {
+		t.Fatalf("window %q, %v", cfg.FFTWindow, err)
+	}
+	if _, err := validateConfig(Config{FFTWindow: "kaiser:x"}, base); err == nil {
+		t.Fatal("expected error for a malformed Kaiser beta")
+	}
+}
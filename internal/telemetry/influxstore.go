@@ -0,0 +1,317 @@
+package telemetry
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// influxStoreTimeout bounds each write and query of an InfluxDB store.
+	influxStoreTimeout = 30 * time.Second
+	// influxBatch is the number of points per write, influxPage per query.
+	influxBatch = 5000
+	influxPage  = 10_000
+	// defaultInfluxMeasurement prefixes the measurement names.
+	defaultInfluxMeasurement = "gosdr_store"
+)
+
+// influxStore keeps samples and events in InfluxDB, for installations whose
+// central time-series database it already is. Each sample or event is one
+// point in <m>_samples or <m>_events whose string field "data" holds its
+// JSON; the tag "seq" numbers the entries of an Append with equal
+// timestamps, which InfluxDB would otherwise merge into one point. It uses
+// the 1.x HTTP API, /write and InfluxQL on /query, which InfluxDB 2.x and
+// 3.x serve for a database mapped to a bucket.
+type influxStore struct {
+	base, db, token string
+	samples, events string // measurement names
+	client          *http.Client
+}
+
+// openInfluxStore opens "influx://host:8086/<database>", or influxs:// for
+// HTTPS, with the optional query parameters token, sent as "Authorization:
+// Token ...", and measurement, the prefix of the measurement names
+// (default gosdr_store). The database must exist.
+func openInfluxStore(dsn string) (Store, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("store %q: want influx://host:port/database", dsn)
+	}
+	scheme := "http"
+	if u.Scheme == "influxs" {
+		scheme = "https"
+	}
+	q := u.Query()
+	m := cmp.Or(q.Get("measurement"), defaultInfluxMeasurement)
+	return &influxStore{
+		base:    scheme + "://" + u.Host,
+		db:      strings.Trim(u.Path, "/"),
+		token:   q.Get("token"),
+		samples: m + "_samples",
+		events:  m + "_events",
+		client:  &http.Client{Timeout: influxStoreTimeout},
+	}, nil
+}
+
+// do sends req with the token and returns the body of a 2xx response.
+func (s *influxStore) do(req *http.Request) ([]byte, error) {
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("influx store %s: %s: %s", req.URL.Path, resp.Status, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+func (s *influxStore) Append(samples []MultiTrackSample, events []DiagnosticEvent) error {
+	var lines []string
+	add := func(measurement string, n int, entry func(int) (time.Time, any)) error {
+		var prev int64
+		seq := 0
+		for i := range n {
+			t, v := entry(i)
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			ts := t.UnixNano()
+			if i > 0 && ts == prev {
+				seq++
+			} else {
+				seq = 0
+			}
+			prev = ts
+			lines = append(lines, influxEscaper.Replace(measurement)+",seq="+strconv.Itoa(seq)+
+				` data="`+influxStringEscaper.Replace(string(data))+`" `+strconv.FormatInt(ts, 10))
+		}
+		return nil
+	}
+	if err := add(s.samples, len(samples), func(i int) (time.Time, any) { return samples[i].Timestamp, samples[i] }); err != nil {
+		return err
+	}
+	if err := add(s.events, len(events), func(i int) (time.Time, any) { return events[i].Timestamp, events[i] }); err != nil {
+		return err
+	}
+	endpoint := s.base + "/write?" + url.Values{"db": {s.db}, "precision": {"ns"}}.Encode()
+	for len(lines) > 0 {
+		n := min(len(lines), influxBatch)
+		req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(strings.Join(lines[:n], "\n")+"\n"))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		if _, err := s.do(req); err != nil {
+			return err
+		}
+		lines = lines[n:]
+	}
+	return nil
+}
+
+// influxSeries is a series of a /query result; with epoch=ns its first
+// column is the time in nanoseconds.
+type influxSeries struct {
+	Columns []string `json:"columns"`
+	Values  [][]any  `json:"values"`
+}
+
+// query runs InfluxQL statements and returns the series of each.
+func (s *influxStore) query(stmts ...string) ([][]influxSeries, error) {
+	values := url.Values{"db": {s.db}, "epoch": {"ns"}, "q": {strings.Join(stmts, "; ")}}
+	req, err := http.NewRequest(http.MethodGet, s.base+"/query?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Results []struct {
+			Series []influxSeries `json:"series"`
+			Error  string         `json:"error"`
+		} `json:"results"`
+		Error string `json:"error"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("influx store query: %w", err)
+	}
+	if out.Error != "" {
+		return nil, fmt.Errorf("influx store query: %s", out.Error)
+	}
+	if len(out.Results) != len(stmts) {
+		return nil, fmt.Errorf("influx store query: %d results for %d statements", len(out.Results), len(stmts))
+	}
+	series := make([][]influxSeries, len(stmts))
+	for i, r := range out.Results {
+		if r.Error != "" {
+			return nil, fmt.Errorf("influx store query: %s", r.Error)
+		}
+		series[i] = r.Series
+	}
+	return series, nil
+}
+
+// influxTime decodes the time column of a row.
+func influxTime(v any) (time.Time, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, fmt.Errorf("influx store: time %v is not in nanoseconds", v)
+	}
+	ns, err := n.Int64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("influx store: time %v: %w", v, err)
+	}
+	return time.Unix(0, ns).UTC(), nil
+}
+
+// scan calls fn with the data of the points of measurement in q's time
+// range, oldest first, until it returns false or an error. The points are
+// read a page at a time, since the filters of fn may skip many.
+func (s *influxStore) scan(measurement string, q StoreQuery, fn func([]byte) (bool, error)) error {
+	var where []string
+	if !q.Since.IsZero() {
+		where = append(where, "time >= "+strconv.FormatInt(q.Since.UnixNano(), 10))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "time < "+strconv.FormatInt(q.Until.UnixNano(), 10))
+	}
+	stmt := `SELECT "data", "seq" FROM ` + influxIdent(measurement)
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	for offset := 0; ; offset += influxPage {
+		series, err := s.query(fmt.Sprintf("%s LIMIT %d OFFSET %d", stmt, influxPage, offset))
+		if err != nil {
+			return err
+		}
+		type row struct {
+			t    time.Time
+			seq  int
+			data string
+		}
+		var rows []row
+		for _, ser := range series[0] {
+			for _, v := range ser.Values {
+				if len(v) != 3 {
+					return fmt.Errorf("influx store: row %v: want time, data and seq", v)
+				}
+				t, err := influxTime(v[0])
+				if err != nil {
+					return err
+				}
+				data, _ := v[1].(string)
+				seq, _ := v[2].(string)
+				n, _ := strconv.Atoi(seq)
+				rows = append(rows, row{t: t, seq: n, data: data})
+			}
+		}
+		// Points of equal time come back in no particular order.
+		sort.SliceStable(rows, func(i, j int) bool {
+			if !rows[i].t.Equal(rows[j].t) {
+				return rows[i].t.Before(rows[j].t)
+			}
+			return rows[i].seq < rows[j].seq
+		})
+		for _, r := range rows {
+			more, err := fn([]byte(r.data))
+			if err != nil || !more {
+				return err
+			}
+		}
+		if len(rows) < influxPage {
+			return nil
+		}
+	}
+}
+
+func (s *influxStore) Samples(q StoreQuery) ([]MultiTrackSample, error) {
+	var out []MultiTrackSample
+	err := s.scan(s.samples, q, func(data []byte) (bool, error) {
+		var sample MultiTrackSample
+		if err := json.Unmarshal(data, &sample); err != nil {
+			return false, err
+		}
+		if sample, ok := q.sample(sample); ok {
+			out = append(out, sample)
+		}
+		return !q.full(len(out)), nil
+	})
+	return out, err
+}
+
+func (s *influxStore) Events(q StoreQuery) ([]DiagnosticEvent, error) {
+	var out []DiagnosticEvent
+	err := s.scan(s.events, q, func(data []byte) (bool, error) {
+		var e DiagnosticEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return false, err
+		}
+		out = append(out, e)
+		return !q.full(len(out)), nil
+	})
+	return out, err
+}
+
+// Span asks for the first and last point of each measurement.
+func (s *influxStore) Span() (first, last time.Time, err error) {
+	var stmts []string
+	for _, m := range []string{s.samples, s.events} {
+		stmts = append(stmts, `SELECT first("data") FROM `+influxIdent(m), `SELECT last("data") FROM `+influxIdent(m))
+	}
+	results, err := s.query(stmts...)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	for _, series := range results {
+		for _, ser := range series {
+			for _, v := range ser.Values {
+				if len(v) == 0 {
+					continue
+				}
+				t, err := influxTime(v[0])
+				if err != nil {
+					return time.Time{}, time.Time{}, err
+				}
+				first, last = widenSpan(first, last, t)
+			}
+		}
+	}
+	return first, last, nil
+}
+
+func (s *influxStore) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+var (
+	influxEscaper       = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// influxIdent quotes an InfluxQL identifier.
+func influxIdent(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
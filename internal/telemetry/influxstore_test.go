@@ -0,0 +1,205 @@
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeInflux serves the part of the InfluxDB 1.x HTTP API the influx store
+// uses, keeping the points of database "gosdr" in memory. Requests must
+// carry the token "secret".
+type fakeInflux struct {
+	mu     sync.Mutex
+	points map[string]map[[2]int64]string // measurement -> time, seq -> data
+}
+
+var (
+	fakeInfluxLine   = regexp.MustCompile(`^(\w+),seq=(\d+) data=("(?:[^"\\]|\\.)*") (-?\d+)$`)
+	fakeInfluxSelect = regexp.MustCompile(`^SELECT "data", "seq" FROM "(\w+)"(?: WHERE (.+?))? LIMIT (\d+) OFFSET (\d+)$`)
+	fakeInfluxEdge   = regexp.MustCompile(`^SELECT (first|last)\("data"\) FROM "(\w+)"$`)
+	fakeInfluxBound  = regexp.MustCompile(`^time (>=|<) (-?\d+)$`)
+)
+
+func newFakeInflux(t *testing.T) *httptest.Server {
+	f := &fakeInflux{points: map[string]map[[2]int64]string{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeInflux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Token secret" {
+		http.Error(w, `{"error":"authorization failed"}`, http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Query().Get("db") != "gosdr" {
+		http.Error(w, `{"error":"database not found"}`, http.StatusNotFound)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/write" && r.URL.Query().Get("precision") == "ns":
+		f.write(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/query" && r.URL.Query().Get("epoch") == "ns":
+		f.query(w, r.URL.Query().Get("q"))
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (f *fakeInflux) write(w http.ResponseWriter, r *http.Request) {
+	sc := bufio.NewScanner(r.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		m := fakeInfluxLine.FindStringSubmatch(sc.Text())
+		if m == nil {
+			http.Error(w, fmt.Sprintf(`{"error":"unable to parse %q"}`, sc.Text()), http.StatusBadRequest)
+			return
+		}
+		data, err := strconv.Unquote(m[3])
+		if err != nil || !json.Valid([]byte(data)) {
+			http.Error(w, `{"error":"bad data field"}`, http.StatusBadRequest)
+			return
+		}
+		seq, _ := strconv.ParseInt(m[2], 10, 64)
+		ts, _ := strconv.ParseInt(m[4], 10, 64)
+		if f.points[m[1]] == nil {
+			f.points[m[1]] = map[[2]int64]string{}
+		}
+		f.points[m[1]][[2]int64{ts, seq}] = data
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sorted returns the keys of the points of measurement in time order; equal
+// times come last seq first, as a real server might.
+func (f *fakeInflux) sorted(measurement string) [][2]int64 {
+	var keys [][2]int64
+	for k := range f.points[measurement] {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] > keys[j][1]
+	})
+	return keys
+}
+
+func (f *fakeInflux) query(w http.ResponseWriter, q string) {
+	type series struct {
+		Name    string   `json:"name"`
+		Columns []string `json:"columns"`
+		Values  [][]any  `json:"values"`
+	}
+	type result struct {
+		StatementID int      `json:"statement_id"`
+		Series      []series `json:"series,omitempty"`
+		Error       string   `json:"error,omitempty"`
+	}
+	var results []result
+	for i, stmt := range strings.Split(q, "; ") {
+		res := result{StatementID: i}
+		if m := fakeInfluxSelect.FindStringSubmatch(stmt); m != nil {
+			since, until := int64(-1<<63), int64(1<<63-1)
+			if m[2] != "" {
+				for _, cond := range strings.Split(m[2], " AND ") {
+					b := fakeInfluxBound.FindStringSubmatch(cond)
+					if b == nil {
+						res.Error = "unsupported condition " + cond
+						break
+					}
+					n, _ := strconv.ParseInt(b[2], 10, 64)
+					if b[1] == ">=" {
+						since = n
+					} else {
+						until = n
+					}
+				}
+			}
+			limit, _ := strconv.Atoi(m[3])
+			offset, _ := strconv.Atoi(m[4])
+			var values [][]any
+			for _, k := range f.sorted(m[1]) {
+				if k[0] >= since && k[0] < until {
+					values = append(values, []any{k[0], f.points[m[1]][k], strconv.FormatInt(k[1], 10)})
+				}
+			}
+			values = values[min(offset, len(values)):]
+			values = values[:min(limit, len(values))]
+			if len(values) > 0 {
+				res.Series = []series{{Name: m[1], Columns: []string{"time", "data", "seq"}, Values: values}}
+			}
+		} else if m := fakeInfluxEdge.FindStringSubmatch(stmt); m != nil {
+			if keys := f.sorted(m[2]); len(keys) > 0 {
+				k := keys[0]
+				if m[1] == "last" {
+					k = keys[len(keys)-1]
+				}
+				res.Series = []series{{Name: m[2], Columns: []string{"time", m[1]}, Values: [][]any{{k[0], f.points[m[2]][k]}}}}
+			}
+		} else {
+			res.Error = "unsupported statement " + stmt
+		}
+		results = append(results, res)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+}
+
+func TestInfluxStore(t *testing.T) {
+	srv := newFakeInflux(t)
+	dsn := strings.Replace(srv.URL, "http://", "influx://", 1) + "/gosdr?token=secret"
+	s, err := OpenStore(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Entries with equal timestamps are all kept, in append order.
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []DiagnosticEvent{
+		{ID: 1, Timestamp: at, Level: "info", Message: `quoted "a" \ b`},
+		{ID: 2, Timestamp: at, Level: "warn", Message: "second,with spaces\nand a newline"},
+		{ID: 3, Timestamp: at.Add(time.Nanosecond), Level: "info", Message: "third"},
+	}
+	if err := s.Append(nil, events); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Events(StoreQuery{})
+	if err != nil || len(got) != 3 {
+		t.Fatalf("events %+v, %v", got, err)
+	}
+	for i, e := range got {
+		if e.ID != events[i].ID || e.Message != events[i].Message || !e.Timestamp.Equal(events[i].Timestamp) {
+			t.Fatalf("event %d = %+v, want %+v", i, e, events[i])
+		}
+	}
+	if got, _ := s.Events(StoreQuery{Since: at.Add(time.Nanosecond)}); len(got) != 1 || got[0].ID != 3 {
+		t.Fatalf("events since %+v", got)
+	}
+
+	for _, bad := range []string{"influx://host:8086", "influx:///gosdr"} {
+		if _, err := OpenStore(bad); err == nil {
+			t.Fatalf("%s accepted", bad)
+		}
+	}
+	wrongToken, _ := OpenStore(strings.Replace(dsn, "secret", "wrong", 1))
+	if err := wrongToken.Append(nil, events); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("append with a wrong token: %v", err)
+	}
+	if _, err := wrongToken.Events(StoreQuery{}); err == nil {
+		t.Fatal("query with a wrong token succeeded")
+	}
+}
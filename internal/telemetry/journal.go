@@ -0,0 +1,328 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+const (
+	// journalSyncInterval bounds how much telemetry a power loss can cost.
+	journalSyncInterval = time.Second
+	// journalCompactInterval is how often the journal is rewritten to the
+	// restore window.
+	journalCompactInterval = 5 * time.Minute
+	// defaultJournalWindow is the restore window used when none is given.
+	defaultJournalWindow = 10 * time.Minute
+)
+
+// Journal entry kinds.
+const (
+	journalSample = "sample"
+	journalConfig = "config"
+)
+
+// journalEntry is one line of the state journal.
+type journalEntry struct {
+	Kind   string            `json:"kind"`
+	Time   time.Time         `json:"time"`
+	Sample *MultiTrackSample `json:"sample,omitempty"`
+	Config *Config           `json:"config,omitempty"`
+}
+
+// journal appends history samples and config applications to a JSON-lines
+// file. Writes are buffered; RunJournal flushes and syncs them every second
+// and periodically compacts the file to the restore window.
+type journal struct {
+	mu     sync.Mutex
+	path   string
+	window time.Duration
+	file   *os.File
+	w      *bufio.Writer
+	err    error // first write error since the last sync, logged once
+	// holding is set while a compaction writes the snapshot it took; the
+	// entries appended meanwhile are kept in held and written to the new
+	// file once it replaced the old one.
+	holding bool
+	held    [][]byte
+}
+
+// OpenJournal enables crash-safe journaling to path. Samples from the last
+// window (10 minutes when non-positive) and the last journaled config are
+// restored first; the next reported sample is then marked as following a gap
+// and the outage is logged. The history limit is kept as configured. Call
+// RunJournal to flush and compact the journal.
+func (h *Hub) OpenJournal(path string, window time.Duration) error {
+	if window <= 0 {
+		window = defaultJournalWindow
+	}
+	entries, err := loadJournal(path)
+	if err != nil {
+		return err
+	}
+	now := h.now()
+	h.restoreJournal(entries, now.Add(-window))
+
+	j := &journal{path: path, window: window}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := j.rewrite(h.config, h.history, now); err != nil {
+		return err
+	}
+	h.journal = j
+	return nil
+}
+
+// restoreJournal replays entries newer than since into the history and
+// applies the last config entry.
+func (h *Hub) restoreJournal(entries []journalEntry, since time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var last time.Time
+	restored := 0
+	for _, entry := range entries {
+		switch {
+		case entry.Kind == journalConfig && entry.Config != nil:
+			cfg, err := validateConfig(*entry.Config, h.config)
+			if err != nil {
+				continue
+			}
+			cfg.HistoryLimit = h.historyLimit
+			h.config = cfg
+		case entry.Kind == journalSample && entry.Sample != nil:
+			last = entry.Sample.Timestamp
+			if entry.Sample.Timestamp.Before(since) || len(entry.Sample.Tracks) == 0 {
+				continue
+			}
+			// Monotonic time does not carry over from the earlier run.
+			entry.Sample.MonotonicMs = 0
+			h.appendHistoryLocked(*entry.Sample)
+			h.seq = max(h.seq, entry.Sample.Seq)
+			restored++
+		}
+	}
+	if last.IsZero() {
+		return
+	}
+	h.gapPending = true
+	h.recordEventLocked("warn", fmt.Sprintf("restored %d samples from journal; telemetry gap since %s", restored, last.UTC().Format(time.RFC3339)))
+}
+
+// RunJournal flushes the journal to disk every second and compacts it every
+// five minutes until ctx is done, then flushes and closes it. It returns at
+// once when no journal is open.
+func (h *Hub) RunJournal(ctx context.Context) {
+	h.mu.RLock()
+	j := h.journal
+	h.mu.RUnlock()
+	if j == nil {
+		return
+	}
+	flush := time.NewTicker(journalSyncInterval)
+	defer flush.Stop()
+	compact := time.NewTicker(journalCompactInterval)
+	defer compact.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.mu.Lock()
+			h.journal = nil
+			h.mu.Unlock()
+			h.logJournalError(j.close())
+			return
+		case <-flush.C:
+			h.logJournalError(j.sync())
+		case <-compact.C:
+			h.logJournalError(h.compactJournal())
+		}
+	}
+}
+
+// compactJournal rewrites the journal to the current config and the history
+// inside the restore window. Only the snapshot is taken under h.mu; samples
+// journaled while the file is rewritten are appended to the new file.
+func (h *Hub) compactJournal() error {
+	h.mu.RLock()
+	j := h.journal
+	if j == nil {
+		h.mu.RUnlock()
+		return nil
+	}
+	now := h.now()
+	start := len(h.history)
+	for start > 0 && inWindow(h.history[start-1], now, j.window) {
+		start--
+	}
+	cfg := h.config
+	samples := slices.Clone(h.history[start:])
+	j.hold()
+	h.mu.RUnlock()
+	return j.rewrite(cfg, samples, now)
+}
+
+// journalLocked appends entry to the journal, if one is open. The caller
+// holds h.mu.
+func (h *Hub) journalLocked(entry journalEntry) {
+	if h.journal != nil {
+		h.journal.append(entry)
+	}
+}
+
+func (h *Hub) logJournalError(err error) {
+	if err != nil {
+		h.logger.Warn("state journal", logging.Field{Key: "error", Value: err})
+	}
+}
+
+func (j *journal) append(entry journalEntry) {
+	data, err := json.Marshal(entry)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case err != nil:
+		if j.err == nil {
+			j.err = err
+		}
+	case j.holding:
+		j.held = append(j.held, append(data, '\n'))
+	default:
+		j.writeLocked(append(data, '\n'))
+	}
+}
+
+func (j *journal) writeLocked(line []byte) {
+	if j.w == nil {
+		return
+	}
+	if _, err := j.w.Write(line); j.err == nil {
+		j.err = err
+	}
+}
+
+// hold keeps appended entries in memory until the next rewrite, which
+// writes them after its snapshot. Call it while the snapshot cannot change.
+func (j *journal) hold() {
+	j.mu.Lock()
+	j.holding = true
+	j.mu.Unlock()
+}
+
+// sync flushes buffered entries and commits them to stable storage.
+func (j *journal) sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.syncLocked()
+}
+
+func (j *journal) syncLocked() error {
+	if j.w == nil {
+		return nil
+	}
+	err := errors.Join(j.err, j.w.Flush(), j.file.Sync())
+	j.err = nil
+	return err
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := errors.Join(j.syncLocked(), j.file.Close())
+	j.file, j.w = nil, nil
+	return err
+}
+
+// rewrite replaces the journal with cfg, dated now, and samples. The new
+// file is written next to the old one and renamed over it, so a crash leaves
+// either version. Appends are not blocked while the file is written: entries
+// held since hold go to the new file, or back to the old one if the rewrite
+// failed.
+func (j *journal) rewrite(cfg Config, samples []MultiTrackSample, now time.Time) error {
+	tmp := j.path + ".tmp"
+	err := writeJournalFile(tmp, cfg, samples, now)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	held := j.held
+	j.holding, j.held = false, nil
+	if err == nil {
+		err = os.Rename(tmp, j.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		for _, line := range held {
+			j.writeLocked(line)
+		}
+		return fmt.Errorf("compact journal: %w", err)
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		j.w = nil
+		return fmt.Errorf("open journal: %w", err)
+	}
+	j.w = bufio.NewWriter(j.file)
+	j.err = nil
+	for _, line := range held {
+		j.writeLocked(line)
+	}
+	return nil
+}
+
+// writeJournalFile writes cfg, dated now, and samples to path and syncs it.
+func writeJournalFile(path string, cfg Config, samples []MultiTrackSample, now time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(journalEntry{Kind: journalConfig, Time: now, Config: &cfg})
+	for i := range samples {
+		if err == nil {
+			err = enc.Encode(journalEntry{Kind: journalSample, Time: samples[i].Timestamp, Sample: &samples[i]})
+		}
+	}
+	return errors.Join(err, w.Flush(), f.Sync(), f.Close())
+}
+
+// loadJournal reads every entry of a journal file. A missing file is empty;
+// malformed lines, such as a write torn by a power loss, are skipped.
+func loadJournal(path string) ([]journalEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,152 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalRestoresRecentHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	first := newTestHub()
+	if err := first.OpenJournal(path, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, at := range []time.Time{now.Add(-5 * time.Minute), now.Add(-20 * time.Second), now.Add(-10 * time.Second)} {
+		first.ReportMultiTrack(MultiTrackSample{Timestamp: at, Tracks: []TrackSample{{ID: "t1", AngleDeg: float64(i)}}})
+	}
+	if err := first.journal.sync(); err != nil {
+		t.Fatal(err)
+	}
+	// A write torn by the crash must not stop the restore.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"kind":"sample","sample":{"tim`)
+	f.Close()
+
+	second := newTestHub()
+	if err := second.OpenJournal(path, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	history := second.History()
+	if len(history) != 2 || history[0].Tracks[0].AngleDeg != 1 || history[1].Tracks[0].AngleDeg != 2 {
+		t.Fatalf("restored history = %+v, want the two samples inside the window", history)
+	}
+	if history[0].MonotonicMs != 0 {
+		t.Fatalf("restored sample kept monotonic time %.0f from the earlier run", history[0].MonotonicMs)
+	}
+	if got, ok := second.TrackHistory("t1"); !ok || len(got) != 2 {
+		t.Fatalf("restored track history = %+v", got)
+	}
+	events := second.recentEvents()
+	if !strings.Contains(events[len(events)-1].Message, "telemetry gap") {
+		t.Fatalf("last event %q does not mark the gap", events[len(events)-1].Message)
+	}
+
+	second.Report(3, -10, 12, 0.9, LockStateTracking, nil)
+	second.Report(4, -10, 12, 0.9, LockStateTracking, nil)
+	history = second.History()
+	n := len(history)
+	if !history[n-2].Gap || history[n-1].Gap {
+		t.Fatalf("gap flags = %v, %v; want only the first new sample marked", history[n-2].Gap, history[n-1].Gap)
+	}
+	if history[n-2].Seq <= history[n-3].Seq {
+		t.Fatalf("seq %d after restart does not follow restored seq %d", history[n-2].Seq, history[n-3].Seq)
+	}
+}
+
+func TestRunJournalCompactsAndCloses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	hub := newTestHub()
+	if err := hub.OpenJournal(path, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: time.Now().Add(-time.Hour), Tracks: []TrackSample{{AngleDeg: 1}}})
+	hub.Report(2, -10, 12, 0.9, LockStateTracking, nil)
+	if err := hub.compactJournal(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.RunJournal(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	entries, err := loadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, entry := range entries {
+		kinds = append(kinds, entry.Kind)
+	}
+	if strings.Join(kinds, ",") != "config,sample" {
+		t.Fatalf("compacted journal kinds = %v, want config,sample", kinds)
+	}
+	if entries[1].Sample.Tracks[0].AngleDeg != 2 {
+		t.Fatalf("compacted journal kept %+v, want the recent sample", entries[1].Sample)
+	}
+	hub.Report(3, -10, 12, 0.9, LockStateTracking, nil) // must not write after close
+}
+
+func TestCompactJournalKeepsEntriesAppendedDuringRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j := &journal{path: path, window: time.Minute}
+	if err := j.rewrite(Config{}, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	sample := func(angle float64) journalEntry {
+		return journalEntry{Kind: journalSample, Sample: &MultiTrackSample{Tracks: []TrackSample{{AngleDeg: angle}}}}
+	}
+	angles := func() []float64 {
+		t.Helper()
+		if err := j.sync(); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := loadJournal(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []float64
+		for _, entry := range entries {
+			if entry.Sample != nil {
+				out = append(out, entry.Sample.Tracks[0].AngleDeg)
+			}
+		}
+		return out
+	}
+
+	// An entry journaled after the snapshot lands after it in the new file.
+	j.hold()
+	j.append(sample(2))
+	if err := j.rewrite(Config{}, []MultiTrackSample{*sample(1).Sample}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	j.append(sample(3))
+	if got := angles(); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("journal samples = %v, want [1 2 3]", got)
+	}
+
+	// A failed rewrite keeps the old file and appends the held entries to it.
+	if err := os.Mkdir(path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	j.hold()
+	j.append(sample(4))
+	if err := j.rewrite(Config{}, nil, time.Now()); err == nil {
+		t.Fatal("rewrite over a directory succeeded")
+	}
+	if got := angles(); len(got) != 4 || got[3] != 4 {
+		t.Fatalf("journal samples = %v, want [1 2 3 4]", got)
+	}
+}
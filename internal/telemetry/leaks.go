@@ -0,0 +1,257 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// Leak thresholds: growth over the baseline flagged as an anomaly, as an
+// absolute floor and a fraction of the baseline, whichever is larger.
+const (
+	leakGoroutineFloor = 50
+	leakFDFloor        = 32
+	leakHeapFloor      = 64 << 20
+	leakGrowthRatio    = 0.5
+	leakHeapRatio      = 1.0
+	leakTopSites       = 5
+)
+
+// LeakSample is one resource measurement of the leak self-check. OpenFDs is
+// -1 where /proc/self/fd is unavailable.
+type LeakSample struct {
+	Time       time.Time `json:"time"`
+	Goroutines int       `json:"goroutines"`
+	OpenFDs    int       `json:"openFds"`
+	HeapLive   uint64    `json:"heapLiveBytes"` // live heap after the last GC
+}
+
+// LeakSite is a goroutine entry function or heap allocation site whose
+// count (goroutines) or in-use bytes (heap) grew since the baseline.
+type LeakSite struct {
+	Site     string `json:"site"`
+	Baseline int64  `json:"baseline"`
+	Current  int64  `json:"current"`
+}
+
+// LeakReport is the latest leak self-check, served in /api/health. The
+// baseline is the lowest usage seen since the first check, so start-up
+// transients do not hide later growth.
+type LeakReport struct {
+	Status          string     `json:"status"` // ok or degraded
+	Checks          int        `json:"checks"`
+	Baseline        LeakSample `json:"baseline"`
+	Current         LeakSample `json:"current"`
+	Anomalies       []string   `json:"anomalies,omitempty"`
+	GoroutineGrowth []LeakSite `json:"goroutineGrowth,omitempty"`
+	HeapGrowth      []LeakSite `json:"heapGrowth,omitempty"`
+}
+
+// leakState is the hub's leak check bookkeeping, guarded by h.mu.
+type leakState struct {
+	baseline       LeakSample
+	goroutineSites map[string]int64 // at the baseline
+	heapSites      map[string]int64
+	report         LeakReport
+}
+
+// WatchLeaks compares goroutine, open file and live heap counts against
+// their baseline every interval until ctx is done, and reports growth in
+// /api/health with the goroutine entry functions and heap allocation sites
+// that grew. It returns at once when interval is not positive.
+func (h *Hub) WatchLeaks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkLeaks()
+		}
+	}
+}
+
+// checkLeaks takes one sample and updates the leak report.
+func (h *Hub) checkLeaks() {
+	sample := sampleLeaks()
+	h.mu.Lock()
+	state := h.leaks
+	if state == nil {
+		state = &leakState{}
+		h.leaks = state
+	}
+	lowered := state.report.Checks == 0 ||
+		sample.Goroutines < state.baseline.Goroutines ||
+		sample.HeapLive < state.baseline.HeapLive ||
+		(sample.OpenFDs >= 0 && sample.OpenFDs < state.baseline.OpenFDs)
+	h.mu.Unlock()
+
+	// Profiles are only collected when the baseline moves or usage grew.
+	var goroutineSites, heapSites map[string]int64
+	if lowered {
+		goroutineSites, heapSites = goroutineProfile(), heapProfile()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if lowered {
+		state.baseline = lowerSample(state.baseline, sample, state.report.Checks == 0)
+		state.goroutineSites, state.heapSites = goroutineSites, heapSites
+	}
+	report := LeakReport{Status: "ok", Checks: state.report.Checks + 1, Baseline: state.baseline, Current: sample}
+	report.Anomalies = leakAnomalies(state.baseline, sample)
+	if len(report.Anomalies) > 0 {
+		report.Status = "degraded"
+		report.GoroutineGrowth = growth(state.goroutineSites, goroutineProfile())
+		report.HeapGrowth = growth(state.heapSites, heapProfile())
+		if state.report.Status != "degraded" {
+			h.logger.Warn("possible resource leak", logging.Field{Key: "anomalies", Value: strings.Join(report.Anomalies, "; ")})
+		}
+	}
+	state.report = report
+}
+
+// LeakReport returns the latest leak self-check, or false before the first.
+func (h *Hub) LeakReport() (LeakReport, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.leaks == nil {
+		return LeakReport{}, false
+	}
+	return h.leaks.report, true
+}
+
+// lowerSample returns the per-resource minimum of base and s; first
+// replaces base entirely.
+func lowerSample(base, s LeakSample, first bool) LeakSample {
+	if first {
+		return s
+	}
+	base.Time = s.Time
+	base.Goroutines = min(base.Goroutines, s.Goroutines)
+	base.HeapLive = min(base.HeapLive, s.HeapLive)
+	if s.OpenFDs >= 0 {
+		base.OpenFDs = min(base.OpenFDs, s.OpenFDs)
+	}
+	return base
+}
+
+func leakAnomalies(base, cur LeakSample) []string {
+	var out []string
+	if limit := max(leakGoroutineFloor, int(leakGrowthRatio*float64(base.Goroutines))); cur.Goroutines-base.Goroutines > limit {
+		out = append(out, fmt.Sprintf("goroutines %d -> %d", base.Goroutines, cur.Goroutines))
+	}
+	if limit := max(leakFDFloor, int(leakGrowthRatio*float64(base.OpenFDs))); base.OpenFDs >= 0 && cur.OpenFDs-base.OpenFDs > limit {
+		out = append(out, fmt.Sprintf("open files %d -> %d", base.OpenFDs, cur.OpenFDs))
+	}
+	if limit := max(leakHeapFloor, uint64(leakHeapRatio*float64(base.HeapLive))); cur.HeapLive > base.HeapLive+limit {
+		out = append(out, fmt.Sprintf("live heap %.1f -> %.1f MB", float64(base.HeapLive)/(1<<20), float64(cur.HeapLive)/(1<<20)))
+	}
+	return out
+}
+
+// growth lists the sites that grew from base to cur, largest growth first.
+func growth(base, cur map[string]int64) []LeakSite {
+	var sites []LeakSite
+	for site, n := range cur {
+		if n > base[site] {
+			sites = append(sites, LeakSite{Site: site, Baseline: base[site], Current: n})
+		}
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		gi, gj := sites[i].Current-sites[i].Baseline, sites[j].Current-sites[j].Baseline
+		if gi != gj {
+			return gi > gj
+		}
+		return sites[i].Site < sites[j].Site
+	})
+	if len(sites) > leakTopSites {
+		sites = sites[:leakTopSites]
+	}
+	return sites
+}
+
+func sampleLeaks() LeakSample {
+	samples := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(samples)
+	var live uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		live = samples[0].Value.Uint64()
+	}
+	return LeakSample{Time: time.Now(), Goroutines: runtime.NumGoroutine(), OpenFDs: openFDCount(), HeapLive: live}
+}
+
+func openFDCount() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+// goroutineProfile counts goroutines by entry function, the outermost
+// non-runtime frame of their stack.
+func goroutineProfile() map[string]int64 {
+	records := make([]runtime.StackRecord, runtime.NumGoroutine()+16)
+	n, ok := runtime.GoroutineProfile(records)
+	for !ok {
+		records = make([]runtime.StackRecord, n+16)
+		n, ok = runtime.GoroutineProfile(records)
+	}
+	out := make(map[string]int64)
+	for _, r := range records[:n] {
+		out[stackSite(r.Stack(), true)]++
+	}
+	return out
+}
+
+// heapProfile sums sampled in-use heap bytes by allocating function, the
+// innermost non-runtime frame.
+func heapProfile() map[string]int64 {
+	var records []runtime.MemProfileRecord
+	n, _ := runtime.MemProfile(nil, false)
+	for {
+		records = make([]runtime.MemProfileRecord, n+16)
+		var ok bool
+		if n, ok = runtime.MemProfile(records, false); ok {
+			break
+		}
+	}
+	out := make(map[string]int64)
+	for _, r := range records[:n] {
+		if bytes := r.InUseBytes(); bytes > 0 {
+			out[stackSite(r.Stack(), false)] += bytes
+		}
+	}
+	return out
+}
+
+// stackSite names the innermost (or outermost) frame of stack outside the
+// runtime.
+func stackSite(stack []uintptr, outermost bool) string {
+	frames := runtime.CallersFrames(stack)
+	site := "runtime"
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			site = frame.Function
+			if !outermost {
+				return site
+			}
+		}
+		if !more {
+			return site
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeakAnomalies(t *testing.T) {
+	base := LeakSample{Goroutines: 200, OpenFDs: 20, HeapLive: 10 << 20}
+	tests := []struct {
+		name string
+		cur  LeakSample
+		want []string
+	}{
+		{name: "steady", cur: base},
+		{name: "goroutines within half", cur: LeakSample{Goroutines: 300, OpenFDs: 20, HeapLive: 10 << 20}},
+		{name: "goroutines", cur: LeakSample{Goroutines: 301, OpenFDs: 20, HeapLive: 10 << 20}, want: []string{"goroutines 200 -> 301"}},
+		{name: "fds", cur: LeakSample{Goroutines: 200, OpenFDs: 53, HeapLive: 10 << 20}, want: []string{"open files 20 -> 53"}},
+		{name: "heap", cur: LeakSample{Goroutines: 200, OpenFDs: 20, HeapLive: 75 << 20}, want: []string{"live heap 10.0 -> 75.0 MB"}},
+		{name: "no fd info", cur: LeakSample{Goroutines: 200, OpenFDs: -1, HeapLive: 10 << 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := leakAnomalies(base, tt.cur)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func leakyWorker(started *sync.WaitGroup, stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	started.Done()
+	<-stop
+}
+
+func TestCheckLeaksReportsGoroutineGrowth(t *testing.T) {
+	hub := newTestHub()
+	hub.checkLeaks()
+	baseline, ok := hub.LeakReport()
+	if !ok || baseline.Status != "ok" {
+		t.Fatalf("baseline check: %+v %v", baseline, ok)
+	}
+
+	// Enough goroutines to exceed both the floor and the relative limit.
+	leaked := baseline.Baseline.Goroutines + leakGoroutineFloor + 1
+	stop := make(chan struct{})
+	var wg, started sync.WaitGroup
+	for i := 0; i < leaked; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go leakyWorker(&started, stop, &wg)
+	}
+	started.Wait()
+	hub.checkLeaks()
+	report, _ := hub.LeakReport()
+	if report.Status != "degraded" || len(report.GoroutineGrowth) == 0 {
+		t.Fatalf("expected goroutine growth, got %+v", report)
+	}
+	if top := report.GoroutineGrowth[0]; !strings.HasSuffix(top.Site, "leakyWorker") || top.Current-top.Baseline != int64(leaked) {
+		t.Fatalf("top growing site %+v", top)
+	}
+	health := hub.healthStatus()
+	if health.Leaks == nil || health.Status == "ok" {
+		t.Fatalf("health should report the leak: %+v", health)
+	}
+
+	close(stop)
+	wg.Wait()
+	time.Sleep(10 * time.Millisecond)
+	hub.checkLeaks()
+	if report, _ := hub.LeakReport(); report.Status != "ok" || report.Checks != 3 {
+		t.Fatalf("expected recovery, got %+v", report)
+	}
+}
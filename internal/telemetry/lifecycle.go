@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// BackendState is the latest lifecycle event of one device's backend, as
+// returned by /api/sdr/state.
+type BackendState struct {
+	Device string `json:"device,omitempty"`
+	sdr.LifecycleEvent
+}
+
+// ObserveLifecycle implements sdr.LifecycleObserver for a single-device setup.
+func (h *Hub) ObserveLifecycle(ev sdr.LifecycleEvent) {
+	h.observeLifecycle("", ev)
+}
+
+// ObserveLifecycle implements sdr.LifecycleObserver for one device.
+func (d *deviceReporter) ObserveLifecycle(ev sdr.LifecycleEvent) {
+	d.hub.observeLifecycle(d.id, ev)
+}
+
+// observeLifecycle stores ev as device's backend state. Only transitions are
+// written to the event log, so a burst of underruns is logged once.
+func (h *Hub) observeLifecycle(device string, ev sdr.LifecycleEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev, seen := h.backendStates[device]
+	h.backendStates[device] = ev
+	if seen && prev.Kind == ev.Kind && prev.Err == ev.Err {
+		return
+	}
+	message := "sdr " + string(ev.Kind)
+	if ev.Backend != "" {
+		message += " (" + ev.Backend + ")"
+	}
+	if ev.Detail != "" {
+		message += ": " + ev.Detail
+	}
+	if ev.Err != "" {
+		message += ": " + ev.Err
+	}
+	if device != "" {
+		message = device + ": " + message
+	}
+	h.recordEventLocked(lifecycleLevel(ev), message)
+}
+
+func lifecycleLevel(ev sdr.LifecycleEvent) string {
+	switch {
+	case ev.Err != "" && ev.Kind == sdr.LifecycleClosed:
+		return "error"
+	case ev.Kind == sdr.LifecycleUnderrun || ev.Kind == sdr.LifecycleReconnecting || ev.Kind == sdr.LifecycleDisconnected:
+		return "warn"
+	}
+	return "info"
+}
+
+// BackendStates returns the latest lifecycle event per device, sorted by
+// device ID.
+func (h *Hub) BackendStates() []BackendState {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]BackendState, 0, len(h.backendStates))
+	for device, ev := range h.backendStates {
+		out = append(out, BackendState{Device: device, LifecycleEvent: ev})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+// handleBackendState returns the backend state of every device, or of the one
+// selected with ?device=.
+func (h *Hub) handleBackendState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	device := parseDevice(r)
+	out := make([]BackendState, 0)
+	for _, state := range h.BackendStates() {
+		if device == "" || state.Device == device {
+			out = append(out, state)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestObserveLifecycleLogsTransitionsOnce(t *testing.T) {
+	hub := newTestHub()
+	north := hub.ForDevice("north", "pluto")
+	observer := north.(sdr.LifecycleObserver)
+
+	observer.ObserveLifecycle(sdr.LifecycleEvent{Kind: sdr.LifecycleConnected, Backend: "pluto"})
+	for i := 0; i < 3; i++ {
+		observer.ObserveLifecycle(sdr.LifecycleEvent{Kind: sdr.LifecycleUnderrun, Backend: "pluto", Err: "timeout"})
+	}
+
+	var underruns int
+	for _, ev := range hub.recentEvents() {
+		if strings.Contains(ev.Message, "underrun") {
+			underruns++
+			if ev.Level != "warn" || !strings.HasPrefix(ev.Message, "north: ") {
+				t.Fatalf("unexpected event %+v", ev)
+			}
+		}
+	}
+	if underruns != 1 {
+		t.Fatalf("expected one underrun event, got %d", underruns)
+	}
+	if devices := hub.Devices(); len(devices) != 1 || devices[0].State != string(sdr.LifecycleUnderrun) {
+		t.Fatalf("unexpected devices %+v", devices)
+	}
+}
+
+func TestHandleBackendState(t *testing.T) {
+	hub := newTestHub()
+	hub.ObserveLifecycle(sdr.LifecycleEvent{Kind: sdr.LifecycleConnected, Backend: "mock"})
+	hub.ForDevice("south", "pluto").(sdr.LifecycleObserver).ObserveLifecycle(sdr.LifecycleEvent{Kind: sdr.LifecycleClosed, Backend: "pluto", Err: "refused"})
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{url: "/api/sdr/state", want: []string{"", "south"}},
+		{url: "/api/sdr/state?device=south", want: []string{"south"}},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		hub.handleBackendState(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		var out []BackendState
+		if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+			t.Fatalf("%s: decode: %v", tt.url, err)
+		}
+		if len(out) != len(tt.want) {
+			t.Fatalf("%s: got %+v", tt.url, out)
+		}
+		for i := range out {
+			if out[i].Device != tt.want[i] {
+				t.Fatalf("%s: got %+v", tt.url, out)
+			}
+		}
+	}
+}
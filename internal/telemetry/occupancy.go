@@ -0,0 +1,110 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+)
+
+// Occupancy is a snapshot of the spectrum occupancy statistics of one device,
+// as returned by /api/spectrum/occupancy.
+type Occupancy struct {
+	Device    string              `json:"device,omitempty"`
+	Timestamp time.Time           `json:"timestamp"`
+	CenterHz  float64             `json:"centerHz"`
+	Frames    int                 `json:"frames"`
+	Bands     []dsp.BandOccupancy `json:"bands"`
+}
+
+// ReportOccupancy stores the latest occupancy snapshot of a single-device setup.
+func (h *Hub) ReportOccupancy(occ Occupancy) {
+	h.reportOccupancy("", occ)
+}
+
+// ReportOccupancy stores the latest occupancy snapshot of one device.
+func (d *deviceReporter) ReportOccupancy(occ Occupancy) {
+	d.hub.reportOccupancy(d.id, occ)
+}
+
+func (h *Hub) reportOccupancy(device string, occ Occupancy) {
+	occ.Device = device
+	occ.Bands = append([]dsp.BandOccupancy(nil), occ.Bands...)
+	h.mu.Lock()
+	h.occupancy[device] = occ
+	h.mu.Unlock()
+}
+
+// OccupancySnapshots returns the latest occupancy snapshot per device, sorted
+// by device ID.
+func (h *Hub) OccupancySnapshots() []Occupancy {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]Occupancy, 0, len(h.occupancy))
+	for _, occ := range h.occupancy {
+		out = append(out, occ)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+// handleOccupancy returns the occupancy snapshots of every device, or of the
+// one selected with ?device=.
+func (h *Hub) handleOccupancy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	device := parseDevice(r)
+	out := make([]Occupancy, 0)
+	for _, occ := range h.OccupancySnapshots() {
+		if device == "" || occ.Device == device {
+			out = append(out, occ)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// handlePrometheus serves the occupancy statistics and the convergence state
+// in the Prometheus text exposition format. Bands are labelled with their
+// absolute edge frequencies.
+func (h *Hub) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	snapshots := h.OccupancySnapshots()
+	metrics := []struct {
+		name, help string
+		value      func(dsp.BandOccupancy) float64
+	}{
+		{"gosdr_band_duty_cycle", "Fraction of frames in which the sub-band was occupied.", func(b dsp.BandOccupancy) float64 { return b.DutyCycle }},
+		{"gosdr_band_power_avg_dbfs", "Mean sub-band power since start.", func(b dsp.BandOccupancy) float64 { return b.AvgPowerDB }},
+		{"gosdr_band_power_peak_dbfs", "Peak sub-band power since start.", func(b dsp.BandOccupancy) float64 { return b.PeakPowerDB }},
+		{"gosdr_band_power_dbfs", "Sub-band power in the latest frame.", func(b dsp.BandOccupancy) float64 { return b.LastPowerDB }},
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# HELP gosdr_occupancy_frames Spectra included in the occupancy statistics.\n# TYPE gosdr_occupancy_frames counter\n")
+	for _, occ := range snapshots {
+		fmt.Fprintf(&sb, "gosdr_occupancy_frames{device=%q} %d\n", occ.Device, occ.Frames)
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, occ := range snapshots {
+			for _, band := range occ.Bands {
+				fmt.Fprintf(&sb, "%s{device=%q,low_hz=\"%.0f\",high_hz=\"%.0f\"} %g\n",
+					m.name, occ.Device, occ.CenterHz+band.LowHz, occ.CenterHz+band.HighHz, m.value(band))
+			}
+		}
+	}
+	h.writeConvergenceMetrics(&sb)
+	h.writeSensorMetrics(&sb)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(sb.String()))
+}
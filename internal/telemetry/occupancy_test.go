@@ -0,0 +1,45 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rjboer/GoSDR/internal/dsp"
+)
+
+func TestOccupancyEndpoints(t *testing.T) {
+	hub := newTestHub()
+	hub.ForDevice("north", "mock").(*deviceReporter).ReportOccupancy(Occupancy{
+		CenterHz: 2.4e9,
+		Frames:   10,
+		Bands:    []dsp.BandOccupancy{{LowHz: -1e6, HighHz: 0, DutyCycle: 0.25, AvgPowerDB: -70, PeakPowerDB: -40, LastPowerDB: -72}},
+	})
+	hub.ReportOccupancy(Occupancy{Frames: 1})
+
+	rr := httptest.NewRecorder()
+	hub.handleOccupancy(rr, httptest.NewRequest(http.MethodGet, "/api/spectrum/occupancy?device=north", nil))
+	var got []Occupancy
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Device != "north" || got[0].Bands[0].DutyCycle != 0.25 {
+		t.Fatalf("unexpected occupancy %+v", got)
+	}
+
+	rr = httptest.NewRecorder()
+	hub.handlePrometheus(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`gosdr_occupancy_frames{device="north"} 10`,
+		`gosdr_band_duty_cycle{device="north",low_hz="2399000000",high_hz="2400000000"} 0.25`,
+		`gosdr_band_power_peak_dbfs{device="north",low_hz="2399000000",high_hz="2400000000"} -40`,
+		"# TYPE gosdr_band_power_avg_dbfs gauge",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
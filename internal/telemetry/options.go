@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"crypto/subtle"
+	"io/fs"
+	"net/http"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// Option configures a WebServer built by NewWebServer.
+type Option func(*WebServer)
+
+// route is an extra handler registered with WithRoute or WithAdminRoute.
+type route struct {
+	pattern string
+	handler http.Handler
+	admin   bool
+}
+
+// WithRoute registers an additional handler on the server mux, alongside the
+// built-in API. Patterns follow http.ServeMux; a pattern equal to a built-in
+// one panics at construction, as ServeMux does.
+func WithRoute(pattern string, handler http.Handler) Option {
+	return func(w *WebServer) {
+		w.routes = append(w.routes, route{pattern: pattern, handler: handler})
+	}
+}
+
+// WithAdminRoute is WithRoute for handlers that change state: requests other
+// than GET and HEAD must carry the admin token, as the built-in admin
+// endpoints require.
+func WithAdminRoute(pattern string, handler http.Handler) Option {
+	return func(w *WebServer) {
+		w.routes = append(w.routes, route{pattern: pattern, handler: handler, admin: true})
+	}
+}
+
+// WithAuth wraps every request, UI and API alike, in the given middleware.
+// BasicAuth provides one that works with browsers and EventSource streams.
+func WithAuth(middleware func(http.Handler) http.Handler) Option {
+	return func(w *WebServer) {
+		w.auth = middleware
+	}
+}
+
+// WithMiddleware wraps the whole server, outside authentication and
+// compression, in the given middleware. It is meant for instrumentation such
+// as otlp.Exporter.Middleware, which then also sees rejected requests.
+func WithMiddleware(middleware func(http.Handler) http.Handler) Option {
+	return func(w *WebServer) {
+		w.outer = middleware
+	}
+}
+
+// WithAssets serves the UI from fsys instead of the embedded files. fsys has
+// the layout of the embedded static directory: index.html, settings.html and
+// the scripts and styles they load, served under /static/.
+func WithAssets(fsys fs.FS) Option {
+	return func(w *WebServer) {
+		w.assets = fsys
+	}
+}
+
+// WithMacros is the option form of SetMacros.
+func WithMacros(macros sdr.Macros) Option {
+	return func(w *WebServer) {
+		w.macros = macros
+	}
+}
+
+// WithAdminToken is the option form of SetAdminToken.
+func WithAdminToken(token string) Option {
+	return func(w *WebServer) {
+		w.admin = token
+	}
+}
+
+// BasicAuth returns middleware that requires HTTP basic authentication with
+// the given credentials. Browsers prompt once and then send them on every
+// request, including the live streams.
+func BasicAuth(user, password string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			u, p, ok := r.BasicAuth()
+			if !ok || subtle.ConstantTimeCompare([]byte(u), []byte(user)) != 1 || subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+				rw.Header().Set("WWW-Authenticate", `Basic realm="GoSDR"`)
+				writeJSONError(rw, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWebServerOptions(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html": {Data: []byte("custom index")},
+		"app.js":     {Data: []byte("custom script")},
+	}
+	ws := NewWebServer(":0", newTestHub(), nil, nil,
+		WithAssets(assets),
+		WithRoute("/api/extra", http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+			rw.Write([]byte("extra"))
+		})),
+		WithAuth(BasicAuth("ops", "secret")),
+	)
+
+	tests := []struct {
+		name     string
+		path     string
+		auth     bool
+		wantCode int
+		wantBody string
+	}{
+		{"no credentials", "/", false, http.StatusUnauthorized, ""},
+		{"custom index", "/", true, http.StatusOK, "custom index"},
+		{"custom static", "/static/app.js", true, http.StatusOK, "custom script"},
+		{"extra route", "/api/extra", true, http.StatusOK, "extra"},
+		{"built-in route", "/api/sdr/capabilities", true, http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth {
+				req.SetBasicAuth("ops", "secret")
+			}
+			rr := httptest.NewRecorder()
+			ws.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", rr.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Fatalf("body %q, want %q", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWebServerAdminRoute(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), nil, nil,
+		WithAdminToken("token"),
+		WithAdminRoute("/api/calibrate", http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Write([]byte(r.Method))
+		})),
+	)
+	tests := []struct {
+		method   string
+		token    string
+		wantCode int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPost, "", http.StatusUnauthorized},
+		{http.MethodPost, "wrong", http.StatusUnauthorized},
+		{http.MethodPost, "token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/calibrate", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rr := httptest.NewRecorder()
+		ws.Handler().ServeHTTP(rr, req)
+		if rr.Code != tt.wantCode {
+			t.Fatalf("%s with token %q: status %d, want %d", tt.method, tt.token, rr.Code, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	maxOutputResolutionDeg = 45
+	maxOutputRateHz        = 1000
+)
+
+// OutputConfig limits the angles sent to external pointing and mapping
+// consumers (the steering API and stream, the map export) for links that
+// cannot carry full-rate, full-precision updates. Angles are rounded to
+// ResolutionDeg and the steering stream sends at most MaxRateHz updates per
+// second and device. The history, live and track APIs keep full precision.
+// The zero value changes nothing.
+type OutputConfig struct {
+	ResolutionDeg float64 `json:"resolutionDeg"`
+	MaxRateHz     float64 `json:"maxRateHz"`
+}
+
+// Validate checks the resolution and rate ranges.
+func (c OutputConfig) Validate() error {
+	if c.ResolutionDeg < 0 || c.ResolutionDeg > maxOutputResolutionDeg || math.IsNaN(c.ResolutionDeg) {
+		return fmt.Errorf("output resolution must be within 0-%d degrees", maxOutputResolutionDeg)
+	}
+	if c.MaxRateHz < 0 || c.MaxRateHz > maxOutputRateHz || math.IsNaN(c.MaxRateHz) {
+		return fmt.Errorf("output rate must be within 0-%d Hz", maxOutputRateHz)
+	}
+	return nil
+}
+
+// Quantize rounds deg to the output resolution.
+func (c OutputConfig) Quantize(deg float64) float64 {
+	if c.ResolutionDeg <= 0 {
+		return deg
+	}
+	q := math.Round(deg/c.ResolutionDeg) * c.ResolutionDeg
+	// Drop the float error of the multiplication, so 0.1° steps serialize
+	// as 12.3 rather than 12.299999999999999.
+	return math.Round(q*1e9) / 1e9
+}
+
+// interval is the minimum time between two updates; 0 is unlimited.
+func (c OutputConfig) interval() time.Duration {
+	if c.MaxRateHz <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / c.MaxRateHz)
+}
+
+// SetOutput replaces the output limits.
+func (h *Hub) SetOutput(cfg OutputConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.output = cfg
+	h.mu.Unlock()
+	return nil
+}
+
+// Output returns the output limits.
+func (h *Hub) Output() OutputConfig {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.output
+}
+
+// outputGate rate-limits the updates of one output stream. An update passes
+// when its value differs from the last one sent and the interval since then
+// has passed. An update that comes too early is held back and sent by due
+// once the interval has passed, so a consumer always ends at the latest
+// value rather than at the last one that happened to fit the rate.
+type outputGate[T any] struct {
+	sent      bool
+	last      time.Time
+	value     float64
+	held      bool
+	heldItem  T
+	heldValue float64
+}
+
+// offer submits item with value at now and reports whether it is to be sent.
+func (g *outputGate[T]) offer(now time.Time, value float64, item T, interval time.Duration) bool {
+	if g.sent && value == g.value {
+		// Back at the value sent last: whatever was held is stale.
+		g.held = false
+		return false
+	}
+	if g.sent && now.Sub(g.last) < interval {
+		g.held, g.heldItem, g.heldValue = true, item, value
+		return false
+	}
+	g.sent, g.last, g.value, g.held = true, now, value, false
+	return true
+}
+
+// due returns the held update once the interval since the last one sent has
+// passed.
+func (g *outputGate[T]) due(now time.Time, interval time.Duration) (T, bool) {
+	if !g.held || now.Sub(g.last) < interval {
+		var zero T
+		return zero, false
+	}
+	g.last, g.value, g.held = now, g.heldValue, false
+	return g.heldItem, true
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestOutputQuantize(t *testing.T) {
+	tests := []struct {
+		res, in, want float64
+	}{
+		{res: 0, in: 12.3456, want: 12.3456},
+		{res: 0.1, in: 12.34, want: 12.3},
+		{res: 0.1, in: -12.36, want: -12.4},
+		{res: 0.5, in: 12.3, want: 12.5},
+		{res: 5, in: 357.6, want: 360},
+	}
+	for _, tt := range tests {
+		if got := (OutputConfig{ResolutionDeg: tt.res}).Quantize(tt.in); got != tt.want {
+			t.Errorf("Quantize(%v) at %v° = %v, want %v", tt.in, tt.res, got, tt.want)
+		}
+	}
+	for _, bad := range []OutputConfig{{ResolutionDeg: -1}, {ResolutionDeg: 90}, {MaxRateHz: -1}, {MaxRateHz: math.NaN()}} {
+		if bad.Validate() == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+}
+
+func TestSteeringOutputRateAndResolution(t *testing.T) {
+	hub := newTestHub()
+	if err := hub.SetOutput(OutputConfig{ResolutionDeg: 1, MaxRateHz: 10}); err != nil {
+		t.Fatal(err)
+	}
+	ch, unsubscribe := hub.subscribeSteering()
+	defer unsubscribe()
+	north := hub.ForDevice("north", "mock").(*deviceReporter)
+
+	start := time.Unix(1700000000, 0)
+	report := func(ms int, angle float64) {
+		north.ReportSteering(Steering{Timestamp: start.Add(time.Duration(ms) * time.Millisecond), RawDeg: angle, AngleDeg: angle, Changed: true})
+	}
+	report(0, 10.2)   // sent as 10
+	report(20, 10.4)  // still 10: nothing to send
+	report(40, 12.6)  // too early: held
+	report(60, 14.1)  // too early: replaces the held 13
+	report(120, 14.3) // 100 ms after the last send: the held 14 goes out
+
+	var got []Steering
+	for len(ch) > 0 {
+		got = append(got, <-ch)
+	}
+	if len(got) != 2 || got[0].AngleDeg != 10 || got[1].AngleDeg != 14 {
+		t.Fatalf("sent %+v, want 10 then 14", got)
+	}
+	if snap := hub.SteeringSnapshots(); len(snap) != 1 || snap[0].AngleDeg != 14 || snap[0].RawDeg != 14.3 {
+		t.Fatalf("snapshot %+v", snap)
+	}
+
+	report(150, 15.8) // too early: held
+	report(170, 14.2) // back at 14 before the rate allowed 16
+	report(400, 14.4)
+	if len(ch) != 0 {
+		t.Fatalf("sent %+v after the angle returned", <-ch)
+	}
+}
+
+func TestTracksGeoJSONOutputResolution(t *testing.T) {
+	hub := newTestHub()
+	if err := hub.SetOutput(OutputConfig{ResolutionDeg: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	hub.SetPosition(Position{LatitudeDeg: 52, LongitudeDeg: 5}, now, true)
+	hub.SetHeading(359.9, now, true)
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: now, Tracks: []TrackSample{
+		{ID: "a", AngleDeg: 0.2, SNR: 20, State: "confirmed", LockState: LockStateLocked},
+	}})
+
+	fc, err := hub.TracksGeoJSON("", 1000, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 2 {
+		t.Fatalf("features %+v", fc.Features)
+	}
+	props := fc.Features[1].Properties
+	if props["bearingDeg"] != 0.0 || props["angleDeg"] != 0.0 {
+		t.Fatalf("bearing %v, angle %v; want 0 and 0", props["bearingDeg"], props["angleDeg"])
+	}
+}
@@ -0,0 +1,320 @@
+package telemetry
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pairing modes.
+const (
+	PairingOff    = "off"    // no pairing
+	PairingRemote = "remote" // clients on the loopback interface skip pairing
+	PairingAll    = "all"    // every client pairs
+)
+
+const (
+	pairingCookie        = "gosdr_session"
+	pairingTokenHeader   = "X-GoSDR-Token" // rotated token for bearer clients
+	pairingMaxFailures   = 5               // wrong codes before the code is replaced
+	defaultSessionTTL    = 24 * time.Hour
+	defaultTokenRotation = time.Hour
+	// rotationGrace is how long a rotated token stays valid, so requests
+	// already sent with it still succeed.
+	rotationGrace = time.Minute
+)
+
+// ParsePairingMode validates a pairing mode; empty selects PairingRemote.
+func ParsePairingMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return PairingRemote, nil
+	case PairingOff, PairingRemote, PairingAll:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown pairing mode %q (off|remote|all)", mode)
+}
+
+// Pairing guards the UI and API with a one-time pairing code. The code is
+// shown on the console; presenting it (opening /?pair=CODE or POST
+// /api/pair) issues a session token as a cookie and in the response. Tokens
+// expire after the session TTL and are replaced every rotation interval:
+// the new token comes back as a cookie and in the X-GoSDR-Token header, and
+// the old one expires a minute later. A used code is replaced at once,
+// as is a code after pairingMaxFailures wrong guesses.
+type Pairing struct {
+	mode     string
+	ttl      time.Duration
+	rotation time.Duration
+	onCode   func(code string)
+	accept   func(token string) bool // set by the web server for the admin token
+	now      func() time.Time
+
+	mu       sync.Mutex
+	code     string
+	failures int
+	sessions map[string]pairingSession // by SHA-256 of the token
+}
+
+type pairingSession struct {
+	issued  time.Time
+	expires time.Time
+}
+
+// NewPairing starts pairing in mode. onCode is called with the first code
+// and with every replacement, typically to print it; it must not call back
+// into the Pairing.
+func NewPairing(mode string, onCode func(code string)) *Pairing {
+	p := &Pairing{
+		mode:     mode,
+		ttl:      defaultSessionTTL,
+		rotation: defaultTokenRotation,
+		onCode:   onCode,
+		now:      time.Now,
+		sessions: make(map[string]pairingSession),
+	}
+	if mode != PairingOff {
+		p.mu.Lock()
+		p.newCodeLocked()
+		p.mu.Unlock()
+	}
+	return p
+}
+
+// WithPairing guards the server with p; see Pairing.
+func WithPairing(p *Pairing) Option {
+	return func(w *WebServer) {
+		w.pairing = p
+	}
+}
+
+// Middleware returns next guarded by pairing. /api/pair stays reachable so
+// the code can be presented.
+func (p *Pairing) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if p.mode == PairingOff || (p.mode == PairingRemote && isLoopback(r)) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		if r.URL.Path == "/api/pair" {
+			p.handlePair(rw, r)
+			return
+		}
+		if code := r.URL.Query().Get("pair"); code != "" && r.Method == http.MethodGet {
+			if token, ok := p.redeem(code); ok {
+				p.setCookie(rw, r, token)
+				q := r.URL.Query()
+				q.Del("pair")
+				target := *r.URL
+				target.RawQuery = q.Encode()
+				http.Redirect(rw, r, target.String(), http.StatusSeeOther)
+				return
+			}
+		}
+		if p.authorized(rw, r) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/metrics" {
+			writeJSONError(rw, http.StatusUnauthorized, "pairing required: present the code shown at startup to /api/pair")
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.WriteHeader(http.StatusUnauthorized)
+		_, _ = rw.Write([]byte(pairingPage))
+	})
+}
+
+// authorized reports whether r carries a session token, as a bearer token or
+// cookie, or a credential accepted in its place (the admin token). A session
+// token due for rotation gets its replacement set on rw.
+func (p *Pairing) authorized(rw http.ResponseWriter, r *http.Request) bool {
+	tokens := []string{bearerToken(r)}
+	if c, err := r.Cookie(pairingCookie); err == nil {
+		tokens = append(tokens, c.Value)
+	}
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		if p.accept != nil && p.accept(token) {
+			return true
+		}
+		if fresh, ok := p.check(token); ok {
+			if fresh != "" {
+				p.setCookie(rw, r, fresh)
+				rw.Header().Set(pairingTokenHeader, fresh)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// handlePair exchanges a code for a session token: {"code": "..."} returns
+// {"token": "...", "expires": "..."}.
+func (p *Pairing) handlePair(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	token, ok := p.redeem(payload.Code)
+	if !ok {
+		// The code is replaced after repeated failures, so guessing is futile.
+		writeJSONError(rw, http.StatusForbidden, "invalid pairing code")
+		return
+	}
+	p.setCookie(rw, r, token)
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(map[string]any{"token": token, "expires": p.now().Add(p.ttl).UTC()})
+}
+
+// redeem consumes code and returns a new session token.
+func (p *Pairing) redeem(code string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code = normalizePairingCode(code)
+	if p.code == "" || subtle.ConstantTimeCompare([]byte(code), []byte(p.code)) != 1 {
+		p.failures++
+		if p.failures >= pairingMaxFailures {
+			p.newCodeLocked()
+		}
+		return "", false
+	}
+	p.newCodeLocked()
+	return p.issueLocked(), true
+}
+
+// check validates token. When the token is due for rotation it returns a
+// replacement as fresh.
+func (p *Pairing) check(token string) (fresh string, ok bool) {
+	if token == "" {
+		return "", false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	key := tokenKey(token)
+	session, found := p.sessions[key]
+	if !found || now.After(session.expires) {
+		delete(p.sessions, key)
+		return "", false
+	}
+	if now.Sub(session.issued) >= p.rotation {
+		// The old token only covers requests in flight from now on, and is
+		// marked as rotated so it is not rotated again.
+		if grace := now.Add(rotationGrace); grace.Before(session.expires) {
+			session.expires = grace
+		}
+		session.issued = session.expires
+		p.sessions[key] = session
+		return p.issueLocked(), true
+	}
+	return "", true
+}
+
+func (p *Pairing) issueLocked() string {
+	now := p.now()
+	for key, s := range p.sessions {
+		if now.After(s.expires) {
+			delete(p.sessions, key)
+		}
+	}
+	token := randomString(32)
+	p.sessions[tokenKey(token)] = pairingSession{issued: now, expires: now.Add(p.ttl)}
+	return token
+}
+
+func (p *Pairing) newCodeLocked() {
+	p.code = randomString(5)
+	p.failures = 0
+	if p.onCode != nil {
+		p.onCode(formatPairingCode(p.code))
+	}
+}
+
+func (p *Pairing) setCookie(rw http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(rw, &http.Cookie{
+		Name:     pairingCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(p.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func isLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes as unpadded base32 (8 characters per
+// 5 bytes).
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("pairing: read random: %v", err))
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
+
+// formatPairingCode splits an 8-character code as ABCD-EFGH for reading
+// aloud; normalizePairingCode undoes it and tolerates lower case.
+func formatPairingCode(code string) string {
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+func normalizePairingCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+const pairingPage = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>GoSDR pairing</title>
+<style>body{font-family:sans-serif;max-width:28em;margin:4em auto}input{font-size:1.4em;width:8em;text-transform:uppercase}</style>
+</head>
+<body>
+<h1>Pair this browser</h1>
+<p>Enter the pairing code printed by the tracker at startup.</p>
+<form method="get" action=""><input name="pair" autocomplete="off" autofocus placeholder="ABCD-EFGH"> <button>Pair</button></form>
+</body>
+</html>
+`
@@ -0,0 +1,137 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPairingModes(t *testing.T) {
+	tests := []struct {
+		mode       string
+		remoteAddr string
+		wantCode   int
+	}{
+		{PairingOff, "192.0.2.1:1234", http.StatusOK},
+		{PairingRemote, "127.0.0.1:1234", http.StatusOK},
+		{PairingRemote, "[::1]:1234", http.StatusOK},
+		{PairingRemote, "192.0.2.1:1234", http.StatusUnauthorized},
+		{PairingAll, "127.0.0.1:1234", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.remoteAddr, func(t *testing.T) {
+			ws := NewWebServer(":0", newTestHub(), nil, nil, WithPairing(NewPairing(tt.mode, nil)))
+			req := httptest.NewRequest(http.MethodGet, "/api/version", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rr := httptest.NewRecorder()
+			ws.Handler().ServeHTTP(rr, req)
+			if rr.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", rr.Code, tt.wantCode)
+			}
+		})
+	}
+	if _, err := ParsePairingMode("sometimes"); err == nil {
+		t.Fatal("unknown mode accepted")
+	}
+}
+
+func TestPairingFlow(t *testing.T) {
+	var codes []string
+	p := NewPairing(PairingAll, func(code string) { codes = append(codes, code) })
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	ws := NewWebServer(":0", newTestHub(), nil, nil, WithPairing(p), WithAdminToken("admin-secret"))
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ws.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Unpaired browsers get the pairing page, API clients a JSON error.
+	if rr := serve(httptest.NewRequest(http.MethodGet, "/", nil)); rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "Pair this browser") {
+		t.Fatalf("page: %d %q", rr.Code, rr.Body.String())
+	}
+
+	// Opening the printed URL sets the session cookie and drops the code.
+	first := codes[0]
+	rr := serve(httptest.NewRequest(http.MethodGet, "/?pair="+strings.ToLower(first), nil))
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/" {
+		t.Fatalf("pair: %d location %q", rr.Code, rr.Header().Get("Location"))
+	}
+	cookie := rr.Result().Cookies()[0]
+	req := httptest.NewRequest(http.MethodGet, "/api/version", nil)
+	req.AddCookie(cookie)
+	if rr := serve(req); rr.Code != http.StatusOK {
+		t.Fatalf("cookie session: %d", rr.Code)
+	}
+	if len(codes) != 2 {
+		t.Fatalf("code not replaced after use: %v", codes)
+	}
+
+	// A used code is rejected; programmatic clients pair with the new one.
+	pair := func(code string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"code": code})
+		return serve(httptest.NewRequest(http.MethodPost, "/api/pair", bytes.NewReader(body)))
+	}
+	if rr := pair(first); rr.Code != http.StatusForbidden {
+		t.Fatalf("reused code: %d", rr.Code)
+	}
+	rr = pair(codes[1])
+	var paired struct{ Token string }
+	if rr.Code != http.StatusOK || json.NewDecoder(rr.Body).Decode(&paired) != nil || paired.Token == "" {
+		t.Fatalf("POST /api/pair: %d %s", rr.Code, rr.Body.String())
+	}
+	bearer := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/version", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return serve(req)
+	}
+	if rr := bearer(paired.Token); rr.Code != http.StatusOK || rr.Header().Get(pairingTokenHeader) != "" {
+		t.Fatalf("bearer: %d rotated %q", rr.Code, rr.Header().Get(pairingTokenHeader))
+	}
+	if rr := bearer("admin-secret"); rr.Code != http.StatusOK {
+		t.Fatalf("admin token: %d", rr.Code)
+	}
+
+	// After the rotation interval a replacement is issued once; the old
+	// token only lasts for the grace period.
+	now = now.Add(defaultTokenRotation)
+	rr = bearer(paired.Token)
+	rotated := rr.Header().Get(pairingTokenHeader)
+	if rr.Code != http.StatusOK || rotated == "" || rotated == paired.Token {
+		t.Fatalf("rotation: %d token %q", rr.Code, rotated)
+	}
+	if rr := bearer(paired.Token); rr.Code != http.StatusOK || rr.Header().Get(pairingTokenHeader) != "" {
+		t.Fatalf("old token after rotation: %d rotated again %q", rr.Code, rr.Header().Get(pairingTokenHeader))
+	}
+	now = now.Add(rotationGrace + time.Second)
+	if rr := bearer(paired.Token); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("old token after the grace period: %d", rr.Code)
+	}
+	if rr := bearer(rotated); rr.Code != http.StatusOK {
+		t.Fatalf("rotated token: %d", rr.Code)
+	}
+}
+
+func TestPairingReplacesGuessedCode(t *testing.T) {
+	var codes []string
+	p := NewPairing(PairingAll, func(code string) { codes = append(codes, code) })
+	for i := 0; i < pairingMaxFailures; i++ {
+		if _, ok := p.redeem("WRONG-CODE"); ok {
+			t.Fatal("wrong code accepted")
+		}
+	}
+	if len(codes) != 2 {
+		t.Fatalf("code not replaced after %d failures: %v", pairingMaxFailures, codes)
+	}
+	if _, ok := p.redeem(codes[0]); ok {
+		t.Fatal("replaced code accepted")
+	}
+	if _, ok := p.redeem(codes[1]); !ok {
+		t.Fatal("current code rejected")
+	}
+}
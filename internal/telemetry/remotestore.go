@@ -0,0 +1,98 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remoteStoreTimeout bounds each query to a remote store.
+const remoteStoreTimeout = 30 * time.Second
+
+// remoteStore queries the /api/store endpoints of another hub, typically a
+// central telemetryd or monopulse instance that keeps the long-term store
+// for several nodes. It is read only: nodes send their tracks to the central
+// instance with agent or -follow, not through this store.
+type remoteStore struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+// openRemoteStore opens "http(s)://host:port[?token=...]". The token is
+// sent as a Bearer token to hubs that require pairing.
+func openRemoteStore(dsn string) (Store, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("store %q: want http(s)://host:port", dsn)
+	}
+	token := u.Query().Get("token")
+	u.RawQuery, u.Fragment = "", ""
+	return &remoteStore{
+		base:   strings.TrimRight(u.String(), "/") + "/api/store/",
+		token:  token,
+		client: &http.Client{Timeout: remoteStoreTimeout},
+	}, nil
+}
+
+func (r *remoteStore) Append([]MultiTrackSample, []DiagnosticEvent) error {
+	return errReadOnlyStore
+}
+
+func (r *remoteStore) get(path string, q StoreQuery, out any) error {
+	values := url.Values{}
+	if !q.Since.IsZero() {
+		values.Set("since", q.Since.Format(time.RFC3339Nano))
+	}
+	if !q.Until.IsZero() {
+		values.Set("until", q.Until.Format(time.RFC3339Nano))
+	}
+	// The remote caps each query, so ask for its maximum when unlimited.
+	values.Set("limit", strconv.Itoa(q.Limit))
+	if q.Device != "" {
+		values.Set("device", q.Device)
+	}
+	if len(q.TrackIDs) > 0 {
+		values.Set("tracks", strings.Join(q.TrackIDs, ","))
+	}
+	req, err := http.NewRequest(http.MethodGet, r.base+path+"?"+values.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote store %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (r *remoteStore) Samples(q StoreQuery) ([]MultiTrackSample, error) {
+	var out []MultiTrackSample
+	err := r.get("samples", q, &out)
+	return out, err
+}
+
+func (r *remoteStore) Events(q StoreQuery) ([]DiagnosticEvent, error) {
+	var out []DiagnosticEvent
+	err := r.get("events", q, &out)
+	return out, err
+}
+
+func (r *remoteStore) Span() (first, last time.Time, err error) {
+	var span storeSpan
+	err = r.get("span", StoreQuery{}, &span)
+	return span.First, span.Last, err
+}
+
+func (r *remoteStore) Close() error { return nil }
@@ -0,0 +1,222 @@
+package telemetry
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSectorFOVDeg is the field of view assumed for an array when none
+	// is configured (±60° around boresight).
+	defaultSectorFOVDeg = 120.0
+	// sectorGateDeg is the maximum bearing difference for two observations to
+	// be treated as the same emitter.
+	sectorGateDeg = 10.0
+	// sectorHoldover is how long a unified track survives without updates.
+	sectorHoldover = 2 * time.Second
+	// sectorHandoverRatio is the weight fraction the current primary array may
+	// drop to before another array takes over, preventing chatter in overlaps.
+	sectorHandoverRatio = 0.8
+)
+
+// Sector describes the coverage of one array in the common bearing frame.
+// Bearings are measured clockwise from the reference direction; a positive
+// array angle maps to a bearing clockwise of boresight.
+type Sector struct {
+	Device       string  `json:"device"`
+	BoresightDeg float64 `json:"boresightDeg"`
+	FOVDeg       float64 `json:"fovDeg"`
+}
+
+// UnifiedTrack is an emitter in the stitched 360° picture. Sources lists the
+// per-device track IDs that contributed to the latest update.
+type UnifiedTrack struct {
+	ID          string    `json:"id"`
+	BearingDeg  float64   `json:"bearingDeg"`
+	SNR         float64   `json:"snr"`
+	Confidence  float64   `json:"confidence"`
+	LockState   LockState `json:"lockState"`
+	Device      string    `json:"device"`
+	Sources     []string  `json:"sources"`
+	Handovers   int       `json:"handovers"`
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+// sectorObservation is a device track converted to the common bearing frame.
+type sectorObservation struct {
+	snapshot   TrackSnapshot
+	bearingDeg float64
+	weight     float64
+}
+
+// sectorCombiner merges device tracks into unified tracks, keeping unified IDs
+// stable while an emitter moves from one array's sector into another's.
+type sectorCombiner struct {
+	mu      sync.Mutex
+	sectors map[string]Sector
+	tracks  []*UnifiedTrack
+	nextID  int
+}
+
+func newSectorCombiner() *sectorCombiner {
+	return &sectorCombiner{sectors: make(map[string]Sector), nextID: 1}
+}
+
+// SetSector records the boresight and field of view of a device's array.
+// A zero fovDeg selects the default of 120°.
+func (h *Hub) SetSector(device string, boresightDeg, fovDeg float64) {
+	if fovDeg <= 0 {
+		fovDeg = defaultSectorFOVDeg
+	}
+	h.sectors.mu.Lock()
+	h.sectors.sectors[device] = Sector{Device: device, BoresightDeg: wrapBearing(boresightDeg), FOVDeg: fovDeg}
+	h.sectors.mu.Unlock()
+}
+
+// UnifiedTracks returns the stitched track picture sorted by bearing.
+func (h *Hub) UnifiedTracks() []UnifiedTrack {
+	h.sectors.mu.Lock()
+	defer h.sectors.mu.Unlock()
+
+	out := make([]UnifiedTrack, 0, len(h.sectors.tracks))
+	for _, track := range h.sectors.tracks {
+		cp := *track
+		cp.Sources = append([]string(nil), track.Sources...)
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].BearingDeg < out[j].BearingDeg })
+	return out
+}
+
+// updateSectors re-runs the combiner over the latest device tracks.
+func (h *Hub) updateSectors(now time.Time) {
+	snapshots := h.trackSnapshots(nil)
+	for _, msg := range h.sectors.update(snapshots, now) {
+		h.recordEvent("info", msg)
+	}
+}
+
+// update associates fresh device tracks with unified tracks and returns
+// handover messages.
+func (c *sectorCombiner) update(snapshots []TrackSnapshot, now time.Time) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	observations := c.observations(snapshots, now)
+	sort.Slice(observations, func(i, j int) bool { return observations[i].weight > observations[j].weight })
+
+	groups := make(map[*UnifiedTrack][]sectorObservation)
+	for _, obs := range observations {
+		track := c.nearest(obs.bearingDeg)
+		if track == nil {
+			track = &UnifiedTrack{ID: fmt.Sprintf("U%d", c.nextID), BearingDeg: obs.bearingDeg, Device: obs.snapshot.Sample.Device}
+			c.nextID++
+			c.tracks = append(c.tracks, track)
+		}
+		groups[track] = append(groups[track], obs)
+	}
+
+	var messages []string
+	kept := c.tracks[:0]
+	for _, track := range c.tracks {
+		if obs, ok := groups[track]; ok {
+			prev := track.Device
+			if applyObservations(track, obs) {
+				messages = append(messages, fmt.Sprintf("track %s handed over from %s to %s", track.ID, prev, track.Device))
+			}
+		}
+		if now.Sub(track.LastUpdated) <= sectorHoldover {
+			kept = append(kept, track)
+		}
+	}
+	c.tracks = kept
+	return messages
+}
+
+// observations converts fresh device tracks inside their sector into the
+// common frame. Tracks near the sector edge are down-weighted so the array
+// looking most directly at the emitter dominates during handover.
+func (c *sectorCombiner) observations(snapshots []TrackSnapshot, now time.Time) []sectorObservation {
+	out := make([]sectorObservation, 0, len(snapshots))
+	for _, snap := range snapshots {
+		sector, ok := c.sectors[snap.Sample.Device]
+		if !ok || now.Sub(snap.LastUpdated) > sectorHoldover {
+			continue
+		}
+		half := sector.FOVDeg / 2
+		if math.Abs(snap.Sample.AngleDeg) > half {
+			continue
+		}
+		edge := math.Cos(snap.Sample.AngleDeg / half * math.Pi / 2)
+		out = append(out, sectorObservation{
+			snapshot:   snap,
+			bearingDeg: wrapBearing(sector.BoresightDeg + snap.Sample.AngleDeg),
+			weight:     math.Max(snap.Sample.Confidence, 0.01) * math.Max(edge, 0.01),
+		})
+	}
+	return out
+}
+
+// nearest returns the unified track closest to bearingDeg within the gate.
+func (c *sectorCombiner) nearest(bearingDeg float64) *UnifiedTrack {
+	var best *UnifiedTrack
+	bestDiff := sectorGateDeg
+	for _, track := range c.tracks {
+		if diff := math.Abs(bearingDiff(track.BearingDeg, bearingDeg)); diff <= bestDiff {
+			best, bestDiff = track, diff
+		}
+	}
+	return best
+}
+
+// applyObservations fuses obs (sorted by descending weight) into track using a
+// weighted circular mean. It reports whether the primary device changed.
+func applyObservations(track *UnifiedTrack, obs []sectorObservation) bool {
+	var sumSin, sumCos float64
+	primary := obs[0]
+	track.Sources = track.Sources[:0]
+	track.SNR = 0
+	for _, o := range obs {
+		rad := o.bearingDeg * math.Pi / 180
+		sumSin += o.weight * math.Sin(rad)
+		sumCos += o.weight * math.Cos(rad)
+		if o.snapshot.Sample.Device == track.Device && o.weight >= sectorHandoverRatio*obs[0].weight {
+			primary = o
+		}
+		track.Sources = append(track.Sources, o.snapshot.ID)
+		track.SNR = math.Max(track.SNR, o.snapshot.Sample.SNR)
+		if o.snapshot.LastUpdated.After(track.LastUpdated) {
+			track.LastUpdated = o.snapshot.LastUpdated
+		}
+	}
+	track.BearingDeg = wrapBearing(math.Atan2(sumSin, sumCos) * 180 / math.Pi)
+	track.Confidence = primary.snapshot.Sample.Confidence
+	track.LockState = primary.snapshot.Sample.LockState
+	changed := track.Device != primary.snapshot.Sample.Device
+	if changed {
+		track.Handovers++
+	}
+	track.Device = primary.snapshot.Sample.Device
+	return changed
+}
+
+// wrapBearing maps deg into [0, 360).
+func wrapBearing(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// bearingDiff returns b-a wrapped into (-180, 180].
+func bearingDiff(a, b float64) float64 {
+	d := wrapBearing(b - a)
+	if d > 180 {
+		d -= 360
+	}
+	return d
+}
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBearingHelpers(t *testing.T) {
+	tests := []struct {
+		a, b, wantDiff float64
+	}{
+		{a: 10, b: 20, wantDiff: 10},
+		{a: 350, b: 10, wantDiff: 20},
+		{a: 10, b: 350, wantDiff: -20},
+		{a: 0, b: 180, wantDiff: 180},
+	}
+	for _, tt := range tests {
+		if got := bearingDiff(tt.a, tt.b); math.Abs(got-tt.wantDiff) > 1e-9 {
+			t.Fatalf("bearingDiff(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.wantDiff)
+		}
+	}
+	if got := wrapBearing(-30); got != 330 {
+		t.Fatalf("wrapBearing(-30) = %v, want 330", got)
+	}
+}
+
+func TestSectorStitchingMergesOverlap(t *testing.T) {
+	hub := newTestHub()
+	north := hub.ForDevice("north", "mock")
+	east := hub.ForDevice("east", "mock")
+	hub.SetSector("north", 0, 120)
+	hub.SetSector("east", 90, 120)
+
+	now := time.Now()
+	// Emitter at 40° seen by both arrays; a second emitter at 300° by north only.
+	north.ReportMultiTrack(MultiTrackSample{Timestamp: now, Tracks: []TrackSample{
+		{ID: "1", AngleDeg: 40, Confidence: 0.9},
+		{ID: "2", AngleDeg: -60, Confidence: 0.9},
+	}})
+	east.ReportMultiTrack(MultiTrackSample{Timestamp: now, Tracks: []TrackSample{{ID: "1", AngleDeg: -50, Confidence: 0.9}}})
+
+	tracks := hub.UnifiedTracks()
+	if len(tracks) != 2 {
+		t.Fatalf("expected 2 unified tracks, got %+v", tracks)
+	}
+	if math.Abs(bearingDiff(tracks[0].BearingDeg, 40)) > 5 || len(tracks[0].Sources) != 2 {
+		t.Fatalf("expected merged track near 40°, got %+v", tracks[0])
+	}
+	if math.Abs(bearingDiff(tracks[1].BearingDeg, 300)) > 1e-6 || tracks[1].Device != "north" {
+		t.Fatalf("expected north-only track at 300°, got %+v", tracks[1])
+	}
+}
+
+func TestSectorStitchingHandover(t *testing.T) {
+	hub := newTestHub()
+	north := hub.ForDevice("north", "mock")
+	east := hub.ForDevice("east", "mock")
+	hub.SetSector("north", 0, 120)
+	hub.SetSector("east", 90, 120)
+
+	start := time.Now()
+	var id string
+	// Sweep an emitter from 20° to 70°; north loses it past its 60° edge.
+	for step, bearing := 0, 20.0; bearing <= 70; step, bearing = step+1, bearing+2 {
+		ts := start.Add(time.Duration(step) * 100 * time.Millisecond)
+		if bearing <= 60 {
+			north.ReportMultiTrack(MultiTrackSample{Timestamp: ts, Tracks: []TrackSample{{ID: "1", AngleDeg: bearing, Confidence: 0.9}}})
+		}
+		if bearing >= 30 {
+			east.ReportMultiTrack(MultiTrackSample{Timestamp: ts, Tracks: []TrackSample{{ID: "1", AngleDeg: bearing - 90, Confidence: 0.9}}})
+		}
+		tracks := hub.UnifiedTracks()
+		if len(tracks) != 1 {
+			t.Fatalf("bearing %.0f: expected one unified track, got %+v", bearing, tracks)
+		}
+		if id == "" {
+			id = tracks[0].ID
+		} else if tracks[0].ID != id {
+			t.Fatalf("bearing %.0f: unified ID changed from %s to %s", bearing, id, tracks[0].ID)
+		}
+	}
+
+	track := hub.UnifiedTracks()[0]
+	if track.Device != "east" || track.Handovers != 1 {
+		t.Fatalf("expected a single handover to east, got %+v", track)
+	}
+}
+
+func TestHandleTracksUnifiedView(t *testing.T) {
+	hub := newTestHub()
+	hub.SetSector("south", 180, 0)
+	hub.ForDevice("south", "mock").Report(10, -20, 15, 0.8, LockStateTracking, nil)
+
+	rr := httptest.NewRecorder()
+	hub.handleTracks(rr, httptest.NewRequest(http.MethodGet, "/api/tracks?view=unified", nil))
+
+	var tracks []UnifiedTrack
+	if err := json.NewDecoder(rr.Body).Decode(&tracks); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(tracks) != 1 || tracks[0].BearingDeg != 190 || tracks[0].Sources[0] != "south" {
+		t.Fatalf("unexpected unified tracks: %+v", tracks)
+	}
+}
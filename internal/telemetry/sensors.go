@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+// sensorHistoryLimit bounds the sensor series kept per device: two hours at
+// the default 10 s interval.
+const sensorHistoryLimit = 720
+
+// SensorSeries is the sensor history of one device, oldest first, as
+// returned by /api/sensors.
+type SensorSeries struct {
+	Device  string        `json:"device,omitempty"`
+	Samples []sdr.Sensors `json:"samples"`
+}
+
+// ObserveSensors implements sdr.SensorObserver for a single-device setup.
+func (h *Hub) ObserveSensors(s sdr.Sensors) {
+	h.observeSensors("", s)
+}
+
+// ObserveSensors implements sdr.SensorObserver for one device.
+func (d *deviceReporter) ObserveSensors(s sdr.Sensors) {
+	d.hub.observeSensors(d.id, s)
+}
+
+// observeSensors appends s to the series of device, dropping the oldest
+// samples beyond sensorHistoryLimit.
+func (h *Hub) observeSensors(device string, s sdr.Sensors) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series := append(h.sensors[device], s)
+	if len(series) > sensorHistoryLimit {
+		series = series[len(series)-sensorHistoryLimit:]
+	}
+	h.sensors[device] = series
+}
+
+// SensorSnapshots returns the sensor series of every device, or of the one
+// named, with the samples taken at or after since, sorted by device ID.
+func (h *Hub) SensorSnapshots(device string, since time.Time) []SensorSeries {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]SensorSeries, 0, len(h.sensors))
+	for id, samples := range h.sensors {
+		if device != "" && id != device {
+			continue
+		}
+		i := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(since) })
+		out = append(out, SensorSeries{Device: id, Samples: append([]sdr.Sensors(nil), samples[i:]...)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+// handleSensors returns the sensor series of every device, or of the one
+// selected with ?device=. ?since= (RFC 3339 time or duration, e.g. 30m)
+// limits the samples.
+func (h *Hub) handleSensors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var since time.Time
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		var err error
+		if since, err = parseSince(raw, h.now()); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.SensorSnapshots(parseDevice(r), since))
+}
+
+// writeSensorMetrics appends the latest sensor readings in the Prometheus
+// text format.
+func (h *Hub) writeSensorMetrics(sb *strings.Builder) {
+	var latest []sdr.Sensors
+	var devices []string
+	for _, series := range h.SensorSnapshots("", time.Time{}) {
+		if n := len(series.Samples); n > 0 {
+			latest = append(latest, series.Samples[n-1])
+			devices = append(devices, series.Device)
+		}
+	}
+	if len(latest) == 0 {
+		return
+	}
+	sb.WriteString("# HELP gosdr_temperature_celsius Radio die temperature.\n# TYPE gosdr_temperature_celsius gauge\n")
+	for i, s := range latest {
+		if s.TemperatureC != 0 {
+			fmt.Fprintf(sb, "gosdr_temperature_celsius{device=%q} %g\n", devices[i], s.TemperatureC)
+		}
+	}
+	sb.WriteString("# HELP gosdr_rssi_db Received signal strength indicator per RX channel.\n# TYPE gosdr_rssi_db gauge\n")
+	for i, s := range latest {
+		for ch, rssi := range []float64{s.RSSI0DB, s.RSSI1DB} {
+			if rssi != 0 {
+				fmt.Fprintf(sb, "gosdr_rssi_db{device=%q,channel=\"%d\"} %g\n", devices[i], ch, rssi)
+			}
+		}
+	}
+}
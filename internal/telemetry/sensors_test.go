@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestSensorEndpoints(t *testing.T) {
+	hub := newTestHub()
+	north := hub.ForDevice("north", "pluto").(*deviceReporter)
+	start := time.Now().Add(-time.Hour)
+	for i := range sensorHistoryLimit + 10 {
+		north.ObserveSensors(sdr.Sensors{Time: start.Add(time.Duration(i) * time.Second), TemperatureC: 40 + float64(i)/1000, RSSI0DB: 90, RSSI1DB: 91})
+	}
+	hub.ObserveSensors(sdr.Sensors{Time: time.Now(), TemperatureC: 35})
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		devices int
+		samples int // of the first device
+	}{
+		{name: "all", query: "", status: http.StatusOK, devices: 2, samples: 1},
+		{name: "device", query: "?device=north", status: http.StatusOK, devices: 1, samples: sensorHistoryLimit},
+		{name: "since", query: "?device=north&since=" + start.Add(time.Duration(sensorHistoryLimit)*time.Second).Format(time.RFC3339), status: http.StatusOK, devices: 1, samples: 10},
+		{name: "bad since", query: "?since=yesterday", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			hub.handleSensors(rr, httptest.NewRequest(http.MethodGet, "/api/sensors"+tt.query, nil))
+			if rr.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tt.status, rr.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got []SensorSeries
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.devices || len(got[0].Samples) != tt.samples {
+				t.Fatalf("got %d devices, first with %d samples; want %d and %d", len(got), len(got[0].Samples), tt.devices, tt.samples)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	hub.handlePrometheus(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`gosdr_temperature_celsius{device=""} 35`,
+		`gosdr_temperature_celsius{device="north"} 40.729`,
+		`gosdr_rssi_db{device="north",channel="1"} 91`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `gosdr_rssi_db{device=""`) {
+		t.Fatalf("metrics report an RSSI the device does not have:\n%s", body)
+	}
+}
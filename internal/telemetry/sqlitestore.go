@@ -0,0 +1,168 @@
+package telemetry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the pure-Go "sqlite" driver
+)
+
+// sqliteSchema creates a table per kind holding the JSON of each sample or
+// event under its timestamp in nanoseconds since 1970; rowid keeps the
+// append order of equal timestamps.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS samples (ts INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE INDEX IF NOT EXISTS samples_ts ON samples (ts);
+CREATE TABLE IF NOT EXISTS events (ts INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE INDEX IF NOT EXISTS events_ts ON events (ts);
+`
+
+// sqliteStore keeps samples and events in an SQLite database, so other
+// tools can query the history with SQL and the JSON functions. Each Append
+// is one transaction. The database runs in WAL mode, which lets readers
+// continue while the hub writes.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func openSQLiteStore(dsn string) (Store, error) {
+	path := strings.TrimPrefix(dsn, "sqlite:")
+	if path == "" {
+		return nil, fmt.Errorf("store %q: want sqlite:<file>", dsn)
+	}
+	return NewSQLiteStore(path)
+}
+
+// NewSQLiteStore returns a store in the SQLite database at path, creating
+// it and its tables if needed.
+func NewSQLiteStore(path string) (Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite store: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("open sqlite store: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Append(samples []MultiTrackSample, events []DiagnosticEvent) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+	insert := func(table string, n int, entry func(int) (time.Time, any)) error {
+		if n == 0 {
+			return nil
+		}
+		stmt, err := tx.Prepare("INSERT INTO " + table + " (ts, data) VALUES (?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i := range n {
+			t, v := entry(i)
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(t.UnixNano(), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := insert("samples", len(samples), func(i int) (time.Time, any) { return samples[i].Timestamp, samples[i] }); err != nil {
+		return err
+	}
+	if err := insert("events", len(events), func(i int) (time.Time, any) { return events[i].Timestamp, events[i] }); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scan calls fn with the rows of table in q's time range, oldest first,
+// until it returns false or an error.
+func (s *sqliteStore) scan(table string, q StoreQuery, fn func([]byte) (bool, error)) error {
+	since, until := int64(math.MinInt64), int64(math.MaxInt64)
+	if !q.Since.IsZero() {
+		since = q.Since.UnixNano()
+	}
+	if !q.Until.IsZero() {
+		until = q.Until.UnixNano()
+	}
+	rows, err := s.db.Query("SELECT data FROM "+table+" WHERE ts >= ? AND ts < ? ORDER BY ts, rowid", since, until)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		more, err := fn(data)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqliteStore) Samples(q StoreQuery) ([]MultiTrackSample, error) {
+	var out []MultiTrackSample
+	err := s.scan("samples", q, func(data []byte) (bool, error) {
+		var sample MultiTrackSample
+		if err := json.Unmarshal(data, &sample); err != nil {
+			return false, err
+		}
+		if sample, ok := q.sample(sample); ok {
+			out = append(out, sample)
+		}
+		return !q.full(len(out)), nil
+	})
+	return out, err
+}
+
+func (s *sqliteStore) Events(q StoreQuery) ([]DiagnosticEvent, error) {
+	var out []DiagnosticEvent
+	err := s.scan("events", q, func(data []byte) (bool, error) {
+		var e DiagnosticEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return false, err
+		}
+		out = append(out, e)
+		return !q.full(len(out)), nil
+	})
+	return out, err
+}
+
+// Span reads the range of the timestamp index of each table.
+func (s *sqliteStore) Span() (first, last time.Time, err error) {
+	for _, table := range []string{"samples", "events"} {
+		var lo, hi sql.NullInt64
+		if err := s.db.QueryRow("SELECT MIN(ts), MAX(ts) FROM "+table).Scan(&lo, &hi); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if lo.Valid {
+			first, last = widenSpan(first, last, time.Unix(0, lo.Int64).UTC())
+			first, last = widenSpan(first, last, time.Unix(0, hi.Int64).UTC())
+		}
+	}
+	return first, last, nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
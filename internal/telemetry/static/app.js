@@ -77,6 +77,11 @@ const snrDisplay = document.getElementById('snrValue');
 const confidenceDisplay = document.getElementById('confidenceValue');
 const lockBadge = document.getElementById('lockBadge');
 const summaryBackend = document.getElementById('summaryBackend');
+const summaryBackendState = document.getElementById('summaryBackendState');
+const iiodConsoleForm = document.getElementById('iiodConsoleForm');
+const iiodToken = document.getElementById('iiodToken');
+const iiodCommand = document.getElementById('iiodCommand');
+const iiodOutput = document.getElementById('iiodOutput');
 const summaryRxLo = document.getElementById('summaryRxLo');
 const summaryToneOffset = document.getElementById('summaryToneOffset');
 const summarySampleRate = document.getElementById('summarySampleRate');
@@ -493,12 +498,14 @@ function normalizeTracks(sample) {
       lockState,
       range: Number.isFinite(track.range) ? track.range : MAX_RANGE_CM / 2,
       ageSeconds: Number.isFinite(track.ageSeconds) ? track.ageSeconds : null,
+      polarization: track.polarization || '',
     };
   });
 }
 
-function updateTrackStore(tracks, timestamp) {
-  const nowMs = timestamp?.getTime?.() ?? Date.now();
+// nowMs is the sample's monotonic time when the server sent one, so track
+// expiry survives wall-clock steps on the tracker.
+function updateTrackStore(tracks, nowMs = Date.now()) {
   const seen = new Set();
   const timeoutMs = configSnapshot.trackTimeoutMs || 5000;
   tracks.forEach((track) => {
@@ -523,7 +530,8 @@ function updateTrackStore(tracks, timestamp) {
   });
 
   Array.from(trackStore.entries()).forEach(([id, entry]) => {
-    if (!seen.has(id) && nowMs - (entry.lastSeen || nowMs) > timeoutMs) {
+    // abs: restored history is on wall-clock time, live samples are not.
+    if (!seen.has(id) && Math.abs(nowMs - (entry.lastSeen || nowMs)) > timeoutMs) {
       trackStore.delete(id);
     }
   });
@@ -543,6 +551,7 @@ function renderTracksTable() {
       snr: entry.last?.snr,
       confidence: entry.last?.trackingConfidence,
       lockState: entry.last?.lockState || 'searching',
+      polarization: entry.last?.polarization || '',
       ageSeconds,
       color: entry.color,
     };
@@ -574,7 +583,7 @@ function renderTracksTable() {
       <span>${Number.isFinite(row.angleDeg) ? row.angleDeg.toFixed(1) : '--'}</span>
       <span>${Number.isFinite(row.snr) ? row.snr.toFixed(1) : '--'}</span>
       <span>${Number.isFinite(row.confidence) ? `${(row.confidence * 100).toFixed(0)}%` : '--'}</span>
-      <span><span class="lock-badge ${row.lockState}">${row.lockState}</span></span>
+      <span><span class="lock-badge ${row.lockState}">${row.lockState}</span>${row.polarization ? ` <span class="muted" title="Dominant polarization">${row.polarization}-pol</span>` : ''}</span>
       <span>${Number.isFinite(row.ageSeconds) ? `${row.ageSeconds.toFixed(1)}s` : '--'}</span>
     `;
     tracksTableBody.appendChild(div);
@@ -671,7 +680,7 @@ function addSample(sample, fromHistory = false) {
   const confidencePercent = Math.max(0, Math.min(1, primary?.trackingConfidence ?? 0)) * 100;
   pushPoint(confidenceChart, timestamp, confidencePercent);
 
-  updateTrackStore(tracks, timestampObj);
+  updateTrackStore(tracks, Number.isFinite(sample.monotonicMs) ? sample.monotonicMs : timestampObj.getTime());
   const radarTracks = Array.from(trackStore.values()).map((entry) => ({
     id: entry.id,
     angleDeg: entry.last?.angleDeg,
@@ -1177,6 +1186,44 @@ function updateSummaryPanel(cfg) {
   }
 }
 
+async function refreshBackendState() {
+  if (!summaryBackendState) return;
+  try {
+    const res = await fetch('/api/sdr/state');
+    if (!res.ok) return;
+    const states = await res.json();
+    summaryBackendState.textContent = states.length
+      ? states.map((s) => (s.device ? `${s.device}: ${s.kind}` : s.kind)).join(', ')
+      : '--';
+    summaryBackendState.title = states.filter((s) => s.error).map((s) => s.error).join('\n');
+  } catch (err) {
+    console.error('backend state', err);
+  }
+}
+
+// runIIODCommand sends one console command to /api/iiod/exec and appends the
+// raw response. The token lives in sessionStorage so it is gone with the tab.
+async function runIIODCommand(event) {
+  event.preventDefault();
+  const command = iiodCommand.value.trim();
+  if (!command) return;
+  sessionStorage.setItem('iiodToken', iiodToken.value);
+  let text;
+  try {
+    const res = await fetch('/api/iiod/exec', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${iiodToken.value}` },
+      body: JSON.stringify({ command }),
+    });
+    const body = await res.json();
+    text = res.ok ? body.response : `error: ${body.error}`;
+  } catch (err) {
+    text = `error: ${err}`;
+  }
+  iiodOutput.textContent += `> ${command}\n${text}\n`;
+  iiodOutput.scrollTop = iiodOutput.scrollHeight;
+}
+
 async function refreshConfigSummary() {
   try {
     const res = await fetch('/api/config');
@@ -1235,6 +1282,8 @@ function pushPoint(chart, label, value) {
 
 refreshConfigSummary();
 setInterval(refreshConfigSummary, CONFIG_REFRESH_MS);
+refreshBackendState();
+setInterval(refreshBackendState, CONFIG_REFRESH_MS);
 
 if (traceViewport) {
   traceViewport.addEventListener('scroll', renderTraceRows);
@@ -1257,6 +1306,10 @@ document.querySelectorAll('[data-track-sort]').forEach((btn) => {
 if (trackFilterInput) {
   trackFilterInput.addEventListener('input', renderTracksTable);
 }
+if (iiodConsoleForm) {
+  iiodToken.value = sessionStorage.getItem('iiodToken') || '';
+  iiodConsoleForm.addEventListener('submit', runIIODCommand);
+}
 if (trackStateFilter) {
   trackStateFilter.addEventListener('change', renderTracksTable);
 }
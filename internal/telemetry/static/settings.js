@@ -8,6 +8,7 @@ const fieldIds = [
   'toneOffsetHz',
   'spacingWavelength',
   'numSamples',
+  'fftWindow',
   'bufferSize',
   'historyLimit',
   'trackingLength',
@@ -29,6 +30,9 @@ const fieldIds = [
   'logLevel',
   'logFormat',
   'debugMode',
+  'angleUnit',
+  'powerUnit',
+  'powerOffsetDb',
 ];
 
 const numericFields = new Set([
@@ -52,6 +56,7 @@ const numericFields = new Set([
   'rxGain0',
   'rxGain1',
   'txGain',
+  'powerOffsetDb',
 ]);
 
 const booleanFields = new Set(['debugMode']);
@@ -62,6 +67,7 @@ const defaults = {
   toneOffsetHz: 200000,
   spacingWavelength: 0.5,
   numSamples: 512,
+  fftWindow: 'hamming',
   bufferSize: 4096,
   historyLimit: 500,
   trackingLength: 50,
@@ -83,6 +89,9 @@ const defaults = {
   logLevel: 'warn',
   logFormat: 'text',
   debugMode: false,
+  angleUnit: 'deg',
+  powerUnit: 'dBFS',
+  powerOffsetDb: 0,
 };
 
 const statusEl = $('status');
@@ -92,6 +101,7 @@ const backendField = $('sdrBackend');
 const restartBanner = $('restartBanner');
 
 let lastSavedConfig = null;
+let configRevision = null;
 
 function setStatus(message, type = 'info') {
   if (!statusEl) return;
@@ -150,6 +160,7 @@ async function loadConfig() {
     const cfg = await res.json();
     applyConfig(cfg);
     lastSavedConfig = cfg;
+    configRevision = cfg.revision;
     showRestartBanner(false);
     setStatus('');
   } catch (err) {
@@ -182,8 +193,12 @@ form.addEventListener('submit', async (evt) => {
     const res = await fetch('/api/config/update', {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify(payload),
+      body: JSON.stringify({ ...payload, revision: configRevision }),
     });
+    if (res.status === 409) {
+      showConflict(await res.json());
+      return;
+    }
     if (!res.ok) {
       const errText = await res.text();
       let message = errText || `Failed with status ${res.status}`;
@@ -199,6 +214,7 @@ form.addEventListener('submit', async (evt) => {
       lastSavedConfig &&
       (lastSavedConfig.sdrBackend !== saved.sdrBackend || lastSavedConfig.sdrUri !== saved.sdrUri);
     lastSavedConfig = saved;
+    configRevision = saved.revision;
     setStatus('Configuration updated', 'success');
     showRestartBanner(Boolean(backendChanged));
   } catch (err) {
@@ -207,6 +223,26 @@ form.addEventListener('submit', async (evt) => {
   }
 });
 
+// showConflict handles a 409 from a stale save: the settings another user
+// changed are loaded into the form, the rest of the edits are kept, and the
+// next save applies them on top of the current revision.
+function showConflict(conflict) {
+  const keys = (conflict.diff || []).map((d) => d.key);
+  keys.forEach((key) => {
+    const el = $(key);
+    if (!el || conflict.current[key] === undefined) return;
+    if (el.type === 'checkbox' || booleanFields.has(key)) {
+      el.checked = Boolean(conflict.current[key]);
+    } else {
+      el.value = conflict.current[key];
+    }
+  });
+  lastSavedConfig = conflict.current;
+  configRevision = conflict.revision;
+  const changed = keys.length ? keys.join(', ') : 'settings';
+  setStatus(`Another user changed ${changed}; review and save again`, 'error');
+}
+
 resetBtn.addEventListener('click', () => {
   applyConfig(defaults);
   setStatus('Restored defaults (not yet saved)');
@@ -216,6 +252,45 @@ backendField?.addEventListener('change', updateBackendState);
 
 loadConfig();
 
+// ===== Backend capabilities =====
+// Clamp inputs to what the running backend supports and disable controls for
+// features it lacks (e.g. TX gain on receive-only backends).
+function applyCapabilities(caps) {
+  const ranges = {
+    rxGain0: [caps.minRxGainDb, caps.maxRxGainDb],
+    rxGain1: [caps.minRxGainDb, caps.maxRxGainDb],
+    txGain: [caps.minTxGainDb, caps.maxTxGainDb],
+    rxLoHz: [caps.minFrequencyHz, caps.maxFrequencyHz],
+    sampleRateHz: [caps.minSampleRateHz, caps.maxSampleRateHz],
+  };
+  Object.entries(ranges).forEach(([id, [min, max]]) => {
+    const el = $(id);
+    if (!el || !(max > min)) return;
+    el.min = min;
+    el.max = max;
+  });
+  const txGainInput = $('txGain');
+  if (txGainInput) {
+    txGainInput.disabled = !caps.supportsTx;
+  }
+  const liveAngleSection = $('mockControlSection');
+  if (liveAngleSection && !caps.simulatedAngle) {
+    liveAngleSection.style.display = 'none';
+  }
+}
+
+async function loadCapabilities() {
+  try {
+    const res = await fetch('/api/sdr/capabilities');
+    if (!res.ok) return;
+    applyCapabilities(await res.json());
+  } catch (err) {
+    console.error('load capabilities failed:', err);
+  }
+}
+
+loadCapabilities();
+
 // ===== MockSDR Live Angle Control =====
 const mockControlSection = $('mockControlSection');
 const mockAngleSlider = $('mockAngleSlider');
@@ -0,0 +1,161 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Steering pairs the raw measured angle with the conditioned steering angle
+// that rotators and other pointing consumers should follow. Changed is set on
+// the updates where the conditioned angle moved.
+type Steering struct {
+	Device    string    `json:"device,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	RawDeg    float64   `json:"rawDeg"`
+	AngleDeg  float64   `json:"angleDeg"`
+	Changed   bool      `json:"changed,omitempty"`
+}
+
+// ReportSteering stores the steering state of a single-device setup.
+func (h *Hub) ReportSteering(s Steering) {
+	h.reportSteering("", s)
+}
+
+// ReportSteering stores the steering state of one device.
+func (d *deviceReporter) ReportSteering(s Steering) {
+	d.hub.reportSteering(d.id, s)
+}
+
+// reportSteering stores s and, when the conditioned angle changed, sends it to
+// the steering stream subscribers. The conditioned angle is rounded to the
+// output resolution, and changes are sent at most at the output rate: a
+// change that comes too early is sent with a later report once the rate
+// allows. Slow subscribers miss updates rather than block the tracker.
+func (h *Hub) reportSteering(device string, s Steering) {
+	s.Device = device
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.AngleDeg = h.output.Quantize(s.AngleDeg)
+	h.steering[device] = s
+
+	gate := h.steeringGates[device]
+	if gate == nil {
+		gate = &outputGate[Steering]{}
+		h.steeringGates[device] = gate
+	}
+	interval := h.output.interval()
+	if held, ok := gate.due(s.Timestamp, interval); ok {
+		h.sendSteering(held)
+	}
+	if s.Changed && gate.offer(s.Timestamp, s.AngleDeg, s, interval) {
+		h.sendSteering(s)
+	}
+}
+
+// sendSteering fans s out to the stream subscribers. h.mu must be held.
+func (h *Hub) sendSteering(s Steering) {
+	for ch := range h.steeringSubs {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
+
+// SteeringSnapshots returns the latest steering state per device, sorted by
+// device ID.
+func (h *Hub) SteeringSnapshots() []Steering {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]Steering, 0, len(h.steering))
+	for _, s := range h.steering {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+// subscribeSteering registers a listener for conditioned steering changes.
+func (h *Hub) subscribeSteering() (chan Steering, func()) {
+	ch := make(chan Steering, 16)
+	h.mu.Lock()
+	h.steeringSubs[ch] = struct{}{}
+	h.mu.Unlock()
+	cancel := func() {
+		h.mu.Lock()
+		delete(h.steeringSubs, ch)
+		close(ch)
+		h.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// handleSteering returns the steering state of every device, or of the one
+// selected with ?device=.
+func (h *Hub) handleSteering(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	device := parseDevice(r)
+	out := make([]Steering, 0)
+	for _, s := range h.SteeringSnapshots() {
+		if device == "" || s.Device == device {
+			out = append(out, s)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// handleSteeringStream streams conditioned steering angles as server-sent
+// events, one event per change, starting with the current state.
+func (h *Hub) handleSteeringStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	device := parseDevice(r)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch, cancel := h.subscribeSteering()
+	defer cancel()
+
+	write := func(s Steering) {
+		payload, _ := json.Marshal(s)
+		w.Write([]byte("data: "))
+		w.Write(payload)
+		w.Write([]byte("\n\n"))
+	}
+	for _, s := range h.SteeringSnapshots() {
+		if device == "" || s.Device == device {
+			write(s)
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case s, ok := <-ch:
+			if !ok {
+				return
+			}
+			if device != "" && s.Device != device {
+				continue
+			}
+			write(s)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
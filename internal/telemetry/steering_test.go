@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSteeringEndpoints(t *testing.T) {
+	hub := newTestHub()
+	hub.ForDevice("north", "mock").(*deviceReporter).ReportSteering(Steering{RawDeg: 11, AngleDeg: 10})
+	hub.ReportSteering(Steering{RawDeg: 3, AngleDeg: 3, Changed: true})
+
+	rr := httptest.NewRecorder()
+	hub.handleSteering(rr, httptest.NewRequest(http.MethodGet, "/api/steering?device=north", nil))
+	var got []Steering
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Device != "north" || got[0].RawDeg != 11 || got[0].AngleDeg != 10 {
+		t.Fatalf("unexpected steering %+v", got)
+	}
+}
+
+func TestSteeringStreamSendsChangesOnly(t *testing.T) {
+	hub := newTestHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		hub.handleSteeringStream(rr, httptest.NewRequest(http.MethodGet, "/api/steering/stream", nil).WithContext(ctx))
+		close(done)
+	}()
+
+	// Wait for the handler to subscribe.
+	for i := 0; i < 100; i++ {
+		hub.mu.RLock()
+		n := len(hub.steeringSubs)
+		hub.mu.RUnlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	hub.ReportSteering(Steering{RawDeg: 10.4, AngleDeg: 10})
+	hub.ReportSteering(Steering{RawDeg: 20, AngleDeg: 20, Changed: true})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	body := rr.Body.String()
+	if strings.Count(body, "data: ") != 1 || !strings.Contains(body, `"angleDeg":20`) {
+		t.Fatalf("unexpected stream:\n%s", body)
+	}
+}
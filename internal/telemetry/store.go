@@ -0,0 +1,547 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+const (
+	// storeFlushInterval is how often queued samples and events are written
+	// to the store.
+	storeFlushInterval = time.Second
+	// maxStoreQueue bounds the samples and events queued while the store is
+	// slow or failing; the oldest are dropped first.
+	maxStoreQueue = 100_000
+	// defaultStoreQueryLimit and maxStoreQueryLimit bound /api/store queries.
+	defaultStoreQueryLimit = 10_000
+	maxStoreQueryLimit     = 100_000
+)
+
+// Store keeps the history samples and diagnostic events beyond the hub's
+// in-memory limits. Tracks are stored as part of their samples. Backends are
+// selected by the scheme of a data source name (see OpenStore) so embedded
+// units can keep a local file while larger installations query a central
+// one.
+type Store interface {
+	// Append stores samples and events, each in time order.
+	Append(samples []MultiTrackSample, events []DiagnosticEvent) error
+	// Samples returns the samples matching q, oldest first.
+	Samples(q StoreQuery) ([]MultiTrackSample, error)
+	// Events returns the events matching q, oldest first.
+	Events(q StoreQuery) ([]DiagnosticEvent, error)
+	// Span returns the time of the oldest and newest stored sample or
+	// event; both are zero for an empty store.
+	Span() (first, last time.Time, err error)
+	Close() error
+}
+
+// StoreQuery selects stored samples or events. Zero values disable the
+// corresponding filter; Device and TrackIDs only apply to samples.
+type StoreQuery struct {
+	// Since is inclusive, Until exclusive.
+	Since, Until time.Time
+	// Limit returns only the first Limit matches.
+	Limit    int
+	Device   string
+	TrackIDs []string
+}
+
+// includes reports whether t lies in the query's time range.
+func (q StoreQuery) includes(t time.Time) bool {
+	return (q.Since.IsZero() || !t.Before(q.Since)) && (q.Until.IsZero() || t.Before(q.Until))
+}
+
+// full reports whether n results reach the limit.
+func (q StoreQuery) full(n int) bool {
+	return q.Limit > 0 && n >= q.Limit
+}
+
+// sample applies the device and track filters to s.
+func (q StoreQuery) sample(s MultiTrackSample) (MultiTrackSample, bool) {
+	if !q.includes(s.Timestamp) {
+		return MultiTrackSample{}, false
+	}
+	if len(q.TrackIDs) == 0 && q.Device == "" {
+		return s, true // keep samples without tracks, too
+	}
+	s, ok := filterTracks(s, trackFilterSet(q.TrackIDs))
+	if !ok {
+		return MultiTrackSample{}, false
+	}
+	return filterDevice(s, q.Device)
+}
+
+// StoreOpener opens a store from a data source name.
+type StoreOpener func(dsn string) (Store, error)
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]StoreOpener{
+		"memory":  openMemoryStore,
+		"file":    openFileStore,
+		"bolt":    openBoltStore,
+		"sqlite":  openSQLiteStore,
+		"influx":  openInfluxStore,
+		"influxs": openInfluxStore,
+		"journal": openJournalStore,
+		"http":    openRemoteStore,
+		"https":   openRemoteStore,
+	}
+)
+
+// RegisterStore makes a backend selectable by the scheme of a data source
+// name, such as "postgres" for "postgres://central/gosdr". Backends that
+// need cgo or a database driver this module does not include register from
+// an init function in a file behind a build tag, so the default build stays
+// free of them.
+func RegisterStore(scheme string, open StoreOpener) error {
+	if scheme == "" || open == nil {
+		return fmt.Errorf("register store: scheme and opener are required")
+	}
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if _, ok := stores[scheme]; ok {
+		return fmt.Errorf("register store: %q already registered", scheme)
+	}
+	stores[scheme] = open
+	return nil
+}
+
+// StoreSchemes returns the registered store schemes, sorted.
+func StoreSchemes() []string {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenStore opens the store named by dsn, "<scheme>:<location>":
+//
+//	memory:                      in process, lost on exit (?limit= samples)
+//	file:/var/lib/gosdr/store    JSON lines, one file per UTC day
+//	bolt:/var/lib/gosdr/tel.db   an embedded bbolt file
+//	sqlite:/var/lib/gosdr/tel.db an embedded SQLite database
+//	influx://central:8086/gosdr  an InfluxDB database (influxs:// for HTTPS)
+//	journal:state.journal        a state journal, read only
+//	http://central:8080          another hub's /api/store, read only
+//
+// Further schemes are added with RegisterStore.
+func OpenStore(dsn string) (Store, error) {
+	scheme, _, ok := strings.Cut(dsn, ":")
+	if !ok || scheme == "" {
+		return nil, fmt.Errorf("store %q: want <scheme>:<location> (have %s)", dsn, strings.Join(StoreSchemes(), ", "))
+	}
+	storesMu.RLock()
+	open, ok := stores[scheme]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store scheme %q (have %s)", scheme, strings.Join(StoreSchemes(), ", "))
+	}
+	return open(dsn)
+}
+
+// errReadOnlyStore is returned by Append of stores that only serve queries.
+var errReadOnlyStore = errors.New("store is read only")
+
+// memoryStore keeps everything in process memory, up to limit samples and
+// limit events.
+type memoryStore struct {
+	mu      sync.RWMutex
+	limit   int
+	samples []MultiTrackSample
+	events  []DiagnosticEvent
+}
+
+// defaultMemoryStoreLimit is the number of samples and events a memory
+// store keeps by default.
+const defaultMemoryStoreLimit = 1_000_000
+
+func openMemoryStore(dsn string) (Store, error) {
+	limit := defaultMemoryStoreLimit
+	if _, query, ok := strings.Cut(dsn, "?"); ok {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("store %q: %w", dsn, err)
+		}
+		if raw := values.Get("limit"); raw != "" {
+			if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+				return nil, fmt.Errorf("store %q: invalid limit %q", dsn, raw)
+			}
+		}
+	}
+	return NewMemoryStore(limit), nil
+}
+
+// NewMemoryStore returns a store that keeps the last limit samples and
+// limit events in memory.
+func NewMemoryStore(limit int) Store {
+	return &memoryStore{limit: limit}
+}
+
+func (m *memoryStore) Append(samples []MultiTrackSample, events []DiagnosticEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range samples {
+		m.samples = append(m.samples, cloneMultiTrackSample(s))
+	}
+	m.events = append(m.events, events...)
+	if len(m.samples) > m.limit {
+		m.samples = append([]MultiTrackSample(nil), m.samples[len(m.samples)-m.limit:]...)
+	}
+	if len(m.events) > m.limit {
+		m.events = append([]DiagnosticEvent(nil), m.events[len(m.events)-m.limit:]...)
+	}
+	return nil
+}
+
+func (m *memoryStore) Samples(q StoreQuery) ([]MultiTrackSample, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []MultiTrackSample
+	for _, s := range m.samples {
+		if q.full(len(out)) {
+			break
+		}
+		if s, ok := q.sample(s); ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryStore) Events(q StoreQuery) ([]DiagnosticEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []DiagnosticEvent
+	for _, e := range m.events {
+		if q.full(len(out)) {
+			break
+		}
+		if q.includes(e.Timestamp) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryStore) Span() (first, last time.Time, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.samples) > 0 {
+		first, last = m.samples[0].Timestamp, m.samples[len(m.samples)-1].Timestamp
+	}
+	if len(m.events) > 0 {
+		first, last = widenSpan(first, last, m.events[0].Timestamp)
+		first, last = widenSpan(first, last, m.events[len(m.events)-1].Timestamp)
+	}
+	return first, last, nil
+}
+
+func (m *memoryStore) Close() error { return nil }
+
+// widenSpan extends [first, last] to include t.
+func widenSpan(first, last, t time.Time) (time.Time, time.Time) {
+	if first.IsZero() || t.Before(first) {
+		first = t
+	}
+	if last.IsZero() || t.After(last) {
+		last = t
+	}
+	return first, last
+}
+
+// openJournalStore serves the samples of a state journal, so "monopulse
+// store migrate" can move a journal into another store.
+func openJournalStore(dsn string) (Store, error) {
+	path := strings.TrimPrefix(dsn, "journal:")
+	history, err := ReadJournalHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &memoryStore{limit: max(len(history), 1)}
+	_ = m.Append(history, nil)
+	return readOnlyStore{m}, nil
+}
+
+// readOnlyStore refuses writes to the store it wraps.
+type readOnlyStore struct{ Store }
+
+func (readOnlyStore) Append([]MultiTrackSample, []DiagnosticEvent) error { return errReadOnlyStore }
+
+// SetStore makes the hub write every recorded sample and event to s as
+// well, in batches once per second while RunStore runs, and serve it under
+// /api/store. The hub does not take ownership: RunStore closes it on exit.
+func (h *Hub) SetStore(s Store) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.store = s
+	h.storeSamples, h.storeEvents, h.storeDropped = nil, nil, 0
+}
+
+// queueStoreSampleLocked queues s for the store, if one is set. The caller
+// holds h.mu.
+func (h *Hub) queueStoreSampleLocked(s MultiTrackSample) {
+	if h.store == nil {
+		return
+	}
+	h.storeSamples = append(h.storeSamples, cloneMultiTrackSample(s))
+	if len(h.storeSamples) > maxStoreQueue {
+		h.storeSamples = h.storeSamples[1:]
+		h.storeDropped++
+	}
+}
+
+// queueStoreEventLocked queues e for the store, if one is set. The caller
+// holds h.mu.
+func (h *Hub) queueStoreEventLocked(e DiagnosticEvent) {
+	if h.store == nil {
+		return
+	}
+	h.storeEvents = append(h.storeEvents, e)
+	if len(h.storeEvents) > maxStoreQueue {
+		h.storeEvents = h.storeEvents[1:]
+		h.storeDropped++
+	}
+}
+
+// RunStore writes the queued samples and events to the store every second
+// until ctx is done, then writes the rest and closes the store. A failing
+// store is logged once until it recovers; the queue keeps the latest
+// entries meanwhile. It returns at once when no store is set.
+func (h *Hub) RunStore(ctx context.Context) {
+	h.mu.RLock()
+	s := h.store
+	h.mu.RUnlock()
+	if s == nil {
+		return
+	}
+	ticker := time.NewTicker(storeFlushInterval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			err := errors.Join(h.flushStore(s), s.Close())
+			if err != nil {
+				h.logger.Warn("telemetry store", logging.Field{Key: "error", Value: err})
+			}
+			return
+		case <-ticker.C:
+			err := h.flushStore(s)
+			switch {
+			case err != nil && !failing:
+				h.logger.Warn("telemetry store failing", logging.Field{Key: "error", Value: err})
+			case err == nil && failing:
+				h.logger.Info("telemetry store recovered")
+			}
+			failing = err != nil
+		}
+	}
+}
+
+// flushStore writes the queue to s. On failure the entries are queued again
+// ahead of newer ones.
+func (h *Hub) flushStore(s Store) error {
+	h.mu.Lock()
+	samples, events, dropped := h.storeSamples, h.storeEvents, h.storeDropped
+	h.storeSamples, h.storeEvents, h.storeDropped = nil, nil, 0
+	h.mu.Unlock()
+	if dropped > 0 {
+		h.logger.Warn("telemetry store queue overflowed", logging.Field{Key: "dropped", Value: dropped})
+	}
+	if len(samples) == 0 && len(events) == 0 {
+		return nil
+	}
+	err := s.Append(samples, events)
+	if err == nil || errors.Is(err, errReadOnlyStore) {
+		return err
+	}
+	h.mu.Lock()
+	h.storeSamples = append(samples, h.storeSamples...)
+	h.storeEvents = append(events, h.storeEvents...)
+	if n := len(h.storeSamples) - maxStoreQueue; n > 0 {
+		h.storeSamples = h.storeSamples[n:]
+		h.storeDropped += uint64(n)
+	}
+	if n := len(h.storeEvents) - maxStoreQueue; n > 0 {
+		h.storeEvents = h.storeEvents[n:]
+		h.storeDropped += uint64(n)
+	}
+	h.mu.Unlock()
+	return err
+}
+
+// parseStoreQuery reads since, until (RFC 3339 times or durations before
+// now), limit, device and tracks.
+func parseStoreQuery(r *http.Request, now time.Time) (StoreQuery, error) {
+	values := r.URL.Query()
+	q := StoreQuery{Device: parseDevice(r), TrackIDs: parseTrackIDs(r), Limit: defaultStoreQueryLimit}
+	var err error
+	if raw := values.Get("since"); raw != "" {
+		if q.Since, err = parseSince(raw, now); err != nil {
+			return StoreQuery{}, err
+		}
+	}
+	if raw := values.Get("until"); raw != "" {
+		if q.Until, err = parseSince(raw, now); err != nil {
+			return StoreQuery{}, fmt.Errorf("invalid until %q: want RFC 3339 time or duration", raw)
+		}
+	}
+	if raw := values.Get("limit"); raw != "" {
+		if q.Limit, err = parseNonNegative(raw, "limit"); err != nil {
+			return StoreQuery{}, err
+		}
+		if q.Limit == 0 || q.Limit > maxStoreQueryLimit {
+			q.Limit = maxStoreQueryLimit
+		}
+	}
+	return q, nil
+}
+
+// storeRequest returns the hub's store and the query of r, or writes the
+// error.
+func (h *Hub) storeRequest(w http.ResponseWriter, r *http.Request) (Store, StoreQuery, bool) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return nil, StoreQuery{}, false
+	}
+	h.mu.RLock()
+	s := h.store
+	h.mu.RUnlock()
+	if s == nil {
+		writeJSONError(w, http.StatusNotFound, "no telemetry store configured")
+		return nil, StoreQuery{}, false
+	}
+	q, err := parseStoreQuery(r, h.now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, StoreQuery{}, false
+	}
+	return s, q, true
+}
+
+// handleStoreSamples serves GET /api/store/samples: stored samples, oldest
+// first.
+func (h *Hub) handleStoreSamples(w http.ResponseWriter, r *http.Request) {
+	s, q, ok := h.storeRequest(w, r)
+	if !ok {
+		return
+	}
+	out, err := s.Samples(q)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if out == nil {
+		out = []MultiTrackSample{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// handleStoreEvents serves GET /api/store/events: stored events, oldest
+// first.
+func (h *Hub) handleStoreEvents(w http.ResponseWriter, r *http.Request) {
+	s, q, ok := h.storeRequest(w, r)
+	if !ok {
+		return
+	}
+	out, err := s.Events(q)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if out == nil {
+		out = []DiagnosticEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// storeSpan is the body of GET /api/store/span.
+type storeSpan struct {
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+}
+
+// handleStoreSpan serves GET /api/store/span: the time range of the store.
+func (h *Hub) handleStoreSpan(w http.ResponseWriter, r *http.Request) {
+	s, _, ok := h.storeRequest(w, r)
+	if !ok {
+		return
+	}
+	first, last, err := s.Span()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(storeSpan{First: first, Last: last})
+}
+
+// MigrateStore copies the samples and events of src in [since, until) to
+// dst, chunk (a day when non-positive) at a time so neither store has to
+// hold the whole range in memory. A chunk may hold fewer than
+// maxStoreQueryLimit samples and as many events. Zero bounds cover the span of src. It
+// returns the number of samples and events copied.
+func MigrateStore(dst, src Store, since, until time.Time, chunk time.Duration) (samples, events int, err error) {
+	if chunk <= 0 {
+		chunk = 24 * time.Hour
+	}
+	first, last, err := src.Span()
+	if err != nil {
+		return 0, 0, err
+	}
+	if first.IsZero() {
+		return 0, 0, nil
+	}
+	if since.IsZero() || since.Before(first) {
+		since = first
+	}
+	if until.IsZero() || until.After(last) {
+		until = last.Add(time.Nanosecond)
+	}
+	for start := since; start.Before(until); start = start.Add(chunk) {
+		end := start.Add(chunk)
+		if end.After(until) {
+			end = until
+		}
+		q := StoreQuery{Since: start, Until: end, Limit: maxStoreQueryLimit}
+		s, err := src.Samples(q)
+		if err != nil {
+			return samples, events, err
+		}
+		e, err := src.Events(q)
+		if err != nil {
+			return samples, events, err
+		}
+		// Remote stores cap each query; a full chunk may have been cut.
+		if len(s) >= maxStoreQueryLimit || len(e) >= maxStoreQueryLimit {
+			return samples, events, fmt.Errorf("migrate %s: more than %d entries in %s; use a shorter chunk", start.UTC().Format(time.RFC3339), maxStoreQueryLimit-1, chunk)
+		}
+		if len(s) == 0 && len(e) == 0 {
+			continue
+		}
+		if err := dst.Append(s, e); err != nil {
+			return samples, events, err
+		}
+		samples += len(s)
+		events += len(e)
+	}
+	return samples, events, nil
+}
@@ -0,0 +1,188 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func storeSample(t time.Time, ids ...string) MultiTrackSample {
+	s := MultiTrackSample{Timestamp: t}
+	for _, id := range ids {
+		s.Tracks = append(s.Tracks, TrackSample{ID: id, AngleDeg: 10, SNR: 20})
+	}
+	return s
+}
+
+func TestStoreBackends(t *testing.T) {
+	day := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	samples := []MultiTrackSample{
+		storeSample(day, "a"),
+		storeSample(day.Add(30*time.Second), "a", "b"),
+		storeSample(day.Add(2*time.Minute), "b"), // the next day
+	}
+	events := []DiagnosticEvent{{ID: 1, Timestamp: day.Add(time.Second), Level: "info", Message: "start"}}
+
+	dir := t.TempDir()
+	influx := strings.Replace(newFakeInflux(t).URL, "http://", "influx://", 1) + "/gosdr?token=secret"
+	for _, dsn := range []string{"memory:", "file:" + dir, "bolt:" + filepath.Join(t.TempDir(), "store.db"), "sqlite:" + filepath.Join(t.TempDir(), "store.db"), influx} {
+		s, err := OpenStore(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Append(samples[:2], events); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Append(samples[2:], nil); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := s.Samples(StoreQuery{})
+		if err != nil || len(all) != 3 || !all[2].Timestamp.Equal(samples[2].Timestamp) {
+			t.Fatalf("%s: samples %+v, %v", dsn, all, err)
+		}
+		got, _ := s.Samples(StoreQuery{Since: day.Add(time.Second), TrackIDs: []string{"b"}})
+		if len(got) != 2 || len(got[0].Tracks) != 1 || got[0].Tracks[0].ID != "b" {
+			t.Fatalf("%s: filtered %+v", dsn, got)
+		}
+		if got, _ := s.Samples(StoreQuery{Until: day.Add(time.Minute), Limit: 1}); len(got) != 1 || len(got[0].Tracks) != 1 {
+			t.Fatalf("%s: limited %+v", dsn, got)
+		}
+		if got, _ := s.Events(StoreQuery{Since: day}); len(got) != 1 || got[0].Message != "start" {
+			t.Fatalf("%s: events %+v", dsn, got)
+		}
+		first, last, err := s.Span()
+		if err != nil || !first.Equal(day) || !last.Equal(samples[2].Timestamp) {
+			t.Fatalf("%s: span %v - %v, %v", dsn, first, last, err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if dsn == "memory:" {
+			continue
+		}
+		reopened, err := OpenStore(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := reopened.Samples(StoreQuery{}); err != nil || len(got) != 3 {
+			t.Fatalf("%s: %d samples after reopening, %v", dsn, len(got), err)
+		}
+		_ = reopened.Close()
+	}
+
+	// A line torn by a crash is skipped.
+	f, err := os.OpenFile(filepath.Join(dir, "samples-2024-05-02.jsonl"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"timestamp":"2024-05-02T00:02`)
+	_ = f.Close()
+	reopened, _ := OpenStore("file:" + dir)
+	if got, err := reopened.Samples(StoreQuery{}); err != nil || len(got) != 3 {
+		t.Fatalf("after torn line: %d samples, %v", len(got), err)
+	}
+}
+
+func TestStoreRegistry(t *testing.T) {
+	if _, err := OpenStore("nosuch://central/gosdr"); err == nil {
+		t.Fatal("unknown scheme accepted")
+	}
+	if err := RegisterStore("memory", openMemoryStore); err == nil {
+		t.Fatal("duplicate scheme accepted")
+	}
+	if _, err := OpenStore("memory:?limit=0"); err == nil {
+		t.Fatal("zero limit accepted")
+	}
+	s, err := OpenStore("memory:?limit=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	_ = s.Append([]MultiTrackSample{storeSample(now), storeSample(now.Add(time.Second)), storeSample(now.Add(2 * time.Second))}, nil)
+	if got, _ := s.Samples(StoreQuery{}); len(got) != 2 || !got[0].Timestamp.Equal(now.Add(time.Second)) {
+		t.Fatalf("limited memory store kept %+v", got)
+	}
+}
+
+func TestMigrateStore(t *testing.T) {
+	src := NewMemoryStore(100)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	var samples []MultiTrackSample
+	for i := range 10 {
+		samples = append(samples, storeSample(start.Add(time.Duration(i)*7*time.Hour), "a"))
+	}
+	_ = src.Append(samples, []DiagnosticEvent{{Timestamp: start.Add(time.Hour), Level: "warn", Message: "x"}})
+
+	dst, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	n, e, err := MigrateStore(dst, src, start.Add(time.Hour), time.Time{}, 12*time.Hour)
+	if err != nil || n != 9 || e != 1 {
+		t.Fatalf("migrated %d samples, %d events, %v", n, e, err)
+	}
+	got, _ := dst.Samples(StoreQuery{})
+	if len(got) != 9 || !got[8].Timestamp.Equal(samples[9].Timestamp) {
+		t.Fatalf("destination holds %+v", got)
+	}
+	if _, _, err := MigrateStore(readOnlyStore{dst}, src, time.Time{}, time.Time{}, 0); err == nil {
+		t.Fatal("migrated into a read-only store")
+	}
+}
+
+func TestHubStore(t *testing.T) {
+	hub := newTestHub()
+	s := NewMemoryStore(100)
+	hub.SetStore(s)
+	now := time.Now()
+	hub.ReportMultiTrack(storeSample(now, "a"))
+	hub.LogEvent("warn", "stored")
+	if err := hub.flushStore(s); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Samples(StoreQuery{}); len(got) != 1 || got[0].Tracks[0].ID != "a" {
+		t.Fatalf("stored samples %+v", got)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(hub.handleStoreEvents))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "?since=1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var events []DiagnosticEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Message != "stored" || events[0].ID == 0 {
+		t.Fatalf("served events %+v", events)
+	}
+
+	// The remote store reads the same endpoints.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/store/samples", hub.handleStoreSamples)
+	mux.HandleFunc("/api/store/span", hub.handleStoreSpan)
+	central := httptest.NewServer(mux)
+	defer central.Close()
+	remote, err := OpenStore(central.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := remote.Samples(StoreQuery{TrackIDs: []string{"a"}}); err != nil || len(got) != 1 {
+		t.Fatalf("remote samples %+v, %v", got, err)
+	}
+	if first, _, err := remote.Span(); err != nil || !first.Equal(now) {
+		t.Fatalf("remote span starts %v, %v", first, err)
+	}
+	if remote.Append(nil, nil) == nil {
+		t.Fatal("remote store accepted writes")
+	}
+}
@@ -0,0 +1,104 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// SweepDetection is a signal found by a frequency sweep.
+type SweepDetection struct {
+	FrequencyHz float64 `json:"frequencyHz"`
+	PowerDBFS   float64 `json:"powerDbfs"`
+	SNRDB       float64 `json:"snrDb"`
+}
+
+// SweepStep is the result of one RX LO step of a frequency sweep. Detections
+// are limited to the part of the capture the step covers, so overlapping
+// captures do not report a signal twice.
+type SweepStep struct {
+	CenterHz       float64          `json:"centerHz"`
+	PeakHz         float64          `json:"peakHz"`
+	PeakDBFS       float64          `json:"peakDbfs"`
+	NoiseFloorDBFS float64          `json:"noiseFloorDbfs"`
+	Detections     []SweepDetection `json:"detections,omitempty"`
+}
+
+// Sweep is the latest frequency sweep of one device, as returned by
+// /api/spectrum/sweep. It is reported after every step; Complete marks the
+// last one of a pass.
+type Sweep struct {
+	Device     string           `json:"device,omitempty"`
+	Timestamp  time.Time        `json:"timestamp"`
+	StartHz    float64          `json:"startHz"`
+	StopHz     float64          `json:"stopHz"`
+	StepHz     float64          `json:"stepHz"`
+	Pass       int              `json:"pass"`
+	Complete   bool             `json:"complete"`
+	Steps      []SweepStep      `json:"steps"`
+	Detections []SweepDetection `json:"detections"` // of all steps, strongest first
+}
+
+// ReportSweep stores the latest sweep of a single-device setup.
+func (h *Hub) ReportSweep(s Sweep) {
+	h.reportSweep("", s)
+}
+
+// ReportSweep stores the latest sweep of one device.
+func (d *deviceReporter) ReportSweep(s Sweep) {
+	d.hub.reportSweep(d.id, s)
+}
+
+// reportSweep stores s and logs the outcome of every completed pass in the
+// event log.
+func (h *Hub) reportSweep(device string, s Sweep) {
+	s.Device = device
+	s.Steps = append([]SweepStep(nil), s.Steps...)
+	s.Detections = append(make([]SweepDetection, 0, len(s.Detections)), s.Detections...)
+	prefix := ""
+	if device != "" {
+		prefix = device + ": "
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweeps[device] = s
+	switch {
+	case !s.Complete:
+	case len(s.Detections) == 0:
+		h.recordEventLocked("info", fmt.Sprintf("%ssweep %d: no signals from %.0f to %.0f Hz", prefix, s.Pass, s.StartHz, s.StopHz))
+	default:
+		h.recordEventLocked("info", fmt.Sprintf("%ssweep %d: %d detections, strongest %.1f dBFS at %.0f Hz", prefix, s.Pass, len(s.Detections), s.Detections[0].PowerDBFS, s.Detections[0].FrequencyHz))
+	}
+}
+
+// SweepSnapshots returns the latest sweep per device, sorted by device ID.
+func (h *Hub) SweepSnapshots() []Sweep {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]Sweep, 0, len(h.sweeps))
+	for _, s := range h.sweeps {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+// handleSweep returns the latest sweep of every device, or of the one
+// selected with ?device=.
+func (h *Hub) handleSweep(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	device := parseDevice(r)
+	out := make([]Sweep, 0)
+	for _, s := range h.SweepSnapshots() {
+		if device == "" || s.Device == device {
+			out = append(out, s)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
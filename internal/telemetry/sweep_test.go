@@ -0,0 +1,40 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSweepEndpoint(t *testing.T) {
+	hub := newTestHub()
+	north := hub.ForDevice("north", "mock").(*deviceReporter)
+	sweep := Sweep{StartHz: 2.3e9, StopHz: 2.5e9, StepHz: 1.6e6, Pass: 1, Steps: []SweepStep{{CenterHz: 2.3e9}}}
+	north.ReportSweep(sweep)
+	sweep.Complete = true
+	sweep.Detections = []SweepDetection{{FrequencyHz: 2.4002e9, PowerDBFS: -20, SNRDB: 60}}
+	north.ReportSweep(sweep)
+	hub.ReportSweep(Sweep{Pass: 3})
+
+	rr := httptest.NewRecorder()
+	hub.handleSweep(rr, httptest.NewRequest(http.MethodGet, "/api/spectrum/sweep?device=north", nil))
+	var got []Sweep
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Device != "north" || !got[0].Complete || got[0].Detections[0].FrequencyHz != 2.4002e9 {
+		t.Fatalf("unexpected sweeps %+v", got)
+	}
+
+	var logged []string
+	for _, ev := range hub.recentEvents() {
+		if strings.Contains(ev.Message, "sweep") {
+			logged = append(logged, ev.Message)
+		}
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "north: sweep 1: 1 detections") {
+		t.Fatalf("sweep events %q, want one for the completed pass", logged)
+	}
+}
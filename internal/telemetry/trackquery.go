@@ -0,0 +1,161 @@
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTrackQueryLimit caps the page size of a track query.
+const maxTrackQueryLimit = 1000
+
+// TrackQuery selects and orders the latest track snapshots. Zero values
+// disable the corresponding filter; a zero Limit returns at most
+// maxTrackQueryLimit snapshots.
+type TrackQuery struct {
+	IDs    []string
+	Device string
+	// State matches the track-manager state (tentative, confirmed, lost) or
+	// the lock state (searching, tracking, locked).
+	State string
+	// MinSNR drops tracks below this SNR when HasMinSNR is set, so that an
+	// explicit min_snr=0 still filters out negative readings.
+	MinSNR    float64
+	HasMinSNR bool
+	// Since drops tracks not updated after this time.
+	Since time.Time
+	// Sort is one of id (default), score, snr or updated. Score, SNR and
+	// updated sort in descending order.
+	Sort   string
+	Offset int
+	Limit  int
+}
+
+// QueryTracks returns the page of track snapshots matching q together with the
+// number of matches before pagination.
+func (h *Hub) QueryTracks(q TrackQuery) ([]TrackSnapshot, int) {
+	snapshots := h.trackSnapshots(trackFilterSet(q.IDs))
+	out := snapshots[:0]
+	for _, snap := range snapshots {
+		if q.matches(snap) {
+			out = append(out, snap)
+		}
+	}
+	sortTracks(out, q.Sort)
+
+	total := len(out)
+	start := min(q.Offset, total)
+	limit := q.Limit
+	if limit <= 0 || limit > maxTrackQueryLimit {
+		limit = maxTrackQueryLimit
+	}
+	end := min(start+limit, total)
+	return out[start:end], total
+}
+
+func (q TrackQuery) matches(snap TrackSnapshot) bool {
+	sample := snap.Sample
+	if q.Device != "" && sample.Device != q.Device {
+		return false
+	}
+	if q.State != "" && !strings.EqualFold(sample.State, q.State) && !strings.EqualFold(string(sample.LockState), q.State) {
+		return false
+	}
+	if q.HasMinSNR && sample.SNR < q.MinSNR {
+		return false
+	}
+	return q.Since.IsZero() || snap.LastUpdated.After(q.Since)
+}
+
+// trackScore returns the track-manager score, falling back to the tracking
+// confidence for producers that do not report one.
+func trackScore(sample TrackSample) float64 {
+	if sample.Score != 0 {
+		return sample.Score
+	}
+	return sample.Confidence
+}
+
+// sortTracks orders snapshots in place. Ties keep the ID order produced by
+// trackSnapshots.
+func sortTracks(snapshots []TrackSnapshot, by string) {
+	var less func(a, b TrackSnapshot) bool
+	switch by {
+	case "score":
+		less = func(a, b TrackSnapshot) bool { return trackScore(a.Sample) > trackScore(b.Sample) }
+	case "snr":
+		less = func(a, b TrackSnapshot) bool { return a.Sample.SNR > b.Sample.SNR }
+	case "updated":
+		less = func(a, b TrackSnapshot) bool { return a.LastUpdated.After(b.LastUpdated) }
+	default:
+		return
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return less(snapshots[i], snapshots[j]) })
+}
+
+// parseTrackQuery reads the /api/tracks query parameters. since accepts an
+// RFC 3339 timestamp or a duration relative to now (e.g. 30s).
+func parseTrackQuery(r *http.Request, now time.Time) (TrackQuery, error) {
+	values := r.URL.Query()
+	q := TrackQuery{
+		IDs:    parseTrackIDs(r),
+		Device: parseDevice(r),
+		State:  strings.TrimSpace(values.Get("state")),
+		Sort:   strings.TrimSpace(values.Get("sort")),
+	}
+	switch q.Sort {
+	case "", "id", "score", "snr", "updated":
+	default:
+		return q, fmt.Errorf("invalid sort %q", q.Sort)
+	}
+	if raw := values.Get("min_snr"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return q, fmt.Errorf("invalid min_snr %q", raw)
+		}
+		q.MinSNR, q.HasMinSNR = v, true
+	}
+	if raw := values.Get("since"); raw != "" {
+		since, err := parseSince(raw, now)
+		if err != nil {
+			return q, err
+		}
+		q.Since = since
+	}
+	var err error
+	if q.Offset, err = parseNonNegative(values.Get("offset"), "offset"); err != nil {
+		return q, err
+	}
+	if q.Limit, err = parseNonNegative(values.Get("limit"), "limit"); err != nil {
+		return q, err
+	}
+	if q.Limit == 0 || q.Limit > maxTrackQueryLimit {
+		q.Limit = maxTrackQueryLimit
+	}
+	return q, nil
+}
+
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	if ts, err := time.Parse(time.RFC3339, raw); err == nil {
+		return ts, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid since %q: want RFC 3339 time or duration", raw)
+	}
+	return now.Add(-d), nil
+}
+
+func parseNonNegative(raw, name string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return v, nil
+}
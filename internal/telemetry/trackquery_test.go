@@ -0,0 +1,144 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func seedQueryTracks(hub *Hub, now time.Time) {
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: now.Add(-time.Minute), Tracks: []TrackSample{
+		{ID: "old", SNR: 30, State: "confirmed", Score: 0.9, LockState: LockStateLocked},
+	}})
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: now, Tracks: []TrackSample{
+		{ID: "a", SNR: 5, State: "tentative", Score: 0.2, LockState: LockStateSearching},
+		{ID: "b", SNR: 20, State: "confirmed", Score: 0.7, LockState: LockStateTracking},
+		{ID: "c", SNR: 12, State: "confirmed", Score: 0.8, LockState: LockStateLocked},
+	}})
+}
+
+func snapshotIDs(snapshots []TrackSnapshot) []string {
+	ids := make([]string, len(snapshots))
+	for i, snap := range snapshots {
+		ids[i] = snap.ID
+	}
+	return ids
+}
+
+func TestQueryTracks(t *testing.T) {
+	hub := newTestHub()
+	now := time.Now()
+	seedQueryTracks(hub, now)
+
+	tests := []struct {
+		name      string
+		query     TrackQuery
+		want      []string
+		wantTotal int
+	}{
+		{name: "all", query: TrackQuery{}, want: []string{"a", "b", "c", "old"}, wantTotal: 4},
+		{name: "confirmed by score", query: TrackQuery{State: "confirmed", Sort: "score"}, want: []string{"old", "c", "b"}, wantTotal: 3},
+		{name: "lock state", query: TrackQuery{State: "locked"}, want: []string{"c", "old"}, wantTotal: 2},
+		{name: "min snr", query: TrackQuery{MinSNR: 10, HasMinSNR: true, Sort: "snr"}, want: []string{"old", "b", "c"}, wantTotal: 3},
+		{name: "since", query: TrackQuery{Since: now.Add(-time.Second)}, want: []string{"a", "b", "c"}, wantTotal: 3},
+		{name: "page", query: TrackQuery{Sort: "score", Offset: 1, Limit: 2}, want: []string{"c", "b"}, wantTotal: 4},
+		{name: "offset past end", query: TrackQuery{Offset: 10}, want: []string{}, wantTotal: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := hub.QueryTracks(tt.query)
+			ids := snapshotIDs(got)
+			if total != tt.wantTotal || len(ids) != len(tt.want) {
+				t.Fatalf("got %v (total %d), want %v (total %d)", ids, total, tt.want, tt.wantTotal)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryTracksMinSNRZeroAndDefaultLimit(t *testing.T) {
+	hub := newTestHub()
+	now := time.Now()
+	tracks := make([]TrackSample, maxTrackQueryLimit+5)
+	for i := range tracks {
+		tracks[i] = TrackSample{ID: fmt.Sprintf("t%04d", i), SNR: 1}
+	}
+	tracks[0].SNR = -3
+	hub.ReportMultiTrack(MultiTrackSample{Timestamp: now, Tracks: tracks})
+
+	for _, limit := range []int{0, maxTrackQueryLimit + 1} {
+		got, total := hub.QueryTracks(TrackQuery{Limit: limit})
+		if total != len(tracks) || len(got) != maxTrackQueryLimit {
+			t.Fatalf("limit %d: got %d of %d, want %d", limit, len(got), total, maxTrackQueryLimit)
+		}
+	}
+	if _, total := hub.QueryTracks(TrackQuery{HasMinSNR: true}); total != len(tracks)-1 {
+		t.Fatalf("min_snr=0 kept %d tracks, want %d", total, len(tracks)-1)
+	}
+}
+
+func TestParseTrackQuery(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		url     string
+		wantErr bool
+		check   func(TrackQuery) bool
+	}{
+		{url: "/api/tracks?state=confirmed&min_snr=6.5&sort=score&limit=10&offset=5", check: func(q TrackQuery) bool {
+			return q.State == "confirmed" && q.HasMinSNR && q.MinSNR == 6.5 && q.Sort == "score" && q.Limit == 10 && q.Offset == 5
+		}},
+		{url: "/api/tracks?since=30s", check: func(q TrackQuery) bool { return q.Since.Equal(now.Add(-30 * time.Second)) }},
+		{url: "/api/tracks?since=2024-01-01T11:00:00Z", check: func(q TrackQuery) bool { return q.Since.Equal(now.Add(-time.Hour)) }},
+		{url: "/api/tracks?limit=99999", check: func(q TrackQuery) bool { return q.Limit == maxTrackQueryLimit }},
+		{url: "/api/tracks?limit=0", check: func(q TrackQuery) bool { return q.Limit == maxTrackQueryLimit }},
+		{url: "/api/tracks", check: func(q TrackQuery) bool { return q.Limit == maxTrackQueryLimit && !q.HasMinSNR }},
+		{url: "/api/tracks?min_snr=0", check: func(q TrackQuery) bool { return q.HasMinSNR && q.MinSNR == 0 }},
+		{url: "/api/tracks?sort=bogus", wantErr: true},
+		{url: "/api/tracks?min_snr=x", wantErr: true},
+		{url: "/api/tracks?since=yesterday", wantErr: true},
+		{url: "/api/tracks?limit=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		q, err := parseTrackQuery(httptest.NewRequest(http.MethodGet, tt.url, nil), now)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+		if err == nil && !tt.check(q) {
+			t.Fatalf("%s: unexpected query %+v", tt.url, q)
+		}
+	}
+}
+
+func TestHandleTracksQuery(t *testing.T) {
+	hub := newTestHub()
+	seedQueryTracks(hub, time.Now())
+
+	rr := httptest.NewRecorder()
+	hub.handleTracks(rr, httptest.NewRequest(http.MethodGet, "/api/tracks?state=confirmed&sort=score&limit=1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d", rr.Code)
+	}
+	if got := rr.Header().Get("X-Total-Count"); got != "3" {
+		t.Fatalf("X-Total-Count = %q, want 3", got)
+	}
+	var out []TrackSnapshot
+	if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != 1 || out[0].ID != "old" {
+		t.Fatalf("unexpected page %+v", out)
+	}
+
+	rr = httptest.NewRecorder()
+	hub.handleTracks(rr, httptest.NewRequest(http.MethodGet, "/api/tracks?min_snr=abc", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rr.Code)
+	}
+}
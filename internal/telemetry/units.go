@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Angle and power display units accepted in Config.
+const (
+	AngleUnitDegrees = "deg"
+	AngleUnitRadians = "rad"
+	// AngleUnitMils selects NATO mils (6400 per full circle).
+	AngleUnitMils = "mil"
+
+	PowerUnitDBFS = "dBFS"
+	// PowerUnitDBm applies Config.PowerOffsetDB to dBFS readings.
+	PowerUnitDBm = "dBm"
+)
+
+// DisplayValues carries a track's bearing (in the configured frame) and power
+// converted to the configured display units. The raw angleDeg and peak fields
+// are always kept alongside.
+type DisplayValues struct {
+	Angle     float64 `json:"angle"`
+	AngleUnit string  `json:"angleUnit"`
+	Power     float64 `json:"power"`
+	PowerUnit string  `json:"powerUnit"`
+}
+
+// ParseAngleUnit returns the canonical angle unit for s; empty selects degrees.
+func ParseAngleUnit(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "deg", "degrees":
+		return AngleUnitDegrees, nil
+	case "rad", "radians":
+		return AngleUnitRadians, nil
+	case "mil", "mils":
+		return AngleUnitMils, nil
+	}
+	return "", fmt.Errorf("angle unit must be deg, rad or mil, got %q", s)
+}
+
+// ParsePowerUnit returns the canonical power unit for s; empty selects dBFS.
+func ParsePowerUnit(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "dbfs":
+		return PowerUnitDBFS, nil
+	case "dbm":
+		return PowerUnitDBm, nil
+	}
+	return "", fmt.Errorf("power unit must be dBFS or dBm, got %q", s)
+}
+
+// normalizeUnits canonicalizes the display units of cfg.
+func normalizeUnits(cfg Config) (Config, error) {
+	var err error
+	if cfg.AngleUnit, err = ParseAngleUnit(cfg.AngleUnit); err != nil {
+		return cfg, err
+	}
+	if cfg.PowerUnit, err = ParsePowerUnit(cfg.PowerUnit); err != nil {
+		return cfg, err
+	}
+	if math.IsNaN(cfg.PowerOffsetDB) || math.Abs(cfg.PowerOffsetDB) > 200 {
+		return cfg, errors.New("power offset must be within ±200 dB")
+	}
+	return cfg, nil
+}
+
+// ConvertAngle converts deg to unit.
+func ConvertAngle(deg float64, unit string) float64 {
+	switch unit {
+	case AngleUnitRadians:
+		return deg * math.Pi / 180
+	case AngleUnitMils:
+		return deg * 6400 / 360
+	default:
+		return deg
+	}
+}
+
+// ConvertPower converts a dBFS reading to unit, adding offsetDB for dBm.
+func ConvertPower(dbfs float64, unit string, offsetDB float64) float64 {
+	if unit == PowerUnitDBm {
+		return dbfs + offsetDB
+	}
+	return dbfs
+}
+
+// displayValues converts track to the units configured in cfg.
+func displayValues(track TrackSample, cfg Config) *DisplayValues {
+	return &DisplayValues{
+		Angle:     ConvertAngle(track.BearingDeg, cfg.AngleUnit),
+		AngleUnit: cfg.AngleUnit,
+		Power:     ConvertPower(track.Peak, cfg.PowerUnit, cfg.PowerOffsetDB),
+		PowerUnit: cfg.PowerUnit,
+	}
+}
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertUnits(t *testing.T) {
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "degrees", got: ConvertAngle(45, AngleUnitDegrees), want: 45},
+		{name: "radians", got: ConvertAngle(90, AngleUnitRadians), want: math.Pi / 2},
+		{name: "mils", got: ConvertAngle(90, AngleUnitMils), want: 1600},
+		{name: "dBFS", got: ConvertPower(-20, PowerUnitDBFS, -30), want: -20},
+		{name: "dBm", got: ConvertPower(-20, PowerUnitDBm, -30), want: -50},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Fatalf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		in      string
+		parse   func(string) (string, error)
+		want    string
+		wantErr bool
+	}{
+		{in: "", parse: ParseAngleUnit, want: AngleUnitDegrees},
+		{in: "Mils", parse: ParseAngleUnit, want: AngleUnitMils},
+		{in: "radians", parse: ParseAngleUnit, want: AngleUnitRadians},
+		{in: "grad", parse: ParseAngleUnit, wantErr: true},
+		{in: "DBM", parse: ParsePowerUnit, want: PowerUnitDBm},
+		{in: "", parse: ParsePowerUnit, want: PowerUnitDBFS},
+		{in: "watts", parse: ParsePowerUnit, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.parse(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("parse(%q) = %q, %v; want %q (err %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateConfigUnits(t *testing.T) {
+	base := defaultConfig()
+	base.AngleUnit = AngleUnitMils
+
+	cfg, err := validateConfig(Config{PowerUnit: "dbm", PowerOffsetDB: -42}, base)
+	if err != nil {
+		t.Fatalf("validateConfig: %v", err)
+	}
+	if cfg.AngleUnit != AngleUnitMils || cfg.PowerUnit != PowerUnitDBm || cfg.PowerOffsetDB != -42 {
+		t.Fatalf("unexpected units %q %q %v", cfg.AngleUnit, cfg.PowerUnit, cfg.PowerOffsetDB)
+	}
+	if _, err := validateConfig(Config{AngleUnit: "grad"}, base); err == nil {
+		t.Fatal("expected error for unknown angle unit")
+	}
+	if _, err := validateConfig(Config{PowerOffsetDB: 500}, base); err == nil {
+		t.Fatal("expected error for out-of-range power offset")
+	}
+}
+
+func TestReportAddsDisplayValues(t *testing.T) {
+	hub := newTestHub()
+	hub.mu.Lock()
+	hub.config.AngleUnit = AngleUnitMils
+	hub.config.PowerUnit = PowerUnitDBm
+	hub.config.PowerOffsetDB = -30
+	hub.mu.Unlock()
+
+	hub.Report(45, -20, 15, 0.9, LockStateLocked, nil)
+	history := hub.History()
+	if len(history) != 1 {
+		t.Fatalf("expected one sample, got %d", len(history))
+	}
+	display := history[0].Tracks[0].Display
+	if display == nil || display.Angle != 800 || display.AngleUnit != "mil" || display.Power != -50 || display.PowerUnit != "dBm" {
+		t.Fatalf("unexpected display values %+v", display)
+	}
+	if history[0].Tracks[0].AngleDeg != 45 {
+		t.Fatalf("raw angle must be preserved, got %v", history[0].Tracks[0].AngleDeg)
+	}
+}
@@ -0,0 +1,188 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+)
+
+// hotConfigKeys are the Config fields the running process applies at once.
+// Everything else is stored for the next start, as with the settings page.
+var hotConfigKeys = map[string]bool{
+	"historyLimit":  true,
+	"angleUnit":     true,
+	"powerUnit":     true,
+	"powerOffsetDb": true,
+}
+
+// configWatch tracks the last config file state seen by WatchConfig.
+type configWatch struct {
+	modTime time.Time
+	size    int64
+	// updatedAt is when the settings page last changed the config. File
+	// edits older than that lose the conflict.
+	updatedAt time.Time
+}
+
+// configSettle is how long WatchConfig waits after the last change
+// notification before reading the file, so an editor's write, rename and
+// chmod are applied once.
+const configSettle = 100 * time.Millisecond
+
+// WatchConfig applies external edits of the config file (by hand or
+// configuration management) after validating them, until ctx is done. When
+// both the file and the settings page changed the config, the newer change
+// wins. It watches the file's directory with fsnotify, which also sees
+// editors that replace the file by renaming a new one over it; where the
+// platform offers no notifications it polls the file every interval
+// instead. A non-positive interval disables watching.
+func (h *Hub) WatchConfig(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	h.checkConfigFile()
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = watcher.Add(filepath.Dir(configFilePath)); err != nil {
+			_ = watcher.Close()
+		}
+	}
+	if err != nil {
+		h.logger.Warn("config file notifications unavailable, polling", logging.Field{Key: "error", Value: err}, logging.Field{Key: "interval", Value: interval.String()})
+		h.pollConfigFile(ctx, interval)
+		return
+	}
+	defer watcher.Close()
+
+	name := filepath.Base(configFilePath)
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(ev.Name) == name {
+				settle = time.After(configSettle)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			h.logger.Warn("config file watch", logging.Field{Key: "error", Value: err})
+		case <-settle:
+			settle = nil
+			h.checkConfigFile()
+		}
+	}
+}
+
+// pollConfigFile checks the config file every interval until ctx is done.
+func (h *Hub) pollConfigFile(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkConfigFile()
+		}
+	}
+}
+
+// checkConfigFile applies the config file if it changed since the last call.
+func (h *Hub) checkConfigFile() {
+	info, err := os.Stat(configFilePath)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	seen := h.watch
+	changed := !info.ModTime().Equal(seen.modTime) || info.Size() != seen.size
+	h.watch.modTime, h.watch.size = info.ModTime(), info.Size()
+	h.mu.Unlock()
+	if !changed || seen.modTime.IsZero() {
+		// The first call only records the file state loaded by NewHub.
+		return
+	}
+	if info.ModTime().Before(seen.updatedAt) {
+		h.recordEvent("warn", "config file edit ignored: settings page change is newer")
+		return
+	}
+
+	stored, err := loadPersistentConfig(configFilePath)
+	if err != nil {
+		h.recordEvent("warn", "config file edit rejected: "+err.Error())
+		return
+	}
+	h.configMu.Lock()
+	defer h.configMu.Unlock()
+	current := h.ConfigSnapshot()
+	fileCfg := configFromPersistent(stored)
+	if stored.PhaseDelta == current.MockPhaseDelta {
+		// phase_delta is saved from the mock delta only; keep the tracker's
+		// delta unless the key was edited.
+		fileCfg.PhaseDeltaDeg = current.PhaseDeltaDeg
+	}
+	cfg, err := validateConfig(fileCfg, current)
+	if err != nil {
+		h.recordEvent("warn", "config file edit rejected: "+err.Error())
+		return
+	}
+	if reflect.DeepEqual(cfg, current) {
+		// Our own write, or an edit to keys this hub does not model.
+		return
+	}
+
+	h.mu.Lock()
+	h.applyConfig(cfg)
+	h.mu.Unlock()
+	h.recordConfigAudit(nil, current, cfg)
+	if pending := restartConfigKeys(current, cfg); len(pending) > 0 {
+		h.recordEvent("info", "config file changes take effect after restart: "+strings.Join(pending, ", "))
+	}
+	h.logger.Info("applied config file edit", logging.Field{Key: "path", Value: configFilePath})
+}
+
+// recordConfigWrite notes the config file state after the hub wrote it, so
+// checkConfigFile does not take the hub's own write for an external edit.
+func (h *Hub) recordConfigWrite() {
+	info, err := os.Stat(configFilePath)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	h.watch.modTime, h.watch.size = info.ModTime(), info.Size()
+	h.mu.Unlock()
+}
+
+// markConfigUpdated records a settings page change for conflict resolution.
+func (h *Hub) markConfigUpdated(at time.Time) {
+	h.mu.Lock()
+	h.watch.updatedAt = at
+	h.mu.Unlock()
+}
+
+// restartConfigKeys lists the changed fields that are not hot-applied.
+func restartConfigKeys(before, after Config) []string {
+	oldFields, newFields := configFields(before), configFields(after)
+	var keys []string
+	for key, value := range newFields {
+		if !hotConfigKeys[key] && !reflect.DeepEqual(oldFields[key], value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
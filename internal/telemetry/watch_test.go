@@ -0,0 +1,142 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// editConfigFile rewrites one key of the config file and moves its
+// modification time to at.
+func editConfigFile(t *testing.T, key string, value any, at time.Time) {
+	t.Helper()
+	if err := persistConfigValue(configFilePath, key, value); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.Chtimes(configFilePath, at, at); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+func TestCheckConfigFileAppliesExternalEdits(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hub := newTestHub()
+	if err := hub.persistConfig(hub.ConfigSnapshot()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	hub.checkConfigFile()
+
+	editConfigFile(t, "history_limit", 42, time.Now().Add(time.Second))
+	editConfigFile(t, "rx_lo", 2.4e9, time.Now().Add(time.Second))
+	hub.checkConfigFile()
+
+	cfg := hub.ConfigSnapshot()
+	if cfg.HistoryLimit != 42 || cfg.RxLoHz != 2.4e9 {
+		t.Fatalf("edit not applied: %+v", cfg)
+	}
+	var restart bool
+	for _, ev := range hub.recentEvents() {
+		if strings.Contains(ev.Message, "after restart: rxLoHz") {
+			restart = true
+		}
+	}
+	if !restart {
+		t.Fatalf("expected restart notice, got %+v", hub.recentEvents())
+	}
+	if entries := hub.AuditLog(); len(entries) != 2 || entries[0].Source != "local" {
+		t.Fatalf("expected two local audit entries, got %+v", entries)
+	}
+}
+
+func TestCheckConfigFileConflictsAndValidation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hub := newTestHub()
+	if err := hub.persistConfig(hub.ConfigSnapshot()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	hub.checkConfigFile()
+	original := hub.ConfigSnapshot()
+
+	hub.markConfigUpdated(time.Now().Add(time.Hour))
+	editConfigFile(t, "history_limit", 42, time.Now().Add(time.Minute))
+	hub.checkConfigFile()
+	if got := hub.ConfigSnapshot(); got.HistoryLimit != original.HistoryLimit {
+		t.Fatalf("older file edit must lose against the settings page, got %d", got.HistoryLimit)
+	}
+
+	editConfigFile(t, "num_samples", 1000, time.Now().Add(2*time.Hour))
+	hub.checkConfigFile()
+	if got := hub.ConfigSnapshot(); got.NumSamples != original.NumSamples {
+		t.Fatalf("invalid edit must be rejected, got %d", got.NumSamples)
+	}
+	var rejected bool
+	for _, ev := range hub.recentEvents() {
+		if strings.HasPrefix(ev.Message, "config file edit rejected") {
+			rejected = true
+		}
+	}
+	if !rejected {
+		t.Fatal("expected rejection event")
+	}
+}
+
+func TestCheckConfigFileIgnoresSettingsPageSave(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hub := newTestHub()
+	if err := hub.persistConfig(hub.ConfigSnapshot()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	// Backdate the file so the save below changes its modification time
+	// even on filesystems with coarse timestamps.
+	past := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(configFilePath, past, past); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	hub.checkConfigFile()
+
+	rr := postConfig(t, hub, map[string]any{"revision": hub.ConfigDocument().Revision, "historyLimit": 42})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", rr.Code, rr.Body.String())
+	}
+	hub.checkConfigFile()
+	for _, ev := range hub.recentEvents() {
+		if strings.HasPrefix(ev.Message, "config file edit") {
+			t.Fatalf("the hub's own write was taken for an edit: %q", ev.Message)
+		}
+	}
+	if got := hub.ConfigSnapshot(); got.HistoryLimit != 42 {
+		t.Fatalf("history limit %d after the save, want 42", got.HistoryLimit)
+	}
+}
+
+func TestWatchConfigNotices(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hub := newTestHub()
+	if err := hub.persistConfig(hub.ConfigSnapshot()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.WatchConfig(ctx, time.Hour)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// The hour-long poll interval only applies without notifications, so
+	// the edit must be seen well before it.
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.ConfigSnapshot().HistoryLimit != 42 {
+		if time.Now().After(deadline) {
+			t.Fatal("config file edit not applied")
+		}
+		editConfigFile(t, "history_limit", 42, time.Now().Add(time.Second))
+		time.Sleep(5 * configSettle)
+	}
+}
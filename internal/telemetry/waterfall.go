@@ -0,0 +1,250 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// waterfallRows bounds the rows kept per device: one minute at the
+	// default row rate.
+	waterfallRows = 300
+	// waterfallFloorDB replaces empty bins, which JSON cannot carry as -Inf.
+	waterfallFloorDB = -200
+	maxWaterfallRate = 50
+	minWaterfallBins = 16
+	maxWaterfallBins = 4096
+)
+
+// WaterfallConfig bounds the bandwidth of the waterfall: at most RowRateHz
+// rows per second and device are kept and streamed, each decimated to at
+// most Bins bins. A zero RowRateHz disables the waterfall.
+type WaterfallConfig struct {
+	RowRateHz float64 `json:"rowRateHz"`
+	Bins      int     `json:"bins"`
+}
+
+// DefaultWaterfallConfig returns the waterfall settings of a new Hub.
+func DefaultWaterfallConfig() WaterfallConfig {
+	return WaterfallConfig{RowRateHz: 5, Bins: 512}
+}
+
+// Validate checks the row rate and bin count ranges.
+func (c WaterfallConfig) Validate() error {
+	if c.RowRateHz < 0 || c.RowRateHz > maxWaterfallRate {
+		return fmt.Errorf("waterfall row rate must be within 0-%d Hz", maxWaterfallRate)
+	}
+	if c.Bins < minWaterfallBins || c.Bins > maxWaterfallBins {
+		return fmt.Errorf("waterfall bins must be within %d-%d", minWaterfallBins, maxWaterfallBins)
+	}
+	return nil
+}
+
+// WaterfallRow is one spectrum line of the waterfall, as returned by
+// /api/waterfall. Bins are dBFS from -SpanHz/2 to +SpanHz/2 around CenterHz,
+// rounded to 0.1 dB.
+type WaterfallRow struct {
+	Device    string    `json:"device,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	CenterHz  float64   `json:"centerHz"`
+	SpanHz    float64   `json:"spanHz"`
+	Bins      []float64 `json:"bins"`
+}
+
+// SetWaterfall replaces the waterfall settings. Rows already kept are not
+// resampled.
+func (h *Hub) SetWaterfall(cfg WaterfallConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.waterfallCfg = cfg
+	h.mu.Unlock()
+	return nil
+}
+
+// WaterfallEnabled reports whether rows are kept, so the tracker can skip
+// the FFT when nobody looks.
+func (h *Hub) WaterfallEnabled() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.waterfallCfg.RowRateHz > 0
+}
+
+// WaterfallEnabled reports whether the hub keeps waterfall rows.
+func (d *deviceReporter) WaterfallEnabled() bool {
+	return d.hub.WaterfallEnabled()
+}
+
+// ReportWaterfall adds a spectrum row of a single-device setup.
+func (h *Hub) ReportWaterfall(row WaterfallRow) {
+	h.reportWaterfall("", row)
+}
+
+// ReportWaterfall adds a spectrum row of one device.
+func (d *deviceReporter) ReportWaterfall(row WaterfallRow) {
+	d.hub.reportWaterfall(d.id, row)
+}
+
+// reportWaterfall keeps row when the row rate allows it, decimated to the
+// configured bins, and sends it to the stream subscribers. Rows arriving
+// faster than the row rate are dropped, so the tracker may report every
+// iteration. Slow subscribers miss rows rather than block the tracker.
+func (h *Hub) reportWaterfall(device string, row WaterfallRow) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cfg := h.waterfallCfg
+	if cfg.RowRateHz <= 0 {
+		return
+	}
+	rows := h.waterfall[device]
+	if n := len(rows); n > 0 {
+		interval := time.Duration(float64(time.Second) / cfg.RowRateHz)
+		if row.Timestamp.Sub(rows[n-1].Timestamp) < interval {
+			return
+		}
+	}
+	row.Device = device
+	row.Bins = decimateBins(row.Bins, cfg.Bins)
+	rows = append(rows, row)
+	if len(rows) > waterfallRows {
+		rows = rows[len(rows)-waterfallRows:]
+	}
+	h.waterfall[device] = rows
+	for ch := range h.waterfallSubs {
+		select {
+		case ch <- row:
+		default:
+		}
+	}
+}
+
+// decimateBins reduces bins to at most n by keeping the maximum of each group
+// of adjacent bins, so narrow carriers survive the reduction, and rounds the
+// result to 0.1 dB. It always returns a new slice.
+func decimateBins(bins []float64, n int) []float64 {
+	factor := (len(bins) + n - 1) / max(n, 1)
+	factor = max(factor, 1)
+	out := make([]float64, 0, (len(bins)+factor-1)/factor)
+	for i := 0; i < len(bins); i += factor {
+		peak := math.Inf(-1)
+		for _, v := range bins[i:min(i+factor, len(bins))] {
+			peak = math.Max(peak, v)
+		}
+		if math.IsInf(peak, 0) || math.IsNaN(peak) || peak < waterfallFloorDB {
+			peak = waterfallFloorDB
+		}
+		out = append(out, math.Round(peak*10)/10)
+	}
+	return out
+}
+
+// WaterfallSnapshots returns the waterfall of every device, or of the one
+// named, limited to the latest limit rows when limit is positive, oldest
+// first and sorted by device ID.
+func (h *Hub) WaterfallSnapshots(device string, limit int) [][]WaterfallRow {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]string, 0, len(h.waterfall))
+	for id := range h.waterfall {
+		if device == "" || id == device {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	out := make([][]WaterfallRow, 0, len(ids))
+	for _, id := range ids {
+		rows := h.waterfall[id]
+		if limit > 0 && len(rows) > limit {
+			rows = rows[len(rows)-limit:]
+		}
+		out = append(out, append([]WaterfallRow(nil), rows...))
+	}
+	return out
+}
+
+// subscribeWaterfall registers a listener for new waterfall rows.
+func (h *Hub) subscribeWaterfall() (chan WaterfallRow, func()) {
+	ch := make(chan WaterfallRow, 16)
+	h.mu.Lock()
+	h.waterfallSubs[ch] = struct{}{}
+	h.mu.Unlock()
+	cancel := func() {
+		h.mu.Lock()
+		delete(h.waterfallSubs, ch)
+		close(ch)
+		h.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// handleWaterfall returns the kept waterfall rows of every device, or of the
+// one selected with ?device=, as a flat list ordered by device and time.
+// ?rows= limits the rows per device.
+func (h *Hub) handleWaterfall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	limit := 0
+	if raw := r.URL.Query().Get("rows"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeJSONError(w, http.StatusBadRequest, "rows must be a non-negative integer")
+			return
+		}
+		limit = n
+	}
+	out := make([]WaterfallRow, 0)
+	for _, rows := range h.WaterfallSnapshots(parseDevice(r), limit) {
+		out = append(out, rows...)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// handleWaterfallStream streams new waterfall rows as server-sent events,
+// one event per row.
+func (h *Hub) handleWaterfallStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	device := parseDevice(r)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch, cancel := h.subscribeWaterfall()
+	defer cancel()
+	flusher.Flush()
+
+	for {
+		select {
+		case row, ok := <-ch:
+			if !ok {
+				return
+			}
+			if device != "" && row.Device != device {
+				continue
+			}
+			payload, _ := json.Marshal(row)
+			w.Write([]byte("data: "))
+			w.Write(payload)
+			w.Write([]byte("\n\n"))
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
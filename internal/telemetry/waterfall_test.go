@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaterfallRateAndDecimation(t *testing.T) {
+	hub := newTestHub()
+	if err := hub.SetWaterfall(WaterfallConfig{RowRateHz: 10, Bins: 16}); err != nil {
+		t.Fatal(err)
+	}
+	north := hub.ForDevice("north", "mock").(*deviceReporter)
+
+	bins := make([]float64, 64)
+	for i := range bins {
+		bins[i] = -90
+	}
+	bins[5] = -31.26
+	bins[63] = math.Inf(-1)
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 20; i++ {
+		// Every 50 ms: only every second row fits the 10 Hz row rate.
+		north.ReportWaterfall(WaterfallRow{Timestamp: start.Add(time.Duration(i) * 50 * time.Millisecond), CenterHz: 2.4e9, SpanHz: 2e6, Bins: bins})
+	}
+
+	snaps := hub.WaterfallSnapshots("north", 0)
+	if len(snaps) != 1 || len(snaps[0]) != 10 {
+		t.Fatalf("kept %v rows, want 10", snaps)
+	}
+	row := snaps[0][0]
+	if row.Device != "north" || len(row.Bins) != 16 {
+		t.Fatalf("row = %+v", row)
+	}
+	if row.Bins[1] != -31.3 || row.Bins[0] != -90 {
+		t.Fatalf("max-hold decimation lost the carrier: %v", row.Bins)
+	}
+	if bins[5] != -31.26 {
+		t.Fatal("decimation modified the reported bins")
+	}
+	if got := hub.WaterfallSnapshots("north", 3); len(got[0]) != 3 || !got[0][2].Timestamp.Equal(snaps[0][9].Timestamp) {
+		t.Fatalf("limit did not keep the latest rows: %v", got)
+	}
+
+	if err := hub.SetWaterfall(WaterfallConfig{RowRateHz: 0, Bins: 16}); err != nil {
+		t.Fatal(err)
+	}
+	if hub.WaterfallEnabled() {
+		t.Fatal("waterfall enabled at 0 Hz")
+	}
+	if err := hub.SetWaterfall(WaterfallConfig{RowRateHz: 5, Bins: 4}); err == nil {
+		t.Fatal("4 bins accepted")
+	}
+}
+
+func TestDecimateBinsFloor(t *testing.T) {
+	got := decimateBins([]float64{math.Inf(-1), math.NaN(), -250, -80.04}, 8)
+	want := []float64{-200, -200, -200, -80}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("decimateBins = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWaterfallEndpoint(t *testing.T) {
+	hub := newTestHub()
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		hub.ForDevice("north", "mock").(*deviceReporter).ReportWaterfall(WaterfallRow{Timestamp: now.Add(time.Duration(i) * time.Second), Bins: []float64{-80, -70}})
+	}
+	hub.ReportWaterfall(WaterfallRow{Timestamp: now, Bins: []float64{-60}})
+
+	rr := httptest.NewRecorder()
+	hub.handleWaterfall(rr, httptest.NewRequest(http.MethodGet, "/api/waterfall?device=north&rows=2", nil))
+	var got []WaterfallRow
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Device != "north" || got[1].Bins[1] != -70 {
+		t.Fatalf("unexpected rows %+v", got)
+	}
+
+	rr = httptest.NewRecorder()
+	hub.handleWaterfall(rr, httptest.NewRequest(http.MethodGet, "/api/waterfall?rows=-1", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status %d for negative rows", rr.Code)
+	}
+}
@@ -2,13 +2,20 @@ package telemetry
 
 import (
 	"context"
+	"crypto/subtle"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
 )
 
 //go:embed static/*
@@ -20,49 +27,126 @@ type SDRBackend interface {
 	GetPhaseDelta() float64
 }
 
+// capabilityReporter is implemented by backends that can describe their limits.
+type capabilityReporter interface {
+	Capabilities() sdr.Capabilities
+}
+
 // WebServer exposes telemetry history and live updates over HTTP.
 type WebServer struct {
 	srv     *http.Server
 	hub     *Hub
 	backend SDRBackend
+	devices map[string]SDRBackend
+	macros  sdr.Macros
+	admin   string
 	log     logging.Logger
+	assets  fs.FS
+	routes  []route
+	auth    func(http.Handler) http.Handler
+	outer   func(http.Handler) http.Handler
+	pairing *Pairing
 }
 
-// NewWebServer builds an HTTP server serving the embedded UI, history and live endpoints.
-func NewWebServer(addr string, hub *Hub, backend SDRBackend, logger logging.Logger) *WebServer {
+// NewWebServer builds an HTTP server serving the UI, history and live
+// endpoints. backend may be nil when the hub is fed from elsewhere, as in
+// cmd/telemetryd; the SDR endpoints then answer 503.
+func NewWebServer(addr string, hub *Hub, backend SDRBackend, logger logging.Logger, opts ...Option) *WebServer {
 	if logger == nil {
 		logger = logging.Default()
 	}
+	assets, _ := fs.Sub(staticFiles, "static")
 	ws := &WebServer{
 		hub:     hub,
 		backend: backend,
+		devices: make(map[string]SDRBackend),
 		log:     logger.With(logging.Field{Key: "subsystem", Value: "telemetry"}),
+		assets:  assets,
+	}
+	for _, opt := range opts {
+		opt(ws)
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/static/", http.FileServer(http.FS(staticFiles)))
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(ws.assets))))
 	mux.HandleFunc("/api/history", hub.handleHistory)
+	mux.HandleFunc("/api/history/export", hub.handleHistoryExport)
+	mux.HandleFunc("/api/store/samples", hub.handleStoreSamples)
+	mux.HandleFunc("/api/store/events", hub.handleStoreEvents)
+	mux.HandleFunc("/api/store/span", hub.handleStoreSpan)
 	mux.HandleFunc("/api/live", hub.handleLive)
 	mux.HandleFunc("/api/tracks", hub.handleTracks)
 	mux.HandleFunc("/api/tracks/", hub.handleTrackHistory)
+	mux.HandleFunc("/api/tracks.geojson", hub.handleTracksGeoJSON)
 	mux.HandleFunc("/api/diagnostics", hub.handleDiagnostics)
 	mux.HandleFunc("/api/diagnostics/metrics", hub.handleMetricsStream)
 	mux.HandleFunc("/api/diagnostics/health", hub.handleHealth)
+	mux.HandleFunc("/api/events", hub.handleEvents)
+	mux.HandleFunc("/api/events/stream", hub.handleEventStream)
+	mux.HandleFunc("/api/version", hub.handleVersion)
 	mux.HandleFunc("/api/diagnostics/spectrum", hub.handleSpectrumSnapshot)
 	mux.HandleFunc("/api/config", hub.handleGetConfig)
 	mux.HandleFunc("/api/config/update", hub.handleSetConfig)
+	mux.HandleFunc("/api/audit", hub.handleAudit)
+	mux.HandleFunc("/api/frame", hub.handleFrame)
 	mux.HandleFunc("/api/mock/angle", ws.handleMockAngle)
+	mux.HandleFunc("/api/sdr/capabilities", ws.handleCapabilities)
+	mux.HandleFunc("/api/sdr/attrs", ws.handleAttrs)
+	mux.HandleFunc("/api/debug/inject", ws.handleInject)
+	mux.HandleFunc("/api/sdr/integrity", ws.handleIntegrity)
+	mux.HandleFunc("/api/sdr/loopback", ws.handleLoopback)
+	mux.HandleFunc("/api/sdr/bist", ws.handleBIST)
+	mux.HandleFunc("/api/sdr/record", ws.handleRecord)
+	mux.HandleFunc("/api/sdr/gain-schedule", ws.handleGainSchedule)
+	mux.HandleFunc("/api/sdr/state", hub.handleBackendState)
+	mux.HandleFunc("/api/spectrum/occupancy", hub.handleOccupancy)
+	mux.HandleFunc("/api/spectrum/sweep", hub.handleSweep)
+	mux.HandleFunc("/api/waterfall", hub.handleWaterfall)
+	mux.HandleFunc("/api/waterfall/stream", hub.handleWaterfallStream)
+	mux.HandleFunc("/metrics", hub.handlePrometheus)
+	mux.HandleFunc("/api/steering", hub.handleSteering)
+	mux.HandleFunc("/api/convergence", hub.handleConvergence)
+	mux.HandleFunc("/api/sensors", hub.handleSensors)
+	mux.HandleFunc("/api/steering/stream", hub.handleSteeringStream)
+	mux.HandleFunc("/api/sdr/macros", ws.handleMacros)
+	mux.HandleFunc("/api/iiod/exec", ws.handleIIODExec)
+	mux.HandleFunc("/api/devices", ws.handleDevices)
+	mux.HandleFunc("/api/devices/", ws.handleDeviceScoped)
 	mux.HandleFunc("/settings", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFileFS(w, r, staticFiles, "static/settings.html")
+		http.ServeFileFS(w, r, ws.assets, "settings.html")
 	})
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFileFS(w, r, staticFiles, "static/index.html")
+		http.ServeFileFS(w, r, ws.assets, "index.html")
 	})
+	for _, rt := range ws.routes {
+		if rt.admin {
+			rt.handler = ws.adminWrites(rt.handler)
+		}
+		mux.Handle(rt.pattern, rt.handler)
+	}
 
-	ws.srv = &http.Server{Addr: addr, Handler: mux}
+	var handler http.Handler = mux
+	if ws.pairing != nil {
+		ws.pairing.accept = ws.isAdminToken
+		handler = ws.pairing.Middleware(handler)
+	}
+	if ws.auth != nil {
+		handler = ws.auth(handler)
+	}
+	handler = compressHandler(handler)
+	if ws.outer != nil {
+		handler = ws.outer(handler)
+	}
+	ws.srv = &http.Server{Addr: addr, Handler: handler}
 	return ws
 }
 
+// Handler returns the server's complete handler, for mounting the telemetry
+// UI and API in another HTTP server instead of calling Start.
+func (w *WebServer) Handler() http.Handler {
+	return w.srv.Handler
+}
+
 func (w *WebServer) handleMockAngle(rw http.ResponseWriter, r *http.Request) {
 	if w.backend == nil {
 		writeJSONError(rw, http.StatusServiceUnavailable, "SDR backend not available")
@@ -87,6 +171,7 @@ func (w *WebServer) handleMockAngle(rw http.ResponseWriter, r *http.Request) {
 			writeJSONError(rw, http.StatusBadRequest, "phaseDelta must be between -90 and 90 degrees")
 			return
 		}
+		w.hub.recordAudit(r, "mock.phaseDelta", w.backend.GetPhaseDelta(), payload.PhaseDelta)
 		w.backend.SetPhaseDelta(payload.PhaseDelta)
 		w.log.Info("mock angle updated", logging.Field{Key: "phaseDelta", Value: payload.PhaseDelta})
 		rw.Header().Set("Content-Type", "application/json")
@@ -97,18 +182,601 @@ func (w *WebServer) handleMockAngle(rw http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Start begins listening and shuts down when the context is canceled.
-func (w *WebServer) Start(ctx context.Context) {
-	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (w *WebServer) handleCapabilities(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	reporter, ok := w.backend.(capabilityReporter)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "SDR backend does not report capabilities")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(reporter.Capabilities())
+}
+
+// handleAttrs reads live hardware attributes (GET) or applies validated writes
+// (POST). A POST body is a JSON object of attribute name to value, e.g.
+// {"rxGain0Db": 30}; all entries are validated before any write is issued.
+func (w *WebServer) handleAttrs(rw http.ResponseWriter, r *http.Request) {
+	accessor, ok := sdr.As[sdr.AttributeAccessor](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "SDR backend does not expose hardware attributes")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var payload map[string]float64
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+			return
+		}
+		if len(payload) == 0 {
+			writeJSONError(rw, http.StatusBadRequest, "no attributes provided")
+			return
+		}
+		var caps sdr.Capabilities
+		if reporter, ok := w.backend.(capabilityReporter); ok {
+			caps = reporter.Capabilities()
+		}
+		names := make([]string, 0, len(payload))
+		for name, value := range payload {
+			if err := sdr.ValidateAttribute(caps, name, value); err != nil {
+				writeJSONError(rw, http.StatusBadRequest, err.Error())
+				return
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		before := attributeValues(ctx, accessor)
+		for _, name := range names {
+			if err := accessor.WriteAttribute(ctx, name, payload[name]); err != nil {
+				writeJSONError(rw, http.StatusBadGateway, err.Error())
+				return
+			}
+			var old any
+			if v, ok := before[name]; ok {
+				old = v
+			}
+			w.hub.recordAudit(r, "sdr."+name, old, payload[name])
+			msg := fmt.Sprintf("SDR attribute %s set to %g", name, payload[name])
+			w.hub.LogEvent("info", msg)
+			w.log.Info("sdr attribute written", logging.Field{Key: "attr", Value: name}, logging.Field{Key: "value", Value: payload[name]})
+		}
+	default:
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	attrs, err := accessor.ReadAttributes(ctx)
+	if err != nil {
+		writeJSONError(rw, http.StatusBadGateway, err.Error())
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(attrs)
+}
+
+// attributeValues reads the current hardware attributes keyed by their JSON
+// names, or returns nil if they cannot be read.
+func attributeValues(ctx context.Context, accessor sdr.AttributeAccessor) map[string]float64 {
+	attrs, err := accessor.ReadAttributes(ctx)
+	if err != nil {
+		return nil
+	}
+	data, _ := json.Marshal(attrs)
+	values := make(map[string]float64)
+	_ = json.Unmarshal(data, &values)
+	return values
+}
+
+// handleInject lists (GET), replaces (POST) or clears (DELETE) the synthetic
+// signals mixed into live RX samples. Injection must be enabled at startup.
+func (w *WebServer) handleInject(rw http.ResponseWriter, r *http.Request) {
+	injector, ok := sdr.As[*sdr.Injector](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "signal injection not enabled")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var payload struct {
+			Signals []sdr.InjectedSignal `json:"signals"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+			return
+		}
+		before := injector.Signals()
+		if err := injector.SetSignals(payload.Signals); err != nil {
+			writeJSONError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(before) > 0 || len(payload.Signals) > 0 {
+			w.hub.recordAudit(r, "debug.inject", before, injector.Signals())
+		}
+		w.hub.LogEvent("warn", fmt.Sprintf("Test signal injection active: %d signal(s)", len(payload.Signals)))
+		w.log.Info("injected signals updated", logging.Field{Key: "count", Value: len(payload.Signals)})
+	case http.MethodDelete:
+		if before := injector.Signals(); len(before) > 0 {
+			w.hub.recordAudit(r, "debug.inject", before, []sdr.InjectedSignal{})
+		}
+		_ = injector.SetSignals(nil)
+		w.hub.LogEvent("info", "Test signal injection cleared")
+		w.log.Info("injected signals cleared")
+	default:
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(map[string]any{"signals": injector.Signals()})
+}
+
+// handleIntegrity reports the RX buffer integrity counters. Checking must be
+// enabled at startup.
+func (w *WebServer) handleIntegrity(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	checker, ok := sdr.As[*sdr.IntegrityChecker](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "RX integrity checking not enabled")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(checker.IntegrityStats())
+}
+
+// handleLoopback returns the last TX/RX loopback delay measurement (GET) or
+// runs a new one (POST, optional LoopbackOptions body). Tracking pauses for
+// the few buffers a measurement takes. The meter must be enabled at startup.
+func (w *WebServer) handleLoopback(rw http.ResponseWriter, r *http.Request) {
+	meter, ok := sdr.As[*sdr.LoopbackMeter](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "loopback measurement not enabled")
+		return
+	}
+
+	var result sdr.LoopbackResult
+	switch r.Method {
+	case http.MethodGet:
+		if result, ok = meter.Last(); !ok {
+			writeJSONError(rw, http.StatusNotFound, "no loopback measurement yet")
+			return
+		}
+	case http.MethodPost:
+		var opts sdr.LoopbackOptions
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+				writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+				return
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 		defer cancel()
-		if err := w.srv.Shutdown(shutdownCtx); err != nil {
-			w.log.Warn("web telemetry shutdown", logging.Field{Key: "error", Value: err})
+		var err error
+		if result, err = meter.Measure(ctx, opts); err != nil {
+			writeJSONError(rw, http.StatusBadGateway, err.Error())
+			return
 		}
-	}()
+		w.hub.LogEvent("info", fmt.Sprintf("loopback delay %d samples (jitter %d)", result.DelaySamples, result.JitterSamples))
+	default:
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(result)
+}
+
+// handleBIST returns the last built-in self test (GET) or runs a new one
+// (POST, optional BISTOptions body). Running writes driver debug attributes,
+// so POST needs the admin token. Tracking pauses for the few buffers a test
+// takes. The runner must be enabled at startup.
+func (w *WebServer) handleBIST(rw http.ResponseWriter, r *http.Request) {
+	runner, ok := sdr.As[*sdr.BISTRunner](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "built-in self test not enabled")
+		return
+	}
+
+	var result sdr.BISTResult
+	switch r.Method {
+	case http.MethodGet:
+		if result, ok = runner.Last(); !ok {
+			writeJSONError(rw, http.StatusNotFound, "no self test yet")
+			return
+		}
+	case http.MethodPost:
+		if !w.authorizeAdmin(rw, r) {
+			return
+		}
+		var opts sdr.BISTOptions
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+				writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+				return
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		var err error
+		if result, err = runner.Run(ctx, opts); err != nil {
+			writeJSONError(rw, http.StatusBadGateway, err.Error())
+			return
+		}
+		w.hub.recordAudit(r, "sdr.bist", nil, result.Mode)
+		level := "info"
+		if !result.Pass {
+			level = "warn"
+		}
+		w.hub.LogEvent(level, fmt.Sprintf("BIST %s: pass=%t", result.Mode, result.Pass))
+	default:
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(result)
+}
 
-	if err := w.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+// handleRecord returns the state of the SigMF IQ recording (GET), starts one
+// (POST, optional {"name": "..."} body) or stops it (DELETE). The recorder
+// must be enabled at startup.
+func (w *WebServer) handleRecord(rw http.ResponseWriter, r *http.Request) {
+	recorder, ok := sdr.As[*sdr.IQRecorder](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "IQ recording not enabled; start with -record")
+		return
+	}
+
+	status := recorder.Status()
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var payload struct {
+			Name string `json:"name"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+				return
+			}
+		}
+		var err error
+		if status, err = recorder.Start(payload.Name); err != nil {
+			writeJSONError(rw, http.StatusConflict, err.Error())
+			return
+		}
+		w.hub.recordAudit(r, "sdr.record", nil, status.Path)
+	case http.MethodDelete:
+		if !status.Active {
+			writeJSONError(rw, http.StatusConflict, "no recording running")
+			return
+		}
+		var err error
+		if status, err = recorder.Stop(); err != nil {
+			writeJSONError(rw, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.hub.recordAudit(r, "sdr.record", status.Path, nil)
+	default:
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(status)
+}
+
+// handleGainSchedule returns (GET), replaces (PUT/POST) or clears (DELETE)
+// the frequency to RX gain table. Changes are applied at the current LO and
+// saved to the config file.
+func (w *WebServer) handleGainSchedule(rw http.ResponseWriter, r *http.Request) {
+	scheduler, ok := sdr.As[*sdr.GainScheduler](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "gain scheduling not enabled; add gain_schedule to the config file")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost, http.MethodDelete:
+		var payload struct {
+			Points []sdr.GainPoint `json:"points"`
+		}
+		if r.Method != http.MethodDelete {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+				return
+			}
+		}
+		schedule, err := sdr.NewGainSchedule(payload.Points)
+		if err != nil {
+			writeJSONError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+		before := scheduler.Schedule()
+		if err := scheduler.SetSchedule(r.Context(), schedule); err != nil {
+			writeJSONError(rw, http.StatusBadGateway, err.Error())
+			return
+		}
+		if len(before) > 0 || len(schedule) > 0 {
+			w.hub.recordAudit(r, "sdr.gainSchedule", before, schedule)
+		}
+		if err := persistConfigValue(configFilePath, "gain_schedule", schedule); err != nil {
+			w.log.Warn("persist gain schedule", logging.Field{Key: "error", Value: err})
+		} else {
+			w.hub.recordConfigWrite()
+		}
+		w.hub.LogEvent("info", fmt.Sprintf("gain schedule updated (%d points)", len(schedule)))
+	default:
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	points := scheduler.Schedule()
+	if points == nil {
+		points = sdr.GainSchedule{}
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(map[string]any{"points": points})
+}
+
+// SetMacros makes the attribute macros runnable through /api/sdr/macros. Call
+// before Start.
+func (w *WebServer) SetMacros(macros sdr.Macros) {
+	w.macros = macros
+}
+
+// handleMacros lists the configured attribute macros (GET) or runs one (POST
+// {"name": "..."}). A failed macro is rolled back and reported with 502.
+func (w *WebServer) handleMacros(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		macros := w.macros
+		if macros == nil {
+			macros = sdr.Macros{}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(map[string]any{"macros": macros})
+		return
+	case http.MethodPost:
+	default:
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	steps, ok := w.macros[payload.Name]
+	if !ok {
+		writeJSONError(rw, http.StatusNotFound, fmt.Sprintf("unknown macro %q", payload.Name))
+		return
+	}
+	accessor, ok := sdr.As[sdr.RawAttributeAccessor](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "SDR backend does not expose raw attributes")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	results, err := sdr.RunMacro(ctx, accessor, steps)
+	if err != nil {
+		w.hub.LogEvent("warn", fmt.Sprintf("macro %s failed and was rolled back: %v", payload.Name, err))
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(rw).Encode(map[string]any{"error": err.Error(), "rolledBack": results})
+		return
+	}
+	before, after := make(map[string]string, len(results)), make(map[string]string, len(results))
+	for _, res := range results {
+		before[res.Step.String()] = res.Old
+		after[res.Step.String()] = res.Step.Value
+	}
+	w.hub.recordAudit(r, "sdr.macro."+payload.Name, before, after)
+	w.hub.LogEvent("info", fmt.Sprintf("macro %s applied (%d steps)", payload.Name, len(results)))
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(map[string]any{"applied": results})
+}
+
+// SetAdminToken enables the admin-only endpoints, which require the token as
+// "Authorization: Bearer <token>". With no token they stay disabled. Call
+// before Start.
+func (w *WebServer) SetAdminToken(token string) {
+	w.admin = token
+}
+
+// isAdminToken reports whether token is the configured admin token.
+func (w *WebServer) isAdminToken(token string) bool {
+	return w.admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(w.admin)) == 1
+}
+
+// authorizeAdmin writes an error response and returns false unless r carries
+// the admin token.
+func (w *WebServer) authorizeAdmin(rw http.ResponseWriter, r *http.Request) bool {
+	if w.admin == "" {
+		writeJSONError(rw, http.StatusServiceUnavailable, "admin endpoints are disabled (no admin token configured)")
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !w.isAdminToken(token) {
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(rw, http.StatusUnauthorized, "admin token required")
+		return false
+	}
+	return true
+}
+
+// adminWrites requires the admin token for requests to next other than GET
+// and HEAD.
+func (w *WebServer) adminWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !w.authorizeAdmin(rw, r) {
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// handleIIODExec runs one IIOD console command (POST {"command": "..."}) on
+// the live connection and returns the raw response. Writes are audited.
+func (w *WebServer) handleIIODExec(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !w.authorizeAdmin(rw, r) {
+		return
+	}
+	var payload struct {
+		Command string `json:"command"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(rw, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	cmd, err := sdr.ParseIIODCommand(payload.Command)
+	if err != nil {
+		writeJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	executor, ok := sdr.As[sdr.CommandExecutor](w.backend)
+	if !ok {
+		writeJSONError(rw, http.StatusServiceUnavailable, "SDR backend does not support IIOD commands")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	response, err := executor.ExecIIOD(ctx, payload.Command)
+	if err != nil {
+		writeJSONError(rw, http.StatusBadGateway, err.Error())
+		return
+	}
+	if cmd.Op == "WRITE" {
+		w.hub.recordAudit(r, "iiod.exec", nil, payload.Command)
+		w.hub.LogEvent("info", fmt.Sprintf("iiod console: %s", payload.Command))
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(map[string]string{"response": response})
+}
+
+// AddDevice exposes a named backend under /api/devices/{id}/. Call before Start.
+func (w *WebServer) AddDevice(id string, backend SDRBackend) {
+	w.devices[id] = backend
+}
+
+func (w *WebServer) handleDevices(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(w.hub.Devices())
+}
+
+// handleDeviceScoped serves /api/devices/{id}/{resource}, reusing the global
+// handlers with the device filter or backend substituted.
+func (w *WebServer) handleDeviceScoped(rw http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/devices/"), "/")
+	id, resource, _ := strings.Cut(rest, "/")
+	backend, ok := w.devices[id]
+	if id == "" || !ok {
+		writeJSONError(rw, http.StatusNotFound, "device not found")
+		return
+	}
+
+	q := r.URL.Query()
+	q.Set("device", id)
+	r.URL.RawQuery = q.Encode()
+
+	scoped := *w
+	scoped.backend = backend
+	switch resource {
+	case "history":
+		w.hub.handleHistory(rw, r)
+	case "history/export":
+		w.hub.handleHistoryExport(rw, r)
+	case "tracks":
+		w.hub.handleTracks(rw, r)
+	case "tracks.geojson":
+		w.hub.handleTracksGeoJSON(rw, r)
+	case "live":
+		w.hub.handleLive(rw, r)
+	case "spectrum/occupancy":
+		w.hub.handleOccupancy(rw, r)
+	case "spectrum/sweep":
+		w.hub.handleSweep(rw, r)
+	case "waterfall":
+		w.hub.handleWaterfall(rw, r)
+	case "waterfall/stream":
+		w.hub.handleWaterfallStream(rw, r)
+	case "steering":
+		w.hub.handleSteering(rw, r)
+	case "convergence":
+		w.hub.handleConvergence(rw, r)
+	case "sensors":
+		w.hub.handleSensors(rw, r)
+	case "steering/stream":
+		w.hub.handleSteeringStream(rw, r)
+	case "sdr/capabilities":
+		scoped.handleCapabilities(rw, r)
+	case "sdr/attrs":
+		scoped.handleAttrs(rw, r)
+	case "sdr/integrity":
+		scoped.handleIntegrity(rw, r)
+	case "sdr/loopback":
+		scoped.handleLoopback(rw, r)
+	case "sdr/bist":
+		scoped.handleBIST(rw, r)
+	case "sdr/record":
+		scoped.handleRecord(rw, r)
+	case "sdr/macros":
+		scoped.handleMacros(rw, r)
+	case "iiod/exec":
+		scoped.handleIIODExec(rw, r)
+	case "mock/angle":
+		scoped.handleMockAngle(rw, r)
+	default:
+		writeJSONError(rw, http.StatusNotFound, "unknown device resource")
+	}
+}
+
+// Start serves until ctx is canceled, then shuts the server down and
+// returns once it has stopped. Requests see ctx as their base context, so
+// streams end with it instead of holding up the shutdown. It returns an
+// error when the server cannot listen or fails while serving.
+func (w *WebServer) Start(ctx context.Context) error {
+	w.srv.BaseContext = func(net.Listener) context.Context { return ctx }
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.srv.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
 		w.log.Error("web telemetry server error", logging.Field{Key: "error", Value: err})
+		return fmt.Errorf("web telemetry: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := w.srv.Shutdown(shutdownCtx); err != nil {
+		w.log.Warn("web telemetry shutdown", logging.Field{Key: "error", Value: err})
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("web telemetry: %w", err)
 	}
+	return nil
 }
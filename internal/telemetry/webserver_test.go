@@ -0,0 +1,541 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+)
+
+func TestHandleCapabilitiesReturnsBackendLimits(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), sdr.NewMock(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sdr/capabilities", nil)
+	rr := httptest.NewRecorder()
+	ws.handleCapabilities(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var caps sdr.Capabilities
+	if err := json.NewDecoder(rr.Body).Decode(&caps); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if caps.Backend != "mock" || caps.RXChannels != 2 || !caps.SimulatedAngle {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+}
+
+func TestHandleCapabilitiesWithoutBackend(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sdr/capabilities", nil)
+	rr := httptest.NewRecorder()
+	ws.handleCapabilities(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rr.Code)
+	}
+}
+
+func TestHandleCapabilitiesMethodNotAllowed(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), sdr.NewMock(), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sdr/capabilities", nil)
+	rr := httptest.NewRecorder()
+	ws.handleCapabilities(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", rr.Code)
+	}
+}
+
+func TestHandleAttrsReadAndWrite(t *testing.T) {
+	backend := sdr.NewMock()
+	if err := backend.Init(context.Background(), sdr.Config{SampleRate: 2e6, RxLO: 2.3e9, RxGain0: 10}); err != nil {
+		t.Fatalf("init mock: %v", err)
+	}
+	ws := NewWebServer(":0", newTestHub(), backend, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sdr/attrs", strings.NewReader(`{"rxGain0Db": 30, "rxLoHz": 2.4e9}`))
+	rr := httptest.NewRecorder()
+	ws.handleAttrs(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sdr/attrs", nil)
+	rr = httptest.NewRecorder()
+	ws.handleAttrs(rr, req)
+	var attrs sdr.HardwareAttributes
+	if err := json.NewDecoder(rr.Body).Decode(&attrs); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if attrs.RxGain0DB != 30 || attrs.RxLOHz != 2.4e9 || attrs.SampleRateHz != 2e6 {
+		t.Fatalf("unexpected attributes: %+v", attrs)
+	}
+}
+
+func TestHandleAttrsRejectsInvalidWrites(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), sdr.NewMock(), nil)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unknown attribute", `{"bogus": 1}`},
+		{"gain out of range", `{"rxGain1Db": 500}`},
+		{"tx unsupported", `{"txGainDb": -10}`},
+		{"empty", `{}`},
+		{"malformed", `{`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/sdr/attrs", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			ws.handleAttrs(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleInject(t *testing.T) {
+	injector := sdr.NewInjector(sdr.NewMock())
+	if err := injector.Init(context.Background(), sdr.Config{SampleRate: 2e6}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	ws := NewWebServer(":0", newTestHub(), injector, nil)
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		wantCode  int
+		wantCount int
+	}{
+		{"set", http.MethodPost, `{"signals":[{"frequencyHz":1000,"amplitude":0.5,"phaseDeltaDeg":10}]}`, http.StatusOK, 1},
+		{"get", http.MethodGet, "", http.StatusOK, 1},
+		{"invalid", http.MethodPost, `{"signals":[{"frequencyHz":1000,"amplitude":5}]}`, http.StatusBadRequest, 1},
+		{"clear", http.MethodDelete, "", http.StatusOK, 0},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, "/api/debug/inject", strings.NewReader(tc.body))
+		rr := httptest.NewRecorder()
+		ws.handleInject(rr, req)
+		if rr.Code != tc.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, tc.wantCode, rr.Code)
+		}
+		if got := len(injector.Signals()); got != tc.wantCount {
+			t.Fatalf("%s: expected %d signals, got %d", tc.name, tc.wantCount, got)
+		}
+	}
+}
+
+func TestHandleInjectDisabled(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), sdr.NewMock(), nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/debug/inject", nil)
+	rr := httptest.NewRecorder()
+	ws.handleInject(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rr.Code)
+	}
+}
+
+func TestHandleLoopback(t *testing.T) {
+	meter := sdr.NewLoopbackMeter(sdr.NewMock())
+	if err := meter.Init(context.Background(), sdr.Config{NumSamples: 1024, SampleRate: 1e6, ToneOffset: 100e3}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	ws := NewWebServer(":0", newTestHub(), sdr.NewInjector(meter), nil)
+
+	tests := []struct {
+		method   string
+		body     string
+		wantCode int
+	}{
+		{http.MethodGet, "", http.StatusNotFound},
+		{http.MethodPost, `{"trials": 2}`, http.StatusOK},
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPost, `{"trials": "x"}`, http.StatusBadRequest},
+		{http.MethodDelete, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		ws.handleLoopback(rr, httptest.NewRequest(tt.method, "/api/sdr/loopback", strings.NewReader(tt.body)))
+		if rr.Code != tt.wantCode {
+			t.Fatalf("%s %s: status %d, want %d (%s)", tt.method, tt.body, rr.Code, tt.wantCode, rr.Body)
+		}
+		if rr.Code == http.StatusOK {
+			var result sdr.LoopbackResult
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil || len(result.Trials) != 2 || !result.Deterministic {
+				t.Fatalf("result = %+v, err = %v", result, err)
+			}
+		}
+	}
+}
+
+func TestHandleBIST(t *testing.T) {
+	runner := sdr.NewBISTRunner(sdr.NewMock())
+	if err := runner.Init(context.Background(), sdr.Config{NumSamples: 1024, SampleRate: 2e6, ToneOffset: 100e3}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	ws := NewWebServer(":0", newTestHub(), sdr.NewInjector(runner), nil)
+	ws.SetAdminToken("secret")
+
+	tests := []struct {
+		method   string
+		token    string
+		body     string
+		wantCode int
+	}{
+		{http.MethodGet, "", "", http.StatusNotFound},
+		{http.MethodPost, "", `{"mode": "tone"}`, http.StatusUnauthorized},
+		{http.MethodPost, "secret", `{"mode": "tone", "levelDB": 6}`, http.StatusOK},
+		{http.MethodGet, "", "", http.StatusOK},
+		{http.MethodPost, "secret", `{"mode": "sweep"}`, http.StatusBadGateway},
+		{http.MethodPost, "secret", `{"mode": 1}`, http.StatusBadRequest},
+		{http.MethodDelete, "", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/sdr/bist", strings.NewReader(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rr := httptest.NewRecorder()
+		ws.handleBIST(rr, req)
+		if rr.Code != tt.wantCode {
+			t.Fatalf("%s %s: status %d, want %d (%s)", tt.method, tt.body, rr.Code, tt.wantCode, rr.Body)
+		}
+		if rr.Code == http.StatusOK {
+			var result sdr.BISTResult
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil || !result.Pass || result.ExpectedHz != 125e3 {
+				t.Fatalf("result = %+v, err = %v", result, err)
+			}
+		}
+	}
+	if entries := ws.hub.AuditLog(); len(entries) != 1 || entries[0].Setting != "sdr.bist" {
+		t.Fatalf("expected one sdr.bist audit entry, got %+v", entries)
+	}
+}
+
+func TestHandleRecord(t *testing.T) {
+	dir := t.TempDir()
+	recorder := sdr.NewIQRecorder(sdr.NewMock(), dir)
+	if err := recorder.Init(context.Background(), sdr.Config{NumSamples: 64, SampleRate: 2e6}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	ws := NewWebServer(":0", newTestHub(), sdr.NewInjector(recorder), nil)
+
+	tests := []struct {
+		method     string
+		body       string
+		wantCode   int
+		wantActive bool
+	}{
+		{http.MethodGet, "", http.StatusOK, false},
+		{http.MethodDelete, "", http.StatusConflict, false},
+		{http.MethodPost, `{"name": "field"}`, http.StatusOK, true},
+		{http.MethodPost, "", http.StatusConflict, false},
+		{http.MethodGet, "", http.StatusOK, true},
+		{http.MethodDelete, "", http.StatusOK, false},
+		{http.MethodPost, `{"name": 1}`, http.StatusBadRequest, false},
+		{http.MethodPut, "", http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		ws.handleRecord(rr, httptest.NewRequest(tt.method, "/api/sdr/record", strings.NewReader(tt.body)))
+		if rr.Code != tt.wantCode {
+			t.Fatalf("%s %s: status %d, want %d (%s)", tt.method, tt.body, rr.Code, tt.wantCode, rr.Body)
+		}
+		if rr.Code == http.StatusOK {
+			var status sdr.IQRecording
+			if err := json.NewDecoder(rr.Body).Decode(&status); err != nil || status.Active != tt.wantActive {
+				t.Fatalf("%s: status = %+v, err = %v", tt.method, status, err)
+			}
+		}
+	}
+	if _, err := sdr.ReadSigMFMeta(filepath.Join(dir, "field")); err != nil {
+		t.Fatalf("recording metadata: %v", err)
+	}
+	if entries := ws.hub.AuditLog(); len(entries) != 2 || entries[0].Setting != "sdr.record" {
+		t.Fatalf("expected two sdr.record audit entries, got %+v", entries)
+	}
+}
+
+func TestHandleIntegrity(t *testing.T) {
+	checker := sdr.NewIntegrityChecker(sdr.NewMock())
+	if err := checker.Init(context.Background(), sdr.Config{NumSamples: 64, SampleRate: 2e6}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if _, _, err := checker.RX(context.Background()); err != nil {
+		t.Fatalf("RX: %v", err)
+	}
+	ws := NewWebServer(":0", newTestHub(), sdr.NewInjector(checker), nil)
+
+	rr := httptest.NewRecorder()
+	ws.handleIntegrity(rr, httptest.NewRequest(http.MethodGet, "/api/sdr/integrity", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var stats sdr.IntegrityStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if stats.Buffers != 1 || stats.AllZero != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	disabled := NewWebServer(":0", newTestHub(), sdr.NewMock(), nil)
+	rr = httptest.NewRecorder()
+	disabled.handleIntegrity(rr, httptest.NewRequest(http.MethodGet, "/api/sdr/integrity", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 without checker, got %d", rr.Code)
+	}
+}
+
+func TestHandleGainSchedule(t *testing.T) {
+	t.Chdir(t.TempDir())
+	scheduler := sdr.NewGainScheduler(sdr.NewMock(), nil)
+	if err := scheduler.Init(context.Background(), sdr.Config{RxLO: 2.3e9, SampleRate: 2e6, NumSamples: 64}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	ws := NewWebServer(":0", newTestHub(), scheduler, nil)
+
+	body := `{"points":[{"frequency_hz":2.4e9,"rx_gain0_db":44,"rx_gain1_db":45},{"frequency_hz":2.2e9,"rx_gain0_db":40,"rx_gain1_db":41}]}`
+	rr := httptest.NewRecorder()
+	ws.handleGainSchedule(rr, httptest.NewRequest(http.MethodPut, "/api/sdr/gain-schedule", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Points []sdr.GainPoint `json:"points"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Points) != 2 || resp.Points[0].FrequencyHz != 2.2e9 {
+		t.Fatalf("expected sorted schedule, got %+v", resp.Points)
+	}
+	attrs, _ := scheduler.ReadAttributes(context.Background())
+	if attrs.RxGain0DB != 42 || attrs.RxGain1DB != 43 {
+		t.Fatalf("expected schedule applied at 2.3 GHz, got %.0f/%.0f", attrs.RxGain0DB, attrs.RxGain1DB)
+	}
+	raw, err := os.ReadFile(configFilePath)
+	if err != nil || !strings.Contains(string(raw), `"gain_schedule"`) {
+		t.Fatalf("expected gain schedule persisted, got %q (%v)", raw, err)
+	}
+
+	rr = httptest.NewRecorder()
+	ws.handleGainSchedule(rr, httptest.NewRequest(http.MethodPut, "/api/sdr/gain-schedule", strings.NewReader(`{"points":[{"frequency_hz":0}]}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for invalid point, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ws.handleGainSchedule(rr, httptest.NewRequest(http.MethodDelete, "/api/sdr/gain-schedule", nil))
+	if rr.Code != http.StatusOK || len(scheduler.Schedule()) != 0 {
+		t.Fatalf("expected schedule cleared, got %d / %v", rr.Code, scheduler.Schedule())
+	}
+}
+
+func TestHandleMacros(t *testing.T) {
+	backend := sdr.NewMock()
+	ws := NewWebServer(":0", newTestHub(), backend, nil)
+	ws.SetMacros(sdr.Macros{"loopback": {{Device: "ad9361-phy", Attr: "loopback", Value: "1"}}})
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{name: "list", method: http.MethodGet, want: http.StatusOK},
+		{name: "run", method: http.MethodPost, body: `{"name":"loopback"}`, want: http.StatusOK},
+		{name: "unknown", method: http.MethodPost, body: `{"name":"obs"}`, want: http.StatusNotFound},
+		{name: "bad method", method: http.MethodDelete, want: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		ws.handleMacros(rr, httptest.NewRequest(tt.method, "/api/sdr/macros", strings.NewReader(tt.body)))
+		if rr.Code != tt.want {
+			t.Fatalf("%s: status %d, want %d: %s", tt.name, rr.Code, tt.want, rr.Body.String())
+		}
+	}
+
+	if v, _ := backend.ReadRawAttribute(context.Background(), "ad9361-phy", "", "loopback"); v != "1" {
+		t.Fatalf("macro not applied, loopback = %q", v)
+	}
+	entries := ws.hub.AuditLog()
+	if len(entries) != 1 || entries[0].Setting != "sdr.macro.loopback" {
+		t.Fatalf("expected macro audit entry, got %+v", entries)
+	}
+}
+
+func TestHandleIIODExec(t *testing.T) {
+	ws := NewWebServer(":0", newTestHub(), sdr.NewMock(), nil)
+	exec := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/iiod/exec", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		ws.handleIIODExec(rr, req)
+		return rr
+	}
+
+	if rr := exec("secret", `{"command":"VERSION"}`); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without admin token configured, got %d", rr.Code)
+	}
+	ws.SetAdminToken("secret")
+
+	tests := []struct {
+		name  string
+		token string
+		body  string
+		want  int
+	}{
+		{name: "no token", body: `{"command":"VERSION"}`, want: http.StatusUnauthorized},
+		{name: "wrong token", token: "guess", body: `{"command":"VERSION"}`, want: http.StatusUnauthorized},
+		{name: "rejected command", token: "secret", body: `{"command":"OPEN cf-ad9361-lpc 4096 3"}`, want: http.StatusBadRequest},
+		{name: "write", token: "secret", body: `{"command":"WRITE ad9361-phy DEBUG loopback 1"}`, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rr := exec(tt.token, tt.body); rr.Code != tt.want {
+			t.Fatalf("%s: status %d, want %d: %s", tt.name, rr.Code, tt.want, rr.Body.String())
+		}
+	}
+
+	rr := exec("secret", `{"command":"READ ad9361-phy DEBUG loopback"}`)
+	var got struct {
+		Response string `json:"response"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil || got.Response != "1" {
+		t.Fatalf("read response = %q, %v; want 1", got.Response, err)
+	}
+	entries := ws.hub.AuditLog()
+	if len(entries) != 1 || entries[0].Setting != "iiod.exec" {
+		t.Fatalf("expected one iiod.exec audit entry, got %+v", entries)
+	}
+}
+
+func TestWebServerStartReturnsErrors(t *testing.T) {
+	logger := logging.New(logging.Debug, logging.Text, io.Discard)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	busy := NewWebServer(ln.Addr().String(), newTestHub(), nil, logger)
+	if err := busy.Start(context.Background()); err == nil {
+		t.Fatal("Start on a busy address returned nil")
+	}
+
+	ws := NewWebServer("127.0.0.1:0", newTestHub(), nil, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ws.Start(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start after cancel: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after cancel")
+	}
+}
+
+// TestPythonClientEndpoints checks the hand-maintained Python client against
+// the routes of NewWebServer and handleDeviceScoped, so a renamed or removed
+// endpoint fails here instead of in the field.
+func TestPythonClientEndpoints(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "webserver.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := map[string]bool{"/api/pair": true} // answered by Pairing.Middleware
+	scoped := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") || len(n.Args) == 0 {
+				return true
+			}
+			if lit, ok := n.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				pattern, _ := strconv.Unquote(lit.Value)
+				routes[pattern] = true
+			}
+		case *ast.FuncDecl:
+			if n.Name.Name != "handleDeviceScoped" {
+				return true
+			}
+			ast.Inspect(n.Body, func(n ast.Node) bool {
+				if c, ok := n.(*ast.CaseClause); ok {
+					for _, e := range c.List {
+						if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+							resource, _ := strconv.Unquote(lit.Value)
+							scoped[resource] = true
+						}
+					}
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+	served := func(path string) bool {
+		for pattern := range routes {
+			if pattern == path || (pattern != "/" && strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	client, err := os.ReadFile(filepath.Join("..", "..", "clients", "python", "gosdr", "__init__.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checked int
+	for _, m := range regexp.MustCompile(`"(/api/[^"]*)"`).FindAllStringSubmatch(string(client), -1) {
+		if m[1] == "/api/" {
+			continue // the prefix of _scoped, checked below
+		}
+		checked++
+		if !served(m[1]) {
+			t.Errorf("Python client calls %s, which the web server does not serve", m[1])
+		}
+	}
+	for _, m := range regexp.MustCompile(`_scoped\("([^"]+)"\)`).FindAllStringSubmatch(string(client), -1) {
+		checked++
+		if !served("/api/" + m[1]) {
+			t.Errorf("Python client calls /api/%s, which the web server does not serve", m[1])
+		}
+		if !scoped[m[1]] {
+			t.Errorf("Python client calls /api/devices/{id}/%s, which handleDeviceScoped does not serve", m[1])
+		}
+	}
+	if checked < 10 {
+		t.Fatalf("found only %d endpoints in the Python client; has its layout changed?", checked)
+	}
+}
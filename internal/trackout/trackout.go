@@ -0,0 +1,273 @@
+// Package trackout sends every tracking iteration as one fixed-format UDP
+// datagram, for consumers that steer antennas in real time and cannot afford
+// the latency of HTTP polling or server-sent events, or the cost of parsing
+// JSON.
+//
+// A datagram is a 36-byte header followed by one 32-byte record per track,
+// all big-endian:
+//
+//	header  magic "GSTK" [4] | version uint8 | tracks uint8 | flags uint16 |
+//	        seq uint32 | time_unix_nano int64 | source [16]
+//	record  id [16] | angle_deg float32 | snr_db float32 |
+//	        confidence float32 | lock_state uint8 | reserved [3]
+//
+// Strings are NUL-padded ASCII and cut at 16 bytes. seq counts datagrams per
+// sender, so a consumer can detect loss. Flags bit 0 marks a gap in the
+// sample stream, bit 1 a wall clock step. An iteration with more than
+// MaxTracks tracks is split over several datagrams with the same timestamp.
+package trackout
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+const (
+	// Magic starts every datagram.
+	Magic = "GSTK"
+	// Version is the format version in the header.
+	Version = 1
+	// HeaderSize and RecordSize are the encoded sizes in bytes.
+	HeaderSize = 36
+	RecordSize = 32
+	// MaxTracks is the number of records that keep a datagram within a
+	// 1500-byte Ethernet MTU.
+	MaxTracks = 44
+
+	nameSize = 16
+)
+
+// Header flags.
+const (
+	FlagGap       = 1 << 0
+	FlagClockStep = 1 << 1
+)
+
+// Lock state codes.
+const (
+	LockUnknown   = 0
+	LockSearching = 1
+	LockTracking  = 2
+	LockLocked    = 3
+)
+
+// Track is one decoded record.
+type Track struct {
+	ID         string
+	AngleDeg   float64
+	SNR        float64
+	Confidence float64
+	LockState  telemetry.LockState
+}
+
+// Message is one decoded datagram.
+type Message struct {
+	Seq    uint32
+	Time   time.Time
+	Source string
+	Flags  uint16
+	Tracks []Track
+}
+
+// lockCode maps a lock state to its code.
+func lockCode(s telemetry.LockState) uint8 {
+	switch s {
+	case telemetry.LockStateSearching:
+		return LockSearching
+	case telemetry.LockStateTracking:
+		return LockTracking
+	case telemetry.LockStateLocked:
+		return LockLocked
+	}
+	return LockUnknown
+}
+
+// lockState maps a code back to its lock state; unknown codes give "".
+func lockState(code uint8) telemetry.LockState {
+	switch code {
+	case LockSearching:
+		return telemetry.LockStateSearching
+	case LockTracking:
+		return telemetry.LockStateTracking
+	case LockLocked:
+		return telemetry.LockStateLocked
+	}
+	return ""
+}
+
+func appendName(b []byte, s string) []byte {
+	var field [nameSize]byte
+	copy(field[:], s)
+	return append(b, field[:]...)
+}
+
+func appendFloat32(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint32(b, math.Float32bits(float32(v)))
+}
+
+// AppendMessage appends the datagram for tracks, at most MaxTracks of them,
+// to b. Angles are rounded to the output resolution.
+func AppendMessage(b []byte, seq uint32, source string, sample telemetry.MultiTrackSample, tracks []telemetry.TrackSample, output telemetry.OutputConfig) []byte {
+	if len(tracks) > MaxTracks {
+		tracks = tracks[:MaxTracks]
+	}
+	var flags uint16
+	if sample.Gap {
+		flags |= FlagGap
+	}
+	if sample.ClockStep {
+		flags |= FlagClockStep
+	}
+	b = append(b, Magic...)
+	b = append(b, Version, uint8(len(tracks)))
+	b = binary.BigEndian.AppendUint16(b, flags)
+	b = binary.BigEndian.AppendUint32(b, seq)
+	b = binary.BigEndian.AppendUint64(b, uint64(sample.Timestamp.UnixNano()))
+	b = appendName(b, source)
+	for _, t := range tracks {
+		b = appendName(b, t.ID)
+		b = appendFloat32(b, output.Quantize(t.AngleDeg))
+		b = appendFloat32(b, t.SNR)
+		b = appendFloat32(b, t.Confidence)
+		b = append(b, lockCode(t.LockState), 0, 0, 0)
+	}
+	return b
+}
+
+func name(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+func float32At(b []byte) float64 {
+	return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+}
+
+// Decode parses one datagram.
+func Decode(b []byte) (Message, error) {
+	if len(b) < HeaderSize || string(b[:4]) != Magic {
+		return Message{}, errors.New("trackout: not a track datagram")
+	}
+	if b[4] != Version {
+		return Message{}, fmt.Errorf("trackout: unsupported version %d", b[4])
+	}
+	n := int(b[5])
+	if len(b) != HeaderSize+n*RecordSize {
+		return Message{}, fmt.Errorf("trackout: %d bytes for %d tracks", len(b), n)
+	}
+	msg := Message{
+		Flags:  binary.BigEndian.Uint16(b[6:]),
+		Seq:    binary.BigEndian.Uint32(b[8:]),
+		Time:   time.Unix(0, int64(binary.BigEndian.Uint64(b[12:]))),
+		Source: name(b[20:HeaderSize]),
+		Tracks: make([]Track, n),
+	}
+	for i := range msg.Tracks {
+		r := b[HeaderSize+i*RecordSize:]
+		msg.Tracks[i] = Track{
+			ID:         name(r[:nameSize]),
+			AngleDeg:   float32At(r[16:]),
+			SNR:        float32At(r[20:]),
+			Confidence: float32At(r[24:]),
+			LockState:  lockState(r[28]),
+		}
+	}
+	return msg, nil
+}
+
+// Sender writes the datagrams to one UDP destination, which may be a
+// multicast group. Sends never block the tracker for longer than the kernel
+// takes to queue a datagram; failures are counted, and logged once until a
+// send succeeds again.
+type Sender struct {
+	conn   net.Conn
+	output telemetry.OutputConfig
+	log    logging.Logger
+
+	mu      sync.Mutex
+	seq     uint32
+	buf     []byte
+	sent    uint64
+	failed  uint64
+	failing bool
+}
+
+// Dial returns a sender for addr (host:port). Angles are rounded to the
+// resolution of output; its rate limit does not apply, every iteration is
+// sent.
+func Dial(addr string, output telemetry.OutputConfig, logger logging.Logger) (*Sender, error) {
+	if err := output.Validate(); err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = logging.Default()
+	}
+	return &Sender{
+		conn:   conn,
+		output: output,
+		log:    logger.With(logging.Field{Key: "subsystem", Value: "trackout"}, logging.Field{Key: "addr", Value: addr}),
+	}, nil
+}
+
+// Send writes the tracks of sample, reported by source, as one or more
+// datagrams. A sample without tracks still sends a header, so consumers see
+// that the tracker is alive but has nothing.
+func (s *Sender) Send(source string, sample telemetry.MultiTrackSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tracks := sample.Tracks
+	for {
+		s.seq++
+		s.buf = AppendMessage(s.buf[:0], s.seq, source, sample, tracks, s.output)
+		s.write()
+		if len(tracks) <= MaxTracks {
+			return
+		}
+		tracks = tracks[MaxTracks:]
+	}
+}
+
+// write sends s.buf; s.mu must be held.
+func (s *Sender) write() {
+	if _, err := s.conn.Write(s.buf); err != nil {
+		s.failed++
+		if !s.failing {
+			s.failing = true
+			s.log.Warn("track output failing", logging.Field{Key: "error", Value: err})
+		}
+		return
+	}
+	s.sent++
+	if s.failing {
+		s.failing = false
+		s.log.Info("track output resumed", logging.Field{Key: "failed", Value: s.failed})
+	}
+}
+
+// Stats returns the number of datagrams sent and failed.
+func (s *Sender) Stats() (sent, failed uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent, s.failed
+}
+
+// Close closes the socket.
+func (s *Sender) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,90 @@
+package trackout
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+	sample := telemetry.MultiTrackSample{Timestamp: now, Gap: true, Tracks: []telemetry.TrackSample{
+		{ID: "1", AngleDeg: 12.34, SNR: 21.5, Confidence: 0.75, LockState: telemetry.LockStateLocked},
+		{ID: "a-very-long-track-identifier", AngleDeg: -40.26, SNR: 3, LockState: telemetry.LockStateSearching},
+	}}
+	b := AppendMessage(nil, 7, "north", sample, sample.Tracks, telemetry.OutputConfig{ResolutionDeg: 0.5})
+	if len(b) != HeaderSize+2*RecordSize {
+		t.Fatalf("encoded %d bytes", len(b))
+	}
+	msg, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Seq != 7 || !msg.Time.Equal(now) || msg.Source != "north" || msg.Flags != FlagGap || len(msg.Tracks) != 2 {
+		t.Fatalf("header %+v", msg)
+	}
+	want := []Track{
+		{ID: "1", AngleDeg: 12.5, SNR: 21.5, Confidence: 0.75, LockState: telemetry.LockStateLocked},
+		{ID: "a-very-long-trac", AngleDeg: -40.5, SNR: 3, LockState: telemetry.LockStateSearching},
+	}
+	for i, tr := range msg.Tracks {
+		if tr != want[i] {
+			t.Errorf("track %d = %+v, want %+v", i, tr, want[i])
+		}
+	}
+
+	if _, err := Decode(b[:len(b)-1]); err == nil {
+		t.Error("truncated datagram accepted")
+	}
+	b[4] = Version + 1
+	if _, err := Decode(b); err == nil {
+		t.Error("unknown version accepted")
+	}
+}
+
+func TestSenderSplitsLargeIterations(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	s, err := Dial(pc.LocalAddr().String(), telemetry.OutputConfig{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	sample := telemetry.MultiTrackSample{Timestamp: time.Unix(1700000000, 0)}
+	for i := 0; i < MaxTracks+6; i++ {
+		sample.Tracks = append(sample.Tracks, telemetry.TrackSample{ID: fmt.Sprint(i), AngleDeg: float64(i)})
+	}
+	s.Send("", sample)
+	s.Send("", telemetry.MultiTrackSample{Timestamp: sample.Timestamp})
+
+	buf := make([]byte, 65536)
+	var got []Message
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(got) < 3 {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("after %d datagrams: %v", len(got), err)
+		}
+		msg, err := Decode(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, msg)
+	}
+	if got[0].Seq != 1 || len(got[0].Tracks) != MaxTracks || got[1].Seq != 2 || len(got[1].Tracks) != 6 || got[1].Tracks[5].ID != "49" {
+		t.Fatalf("split into %d+%d tracks, seq %d and %d", len(got[0].Tracks), len(got[1].Tracks), got[0].Seq, got[1].Seq)
+	}
+	if !got[1].Time.Equal(got[0].Time) || got[2].Seq != 3 || len(got[2].Tracks) != 0 {
+		t.Fatalf("unexpected datagrams %+v", got[1:])
+	}
+	if sent, failed := s.Stats(); sent != 3 || failed != 0 {
+		t.Fatalf("stats %d sent, %d failed", sent, failed)
+	}
+}
@@ -0,0 +1,437 @@
+// Package tsdb writes tracking samples and radio sensor readings to a
+// time-series database, for sites that already collect their sensor fleets
+// in one. It speaks the InfluxDB line protocol over HTTP, which InfluxDB 1.x
+// (/write), 2.x and 3.x (/api/v2/write) accept, using only the standard
+// library.
+//
+// Every tracking iteration writes one point per track and one per sample:
+//
+//	<m>_track,device=<d>,track=<id> angle_deg=..,bearing_deg=..,peak_dbfs=..,snr_db=..,confidence=..,lock_state="locked" <ns>
+//	<m>_sample,device=<d> tracks=2i,gap=false <ns>
+//	<m>_sensors,device=<d> temperature_c=..,rssi0_db=..,rssi1_db=.. <ns>
+//
+// where <m> is Config.Measurement and the device tag is left out for a
+// single unnamed device. Points are buffered and written in batches; failed
+// batches are retried with backoff while the buffer holds them.
+//
+// A nil *Writer is valid and writes nothing, so callers need no checks.
+package tsdb
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/logging"
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+// Database kinds of Config.Kind.
+const (
+	KindInflux    = "influx"
+	KindTimescale = "timescale"
+)
+
+// Defaults of the Config fields.
+const (
+	DefaultMeasurement   = "gosdr"
+	DefaultBatchSize     = 5000
+	DefaultFlushInterval = time.Second
+	DefaultMaxBuffer     = 100_000
+	// maxRetryDelay bounds the backoff after failed writes.
+	maxRetryDelay = time.Minute
+)
+
+// Config is the "tsdb" object of config.json.
+type Config struct {
+	// Kind is influx (default). timescale is recognised but needs a
+	// PostgreSQL driver, which this build does not include.
+	Kind string `json:"kind,omitempty"`
+	// URL is the base URL of the database, such as http://influx:8086.
+	URL string `json:"url"`
+	// Database selects the InfluxDB 1.x write endpoint; Org and Bucket the
+	// 2.x one.
+	Database string `json:"database,omitempty"`
+	Org      string `json:"org,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+	// Token is sent as "Authorization: Token ..."; it may be an env:, file:
+	// or secret: reference, resolved by the caller.
+	Token string `json:"token,omitempty"`
+	// Measurement prefixes the measurement names, default "gosdr".
+	Measurement string `json:"measurement,omitempty"`
+	// Tags are added to every point, such as {"site": "north"}.
+	Tags map[string]string `json:"tags,omitempty"`
+	// BatchSize is the number of points per write, default 5000.
+	BatchSize int `json:"batch_size,omitempty"`
+	// FlushInterval is the time between writes, default 1s.
+	FlushInterval string `json:"flush_interval,omitempty"`
+	// MaxBuffer is the number of points kept while the database is
+	// unreachable, default 100000; the oldest are dropped first.
+	MaxBuffer int `json:"max_buffer,omitempty"`
+}
+
+// Validate checks c and returns its flush interval.
+func (c Config) Validate() (time.Duration, error) {
+	switch c.Kind {
+	case "", KindInflux:
+	case KindTimescale:
+		return 0, errors.New("tsdb: timescale needs a PostgreSQL driver, which this build does not include; use kind influx")
+	default:
+		return 0, fmt.Errorf("tsdb: unknown kind %q (have %s, %s)", c.Kind, KindInflux, KindTimescale)
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, fmt.Errorf("tsdb: url %q: want an http(s) URL such as http://influx:8086", c.URL)
+	}
+	if (c.Database == "") == (c.Bucket == "") {
+		return 0, errors.New("tsdb: set either database (InfluxDB 1.x) or bucket (InfluxDB 2.x)")
+	}
+	if c.BatchSize < 0 || c.MaxBuffer < 0 {
+		return 0, errors.New("tsdb: batch_size and max_buffer must not be negative")
+	}
+	interval := DefaultFlushInterval
+	if c.FlushInterval != "" {
+		if interval, err = time.ParseDuration(c.FlushInterval); err != nil || interval <= 0 {
+			return 0, fmt.Errorf("tsdb: invalid flush_interval %q", c.FlushInterval)
+		}
+	}
+	return interval, nil
+}
+
+// writeURL returns the write endpoint of c.
+func (c Config) writeURL() string {
+	base := strings.TrimSuffix(c.URL, "/")
+	if c.Bucket != "" {
+		q := url.Values{"bucket": {c.Bucket}, "precision": {"ns"}}
+		if c.Org != "" {
+			q.Set("org", c.Org)
+		}
+		return base + "/api/v2/write?" + q.Encode()
+	}
+	return base + "/write?" + url.Values{"db": {c.Database}, "precision": {"ns"}}.Encode()
+}
+
+// Writer buffers points and writes them in batches.
+type Writer struct {
+	cfg      Config
+	endpoint string
+	interval time.Duration
+	tags     string // the encoded Config.Tags, with a leading comma
+	client   *http.Client
+	logger   logging.Logger // nil means logging.Default at the time of a failure
+	kick     chan struct{}
+	flushMu  sync.Mutex // one Flush at a time
+
+	mu      sync.Mutex
+	lines   []string
+	trimmed uint64 // lines dropped by queue when the buffer is full, ever
+	written uint64
+	dropped uint64
+	failing bool
+}
+
+// New validates cfg and returns a writer. Call Run to write.
+func New(cfg Config, logger logging.Logger) (*Writer, error) {
+	interval, err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Measurement = cmp.Or(cfg.Measurement, DefaultMeasurement)
+	cfg.BatchSize = cmp.Or(cfg.BatchSize, DefaultBatchSize)
+	cfg.MaxBuffer = cmp.Or(cfg.MaxBuffer, DefaultMaxBuffer)
+	keys := make([]string, 0, len(cfg.Tags))
+	for k := range cfg.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // InfluxDB prefers tags in key order
+	var tags strings.Builder
+	for _, k := range keys {
+		tags.WriteString("," + escapeTag(k) + "=" + escapeTag(cfg.Tags[k]))
+	}
+	return &Writer{
+		cfg:      cfg,
+		endpoint: cfg.writeURL(),
+		interval: interval,
+		tags:     tags.String(),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+		kick:     make(chan struct{}, 1),
+	}, nil
+}
+
+// WriteSample queues the points of one tracking iteration reported by
+// device. Tracks carrying their own device are tagged with it.
+func (w *Writer) WriteSample(device string, sample telemetry.MultiTrackSample) {
+	if w == nil {
+		return
+	}
+	ts := sample.Timestamp.UnixNano()
+	lines := make([]string, 0, len(sample.Tracks)+1)
+	for _, t := range sample.Tracks {
+		var fields fieldSet
+		fields.float("angle_deg", t.AngleDeg)
+		fields.float("bearing_deg", t.BearingDeg)
+		fields.float("peak_dbfs", t.Peak)
+		fields.float("snr_db", t.SNR)
+		fields.float("confidence", t.Confidence)
+		fields.string("lock_state", string(t.LockState))
+		tags := deviceTag(cmp.Or(t.Device, device))
+		if t.ID != "" {
+			tags += ",track=" + escapeTag(t.ID)
+		}
+		lines = append(lines, w.line("_track", tags, &fields, ts))
+	}
+	var fields fieldSet
+	fields.int("tracks", len(sample.Tracks))
+	fields.bool("gap", sample.Gap)
+	lines = append(lines, w.line("_sample", deviceTag(device), &fields, ts))
+	w.queue(lines)
+}
+
+// WriteSensors queues a sensor reading of device. Sensors that could not be
+// read, or that the backend lacks (reported as zero, like in /api/sensors),
+// are left out.
+func (w *Writer) WriteSensors(device string, s sdr.Sensors) {
+	if w == nil {
+		return
+	}
+	var fields fieldSet
+	for _, f := range []struct {
+		json, field string
+		v           float64
+	}{
+		{"temperatureC", "temperature_c", s.TemperatureC},
+		{"rssi0Db", "rssi0_db", s.RSSI0DB},
+		{"rssi1Db", "rssi1_db", s.RSSI1DB},
+	} {
+		if _, failed := s.Errors[f.json]; !failed && f.v != 0 {
+			fields.float(f.field, f.v)
+		}
+	}
+	if fields.b.Len() == 0 {
+		return
+	}
+	w.queue([]string{w.line("_sensors", deviceTag(device), &fields, s.Time.UnixNano())})
+}
+
+func deviceTag(device string) string {
+	if device == "" {
+		return ""
+	}
+	return ",device=" + escapeTag(device)
+}
+
+func (w *Writer) line(suffix, tags string, fields *fieldSet, ts int64) string {
+	return escapeMeasurement(w.cfg.Measurement+suffix) + w.tags + tags + " " + fields.b.String() + " " + strconv.FormatInt(ts, 10)
+}
+
+// queue appends lines to the buffer, dropping the oldest when it is full,
+// and wakes Run once a batch is complete.
+func (w *Writer) queue(lines []string) {
+	w.mu.Lock()
+	w.lines = append(w.lines, lines...)
+	if n := len(w.lines) - w.cfg.MaxBuffer; n > 0 {
+		w.lines = w.lines[n:]
+		w.dropped += uint64(n)
+		w.trimmed += uint64(n)
+	}
+	full := len(w.lines) >= w.cfg.BatchSize
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run writes the buffered points every flush interval, and as soon as a
+// batch is complete, until ctx is done; then it writes what is left. After a
+// failure it waits twice as long, up to a minute, before the next attempt.
+func (w *Writer) Run(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	delay := w.interval
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			w.logFlush(w.Flush(flushCtx))
+			cancel()
+			return
+		case <-w.kick:
+			if delay > w.interval {
+				continue // backing off; the timer retries
+			}
+		case <-timer.C:
+		}
+		err := w.Flush(ctx)
+		w.logFlush(err)
+		if err != nil {
+			delay = min(2*delay, maxRetryDelay)
+		} else {
+			delay = w.interval
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(delay)
+	}
+}
+
+// Flush writes the buffered points in batches until the buffer is empty or
+// a write fails. A batch the database rejects as invalid is dropped, since
+// it would never succeed; other failures keep it for the next attempt.
+func (w *Writer) Flush(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	for {
+		w.mu.Lock()
+		n := min(len(w.lines), w.cfg.BatchSize)
+		batch, trimmed := w.lines[:n:n], w.trimmed
+		w.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		err := w.post(ctx, batch)
+		var rejected *rejectedError
+		w.mu.Lock()
+		// Points queued meanwhile may have pushed part of the batch out.
+		left := max(0, n-int(w.trimmed-trimmed))
+		switch {
+		case err == nil:
+			w.written += uint64(n)
+			w.lines = w.lines[left:]
+		case errors.As(err, &rejected):
+			w.dropped += uint64(left)
+			w.lines = w.lines[left:]
+		}
+		w.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// rejectedError is a write the database refused as invalid.
+type rejectedError struct{ msg string }
+
+func (e *rejectedError) Error() string { return e.msg }
+
+func (w *Writer) post(ctx context.Context, lines []string) error {
+	body := []byte(strings.Join(lines, "\n") + "\n")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.cfg.Token)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("write to %s: %w", w.cfg.URL, err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("write to %s: %s: %s", w.cfg.URL, resp.Status, bytes.TrimSpace(msg))
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return &rejectedError{err.Error()}
+	}
+	return err
+}
+
+// logFlush logs failures once until writes succeed again.
+func (w *Writer) logFlush(err error) {
+	w.mu.Lock()
+	changed := (err != nil) != w.failing
+	w.failing = err != nil
+	dropped := w.dropped
+	w.mu.Unlock()
+	if !changed {
+		return
+	}
+	logger := w.logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+	if err != nil {
+		logger.Warn("tsdb write failed", logging.Field{Key: "subsystem", Value: "tsdb"}, logging.Field{Key: "error", Value: err})
+	} else {
+		logger.Info("tsdb write recovered", logging.Field{Key: "subsystem", Value: "tsdb"}, logging.Field{Key: "dropped", Value: dropped})
+	}
+}
+
+// Stats returns the number of points written and dropped, and the number
+// still buffered.
+func (w *Writer) Stats() (written, dropped uint64, buffered int) {
+	if w == nil {
+		return 0, 0, 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written, w.dropped, len(w.lines)
+}
+
+// fieldSet builds the field part of a line.
+type fieldSet struct{ b strings.Builder }
+
+func (f *fieldSet) add(key, value string) {
+	if f.b.Len() > 0 {
+		f.b.WriteByte(',')
+	}
+	f.b.WriteString(escapeTag(key))
+	f.b.WriteByte('=')
+	f.b.WriteString(value)
+}
+
+// float adds a float field; NaN and infinities, which the line protocol
+// cannot carry, are left out.
+func (f *fieldSet) float(key string, v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	f.add(key, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (f *fieldSet) int(key string, v int) { f.add(key, strconv.Itoa(v)+"i") }
+
+func (f *fieldSet) bool(key string, v bool) { f.add(key, strconv.FormatBool(v)) }
+
+func (f *fieldSet) string(key, v string) {
+	f.add(key, `"`+strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v)+`"`)
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+func escapeMeasurement(s string) string { return measurementEscaper.Replace(s) }
+
+func escapeTag(s string) string { return tagEscaper.Replace(s) }
@@ -0,0 +1,140 @@
+package tsdb
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rjboer/GoSDR/internal/sdr"
+	"github.com/rjboer/GoSDR/internal/telemetry"
+)
+
+func TestConfigValidate(t *testing.T) {
+	good := Config{URL: "http://influx:8086", Bucket: "sdr", Org: "site"}
+	if _, err := good.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := good.writeURL(); got != "http://influx:8086/api/v2/write?bucket=sdr&org=site&precision=ns" {
+		t.Fatalf("v2 write URL %s", got)
+	}
+	if got := (Config{URL: "http://influx:8086/", Database: "sdr"}).writeURL(); got != "http://influx:8086/write?db=sdr&precision=ns" {
+		t.Fatalf("v1 write URL %s", got)
+	}
+	for _, bad := range []Config{
+		{URL: "influx:8086", Bucket: "sdr"},
+		{URL: "http://influx:8086"},
+		{URL: "http://influx:8086", Bucket: "sdr", Database: "sdr"},
+		{URL: "http://influx:8086", Bucket: "sdr", FlushInterval: "0s"},
+		{URL: "http://influx:8086", Bucket: "sdr", BatchSize: -1},
+		{Kind: KindTimescale, URL: "http://pg:5432", Bucket: "sdr"},
+		{Kind: "graphite", URL: "http://g:2003", Bucket: "sdr"},
+	} {
+		if _, err := bad.Validate(); err == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	w, err := New(Config{URL: "http://influx:8086", Database: "sdr", Tags: map[string]string{"site": "north pole", "array": "a,1"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1700000000, 5)
+	w.WriteSample("mast", telemetry.MultiTrackSample{Timestamp: ts, Gap: true, Tracks: []telemetry.TrackSample{
+		{ID: "t=1", AngleDeg: 12.5, BearingDeg: 102.5, Peak: -20, SNR: 18, Confidence: 0.5, LockState: telemetry.LockStateLocked},
+		{ID: "2", Device: "aux", AngleDeg: math.NaN(), LockState: `say "hi"`},
+	}})
+	w.WriteSensors("", sdr.Sensors{Time: ts, TemperatureC: 41.5, RSSI0DB: 60, Errors: map[string]string{"rssi1Db": "timeout"}})
+	w.WriteSensors("mast", sdr.Sensors{Time: ts, Errors: map[string]string{"temperatureC": "timeout"}})
+
+	want := []string{
+		`gosdr_track,array=a\,1,site=north\ pole,device=mast,track=t\=1 angle_deg=12.5,bearing_deg=102.5,peak_dbfs=-20,snr_db=18,confidence=0.5,lock_state="locked" 1700000000000000005`,
+		`gosdr_track,array=a\,1,site=north\ pole,device=aux,track=2 bearing_deg=0,peak_dbfs=0,snr_db=0,confidence=0,lock_state="say \"hi\"" 1700000000000000005`,
+		`gosdr_sample,array=a\,1,site=north\ pole,device=mast tracks=2i,gap=true 1700000000000000005`,
+		`gosdr_sensors,array=a\,1,site=north\ pole temperature_c=41.5,rssi0_db=60 1700000000000000005`,
+	}
+	if len(w.lines) != len(want) {
+		t.Fatalf("%d lines:\n%s", len(w.lines), strings.Join(w.lines, "\n"))
+	}
+	for i := range want {
+		if w.lines[i] != want[i] {
+			t.Errorf("line %d\n got %s\nwant %s", i, w.lines[i], want[i])
+		}
+	}
+}
+
+func TestWriterBatchesAndRetries(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		bodies  []string
+		failing = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token s3cret" || r.URL.Query().Get("bucket") != "sdr" {
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			http.Error(rw, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "reject") {
+			http.Error(rw, "partial write: bad line", http.StatusBadRequest)
+			return
+		}
+		bodies = append(bodies, string(body))
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w, err := New(Config{URL: srv.URL, Bucket: "sdr", Token: "s3cret", BatchSize: 2, MaxBuffer: 4}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1700000000, 0)
+	for i := range 5 {
+		w.WriteSensors("d", sdr.Sensors{Time: ts.Add(time.Duration(i) * time.Second), TemperatureC: float64(40 + i)})
+	}
+	ctx := context.Background()
+	if err := w.Flush(ctx); err == nil {
+		t.Fatal("flush succeeded against a failing server")
+	}
+	if written, dropped, buffered := w.Stats(); written != 0 || dropped != 1 || buffered != 4 {
+		t.Fatalf("after failure: %d written, %d dropped, %d buffered", written, dropped, buffered)
+	}
+
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if err := w.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || strings.Count(bodies[0], "\n") != 2 || !strings.Contains(bodies[0], "temperature_c=41 ") {
+		t.Fatalf("bodies %q", bodies)
+	}
+
+	// A batch the database rejects is dropped rather than retried forever.
+	w.WriteSensors("reject", sdr.Sensors{Time: ts, TemperatureC: 1})
+	if err := w.Flush(ctx); err == nil {
+		t.Fatal("rejected batch reported no error")
+	}
+	if written, dropped, buffered := w.Stats(); written != 4 || dropped != 2 || buffered != 0 {
+		t.Fatalf("after rejection: %d written, %d dropped, %d buffered", written, dropped, buffered)
+	}
+
+	var nilWriter *Writer
+	nilWriter.WriteSample("", telemetry.MultiTrackSample{})
+	if err := nilWriter.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+}